@@ -35,6 +35,7 @@ const (
 	CmdProposalCommentsLikes = "proposalcommentslikes"
 	CmdInventory             = "inventory"
 	CmdTokenInventory        = "tokeninventory"
+	CmdExport                = "export"
 	MDStreamAuthorizeVote    = 13 // Vote authorization by proposal author
 	MDStreamVoteBits         = 14 // Vote bits and mask
 	MDStreamVoteSnapshot     = 15 // Vote tickets and start/end parameters
@@ -1258,3 +1259,58 @@ func DecodeLoadVoteResultsReply(payload []byte) (*LoadVoteResultsReply, error) {
 
 	return &reply, nil
 }
+
+// Export requests all decred plugin data of a proposal so that it can be
+// included in a record bundle.
+type Export struct {
+	Token string `json:"token"` // Censorship token
+}
+
+// EncodeExport encodes an Export into a JSON byte slice.
+func EncodeExport(e Export) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// DecodeExport decodes a JSON byte slice into an Export.
+func DecodeExport(payload []byte) (*Export, error) {
+	var e Export
+
+	err := json.Unmarshal(payload, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// CastVoteDetails is a cast vote along with the receipt that was issued by
+// the server when the vote was cast.
+type CastVoteDetails struct {
+	CastVote CastVote `json:"castvote"` // Client side vote
+	Receipt  string   `json:"receipt"`  // Server signature of CastVote.Signature
+}
+
+// ExportReply is the reply to the Export command.  Comments are sorted by
+// comment ID and cast votes are in the order they were received.  Censored
+// comments are included without their comment text.
+type ExportReply struct {
+	Comments  []Comment         `json:"comments"`  // All comments
+	CastVotes []CastVoteDetails `json:"castvotes"` // All cast votes
+}
+
+// EncodeExportReply encodes an ExportReply into a JSON byte slice.
+func EncodeExportReply(er ExportReply) ([]byte, error) {
+	return json.Marshal(er)
+}
+
+// DecodeExportReply decodes a JSON byte slice into an ExportReply.
+func DecodeExportReply(payload []byte) (*ExportReply, error) {
+	var er ExportReply
+
+	err := json.Unmarshal(payload, &er)
+	if err != nil {
+		return nil, err
+	}
+
+	return &er, nil
+}
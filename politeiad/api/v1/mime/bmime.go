@@ -1,28 +1,76 @@
 package mime
 
 import (
+	"bytes"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	svg "github.com/h2non/go-is-svg"
 )
 
+// Validator verifies that a payload of the provided size is well formed for
+// its MIME type.  The payload is read through r so that large files do not
+// need to be held in memory.
+type Validator func(r io.ReaderAt, size int64) error
+
+// Sanitizer returns a copy of the payload with all active content, such as
+// scripts, removed.
+type Sanitizer func(payload []byte) ([]byte, error)
+
+// Type describes a MIME type that can be communicated between client and
+// server.
+type Type struct {
+	Name     string    // MIME type
+	Sniffed  string    // MIME type detected by DetectMimeType, defaults to Name
+	Validate Validator // Verifies the payload, optional
+	Sanitize Sanitizer // Removes active content from the payload, optional
+}
+
 var (
 	// validMimeTypesList is a list of all acceptable MIME types that
 	// can be communicated between client and server.
-	validMimeTypesList = []string{
-		"image/png",
-		"text/plain",
-		"text/plain; charset=utf-8",
-	}
+	validMimeTypesList []string
 
 	// validMimeTypesMap is the same as ValidMimeTypesList, but structured
 	// as a map for fast access.
-	validMimeTypesMap = make(map[string]struct{}, len(validMimeTypesList))
+	validMimeTypesMap = make(map[string]Type)
 
 	ErrUnsupportedMimeType = errors.New("unsupported MIME type")
 )
 
+const (
+	// sniffLen is the number of bytes at the start of a payload that are
+	// used to detect its MIME type.
+	sniffLen = 512
+
+	// svgHeadLen is the number of bytes at the start of a text payload
+	// in which the root svg element must start for the payload to be
+	// detected as an SVG when it is read through a reader.
+	svgHeadLen = 4096
+
+	// svgTrailerLen is the number of bytes at the end of a text payload
+	// that are searched for the closing svg tag.
+	svgTrailerLen = 64
+)
+
+// Register adds a MIME type to the list of acceptable MIME types.  It is
+// meant to be called from init functions and panics if the MIME type has
+// already been registered.
+func Register(t Type) {
+	if _, ok := validMimeTypesMap[t.Name]; ok {
+		panic(fmt.Sprintf("mime: %v registered twice", t.Name))
+	}
+	if t.Sniffed == "" {
+		t.Sniffed = t.Name
+	}
+	validMimeTypesList = append(validMimeTypesList, t.Name)
+	validMimeTypesMap[t.Name] = t
+}
+
 // MimeValid returns true if the passed string is a valid
 // MIME type, false otherwise.
 func MimeValid(s string) bool {
@@ -30,11 +78,57 @@ func MimeValid(s string) bool {
 	return ok
 }
 
+// MimeMatches returns true if a payload for which DetectMimeType returned
+// detected may be declared as the provided MIME type.  Not all MIME types
+// can be told apart by sniffing, e.g. CSV files are detected as plain text.
+func MimeMatches(declared, detected string) bool {
+	if declared == detected {
+		return true
+	}
+	t, ok := validMimeTypesMap[declared]
+	return ok && t.Sniffed == detected
+}
+
 // ValidMimeTypes returns the list of supported MIME types.
 func ValidMimeTypes() []string {
 	return validMimeTypesList
 }
 
+// Validate verifies that the payload is well formed for the provided MIME
+// type and that it does not contain active content.
+func Validate(mimeType string, payload []byte) error {
+	return ValidateReader(mimeType, bytes.NewReader(payload),
+		int64(len(payload)))
+}
+
+// ValidateReader is identical to Validate except that the payload of the
+// provided size is read through r.
+func ValidateReader(mimeType string, r io.ReaderAt, size int64) error {
+	t, ok := validMimeTypesMap[mimeType]
+	if !ok {
+		return ErrUnsupportedMimeType
+	}
+	if t.Validate == nil {
+		return nil
+	}
+	return t.Validate(r, size)
+}
+
+// Sanitize returns the payload with all active content removed.  Payloads
+// of MIME types that do not have a sanitizer are returned unaltered.
+// Clients must sanitize files before calculating their digests since
+// politeiad rejects files that contain active content.
+func Sanitize(mimeType string, payload []byte) ([]byte, error) {
+	t, ok := validMimeTypesMap[mimeType]
+	if !ok {
+		return nil, ErrUnsupportedMimeType
+	}
+	if t.Sanitize == nil {
+		return payload, nil
+	}
+	return t.Sanitize(payload)
+}
+
 // DetectMimeType returns the file MIME type
 func DetectMimeType(data []byte) string {
 	// svg needs a specific check because the algorithm
@@ -45,8 +139,100 @@ func DetectMimeType(data []byte) string {
 	return http.DetectContentType(data)
 }
 
-func init() {
-	for _, v := range validMimeTypesList {
-		validMimeTypesMap[v] = struct{}{}
+// isSVGReader returns true if the text payload of the provided size that is
+// read through r is an SVG.  The payload is never read in full.  It must
+// start with the root svg element, optionally preceded by an XML
+// declaration, a doctype and comments within the first svgHeadLen bytes, and
+// it must end with the closing svg tag.
+func isSVGReader(r io.ReaderAt, size int64) (bool, error) {
+	trailerLen := int64(svgTrailerLen)
+	if trailerLen > size {
+		trailerLen = size
 	}
+	trailer := make([]byte, trailerLen)
+	_, err := r.ReadAt(trailer, size-trailerLen)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if !bytes.HasSuffix(bytes.ToLower(bytes.TrimSpace(trailer)),
+		[]byte("</svg>")) {
+		return false, nil
+	}
+
+	d := xml.NewDecoder(io.NewSectionReader(r, 0, svgHeadLen))
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			// Malformed or too long to find the root element.
+			return false, nil
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return strings.EqualFold(t.Name.Local, "svg"), nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				return false, nil
+			}
+		case xml.EndElement:
+			return false, nil
+		}
+	}
+}
+
+// DetectMimeTypeReader is identical to DetectMimeType except that the payload
+// of the provided size is read through r.  Only the start of the payload is
+// read in order to sniff it.  Text payloads that are larger than sniffLen
+// are additionally checked for being an SVG without reading them in full,
+// see isSVGReader.
+func DetectMimeTypeReader(r io.ReaderAt, size int64) (string, error) {
+	n := size
+	if n > sniffLen {
+		n = sniffLen
+	}
+	head := make([]byte, n)
+	_, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	detected := http.DetectContentType(head)
+	if !strings.HasPrefix(detected, "text/") || n == size {
+		return DetectMimeType(head), nil
+	}
+
+	isSVG, err := isSVGReader(r, size)
+	if err != nil {
+		return "", err
+	}
+	if isSVG {
+		return "image/svg+xml", nil
+	}
+	return detected, nil
+}
+
+func init() {
+	Register(Type{
+		Name:     "image/png",
+		Validate: validatePNG,
+	})
+	Register(Type{
+		Name: "text/plain",
+	})
+	Register(Type{
+		Name:     "text/plain; charset=utf-8",
+		Validate: validateText,
+	})
+	Register(Type{
+		Name:     "image/svg+xml",
+		Validate: validateSVG,
+		Sanitize: sanitizeSVG,
+	})
+	Register(Type{
+		Name:     "application/pdf",
+		Validate: validatePDF,
+	})
+	Register(Type{
+		Name:     "text/csv",
+		Sniffed:  "text/plain; charset=utf-8",
+		Validate: validateCSV,
+	})
 }
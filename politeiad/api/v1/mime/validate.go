@@ -0,0 +1,342 @@
+package mime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// pdfHeaderSize is the number of bytes at the start of a PDF that are
+	// matched against the PDF header.
+	pdfHeaderSize = 16
+
+	// pdfTrailerSize is the number of bytes at the end of a PDF that are
+	// searched for the startxref keyword and the end of file marker.
+	pdfTrailerSize = 1024
+
+	// pdfXrefSize is the number of bytes at the startxref offset that are
+	// matched against a cross reference table or stream.
+	pdfXrefSize = 64
+)
+
+var (
+	// pdfHeader matches the header that every PDF starts with.
+	pdfHeader = regexp.MustCompile(`^%PDF-[12]\.[0-9]`)
+
+	// pdfObject matches the start of an indirect object.
+	pdfObject = regexp.MustCompile(`^\s*[0-9]+\s+[0-9]+\s+obj`)
+
+	// pdfActive matches the PDF names that introduce active content.
+	// Content hidden in compressed object streams is not detected.
+	pdfActive = regexp.MustCompile(`/(JavaScript|JS|Launch|EmbeddedFiles?|RichMedia)[\s/<>\[\]()]`)
+
+	// svgActiveElements are the SVG elements that are removed along with
+	// their children.
+	svgActiveElements = map[string]struct{}{
+		"script":        {},
+		"foreignobject": {},
+		"iframe":        {},
+		"embed":         {},
+		"object":        {},
+	}
+
+	// ErrActiveContent is returned when a payload contains scripts or
+	// other active content.
+	ErrActiveContent = errors.New("active content")
+)
+
+// validatePNG verifies that the payload is a PNG image.
+func validatePNG(r io.ReaderAt, size int64) error {
+	_, err := png.DecodeConfig(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return fmt.Errorf("png: %v", err)
+	}
+	return nil
+}
+
+// validUTF8 verifies that the payload is UTF-8 encoded.  The payload is
+// streamed so that it does not have to be held in memory.
+func validUTF8(r io.ReaderAt, size int64) error {
+	br := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	for {
+		c, n, err := br.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c == utf8.RuneError && n == 1 {
+			return fmt.Errorf("invalid utf-8")
+		}
+	}
+}
+
+// validateText verifies that the payload is UTF-8 encoded text.
+func validateText(r io.ReaderAt, size int64) error {
+	err := validUTF8(r, size)
+	if err != nil {
+		return fmt.Errorf("text: %v", err)
+	}
+	return nil
+}
+
+// validateCSV verifies that the payload is an UTF-8 encoded CSV file in
+// which all records have the same number of fields.
+func validateCSV(r io.ReaderAt, size int64) error {
+	err := validUTF8(r, size)
+	if err != nil {
+		return fmt.Errorf("csv: %v", err)
+	}
+
+	cr := csv.NewReader(io.NewSectionReader(r, 0, size))
+	cr.ReuseRecord = true
+	var records int
+	for {
+		_, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("csv: %v", err)
+		}
+		records++
+	}
+	if records == 0 {
+		return fmt.Errorf("csv: no records")
+	}
+	return nil
+}
+
+// readAt returns n bytes of the payload starting at offset.  Fewer bytes are
+// returned if the payload ends first.
+func readAt(r io.ReaderAt, offset, n int64) ([]byte, error) {
+	b := make([]byte, n)
+	c, err := r.ReadAt(b, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return b[:c], nil
+}
+
+// validatePDF performs a structural check of a PDF.  It verifies the header,
+// the end of file marker and that the startxref offset points to a cross
+// reference table or stream.  PDFs that contain scripts, launch actions or
+// embedded files are rejected.
+func validatePDF(r io.ReaderAt, size int64) error {
+	header, err := readAt(r, 0, pdfHeaderSize)
+	if err != nil {
+		return fmt.Errorf("pdf: %v", err)
+	}
+	if !pdfHeader.Match(header) {
+		return fmt.Errorf("pdf: invalid header")
+	}
+
+	offset := size - pdfTrailerSize
+	if offset < 0 {
+		offset = 0
+	}
+	trailer, err := readAt(r, offset, size-offset)
+	if err != nil {
+		return fmt.Errorf("pdf: %v", err)
+	}
+	eof := bytes.LastIndex(trailer, []byte("%%EOF"))
+	if eof == -1 {
+		return fmt.Errorf("pdf: missing end of file marker")
+	}
+	i := bytes.LastIndex(trailer[:eof], []byte("startxref"))
+	if i == -1 {
+		return fmt.Errorf("pdf: missing startxref")
+	}
+	xrefOffset, err := strconv.ParseInt(string(bytes.TrimSpace(
+		trailer[i+len("startxref"):eof])), 10, 64)
+	if err != nil || xrefOffset <= 0 || xrefOffset >= size {
+		return fmt.Errorf("pdf: invalid startxref")
+	}
+	xref, err := readAt(r, xrefOffset, pdfXrefSize)
+	if err != nil {
+		return fmt.Errorf("pdf: %v", err)
+	}
+	if !bytes.HasPrefix(xref, []byte("xref")) && !pdfObject.Match(xref) {
+		return fmt.Errorf("pdf: invalid cross reference")
+	}
+
+	// Offsets that are returned for a rune reader are byte offsets.
+	m := pdfActive.FindReaderIndex(bufio.NewReader(
+		io.NewSectionReader(r, 0, size)))
+	if m != nil {
+		active, err := readAt(r, int64(m[0]), int64(m[1]-m[0]))
+		if err != nil {
+			return fmt.Errorf("pdf: %v", err)
+		}
+		return fmt.Errorf("pdf: %v %s", ErrActiveContent,
+			bytes.TrimRight(active[1:], " \t\r\n/<>[]()"))
+	}
+
+	return nil
+}
+
+// svgActiveAttr returns true if the attribute is an event handler or if its
+// value is a script URI.
+func svgActiveAttr(a xml.Attr) bool {
+	if strings.HasPrefix(strings.ToLower(a.Name.Local), "on") {
+		return true
+	}
+	v := strings.ToLower(strings.Join(strings.Fields(a.Value), ""))
+	return strings.Contains(v, "javascript:") ||
+		strings.Contains(v, "data:text/html")
+}
+
+// svgName returns the raw, possibly prefixed, name of an element or an
+// attribute.
+func svgName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// svgWalk copies the SVG to out while dropping script elements, event handler
+// attributes, script URIs and directives.  It returns true if anything was
+// dropped.  Everything else is copied byte for byte.
+func svgWalk(r io.ReaderAt, size int64, out io.Writer) (bool, error) {
+	var (
+		last    int64 // Offset up to which the payload has been handled
+		skip    int   // Depth inside of a dropped element
+		changed bool
+	)
+	// copyTo copies the payload that has not been handled yet up to the
+	// provided offset.
+	copyTo := func(offset int64) error {
+		_, err := io.Copy(out, io.NewSectionReader(r, last, offset-last))
+		return err
+	}
+	d := xml.NewDecoder(io.NewSectionReader(r, 0, size))
+	for {
+		start := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		end := d.InputOffset()
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			name := strings.ToLower(t.Name.Local)
+			if _, ok := svgActiveElements[name]; ok {
+				err := copyTo(start)
+				if err != nil {
+					return false, err
+				}
+				skip = 1
+				changed = true
+				continue
+			}
+
+			attrs := make([]xml.Attr, 0, len(t.Attr))
+			for _, a := range t.Attr {
+				if !svgActiveAttr(a) {
+					attrs = append(attrs, a)
+				}
+			}
+			if len(attrs) == len(t.Attr) {
+				continue
+			}
+
+			// Rewrite the tag without the active attributes.
+			err := copyTo(start)
+			if err != nil {
+				return false, err
+			}
+			var tag bytes.Buffer
+			tag.WriteString("<" + svgName(t.Name))
+			for _, a := range attrs {
+				tag.WriteString(" " + svgName(a.Name) + `="`)
+				xml.EscapeText(&tag, []byte(a.Value))
+				tag.WriteString(`"`)
+			}
+			closing, err := readAt(r, end-2, 2)
+			if err != nil {
+				return false, err
+			}
+			if bytes.Equal(closing, []byte("/>")) {
+				tag.WriteString("/>")
+			} else {
+				tag.WriteString(">")
+			}
+			_, err = out.Write(tag.Bytes())
+			if err != nil {
+				return false, err
+			}
+			last = end
+			changed = true
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				if skip == 0 {
+					last = end
+				}
+			}
+		case xml.Directive:
+			if skip > 0 {
+				continue
+			}
+			err := copyTo(start)
+			if err != nil {
+				return false, err
+			}
+			last = end
+			changed = true
+		}
+	}
+	if skip > 0 {
+		return false, fmt.Errorf("unexpected EOF")
+	}
+	err := copyTo(size)
+	if err != nil {
+		return false, err
+	}
+
+	return changed, nil
+}
+
+// sanitizeSVG removes scripts and other active content from an SVG.
+func sanitizeSVG(payload []byte) ([]byte, error) {
+	var b bytes.Buffer
+	_, err := svgWalk(bytes.NewReader(payload), int64(len(payload)), &b)
+	if err != nil {
+		return nil, fmt.Errorf("svg: %v", err)
+	}
+	return b.Bytes(), nil
+}
+
+// validateSVG verifies that the payload is well formed XML that does not
+// contain any active content.
+func validateSVG(r io.ReaderAt, size int64) error {
+	changed, err := svgWalk(r, size, ioutil.Discard)
+	if err != nil {
+		return fmt.Errorf("svg: %v", err)
+	}
+	if changed {
+		return fmt.Errorf("svg: %v", ErrActiveContent)
+	}
+	return nil
+}
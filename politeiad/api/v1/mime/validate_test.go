@@ -0,0 +1,154 @@
+package mime
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// newPDF returns a minimal PDF with a valid cross reference table.
+func newPDF(body string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	obj := b.Len()
+	fmt.Fprintf(&b, "1 0 obj\n<< /Type /Catalog %v>>\nendobj\n", body)
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 2\n0000000000 65535 f \n%010d 00000 n \n", obj)
+	fmt.Fprintf(&b, "trailer\n<< /Size 2 /Root 1 0 R >>\nstartxref\n%v\n%%%%EOF\n",
+		xref)
+	return b.Bytes()
+}
+
+func TestValidate(t *testing.T) {
+	var p bytes.Buffer
+	err := png.Encode(&p, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mime    string
+		payload []byte
+		valid   bool
+	}{
+		{"png", "image/png", p.Bytes(), true},
+		{"png truncated", "image/png", p.Bytes()[:10], false},
+		{"text", "text/plain; charset=utf-8", []byte("hello"), true},
+		{"text utf-8", "text/plain; charset=utf-8", []byte("a\xffb"),
+			false},
+		{"pdf", "application/pdf", newPDF(""), true},
+		{"pdf header", "application/pdf", []byte("%PDF-"), false},
+		{"pdf truncated", "application/pdf", newPDF("")[:40], false},
+		{"pdf javascript", "application/pdf",
+			newPDF("/OpenAction << /S /JavaScript /JS (x) >> "), false},
+		{"csv", "text/csv", []byte("a,b\n1,2\n"), true},
+		{"csv fields", "text/csv", []byte("a,b\n1\n"), false},
+		{"csv empty", "text/csv", []byte(""), false},
+		{"svg", "image/svg+xml",
+			[]byte(`<svg xmlns="http://www.w3.org/2000/svg"><rect/></svg>`),
+			true},
+		{"svg script", "image/svg+xml",
+			[]byte(`<svg><script>alert(1)</script></svg>`), false},
+		{"svg handler", "image/svg+xml",
+			[]byte(`<svg onload="alert(1)"></svg>`), false},
+		{"unsupported", "application/zip", []byte("PK"), false},
+	}
+	for _, v := range tests {
+		err := Validate(v.mime, v.payload)
+		if (err == nil) != v.valid {
+			t.Errorf("%v: got %v, want valid %v", v.name, err, v.valid)
+		}
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	in := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "y">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" onload="alert(1)">
+  <script type="text/javascript"><![CDATA[alert(2)]]></script>
+  <a xlink:href="javascript:alert(3)"><rect width="1" height="1"/></a>
+  <foreignObject><div><script>alert(4)</script></div></foreignObject>
+  <circle r="1" onclick="alert(5)"/>
+</svg>`
+	out, err := Sanitize("image/svg+xml", []byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"alert", "script", "foreignObject",
+		"ENTITY"} {
+		if strings.Contains(string(out), v) {
+			t.Fatalf("%v not removed: %s", v, out)
+		}
+	}
+	for _, v := range []string{`<rect width="1" height="1"/>`,
+		`<circle r="1"/>`, `<a>`} {
+		if !strings.Contains(string(out), v) {
+			t.Fatalf("%v missing: %s", v, out)
+		}
+	}
+	if err := Validate("image/svg+xml", out); err != nil {
+		t.Fatalf("sanitized svg is invalid: %v", err)
+	}
+
+	// Sanitizing a clean file is a no-op.
+	again, err := Sanitize("image/svg+xml", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Fatalf("sanitize is not idempotent: %s", again)
+	}
+}
+
+func TestMimeMatches(t *testing.T) {
+	csv := []byte("a,b\n1,2\n")
+	detected := DetectMimeType(csv)
+	if !MimeMatches("text/csv", detected) {
+		t.Fatalf("text/csv does not match %v", detected)
+	}
+	if MimeMatches("image/png", detected) {
+		t.Fatalf("image/png matches %v", detected)
+	}
+	if d := DetectMimeType(newPDF("")); !MimeMatches("application/pdf", d) {
+		t.Fatalf("application/pdf does not match %v", d)
+	}
+}
+
+func TestDetectMimeTypeReader(t *testing.T) {
+	large := strings.Repeat("<rect/>\n", sniffLen)
+	svg := `<?xml version="1.0"?>
+<!-- comment -->
+<svg xmlns="http://www.w3.org/2000/svg">` + large + "</svg>\n"
+
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{"small svg", "<svg><rect/></svg>", "image/svg+xml"},
+		{"large svg", svg, "image/svg+xml"},
+		{"large text", strings.Repeat("hello\n", sniffLen),
+			"text/plain; charset=utf-8"},
+		{"unclosed svg", "<svg>" + large,
+			"text/plain; charset=utf-8"},
+		{"html", "<html>" + large + "</svg>",
+			"text/html; charset=utf-8"},
+		{"late svg", "<!--" + strings.Repeat(" ", svgHeadLen) +
+			"-->" + "<svg>" + large + "</svg>",
+			"text/html; charset=utf-8"},
+	}
+	for _, v := range tests {
+		got, err := DetectMimeTypeReader(strings.NewReader(v.payload),
+			int64(len(v.payload)))
+		if err != nil {
+			t.Fatalf("%v: %v", v.name, err)
+		}
+		if got != v.want {
+			t.Errorf("%v: got %v, want %v", v.name, got, v.want)
+		}
+	}
+}
@@ -5,11 +5,14 @@
 package v1
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 
 	"github.com/decred/dcrtime/merkle"
 	"github.com/thi4go/politeia/politeiad/api/v1/identity"
@@ -18,6 +21,9 @@ import (
 
 type ErrorStatusT int
 type RecordStatusT int
+type DiffActionT int
+type TimestampStatusT int
+type ChangeT int
 
 const (
 	// Routes
@@ -28,6 +34,12 @@ const (
 	UpdateVettedMetadataRoute = "/v1/updatevettedmd/" // Update vetted metadata
 	GetUnvettedRoute          = "/v1/getunvetted/"    // Retrieve unvetted record
 	GetVettedRoute            = "/v1/getvetted/"      // Retrieve vetted record
+	GetVettedDiffRoute        = "/v1/getvetteddiff/"  // Diff vetted versions
+	GetTombstoneRoute         = "/v1/gettombstone/"   // Retrieve tombstone
+	GetTimestampsRoute        = "/v1/gettimestamps/"  // Retrieve anchor proofs
+	GetBundleRoute            = "/v1/getbundle/"      // Retrieve record bundle
+	UploadInitRoute           = "/v1/uploadinit/"     // Start chunked upload
+	UploadPartRoute           = "/v1/uploadpart/"     // Upload single part
 
 	// Auth required
 	InventoryRoute         = "/v1/inventory/"                  // Inventory records
@@ -36,10 +48,38 @@ const (
 	PluginCommandRoute     = "/v1/plugin/"                     // Send a command to a plugin
 	PluginInventoryRoute   = PluginCommandRoute + "inventory/" // Inventory all plugins
 	UpdateReadmeRoute      = "/v1/updatereadme/"               // Update README
+	InventoryPageRoute     = "/v1/inventorypage/"              // Inventory page of records
+	PurgeRecordRoute       = "/v1/purgerecord/"                // Purge censored record
+	VerifyCacheRoute       = "/v1/verifycache/"                // Verify cache
+	ChangeFeedRoute        = "/v1/changefeed/"                 // Wait for record changes
+	AuditLogRoute          = "/v1/auditlog/"                   // Page through audit log
 
 	ChallengeSize      = 32         // Size of challenge token in bytes
 	TokenSize          = 32         // Size of token
 	MetadataStreamsMax = uint64(16) // Maximum number of metadata streams
+	InventoryPageSize  = uint(100)  // Maximum number of records per page
+
+	// Chunked uploads
+	UploadIDSize   = 16               // Size of upload identifier in bytes
+	UploadPartSize = int64(1 << 20)   // Size of every part but the last
+	UploadMaxSize  = int64(256 << 20) // Maximum size of an uploaded file
+	UploadExpiry   = int64(60 * 60)   // Seconds an upload remains valid
+
+	// Signed requests.  Privileged routes accept requests that are signed
+	// with the ed25519 identity of a configured client as an alternative
+	// to the rpcuser and rpcpass credentials.
+	ClientKeyHeader       = "X-Politeiad-Key"       // Hex encoded public key
+	ClientTimestampHeader = "X-Politeiad-Timestamp" // Unix time of signing
+	ClientSignatureHeader = "X-Politeiad-Signature" // Hex encoded signature
+	ClientSignatureMaxAge = int64(5 * 60)           // Seconds a signature is valid
+	ClientRequestMaxSize  = 2 * UploadPartSize      // Maximum size of a request body
+
+	// Change feed
+	ChangeFeedPageSize   = uint32(100) // Maximum number of events per reply
+	ChangeFeedTimeoutMax = uint32(300) // Maximum seconds to wait for events
+
+	// Audit log
+	AuditLogPageSize = uint32(100) // Maximum number of entries per reply
 
 	// Error status codes
 	ErrorStatusInvalid                       ErrorStatusT = 0
@@ -59,6 +99,23 @@ const (
 	ErrorStatusNoChanges                     ErrorStatusT = 14
 	ErrorStatusRecordFound                   ErrorStatusT = 15
 	ErrorStatusInvalidRPCCredentials         ErrorStatusT = 16
+	ErrorStatusInvalidRecordVersion          ErrorStatusT = 17
+	ErrorStatusInvalidCursor                 ErrorStatusT = 18
+	ErrorStatusInvalidInventoryFilter        ErrorStatusT = 19
+	ErrorStatusRecordNotCensored             ErrorStatusT = 20
+	ErrorStatusRecordPurged                  ErrorStatusT = 21
+	ErrorStatusInvalidUpload                 ErrorStatusT = 22
+	ErrorStatusInvalidUploadPart             ErrorStatusT = 23
+	ErrorStatusUploadIncomplete              ErrorStatusT = 24
+	ErrorStatusMalformedFile                 ErrorStatusT = 25
+	ErrorStatusInvalidPluginID               ErrorStatusT = 26
+	ErrorStatusInvalidPluginCmd              ErrorStatusT = 27
+	ErrorStatusNotSupported                  ErrorStatusT = 28
+	ErrorStatusInvalidChangeSequence         ErrorStatusT = 29
+	ErrorStatusPermissionDenied              ErrorStatusT = 30
+	ErrorStatusInvalidAuditSequence          ErrorStatusT = 31
+	ErrorStatusUploadLimit                   ErrorStatusT = 32
+	ErrorStatusRecordNotFound                ErrorStatusT = 33
 
 	// Record status codes (set and get)
 	RecordStatusInvalid           RecordStatusT = 0 // Invalid status
@@ -69,6 +126,27 @@ const (
 	RecordStatusUnreviewedChanges RecordStatusT = 5 // Unvetted record that has been changed
 	RecordStatusArchived          RecordStatusT = 6 // Vetted record that has been archived
 
+	// Diff actions
+	DiffActionInvalid  DiffActionT = 0 // Invalid action
+	DiffActionAdded    DiffActionT = 1 // Only present in the new version
+	DiffActionRemoved  DiffActionT = 2 // Only present in the old version
+	DiffActionModified DiffActionT = 3 // Present in both but different
+
+	// Timestamp status codes
+	TimestampStatusInvalid     TimestampStatusT = 0 // Invalid status
+	TimestampStatusNotAnchored TimestampStatusT = 1 // Not anchored yet
+	TimestampStatusUnconfirmed TimestampStatusT = 2 // Anchor not confirmed
+	TimestampStatusConfirmed   TimestampStatusT = 3 // Anchor confirmed
+
+	// Change types
+	ChangeInvalid        ChangeT = 0 // Invalid change
+	ChangeNewRecord      ChangeT = 1 // Record was submitted
+	ChangeUpdateRecord   ChangeT = 2 // Record files or metadata were updated
+	ChangeSetStatus      ChangeT = 3 // Record status was changed
+	ChangeUpdateMetadata ChangeT = 4 // Vetted record metadata was updated
+	ChangePluginCommand  ChangeT = 5 // Plugin command was executed
+	ChangePurgeRecord    ChangeT = 6 // Censored record was purged
+
 	// Default network bits
 	DefaultMainnetHost = "politeia.decred.org"
 	DefaultMainnetPort = "49374"
@@ -98,6 +176,23 @@ var (
 		ErrorStatusNoChanges:                     "no changes in record",
 		ErrorStatusRecordFound:                   "record found",
 		ErrorStatusInvalidRPCCredentials:         "invalid RPC client credentials",
+		ErrorStatusInvalidRecordVersion:          "invalid record version",
+		ErrorStatusInvalidCursor:                 "invalid cursor",
+		ErrorStatusInvalidInventoryFilter:        "invalid inventory filter",
+		ErrorStatusRecordNotCensored:             "record is not censored",
+		ErrorStatusRecordPurged:                  "record has been purged",
+		ErrorStatusInvalidUpload:                 "invalid upload",
+		ErrorStatusInvalidUploadPart:             "invalid upload part",
+		ErrorStatusUploadIncomplete:              "upload incomplete",
+		ErrorStatusMalformedFile:                 "malformed file",
+		ErrorStatusInvalidPluginID:               "invalid plugin id",
+		ErrorStatusInvalidPluginCmd:              "invalid plugin command",
+		ErrorStatusNotSupported:                  "not supported by backend",
+		ErrorStatusInvalidChangeSequence:         "invalid change sequence",
+		ErrorStatusPermissionDenied:              "permission denied",
+		ErrorStatusInvalidAuditSequence:          "invalid audit log sequence",
+		ErrorStatusUploadLimit:                   "too many pending uploads",
+		ErrorStatusRecordNotFound:                "record not found",
 	}
 
 	// RecordStatus converts record status codes to human readable text.
@@ -111,6 +206,34 @@ var (
 		RecordStatusArchived:          "archived",
 	}
 
+	// DiffAction converts diff actions to human readable text.
+	DiffAction = map[DiffActionT]string{
+		DiffActionInvalid:  "invalid",
+		DiffActionAdded:    "added",
+		DiffActionRemoved:  "removed",
+		DiffActionModified: "modified",
+	}
+
+	// TimestampStatus converts timestamp status codes to human readable
+	// text.
+	TimestampStatus = map[TimestampStatusT]string{
+		TimestampStatusInvalid:     "invalid status",
+		TimestampStatusNotAnchored: "not anchored",
+		TimestampStatusUnconfirmed: "unconfirmed",
+		TimestampStatusConfirmed:   "confirmed",
+	}
+
+	// Change converts change types to human readable text.
+	Change = map[ChangeT]string{
+		ChangeInvalid:        "invalid change",
+		ChangeNewRecord:      "new record",
+		ChangeUpdateRecord:   "update record",
+		ChangeSetStatus:      "set status",
+		ChangeUpdateMetadata: "update metadata",
+		ChangePluginCommand:  "plugin command",
+		ChangePurgeRecord:    "purge record",
+	}
+
 	// Input validation
 	RegexpSHA256 = regexp.MustCompile("[A-Fa-f0-9]{64}")
 
@@ -119,6 +242,8 @@ var (
 	ErrInvalidBase64 = errors.New("corrupt base64")
 	ErrInvalidMerkle = errors.New("merkle roots do not match")
 	ErrCorrupt       = errors.New("signature verification failed")
+	ErrInvalidHash   = errors.New("hash does not match contents")
+	ErrBrokenChain   = errors.New("audit log chain is broken")
 )
 
 // Verify ensures that a CensorshipRecord properly describes the array of
@@ -167,6 +292,190 @@ func Verify(pid identity.PublicIdentity, csr CensorshipRecord, files []File) err
 	return nil
 }
 
+// TombstoneMessage returns the message that is signed by the server to
+// vouch for a tombstone.  It is the concatenation of the merkle root, the
+// token, the hex encoded SHA256 digest of the reason and the timestamp.
+func TombstoneMessage(t Tombstone) []byte {
+	d := sha256.Sum256([]byte(t.Reason))
+	return []byte(t.CensorshipRecord.Merkle + t.CensorshipRecord.Token +
+		hex.EncodeToString(d[:]) + strconv.FormatInt(t.Timestamp, 10))
+}
+
+// RequestMessage returns the message that is signed by a client to
+// authenticate a request to a privileged route.  It is the concatenation of
+// the method, the route, the timestamp and the hex encoded SHA256 digest of
+// the request body, separated by newlines.
+func RequestMessage(method, route string, timestamp int64, body []byte) []byte {
+	d := sha256.Sum256(body)
+	return []byte(method + "\n" + route + "\n" +
+		strconv.FormatInt(timestamp, 10) + "\n" + hex.EncodeToString(d[:]))
+}
+
+// AuditEntryMessage returns the message that is hashed to chain an audit log
+// entry to its predecessor.  It is the concatenation of the sequence number,
+// the timestamp, the client, the route, the token, the status, the request
+// digest and the hash of the previous entry, separated by newlines.
+func AuditEntryMessage(e AuditEntry) []byte {
+	return []byte(strconv.FormatUint(e.Sequence, 10) + "\n" +
+		strconv.FormatInt(e.Timestamp, 10) + "\n" + e.Client + "\n" +
+		e.Route + "\n" + e.Token + "\n" +
+		strconv.Itoa(int(e.Status)) + "\n" + e.Digest + "\n" +
+		e.Previous)
+}
+
+// AuditEntryHash returns the hex encoded SHA256 digest of the audit entry
+// message.
+func AuditEntryHash(e AuditEntry) string {
+	d := sha256.Sum256(AuditEntryMessage(e))
+	return hex.EncodeToString(d[:])
+}
+
+// VerifyAuditEntry ensures that the hash of an audit log entry matches its
+// contents and that the hash was signed by the server.
+func VerifyAuditEntry(pid identity.PublicIdentity, e AuditEntry) error {
+	if AuditEntryHash(e) != e.Hash {
+		return ErrInvalidHash
+	}
+	s, err := hex.DecodeString(e.Signature)
+	if err != nil || len(s) != identity.SignatureSize {
+		return ErrInvalidHex
+	}
+	var signature [identity.SignatureSize]byte
+	copy(signature[:], s)
+	if !pid.VerifyMessage([]byte(e.Hash), signature) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// VerifyAuditLog ensures that consecutive audit log entries were signed by
+// the server and form an unbroken chain.  Previous is the hash of the entry
+// that precedes the first entry, it is empty when the entries start at the
+// beginning of the log.
+func VerifyAuditLog(pid identity.PublicIdentity, previous string, entries []AuditEntry) error {
+	for k, v := range entries {
+		err := VerifyAuditEntry(pid, v)
+		if err != nil {
+			return fmt.Errorf("audit entry %v: %v", v.Sequence, err)
+		}
+		if v.Previous != previous ||
+			(k > 0 && v.Sequence != entries[k-1].Sequence+1) {
+			return fmt.Errorf("audit entry %v: %v", v.Sequence,
+				ErrBrokenChain)
+		}
+		previous = v.Hash
+	}
+	return nil
+}
+
+// VerifyTombstone ensures that a Tombstone properly describes the files of a
+// purged record and that it was signed by the server.  The censorship record
+// is verified against the digests of the purged files since the payloads no
+// longer exist.
+func VerifyTombstone(pid identity.PublicIdentity, t Tombstone) error {
+	digests := make([]*[sha256.Size]byte, 0, len(t.Files))
+	for _, file := range t.Files {
+		d, err := hex.DecodeString(file.Digest)
+		if err != nil || len(d) != sha256.Size {
+			return ErrInvalidHex
+		}
+		var digest [sha256.Size]byte
+		copy(digest[:], d)
+
+		digests = append(digests, &digest)
+	}
+
+	// Verify merkle root
+	root := merkle.Root(digests)
+	if hex.EncodeToString(root[:]) != t.CensorshipRecord.Merkle {
+		return ErrInvalidMerkle
+	}
+
+	// Verify censorship record signature
+	s, err := hex.DecodeString(t.CensorshipRecord.Signature)
+	if err != nil {
+		return ErrInvalidHex
+	}
+	var signature [identity.SignatureSize]byte
+	copy(signature[:], s)
+	r := hex.EncodeToString(root[:])
+	if !pid.VerifyMessage([]byte(r+t.CensorshipRecord.Token), signature) {
+		return ErrCorrupt
+	}
+
+	// Verify tombstone signature
+	s, err = hex.DecodeString(t.Signature)
+	if err != nil {
+		return ErrInvalidHex
+	}
+	copy(signature[:], s)
+	if !pid.VerifyMessage(TombstoneMessage(t), signature) {
+		return ErrCorrupt
+	}
+
+	return nil
+}
+
+// verifyBranch ensures that the provided digest is part of a merkle branch
+// that leads to the provided merkle root.
+func verifyBranch(b *merkle.Branch, digest, root string) error {
+	d, err := hex.DecodeString(digest)
+	if err != nil || len(d) != sha256.Size {
+		return ErrInvalidHex
+	}
+	var found bool
+	for _, v := range b.Hashes {
+		if bytes.Equal(v[:], d) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrInvalidMerkle
+	}
+	mr, err := merkle.VerifyAuthPath(b)
+	if err != nil {
+		return ErrInvalidMerkle
+	}
+	if hex.EncodeToString(mr[:]) != root {
+		return ErrInvalidMerkle
+	}
+	return nil
+}
+
+// VerifyRecordTimestamp ensures that a RecordTimestamp proves that its digest
+// was anchored.  Timestamps that have not been confirmed yet are only
+// verified up to the anchor merkle root.
+func VerifyRecordTimestamp(rt RecordTimestamp) error {
+	switch rt.Status {
+	case TimestampStatusNotAnchored:
+		return nil
+	case TimestampStatusUnconfirmed, TimestampStatusConfirmed:
+	default:
+		return ErrCorrupt
+	}
+
+	// Verify the path from the commit digest to the anchor merkle root
+	if rt.MerklePath == nil {
+		return ErrInvalidMerkle
+	}
+	err := verifyBranch(rt.MerklePath, rt.Digest, rt.AnchorMerkle)
+	if err != nil {
+		return err
+	}
+	if rt.Status == TimestampStatusUnconfirmed {
+		return nil
+	}
+
+	// Verify the path from the anchor merkle root to the dcrtime merkle
+	// root that was included in the transaction
+	ci := rt.ChainInformation
+	if ci == nil {
+		return ErrInvalidMerkle
+	}
+	return verifyBranch(&ci.MerklePath, rt.AnchorMerkle, ci.MerkleRoot)
+}
+
 // CensorshipRecord contains the proof that a record was accepted for review.
 // The proof is verifiable on the client side.
 //
@@ -194,11 +503,17 @@ type IdentityReply struct {
 // File describes an individual file that is part of the record.  The
 // directory structure must be flattened.  The server side SHALL verify MIME
 // and Digest.
+//
+// Large files may be sent through a chunked upload instead.  In that case
+// Upload is set to the identifier of a completed upload and Payload is left
+// empty.  Name, MIME and Digest default to the values that were provided
+// when the upload was initiated.
 type File struct {
-	Name    string `json:"name"`    // Suggested filename
-	MIME    string `json:"mime"`    // Mime type
-	Digest  string `json:"digest"`  // Payload digest
-	Payload string `json:"payload"` // File content
+	Name    string `json:"name"`             // Suggested filename
+	MIME    string `json:"mime"`             // Mime type
+	Digest  string `json:"digest"`           // Payload digest
+	Payload string `json:"payload"`          // File content
+	Upload  string `json:"upload,omitempty"` // Upload identifier
 }
 
 // MetadataStream identifies a metadata stream by its identity.
@@ -263,6 +578,49 @@ type GetVettedReply struct {
 	Record   Record `json:"record"`
 }
 
+// FileDiff describes how a single file changed between two versions of a
+// record.  Text files carry a unified diff, binary files are only described
+// by their digests.
+type FileDiff struct {
+	Name      string      `json:"name"`                // Filename
+	Action    DiffActionT `json:"action"`              // How the file changed
+	MIME      string      `json:"mime"`                // Mime type
+	OldDigest string      `json:"olddigest,omitempty"` // Old payload digest
+	NewDigest string      `json:"newdigest,omitempty"` // New payload digest
+	Diff      string      `json:"diff,omitempty"`      // Unified diff
+}
+
+// MetadataStreamDiff describes how a single metadata stream changed between
+// two versions of a record.
+type MetadataStreamDiff struct {
+	ID     uint64      `json:"id"`     // Stream identity
+	Action DiffActionT `json:"action"` // How the stream changed
+	Diff   string      `json:"diff"`   // Unified diff
+}
+
+// GetVettedDiff requests the changes between two versions of a vetted record.
+// NewVersion defaults to the latest version and OldVersion defaults to the
+// version that precedes NewVersion.
+type GetVettedDiff struct {
+	Challenge  string `json:"challenge"`  // Random challenge
+	Token      string `json:"token"`      // Censorship token
+	OldVersion string `json:"oldversion"` // Old record version
+	NewVersion string `json:"newversion"` // New record version
+}
+
+// GetVettedDiffReply returns the files and metadata streams that changed
+// between two versions of a vetted record.  Unchanged files and streams are
+// omitted.  Status is set to RecordStatusNotFound if the record does not
+// exist.
+type GetVettedDiffReply struct {
+	Response   string               `json:"response"`   // Challenge response
+	Status     RecordStatusT        `json:"status"`     // Record status
+	OldVersion string               `json:"oldversion"` // Old record version
+	NewVersion string               `json:"newversion"` // New record version
+	Files      []FileDiff           `json:"files"`      // Changed files
+	Metadata   []MetadataStreamDiff `json:"metadata"`   // Changed streams
+}
+
 // SetUnvettedStatus updates the status of an unvetted record.  This is used
 // to either promote a record to the public viewable repository or to censor
 // it. Additionally, metadata updates may travel along.
@@ -366,6 +724,286 @@ type InventoryReply struct {
 	Branches []Record `json:"branches"` // Last N branches (censored, new etc)
 }
 
+// InventoryFilter narrows down the records that are returned by InventoryPage.
+// Empty fields match all records.
+type InventoryFilter struct {
+	Statuses      []RecordStatusT `json:"statuses,omitempty"`      // Any of these statuses
+	FromTimestamp int64           `json:"fromtimestamp,omitempty"` // Updated at or after
+	ToTimestamp   int64           `json:"totimestamp,omitempty"`   // Updated at or before
+	MDStreams     []uint64        `json:"mdstreams,omitempty"`     // All these metadata streams
+}
+
+// InventoryPage requests a single page of records.  Records are ordered by
+// token and are returned regardless of being vetted or not, use the status
+// filter to narrow them down.  The first page is requested with an empty
+// cursor and subsequent pages with the cursor of the previous reply.  Limit
+// defaults to, and is capped at, InventoryPageSize.
+//
+// When AllVersions is set the older versions of a vetted record directly
+// follow its latest version.  They are not counted against the limit.
+type InventoryPage struct {
+	Challenge    string          `json:"challenge"`        // Random challenge
+	Cursor       string          `json:"cursor,omitempty"` // Cursor of the previous reply
+	Limit        uint            `json:"limit,omitempty"`  // Maximum number of records
+	IncludeFiles bool            `json:"includefiles"`     // Include files in records
+	AllVersions  bool            `json:"allversions"`      // Include all versions of vetted records
+	Filter       InventoryFilter `json:"filter"`           // Filter records
+}
+
+// InventoryPageReply returns a single page of records and the cursor of the
+// next page.  The cursor is empty when there are no more records.
+type InventoryPageReply struct {
+	Response string   `json:"response"` // Challenge response
+	Records  []Record `json:"records"`  // Records of this page
+	Cursor   string   `json:"cursor"`   // Cursor of the next page
+}
+
+// UploadInit starts a chunked upload of a single file.  The file is sent in
+// parts of UploadPartSize bytes, the last part may be smaller.  Once all parts
+// have been uploaded the upload identifier can be used in place of a file
+// payload in NewRecord and UpdateRecord.
+type UploadInit struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Name      string `json:"name"`      // Suggested filename
+	MIME      string `json:"mime"`      // Mime type
+	Digest    string `json:"digest"`    // SHA256 digest of the entire file
+	Size      int64  `json:"size"`      // Size of the entire file
+}
+
+// UploadInitReply returns the upload identifier and the number of parts that
+// are expected.  Uploads that are not committed before Expires are
+// discarded.
+type UploadInitReply struct {
+	Response string `json:"response"` // Challenge response
+	UploadID string `json:"uploadid"` // Upload identifier
+	PartSize int64  `json:"partsize"` // Size of every part but the last
+	Parts    uint   `json:"parts"`    // Number of parts
+	Expires  int64  `json:"expires"`  // Unix time the upload expires
+}
+
+// UploadPart uploads a single part of a file.  Parts may be sent in any
+// order.
+type UploadPart struct {
+	Challenge string `json:"challenge"` // Random challenge
+	UploadID  string `json:"uploadid"`  // Upload identifier
+	Index     uint   `json:"index"`     // Part index, starts at 0
+	Digest    string `json:"digest"`    // SHA256 digest of the part
+	Payload   string `json:"payload"`   // Part content, base64 encoded
+}
+
+// UploadPartReply is returned once the part has been stored.
+type UploadPartReply struct {
+	Response string `json:"response"` // Challenge response
+}
+
+// Tombstone is left behind when the files of a censored record are purged.
+// The censorship record and the digests of the purged files prove that the
+// record existed.  The signature proves that the server purged it.  Files
+// are in their original order and do not carry a payload.
+type Tombstone struct {
+	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
+	Reason           string           `json:"reason"`    // Reason for purging
+	Timestamp        int64            `json:"timestamp"` // Time of purge
+	Files            []File           `json:"files"`     // Purged files
+	Signature        string           `json:"signature"` // Signature of TombstoneMessage
+}
+
+// PurgeRecord deletes the files of a censored record and replaces them with a
+// tombstone.  A reason must be provided.
+type PurgeRecord struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+	Reason    string `json:"reason"`    // Reason for purging
+}
+
+// PurgeRecordReply returns the tombstone of the purged record.
+type PurgeRecordReply struct {
+	Response  string    `json:"response"` // Challenge response
+	Tombstone Tombstone `json:"tombstone"`
+}
+
+// VerifyCache requests a comparison of the cache against the backend, which
+// is the source of truth.  Record versions, statuses, metadata streams, files
+// and the plugin data of every record are compared.  If Repair is set the
+// divergent records are rebuilt from the backend.
+type VerifyCache struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Repair    bool   `json:"repair"`    // Repair divergent records
+}
+
+// CacheDivergence describes how the cache of a record differs from the
+// backend.  Plugin is set if the divergence was found in the plugin data.
+type CacheDivergence struct {
+	Token   string   `json:"token"`            // Censorship token
+	Plugin  string   `json:"plugin,omitempty"` // Plugin ID
+	Reasons []string `json:"reasons"`          // Description of differences
+}
+
+// VerifyCacheReply returns the divergent records.  Repaired is set if the
+// divergent records were repaired.
+type VerifyCacheReply struct {
+	Response    string            `json:"response"`    // Challenge response
+	Records     int               `json:"records"`     // Number of records verified
+	Divergences []CacheDivergence `json:"divergences"` // Divergent records
+	Repaired    bool              `json:"repaired"`    // Divergences were repaired
+}
+
+// ChangeFeed requests the record changes that followed the provided sequence
+// number.  Use a sequence number of zero to start at the beginning of the
+// feed.  If there are no such changes the request is held open for up to
+// Timeout seconds, or until a change is made, before an empty reply is
+// returned.
+type ChangeFeed struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Since     uint64 `json:"since"`     // Last sequence number seen
+	Timeout   uint32 `json:"timeout"`   // Seconds to wait for changes
+}
+
+// ChangeEvent describes a single change.  Token, Version and Status are set
+// for record changes.  Plugin and Command are set for plugin commands, Token
+// is set as well when the command payload refers to a record.
+type ChangeEvent struct {
+	Sequence  uint64        `json:"sequence"`          // Sequence number
+	Type      ChangeT       `json:"type"`              // Type of change
+	Timestamp int64         `json:"timestamp"`         // Time of change
+	Token     string        `json:"token,omitempty"`   // Censorship token
+	Version   string        `json:"version,omitempty"` // Record version
+	Status    RecordStatusT `json:"status,omitempty"`  // Record status
+	Plugin    string        `json:"plugin,omitempty"`  // Plugin ID
+	Command   string        `json:"command,omitempty"` // Plugin command
+}
+
+// ChangeFeedReply returns up to ChangeFeedPageSize changes in order.  Last is
+// the sequence number of the most recent change in the feed.
+type ChangeFeedReply struct {
+	Response string        `json:"response"` // Challenge response
+	Events   []ChangeEvent `json:"events"`   // Changes after Since
+	Last     uint64        `json:"last"`     // Most recent sequence number
+}
+
+// AuditLog requests the audit log entries that followed the provided sequence
+// number.  Use a sequence number of zero to start at the beginning of the
+// log.
+type AuditLog struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Since     uint64 `json:"since"`     // Last sequence number seen
+}
+
+// AuditEntry records a call to a privileged route that changed the status,
+// metadata or README of politeiad.  Entries are chained by including the
+// hash of the previous entry, which is empty for the first entry, and the
+// hash of every entry is signed by the server.
+type AuditEntry struct {
+	Sequence  uint64        `json:"sequence"`         // Sequence number
+	Timestamp int64         `json:"timestamp"`        // Time of call
+	Client    string        `json:"client"`           // Calling client
+	Route     string        `json:"route"`            // Privileged route
+	Token     string        `json:"token,omitempty"`  // Censorship token
+	Status    RecordStatusT `json:"status,omitempty"` // New record status
+	Digest    string        `json:"digest"`           // SHA256 of request
+	Previous  string        `json:"previous"`         // Previous entry hash
+	Hash      string        `json:"hash"`             // SHA256 of entry
+	Signature string        `json:"signature"`        // Signature of Hash
+}
+
+// AuditLogReply returns up to AuditLogPageSize audit log entries in order.
+// Previous is the hash of the entry that precedes the returned entries so
+// that a page can be verified on its own.  Last is the sequence number of
+// the most recent entry in the log.
+type AuditLogReply struct {
+	Response string       `json:"response"` // Challenge response
+	Previous string       `json:"previous"` // Hash of entry Since
+	Entries  []AuditEntry `json:"entries"`  // Entries after Since
+	Last     uint64       `json:"last"`     // Most recent sequence number
+}
+
+// GetTombstone requests the tombstone of a purged record.
+type GetTombstone struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+}
+
+// GetTombstoneReply returns the tombstone of a purged record.  Status is set
+// to RecordStatusNotFound if the record has not been purged.
+type GetTombstoneReply struct {
+	Response  string        `json:"response"` // Challenge response
+	Status    RecordStatusT `json:"status"`   // Record status
+	Tombstone Tombstone     `json:"tombstone"`
+}
+
+// ChainInformation describes the dcrtime anchor of a digest.  MerklePath
+// leads from the digest to MerkleRoot, which is stored in Transaction.
+type ChainInformation struct {
+	ChainTimestamp int64         `json:"chaintimestamp"` // Time of the block
+	Transaction    string        `json:"transaction"`    // Anchor transaction
+	MerkleRoot     string        `json:"merkleroot"`     // Dcrtime merkle root
+	MerklePath     merkle.Branch `json:"merklepath"`     // Path to MerkleRoot
+}
+
+// RecordTimestamp proves when a record version was anchored.  Digest is the
+// commit that created the version and MerklePath leads from Digest to
+// AnchorMerkle.  AnchorMerkle is the digest that politeiad timestamped in
+// dcrtime and ChainInformation proves that it made it into the blockchain.
+// ChainInformation is only set once the anchor has been confirmed.
+type RecordTimestamp struct {
+	Version          string            `json:"version"`                    // Record version
+	Digest           string            `json:"digest"`                     // Anchored commit digest
+	Status           TimestampStatusT  `json:"status"`                     // Anchor status
+	AnchorMerkle     string            `json:"anchormerkle,omitempty"`     // Anchor merkle root
+	AnchorTime       int64             `json:"anchortime,omitempty"`       // Time anchor was dropped
+	MerklePath       *merkle.Branch    `json:"merklepath,omitempty"`       // Path to AnchorMerkle
+	ChainInformation *ChainInformation `json:"chaininformation,omitempty"` // Dcrtime anchor
+}
+
+// GetTimestamps requests the anchor proofs of all versions of a vetted
+// record.
+type GetTimestamps struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+}
+
+// GetTimestampsReply returns the anchor proofs of all versions of a vetted
+// record, ordered by version.  Status is set to RecordStatusNotFound if the
+// record does not exist.
+type GetTimestampsReply struct {
+	Response   string            `json:"response"`   // Challenge response
+	Status     RecordStatusT     `json:"status"`     // Record status
+	Timestamps []RecordTimestamp `json:"timestamps"` // Anchor proofs
+}
+
+// PluginExport contains the data that a plugin keeps about a record.  The
+// payload is plugin specific; for the decred plugin it is a JSON encoded
+// decredplugin.ExportReply.
+type PluginExport struct {
+	ID      string `json:"id"`      // Plugin identifier
+	Payload string `json:"payload"` // Plugin data
+}
+
+// Bundle is a self-contained export of a vetted record that can be verified
+// offline.  Records contains every version of the record, oldest first.  All
+// signatures and receipts in the bundle can be verified against
+// ServerPublicKey.
+type Bundle struct {
+	ServerPublicKey string            `json:"serverpublickey"` // Server public key
+	Records         []Record          `json:"records"`         // All record versions
+	Timestamps      []RecordTimestamp `json:"timestamps"`      // Anchor proofs
+	Plugins         []PluginExport    `json:"plugins"`         // Plugin data
+}
+
+// GetBundle requests a bundle of a vetted record.
+type GetBundle struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+}
+
+// GetBundleReply returns the bundle of a vetted record.  Status is set to
+// RecordStatusNotFound if the record does not exist.
+type GetBundleReply struct {
+	Response string        `json:"response"` // Challenge response
+	Status   RecordStatusT `json:"status"`   // Record status
+	Bundle   Bundle        `json:"bundle"`   // Record bundle
+}
+
 // UserErrorReply returns details about an error that occurred while trying to
 // execute a command due to bad input from the client.
 type UserErrorReply struct {
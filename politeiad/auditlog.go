@@ -0,0 +1,113 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/auditlog"
+	"github.com/thi4go/politeia/util"
+)
+
+const (
+	// defaultAuditLogFilename is the file, relative to the politeiad data
+	// directory, that the audit log is stored in.
+	defaultAuditLogFilename = "audit.journal"
+)
+
+// audit appends a privileged call to the audit log.  The request payload is
+// recorded as the SHA256 digest of its JSON encoding.  It must be called
+// before the backend is asked to make the change and the call must be refused
+// if an error is returned, so that no change is ever made without an entry in
+// the log.
+func (p *politeia) audit(r *http.Request, token string, status v1.RecordStatusT, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode: %v", err)
+	}
+	d := sha256.Sum256(b)
+	e, err := p.audits.Append(v1.AuditEntry{
+		Client: requestClient(r),
+		Route:  r.URL.Path,
+		Token:  token,
+		Status: status,
+		Digest: hex.EncodeToString(d[:]),
+	})
+	if err != nil {
+		return fmt.Errorf("append: %v", err)
+	}
+
+	log.Debugf("Audit log %v: %v %v", e.Sequence, e.Client, e.Route)
+
+	return nil
+}
+
+// auditOrFail records a privileged call in the audit log before the change is
+// made.  The call is answered with a server error and false is returned if it
+// could not be recorded, in which case the handler must return without making
+// the change.
+func (p *politeia) auditOrFail(w http.ResponseWriter, r *http.Request, token string, status v1.RecordStatusT, payload interface{}) bool {
+	err := p.audit(r, token, status, payload)
+	if err != nil {
+		errorCode := time.Now().Unix()
+		log.Criticalf("%v Audit log error code %v %v %v: %v",
+			remoteAddr(r), errorCode, r.URL.Path, token, err)
+		p.respondWithServerError(w, errorCode)
+		return false
+	}
+	return true
+}
+
+func (p *politeia) auditLog(w http.ResponseWriter, r *http.Request) {
+	var t v1.AuditLog
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	previous, entries, err := p.audits.Entries(t.Since,
+		int(v1.AuditLogPageSize))
+	if err != nil {
+		if err == auditlog.ErrInvalidSequence {
+			p.respondWithUserError(w,
+				v1.ErrorStatusInvalidAuditSequence, nil)
+			return
+		}
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Audit log error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	// Prepare reply
+	reply := v1.AuditLogReply{
+		Response: hex.EncodeToString(response[:]),
+		Previous: previous,
+		Entries:  entries,
+		Last:     p.audits.Last(),
+	}
+
+	log.Debugf("Audit log %v: since %v, %v entries", remoteAddr(r),
+		t.Since, len(reply.Entries))
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
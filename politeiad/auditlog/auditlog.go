@@ -0,0 +1,239 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package auditlog provides an append only log of the privileged calls that
+// politeiad serves.  Every entry includes the hash of the entry before it and
+// the hash of every entry is signed with the politeiad identity, which makes
+// it possible to prove that entries were neither altered nor removed.  The
+// entries are stored in a file, one JSON encoded entry per line.
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+)
+
+var (
+	// ErrInvalidSequence is returned when entries are requested after a
+	// sequence number that the log has not reached yet.
+	ErrInvalidSequence = errors.New("invalid sequence")
+
+	// ErrClosed is returned when the log is used after it was closed.
+	ErrClosed = errors.New("audit log closed")
+)
+
+// Log is an append only, hash chained and signed log of audit entries.  It
+// is safe for concurrent use.
+type Log struct {
+	sync.RWMutex
+	file     *os.File
+	identity *identity.FullIdentity
+	offsets  []int64 // [sequence-1]offset of the entry in file
+	size     int64   // Size of file
+	last     string  // Hash of the most recent entry
+	closed   bool
+}
+
+// New opens the log that is stored in filename, creating it if it does not
+// exist.  The chain and the signatures of the existing entries are verified
+// against the provided identity.  An entry that was only partially written
+// when politeiad last shut down is discarded.
+func New(filename string, id *identity.FullIdentity) (*Log, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index and verify the existing entries
+	l := &Log{
+		file:     file,
+		identity: id,
+		offsets:  make([]int64, 0, 1024),
+	}
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, err
+		}
+		var e v1.AuditEntry
+		err = json.Unmarshal(line, &e)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("entry at offset %v: %v", l.size,
+				err)
+		}
+		if e.Sequence != uint64(len(l.offsets))+1 {
+			file.Close()
+			return nil, fmt.Errorf("entry at offset %v: got "+
+				"sequence %v, want %v", l.size, e.Sequence,
+				len(l.offsets)+1)
+		}
+		err = v1.VerifyAuditLog(id.Public, l.last, []v1.AuditEntry{e})
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		l.offsets = append(l.offsets, l.size)
+		l.size += int64(len(line))
+		l.last = e.Hash
+	}
+
+	// Drop a trailing partial entry
+	err = file.Truncate(l.size)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	_, err = file.Seek(l.size, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Last returns the sequence number of the most recent entry.  Zero is
+// returned if the log is empty.
+func (l *Log) Last() uint64 {
+	l.RLock()
+	defer l.RUnlock()
+
+	return uint64(len(l.offsets))
+}
+
+// Append assigns the next sequence number to the provided entry, chains it
+// to the most recent entry, signs it and stores it.  The stored entry is
+// returned.
+func (l *Log) Append(e v1.AuditEntry) (*v1.AuditEntry, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.closed {
+		return nil, ErrClosed
+	}
+
+	e.Sequence = uint64(len(l.offsets)) + 1
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().Unix()
+	}
+	e.Previous = l.last
+	e.Hash = v1.AuditEntryHash(e)
+	signature := l.identity.SignMessage([]byte(e.Hash))
+	e.Signature = hex.EncodeToString(signature[:])
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, '\n')
+	_, err = l.file.Write(b)
+	if err != nil {
+		// Do not leave a partial entry behind
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, io.SeekStart)
+		return nil, err
+	}
+	err = l.file.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	l.offsets = append(l.offsets, l.size)
+	l.size += int64(len(b))
+	l.last = e.Hash
+
+	return &e, nil
+}
+
+// Entries returns up to limit entries that follow the provided sequence
+// number along with the hash of the entry at that sequence number, which is
+// empty for sequence number zero.  An empty slice is returned if there are
+// no such entries.
+func (l *Log) Entries(since uint64, limit int) (string, []v1.AuditEntry, error) {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.closed {
+		return "", nil, ErrClosed
+	}
+	last := uint64(len(l.offsets))
+	if since > last {
+		return "", nil, ErrInvalidSequence
+	}
+
+	// Include the entry at since in order to return its hash
+	start := since
+	if start > 0 {
+		start--
+	}
+	end := last
+	if limit < 0 {
+		limit = 0
+	}
+	if end-since > uint64(limit) {
+		end = since + uint64(limit)
+	}
+	if start == end {
+		return "", []v1.AuditEntry{}, nil
+	}
+	stop := l.size
+	if end < last {
+		stop = l.offsets[end]
+	}
+	b := make([]byte, stop-l.offsets[start])
+	_, err := l.file.ReadAt(b, l.offsets[start])
+	if err != nil {
+		return "", nil, err
+	}
+
+	var previous string
+	entries := make([]v1.AuditEntry, 0, end-since)
+	for k, line := range bytes.SplitAfter(b, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var e v1.AuditEntry
+		err := json.Unmarshal(line, &e)
+		if err != nil {
+			return "", nil, fmt.Errorf("entry %v: %v",
+				start+uint64(k)+1, err)
+		}
+		if e.Sequence == since {
+			previous = e.Hash
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	return previous, entries, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+
+	return l.file.Close()
+}
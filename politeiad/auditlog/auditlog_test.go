@@ -0,0 +1,165 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package auditlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+)
+
+func newTestLog(t *testing.T) (*Log, *identity.FullIdentity, string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "auditlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "audit.journal")
+	l, err := New(filename, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l, id, filename, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAppendEntries(t *testing.T) {
+	l, id, filename, cleanup := newTestLog(t)
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		e, err := l.Append(v1.AuditEntry{
+			Client: "admin",
+			Route:  v1.SetUnvettedStatusRoute,
+			Token:  "token",
+			Status: v1.RecordStatusCensored,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Sequence != uint64(i)+1 {
+			t.Fatalf("sequence: got %v, want %v", e.Sequence, i+1)
+		}
+	}
+
+	// The whole log verifies
+	previous, entries, err := l.Entries(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "" || len(entries) != 5 {
+		t.Fatalf("unexpected entries: %v %v", previous, entries)
+	}
+	err = v1.VerifyAuditLog(id.Public, "", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A page verifies against the hash of the entry before it
+	previous, page, err := l.Entries(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != entries[1].Hash || len(page) != 2 ||
+		page[0].Sequence != 3 {
+		t.Fatalf("unexpected page: %v %v", previous, page)
+	}
+	err = v1.VerifyAuditLog(id.Public, previous, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = l.Entries(6, 10)
+	if err != ErrInvalidSequence {
+		t.Fatalf("got %v, want %v", err, ErrInvalidSequence)
+	}
+
+	// Removing an entry breaks the chain
+	err = v1.VerifyAuditLog(id.Public, "",
+		append(entries[:2:2], entries[3:]...))
+	if err == nil {
+		t.Fatal("expected broken chain")
+	}
+
+	// Altering an entry invalidates its hash
+	altered := append([]v1.AuditEntry{}, entries...)
+	altered[2].Status = v1.RecordStatusPublic
+	err = v1.VerifyAuditLog(id.Public, "", altered)
+	if err == nil {
+		t.Fatal("expected invalid hash")
+	}
+
+	// Entries that were signed by another identity are rejected
+	other, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v1.VerifyAuditLog(other.Public, "", entries)
+	if err == nil {
+		t.Fatal("expected invalid signature")
+	}
+
+	// A tampered log file is rejected when it is opened
+	l.Close()
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(b, []byte(`"status":3`),
+		[]byte(`"status":4`), 1)
+	err = ioutil.WriteFile(filename, tampered, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = New(filename, id)
+	if err == nil {
+		t.Fatal("expected tampered log to be rejected")
+	}
+
+	// A partially written entry is discarded and the chain resumes
+	err = ioutil.WriteFile(filename, append(b, `{"sequence":6,"ti`...),
+		0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err = New(filename, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if l.Last() != 5 {
+		t.Fatalf("last: got %v, want 5", l.Last())
+	}
+	e, err := l.Append(v1.AuditEntry{
+		Client: "admin",
+		Route:  v1.UpdateReadmeRoute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Sequence != 6 || e.Previous != entries[4].Hash {
+		t.Fatalf("unexpected entry: %v", e)
+	}
+	_, entries, err = l.Entries(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v1.VerifyAuditLog(id.Public, "", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
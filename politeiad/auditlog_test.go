@@ -0,0 +1,70 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/backend"
+)
+
+func TestAuditFailClosed(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	audited := newTestRecord(t, p, false, 1)
+	refused := newTestRecord(t, p, false, 1)
+
+	// setStatus makes the record public and returns the reply status
+	setStatus := func(token string) int {
+		r := newTestRequest(t, v1.SetUnvettedStatusRoute,
+			v1.SetUnvettedStatus{
+				Challenge: newTestChallenge(t),
+				Token:     token,
+				Status:    v1.RecordStatusPublic,
+			})
+		w := httptest.NewRecorder()
+		p.setUnvettedStatus(w, r)
+		return w.Code
+	}
+
+	// The change is recorded in the audit log
+	if code := setStatus(audited); code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", code, http.StatusOK)
+	}
+	_, entries, err := p.audits.Entries(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Token != audited ||
+		entries[0].Status != v1.RecordStatusPublic {
+		t.Fatalf("got entries %v, want a status change of %v", entries,
+			audited)
+	}
+
+	// No change is made when the call can not be recorded
+	p.audits.Close()
+	if code := setStatus(refused); code != http.StatusInternalServerError {
+		t.Fatalf("got status %v, want %v", code,
+			http.StatusInternalServerError)
+	}
+	token, err := hex.DecodeString(refused)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.backend.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Status != backend.MDStatusUnvetted {
+		t.Errorf("got status %v, want %v",
+			backend.MDStatus[r.RecordMetadata.Status],
+			backend.MDStatus[backend.MDStatusUnvetted])
+	}
+}
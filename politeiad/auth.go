@@ -0,0 +1,291 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/util"
+)
+
+type permission uint
+
+const (
+	permissionPublic    permission = iota
+	permissionInventory            // Read inventory and change feed
+	permissionStatus               // Set record status
+	permissionMetadata             // Update vetted metadata
+	permissionPlugin               // Execute plugin commands
+	permissionAdmin                // Purge records, verify cache, README
+)
+
+const (
+	// permissionAll grants all permissions to a client.
+	permissionAll = "all"
+
+	// clientKey is the request context key of the authenticated client.
+	clientKey contextKey = "client"
+)
+
+// contextKey is the type of the request context keys that are set by
+// politeiad.
+type contextKey string
+
+var (
+	// permissions maps the permission names that are used in the client
+	// configuration to permissions.
+	permissions = map[string]permission{
+		"inventory": permissionInventory,
+		"status":    permissionStatus,
+		"metadata":  permissionMetadata,
+		"plugin":    permissionPlugin,
+		"admin":     permissionAdmin,
+	}
+
+	errUnauthenticated = errors.New("unauthenticated")
+	errRequestTooLarge = errors.New("request body too large")
+)
+
+// client is a client that is allowed to call privileged routes.
+type client struct {
+	name        string
+	identity    *identity.PublicIdentity // Nil for the rpcuser client
+	permissions map[permission]bool
+}
+
+// parseClient parses a client in the format that is used by the client
+// config option: name:publickey:permission[,permission...]
+func parseClient(s string) (*client, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("want name:publickey:permissions")
+	}
+	if parts[0] == "" {
+		return nil, fmt.Errorf("empty name")
+	}
+	id, err := util.IdentityFromString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	c := client{
+		name:        parts[0],
+		identity:    id,
+		permissions: make(map[permission]bool),
+	}
+	for _, v := range strings.Split(parts[2], ",") {
+		if v == permissionAll {
+			for _, p := range permissions {
+				c.permissions[p] = true
+			}
+			continue
+		}
+		p, ok := permissions[v]
+		if !ok {
+			return nil, fmt.Errorf("invalid permission: %v", v)
+		}
+		c.permissions[p] = true
+	}
+	return &c, nil
+}
+
+// clients holds the clients that are allowed to call privileged routes.
+type clients struct {
+	sync.Mutex
+	rpc  client             // rpcuser and rpcpass client
+	keys map[string]*client // [publickey]client
+	seen map[string]int64   // [signature]timestamp of recent requests
+}
+
+// newClients returns the clients that are described by the client config
+// options.  The rpcuser client is granted all permissions.
+func newClients(cfg *config) (*clients, error) {
+	c := clients{
+		rpc: client{
+			name:        cfg.RPCUser,
+			permissions: make(map[permission]bool),
+		},
+		keys: make(map[string]*client),
+		seen: make(map[string]int64),
+	}
+	for _, v := range permissions {
+		c.rpc.permissions[v] = true
+	}
+	names := make(map[string]bool)
+	for _, v := range cfg.Clients {
+		cl, err := parseClient(v)
+		if err != nil {
+			return nil, fmt.Errorf("client %v: %v", v, err)
+		}
+		key := cl.identity.String()
+		if _, ok := c.keys[key]; ok {
+			return nil, fmt.Errorf("client %v: duplicate public key",
+				cl.name)
+		}
+		if names[cl.name] {
+			return nil, fmt.Errorf("client %v: duplicate name", cl.name)
+		}
+		names[cl.name] = true
+		c.keys[key] = cl
+	}
+	return &c, nil
+}
+
+// replayed records the signature of a request and returns whether it has
+// been seen before.  Signatures are forgotten once they have expired.
+func (c *clients) replayed(signature string, timestamp int64) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now().Unix()
+	for k, v := range c.seen {
+		if now-v > v1.ClientSignatureMaxAge {
+			delete(c.seen, k)
+		}
+	}
+	if _, ok := c.seen[signature]; ok {
+		return true
+	}
+	c.seen[signature] = timestamp
+	return false
+}
+
+// authenticate returns the client that sent the request.  Requests are
+// either signed by a configured client or carry the rpcuser and rpcpass
+// credentials.  The body of a signed request is read in order to verify the
+// signature and is replaced so that the handler can read it again.  Bodies
+// are limited to ClientRequestMaxSize and larger signed requests are rejected
+// with errRequestTooLarge before their signature is verified.
+func (c *clients) authenticate(w http.ResponseWriter, r *http.Request, rpcpass string) (*client, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, v1.ClientRequestMaxSize)
+
+	key := r.Header.Get(v1.ClientKeyHeader)
+	if key == "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(c.rpc.name)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(rpcpass)) != 1 {
+			return nil, errUnauthenticated
+		}
+		return &c.rpc, nil
+	}
+
+	cl, ok := c.keys[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown client %v", key)
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(v1.ClientTimestampHeader),
+		10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%v: invalid timestamp", cl.name)
+	}
+	age := time.Now().Unix() - timestamp
+	if age > v1.ClientSignatureMaxAge || age < -v1.ClientSignatureMaxAge {
+		return nil, fmt.Errorf("%v: signature expired", cl.name)
+	}
+	signature := r.Header.Get(v1.ClientSignatureHeader)
+	sig, err := identity.SignatureFromString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%v: invalid signature", cl.name)
+	}
+	if r.ContentLength > v1.ClientRequestMaxSize {
+		return nil, errRequestTooLarge
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		// The body is only cut short by the limit once all
+		// allowed bytes have been read.
+		if int64(len(body)) >= v1.ClientRequestMaxSize {
+			return nil, errRequestTooLarge
+		}
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	msg := v1.RequestMessage(r.Method, r.URL.Path, timestamp, body)
+	if !cl.identity.VerifyMessage(msg, *sig) {
+		return nil, fmt.Errorf("%v: invalid signature", cl.name)
+	}
+	if c.replayed(signature, timestamp) {
+		return nil, fmt.Errorf("%v: replayed request", cl.name)
+	}
+
+	return cl, nil
+}
+
+// names returns the names of the configured clients.
+func (c *clients) names() []string {
+	names := make([]string, 0, len(c.keys))
+	for _, v := range c.keys {
+		names = append(names, v.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requestClient returns the name of the client that sent the request.  An
+// empty string is returned for requests to public routes.
+func requestClient(r *http.Request) string {
+	name, _ := r.Context().Value(clientKey).(string)
+	return name
+}
+
+// auth returns a handler that only calls fn for clients that have been granted
+// the provided permission.  The name of the client is added to the request
+// context.  Oversized requests are answered with a 413, unauthenticated
+// requests with a 401 and requests of clients that lack the permission with a
+// 403.
+func (p *politeia) auth(fn http.HandlerFunc, perm permission) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := p.clients.authenticate(w, r, p.cfg.RPCPass)
+		if err == errRequestTooLarge {
+			log.Errorf("%v Request too large: %v", remoteAddr(r),
+				r.URL.Path)
+			util.RespondWithJSON(w, http.StatusRequestEntityTooLarge,
+				v1.UserErrorReply{
+					ErrorCode: v1.ErrorStatusInvalidRequestPayload,
+				})
+			return
+		}
+		if err != nil {
+			user, _, _ := r.BasicAuth()
+			log.Errorf("%v Unauthorized access for: %v: %v",
+				remoteAddr(r), user, err)
+			w.Header().Set("WWW-Authenticate",
+				`Basic realm="Politeiad"`)
+			util.RespondWithJSON(w, http.StatusUnauthorized,
+				v1.UserErrorReply{
+					ErrorCode: v1.ErrorStatusInvalidRPCCredentials,
+				})
+			return
+		}
+		if !c.permissions[perm] {
+			log.Errorf("%v Permission denied for client %v: %v",
+				remoteAddr(r), c.name, r.URL.Path)
+			util.RespondWithJSON(w, http.StatusForbidden,
+				v1.UserErrorReply{
+					ErrorCode: v1.ErrorStatusPermissionDenied,
+				})
+			return
+		}
+
+		// Attribute the call to the client
+		r = r.WithContext(context.WithValue(r.Context(), clientKey,
+			c.name))
+
+		fn(w, r)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+)
+
+const (
+	testRPCUser = "user"
+	testRPCPass = "pass"
+)
+
+// newTestIdentity returns a new client identity.
+func newTestIdentity(t *testing.T) *identity.FullIdentity {
+	t.Helper()
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// newTestAuthRequest returns a request for the given route that is signed by
+// the client identity at the provided time.
+func newTestAuthRequest(id *identity.FullIdentity, route string, body []byte, timestamp int64) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, route, bytes.NewReader(body))
+	sig := id.SignMessage(v1.RequestMessage(r.Method, r.URL.Path,
+		timestamp, body))
+	r.Header.Set(v1.ClientKeyHeader, id.Public.String())
+	r.Header.Set(v1.ClientTimestampHeader, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(v1.ClientSignatureHeader, hex.EncodeToString(sig[:]))
+	return r
+}
+
+func TestAuth(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	reader := newTestIdentity(t)
+	admin := newTestIdentity(t)
+	unknown := newTestIdentity(t)
+
+	p.cfg.RPCUser = testRPCUser
+	p.cfg.RPCPass = testRPCPass
+	p.cfg.Clients = []string{
+		"reader:" + reader.Public.String() + ":inventory",
+		"admin:" + admin.Public.String() + ":all",
+	}
+	var err error
+	p.clients, err = newClients(p.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The handlers reply with the name of the client and the body they
+	// were called with.
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+		b.ReadFrom(r.Body)
+		w.Write([]byte(requestClient(r) + ":" + b.String()))
+	}
+	p.addRoute(http.MethodPost, v1.InventoryRoute, handler,
+		permissionInventory)
+	p.addRoute(http.MethodPost, v1.PurgeRecordRoute, handler,
+		permissionAdmin)
+
+	body := []byte(`{"challenge":"00"}`)
+	now := time.Now().Unix()
+	badSignature := newTestAuthRequest(reader, v1.InventoryRoute, body, now)
+	badSignature.Header.Set(v1.ClientKeyHeader, admin.Public.String())
+	tampered := newTestAuthRequest(reader, v1.InventoryRoute, body, now)
+	tampered.Body = ioutil.NopCloser(bytes.NewReader(
+		[]byte(`{"challenge":"01"}`)))
+	wrongRoute := newTestAuthRequest(reader, v1.InventoryRoute, body, now)
+	wrongRoute.URL.Path = v1.PurgeRecordRoute
+	basic := httptest.NewRequest(http.MethodPost, v1.PurgeRecordRoute,
+		bytes.NewReader(body))
+	basic.SetBasicAuth(testRPCUser, testRPCPass)
+	badPass := httptest.NewRequest(http.MethodPost, v1.PurgeRecordRoute,
+		bytes.NewReader(body))
+	badPass.SetBasicAuth(testRPCUser, "wrong")
+	large := bytes.Repeat([]byte{'0'}, int(v1.ClientRequestMaxSize)+1)
+	tooLarge := newTestAuthRequest(reader, v1.InventoryRoute, large, now)
+	// Bodies of unknown length are cut short while they are read.
+	tooLargeChunked := newTestAuthRequest(reader, v1.InventoryRoute, large,
+		now)
+	tooLargeChunked.ContentLength = -1
+
+	var tests = []struct {
+		name   string
+		r      *http.Request
+		status int
+		client string          // Client the call is attributed to
+		code   v1.ErrorStatusT // Error code of failed requests
+	}{
+		{"valid signature",
+			newTestAuthRequest(reader, v1.InventoryRoute, body, now),
+			http.StatusOK, "reader", 0},
+		{"valid signature all permissions",
+			newTestAuthRequest(admin, v1.PurgeRecordRoute, body, now),
+			http.StatusOK, "admin", 0},
+		// Signatures are deterministic so the same request is sent
+		// twice.
+		{"first request",
+			newTestAuthRequest(admin, v1.InventoryRoute, body, now),
+			http.StatusOK, "admin", 0},
+		{"replayed request",
+			newTestAuthRequest(admin, v1.InventoryRoute, body, now),
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"signature of another client", badSignature,
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"tampered body", tampered, http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"signed for another route", wrongRoute,
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"unknown client",
+			newTestAuthRequest(unknown, v1.InventoryRoute, body, now),
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"stale timestamp",
+			newTestAuthRequest(reader, v1.InventoryRoute, body,
+				now-v1.ClientSignatureMaxAge-1),
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"future timestamp",
+			newTestAuthRequest(reader, v1.InventoryRoute, body,
+				now+v1.ClientSignatureMaxAge+1),
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"route not authorized",
+			newTestAuthRequest(reader, v1.PurgeRecordRoute, body, now),
+			http.StatusForbidden, "",
+			v1.ErrorStatusPermissionDenied},
+		{"rpc credentials", basic, http.StatusOK, testRPCUser, 0},
+		{"bad rpc credentials", badPass, http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+		{"body too large", tooLarge,
+			http.StatusRequestEntityTooLarge, "",
+			v1.ErrorStatusInvalidRequestPayload},
+		{"body of unknown length too large", tooLargeChunked,
+			http.StatusRequestEntityTooLarge, "",
+			v1.ErrorStatusInvalidRequestPayload},
+		{"no credentials",
+			httptest.NewRequest(http.MethodPost, v1.InventoryRoute,
+				bytes.NewReader(body)),
+			http.StatusUnauthorized, "",
+			v1.ErrorStatusInvalidRPCCredentials},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			p.router.ServeHTTP(w, v.r)
+
+			if w.Code != v.status {
+				t.Fatalf("got status %v, want %v", w.Code, v.status)
+			}
+			if v.status == http.StatusOK {
+				want := v.client + ":" + string(body)
+				if w.Body.String() != want {
+					t.Errorf("got reply %q, want %q",
+						w.Body.String(), want)
+				}
+				return
+			}
+			var uer v1.UserErrorReply
+			err := json.Unmarshal(w.Body.Bytes(), &uer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uer.ErrorCode != v.code {
+				t.Errorf("got error %v, want %v",
+					v1.ErrorStatus[uer.ErrorCode],
+					v1.ErrorStatus[v.code])
+			}
+		})
+	}
+}
@@ -42,6 +42,18 @@ var (
 	// and the subsequent code expect it to be replayed
 	ErrJournalsNotReplayed = errors.New("journals have not been replayed")
 
+	// ErrRecordNotCensored is returned when a purge was attempted on a
+	// record that is not censored.
+	ErrRecordNotCensored = errors.New("record is not censored")
+
+	// ErrRecordPurged is returned when a purge was attempted on a record
+	// that has already been purged.
+	ErrRecordPurged = errors.New("record has been purged")
+
+	// ErrNotSupported is returned when the backend does not implement an
+	// optional operation.
+	ErrNotSupported = errors.New("not supported by backend")
+
 	// Plugin names must be all lowercase letters and have a length of <20
 	PluginRE = regexp.MustCompile(`^[a-z]{1,20}$`)
 )
@@ -62,6 +74,7 @@ type File struct {
 	MIME    string // MIME type
 	Digest  string // SHA256 of decoded Payload
 	Payload string // base64 encoded file
+	Path    string // Decoded file on disk, used instead of Payload if set
 }
 
 type MDStatusT int
@@ -138,6 +151,7 @@ type Plugin struct {
 	ID       string          // Identifier
 	Version  string          // Version
 	Settings []PluginSetting // Settings
+	Client   PluginClient    // Plugin implementation
 }
 
 type Backend interface {
@@ -179,14 +193,31 @@ type Backend interface {
 	SetVettedStatus([]byte, MDStatusT, []MetadataStream,
 		[]MetadataStream) (*Record, error)
 
+	// Purge the files of a censored record and leave a tombstone
+	// (token, reason)
+	PurgeRecord([]byte, string) (*Tombstone, error)
+
+	// Get the tombstone of a purged record
+	GetTombstone([]byte) (*Tombstone, error)
+
+	// Get the anchor proofs of all versions of a vetted record
+	GetTimestamps([]byte) ([]RecordTimestamp, error)
+
 	// Inventory retrieves various record records.
 	Inventory(uint, uint, bool, bool) ([]Record, []Record, error)
 
+	// Inventory page retrieves a filtered page of records and the cursor
+	// of the next page.
+	InventoryPage(InventoryQuery) ([]Record, string, error)
+
+	// Register a plugin with the backend
+	RegisterPlugin(Plugin) error
+
 	// Obtain plugin settings
 	GetPlugins() ([]Plugin, error)
 
-	// Plugin pass-through command
-	Plugin(string, string) (string, string, error) // command type, payload, error
+	// Plugin pass-through command (pluginID, command, payload)
+	Plugin(string, string, string) (string, string, error) // command type, payload, error
 
 	// Close performs cleanup of the backend.
 	Close()
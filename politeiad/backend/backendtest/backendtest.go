@@ -0,0 +1,1082 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package backendtest provides a conformance suite for backend.Backend
+// implementations.  It verifies the record semantics that politeiad relies on:
+// iterations, versioning, metadata handling and the record status transitions.
+//
+// UnvettedExists is deliberately not covered since gitbe answers it by looking
+// at the master branch of the unvetted repository.
+package backendtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/dcrtime/merkle"
+	"github.com/thi4go/politeia/decredplugin"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/mime"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/util"
+)
+
+// Setup returns a freshly initialized backend and a function that releases
+// all of its resources.
+type Setup func(t *testing.T) (backend.Backend, func())
+
+// Run runs the conformance suite against the backends that are returned by
+// setup.  Every test runs against its own backend.
+func Run(t *testing.T, setup Setup) {
+	tests := []struct {
+		name string
+		f    func(*testing.T, backend.Backend)
+	}{
+		{"NewRecord", testNewRecord},
+		{"NewRecordFromPath", testNewRecordFromPath},
+		{"UpdateUnvettedRecord", testUpdateUnvettedRecord},
+		{"SetUnvettedStatus", testSetUnvettedStatus},
+		{"SetVettedStatus", testSetVettedStatus},
+		{"UpdateVettedRecord", testUpdateVettedRecord},
+		{"UpdateVettedMetadata", testUpdateVettedMetadata},
+		{"Inventory", testInventory},
+		{"InventoryPage", testInventoryPage},
+		{"PurgeRecord", testPurgeRecord},
+		{"UpdateReadme", testUpdateReadme},
+		{"Plugin", testPlugin},
+		{"PluginHooks", testPluginHooks},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, cleanup := setup(t)
+			defer cleanup()
+			test.f(t, b)
+		})
+	}
+}
+
+// newFiles returns count text files whose names sort in creation order.
+func newFiles(t *testing.T, name string, count int) []backend.File {
+	t.Helper()
+
+	files := make([]backend.File, 0, count)
+	for i := 0; i < count; i++ {
+		r, err := util.Random(64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Create text file
+		payload := hex.EncodeToString(r)
+		digest := hex.EncodeToString(util.Digest([]byte(payload)))
+		// We expect base64 encoded content
+		b64 := base64.StdEncoding.EncodeToString([]byte(payload))
+
+		files = append(files, backend.File{
+			Name:    name + "_" + strconv.Itoa(i),
+			MIME:    mime.DetectMimeType([]byte(payload)),
+			Digest:  digest,
+			Payload: b64,
+		})
+	}
+	return files
+}
+
+// newRecord creates an unvetted record and returns its token.
+func newRecord(t *testing.T, b backend.Backend, md []backend.MetadataStream, files []backend.File) []byte {
+	t.Helper()
+
+	rm, err := b.New(md, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// setUnvettedStatus moves an unvetted record to the provided status.
+func setUnvettedStatus(t *testing.T, b backend.Backend, token []byte, status backend.MDStatusT) {
+	t.Helper()
+
+	_, err := b.SetUnvettedStatus(token, status, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// merkleRoot returns the hex encoded merkle root of the file digests.
+func merkleRoot(t *testing.T, files []backend.File) string {
+	t.Helper()
+
+	hashes := make([]*[sha256.Size]byte, 0, len(files))
+	for _, v := range sortedFiles(files) {
+		d, ok := util.ConvertDigest(v.Digest)
+		if !ok {
+			t.Fatalf("invalid digest: %v", v.Digest)
+		}
+		hashes = append(hashes, &d)
+	}
+	m := *merkle.Root(hashes)
+	return hex.EncodeToString(m[:])
+}
+
+// sortedFiles returns a copy of the files sorted by name.
+func sortedFiles(files []backend.File) []backend.File {
+	s := make([]backend.File, len(files))
+	copy(s, files)
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Name < s[j].Name
+	})
+	return s
+}
+
+// verifyRecord verifies the parts of a record that every backend must return
+// identically.
+func verifyRecord(r *backend.Record, status backend.MDStatusT, iteration uint64, version string, md []backend.MetadataStream, files []backend.File) error {
+	if r.RecordMetadata.Status != status {
+		return fmt.Errorf("status got %v, want %v",
+			r.RecordMetadata.Status, status)
+	}
+	if r.RecordMetadata.Iteration != iteration {
+		return fmt.Errorf("iteration got %v, want %v",
+			r.RecordMetadata.Iteration, iteration)
+	}
+	if r.Version != version {
+		return fmt.Errorf("version got %v, want %v", r.Version, version)
+	}
+	if !reflect.DeepEqual(r.Metadata, md) {
+		return fmt.Errorf("metadata got %v, want %v",
+			spew.Sdump(r.Metadata), spew.Sdump(md))
+	}
+	if files == nil {
+		if len(r.Files) != 0 {
+			return fmt.Errorf("unexpected files: %v", len(r.Files))
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(r.Files, sortedFiles(files)) {
+		return fmt.Errorf("files got %v, want %v",
+			spew.Sdump(r.Files), spew.Sdump(sortedFiles(files)))
+	}
+	return nil
+}
+
+// verifyErrorCode verifies that err is a ContentVerificationError with the
+// provided error code.
+func verifyErrorCode(err error, code pd.ErrorStatusT) error {
+	e, ok := err.(backend.ContentVerificationError)
+	if !ok {
+		return fmt.Errorf("expected ContentVerificationError, got %v", err)
+	}
+	if e.ErrorCode != code {
+		return fmt.Errorf("error code got %v, want %v", e.ErrorCode, code)
+	}
+	return nil
+}
+
+// verifyTransition verifies that err is the StateTransitionError for the
+// provided status change.
+func verifyTransition(err error, from, to backend.MDStatusT) error {
+	e, ok := err.(backend.StateTransitionError)
+	if !ok {
+		return fmt.Errorf("expected StateTransitionError, got %v", err)
+	}
+	if e.From != from || e.To != to {
+		return fmt.Errorf("transition got %v -> %v, want %v -> %v",
+			e.From, e.To, from, to)
+	}
+	return nil
+}
+
+func testNewRecord(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{
+		{ID: 2, Payload: "two"},
+		{ID: 1, Payload: "one"},
+	}
+	files := newFiles(t, "file", 3)
+
+	rm, err := b.New(md, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rm.Status != backend.MDStatusUnvetted || rm.Iteration != 1 {
+		t.Fatalf("unexpected record metadata: %v", spew.Sdump(rm))
+	}
+	if len(rm.Token) != pd.TokenSize*2 {
+		t.Fatalf("invalid token: %v", rm.Token)
+	}
+	if rm.Merkle != merkleRoot(t, files) {
+		t.Fatalf("merkle got %v, want %v", rm.Merkle,
+			merkleRoot(t, files))
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := b.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMD := []backend.MetadataStream{md[1], md[0]}
+	err = verifyRecord(r, backend.MDStatusUnvetted, 1, "1", wantMD, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Token != rm.Token {
+		t.Fatalf("token got %v, want %v", r.RecordMetadata.Token,
+			rm.Token)
+	}
+
+	// An unvetted record is not vetted.
+	if b.VettedExists(token) {
+		t.Fatalf("unvetted record exists as vetted")
+	}
+	_, err = b.GetVetted(token, "")
+	if err == nil {
+		t.Fatalf("expected error getting unvetted record as vetted")
+	}
+
+	// Invalid content is rejected.
+	_, err = b.New(md, nil)
+	if err := verifyErrorCode(err, pd.ErrorStatusEmpty); err != nil {
+		t.Fatal(err)
+	}
+	bad := newFiles(t, "bad", 1)
+	bad[0].Digest = files[0].Digest
+	_, err = b.New(md, bad)
+	err = verifyErrorCode(err, pd.ErrorStatusInvalidFileDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dup := []backend.File{files[0], files[0]}
+	_, err = b.New(md, dup)
+	err = verifyErrorCode(err, pd.ErrorStatusDuplicateFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.New([]backend.MetadataStream{md[0], md[0]}, files)
+	err = verifyErrorCode(err, pd.ErrorStatusDuplicateMDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testNewRecordFromPath verifies that files that are handed to the backend by
+// path are stored exactly like files that carry their payload.
+func testNewRecordFromPath(t *testing.T, b backend.Backend) {
+	dir, err := ioutil.TempDir("", "backendtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := newFiles(t, "file", 2)
+	pathFiles := make([]backend.File, len(files))
+	copy(pathFiles, files)
+	for i, v := range files {
+		payload, err := base64.StdEncoding.DecodeString(v.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, v.Name)
+		err = ioutil.WriteFile(path, payload, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pathFiles[i].Payload = ""
+		pathFiles[i].Path = path
+	}
+
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	token := newRecord(t, b, md, pathFiles)
+	r, err := b.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusUnvetted, 1, "1", md, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The content on disk is verified like any other payload.
+	bad := []backend.File{pathFiles[0]}
+	bad[0].Digest = files[1].Digest
+	_, err = b.New(md, bad)
+	err = verifyErrorCode(err, pd.ErrorStatusInvalidFileDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testUpdateUnvettedRecord(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{
+		{ID: 1, Payload: "one"},
+		{ID: 2, Payload: "two"},
+		{ID: decredplugin.MDStreamAuthorizeVote, Payload: "authorized"},
+	}
+	files := newFiles(t, "file", 3)
+	token := newRecord(t, b, md, files)
+
+	// Add, replace and delete files and update metadata.
+	add := newFiles(t, "added", 1)
+	replace := newFiles(t, "file", 1)
+	r, err := b.UpdateUnvettedRecord(token,
+		[]backend.MetadataStream{{ID: 1, Payload: " appended"}},
+		[]backend.MetadataStream{{ID: 2, Payload: "overwritten"}},
+		append(add, replace...), []string{files[2].Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The vote authorization is dropped when the content changes.
+	wantMD := []backend.MetadataStream{
+		{ID: 1, Payload: "one appended"},
+		{ID: 2, Payload: "overwritten"},
+	}
+	wantFiles := []backend.File{add[0], replace[0], files[1]}
+	err = verifyRecord(r, backend.MDStatusIterationUnvetted, 2, "1",
+		wantMD, wantFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Merkle != merkleRoot(t, wantFiles) {
+		t.Fatalf("merkle got %v, want %v", r.RecordMetadata.Merkle,
+			merkleRoot(t, wantFiles))
+	}
+	r, err = b.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusIterationUnvetted, 2, "1",
+		wantMD, wantFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates that change nothing are rejected.
+	_, err = b.UpdateUnvettedRecord(token, nil, wantMD[1:], nil, nil)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+
+	// Deleting a file that does not exist is rejected.
+	_, err = b.UpdateUnvettedRecord(token, nil, nil, nil,
+		[]string{files[2].Name})
+	err = verifyErrorCode(err, pd.ErrorStatusFileNotFound)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unvetted record can not be updated through the vetted calls.
+	_, err = b.UpdateVettedRecord(token, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Iterations keep counting.
+	r, err = b.UpdateUnvettedRecord(token, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Iteration != 3 || r.Version != "1" {
+		t.Fatalf("unexpected iteration %v version %v",
+			r.RecordMetadata.Iteration, r.Version)
+	}
+}
+
+func testSetUnvettedStatus(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	files := newFiles(t, "file", 2)
+
+	// Invalid transitions out of the unvetted states.
+	token := newRecord(t, b, md, files)
+	for _, status := range []backend.MDStatusT{
+		backend.MDStatusUnvetted,
+		backend.MDStatusIterationUnvetted,
+		backend.MDStatusArchived,
+	} {
+		_, err := b.SetUnvettedStatus(token, status, nil, nil)
+		err = verifyTransition(err, backend.MDStatusUnvetted, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// unvetted -> vetted
+	r, err := b.SetUnvettedStatus(token, backend.MDStatusVetted,
+		[]backend.MetadataStream{{ID: 1, Payload: " appended"}},
+		[]backend.MetadataStream{{ID: 2, Payload: "two"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMD := []backend.MetadataStream{
+		{ID: 1, Payload: "one appended"},
+		{ID: 2, Payload: "two"},
+	}
+	err = verifyRecord(r, backend.MDStatusVetted, 2, "1", wantMD, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.VettedExists(token) {
+		t.Fatalf("vetted record does not exist")
+	}
+	r, err = b.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusVetted, 2, "1", wantMD, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.GetUnvetted(token)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	_, err = b.SetUnvettedStatus(token, backend.MDStatusCensored, nil, nil)
+	if err == nil {
+		t.Fatalf("expected error censoring vetted record")
+	}
+	_, err = b.UpdateUnvettedRecord(token, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != backend.ErrRecordFound {
+		t.Fatalf("expected ErrRecordFound, got %v", err)
+	}
+
+	// iteration unvetted -> censored
+	token = newRecord(t, b, md, files)
+	_, err = b.UpdateUnvettedRecord(token, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = b.SetUnvettedStatus(token, backend.MDStatusCensored, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusCensored, 3, "1", md, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = b.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Status != backend.MDStatusCensored {
+		t.Fatalf("status got %v, want %v", r.RecordMetadata.Status,
+			backend.MDStatusCensored)
+	}
+	if b.VettedExists(token) {
+		t.Fatalf("censored record exists as vetted")
+	}
+
+	// Censored is final.
+	for _, status := range []backend.MDStatusT{
+		backend.MDStatusVetted,
+		backend.MDStatusCensored,
+	} {
+		_, err := b.SetUnvettedStatus(token, status, nil, nil)
+		err = verifyTransition(err, backend.MDStatusCensored, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = b.UpdateUnvettedRecord(token, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err == nil {
+		t.Fatalf("expected error updating censored record")
+	}
+}
+
+func testSetVettedStatus(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	files := newFiles(t, "file", 2)
+	token := newRecord(t, b, md, files)
+
+	// The record has to be vetted.
+	_, err := b.SetVettedStatus(token, backend.MDStatusArchived, nil, nil)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	setUnvettedStatus(t, b, token, backend.MDStatusVetted)
+
+	// Invalid transitions out of vetted.
+	for _, status := range []backend.MDStatusT{
+		backend.MDStatusUnvetted,
+		backend.MDStatusVetted,
+		backend.MDStatusCensored,
+	} {
+		_, err := b.SetVettedStatus(token, status, nil, nil)
+		err = verifyTransition(err, backend.MDStatusVetted, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// vetted -> archived
+	r, err := b.SetVettedStatus(token, backend.MDStatusArchived,
+		[]backend.MetadataStream{{ID: 1, Payload: " appended"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMD := []backend.MetadataStream{{ID: 1, Payload: "one appended"}}
+	err = verifyRecord(r, backend.MDStatusArchived, 3, "1", wantMD, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = b.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusArchived, 3, "1", wantMD, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.VettedExists(token) {
+		t.Fatalf("archived record does not exist")
+	}
+
+	// Archived is final.
+	for _, status := range []backend.MDStatusT{
+		backend.MDStatusVetted,
+		backend.MDStatusArchived,
+	} {
+		_, err := b.SetVettedStatus(token, status, nil, nil)
+		if err != backend.ErrRecordArchived {
+			t.Fatalf("expected ErrRecordArchived, got %v", err)
+		}
+	}
+}
+
+func testUpdateVettedRecord(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	files := newFiles(t, "file", 2)
+	token := newRecord(t, b, md, files)
+	setUnvettedStatus(t, b, token, backend.MDStatusVetted)
+
+	// Every vetted update creates a new version.
+	add := newFiles(t, "added", 1)
+	r, err := b.UpdateVettedRecord(token,
+		[]backend.MetadataStream{{ID: 1, Payload: " appended"}}, nil,
+		add, []string{files[0].Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMD := []backend.MetadataStream{{ID: 1, Payload: "one appended"}}
+	wantFiles := []backend.File{add[0], files[1]}
+	err = verifyRecord(r, backend.MDStatusVetted, 3, "2", wantMD,
+		wantFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Previous versions remain available.
+	r, err = b.GetVetted(token, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusVetted, 2, "1", md, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = b.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusVetted, 3, "2", wantMD,
+		wantFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.GetVetted(token, "3")
+	if err == nil {
+		t.Fatalf("expected error getting invalid version")
+	}
+
+	// Updates that change nothing are rejected.
+	_, err = b.UpdateVettedRecord(token, nil, wantMD, nil, nil)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+
+	r, err = b.UpdateVettedRecord(token, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Iteration != 4 || r.Version != "3" {
+		t.Fatalf("unexpected iteration %v version %v",
+			r.RecordMetadata.Iteration, r.Version)
+	}
+}
+
+func testUpdateVettedMetadata(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	files := newFiles(t, "file", 2)
+	token := newRecord(t, b, md, files)
+
+	// The record has to be vetted.
+	err := b.UpdateVettedMetadata(token, nil,
+		[]backend.MetadataStream{{ID: 2, Payload: "two"}})
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	setUnvettedStatus(t, b, token, backend.MDStatusVetted)
+
+	err = b.UpdateVettedMetadata(token,
+		[]backend.MetadataStream{{ID: 1, Payload: " appended"}},
+		[]backend.MetadataStream{{ID: 2, Payload: "two"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMD := []backend.MetadataStream{
+		{ID: 1, Payload: "one appended"},
+		{ID: 2, Payload: "two"},
+	}
+
+	// The record itself does not change.
+	r, err := b.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusVetted, 2, "1", wantMD, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates that change nothing are rejected.
+	err = b.UpdateVettedMetadata(token, nil, wantMD[1:])
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+
+	// Archived records are locked.
+	_, err = b.SetVettedStatus(token, backend.MDStatusArchived, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.UpdateVettedMetadata(token, nil,
+		[]backend.MetadataStream{{ID: 2, Payload: "changed"}})
+	if err != backend.ErrRecordArchived {
+		t.Fatalf("expected ErrRecordArchived, got %v", err)
+	}
+}
+
+func testInventory(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+
+	unvetted := newRecord(t, b, md, newFiles(t, "file", 1))
+	censored := newRecord(t, b, md, newFiles(t, "file", 1))
+	setUnvettedStatus(t, b, censored, backend.MDStatusCensored)
+	vetted := newRecord(t, b, md, newFiles(t, "file", 1))
+	setUnvettedStatus(t, b, vetted, backend.MDStatusVetted)
+	_, err := b.UpdateVettedRecord(vetted, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens returns the sorted tokens and versions of the records.
+	tokens := func(records []backend.Record) []string {
+		s := make([]string, 0, len(records))
+		for _, v := range records {
+			s = append(s, v.RecordMetadata.Token+":"+v.Version)
+		}
+		sort.Strings(s)
+		return s
+	}
+	sorted := func(tokens ...string) []string {
+		sort.Strings(tokens)
+		return tokens
+	}
+	h := hex.EncodeToString
+
+	pr, br, err := b.Inventory(0, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tokens(pr), sorted(h(vetted)+":2"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("vetted got %v, want %v", got, want)
+	}
+	if got, want := tokens(br), sorted(h(unvetted)+":1",
+		h(censored)+":1"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unvetted got %v, want %v", got, want)
+	}
+	for _, v := range append(pr, br...) {
+		if len(v.Files) != 0 {
+			t.Fatalf("unexpected files %v", v.RecordMetadata.Token)
+		}
+	}
+
+	pr, br, err = b.Inventory(0, 0, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tokens(pr), sorted(h(vetted)+":1",
+		h(vetted)+":2"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("vetted got %v, want %v", got, want)
+	}
+	if len(br) != 2 {
+		t.Fatalf("unvetted got %v, want 2", len(br))
+	}
+	for _, v := range append(pr, br...) {
+		if len(v.Files) == 0 {
+			t.Fatalf("missing files %v", v.RecordMetadata.Token)
+		}
+	}
+}
+
+func testInventoryPage(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+
+	unvetted := newRecord(t, b, append(md,
+		backend.MetadataStream{ID: 5, Payload: "five"}),
+		newFiles(t, "file", 1))
+	censored := newRecord(t, b, md, newFiles(t, "file", 1))
+	setUnvettedStatus(t, b, censored, backend.MDStatusCensored)
+	vetted := newRecord(t, b, md, newFiles(t, "file", 1))
+	setUnvettedStatus(t, b, vetted, backend.MDStatusVetted)
+	_, err := b.UpdateVettedRecord(vetted, nil, nil, newFiles(t, "x", 1),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens returns the tokens and versions of the records in order.
+	tokens := func(records []backend.Record) []string {
+		s := make([]string, 0, len(records))
+		for _, v := range records {
+			s = append(s, v.RecordMetadata.Token+":"+v.Version)
+		}
+		return s
+	}
+	h := hex.EncodeToString
+
+	// Walk the entire inventory one record at a time.
+	var (
+		got    []string
+		cursor string
+		pages  int
+	)
+	for {
+		records, next, err := b.InventoryPage(backend.InventoryQuery{
+			Cursor: cursor,
+			Limit:  1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if len(records) != 1 {
+			t.Fatalf("page %v: got %v records, want 1", pages,
+				len(records))
+		}
+		if len(records[0].Files) != 0 {
+			t.Fatalf("unexpected files %v",
+				records[0].RecordMetadata.Token)
+		}
+		got = append(got, records[0].RecordMetadata.Token)
+		if next == "" {
+			break
+		}
+		if pages > 3 {
+			t.Fatalf("too many pages")
+		}
+		cursor = next
+	}
+	want := []string{h(unvetted), h(censored), h(vetted)}
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// Filter by status and include all versions.
+	records, next, err := b.InventoryPage(backend.InventoryQuery{
+		IncludeFiles: true,
+		AllVersions:  true,
+		Filter: backend.InventoryFilter{
+			Statuses: []backend.MDStatusT{backend.MDStatusVetted},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != "" {
+		t.Fatalf("unexpected cursor %v", next)
+	}
+	if got, want := tokens(records), []string{h(vetted) + ":2",
+		h(vetted) + ":1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, v := range records {
+		if len(v.Files) == 0 {
+			t.Fatalf("missing files %v", v.RecordMetadata.Token)
+		}
+	}
+
+	// Filter by metadata stream.
+	records, _, err = b.InventoryPage(backend.InventoryQuery{
+		Filter: backend.InventoryFilter{
+			MDStreams: []uint64{1, 5},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tokens(records),
+		[]string{h(unvetted) + ":1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// Filter by timestamp.
+	records, _, err = b.InventoryPage(backend.InventoryQuery{
+		Filter: backend.InventoryFilter{
+			FromTimestamp: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %v records, want 3", len(records))
+	}
+	records, _, err = b.InventoryPage(backend.InventoryQuery{
+		Filter: backend.InventoryFilter{
+			ToTimestamp: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("got %v records, want 0", len(records))
+	}
+}
+
+func testPurgeRecord(t *testing.T, b backend.Backend) {
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	files := newFiles(t, "file", 3)
+
+	// Only censored records can be purged.
+	token := newRecord(t, b, md, files)
+	_, err := b.PurgeRecord(token, "reason")
+	if err != backend.ErrRecordNotCensored {
+		t.Fatalf("expected ErrRecordNotCensored, got %v", err)
+	}
+	setUnvettedStatus(t, b, token, backend.MDStatusVetted)
+	_, err = b.PurgeRecord(token, "reason")
+	if err != backend.ErrRecordNotCensored {
+		t.Fatalf("expected ErrRecordNotCensored, got %v", err)
+	}
+	_, err = b.GetTombstone(token)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// censored -> purged
+	token = newRecord(t, b, md, files)
+	setUnvettedStatus(t, b, token, backend.MDStatusCensored)
+	ts, err := b.PurgeRecord(token, "reason")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Token != hex.EncodeToString(token) || ts.Reason != "reason" {
+		t.Fatalf("unexpected tombstone: %v", spew.Sdump(ts))
+	}
+	if ts.Merkle != merkleRoot(t, files) {
+		t.Fatalf("merkle got %v, want %v", ts.Merkle,
+			merkleRoot(t, files))
+	}
+	if len(ts.Files) != len(files) {
+		t.Fatalf("got %v files, want %v", len(ts.Files), len(files))
+	}
+	for k, v := range sortedFiles(files) {
+		f := ts.Files[k]
+		if f.Name != v.Name || f.MIME != v.MIME || f.Digest != v.Digest ||
+			f.Payload != "" {
+			t.Fatalf("unexpected tombstone file: %v", spew.Sdump(f))
+		}
+	}
+
+	// The record still exists without its files.
+	r, err := b.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifyRecord(r, backend.MDStatusCensored, 2, "1", md, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.GetTombstone(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ts) {
+		t.Fatalf("tombstone got %v, want %v", spew.Sdump(got),
+			spew.Sdump(ts))
+	}
+
+	// A record can only be purged once.
+	_, err = b.PurgeRecord(token, "reason")
+	if err != backend.ErrRecordPurged {
+		t.Fatalf("expected ErrRecordPurged, got %v", err)
+	}
+}
+
+func testUpdateReadme(t *testing.T, b backend.Backend) {
+	content := "Updated Readme Content!! \n"
+	err := b.UpdateReadme(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trying to update readme to the same content returns an error.
+	err = b.UpdateReadme(content)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+}
+
+func testPlugin(t *testing.T, b backend.Backend) {
+	plugins, err := b.GetPlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range plugins {
+		if !backend.PluginRE.MatchString(v.ID) {
+			t.Fatalf("invalid plugin id: %v", v.ID)
+		}
+	}
+
+	_, _, err = b.Plugin("invalidplugin", "invalidcommand", "")
+	if err != backend.ErrPluginNotFound {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+}
+
+// hookClient is a plugin client that records the hooks it is called with.
+// It vetoes new records that carry a metadata stream with the payload veto.
+type hookClient struct {
+	hooks []backend.HookT
+}
+
+func (c *hookClient) Setup() error {
+	return nil
+}
+
+func (c *hookClient) Cmd(cmd, payload string) (string, error) {
+	if cmd != "echo" {
+		return "", backend.ErrPluginCmdInvalid
+	}
+	return payload, nil
+}
+
+func (c *hookClient) Hook(h backend.HookT, payload string) error {
+	c.hooks = append(c.hooks, h)
+	if h != backend.HookPreNewRecord {
+		return nil
+	}
+	var hook backend.HookNewRecord
+	err := json.Unmarshal([]byte(payload), &hook)
+	if err != nil {
+		return err
+	}
+	for _, v := range hook.Metadata {
+		if v.Payload == "veto" {
+			return backend.ContentVerificationError{
+				ErrorCode: pd.ErrorStatusInvalidRequestPayload,
+			}
+		}
+	}
+	return nil
+}
+
+// verifyHooks verifies that the plugin was called with the expected hooks
+// since the last call.
+func verifyHooks(t *testing.T, c *hookClient, want ...backend.HookT) {
+	t.Helper()
+
+	if !reflect.DeepEqual(c.hooks, want) {
+		t.Fatalf("got hooks %v, want %v", c.hooks, want)
+	}
+	c.hooks = nil
+}
+
+func testPluginHooks(t *testing.T, b backend.Backend) {
+	c := &hookClient{}
+	p := backend.Plugin{
+		ID:      "test",
+		Version: "1",
+		Client:  c,
+	}
+	err := b.RegisterPlugin(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RegisterPlugin(p); err == nil {
+		t.Fatalf("expected error for duplicate plugin")
+	}
+
+	// Plugin commands are routed by plugin id.
+	cmd, reply, err := b.Plugin(p.ID, "echo", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd != "echo" || reply != "payload" {
+		t.Fatalf("got %v %v, want echo payload", cmd, reply)
+	}
+	_, _, err = b.Plugin(p.ID, "invalidcommand", "")
+	if err != backend.ErrPluginCmdInvalid {
+		t.Fatalf("expected ErrPluginCmdInvalid, got %v", err)
+	}
+
+	// Record changes call the pre and post hooks.
+	md := []backend.MetadataStream{{ID: 1, Payload: "one"}}
+	token := newRecord(t, b, md, newFiles(t, "file", 1))
+	verifyHooks(t, c, backend.HookPreNewRecord, backend.HookPostNewRecord)
+
+	_, err = b.UpdateUnvettedRecord(token, nil, nil,
+		newFiles(t, "added", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyHooks(t, c, backend.HookPreEditRecord, backend.HookPostEditRecord)
+
+	setUnvettedStatus(t, b, token, backend.MDStatusVetted)
+	verifyHooks(t, c, backend.HookPreSetRecordStatus,
+		backend.HookPostSetRecordStatus)
+
+	// A pre hook vetoes the change.
+	_, err = b.New([]backend.MetadataStream{{ID: 1, Payload: "veto"}},
+		newFiles(t, "file", 1))
+	err = verifyErrorCode(err, pd.ErrorStatusInvalidRequestPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyHooks(t, c, backend.HookPreNewRecord)
+
+	vetted, unvetted, err := b.Inventory(0, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 1 || len(unvetted) != 0 {
+		t.Fatalf("got %v vetted and %v unvetted records, want 1 and 0",
+			len(vetted), len(unvetted))
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/subosito/gozaru"
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/mime"
+	"github.com/thi4go/politeia/util"
+)
+
+// VerifiedFile is a file whose name, digest, MIME type and content have been
+// verified by VerifyContent.
+type VerifiedFile struct {
+	Name    string // Basename of the file
+	MIME    string // Declared MIME type
+	Digest  []byte // SHA256 of payload
+	Payload []byte // Decoded payload, nil if Path is set
+	Path    string // Payload on disk, used instead of Payload if set
+}
+
+// ReadPayload returns the decoded payload of the file.  Payloads on disk are
+// read in full.
+func (f VerifiedFile) ReadPayload() ([]byte, error) {
+	if f.Path == "" {
+		return f.Payload, nil
+	}
+	return ioutil.ReadFile(f.Path)
+}
+
+// verifyFile verifies the digest, the MIME type and the content of a file
+// whose payload of the provided size is read through r.
+func verifyFile(f File, digest [sha256.Size]byte, r io.ReaderAt, size int64) error {
+	h := sha256.New()
+	_, err := io.Copy(h, io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(digest[:], h.Sum(nil)) {
+		return ContentVerificationError{
+			ErrorCode:    v1.ErrorStatusInvalidFileDigest,
+			ErrorContext: []string{f.Name},
+		}
+	}
+
+	detected, err := mime.DetectMimeTypeReader(r, size)
+	if err != nil {
+		return err
+	}
+	if !mime.MimeMatches(f.MIME, detected) {
+		return ContentVerificationError{
+			ErrorCode:    v1.ErrorStatusInvalidMIMEType,
+			ErrorContext: []string{f.Name, detected},
+		}
+	}
+	if !mime.MimeValid(f.MIME) {
+		return ContentVerificationError{
+			ErrorCode:    v1.ErrorStatusUnsupportedMIMEType,
+			ErrorContext: []string{f.Name, f.MIME},
+		}
+	}
+
+	err = mime.ValidateReader(f.MIME, r, size)
+	if err != nil {
+		return ContentVerificationError{
+			ErrorCode:    v1.ErrorStatusMalformedFile,
+			ErrorContext: []string{f.Name, err.Error()},
+		}
+	}
+
+	return nil
+}
+
+// verifyPath verifies a file whose payload is on disk.  The payload is
+// streamed from disk so that it never has to be held in memory.
+func verifyPath(f File, digest [sha256.Size]byte) error {
+	fh, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	fi, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+	return verifyFile(f, digest, fh, fi.Size())
+}
+
+// VerifyContent verifies that all provided metadata streams and files are
+// sane and returns the verified files.  Files to be deleted are only checked
+// for their names.
+func VerifyContent(metadata []MetadataStream, files []File, filesDel []string) ([]VerifiedFile, error) {
+	// Make sure all metadata is within maxima.
+	for _, v := range metadata {
+		if v.ID > v1.MetadataStreamsMax-1 {
+			return nil, ContentVerificationError{
+				ErrorCode: v1.ErrorStatusInvalidMDID,
+				ErrorContext: []string{
+					strconv.FormatUint(v.ID, 10),
+				},
+			}
+		}
+	}
+	for i := range metadata {
+		for j := range metadata {
+			// Skip self and non duplicates.
+			if i == j || metadata[i].ID != metadata[j].ID {
+				continue
+			}
+			return nil, ContentVerificationError{
+				ErrorCode: v1.ErrorStatusDuplicateMDID,
+				ErrorContext: []string{
+					strconv.FormatUint(metadata[i].ID, 10),
+				},
+			}
+		}
+	}
+
+	// Prevent paths
+	for i := range files {
+		if filepath.Base(files[i].Name) != files[i].Name {
+			return nil, ContentVerificationError{
+				ErrorCode:    v1.ErrorStatusInvalidFilename,
+				ErrorContext: []string{files[i].Name},
+			}
+		}
+	}
+	for _, v := range filesDel {
+		if filepath.Base(v) != v {
+			return nil, ContentVerificationError{
+				ErrorCode:    v1.ErrorStatusInvalidFilename,
+				ErrorContext: []string{v},
+			}
+		}
+	}
+
+	// Now check files
+	if len(files) == 0 {
+		return nil, ContentVerificationError{
+			ErrorCode: v1.ErrorStatusEmpty,
+		}
+	}
+
+	// Prevent bad filenames and duplicate filenames
+	for i := range files {
+		for j := range files {
+			if i == j {
+				continue
+			}
+			if files[i].Name == files[j].Name {
+				return nil, ContentVerificationError{
+					ErrorCode:    v1.ErrorStatusDuplicateFilename,
+					ErrorContext: []string{files[i].Name},
+				}
+			}
+		}
+		// Check against filesDel
+		for _, v := range filesDel {
+			if files[i].Name == v {
+				return nil, ContentVerificationError{
+					ErrorCode:    v1.ErrorStatusDuplicateFilename,
+					ErrorContext: []string{files[i].Name},
+				}
+			}
+		}
+	}
+
+	fa := make([]VerifiedFile, 0, len(files))
+	for i := range files {
+		if gozaru.Sanitize(files[i].Name) != files[i].Name {
+			return nil, ContentVerificationError{
+				ErrorCode:    v1.ErrorStatusInvalidFilename,
+				ErrorContext: []string{files[i].Name},
+			}
+		}
+
+		// Validate digest
+		d, ok := util.ConvertDigest(files[i].Digest)
+		if !ok {
+			return nil, ContentVerificationError{
+				ErrorCode:    v1.ErrorStatusInvalidFileDigest,
+				ErrorContext: []string{files[i].Name},
+			}
+		}
+
+		f := VerifiedFile{
+			Name:   files[i].Name,
+			MIME:   files[i].MIME,
+			Digest: d[:],
+		}
+
+		// Files that were uploaded in parts are verified from disk.
+		// All other files are decoded from their base64 payload.
+		if files[i].Path != "" {
+			err := verifyPath(files[i], d)
+			if err != nil {
+				return nil, err
+			}
+			f.Path = files[i].Path
+		} else {
+			payload, err := base64.StdEncoding.DecodeString(files[i].Payload)
+			if err != nil {
+				return nil, ContentVerificationError{
+					ErrorCode:    v1.ErrorStatusInvalidBase64,
+					ErrorContext: []string{files[i].Name},
+				}
+			}
+			err = verifyFile(files[i], d, bytes.NewReader(payload),
+				int64(len(payload)))
+			if err != nil {
+				return nil, err
+			}
+			f.Payload = payload
+		}
+
+		fa = append(fa, f)
+	}
+
+	return fa, nil
+}
+
+// ConvertVerifiedFiles converts verified files into files with a base64
+// encoded payload.  Payloads on disk are read at this point, which is only
+// done by backends that store the payloads in their database.
+func ConvertVerifiedFiles(fa []VerifiedFile) ([]File, error) {
+	bf := make([]File, 0, len(fa))
+	for _, f := range fa {
+		payload, err := f.ReadPayload()
+		if err != nil {
+			return nil, err
+		}
+		bf = append(bf, File{
+			Name:    f.Name,
+			MIME:    f.MIME,
+			Digest:  hex.EncodeToString(f.Digest),
+			Payload: base64.StdEncoding.EncodeToString(payload),
+		})
+	}
+	return bf, nil
+}
+
+// SortFiles sorts the files by name.  This is the directory order in which
+// gitbe returns the files of a record.
+func SortFiles(files []File) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+}
+
+// SortMetadata sorts the metadata streams by ID.
+func SortMetadata(md []MetadataStream) {
+	sort.Slice(md, func(i, j int) bool {
+		return md[i].ID < md[j].ID
+	})
+}
+
+// ApplyMetadata returns a new set of metadata streams that is the result of
+// appending and overwriting the provided streams to the existing streams.
+// The result is sorted by ID.
+func ApplyMetadata(md, mdAppend, mdOverwrite []MetadataStream) []MetadataStream {
+	streams := make(map[uint64]string, len(md))
+	for _, v := range md {
+		streams[v.ID] = v.Payload
+	}
+	for _, v := range mdOverwrite {
+		streams[v.ID] = v.Payload
+	}
+	for _, v := range mdAppend {
+		streams[v.ID] += v.Payload
+	}
+
+	nmd := make([]MetadataStream, 0, len(streams))
+	for id, payload := range streams {
+		nmd = append(nmd, MetadataStream{
+			ID:      id,
+			Payload: payload,
+		})
+	}
+	SortMetadata(nmd)
+
+	return nmd
+}
+
+// CreateRecordMetadata returns the metadata of a record with the provided
+// token that consists of the provided files.  The merkle root is calculated
+// over the file digests in the order of the files.
+func CreateRecordMetadata(token string, status MDStatusT, iteration uint64, files []File) (*RecordMetadata, error) {
+	hashes := make([]*[sha256.Size]byte, 0, len(files))
+	for _, v := range files {
+		d, ok := util.ConvertDigest(v.Digest)
+		if !ok {
+			return nil, fmt.Errorf("invalid digest: %v", v.Digest)
+		}
+		hashes = append(hashes, &d)
+	}
+
+	m := *merkle.Root(hashes)
+	return &RecordMetadata{
+		Version:   VersionRecordMD,
+		Iteration: iteration,
+		Status:    status,
+		Merkle:    hex.EncodeToString(m[:]),
+		Timestamp: time.Now().Unix(),
+		Token:     token,
+	}, nil
+}
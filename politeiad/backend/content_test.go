@@ -0,0 +1,93 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+)
+
+func newContentFile(name, mimeType, content string) File {
+	d := sha256.Sum256([]byte(content))
+	return File{
+		Name:    name,
+		MIME:    mimeType,
+		Digest:  hex.EncodeToString(d[:]),
+		Payload: base64.StdEncoding.EncodeToString([]byte(content)),
+	}
+}
+
+func TestVerifyContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := "a,b\n1,2\n"
+	path := filepath.Join(dir, "upload")
+	err = ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onDisk := newContentFile("disk.csv", "text/csv", content)
+	onDisk.Payload = ""
+	onDisk.Path = path
+
+	badDigest := onDisk
+	badDigest.Digest = newContentFile("", "", "other").Digest
+
+	var tests = []struct {
+		name  string
+		files []File
+		want  v1.ErrorStatusT
+	}{
+		{"payload", []File{newContentFile("a.csv", "text/csv", content)},
+			v1.ErrorStatusInvalid},
+		{"path", []File{onDisk}, v1.ErrorStatusInvalid},
+		{"path digest", []File{badDigest},
+			v1.ErrorStatusInvalidFileDigest},
+		{"mime", []File{newContentFile("a.png", "image/png", content)},
+			v1.ErrorStatusInvalidMIMEType},
+		{"malformed", []File{newContentFile("a.csv", "text/csv", "a,b\n1\n")},
+			v1.ErrorStatusMalformedFile},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			fa, err := VerifyContent(nil, v.files, nil)
+			if v.want != v1.ErrorStatusInvalid {
+				e, ok := err.(ContentVerificationError)
+				if !ok || e.ErrorCode != v.want {
+					t.Fatalf("got %v, want %v", err, v.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The declared MIME type is kept and payloads on
+			// disk are only read on conversion.
+			if fa[0].MIME != "text/csv" {
+				t.Fatalf("got MIME %v", fa[0].MIME)
+			}
+			bf, err := ConvertVerifiedFiles(fa)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bf[0].Digest != v.files[0].Digest ||
+				bf[0].Payload != newContentFile("", "", content).Payload {
+				t.Fatalf("unexpected file %v", bf[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffActionT describes how a file or a metadata stream changed between two
+// versions of a record.
+type DiffActionT int
+
+const (
+	// All possible diff actions
+	DiffActionInvalid  DiffActionT = 0 // Invalid action, this is a bug
+	DiffActionAdded    DiffActionT = 1 // Only present in the new version
+	DiffActionRemoved  DiffActionT = 2 // Only present in the old version
+	DiffActionModified DiffActionT = 3 // Present in both but different
+
+	// diffContext is the number of unchanged lines that surround a change
+	// in a unified diff.
+	diffContext = 3
+)
+
+var (
+	// DiffAction converts a diff action to a human readable string.
+	DiffAction = map[DiffActionT]string{
+		DiffActionInvalid:  "invalid",
+		DiffActionAdded:    "added",
+		DiffActionRemoved:  "removed",
+		DiffActionModified: "modified",
+	}
+)
+
+// FileDiff describes how a single file changed.  Text files carry a unified
+// diff, binary files are only described by their digests.
+type FileDiff struct {
+	Name      string      // Basename of the file
+	Action    DiffActionT // How the file changed
+	MIME      string      // MIME type of the newest copy of the file
+	OldDigest string      // SHA256 of the old payload, empty when added
+	NewDigest string      // SHA256 of the new payload, empty when removed
+	Diff      string      // Unified diff, empty for binary files
+}
+
+// MetadataStreamDiff describes how a single metadata stream changed.
+type MetadataStreamDiff struct {
+	ID     uint64      // Stream identity
+	Action DiffActionT // How the stream changed
+	Diff   string      // Unified diff of the payload
+}
+
+// isText returns true if the MIME type describes a file that can be diffed
+// line by line.
+func isText(mime string) bool {
+	return strings.HasPrefix(mime, "text/")
+}
+
+// unifiedDiff returns the unified diff between a and b.
+func unifiedDiff(a, b, fromFile, toFile string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  diffContext,
+	})
+}
+
+// decodePayload returns the decoded payload of a text file.  Binary files
+// return an empty payload since they are not diffed.
+func decodePayload(f *File) (string, error) {
+	if f == nil || !isText(f.MIME) {
+		return "", nil
+	}
+	b, err := base64.StdEncoding.DecodeString(f.Payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid payload %v: %v", f.Name, err)
+	}
+	return string(b), nil
+}
+
+// diffFiles returns the files that changed between two versions.
+func diffFiles(oldVersion, newVersion string, oldFiles, newFiles []File) ([]FileDiff, error) {
+	of := make(map[string]File, len(oldFiles))
+	for _, v := range oldFiles {
+		of[v.Name] = v
+	}
+	nf := make(map[string]File, len(newFiles))
+	for _, v := range newFiles {
+		nf[v.Name] = v
+	}
+
+	names := make([]string, 0, len(of)+len(nf))
+	for k := range of {
+		names = append(names, k)
+	}
+	for k := range nf {
+		if _, ok := of[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	fd := make([]FileDiff, 0, len(names))
+	for _, name := range names {
+		var (
+			o, n *File
+			d    = FileDiff{Name: name}
+		)
+		if v, ok := of[name]; ok {
+			o = &v
+			d.OldDigest = v.Digest
+			d.MIME = v.MIME
+		}
+		if v, ok := nf[name]; ok {
+			n = &v
+			d.NewDigest = v.Digest
+			d.MIME = v.MIME
+		}
+
+		switch {
+		case o == nil:
+			d.Action = DiffActionAdded
+		case n == nil:
+			d.Action = DiffActionRemoved
+		case o.Digest != n.Digest:
+			d.Action = DiffActionModified
+		default:
+			// Unchanged
+			continue
+		}
+
+		// Only text files are diffed.  A file that changed from text
+		// to binary, or vice versa, is only described by its digests.
+		if (o == nil || isText(o.MIME)) && (n == nil || isText(n.MIME)) {
+			a, err := decodePayload(o)
+			if err != nil {
+				return nil, err
+			}
+			b, err := decodePayload(n)
+			if err != nil {
+				return nil, err
+			}
+			d.Diff, err = unifiedDiff(a, b, oldVersion+"/"+name,
+				newVersion+"/"+name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fd = append(fd, d)
+	}
+
+	return fd, nil
+}
+
+// diffMetadata returns the metadata streams that changed between two
+// versions.
+func diffMetadata(oldVersion, newVersion string, oldMD, newMD []MetadataStream) ([]MetadataStreamDiff, error) {
+	om := make(map[uint64]string, len(oldMD))
+	for _, v := range oldMD {
+		om[v.ID] = v.Payload
+	}
+	nm := make(map[uint64]string, len(newMD))
+	for _, v := range newMD {
+		nm[v.ID] = v.Payload
+	}
+
+	ids := make([]uint64, 0, len(om)+len(nm))
+	for k := range om {
+		ids = append(ids, k)
+	}
+	for k := range nm {
+		if _, ok := om[k]; !ok {
+			ids = append(ids, k)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	md := make([]MetadataStreamDiff, 0, len(ids))
+	for _, id := range ids {
+		o, oldOK := om[id]
+		n, newOK := nm[id]
+
+		d := MetadataStreamDiff{ID: id}
+		switch {
+		case !oldOK:
+			d.Action = DiffActionAdded
+		case !newOK:
+			d.Action = DiffActionRemoved
+		case o != n:
+			d.Action = DiffActionModified
+		default:
+			// Unchanged
+			continue
+		}
+
+		stream := strconv.FormatUint(id, 10)
+		var err error
+		d.Diff, err = unifiedDiff(o, n, oldVersion+"/"+stream,
+			newVersion+"/"+stream)
+		if err != nil {
+			return nil, err
+		}
+
+		md = append(md, d)
+	}
+
+	return md, nil
+}
+
+// DiffRecords returns the files and metadata streams that changed between
+// two versions of a record.  Unchanged files and streams are omitted.
+func DiffRecords(from, to *Record) ([]FileDiff, []MetadataStreamDiff, error) {
+	fd, err := diffFiles(from.Version, to.Version, from.Files, to.Files)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := diffMetadata(from.Version, to.Version, from.Metadata,
+		to.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fd, md, nil
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func newTextFile(name, digest, content string) File {
+	return File{
+		Name:    name,
+		MIME:    "text/plain; charset=utf-8",
+		Digest:  digest,
+		Payload: base64.StdEncoding.EncodeToString([]byte(content)),
+	}
+}
+
+func TestDiffRecords(t *testing.T) {
+	from := &Record{
+		Version: "1",
+		Metadata: []MetadataStream{
+			{ID: 0, Payload: "same"},
+			{ID: 1, Payload: "old"},
+			{ID: 2, Payload: "removed"},
+		},
+		Files: []File{
+			newTextFile("a", "aa", "line 1\nline 2\n"),
+			newTextFile("b", "bb", "unchanged\n"),
+			newTextFile("c", "cc", "removed\n"),
+			{Name: "d.png", MIME: "image/png", Digest: "dd", Payload: "AA=="},
+		},
+	}
+	to := &Record{
+		Version: "2",
+		Metadata: []MetadataStream{
+			{ID: 0, Payload: "same"},
+			{ID: 1, Payload: "new"},
+			{ID: 3, Payload: "added"},
+		},
+		Files: []File{
+			newTextFile("a", "a2", "line 1\nline two\n"),
+			newTextFile("b", "bb", "unchanged\n"),
+			{Name: "d.png", MIME: "image/png", Digest: "d2", Payload: "AQ=="},
+			newTextFile("e", "ee", "added\n"),
+		},
+	}
+
+	fd, md, err := DiffRecords(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files
+	want := []struct {
+		name   string
+		action DiffActionT
+		diff   bool
+	}{
+		{"a", DiffActionModified, true},
+		{"c", DiffActionRemoved, true},
+		{"d.png", DiffActionModified, false},
+		{"e", DiffActionAdded, true},
+	}
+	if len(fd) != len(want) {
+		t.Fatalf("got %v file diffs, want %v", len(fd), len(want))
+	}
+	for k, v := range want {
+		if fd[k].Name != v.name || fd[k].Action != v.action {
+			t.Fatalf("file %v: got %v %v, want %v %v", k,
+				fd[k].Name, DiffAction[fd[k].Action], v.name,
+				DiffAction[v.action])
+		}
+		if (fd[k].Diff != "") != v.diff {
+			t.Fatalf("file %v: unexpected diff %q", v.name, fd[k].Diff)
+		}
+	}
+	if !strings.Contains(fd[0].Diff, "-line 2\n") ||
+		!strings.Contains(fd[0].Diff, "+line two\n") {
+		t.Fatalf("unexpected diff: %q", fd[0].Diff)
+	}
+	if fd[1].OldDigest != "cc" || fd[1].NewDigest != "" {
+		t.Fatalf("unexpected digests: %v %v", fd[1].OldDigest,
+			fd[1].NewDigest)
+	}
+	if fd[2].OldDigest != "dd" || fd[2].NewDigest != "d2" {
+		t.Fatalf("unexpected digests: %v %v", fd[2].OldDigest,
+			fd[2].NewDigest)
+	}
+
+	// Metadata streams
+	wantMD := []struct {
+		id     uint64
+		action DiffActionT
+	}{
+		{1, DiffActionModified},
+		{2, DiffActionRemoved},
+		{3, DiffActionAdded},
+	}
+	if len(md) != len(wantMD) {
+		t.Fatalf("got %v metadata diffs, want %v", len(md), len(wantMD))
+	}
+	for k, v := range wantMD {
+		if md[k].ID != v.id || md[k].Action != v.action {
+			t.Fatalf("metadata %v: got %v %v, want %v %v", k,
+				md[k].ID, DiffAction[md[k].Action], v.id,
+				DiffAction[v.action])
+		}
+		if md[k].Diff == "" {
+			t.Fatalf("metadata %v: missing diff", v.id)
+		}
+	}
+}
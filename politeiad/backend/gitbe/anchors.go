@@ -172,7 +172,8 @@ func parseAnchorCommit(commit *GitCommit) ([][]byte, []string, error) {
 	var messages []string
 	for _, line := range commit.Message[2 : len(commit.Message)-1] {
 		// The first word is the commit hash. The rest is the one-line commit message.
-		lineParts := strings.SplitN(line, " ", 2)
+		// git log indents the message, so strip the indentation first.
+		lineParts := strings.SplitN(strings.TrimSpace(line), " ", 2)
 		digest, err := hex.DecodeString(lineParts[0])
 		if err != nil {
 			return nil, nil, err
@@ -318,3 +319,49 @@ func (g *gitBackEnd) readUnconfirmedAnchorRecord() (*UnconfirmedAnchor, error) {
 
 	return &ua, nil
 }
+
+// readAnchorRecords retrieves all anchor records from the git log.  The
+// returned map is indexed by the hex encoded Merkle root of the anchors.
+func (g *gitBackEnd) readAnchorRecords() (map[string]*Anchor, error) {
+	// Get the git log
+	gitLog, err := g.gitLog(g.vetted)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anchor confirmations are always more recent than the anchor they
+	// confirm, so they are seen first while iterating over the log.
+	anchors := make(map[string]*Anchor)
+	confirmed := make(map[string]bool)
+	currLine := 0
+	for currLine < len(gitLog) {
+		commit, linesUsed, err := extractCommit(gitLog[currLine:])
+		if err != nil {
+			return nil, err
+		}
+		currLine += linesUsed
+
+		firstLine := commit.Message[0]
+		if regexAnchorConfirmation.MatchString(firstLine) {
+			confirmed[anchorConfirmationMerkle(commit)] = true
+		} else if regexAnchor.MatchString(firstLine) {
+			digests, messages, err := parseAnchorCommit(commit)
+			if err != nil {
+				return nil, err
+			}
+			key := anchorCommitMerkle(commit)
+			anchorType := AnchorUnverified
+			if confirmed[key] {
+				anchorType = AnchorVerified
+			}
+			anchors[key] = &Anchor{
+				Type:     anchorType,
+				Time:     commit.Time,
+				Digests:  digests,
+				Messages: messages,
+			}
+		}
+	}
+
+	return anchors, nil
+}
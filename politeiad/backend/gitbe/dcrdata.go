@@ -0,0 +1,127 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	dcrdataapi "github.com/decred/dcrdata/api/types/v4"
+)
+
+// dcrdata is the subset of the dcrdata API that is required by the decred
+// plugin.  It is satisfied by dcrdataHTTP and by the dcrdatasim simulator.
+type dcrdata interface {
+	// BestBlock returns the best block.
+	BestBlock() (*dcrdataapi.BlockDataBasic, error)
+
+	// Block returns the block at the provided height.
+	Block(height uint32) (*dcrdataapi.BlockDataBasic, error)
+
+	// TicketPool returns the sorted ticket pool as of the block with the
+	// provided hash.
+	TicketPool(hash string) ([]string, error)
+
+	// TrimmedTxs returns the provided transactions in the order in which
+	// they were requested.
+	TrimmedTxs(hashes []string) ([]dcrdataapi.TrimmedTx, error)
+}
+
+// dcrdataHTTP is a dcrdata client that talks to a dcrdata instance.
+type dcrdataHTTP struct {
+	host string // Dcrdata host including the scheme
+}
+
+// decode decodes the reply of a dcrdata request into v.
+func (d *dcrdataHTTP) decode(url string, r *http.Response, v interface{}) error {
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("dcrdata error: %v %v %v",
+				r.StatusCode, url, err)
+		}
+		return fmt.Errorf("dcrdata error: %v %v %s",
+			r.StatusCode, url, body)
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	return decoder.Decode(v)
+}
+
+// get performs a GET request and decodes the reply into v.
+func (d *dcrdataHTTP) get(route string, v interface{}) error {
+	url := d.host + route
+	log.Debugf("connecting to %v", url)
+	// XXX this http command needs a reasonable timeout.
+	r, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	return d.decode(url, r, v)
+}
+
+// BestBlock satisfies the dcrdata interface.
+func (d *dcrdataHTTP) BestBlock() (*dcrdataapi.BlockDataBasic, error) {
+	var bdb dcrdataapi.BlockDataBasic
+	err := d.get("/api/block/best", &bdb)
+	if err != nil {
+		return nil, err
+	}
+	return &bdb, nil
+}
+
+// Block satisfies the dcrdata interface.
+func (d *dcrdataHTTP) Block(height uint32) (*dcrdataapi.BlockDataBasic, error) {
+	h := strconv.FormatUint(uint64(height), 10)
+	var bdb dcrdataapi.BlockDataBasic
+	err := d.get("/api/block/"+h, &bdb)
+	if err != nil {
+		return nil, err
+	}
+	return &bdb, nil
+}
+
+// TicketPool satisfies the dcrdata interface.
+func (d *dcrdataHTTP) TicketPool(hash string) ([]string, error) {
+	var tickets []string
+	err := d.get("/api/stake/pool/b/"+hash+"/full?sort=true", &tickets)
+	if err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// TrimmedTxs satisfies the dcrdata interface.
+func (d *dcrdataHTTP) TrimmedTxs(hashes []string) ([]dcrdataapi.TrimmedTx, error) {
+	// Request body is dcrdataapi.Txns marshalled to JSON
+	reqBody, err := json.Marshal(dcrdataapi.Txns{
+		Transactions: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the POST request
+	url := d.host + "/api/txs/trimmed"
+	log.Debugf("connecting to %v", url)
+	r, err := http.Post(url, "application/json; charset=utf-8",
+		bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var ttx []dcrdataapi.TrimmedTx
+	err = d.decode(url, r, &ttx)
+	if err != nil {
+		return nil, err
+	}
+	return ttx, nil
+}
@@ -14,9 +14,9 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -25,19 +25,19 @@ import (
 	"github.com/decred/dcrd/dcrec/secp256k1"
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrd/wire"
-	dcrdataapi "github.com/decred/dcrdata/api/types/v4"
 	"github.com/thi4go/politeia/decredplugin"
 	"github.com/thi4go/politeia/politeiad/api/v1/identity"
 	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/dcrdatasim"
 	"github.com/thi4go/politeia/util"
 )
 
-// XXX plugins really need to become an interface. Run with this for now.
-
 const (
 	decredPluginIdentity  = "fullidentity"
 	decredPluginJournals  = "journals"
 	decredPluginInventory = "inventory"
+	decredPluginDcrdata   = "dcrdata"
+	decredPluginSim       = "dcrdatasim"
 
 	defaultCommentIDFilename = "commentid.txt"
 	defaultCommentFilename   = "comments.journal"
@@ -52,9 +52,6 @@ const (
 	journalActionAddLike = "addlike" // Add comment like
 
 	flushRecordVersion = "1" // Version 1 of the flush journal
-
-	// Following are what should be well-known interface hooks
-	PluginPostHookEdit = "postedit" // Hook Post Edit
 )
 
 var (
@@ -111,8 +108,7 @@ func decodeCastVoteJournal(payload []byte) (*CastVoteJournal, error) {
 }
 
 var (
-	decredPluginSettings map[string]string             // [key]setting
-	decredPluginHooks    map[string]func(string) error // [key]func(token) error
+	decredPluginSettings = make(map[string]string) // [key]setting
 
 	// Cached values, requires lock. These caches are lazy loaded.
 	decredPluginVoteCache         = make(map[string]decredplugin.StartVote)      // [token]StartVote
@@ -159,21 +155,40 @@ func init() {
 	if err != nil {
 		panic(err.Error())
 	}
+
+	backend.RegisterPlugin(decredplugin.ID, newDecredPlugin)
+}
+
+// decredClient is the gitbe implementation of the decred plugin.  It
+// satisfies the backend.PluginClient interface.
+type decredClient struct {
+	g *gitBackEnd
 }
 
-func getDecredPlugin(dcrdataHost string) backend.Plugin {
+// newDecredPlugin returns the decred plugin.  The plugin only works with the
+// git backend.
+func newDecredPlugin(b backend.Backend, settings []backend.PluginSetting) (*backend.Plugin, error) {
+	g, ok := b.(*gitBackEnd)
+	if !ok {
+		return nil, fmt.Errorf("decred plugin requires the git backend")
+	}
+
 	decredPlugin := backend.Plugin{
 		ID:       decredplugin.ID,
 		Version:  decredplugin.Version,
 		Settings: []backend.PluginSetting{},
+		Client:   &decredClient{g: g},
 	}
 
-	decredPlugin.Settings = append(decredPlugin.Settings,
-		backend.PluginSetting{
-			Key:   "dcrdata",
-			Value: dcrdataHost,
-		},
-	)
+	for _, v := range settings {
+		switch v.Key {
+		case decredPluginDcrdata, decredPluginSim:
+		default:
+			return nil, fmt.Errorf("invalid decred plugin setting: %v",
+				v.Key)
+		}
+		decredPlugin.Settings = append(decredPlugin.Settings, v)
+	}
 
 	// This setting is used to tell politeiad how to retrieve the
 	// decred plugin data that is required to build the external
@@ -184,15 +199,95 @@ func getDecredPlugin(dcrdataHost string) backend.Plugin {
 			Value: decredplugin.CmdInventory,
 		})
 
-	// Initialize hooks
-	decredPluginHooks = make(map[string]func(string) error)
-
 	// Initialize settings map
-	decredPluginSettings = make(map[string]string)
 	for _, v := range decredPlugin.Settings {
-		decredPluginSettings[v.Key] = v.Value
+		setDecredPluginSetting(v.Key, v.Value)
+	}
+	setDecredPluginSetting(decredPluginJournals, g.journals)
+
+	// Use the dcrdata simulator when a fixture was provided.  This allows
+	// the voting flows to be tested without a live dcrdata.
+	if fixture, ok := decredPluginSettings[decredPluginSim]; ok {
+		sim, err := dcrdatasim.Load(util.CleanAndExpandPath(fixture))
+		if err != nil {
+			return nil, fmt.Errorf("dcrdatasim: %v", err)
+		}
+		log.Infof("Dcrdata simulator: %v", fixture)
+		g.dcrdata = sim
+	} else {
+		g.dcrdata = &dcrdataHTTP{
+			host: decredPluginSettings[decredPluginDcrdata],
+		}
+	}
+
+	return &decredPlugin, nil
+}
+
+// Setup creates the journals and replays them.
+//
+// Setup satisfies the backend.PluginClient interface.
+func (c *decredClient) Setup() error {
+	log.Infof("Journals directory: %v", c.g.journals)
+	err := os.MkdirAll(c.g.journals, 0760)
+	if err != nil {
+		return err
+	}
+
+	c.g.journal = NewJournal()
+
+	// this function must be called after g.journal is created
+	return c.g.initDecredPluginJournals()
+}
+
+// Cmd executes a decred plugin command.
+//
+// Cmd satisfies the backend.PluginClient interface.
+func (c *decredClient) Cmd(command, payload string) (string, error) {
+	switch command {
+	case decredplugin.CmdAuthorizeVote:
+		return c.g.pluginAuthorizeVote(payload)
+	case decredplugin.CmdStartVote:
+		return c.g.pluginStartVote(payload)
+	case decredplugin.CmdBallot:
+		return c.g.pluginBallot(payload)
+	case decredplugin.CmdProposalVotes:
+		return c.g.pluginProposalVotes(payload)
+	case decredplugin.CmdBestBlock:
+		return c.g.pluginBestBlock()
+	case decredplugin.CmdNewComment:
+		return c.g.pluginNewComment(payload)
+	case decredplugin.CmdLikeComment:
+		return c.g.pluginLikeComment(payload)
+	case decredplugin.CmdCensorComment:
+		return c.g.pluginCensorComment(payload)
+	case decredplugin.CmdGetComments:
+		return c.g.pluginGetComments(payload)
+	case decredplugin.CmdProposalCommentsLikes:
+		return c.g.pluginGetProposalCommentsLikes(payload)
+	case decredplugin.CmdInventory:
+		return c.g.pluginInventory()
+	case decredplugin.CmdLoadVoteResults:
+		return c.g.pluginLoadVoteResults()
+	case backend.PluginCmdExport:
+		return c.g.pluginExport(payload)
+	}
+	return "", backend.ErrPluginCmdInvalid
+}
+
+// Hook flushes the comment journal of a record into the record when the
+// record is edited.
+//
+// Hook satisfies the backend.PluginClient interface.
+func (c *decredClient) Hook(h backend.HookT, payload string) error {
+	if h != backend.HookPostEditRecord {
+		return nil
 	}
-	return decredPlugin
+	var hook backend.HookEditRecord
+	err := json.Unmarshal([]byte(payload), &hook)
+	if err != nil {
+		return err
+	}
+	return c.g.decredPluginPostEdit(hook.Token)
 }
 
 // initDecredPlugin is called externally to run initial procedures
@@ -247,10 +342,6 @@ func setDecredPluginSetting(key, value string) {
 	decredPluginSettings[key] = value
 }
 
-func setDecredPluginHook(name string, f func(string) error) {
-	decredPluginHooks[name] = f
-}
-
 func (g *gitBackEnd) unvettedPropExists(token string) bool {
 	tokenb, err := util.ConvertStringToken(token)
 	if err != nil {
@@ -388,141 +479,17 @@ func (g *gitBackEnd) verifyMessage(address, message, signature string) (bool, er
 	return a.EncodeAddress() == address, nil
 }
 
-func bestBlock() (*dcrdataapi.BlockDataBasic, error) {
-	url := decredPluginSettings["dcrdata"] + "/api/block/best"
-	log.Debugf("connecting to %v", url)
-	// XXX this http command needs a reasonable timeout.
-	r, err := http.Get(url)
-	log.Debugf("http connecting to %v", url)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	var bdb dcrdataapi.BlockDataBasic
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&bdb); err != nil {
-		return nil, err
-	}
-
-	return &bdb, nil
-}
-
-func block(block uint32) (*dcrdataapi.BlockDataBasic, error) {
-	h := strconv.FormatUint(uint64(block), 10)
-	url := decredPluginSettings["dcrdata"] + "/api/block/" + h
-	log.Debugf("connecting to %v", url)
-	r, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	var bdb dcrdataapi.BlockDataBasic
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&bdb); err != nil {
-		return nil, err
-	}
-
-	return &bdb, nil
-}
-
-func snapshot(hash string) ([]string, error) {
-	url := decredPluginSettings["dcrdata"] + "/api/stake/pool/b/" + hash +
-		"/full?sort=true"
-	log.Debugf("connecting to %v", url)
-	r, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	var tickets []string
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&tickets); err != nil {
-		return nil, err
-	}
-
-	return tickets, nil
-}
-
-func batchTransactions(hashes []string) ([]dcrdataapi.TrimmedTx, error) {
-	// Request body is dcrdataapi.Txns marshalled to JSON
-	reqBody, err := json.Marshal(dcrdataapi.Txns{
-		Transactions: hashes,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	// Make the POST request
-	url := decredPluginSettings["dcrdata"] + "/api/txs/trimmed"
-	log.Debugf("connecting to %v", url)
-	r, err := http.Post(url, "application/json; charset=utf-8",
-		bytes.NewReader(reqBody))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Body.Close()
-
-	if r.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("dcrdata error: %v %v %v",
-				r.StatusCode, url, err)
-		}
-		return nil, fmt.Errorf("dcrdata error: %v %v %s",
-			r.StatusCode, url, body)
-	}
-
-	// Unmarshal the response
-	var ttx []dcrdataapi.TrimmedTx
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&ttx); err != nil {
-		return nil, err
-	}
-	return ttx, nil
-}
-
 // largestCommitmentResult returns the largest commitment address or an error.
 type largestCommitmentResult struct {
 	bestAddr string
 	err      error
 }
 
-func largestCommitmentAddresses(hashes []string) ([]largestCommitmentResult, error) {
+// largestCommitmentAddresses returns the largest commitment address of every
+// provided ticket.
+func (g *gitBackEnd) largestCommitmentAddresses(hashes []string) ([]largestCommitmentResult, error) {
 	// Batch request all of the transaction info from dcrdata.
-	ttxs, err := batchTransactions(hashes)
+	ttxs, err := g.dcrdata.TrimmedTxs(hashes)
 	if err != nil {
 		return nil, err
 	}
@@ -562,7 +529,7 @@ func largestCommitmentAddresses(hashes []string) ([]largestCommitmentResult, err
 
 // pluginBestBlock returns current best block height from wallet.
 func (g *gitBackEnd) pluginBestBlock() (string, error) {
-	bb, err := bestBlock()
+	bb, err := g.dcrdata.BestBlock()
 	if err != nil {
 		return "", err
 	}
@@ -740,6 +707,7 @@ func (g *gitBackEnd) flushCommentJournals() error {
 	// We may have to make this more granular
 	g.Lock()
 	defer g.Unlock()
+	defer observeFlush("comments", time.Now())
 
 	// git checkout master
 	err := g.gitCheckout(g.unvetted, "master")
@@ -915,6 +883,7 @@ func (g *gitBackEnd) flushVoteJournals() error {
 	// We may have to make this more granular
 	g.Lock()
 	defer g.Unlock()
+	defer observeFlush("votes", time.Now())
 
 	// git checkout master
 	err := g.gitCheckout(g.unvetted, "master")
@@ -998,6 +967,11 @@ func (g *gitBackEnd) flushVoteJournals() error {
 	return nil
 }
 func (g *gitBackEnd) decredPluginJournalFlusher() {
+	// Nothing to do when the decred plugin is not registered
+	if g.journal == nil {
+		return
+	}
+
 	// XXX make this a single PR instead of 2 to save some git time
 	err := g.flushCommentJournals()
 	if err != nil {
@@ -1657,7 +1631,7 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 	}
 
 	// 1. Get best block
-	bb, err := bestBlock()
+	bb, err := g.dcrdata.BestBlock()
 	if err != nil {
 		return "", fmt.Errorf("bestBlock %v", err)
 	}
@@ -1666,13 +1640,13 @@ func (g *gitBackEnd) pluginStartVote(payload string) (string, error) {
 	}
 	// 2. Subtract TicketMaturity from block height to get into
 	// unforkable teritory
-	snapshotBlock, err := block(bb.Height -
+	snapshotBlock, err := g.dcrdata.Block(bb.Height -
 		uint32(g.activeNetParams.TicketMaturity))
 	if err != nil {
 		return "", fmt.Errorf("bestBlock %v", err)
 	}
 	// 3. Get ticket pool snapshot
-	snapshot, err := snapshot(snapshotBlock.Hash)
+	snapshot, err := g.dcrdata.TicketPool(snapshotBlock.Hash)
 	if err != nil {
 		return "", fmt.Errorf("snapshot %v", err)
 	}
@@ -2169,7 +2143,7 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 	}
 
 	// Get best block
-	bb, err := bestBlock()
+	bb, err := g.dcrdata.BestBlock()
 	if err != nil {
 		return "", fmt.Errorf("bestBlock %v", err)
 	}
@@ -2180,7 +2154,7 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 	for _, v := range ballot.Votes {
 		tickets = append(tickets, v.Ticket)
 	}
-	ticketAddresses, err := largestCommitmentAddresses(tickets)
+	ticketAddresses, err := g.largestCommitmentAddresses(tickets)
 	if err != nil {
 		return "", err
 	}
@@ -2325,6 +2299,24 @@ func (g *gitBackEnd) pluginBallot(payload string) (string, error) {
 //
 // Function must be called WITH the lock held.
 func (g *gitBackEnd) tallyVotes(token string) ([]decredplugin.CastVote, error) {
+	cvj, err := g.replayCastVoteJournal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	cv := make([]decredplugin.CastVote, 0, len(cvj))
+	for _, v := range cvj {
+		cv = append(cv, v.CastVote)
+	}
+
+	return cv, nil
+}
+
+// replayCastVoteJournal replays the ballot journal for a proposal and returns
+// all cast votes along with their receipts in the order they were received.
+//
+// Function must be called WITH the lock held.
+func (g *gitBackEnd) replayCastVoteJournal(token string) ([]CastVoteJournal, error) {
 	// Do some cheap things before expensive calls
 	bfilename := pijoin(g.journals, token, defaultBallotFilename)
 
@@ -2334,7 +2326,7 @@ func (g *gitBackEnd) tallyVotes(token string) ([]decredplugin.CastVote, error) {
 		if !os.IsNotExist(err) {
 			return nil, fmt.Errorf("journal.Open: %v", err)
 		}
-		return []decredplugin.CastVote{}, nil
+		return []CastVoteJournal{}, nil
 	}
 	defer func() {
 		err = g.journal.Close(bfilename)
@@ -2343,7 +2335,7 @@ func (g *gitBackEnd) tallyVotes(token string) ([]decredplugin.CastVote, error) {
 		}
 	}()
 
-	cv := make([]decredplugin.CastVote, 0, 41000)
+	cv := make([]CastVoteJournal, 0, 41000)
 	for {
 		err = g.journal.Replay(bfilename, func(s string) error {
 			ss := bytes.NewReader([]byte(s))
@@ -2364,7 +2356,7 @@ func (g *gitBackEnd) tallyVotes(token string) ([]decredplugin.CastVote, error) {
 					return fmt.Errorf("journal add: %v",
 						err)
 				}
-				cv = append(cv, cvj.CastVote)
+				cv = append(cv, cvj)
 
 			default:
 				return fmt.Errorf("invalid action: %v",
@@ -2432,6 +2424,67 @@ func (g *gitBackEnd) pluginProposalVotes(payload string) (string, error) {
 	return string(reply), nil
 }
 
+// pluginExport returns all decred plugin data of a vetted proposal so that it
+// can be included in a record bundle.
+func (g *gitBackEnd) pluginExport(payload string) (string, error) {
+	log.Tracef("pluginExport: %v", payload)
+
+	// Check if journals were replayed
+	if !journalsReplayed {
+		return "", backend.ErrJournalsNotReplayed
+	}
+
+	e, err := decredplugin.DecodeExport([]byte(payload))
+	if err != nil {
+		return "", fmt.Errorf("DecodeExport: %v", err)
+	}
+
+	// Verify proposal exists, we can run this lockless
+	if !g.vettedPropExists(e.Token) {
+		return "", fmt.Errorf("proposal not found: %v", e.Token)
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if g.shutdown {
+		return "", backend.ErrShutdown
+	}
+
+	// Comments are sorted by ID so that exports are deterministic.
+	comments := decredPluginCommentsCache[e.Token]
+	er := decredplugin.ExportReply{
+		Comments: make([]decredplugin.Comment, 0, len(comments)),
+	}
+	for _, v := range comments {
+		er.Comments = append(er.Comments, v)
+	}
+	sort.Slice(er.Comments, func(i, j int) bool {
+		a, _ := strconv.Atoi(er.Comments[i].CommentID)
+		b, _ := strconv.Atoi(er.Comments[j].CommentID)
+		return a < b
+	})
+
+	cvj, err := g.replayCastVoteJournal(e.Token)
+	if err != nil {
+		return "", fmt.Errorf("replayCastVoteJournal: %v", err)
+	}
+	er.CastVotes = make([]decredplugin.CastVoteDetails, 0, len(cvj))
+	for _, v := range cvj {
+		er.CastVotes = append(er.CastVotes, decredplugin.CastVoteDetails{
+			CastVote: v.CastVote,
+			Receipt:  v.Receipt,
+		})
+	}
+
+	reply, err := decredplugin.EncodeExportReply(er)
+	if err != nil {
+		return "", fmt.Errorf("EncodeExportReply: %v", err)
+	}
+
+	return string(reply), nil
+}
+
 // pluginInventory returns the decred plugin inventory for all proposals.  The
 // inventory consists of comments, like comments, vote authorizations, vote
 // details, and cast votes.
@@ -0,0 +1,402 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/slog"
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/api/v1/mime"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/dcrdatasim"
+	"github.com/thi4go/politeia/util"
+)
+
+// testTicket is a simulated ticket along with the key of its largest
+// commitment address.
+type testTicket struct {
+	hash    string
+	address string
+	key     *secp256k1.PrivateKey
+}
+
+func newTestTicket(t *testing.T, i int) testTicket {
+	t.Helper()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := dcrutil.NewAddressSecpPubKey(
+		key.PubKey().SerializeCompressed(), &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := chainhash.HashH([]byte("ticket" + strconv.Itoa(i)))
+	return testTicket{
+		hash:    h.String(),
+		address: addr.EncodeAddress(),
+		key:     key,
+	}
+}
+
+// vote returns a cast vote that is signed by the commitment address key of
+// the ticket.
+func (tt testTicket) vote(t *testing.T, token, voteBit string) decredplugin.CastVote {
+	t.Helper()
+
+	msg := token + tt.hash + voteBit
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, msg)
+	sig, err := secp256k1.SignCompact(tt.key, chainhash.HashB(buf.Bytes()),
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decredplugin.CastVote{
+		Token:     token,
+		Ticket:    tt.hash,
+		VoteBit:   voteBit,
+		Signature: hex.EncodeToString(sig),
+	}
+}
+
+func pluginCmd(t *testing.T, g *gitBackEnd, cmd string, payload []byte) string {
+	t.Helper()
+
+	_, reply, err := g.Plugin(decredplugin.ID, cmd, string(payload))
+	if err != nil {
+		t.Fatalf("%v: %v", cmd, err)
+	}
+	return reply
+}
+
+func TestVoteWithDcrdataSimulator(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Script a chain with a ticket pool of three tickets.  The fourth
+	// ticket has a commitment address but is not part of the pool.
+	tickets := make([]testTicket, 4)
+	commitments := make(map[string]string, len(tickets))
+	for i := range tickets {
+		tickets[i] = newTestTicket(t, i)
+		commitments[tickets[i].hash] = tickets[i].address
+	}
+	params := &chaincfg.TestNet3Params
+	best := uint32(params.TicketMaturity) + 100
+	fixture := dcrdatasim.Fixture{
+		BestBlock: best,
+		Blocks: []dcrdatasim.Block{{
+			Height: 10,
+			TicketPool: []string{tickets[0].hash, tickets[1].hash,
+				tickets[2].hash},
+		}},
+		Commitments: commitments,
+	}
+	fb, err := json.Marshal(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtureFile := filepath.Join(dir, "dcrdatasim.json")
+	err = ioutil.WriteFile(fixtureFile, fb, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := New(params, filepath.Join(dir, "data"),
+		util.NewLocalTimestamper(0), "", fi, testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	g.test = true
+
+	p, err := backend.NewPlugin(decredplugin.ID, g, []backend.PluginSetting{{
+		Key:   decredPluginSim,
+		Value: fixtureFile,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = g.RegisterPlugin(*p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim, ok := g.dcrdata.(*dcrdatasim.Simulator)
+	if !ok {
+		t.Fatalf("dcrdata simulator not used: %T", g.dcrdata)
+	}
+
+	// Create and publish a record
+	payload := "this is a proposal"
+	rm, err := g.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{{
+		Name:    "index.md",
+		MIME:    mime.DetectMimeType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := rm.Token
+	tokenb, err := util.ConvertStringToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.SetUnvettedStatus(tokenb, backend.MDStatusVetted, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Authorize and start the vote
+	user, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	av, err := decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+		Action:    decredplugin.AuthVoteActionAuthorize,
+		Token:     token,
+		Signature: "signature",
+		PublicKey: hex.EncodeToString(user.Public.Key[:]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pluginCmd(t, g, decredplugin.CmdAuthorizeVote, av)
+
+	vote := decredplugin.VoteV2{
+		Token:            token,
+		ProposalVersion:  1,
+		Type:             decredplugin.VoteTypeStandard,
+		Mask:             0x03,
+		Duration:         decredplugin.VoteDurationMin,
+		QuorumPercentage: 20,
+		PassPercentage:   60,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Description: "no", Bits: 0x01},
+			{Id: "yes", Description: "yes", Bits: 0x02},
+		},
+	}
+	vb, err := json.Marshal(vote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := user.SignMessage([]byte(hex.EncodeToString(util.Digest(vb))))
+	sv, err := decredplugin.EncodeStartVoteV2(decredplugin.StartVoteV2{
+		PublicKey: hex.EncodeToString(user.Public.Key[:]),
+		Vote:      vote,
+		Signature: hex.EncodeToString(sig[:]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr, err := decredplugin.DecodeStartVoteReply([]byte(
+		pluginCmd(t, g, decredplugin.CmdStartVote, sv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotHeight := strconv.FormatUint(uint64(best)-
+		uint64(params.TicketMaturity), 10)
+	if svr.StartBlockHeight != snapshotHeight {
+		t.Fatalf("start block height got %v, want %v",
+			svr.StartBlockHeight, snapshotHeight)
+	}
+	if len(svr.EligibleTickets) != 3 {
+		t.Fatalf("eligible tickets got %v, want 3",
+			len(svr.EligibleTickets))
+	}
+
+	// Cast a ballot
+	ballot, err := decredplugin.EncodeBallot(decredplugin.Ballot{
+		Votes: []decredplugin.CastVote{
+			tickets[0].vote(t, token, "2"),
+			tickets[1].vote(t, token, "2"),
+			tickets[2].vote(t, token, "1"),
+			tickets[3].vote(t, token, "1"), // Ineligible
+			tickets[0].vote(t, token, "1"), // Duplicate
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err := decredplugin.DecodeBallotReply([]byte(
+		pluginCmd(t, g, decredplugin.CmdBallot, ballot)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []decredplugin.ErrorStatusT{
+		decredplugin.ErrorStatusInvalid,
+		decredplugin.ErrorStatusInvalid,
+		decredplugin.ErrorStatusInvalid,
+		decredplugin.ErrorStatusIneligibleTicket,
+		decredplugin.ErrorStatusDuplicateVote,
+	}
+	for i, v := range br.Receipts {
+		if v.ErrorStatus != want[i] {
+			t.Fatalf("vote %v: got %v (%v), want %v", i,
+				v.ErrorStatus, v.Error, want[i])
+		}
+	}
+
+	// Votes are rejected once the vote has ended
+	sim.Mine(decredplugin.VoteDurationMin)
+	ballot, err = decredplugin.EncodeBallot(decredplugin.Ballot{
+		Votes: []decredplugin.CastVote{tickets[2].vote(t, token, "2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	br, err = decredplugin.DecodeBallotReply([]byte(
+		pluginCmd(t, g, decredplugin.CmdBallot, ballot)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if br.Receipts[0].ErrorStatus != decredplugin.ErrorStatusVoteHasEnded {
+		t.Fatalf("vote after end got %v, want %v",
+			br.Receipts[0].ErrorStatus,
+			decredplugin.ErrorStatusVoteHasEnded)
+	}
+
+	// Tally the votes
+	vr, err := decredplugin.EncodeVoteResults(decredplugin.VoteResults{
+		Token: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vrr, err := decredplugin.DecodeVoteResultsReply([]byte(
+		pluginCmd(t, g, decredplugin.CmdProposalVotes, vr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tally := make(map[string]int)
+	for _, v := range vrr.CastVotes {
+		tally[v.VoteBit]++
+	}
+	if len(vrr.CastVotes) != 3 || tally["2"] != 2 || tally["1"] != 1 {
+		t.Fatalf("unexpected tally: %v", tally)
+	}
+
+	// The export must contain every cast vote along with a receipt that
+	// was signed by the server.
+	ex, err := decredplugin.EncodeExport(decredplugin.Export{
+		Token: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reply, err := g.Plugin(decredplugin.ID, backend.PluginCmdExport,
+		string(ex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	er, err := decredplugin.DecodeExportReply([]byte(reply))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(er.CastVotes) != 3 {
+		t.Fatalf("exported votes got %v, want 3", len(er.CastVotes))
+	}
+	for _, v := range er.CastVotes {
+		receipt, err := util.ConvertSignature(v.Receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.Public.VerifyMessage([]byte(v.CastVote.Signature),
+			receipt) {
+			t.Fatalf("invalid receipt for ticket %v",
+				v.CastVote.Ticket)
+		}
+	}
+}
+
+func TestDcrdataHTTP(t *testing.T) {
+	ticket := chainhash.HashH([]byte("ticket")).String()
+	sim, err := dcrdatasim.New(dcrdatasim.Fixture{
+		BestBlock: 20,
+		Blocks: []dcrdatasim.Block{{
+			Height:     5,
+			TicketPool: []string{ticket},
+		}},
+		Commitments: map[string]string{ticket: "TsAddress"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(sim)
+	defer s.Close()
+
+	// The http client must return the same chain as the simulator.
+	var d dcrdata = &dcrdataHTTP{host: s.URL}
+	bb, err := d.BestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bb.Height != 20 {
+		t.Fatalf("best block got %v, want 20", bb.Height)
+	}
+	b, err := d.Block(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := d.TicketPool(b.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool) != 1 || pool[0] != ticket {
+		t.Fatalf("unexpected ticket pool: %v", pool)
+	}
+	ttxs, err := d.TrimmedTxs([]string{ticket, "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ttxs) != 2 {
+		t.Fatalf("trimmed txs got %v, want 2", len(ttxs))
+	}
+	addrs := make([]string, 0, 1)
+	for _, v := range ttxs {
+		for _, vout := range v.Vout {
+			addrs = append(addrs, vout.ScriptPubKeyDecoded.Addresses...)
+		}
+	}
+	if len(addrs) != 1 || addrs[0] != "TsAddress" {
+		t.Fatalf("unexpected commitment addresses: %v", addrs)
+	}
+	_, err = d.Block(21)
+	if err == nil {
+		t.Fatalf("expected error for block above best block")
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // gitError contains all the components of a git invocation.
@@ -85,7 +86,9 @@ func (g *gitBackEnd) git(path string, args ...string) ([]string, error) {
 		cmd.Dir = path
 	}
 
+	start := time.Now()
 	doneError := cmd.Run()
+	gitDuration.WithLabelValues(args[0]).Observe(time.Since(start).Seconds())
 
 	// Prepare output
 	var err error
@@ -169,6 +172,22 @@ func (g *gitBackEnd) gitBranchDelete(path, branch string) error {
 	return err
 }
 
+func (g *gitBackEnd) gitBranchRename(path, from, to string) error {
+	_, err := g.git(path, "branch", "-m", from, to)
+	return err
+}
+
+// gitPrune expires the reflog and prunes all unreachable objects.  It is used
+// to get rid of content that is no longer referenced by any branch.
+func (g *gitBackEnd) gitPrune(path string) error {
+	_, err := g.git(path, "reflog", "expire", "--expire=now", "--all")
+	if err != nil {
+		return err
+	}
+	_, err = g.git(path, "gc", "--prune=now")
+	return err
+}
+
 func (g *gitBackEnd) gitClean(path string) error {
 	_, err := g.git(path, "clean", "-xdf")
 	return err
@@ -288,6 +307,30 @@ func (g *gitBackEnd) gitLastDigest(path string) ([]byte, error) {
 	return d, nil
 }
 
+// gitFirstDigest returns the digest of the oldest commit that touched the
+// provided file or directory.
+func (g *gitBackEnd) gitFirstDigest(path, filename string) ([]byte, error) {
+	out, err := g.git(path, "log", "--reverse", "--pretty=format:%H", "--",
+		filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no commits for %v", filename)
+	}
+
+	d, err := hex.DecodeString(out[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d) != sha1.Size {
+		return nil, fmt.Errorf("invalid sha1 size")
+	}
+
+	return d, nil
+}
+
 func (g *gitBackEnd) gitLog(path string) ([]string, error) {
 	out, err := g.git(path, "log")
 	if err != nil {
@@ -17,6 +17,8 @@ import (
 	"testing"
 
 	"github.com/decred/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 type testWriter struct {
@@ -280,3 +282,40 @@ func TestFsck(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// gitCommands returns the number of recorded durations of a git command.
+func gitCommands(t *testing.T, command string) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	err := gitDuration.WithLabelValues(command).(prometheus.Metric).Write(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestGitDuration(t *testing.T) {
+	g := newGitBackEnd()
+	defer os.RemoveAll(g.root)
+
+	versions := gitCommands(t, "version")
+	logs := gitCommands(t, "log")
+
+	_, err := g.gitVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Failed commands are recorded as well
+	_, err = g.gitLog(g.root)
+	if err == nil {
+		t.Fatal("log outside of a repo should fail")
+	}
+
+	if n := gitCommands(t, "version") - versions; n != 1 {
+		t.Errorf("got %v version durations, want 1", n)
+	}
+	if n := gitCommands(t, "log") - logs; n != 1 {
+		t.Errorf("got %v log durations, want 1", n)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"bytes"
 	"crypto/sha1"
 	"crypto/sha256"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -30,12 +29,10 @@ import (
 	"github.com/thi4go/politeia/decredplugin"
 	pd "github.com/thi4go/politeia/politeiad/api/v1"
 	"github.com/thi4go/politeia/politeiad/api/v1/identity"
-	"github.com/thi4go/politeia/politeiad/api/v1/mime"
 	"github.com/thi4go/politeia/politeiad/backend"
 	"github.com/thi4go/politeia/util"
 	filesystem "github.com/otiai10/copy"
 	"github.com/robfig/cron"
-	"github.com/subosito/gozaru"
 )
 
 const (
@@ -71,6 +68,10 @@ const (
 	// defaultPayloadDir is the default path to store a record payload.
 	defaultPayloadDir = "payload"
 
+	// defaultTombstoneFilename is the filename of the tombstone that
+	// replaces the payload of a purged record.
+	defaultTombstoneFilename = "tombstone.json"
+
 	// anchorSchedule determines how often we anchor the vetted repo.
 	// Seconds Minutes Hours Days Months DayOfWeek
 	anchorSchedule = "0 58 * * * *" // At 58 minutes every hour
@@ -108,6 +109,32 @@ type file struct {
 	name    string // Basename of the file
 	digest  []byte // SHA256 of payload
 	payload []byte // Actual file payload
+	path    string // Path of the payload on disk, used instead of payload
+}
+
+// writeFile writes the payload of a cooked file to filename.  Payloads that
+// are on disk are copied without reading them into memory.
+func writeFile(filename string, f file) error {
+	if f.path == "" {
+		return ioutil.WriteFile(filename, f.payload, 0664)
+	}
+
+	src, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		0664)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
 }
 
 // gitBackEnd is a git based backend context that satisfies the backend
@@ -122,13 +149,14 @@ type gitBackEnd struct {
 	unvetted        string           // Unvettend content
 	vetted          string           // Vetted, public, visible content
 	journals        string           // Journals/cache
-	dcrtimeHost     string           // Dcrtimed host
+	timestamper     util.Timestamper // Dcrtime client
 	gitPath         string           // Path to git
 	gitTrace        bool             // Enable git tracing
 	test            bool             // Set during UT
 	exit            chan struct{}    // Close channel
 	checkAnchor     chan struct{}    // Work notification
-	plugins         []backend.Plugin // Plugins
+	plugins         backend.Plugins  // Plugins
+	dcrdata         dcrdata          // Dcrdata client, set by the decred plugin
 
 	// The following items are used for testing only
 	testAnchors map[string]bool // [digest]anchored
@@ -259,163 +287,19 @@ func extendSHA1FromString(s string) (string, error) {
 // verifyContent verifies that all provided backend.MetadataStream and
 // backend.File are sane and returns a cooked array of the files.
 func verifyContent(metadata []backend.MetadataStream, files []backend.File, filesDel []string) ([]file, error) {
-	// Make sure all metadata is within maxima.
-	for _, v := range metadata {
-		if v.ID > pd.MetadataStreamsMax-1 {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidMDID,
-				ErrorContext: []string{
-					strconv.FormatUint(v.ID, 10),
-				},
-			}
-		}
-	}
-	for i := range metadata {
-		for j := range metadata {
-			// Skip self and non duplicates.
-			if i == j || metadata[i].ID != metadata[j].ID {
-				continue
-			}
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusDuplicateMDID,
-				ErrorContext: []string{
-					strconv.FormatUint(metadata[i].ID, 10),
-				},
-			}
-		}
-	}
-
-	// Prevent paths
-	for i := range files {
-		if filepath.Base(files[i].Name) != files[i].Name {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFilename,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-	}
-	for _, v := range filesDel {
-		if filepath.Base(v) != v {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFilename,
-				ErrorContext: []string{
-					v,
-				},
-			}
-		}
-	}
-
-	// Now check files
-	if len(files) == 0 {
-		return nil, backend.ContentVerificationError{
-			ErrorCode: pd.ErrorStatusEmpty,
-		}
-	}
-
-	// Prevent bad filenames and duplicate filenames
-	for i := range files {
-		for j := range files {
-			if i == j {
-				continue
-			}
-			if files[i].Name == files[j].Name {
-				return nil, backend.ContentVerificationError{
-					ErrorCode: pd.ErrorStatusDuplicateFilename,
-					ErrorContext: []string{
-						files[i].Name,
-					},
-				}
-			}
-		}
-		// Check against filesDel
-		for _, v := range filesDel {
-			if files[i].Name == v {
-				return nil, backend.ContentVerificationError{
-					ErrorCode: pd.ErrorStatusDuplicateFilename,
-					ErrorContext: []string{
-						files[i].Name,
-					},
-				}
-			}
-		}
+	vf, err := backend.VerifyContent(metadata, files, filesDel)
+	if err != nil {
+		return nil, err
 	}
 
-	fa := make([]file, 0, len(files))
-	for i := range files {
-		if gozaru.Sanitize(files[i].Name) != files[i].Name {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFilename,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-
-		// Validate digest
-		d, ok := util.ConvertDigest(files[i].Digest)
-		if !ok {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFileDigest,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-
-		// Setup cooked file.
-		f := file{
-			name: files[i].Name,
-		}
-
-		// Decode base64 payload
-		var err error
-		f.payload, err = base64.StdEncoding.DecodeString(files[i].Payload)
-		if err != nil {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidBase64,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-
-		// Calculate payload digest
-		dp := util.Digest(f.payload)
-		if !bytes.Equal(d[:], dp) {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidFileDigest,
-				ErrorContext: []string{
-					files[i].Name,
-				},
-			}
-		}
-		f.digest = dp
-
-		// Verify MIME
-		detectedMIMEType := mime.DetectMimeType(f.payload)
-		if detectedMIMEType != files[i].MIME {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusInvalidMIMEType,
-				ErrorContext: []string{
-					files[i].Name,
-					detectedMIMEType,
-				},
-			}
-		}
-
-		if !mime.MimeValid(files[i].MIME) {
-			return nil, backend.ContentVerificationError{
-				ErrorCode: pd.ErrorStatusUnsupportedMIMEType,
-				ErrorContext: []string{
-					files[i].Name,
-					files[i].MIME,
-				},
-			}
-		}
-
-		fa = append(fa, f)
+	fa := make([]file, 0, len(vf))
+	for _, v := range vf {
+		fa = append(fa, file{
+			name:    v.Name,
+			digest:  v.Digest,
+			payload: v.Payload,
+			path:    v.Path,
+		})
 	}
 
 	return fa, nil
@@ -679,7 +563,7 @@ func (g *gitBackEnd) anchor(digests []*[sha256.Size]byte) error {
 		return nil
 	}
 
-	return util.Timestamp("politeia", g.dcrtimeHost, digests)
+	return g.timestamper.Timestamp("politeia", digests)
 }
 
 // appendAuditTrail adds a record to the audit trail.
@@ -1027,7 +911,7 @@ func (g *gitBackEnd) verifyAnchor(digest string) (*v1.VerifyDigest, error) {
 		})
 	} else {
 		// Call dcrtime
-		vr, err = util.Verify("politeia", g.dcrtimeHost,
+		vr, err = g.timestamper.Verify("politeia",
 			[]string{digest})
 		if err != nil {
 			return nil, err
@@ -1052,7 +936,7 @@ func (g *gitBackEnd) verifyAnchor(digest string) (*v1.VerifyDigest, error) {
 // can simply unwind it said branch.
 //
 // Function must be called with the lock held.
-func (g *gitBackEnd) _newRecord(id string, metadata []backend.MetadataStream, fa []file) (*backend.RecordMetadata, error) {
+func (g *gitBackEnd) _newRecord(id string, metadata []backend.MetadataStream, fa []file, hook backend.HookNewRecord) (*backend.RecordMetadata, error) {
 	// Process files.
 	path := pijoin(g.unvetted, id, "1", defaultPayloadDir)
 	err := os.MkdirAll(path, 0774)
@@ -1064,7 +948,7 @@ func (g *gitBackEnd) _newRecord(id string, metadata []backend.MetadataStream, fa
 	for i := range fa {
 		// Copy files into directory id/payload/filename.
 		filename := pijoin(path, fa[i].name)
-		err = ioutil.WriteFile(filename, fa[i].payload, 0664)
+		err = writeFile(filename, fa[i])
 		if err != nil {
 			return nil, err
 		}
@@ -1111,6 +995,13 @@ func (g *gitBackEnd) _newRecord(id string, metadata []backend.MetadataStream, fa
 		return nil, err
 	}
 
+	// Call plugin hooks
+	hook.RecordMetadata = brm
+	err = g.plugins.Hook(backend.HookPostNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
 	// git commit -m "message"
 	err = g.gitCommit(path, "Add record "+id)
 	if err != nil {
@@ -1126,7 +1017,7 @@ func (g *gitBackEnd) _newRecord(id string, metadata []backend.MetadataStream, fa
 // anything of value.
 //
 // Function must be called with the lock held.
-func (g *gitBackEnd) newRecord(token []byte, metadata []backend.MetadataStream, fa []file) (*backend.RecordMetadata, error) {
+func (g *gitBackEnd) newRecord(token []byte, metadata []backend.MetadataStream, fa []file, hook backend.HookNewRecord) (*backend.RecordMetadata, error) {
 	id := hex.EncodeToString(token)
 
 	log.Tracef("newRecord %v", id)
@@ -1137,7 +1028,7 @@ func (g *gitBackEnd) newRecord(token []byte, metadata []backend.MetadataStream,
 		return nil, err
 	}
 
-	rm, err2 := g._newRecord(id, metadata, fa)
+	rm, err2 := g._newRecord(id, metadata, fa, hook)
 	if err2 != nil {
 		// Unwind and complain
 		err = g.gitUnwindBranch(g.unvetted, id)
@@ -1184,6 +1075,16 @@ func (g *gitBackEnd) New(metadata []backend.MetadataStream, files []backend.File
 		return nil, backend.ErrShutdown
 	}
 
+	// Call plugin hooks
+	hook := backend.HookNewRecord{
+		Metadata: metadata,
+		Files:    files,
+	}
+	err = g.plugins.Hook(backend.HookPreNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
 	// git checkout master
 	err = g.gitCheckout(g.unvetted, "master")
 	if err != nil {
@@ -1196,7 +1097,7 @@ func (g *gitBackEnd) New(metadata []backend.MetadataStream, files []backend.File
 		return nil, err
 	}
 
-	return g.newRecord(token, metadata, fa)
+	return g.newRecord(token, metadata, fa, hook)
 }
 
 // updateMetadata appends or overwrites in the unvetted repository.
@@ -1299,7 +1200,7 @@ func (g *gitBackEnd) checkoutRecordBranch(id string) (bool, error) {
 // responsible to unwinding the changes.
 //
 // Function must be  called with the lock held.
-func (g *gitBackEnd) _updateRecord(commit bool, id string, mdAppend, mdOverwrite []backend.MetadataStream, fa []file, filesDel []string) error {
+func (g *gitBackEnd) _updateRecord(commit bool, id string, mdAppend, mdOverwrite []backend.MetadataStream, fa []file, filesDel []string, hook backend.HookEditRecord) error {
 	// Get version for relative git rm command later.
 	version, err := getLatest(pijoin(g.unvetted, id))
 	if err != nil {
@@ -1345,7 +1246,7 @@ func (g *gitBackEnd) _updateRecord(commit bool, id string, mdAppend, mdOverwrite
 	for i := range fa {
 		// Copy files into directory id/payload/filename.
 		filename := pijoin(path, fa[i].name)
-		err = ioutil.WriteFile(filename, fa[i].payload, 0664)
+		err = writeFile(filename, fa[i])
 		if err != nil {
 			return err
 		}
@@ -1430,13 +1331,9 @@ func (g *gitBackEnd) _updateRecord(commit bool, id string, mdAppend, mdOverwrite
 	}
 
 	// Call plugin hooks
-	f, ok := decredPluginHooks[PluginPostHookEdit]
-	if ok {
-		log.Tracef("Calling hook: %v(%v)", PluginPostHookEdit, id)
-		err = f(id)
-		if err != nil {
-			return err
-		}
+	err = g.plugins.Hook(backend.HookPostEditRecord, hook)
+	if err != nil {
+		return err
 	}
 
 	// git add id/recordmetadata.json
@@ -1473,7 +1370,8 @@ func (g *gitBackEnd) wouldChange(id string, mdAppend []backend.MetadataStream, m
 	}
 
 	var rv bool
-	err = g._updateRecord(false, id, mdAppend, mdOverwrite, fa, filesDel)
+	err = g._updateRecord(false, id, mdAppend, mdOverwrite, fa, filesDel,
+		backend.HookEditRecord{})
 	if err == backend.ErrChangesRecord {
 		rv = true
 	}
@@ -1521,7 +1419,21 @@ func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStrea
 		return nil, err
 	}
 
+	// Call plugin hooks
 	id := hex.EncodeToString(token)
+	hook := backend.HookEditRecord{
+		Token:       id,
+		Vetted:      master,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	err = g.plugins.Hook(backend.HookPreEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
 	if master {
 		// Vetted path
 
@@ -1580,7 +1492,7 @@ func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStrea
 
 		// Do the work, if there is an error we must unwind git.
 		errReturn := g._updateRecord(true, id, mdAppend, mdOverwrite,
-			fa, filesDel)
+			fa, filesDel, hook)
 		if errReturn == nil {
 			// Success path
 
@@ -1623,7 +1535,7 @@ func (g *gitBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStrea
 
 	// Do the work, if there is an error we must unwind git.
 	errReturn := g._updateRecord(true, id, mdAppend, mdOverwrite, fa,
-		filesDel)
+		filesDel, hook)
 	if errReturn == nil {
 		// success
 		return g.getRecord(token, "", g.unvetted, true)
@@ -1912,8 +1824,12 @@ func (g *gitBackEnd) _getRecord(id, version, repo string, includeFiles bool) (*b
 		return nil, err
 	}
 
+	// Purged records no longer have a payload
+	_, err = os.Stat(pijoin(repo, id, version, defaultTombstoneFilename))
+	purged := err == nil
+
 	var files []backend.File
-	if includeFiles {
+	if includeFiles && !purged {
 		// load files
 		files, err = loadRecord(repo, id, version)
 		if err != nil {
@@ -2050,7 +1966,7 @@ func (g *gitBackEnd) fsck(path string) error {
 	for d := range gitDigests {
 		digests = append(digests, d)
 	}
-	vr, err := util.Verify("politeia", g.dcrtimeHost, digests)
+	vr, err := g.timestamper.Verify("politeia", digests)
 	if err != nil {
 		return err
 	}
@@ -2146,6 +2062,14 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 		return nil, err
 	}
 
+	hook := backend.HookSetRecordStatus{
+		Token:       id,
+		Current:     record.RecordMetadata.Status,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+
 	// We only allow a transition from unvetted to vetted or censored
 	switch {
 	case (record.RecordMetadata.Status == backend.MDStatusUnvetted ||
@@ -2154,6 +2078,12 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 
 		// unvetted -> vetted
 
+		// Call plugin hooks
+		err = g.plugins.Hook(backend.HookPreSetRecordStatus, hook)
+		if err != nil {
+			return nil, err
+		}
+
 		// Update MD first
 		record.RecordMetadata.Status = backend.MDStatusVetted
 		record.RecordMetadata.Iteration += 1
@@ -2169,6 +2099,12 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 			return nil, err
 		}
 
+		// Call plugin hooks
+		err = g.plugins.Hook(backend.HookPostSetRecordStatus, hook)
+		if err != nil {
+			return nil, err
+		}
+
 		// Commit brm
 		err = g.commitMD(g.unvetted, id, "published")
 		if err != nil {
@@ -2185,6 +2121,13 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 		record.RecordMetadata.Status == backend.MDStatusIterationUnvetted) &&
 		status == backend.MDStatusCensored:
 		// unvetted -> censored
+
+		// Call plugin hooks
+		err = g.plugins.Hook(backend.HookPreSetRecordStatus, hook)
+		if err != nil {
+			return nil, err
+		}
+
 		record.RecordMetadata.Status = backend.MDStatusCensored
 		record.RecordMetadata.Iteration += 1
 		record.RecordMetadata.Timestamp = time.Now().Unix()
@@ -2199,6 +2142,12 @@ func (g *gitBackEnd) setUnvettedStatus(token []byte, status backend.MDStatusT, m
 			return nil, err
 		}
 
+		// Call plugin hooks
+		err = g.plugins.Hook(backend.HookPostSetRecordStatus, hook)
+		if err != nil {
+			return nil, err
+		}
+
 		// Commit brm
 		err = g.commitMD(g.unvetted, id, "censored")
 		if err != nil {
@@ -2252,6 +2201,340 @@ func (g *gitBackEnd) SetUnvettedStatus(token []byte, status backend.MDStatusT, m
 	return record, nil
 }
 
+// purgeRecord recreates a censored record on a new branch that only contains
+// its metadata and a tombstone and replaces the record branch with it.  The
+// old branch is pruned so that the payload is no longer present in the
+// repository.  Note that this function must be wrapped by a function that
+// delivers the call with the unvetted repo sitting in master.
+//
+// Function must be called with the lock held.
+func (g *gitBackEnd) purgeRecord(id, idPurge, reason string) (*backend.Tombstone, error) {
+	// git checkout id
+	err := g.gitCheckout(g.unvetted, id)
+	if err != nil {
+		// Vetted records no longer have a branch.
+		_, err = os.Stat(pijoin(g.vetted, id))
+		if err == nil {
+			return nil, backend.ErrRecordNotCensored
+		}
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Load record
+	record, err := g._getRecord(id, "", g.unvetted, true)
+	if err != nil {
+		return nil, err
+	}
+	if record.RecordMetadata.Status != backend.MDStatusCensored {
+		return nil, backend.ErrRecordNotCensored
+	}
+	_, err = os.Stat(pijoin(g.unvetted, id, record.Version,
+		defaultTombstoneFilename))
+	if err == nil {
+		return nil, backend.ErrRecordPurged
+	}
+	ts := backend.NewTombstone(record, reason)
+
+	// git checkout master
+	err = g.gitCheckout(g.unvetted, "master")
+	if err != nil {
+		return nil, err
+	}
+
+	// git checkout -b id_purge
+	err = g.gitNewBranch(g.unvetted, idPurge)
+	if err != nil {
+		return nil, err
+	}
+
+	path := pijoin(g.unvetted, id, record.Version)
+	err = os.MkdirAll(path, 0774)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save all metadata streams
+	for _, v := range record.Metadata {
+		filename := pijoin(path, fmt.Sprintf("%02v%v", v.ID,
+			defaultMDFilenameSuffix))
+		err = ioutil.WriteFile(filename, []byte(v.Payload), 0664)
+		if err != nil {
+			return nil, err
+		}
+		err = g.gitAdd(g.unvetted, filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Save record metadata
+	err = updateMD(g.unvetted, id, &record.RecordMetadata)
+	if err != nil {
+		return nil, err
+	}
+	err = g.gitAdd(g.unvetted, pijoin(path, defaultRecordMetadataFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	// Save tombstone
+	b, err := json.Marshal(ts)
+	if err != nil {
+		return nil, err
+	}
+	filename := pijoin(path, defaultTombstoneFilename)
+	err = ioutil.WriteFile(filename, b, 0664)
+	if err != nil {
+		return nil, err
+	}
+	err = g.gitAdd(g.unvetted, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// git commit -m "message"
+	err = g.gitCommit(g.unvetted, "Purge record "+id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the record branch
+	err = g.gitCheckout(g.unvetted, "master")
+	if err != nil {
+		return nil, err
+	}
+	err = g.gitBranchDelete(g.unvetted, id)
+	if err != nil {
+		return nil, err
+	}
+	err = g.gitBranchRename(g.unvetted, idPurge, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get rid of the payload for good.  The record has been purged at
+	// this point so a failure is not fatal.
+	err = g.gitPrune(g.unvetted)
+	if err != nil {
+		log.Errorf("purgeRecord: prune %v: %v", id, err)
+	}
+
+	return ts, nil
+}
+
+// PurgeRecord removes the payload of a censored record, including its git
+// history, and leaves a tombstone in its place.
+//
+// PurgeRecord satisfies the backend interface.
+func (g *gitBackEnd) PurgeRecord(token []byte, reason string) (*backend.Tombstone, error) {
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("PurgeRecord %x", token)
+
+	id := hex.EncodeToString(token)
+	idPurge := id + "_purge"
+	ts, err := g.purgeRecord(id, idPurge, reason)
+	if err != nil {
+		err2 := g.gitUnwind(g.unvetted)
+		if err2 != nil {
+			log.Criticalf("PurgeRecord: unwind %v", err2)
+		}
+		err2 = g.gitCheckout(g.unvetted, "master")
+		if err2 != nil {
+			log.Criticalf("PurgeRecord: checkout %v", err2)
+		}
+		// The purge branch only exists if the record was not
+		// replaced yet.
+		branches, err2 := g.gitBranches(g.unvetted)
+		if err2 != nil {
+			log.Criticalf("PurgeRecord: branches %v", err2)
+		}
+		for _, v := range branches {
+			if v != idPurge {
+				continue
+			}
+			err2 = g.gitBranchDelete(g.unvetted, idPurge)
+			if err2 != nil {
+				log.Criticalf("PurgeRecord: branch delete %v",
+					err2)
+			}
+		}
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// GetTombstone returns the tombstone of a purged record.
+//
+// GetTombstone satisfies the backend interface.
+func (g *gitBackEnd) GetTombstone(token []byte) (*backend.Tombstone, error) {
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("GetTombstone %x", token)
+
+	// git checkout id
+	id := hex.EncodeToString(token)
+	err := g.gitCheckout(g.unvetted, id)
+	if err != nil {
+		return nil, backend.ErrRecordNotFound
+	}
+	defer func() {
+		// git checkout master
+		err := g.gitCheckout(g.unvetted, "master")
+		if err != nil {
+			log.Errorf("could not switch to master: %v", err)
+		}
+	}()
+
+	version, err := getLatest(pijoin(g.unvetted, id))
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(pijoin(g.unvetted, id, version,
+		defaultTombstoneFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, backend.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	var ts backend.Tombstone
+	err = json.Unmarshal(b, &ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ts, nil
+}
+
+// recordTimestamp returns the anchor proof of a single version of a vetted
+// record.  The anchors map is indexed by Merkle root and the anchored map
+// links every anchored digest to the Merkle root of its anchor.
+//
+// This function must be called WITH the lock held.
+func (g *gitBackEnd) recordTimestamp(id, version string, anchors map[string]*Anchor, anchored map[string]string) (*backend.RecordTimestamp, error) {
+	// The versions of a record are immutable, so the commit that created
+	// the version directory proves when its content existed.
+	sha1Digest, err := g.gitFirstDigest(g.vetted, pijoin(id, version))
+	if err != nil {
+		return nil, err
+	}
+	digest := extendSHA1(sha1Digest)
+	rt := backend.RecordTimestamp{
+		Version: version,
+		Digest:  digest,
+		Status:  backend.TimestampStatusNotAnchored,
+	}
+
+	key, ok := anchored[hex.EncodeToString(digest)]
+	if !ok {
+		return &rt, nil
+	}
+	a := anchors[key]
+	mr, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	rt.Status = backend.TimestampStatusUnconfirmed
+	rt.AnchorMerkle = mr
+	rt.AnchorTime = a.Time
+
+	// Prove that the digest is part of the anchor merkle root.  The
+	// leaves must be sorted the same way merkle.Root sorts them.
+	leaves := make([]*[sha256.Size]byte, 0, len(a.Digests))
+	for _, v := range a.Digests {
+		var leaf [sha256.Size]byte
+		copy(leaf[:], v)
+		leaves = append(leaves, &leaf)
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i][:], leaves[j][:]) < 0
+	})
+	var d [sha256.Size]byte
+	copy(d[:], digest)
+	rt.MerklePath = merkle.AuthPath(leaves, &d)
+
+	if a.Type != AnchorVerified {
+		return &rt, nil
+	}
+
+	// Confirmed anchors store the dcrtime chain information.
+	b, err := ioutil.ReadFile(pijoin(g.vetted, defaultAnchorsDirectory,
+		key))
+	if err != nil {
+		return nil, err
+	}
+	var ci v1.ChainInformation
+	err = json.Unmarshal(b, &ci)
+	if err != nil {
+		return nil, err
+	}
+	rt.Status = backend.TimestampStatusConfirmed
+	rt.ChainInformation = &ci
+
+	return &rt, nil
+}
+
+// GetTimestamps returns the anchor proofs of all versions of a vetted record.
+//
+// GetTimestamps satisfies the backend interface.
+func (g *gitBackEnd) GetTimestamps(token []byte) ([]backend.RecordTimestamp, error) {
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("GetTimestamps %x", token)
+
+	id := hex.EncodeToString(token)
+	latest, err := getLatest(pijoin(g.vetted, id))
+	if err != nil {
+		return nil, err
+	}
+	latestVersion, err := strconv.ParseUint(latest, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index all anchored digests by the Merkle root of their anchor.
+	anchors, err := g.readAnchorRecords()
+	if err != nil {
+		return nil, err
+	}
+	anchored := make(map[string]string)
+	for k, v := range anchors {
+		for _, digest := range v.Digests {
+			anchored[hex.EncodeToString(digest)] = k
+		}
+	}
+
+	rts := make([]backend.RecordTimestamp, 0, latestVersion)
+	for i := uint64(1); i <= latestVersion; i++ {
+		rt, err := g.recordTimestamp(id, strconv.FormatUint(i, 10),
+			anchors, anchored)
+		if err != nil {
+			return nil, err
+		}
+		rts = append(rts, *rt)
+	}
+
+	return rts, nil
+}
+
 // setVettedStatus takes various parameters to update a record metadata and
 // status.  It goes through the normal stages of updating unvetted, pushing PR,
 // merge PR, pull remote. Note that this function must be wrapped by a function
@@ -2305,6 +2588,19 @@ func (g *gitBackEnd) _setVettedStatus(token []byte, status backend.MDStatusT, md
 		}
 	}
 
+	// Call plugin hooks
+	hook := backend.HookSetRecordStatus{
+		Token:       id,
+		Current:     record.RecordMetadata.Status,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	err = g.plugins.Hook(backend.HookPreSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
 	// Delete any leftover tmp branch. There shouldn't be one.
 	idTmp := id + "_tmp"
 	_ = g.gitBranchDelete(g.unvetted, idTmp)
@@ -2330,6 +2626,12 @@ func (g *gitBackEnd) _setVettedStatus(token []byte, status backend.MDStatusT, md
 		return nil, err
 	}
 
+	// Call plugin hooks
+	err = g.plugins.Hook(backend.HookPostSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
 	// Commit changes
 	err = g.commitMD(g.unvetted, id, "archived")
 	if err != nil {
@@ -2460,12 +2762,75 @@ func (g *gitBackEnd) Inventory(vettedCount, branchCount uint, includeFiles, allV
 	return pr, br, nil
 }
 
+// InventoryPage returns a filtered page of records, ordered by token, and the
+// cursor of the next page.
+//
+// InventoryPage satisfies the backend interface.
+func (g *gitBackEnd) InventoryPage(q backend.InventoryQuery) ([]backend.Record, string, error) {
+	log.Debugf("InventoryPage: %v %v", q.Cursor, q.Limit)
+
+	// Lock filesystem
+	g.Lock()
+	defer g.Unlock()
+	if g.shutdown {
+		return nil, "", backend.ErrShutdown
+	}
+
+	// Vetted records live in the vetted directory and all other records
+	// are branches on unvetted.
+	repos := make(map[string]string)
+	files, err := ioutil.ReadDir(g.vetted)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, v := range files {
+		if util.IsDigest(v.Name()) {
+			repos[v.Name()] = g.vetted
+		}
+	}
+	branches, err := g.gitBranches(g.unvetted)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, id := range branches {
+		if !util.IsDigest(id) {
+			continue
+		}
+		if _, ok := repos[id]; !ok {
+			repos[id] = g.unvetted
+		}
+	}
+
+	tokens := make([]string, 0, len(repos))
+	for id := range repos {
+		tokens = append(tokens, id)
+	}
+
+	return backend.PaginateInventory(q, tokens,
+		func(id, version string) (*backend.Record, error) {
+			token, err := hex.DecodeString(id)
+			if err != nil {
+				return nil, err
+			}
+			return g.getRecord(token, version, repos[id],
+				q.IncludeFiles)
+		})
+}
+
+// RegisterPlugin sets up a plugin and registers it with the backend.
+//
+// RegisterPlugin satisfies the backend interface.
+func (g *gitBackEnd) RegisterPlugin(p backend.Plugin) error {
+	log.Debugf("RegisterPlugin: %v", p.ID)
+	return g.plugins.Register(p)
+}
+
 // GetPlugins returns a list of currently supported plugins and their settings.
 //
 // GetPlugins satisfies the backend interface.
 func (g *gitBackEnd) GetPlugins() ([]backend.Plugin, error) {
 	log.Debugf("GetPlugins")
-	return g.plugins, nil
+	return g.plugins.Get(), nil
 }
 
 // Plugin send a passthrough command. The return values are: incomming command
@@ -2473,47 +2838,10 @@ func (g *gitBackEnd) GetPlugins() ([]backend.Plugin, error) {
 // execute.
 //
 // Plugin satisfies the backend interface.
-func (g *gitBackEnd) Plugin(command, payload string) (string, string, error) {
-	log.Debugf("Plugin: %v", command)
-	switch command {
-	case decredplugin.CmdAuthorizeVote:
-		payload, err := g.pluginAuthorizeVote(payload)
-		return decredplugin.CmdAuthorizeVote, payload, err
-	case decredplugin.CmdStartVote:
-		payload, err := g.pluginStartVote(payload)
-		return decredplugin.CmdStartVote, payload, err
-	case decredplugin.CmdBallot:
-		payload, err := g.pluginBallot(payload)
-		return decredplugin.CmdBallot, payload, err
-	case decredplugin.CmdProposalVotes:
-		payload, err := g.pluginProposalVotes(payload)
-		return decredplugin.CmdProposalVotes, payload, err
-	case decredplugin.CmdBestBlock:
-		payload, err := g.pluginBestBlock()
-		return decredplugin.CmdBestBlock, payload, err
-	case decredplugin.CmdNewComment:
-		payload, err := g.pluginNewComment(payload)
-		return decredplugin.CmdNewComment, payload, err
-	case decredplugin.CmdLikeComment:
-		payload, err := g.pluginLikeComment(payload)
-		return decredplugin.CmdLikeComment, payload, err
-	case decredplugin.CmdCensorComment:
-		payload, err := g.pluginCensorComment(payload)
-		return decredplugin.CmdCensorComment, payload, err
-	case decredplugin.CmdGetComments:
-		payload, err := g.pluginGetComments(payload)
-		return decredplugin.CmdGetComments, payload, err
-	case decredplugin.CmdProposalCommentsLikes:
-		payload, err := g.pluginGetProposalCommentsLikes(payload)
-		return decredplugin.CmdProposalCommentsLikes, payload, err
-	case decredplugin.CmdInventory:
-		payload, err := g.pluginInventory()
-		return decredplugin.CmdInventory, payload, err
-	case decredplugin.CmdLoadVoteResults:
-		payload, err := g.pluginLoadVoteResults()
-		return decredplugin.CmdLoadVoteResults, payload, err
-	}
-	return "", "", fmt.Errorf("invalid payload command") // XXX this needs to become a type error
+func (g *gitBackEnd) Plugin(pluginID, command, payload string) (string, string, error) {
+	log.Debugf("Plugin: %v %v", pluginID, command)
+	reply, err := g.plugins.Cmd(pluginID, command, payload)
+	return command, reply, err
 }
 
 // Close shuts down the backend.  It obtains the lock and sets the shutdown
@@ -2655,7 +2983,7 @@ func (g *gitBackEnd) rebasePR(id string) error {
 }
 
 // New returns a gitBackEnd context.  It verifies that git is installed.
-func New(anp *chaincfg.Params, root string, dcrtimeHost string, gitPath string, id *identity.FullIdentity, gitTrace bool, dcrdataHost string) (*gitBackEnd, error) {
+func New(anp *chaincfg.Params, root string, ts util.Timestamper, gitPath string, id *identity.FullIdentity, gitTrace bool) (*gitBackEnd, error) {
 	// Default to system git
 	if gitPath == "" {
 		gitPath = "git"
@@ -2669,36 +2997,17 @@ func New(anp *chaincfg.Params, root string, dcrtimeHost string, gitPath string,
 		vetted:          filepath.Join(root, DefaultVettedPath),
 		journals:        filepath.Join(root, DefaultJournalsPath),
 		gitPath:         gitPath,
-		dcrtimeHost:     dcrtimeHost,
+		timestamper:     ts,
 		gitTrace:        gitTrace,
 		exit:            make(chan struct{}),
 		checkAnchor:     make(chan struct{}),
 		testAnchors:     make(map[string]bool),
-		plugins:         []backend.Plugin{getDecredPlugin(dcrdataHost)},
 	}
 	idJSON, err := id.Marshal()
 	if err != nil {
 		return nil, err
 	}
 	setDecredPluginSetting(decredPluginIdentity, string(idJSON))
-	setDecredPluginSetting(decredPluginJournals, g.journals)
-	setDecredPluginHook(PluginPostHookEdit, g.decredPluginPostEdit)
-
-	// Create jounals path
-	// XXX this needs to move into plugin init
-	log.Infof("Journals directory: %v", g.journals)
-	err = os.MkdirAll(g.journals, 0760)
-	if err != nil {
-		return nil, err
-	}
-
-	g.journal = NewJournal()
-
-	// this function must be called after g.journal is created
-	err = g.initDecredPluginJournals()
-	if err != nil {
-		return nil, err
-	}
 
 	err = g.newLocked()
 	if err != nil {
@@ -2726,7 +3035,7 @@ func New(anp *chaincfg.Params, root string, dcrtimeHost string, gitPath string,
 	g.cron.Start()
 
 	// Message user
-	log.Infof("Timestamp host: %v", g.dcrtimeHost)
+	log.Infof("Timestamp host: %v", g.timestamper)
 
 	log.Infof("Running dcrtime fsck on vetted repository")
 	err = g.fsck(g.vetted)
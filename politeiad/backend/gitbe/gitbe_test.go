@@ -18,11 +18,14 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrtime/merkle"
 	"github.com/thi4go/politeia/politeiad/api/v1/mime"
 	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/backend/backendtest"
 	"github.com/thi4go/politeia/util"
 	"github.com/decred/slog"
 )
@@ -65,8 +68,8 @@ func TestAnchorWithCommits(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	// Initialize stuff we need
-	g, err := New(&chaincfg.TestNet3Params, dir, "", "", nil,
-		testing.Verbose(), "")
+	g, err := New(&chaincfg.TestNet3Params, dir, util.NewDcrtime(""),
+		"", nil, testing.Verbose())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -465,8 +468,8 @@ func TestUpdateReadme(t *testing.T) {
 	}
 	defer os.RemoveAll(dir)
 
-	g, err := New(&chaincfg.TestNet3Params, dir, "", "", nil,
-		testing.Verbose(), "")
+	g, err := New(&chaincfg.TestNet3Params, dir, util.NewDcrtime(""),
+		"", nil, testing.Verbose())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -557,3 +560,246 @@ func TestUpdateReadme(t *testing.T) {
 		t.Fatalf("The only branch in the vetted repo should be master")
 	}
 }
+
+func TestBackendConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) (backend.Backend, func()) {
+		dir, err := ioutil.TempDir("", "politeia.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := New(&chaincfg.TestNet3Params, dir, util.NewDcrtime(""),
+			"", nil, testing.Verbose())
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+		g.test = true
+
+		return g, func() {
+			g.Close()
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestAnchorWithLocalTimestamper(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Anchors are confirmed by the local timestamper instead of being
+	// faked by the test mode.
+	delay := 500 * time.Millisecond
+	g, err := New(&chaincfg.TestNet3Params, dir,
+		util.NewLocalTimestamper(delay), "", nil, testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	payload := "this is a record"
+	rm, err := g.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{{
+		Name:    "index.md",
+		MIME:    mime.DetectMimeType([]byte(payload)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.SetUnvettedStatus(token, backend.MDStatusVetted, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drop an anchor
+	err = g.anchorAllRepos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ua, err := g.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ua.Merkles) != 1 {
+		t.Fatalf("unconfirmed anchors got %v, want 1", len(ua.Merkles))
+	}
+	merkle := hex.EncodeToString(ua.Merkles[0])
+
+	// The anchor is not confirmed before the delay elapsed
+	err = g.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ua, err = g.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ua.Merkles) != 1 {
+		t.Fatalf("anchor confirmed before the confirmation delay")
+	}
+
+	// The anchor is confirmed once the delay elapsed
+	time.Sleep(delay)
+	err = g.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ua, err = g.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ua.Merkles) != 0 {
+		t.Fatalf("anchor not confirmed: %v", len(ua.Merkles))
+	}
+	if !util.FileExists(filepath.Join(g.vetted, defaultAnchorsDirectory,
+		merkle)) {
+		t.Fatalf("anchor chain information missing: %v", merkle)
+	}
+	out, err := g.gitLog(g.vetted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(out, "\n"),
+		markerAnchorConfirmation+" "+merkle) {
+		t.Fatalf("anchor confirmation commit missing: %v", merkle)
+	}
+}
+
+func TestGetTimestamps(t *testing.T) {
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, err := New(&chaincfg.TestNet3Params, dir,
+		util.NewLocalTimestamper(0), "", nil, testing.Verbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	newFile := func(payload string) backend.File {
+		return backend.File{
+			Name:    "index.md",
+			MIME:    mime.DetectMimeType([]byte(payload)),
+			Digest:  hex.EncodeToString(util.Digest([]byte(payload))),
+			Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+		}
+	}
+	rm, err := g.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, []backend.File{newFile("this is a record")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unvetted records are not anchored
+	_, err = g.GetTimestamps(token)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("got %v, want %v", err, backend.ErrRecordNotFound)
+	}
+
+	_, err = g.SetUnvettedStatus(token, backend.MDStatusVetted, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rts, err := g.GetTimestamps(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rts) != 1 {
+		t.Fatalf("got %v timestamps, want 1", len(rts))
+	}
+	if rts[0].Status != backend.TimestampStatusNotAnchored {
+		t.Fatalf("got status %v, want %v", rts[0].Status,
+			backend.TimestampStatusNotAnchored)
+	}
+
+	// Drop an anchor and add a new version that is not part of it
+	err = g.anchorAllRepos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.UpdateVettedRecord(token, nil, nil,
+		[]backend.File{newFile("this is an updated record")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rts, err = g.GetTimestamps(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rts) != 2 {
+		t.Fatalf("got %v timestamps, want 2", len(rts))
+	}
+	if rts[0].Status != backend.TimestampStatusUnconfirmed {
+		t.Fatalf("got status %v, want %v", rts[0].Status,
+			backend.TimestampStatusUnconfirmed)
+	}
+	if rts[1].Status != backend.TimestampStatusNotAnchored {
+		t.Fatalf("got status %v, want %v", rts[1].Status,
+			backend.TimestampStatusNotAnchored)
+	}
+
+	// Confirm the anchor and verify the proof of the first version
+	err = g.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rts, err = g.GetTimestamps(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := rts[0]
+	if rt.Status != backend.TimestampStatusConfirmed {
+		t.Fatalf("got status %v, want %v", rt.Status,
+			backend.TimestampStatusConfirmed)
+	}
+	if rt.Version != "1" {
+		t.Fatalf("got version %v, want 1", rt.Version)
+	}
+	root, err := merkle.VerifyAuthPath(rt.MerklePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root[:], rt.AnchorMerkle) {
+		t.Fatalf("digest does not lead to anchor merkle root")
+	}
+	ci := rt.ChainInformation
+	if ci == nil {
+		t.Fatalf("chain information missing")
+	}
+	root, err = merkle.VerifyAuthPath(&ci.MerklePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(root[:]) != ci.MerkleRoot {
+		t.Fatalf("anchor merkle root does not lead to chain merkle root")
+	}
+	if ci.Transaction == "" {
+		t.Fatalf("transaction missing")
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gitbe
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// gitDuration is the duration of git subprocesses by git command.
+	gitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "politeiad",
+		Subsystem: "git",
+		Name:      "command_duration_seconds",
+		Help:      "Duration of git subprocesses.",
+	}, []string{"command"})
+
+	// flushDuration is the duration of journal flushes by journal type.
+	flushDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "politeiad",
+		Subsystem: "journal",
+		Name:      "flush_duration_seconds",
+		Help:      "Duration of journal flushes.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"journal"})
+)
+
+// observeFlush records the duration of a journal flush that started at the
+// provided time.
+func observeFlush(journal string, start time.Time) {
+	flushDuration.WithLabelValues(journal).Observe(time.Since(start).Seconds())
+}
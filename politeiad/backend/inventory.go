@@ -0,0 +1,138 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"sort"
+	"strconv"
+)
+
+// InventoryFilter narrows down the records that are returned by
+// InventoryPage.  Empty fields match all records.
+type InventoryFilter struct {
+	Statuses      []MDStatusT // Record has any of these statuses
+	FromTimestamp int64       // Record was last updated at or after
+	ToTimestamp   int64       // Record was last updated at or before
+	MDStreams     []uint64    // Record contains all these metadata streams
+}
+
+// Match returns true if the latest version of a record satisfies the filter.
+func (f *InventoryFilter) Match(r *Record) bool {
+	if len(f.Statuses) > 0 {
+		var found bool
+		for _, v := range f.Statuses {
+			if r.RecordMetadata.Status == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	ts := r.RecordMetadata.Timestamp
+	if f.FromTimestamp != 0 && ts < f.FromTimestamp {
+		return false
+	}
+	if f.ToTimestamp != 0 && ts > f.ToTimestamp {
+		return false
+	}
+
+	for _, id := range f.MDStreams {
+		var found bool
+		for _, v := range r.Metadata {
+			if v.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InventoryQuery describes a single page of the inventory.  Records are
+// ordered by token.  The cursor is the token of the last record of the
+// previous page and it is empty for the first page.  A limit of 0 returns all
+// records that follow the cursor.
+//
+// When AllVersions is set the older versions of a vetted record directly
+// follow its latest version.  They are not counted against the limit and the
+// filter is only applied to the latest version.
+type InventoryQuery struct {
+	Cursor       string          // Token of the last record of the previous page
+	Limit        uint            // Maximum number of records
+	IncludeFiles bool            // Include files in records
+	AllVersions  bool            // Include all versions of vetted records
+	Filter       InventoryFilter // Filter records
+}
+
+// PaginateInventory returns a page of the inventory and the cursor of the
+// next page.  The cursor is empty when there are no more records.  The tokens
+// do not need to be sorted.  get is used to retrieve the requested version of
+// a record, the latest version is requested with an empty version.
+//
+// This function is meant to be used by backends to implement InventoryPage.
+func PaginateInventory(q InventoryQuery, tokens []string, get func(token, version string) (*Record, error)) ([]Record, string, error) {
+	sort.Strings(tokens)
+
+	// Skip the tokens of previous pages.
+	i := sort.Search(len(tokens), func(i int) bool {
+		return tokens[i] > q.Cursor
+	})
+	tokens = tokens[i:]
+
+	var (
+		records = make([]Record, 0)
+		count   uint
+	)
+	for k, token := range tokens {
+		r, err := get(token, "")
+		if err != nil {
+			return nil, "", err
+		}
+		if !q.Filter.Match(r) {
+			continue
+		}
+		if !q.IncludeFiles {
+			r.Files = nil
+		}
+		records = append(records, *r)
+
+		status := r.RecordMetadata.Status
+		if q.AllVersions &&
+			(status == MDStatusVetted || status == MDStatusArchived) {
+			// Include all versions of the record
+			latest, err := strconv.Atoi(r.Version)
+			if err != nil {
+				return nil, "", err
+			}
+			for v := 1; v < latest; v++ {
+				r, err := get(token, strconv.Itoa(v))
+				if err != nil {
+					return nil, "", err
+				}
+				if !q.IncludeFiles {
+					r.Files = nil
+				}
+				records = append(records, *r)
+			}
+		}
+
+		count++
+		if q.Limit != 0 && count == q.Limit {
+			if k == len(tokens)-1 {
+				break
+			}
+			return records, token, nil
+		}
+	}
+
+	return records, "", nil
+}
@@ -0,0 +1,469 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package levelbe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "github.com/decred/dcrtime/api/v1"
+	"github.com/decred/dcrtime/merkle"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/util"
+)
+
+// An anchor corresponds to a set of commit digests, along with their merkle
+// root, that get checkpointed in dcrtime. This provides censorship resistance
+// by anchoring activity on politeia to the blockchain.
+//
+// Unlike gitbe, which has to parse the git log in order to find the last
+// anchor and the unconfirmed anchors, levelbe stores these records directly
+// in the database.
+
+// AnchorType discriminates between the various Anchor record types.
+type AnchorType uint32
+
+const (
+	AnchorInvalid    AnchorType = 0 // Invalid anchor
+	AnchorUnverified AnchorType = 1 // Unverified anchor
+	AnchorVerified   AnchorType = 2 // Verified anchor
+)
+
+type Anchor struct {
+	Type     AnchorType // Type of anchor this record represents
+	Time     int64      // OS time when record was created
+	Digests  [][]byte   // All digests that were merkled to get to key of record
+	Messages []string   // All one-line Commit messages
+	// len(Digests) == len(Messages) and index offsets are linked. e.g.
+	// Digests[15] commit messages is in Messages[15].
+
+	// ChainInformation is filled out once dcrtime has confirmed the
+	// anchor.
+	ChainInformation *v1.ChainInformation `json:",omitempty"`
+}
+
+// LastAnchor stores the last commit anchored in dcrtime.
+type LastAnchor struct {
+	Last     []byte // Last commit digest that was anchored
+	Sequence uint64 // Sequence number of the last anchored commit
+	Time     int64  // OS time when record was created
+	Merkle   []byte // Merkle root that points to Anchor record, if valid
+}
+
+// UnconfirmedAnchor stores Merkle roots of anchors that have not been confirmed
+// yet by dcrtime.
+type UnconfirmedAnchor struct {
+	Merkles [][]byte // List of Merkle root that points to Anchor records
+}
+
+// newAnchorRecord creates an Anchor Record and the Merkle Root from the
+// provided pieces.  Note that the merkle root is of the commit digests!
+func newAnchorRecord(t AnchorType, digests []*[sha256.Size]byte, messages []string) (*Anchor, *[sha256.Size]byte, error) {
+	if len(digests) != len(messages) {
+		return nil, nil, fmt.Errorf("invalid digest and messages length")
+	}
+
+	if t == AnchorInvalid {
+		return nil, nil, fmt.Errorf("invalid anchor type")
+	}
+
+	a := Anchor{
+		Type:     t,
+		Messages: messages,
+		Digests:  make([][]byte, 0, len(digests)),
+		Time:     time.Now().Unix(),
+	}
+
+	for _, digest := range digests {
+		d := make([]byte, sha256.Size)
+		copy(d, digest[:])
+		a.Digests = append(a.Digests, d)
+	}
+
+	return &a, merkle.Root(digests), nil
+}
+
+// anchorKey returns the database key of the anchor record that is identified
+// by the provided merkle root.
+func anchorKey(key [sha256.Size]byte) []byte {
+	return []byte(prefixAnchor + hex.EncodeToString(key[:]))
+}
+
+// readAnchorRecord retrieves the anchor record identified by the provided
+// merkle root.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) readAnchorRecord(key [sha256.Size]byte) (*Anchor, error) {
+	payload, err := l.db.Get(anchorKey(key), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, fmt.Errorf("anchor not found: %x", key)
+		}
+		return nil, err
+	}
+
+	var anchor Anchor
+	err = json.Unmarshal(payload, &anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &anchor, nil
+}
+
+// readLastAnchorRecord retrieves the last anchor record.  An empty record is
+// returned when nothing has been anchored yet.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) readLastAnchorRecord() (*LastAnchor, error) {
+	payload, err := l.db.Get([]byte(keyLastAnchor), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return &LastAnchor{}, nil
+		}
+		return nil, err
+	}
+
+	var la LastAnchor
+	err = json.Unmarshal(payload, &la)
+	if err != nil {
+		return nil, err
+	}
+
+	return &la, nil
+}
+
+// readUnconfirmedAnchorRecord retrieves the unconfirmed anchor record.  An
+// empty record is returned when there are no unconfirmed anchors.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) readUnconfirmedAnchorRecord() (*UnconfirmedAnchor, error) {
+	payload, err := l.db.Get([]byte(keyUnconfirmed), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return &UnconfirmedAnchor{}, nil
+		}
+		return nil, err
+	}
+
+	var ua UnconfirmedAnchor
+	err = json.Unmarshal(payload, &ua)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ua, nil
+}
+
+// anchor takes a slice of commit digests and anchors them in dcrtime.
+//
+// Just like gitbe, this function anchors the merkle root and all individual
+// commit digests in order to be able to externally validate that a commit
+// made it into the time stamp.
+//
+// This function should be called with the lock held.
+func (l *levelBackEnd) anchor(digests []*[sha256.Size]byte) error {
+	// Anchor all digests
+	if l.test {
+		// We always append the anchorKey as the last element
+		x := len(digests) - 1
+		l.testAnchors[hex.EncodeToString(digests[x][:])] = false
+		return nil
+	}
+
+	return l.timestamper.Timestamp("politeia", digests)
+}
+
+// anchorRecords drops an anchor for all commits that were made since the last
+// anchor.
+func (l *levelBackEnd) anchorRecords() error {
+	log.Infof("Dropping anchor")
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return fmt.Errorf("anchorRecords: %v", backend.ErrShutdown)
+	}
+
+	// Check for unanchored commits
+	last, err := l.readLastAnchorRecord()
+	if err != nil {
+		return fmt.Errorf("could not find last digest: %v", err)
+	}
+	commits, err := l.commitsSince(last.Sequence)
+	if err != nil {
+		return fmt.Errorf("could not determine delta: %v", err)
+	}
+	if len(commits) == 0 {
+		log.Infof("Anchoring: nothing to do")
+		return nil
+	}
+
+	digests := make([]*[sha256.Size]byte, 0, len(commits))
+	messages := make([]string, 0, len(commits))
+	for _, c := range commits {
+		var d [sha256.Size]byte
+		copy(d[:], c.Digest)
+		digests = append(digests, &d)
+		messages = append(messages, c.Message)
+	}
+
+	// Create anchor record before calling anchor since merkle.Root sorts
+	// the digests.
+	anchorRecord, key, err := newAnchorRecord(AnchorUnverified, digests,
+		messages)
+	if err != nil {
+		return fmt.Errorf("newAnchorRecord: %v", err)
+	}
+
+	// Append MerkleRoot to digests.  We have to do this since this is
+	// politeia's lookup key but dcrtime will likely return a different
+	// merkle.
+	digests = append(digests, key)
+
+	// Anchor commits
+	err = l.anchor(digests)
+	if err != nil {
+		return fmt.Errorf("anchor: %v", err)
+	}
+
+	// Update anchor records
+	ua, err := l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		return err
+	}
+	ua.Merkles = append(ua.Merkles, key[:])
+	lastCommit := commits[len(commits)-1]
+	la := LastAnchor{
+		Last:     lastCommit.Digest,
+		Sequence: lastCommit.Sequence,
+		Time:     anchorRecord.Time,
+		Merkle:   key[:],
+	}
+
+	batch := new(leveldb.Batch)
+	err = batchJSON(batch, anchorKey(*key), anchorRecord)
+	if err != nil {
+		return err
+	}
+	err = batchJSON(batch, []byte(keyUnconfirmed), ua)
+	if err != nil {
+		return err
+	}
+	err = batchJSON(batch, []byte(keyLastAnchor), la)
+	if err != nil {
+		return err
+	}
+	err = l.db.Write(batch, nil)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Dropping anchor complete: %x", *key)
+
+	return nil
+}
+
+// anchorRecordsCronJob is the cron job that anchors all records at a preset
+// time.
+func (l *levelBackEnd) anchorRecordsCronJob() {
+	err := l.anchorRecords()
+	if err != nil {
+		log.Errorf("%v", err)
+	}
+}
+
+// periodicAnchorChecker must be run as a go routine.  It sits around and
+// periodically checks if there is work to do.  It can also be tickled by
+// messaging checkAnchor.
+func (l *levelBackEnd) periodicAnchorChecker() {
+	log.Infof("Periodic anchor checker launched")
+	defer log.Infof("Periodic anchor checker exited")
+	for {
+		select {
+		case <-l.exit:
+			return
+		case <-l.checkAnchor:
+		case <-time.After(5 * time.Minute):
+		}
+
+		l.Lock()
+		isShutdown := l.shutdown
+		l.Unlock()
+		if isShutdown {
+			return
+		}
+
+		err := l.anchorChecker()
+		if err != nil {
+			// Not much we can do past logging
+			log.Errorf("periodicAnchorChecker: %v", err)
+		}
+	}
+}
+
+// anchorChecker does the work for periodicAnchorChecker.  It lives in its own
+// function for testing purposes.
+func (l *levelBackEnd) anchorChecker() error {
+	l.Lock()
+	ua, err := l.readUnconfirmedAnchorRecord()
+	l.Unlock()
+	if err != nil {
+		return fmt.Errorf("anchorChecker read: %v", err)
+	}
+
+	// Check for work
+	if len(ua.Merkles) == 0 {
+		return nil
+	}
+
+	// Do one verify at a time for now
+	vrs := make([]v1.VerifyDigest, 0, len(ua.Merkles))
+	for _, u := range ua.Merkles {
+		digest := hex.EncodeToString(u)
+		vr, err := l.verifyAnchor(digest)
+		if err != nil {
+			log.Errorf("anchorChecker verify: %v", err)
+			continue
+		}
+		vrs = append(vrs, *vr)
+	}
+
+	err = l.afterAnchorVerify(vrs)
+	if err != nil {
+		return fmt.Errorf("afterAnchorVerify: %v", err)
+	}
+
+	return nil
+}
+
+// afterAnchorVerify completes the anchor verification process.  It marks the
+// anchor records as verified, stores the dcrtime chain information and
+// removes them from the unconfirmed anchor record.
+func (l *levelBackEnd) afterAnchorVerify(vrs []v1.VerifyDigest) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if len(vrs) == 0 {
+		return nil
+	}
+
+	ua, err := l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	confirmed := make(map[string]struct{}, len(vrs))
+	for _, vr := range vrs {
+		if vr.ChainInformation.ChainTimestamp == 0 {
+			// dcrtime returns 0 when there are not enough
+			// confirmations yet.
+			return fmt.Errorf("not enough confirmations: %v",
+				vr.Digest)
+		}
+
+		mr, ok := util.ConvertDigest(vr.Digest)
+		if !ok {
+			return fmt.Errorf("invalid digest: %v", vr.Digest)
+		}
+		anchor, err := l.readAnchorRecord(mr)
+		if err != nil {
+			return err
+		}
+		ci := vr.ChainInformation
+		anchor.Type = AnchorVerified
+		anchor.ChainInformation = &ci
+		err = batchJSON(batch, anchorKey(mr), anchor)
+		if err != nil {
+			return err
+		}
+		confirmed[vr.Digest] = struct{}{}
+
+		log.Infof("%v anchored in TX %v", vr.Digest,
+			vr.ChainInformation.Transaction)
+	}
+
+	// Remove confirmed anchors from the unconfirmed list
+	merkles := make([][]byte, 0, len(ua.Merkles))
+	for _, v := range ua.Merkles {
+		if _, ok := confirmed[hex.EncodeToString(v)]; ok {
+			continue
+		}
+		merkles = append(merkles, v)
+	}
+	ua.Merkles = merkles
+	err = batchJSON(batch, []byte(keyUnconfirmed), ua)
+	if err != nil {
+		return err
+	}
+
+	err = l.db.Write(batch, nil)
+	if err != nil {
+		return err
+	}
+
+	// Mark test anchors as confirmed by dcrtime
+	if l.test {
+		for digest := range confirmed {
+			l.testAnchors[digest] = true
+		}
+	}
+
+	return nil
+}
+
+// verifyAnchor asks dcrtime if an anchor has been verified and returns a TX if
+// it has.
+func (l *levelBackEnd) verifyAnchor(digest string) (*v1.VerifyDigest, error) {
+	var (
+		vr  *v1.VerifyReply
+		err error
+	)
+
+	// In test mode we fake success.
+	if l.test {
+		vr = &v1.VerifyReply{}
+		l.Lock()
+		anchored, ok := l.testAnchors[digest]
+		l.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("test not found")
+		}
+		if anchored {
+			return nil, fmt.Errorf("already anchored")
+		}
+		vr.Digests = append(vr.Digests, v1.VerifyDigest{
+			Digest: digest,
+			Result: v1.ResultOK,
+			ChainInformation: v1.ChainInformation{
+				ChainTimestamp: time.Now().Unix(),
+				Transaction:    expectedTestTX,
+			},
+		})
+	} else {
+		// Call dcrtime
+		vr, err = l.timestamper.Verify("politeia",
+			[]string{digest})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Do some sanity checks
+	if len(vr.Digests) != 1 {
+		return nil, fmt.Errorf("unexpected number of digests")
+	}
+	if vr.Digests[0].Result != v1.ResultOK {
+		return nil, fmt.Errorf("unexpected result: %v",
+			vr.Digests[0].Result)
+	}
+
+	return &vr.Digests[0], nil
+}
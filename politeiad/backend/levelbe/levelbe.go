@@ -0,0 +1,1155 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package levelbe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/syndtr/goleveldb/leveldb"
+	ldbutil "github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/thi4go/politeia/decredplugin"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/util"
+)
+
+const (
+	// DefaultDbPath is the directory, relative to the politeiad data
+	// directory, where the leveldb database lives.
+	DefaultDbPath = "levelbe"
+
+	// DbVersion is the current database version.
+	DbVersion uint32 = 1
+
+	// anchorSchedule determines how often we anchor the commit log.
+	// Seconds Minutes Hours Days Months DayOfWeek
+	anchorSchedule = "0 58 * * * *" // At 58 minutes every hour
+
+	// expectedTestTX is a fake TX used by unit tests.
+	expectedTestTX = "TESTTX"
+
+	// Database keys
+	keyVersion     = "version"     // Database version
+	keyReadme      = "readme"      // README.md content
+	keyLastCommit  = "lastcommit"  // Last entry of the commit log
+	keyLastAnchor  = "lastanchor"  // LastAnchor record
+	keyUnconfirmed = "unconfirmed" // UnconfirmedAnchor record
+
+	// Database key prefixes
+	prefixLatest = "latest:" // latest:token -> latest record version
+	prefixRecord = "record:" // record:token:version -> backend.Record
+	prefixCommit = "commit:" // commit:sequence -> Commit
+	prefixAnchor = "anchor:" // anchor:merkle -> Anchor
+	prefixPurged = "purged:" // purged:token -> backend.Tombstone
+)
+
+var (
+	_ backend.Backend = (*levelBackEnd)(nil)
+)
+
+// Version contains the database version.
+type Version struct {
+	Version uint32 `json:"version"` // Database version
+	Time    int64  `json:"time"`    // Time of record creation
+}
+
+// Commit is an entry in the append only commit log.  Every change to the
+// records is recorded as a commit whose digest chains the digest of the
+// previous commit.  The commit digests are what gets anchored in dcrtime, the
+// same way gitbe anchors git commit digests.
+type Commit struct {
+	Sequence  uint64 `json:"sequence"`  // Commit number, starts at 1
+	Parent    []byte `json:"parent"`    // Digest of the previous commit
+	Data      []byte `json:"data"`      // SHA256 of the committed data
+	Message   string `json:"message"`   // One line commit message
+	Digest    []byte `json:"digest"`    // SHA256(Parent|Data|Message)
+	Timestamp int64  `json:"timestamp"` // OS time when commit was created
+}
+
+// commitDigest returns the digest of a commit.
+func commitDigest(parent, data []byte, message string) []byte {
+	h := sha256.New()
+	h.Write(parent)
+	h.Write(data)
+	h.Write([]byte(message))
+	return h.Sum(nil)
+}
+
+// levelBackEnd is a leveldb based backend context that satisfies the backend
+// interface.  It provides the same record semantics as gitbe without
+// requiring git.
+type levelBackEnd struct {
+	sync.Mutex                   // Global lock
+	cron        *cron.Cron       // Scheduler for periodic tasks
+	shutdown    bool             // Backend is shutdown
+	root        string           // Root directory
+	db          *leveldb.DB      // Database context
+	timestamper util.Timestamper // Dcrtime client
+	test        bool             // Set during UT
+	exit        chan struct{}    // Close channel
+	wg          sync.WaitGroup   // Anchor checker
+	checkAnchor chan struct{}    // Work notification
+	plugins     backend.Plugins  // Plugins
+
+	// The following items are used for testing only
+	testAnchors map[string]bool // [digest]anchored
+}
+
+func latestKey(id string) []byte {
+	return []byte(prefixLatest + id)
+}
+
+func recordKey(id, version string) []byte {
+	return []byte(prefixRecord + id + ":" + version)
+}
+
+func tombstoneKey(id string) []byte {
+	return []byte(prefixPurged + id)
+}
+
+func commitKey(sequence uint64) []byte {
+	return []byte(fmt.Sprintf("%v%016x", prefixCommit, sequence))
+}
+
+// batchJSON JSON encodes the provided value and adds it to the batch.
+func batchJSON(batch *leveldb.Batch, key []byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	batch.Put(key, payload)
+	return nil
+}
+
+// isUnvetted returns true if the status belongs to a record that lives in the
+// unvetted set.
+func isUnvetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusUnvetted ||
+		status == backend.MDStatusIterationUnvetted ||
+		status == backend.MDStatusCensored
+}
+
+// isVetted returns true if the status belongs to a record that lives in the
+// vetted set.
+func isVetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusVetted ||
+		status == backend.MDStatusArchived
+}
+
+// lastCommit returns the last entry of the commit log.  An empty commit is
+// returned if the commit log is empty.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) lastCommit() (*Commit, error) {
+	payload, err := l.db.Get([]byte(keyLastCommit), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return &Commit{}, nil
+		}
+		return nil, err
+	}
+
+	var c Commit
+	err = json.Unmarshal(payload, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// commit appends an entry to the commit log and atomically writes it to the
+// database together with the provided batch.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) commit(batch *leveldb.Batch, message string, data []byte) error {
+	last, err := l.lastCommit()
+	if err != nil {
+		return err
+	}
+
+	c := Commit{
+		Sequence:  last.Sequence + 1,
+		Parent:    last.Digest,
+		Data:      util.Digest(data),
+		Message:   message,
+		Timestamp: time.Now().Unix(),
+	}
+	c.Digest = commitDigest(c.Parent, c.Data, c.Message)
+
+	err = batchJSON(batch, commitKey(c.Sequence), c)
+	if err != nil {
+		return err
+	}
+	err = batchJSON(batch, []byte(keyLastCommit), c)
+	if err != nil {
+		return err
+	}
+
+	log.Tracef("commit %v %x: %v", c.Sequence, c.Digest, c.Message)
+
+	return l.db.Write(batch, nil)
+}
+
+// commitsSince returns all commits with a sequence number greater than the
+// provided one.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) commitsSince(sequence uint64) ([]Commit, error) {
+	iter := l.db.NewIterator(ldbutil.BytesPrefix([]byte(prefixCommit)), nil)
+	defer iter.Release()
+
+	commits := make([]Commit, 0)
+	for ok := iter.Seek(commitKey(sequence + 1)); ok; ok = iter.Next() {
+		var c Commit
+		err := json.Unmarshal(iter.Value(), &c)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+
+	return commits, iter.Error()
+}
+
+// fsck walks the commit log and verifies that it is an unbroken hash chain.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) fsck() error {
+	commits, err := l.commitsSince(0)
+	if err != nil {
+		return err
+	}
+
+	var parent []byte
+	for k, c := range commits {
+		if c.Sequence != uint64(k+1) {
+			return fmt.Errorf("commit %v: unexpected sequence %v",
+				k+1, c.Sequence)
+		}
+		if !bytes.Equal(c.Parent, parent) {
+			return fmt.Errorf("commit %v: invalid parent %x",
+				c.Sequence, c.Parent)
+		}
+		if !bytes.Equal(c.Digest, commitDigest(c.Parent, c.Data,
+			c.Message)) {
+			return fmt.Errorf("commit %v: invalid digest %x",
+				c.Sequence, c.Digest)
+		}
+		parent = c.Digest
+	}
+
+	log.Infof("fsck: verified %v commits", len(commits))
+
+	return nil
+}
+
+// getRecord returns the requested version of a record.  The latest version is
+// returned if version is empty.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) getRecord(id, version string) (*backend.Record, error) {
+	if version == "" {
+		v, err := l.db.Get(latestKey(id), nil)
+		if err != nil {
+			if err == leveldb.ErrNotFound {
+				return nil, backend.ErrRecordNotFound
+			}
+			return nil, err
+		}
+		version = string(v)
+	}
+
+	payload, err := l.db.Get(recordKey(id, version), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, backend.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	var r backend.Record
+	err = json.Unmarshal(payload, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// putRecord stores the record as the latest version and appends the change to
+// the commit log.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) putRecord(r *backend.Record, message string) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	id := r.RecordMetadata.Token
+	batch := new(leveldb.Batch)
+	batch.Put(recordKey(id, r.Version), payload)
+	batch.Put(latestKey(id), []byte(r.Version))
+
+	return l.commit(batch, message, payload)
+}
+
+// New takes a record verifies it and stores it in the database as an unvetted
+// record.  The function returns a RecordMetadata.
+//
+// New satisfies the backend interface.
+func (l *levelBackEnd) New(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
+	log.Tracef("New")
+	fa, err := backend.VerifyContent(metadata, files, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a censorship token.
+	token, err := util.Random(pd.TokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("New %x", token)
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	hook := backend.HookNewRecord{
+		Metadata: metadata,
+		Files:    files,
+	}
+	err = l.plugins.Hook(backend.HookPreNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	id := hex.EncodeToString(token)
+	bf, err := backend.ConvertVerifiedFiles(fa)
+	if err != nil {
+		return nil, err
+	}
+	backend.SortFiles(bf)
+	rm, err := backend.CreateRecordMetadata(id, backend.MDStatusUnvetted, 1, bf)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Version:        "1",
+		Metadata:       backend.ApplyMetadata(nil, nil, metadata),
+		Files:          bf,
+	}
+
+	hook.RecordMetadata = rm
+	err = l.plugins.Hook(backend.HookPostNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.putRecord(&r, "Add record "+id)
+	if err != nil {
+		return nil, err
+	}
+
+	return rm, nil
+}
+
+// updateRecord is the generic implementation of UpdateVettedRecord and
+// UpdateUnvettedRecord.
+func (l *levelBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string, vetted bool) (*backend.Record, error) {
+	log.Tracef("updateRecord: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	fa, err := backend.VerifyContent(allMD, filesAdd, filesDel)
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return nil, err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return nil, err
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	old, err := l.getRecord(id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	status := old.RecordMetadata.Status
+	if vetted {
+		switch status {
+		case backend.MDStatusVetted:
+		case backend.MDStatusArchived:
+			return nil, backend.ErrRecordArchived
+		default:
+			return nil, backend.ErrRecordNotFound
+		}
+	} else {
+		switch status {
+		case backend.MDStatusUnvetted, backend.MDStatusIterationUnvetted:
+		case backend.MDStatusVetted, backend.MDStatusArchived:
+			return nil, backend.ErrRecordFound
+		default:
+			return nil, fmt.Errorf("can not update record that "+
+				"has status: %v %v", status, backend.MDStatus[status])
+		}
+	}
+
+	hook := backend.HookEditRecord{
+		Token:       id,
+		Vetted:      vetted,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	err = l.plugins.Hook(backend.HookPreEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify all deletes before executing
+	files := make(map[string]backend.File, len(old.Files))
+	for _, v := range old.Files {
+		files[v.Name] = v
+	}
+	for _, v := range filesDel {
+		if _, ok := files[v]; !ok {
+			return nil, backend.ContentVerificationError{
+				ErrorCode:    pd.ErrorStatusFileNotFound,
+				ErrorContext: []string{v},
+			}
+		}
+		delete(files, v)
+	}
+	added, err := backend.ConvertVerifiedFiles(fa)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range added {
+		files[v.Name] = v
+	}
+	if len(files) == 0 {
+		return nil, backend.ContentVerificationError{
+			ErrorCode: pd.ErrorStatusEmpty,
+		}
+	}
+	bf := make([]backend.File, 0, len(files))
+	for _, v := range files {
+		bf = append(bf, v)
+	}
+	backend.SortFiles(bf)
+	md := backend.ApplyMetadata(old.Metadata, mdAppend, mdOverwrite)
+
+	// If there are no changes DO NOT update the record and reply with no
+	// changes.
+	if reflect.DeepEqual(bf, old.Files) &&
+		reflect.DeepEqual(md, old.Metadata) {
+		return nil, backend.ErrNoChanges
+	}
+
+	// Delete the vote authorization since the record content changed.
+	for k, v := range md {
+		if v.ID == decredplugin.MDStreamAuthorizeVote {
+			md = append(md[:k], md[k+1:]...)
+			break
+		}
+	}
+
+	ns := backend.MDStatusIterationUnvetted
+	version := old.Version
+	if vetted {
+		ns = backend.MDStatusVetted
+		v, err := strconv.ParseUint(old.Version, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		version = strconv.FormatUint(v+1, 10)
+	}
+	rm, err := backend.CreateRecordMetadata(id, ns, old.RecordMetadata.Iteration+1, bf)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Version:        version,
+		Metadata:       md,
+		Files:          bf,
+	}
+
+	err = l.plugins.Hook(backend.HookPostEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.putRecord(&r, "Update record "+id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// UpdateVettedRecord updates the vetted record by creating a new version.
+//
+// This function is part of the interface.
+func (l *levelBackEnd) UpdateVettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateVettedRecord %x", token)
+	return l.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
+		true)
+}
+
+// UpdateUnvettedRecord updates the unvetted record.
+//
+// This function is part of the interface.
+func (l *levelBackEnd) UpdateUnvettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateUnvettedRecord %x", token)
+	return l.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
+		false)
+}
+
+// UpdateVettedMetadata updates metadata in vetted record.  Record itself is
+// not changed.
+//
+// UpdateVettedMetadata satisfies the backend interface.
+func (l *levelBackEnd) UpdateVettedMetadata(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream) error {
+	log.Debugf("UpdateVettedMetadata: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	_, err := backend.VerifyContent(allMD, []backend.File{}, []string{})
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return err
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	r, err := l.getRecord(id, "")
+	if err != nil {
+		return err
+	}
+	switch r.RecordMetadata.Status {
+	case backend.MDStatusVetted:
+	case backend.MDStatusArchived:
+		return backend.ErrRecordArchived
+	default:
+		return backend.ErrRecordNotFound
+	}
+
+	md := backend.ApplyMetadata(r.Metadata, mdAppend, mdOverwrite)
+	if reflect.DeepEqual(md, r.Metadata) {
+		return backend.ErrNoChanges
+	}
+	r.Metadata = md
+
+	log.Debugf("updating vetted metadata %x", token)
+
+	return l.putRecord(r, "Update vetted metadata "+id)
+}
+
+// UpdateReadme updates the README.md content.
+//
+// UpdateReadme satisfies the backend interface.
+func (l *levelBackEnd) UpdateReadme(content string) error {
+	log.Debugf("UpdateReadme")
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return backend.ErrShutdown
+	}
+
+	readme, err := l.db.Get([]byte(keyReadme), nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+	if string(readme) == content {
+		return backend.ErrNoChanges
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(keyReadme), []byte(content))
+
+	return l.commit(batch, "Update README.md", []byte(content))
+}
+
+// readme returns the README.md content.
+func (l *levelBackEnd) readme() (string, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	readme, err := l.db.Get([]byte(keyReadme), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return string(readme), nil
+}
+
+// recordExists returns true if the latest version of the record has a status
+// that satisfies the provided function.
+func (l *levelBackEnd) recordExists(token []byte, f func(backend.MDStatusT) bool) bool {
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return false
+	}
+
+	r, err := l.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return false
+	}
+
+	return f(r.RecordMetadata.Status)
+}
+
+// UnvettedExists returns whether the given token corresponds to an unvetted
+// record.
+//
+// UnvettedExists satisfies the backend interface.
+func (l *levelBackEnd) UnvettedExists(token []byte) bool {
+	log.Tracef("UnvettedExists %x", token)
+	return l.recordExists(token, isUnvetted)
+}
+
+// VettedExists returns whether the given token corresponds to a vetted
+// record.
+//
+// VettedExists satisfies the backend interface.
+func (l *levelBackEnd) VettedExists(token []byte) bool {
+	log.Tracef("VettedExists %x", token)
+	return l.recordExists(token, isVetted)
+}
+
+// getRecordLock returns the requested record if its status satisfies the
+// provided function.
+//
+// This function must be called WITHOUT the lock held.
+func (l *levelBackEnd) getRecordLock(token []byte, version string, f func(backend.MDStatusT) bool) (*backend.Record, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	r, err := l.getRecord(hex.EncodeToString(token), version)
+	if err != nil {
+		return nil, err
+	}
+	if !f(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	return r, nil
+}
+
+// GetUnvetted returns an unvetted record.
+//
+// GetUnvetted satisfies the backend interface.
+func (l *levelBackEnd) GetUnvetted(token []byte) (*backend.Record, error) {
+	log.Debugf("GetUnvetted %x", token)
+	return l.getRecordLock(token, "", isUnvetted)
+}
+
+// GetVetted returns the requested version of a vetted record.
+//
+// GetVetted satisfies the backend interface.
+func (l *levelBackEnd) GetVetted(token []byte, version string) (*backend.Record, error) {
+	log.Debugf("GetVetted %x", token)
+	return l.getRecordLock(token, version, isVetted)
+}
+
+// setStatus updates the record status, handles the metadata and returns the
+// updated record without the Files component.
+//
+// This function must be called with the lock held.
+func (l *levelBackEnd) setStatus(r *backend.Record, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream, msg string) (*backend.Record, error) {
+	hook := backend.HookSetRecordStatus{
+		Token:       r.RecordMetadata.Token,
+		Current:     r.RecordMetadata.Status,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	err := l.plugins.Hook(backend.HookPreSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	r.RecordMetadata.Status = status
+	r.RecordMetadata.Iteration += 1
+	r.RecordMetadata.Timestamp = time.Now().Unix()
+	r.Metadata = backend.ApplyMetadata(r.Metadata, mdAppend, mdOverwrite)
+
+	err = l.plugins.Hook(backend.HookPostSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.putRecord(r, "Update record status "+
+		r.RecordMetadata.Token+" "+msg)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Files = nil
+	return r, nil
+}
+
+// SetUnvettedStatus tries to update the status for an unvetted record. It
+// returns the updated record if successful but without the Files component.
+//
+// SetUnvettedStatus satisfies the backend interface.
+func (l *levelBackEnd) SetUnvettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	r, err := l.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return nil, err
+	}
+	if !isUnvetted(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// We only allow a transition from unvetted to vetted or censored
+	switch {
+	case (r.RecordMetadata.Status == backend.MDStatusUnvetted ||
+		r.RecordMetadata.Status == backend.MDStatusIterationUnvetted) &&
+		status == backend.MDStatusVetted:
+		// unvetted -> vetted
+		return l.setStatus(r, status, mdAppend, mdOverwrite, "published")
+
+	case (r.RecordMetadata.Status == backend.MDStatusUnvetted ||
+		r.RecordMetadata.Status == backend.MDStatusIterationUnvetted) &&
+		status == backend.MDStatusCensored:
+		// unvetted -> censored
+		return l.setStatus(r, status, mdAppend, mdOverwrite, "censored")
+	}
+
+	return nil, backend.StateTransitionError{
+		From: r.RecordMetadata.Status,
+		To:   status,
+	}
+}
+
+// SetVettedStatus tries to update the status for a vetted record.  It returns
+// the updated record if successful but without the Files component.
+//
+// SetVettedStatus satisfies the backend interface.
+func (l *levelBackEnd) SetVettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	r, err := l.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return nil, err
+	}
+	if !isVetted(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Make sure record is not locked.
+	if r.RecordMetadata.Status == backend.MDStatusArchived {
+		return nil, backend.ErrRecordArchived
+	}
+
+	// We only allow a transition from vetted to archived
+	if status != backend.MDStatusArchived {
+		return nil, backend.StateTransitionError{
+			From: r.RecordMetadata.Status,
+			To:   status,
+		}
+	}
+
+	return l.setStatus(r, status, mdAppend, mdOverwrite, "archived")
+}
+
+// PurgeRecord removes the files of all versions of a censored record and
+// leaves a tombstone in their place.  The database is compacted afterwards so
+// that the file payloads do not linger on disk.
+//
+// PurgeRecord satisfies the backend interface.
+func (l *levelBackEnd) PurgeRecord(token []byte, reason string) (*backend.Tombstone, error) {
+	log.Debugf("PurgeRecord %x", token)
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	r, err := l.getRecord(id, "")
+	if err != nil {
+		return nil, err
+	}
+	if r.RecordMetadata.Status != backend.MDStatusCensored {
+		return nil, backend.ErrRecordNotCensored
+	}
+	ok, err := l.db.Has(tombstoneKey(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, backend.ErrRecordPurged
+	}
+
+	latest, err := strconv.Atoi(r.Version)
+	if err != nil {
+		return nil, err
+	}
+	ts := backend.NewTombstone(r, reason)
+
+	// Rewrite all versions without files.
+	batch := new(leveldb.Batch)
+	for v := 1; v <= latest; v++ {
+		version := strconv.Itoa(v)
+		rv, err := l.getRecord(id, version)
+		if err != nil {
+			return nil, err
+		}
+		rv.Files = nil
+		err = batchJSON(batch, recordKey(id, version), rv)
+		if err != nil {
+			return nil, err
+		}
+	}
+	payload, err := json.Marshal(ts)
+	if err != nil {
+		return nil, err
+	}
+	batch.Put(tombstoneKey(id), payload)
+
+	err = l.commit(batch, "Purge record "+id, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get rid of the stale payloads.
+	err = l.db.CompactRange(*ldbutil.BytesPrefix(recordKey(id, "")))
+	if err != nil {
+		log.Errorf("PurgeRecord: compact %v: %v", id, err)
+	}
+
+	return ts, nil
+}
+
+// GetTombstone returns the tombstone of a purged record.
+//
+// GetTombstone satisfies the backend interface.
+func (l *levelBackEnd) GetTombstone(token []byte) (*backend.Tombstone, error) {
+	log.Debugf("GetTombstone %x", token)
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	payload, err := l.db.Get(tombstoneKey(hex.EncodeToString(token)), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, backend.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	var ts backend.Tombstone
+	err = json.Unmarshal(payload, &ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ts, nil
+}
+
+// GetTimestamps is not supported by this backend.
+//
+// GetTimestamps satisfies the backend interface.
+func (l *levelBackEnd) GetTimestamps(token []byte) ([]backend.RecordTimestamp, error) {
+	return nil, backend.ErrNotSupported
+}
+
+// Inventory returns an inventory of vetted and unvetted records.  If
+// includeFiles is set the content is also returned.
+//
+// Inventory satisfies the backend interface.
+func (l *levelBackEnd) Inventory(vettedCount, branchCount uint, includeFiles, allVersions bool) ([]backend.Record, []backend.Record, error) {
+	log.Debugf("Inventory: %v %v %v", vettedCount, branchCount, includeFiles)
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, nil, backend.ErrShutdown
+	}
+
+	iter := l.db.NewIterator(ldbutil.BytesPrefix([]byte(prefixLatest)), nil)
+	defer iter.Release()
+
+	pr := make([]backend.Record, 0)
+	br := make([]backend.Record, 0)
+	for iter.Next() {
+		id := string(iter.Key()[len(prefixLatest):])
+		r, err := l.getRecord(id, string(iter.Value()))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !includeFiles {
+			r.Files = nil
+		}
+
+		if isUnvetted(r.RecordMetadata.Status) {
+			br = append(br, *r)
+			continue
+		}
+		pr = append(pr, *r)
+
+		if allVersions {
+			// Include all versions of the record
+			latest, err := strconv.Atoi(r.Version)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := 1; i < latest; i++ {
+				r, err := l.getRecord(id, strconv.Itoa(i))
+				if err != nil {
+					return nil, nil, err
+				}
+				if !includeFiles {
+					r.Files = nil
+				}
+				pr = append(pr, *r)
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+
+	return pr, br, nil
+}
+
+// InventoryPage returns a filtered page of records, ordered by token, and the
+// cursor of the next page.
+//
+// InventoryPage satisfies the backend interface.
+func (l *levelBackEnd) InventoryPage(q backend.InventoryQuery) ([]backend.Record, string, error) {
+	log.Debugf("InventoryPage: %v %v", q.Cursor, q.Limit)
+
+	l.Lock()
+	defer l.Unlock()
+	if l.shutdown {
+		return nil, "", backend.ErrShutdown
+	}
+
+	// Only walk the keys that sort after the cursor.
+	r := ldbutil.BytesPrefix([]byte(prefixLatest))
+	if q.Cursor != "" {
+		r.Start = append(latestKey(q.Cursor), 0)
+	}
+	iter := l.db.NewIterator(r, nil)
+	defer iter.Release()
+
+	tokens := make([]string, 0)
+	for iter.Next() {
+		tokens = append(tokens, string(iter.Key()[len(prefixLatest):]))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", err
+	}
+
+	return backend.PaginateInventory(q, tokens, l.getRecord)
+}
+
+// RegisterPlugin sets up a plugin and registers it with the backend.
+//
+// RegisterPlugin satisfies the backend interface.
+func (l *levelBackEnd) RegisterPlugin(p backend.Plugin) error {
+	log.Debugf("RegisterPlugin: %v", p.ID)
+	return l.plugins.Register(p)
+}
+
+// GetPlugins returns a list of currently supported plugins and their settings.
+//
+// GetPlugins satisfies the backend interface.
+func (l *levelBackEnd) GetPlugins() ([]backend.Plugin, error) {
+	log.Debugf("GetPlugins")
+	return l.plugins.Get(), nil
+}
+
+// Plugin send a passthrough command. The return values are: incomming command
+// identifier, encoded command result and an error if the command failed to
+// execute.
+//
+// Plugin satisfies the backend interface.
+func (l *levelBackEnd) Plugin(pluginID, command, payload string) (string, string, error) {
+	log.Debugf("Plugin: %v %v", pluginID, command)
+	reply, err := l.plugins.Cmd(pluginID, command, payload)
+	return command, reply, err
+}
+
+// Close shuts down the backend.  It obtains the lock and sets the shutdown
+// boolean to true and waits for the anchor checker to exit.  All interface
+// functions MUST return with errShutdown if the backend is shutting down.
+//
+// Close satisfies the backend interface.
+func (l *levelBackEnd) Close() {
+	log.Debugf("Close")
+
+	l.Lock()
+	l.shutdown = true
+	close(l.exit)
+	l.Unlock()
+
+	// Wait for the anchor checker without holding the lock since the
+	// checker obtains it.
+	l.wg.Wait()
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.cron.Stop()
+	l.db.Close()
+}
+
+// openVersion verifies the database version or, if the database is new,
+// stores the current version.
+func (l *levelBackEnd) openVersion() error {
+	payload, err := l.db.Get([]byte(keyVersion), nil)
+	if err == leveldb.ErrNotFound {
+		v, err := json.Marshal(Version{
+			Version: DbVersion,
+			Time:    time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+		return l.db.Put([]byte(keyVersion), v, nil)
+	} else if err != nil {
+		return err
+	}
+
+	var v Version
+	err = json.Unmarshal(payload, &v)
+	if err != nil {
+		return err
+	}
+	if v.Version != DbVersion {
+		return fmt.Errorf("unsupported database version: got %v want %v",
+			v.Version, DbVersion)
+	}
+
+	return nil
+}
+
+// New returns a levelBackEnd context.  The database is created in the root
+// directory if it does not exist.
+func New(root string, ts util.Timestamper) (*levelBackEnd, error) {
+	dbPath := filepath.Join(root, DefaultDbPath)
+	log.Infof("Database: %v", dbPath)
+	db, err := leveldb.OpenFile(dbPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &levelBackEnd{
+		root:        root,
+		db:          db,
+		cron:        cron.New(),
+		timestamper: ts,
+		exit:        make(chan struct{}),
+		checkAnchor: make(chan struct{}),
+		testAnchors: make(map[string]bool),
+	}
+
+	err = l.openVersion()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Launch anchor checker and don't do any work just yet.
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.periodicAnchorChecker()
+	}()
+
+	// Launch cron.
+	err = l.cron.AddFunc(anchorSchedule, l.anchorRecordsCronJob)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	l.cron.Start()
+
+	// Message user
+	log.Infof("Timestamp host: %v", l.timestamper)
+
+	log.Infof("Running fsck on commit log")
+	l.Lock()
+	err = l.fsck()
+	l.Unlock()
+	if err != nil {
+		// Log error but continue
+		log.Errorf("fsck: %v", err)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,490 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package levelbe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/slog"
+	"github.com/thi4go/politeia/decredplugin"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/mime"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/backend/backendtest"
+	"github.com/thi4go/politeia/util"
+)
+
+type testWriter struct {
+	t *testing.T
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.t.Logf("%s", p)
+	return len(p), nil
+}
+
+func newTestBackEnd(t *testing.T) (*levelBackEnd, func()) {
+	t.Helper()
+
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := New(dir, util.NewDcrtime(""))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	l.test = true
+
+	return l, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestFiles(t *testing.T, name string, count int) []backend.File {
+	t.Helper()
+
+	files := make([]backend.File, 0, count)
+	for j := 0; j < count; j++ {
+		r, err := util.Random(64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Create text file
+		payload := hex.EncodeToString(r)
+		digest := hex.EncodeToString(util.Digest([]byte(payload)))
+		// We expect base64 encoded content
+		b64 := base64.StdEncoding.EncodeToString([]byte(payload))
+
+		files = append(files, backend.File{
+			Name:    name + "_" + strconv.Itoa(j),
+			MIME:    mime.DetectMimeType([]byte(payload)),
+			Digest:  digest,
+			Payload: b64,
+		})
+	}
+	return files
+}
+
+func validateMD(got, want *backend.RecordMetadata) error {
+	if got.Iteration != want.Iteration+1 ||
+		got.Status != backend.MDStatusVetted ||
+		want.Status != backend.MDStatusUnvetted ||
+		got.Merkle != want.Merkle ||
+		got.Token != want.Token {
+		return fmt.Errorf("unexpected rm got %v, wanted %v",
+			spew.Sdump(*got), spew.Sdump(*want))
+	}
+
+	return nil
+}
+
+func TestAnchorWithCommits(t *testing.T) {
+	l, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	// Create 5 unvetted records
+	propCount := 5
+	fileCount := 3
+	t.Logf("===== CREATE %v RECORDS WITH %v FILES =====", propCount,
+		fileCount)
+	rm := make([]*backend.RecordMetadata, propCount)
+	allFiles := make([][]backend.File, propCount)
+	for i := 0; i < propCount; i++ {
+		allFiles[i] = newTestFiles(t, fmt.Sprintf("record%v", i),
+			fileCount)
+
+		var err error
+		rm[i], err = l.New([]backend.MetadataStream{{
+			ID:      0,
+			Payload: "this is metadata",
+		}}, allFiles[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Expect propCount unvetted records
+	vetted, unvetted, err := l.Inventory(0, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 0 || len(unvetted) != propCount {
+		t.Fatalf("unexpected inventory got %v/%v wanted 0/%v",
+			len(vetted), len(unvetted), propCount)
+	}
+
+	// Call getunvetted to verify integrity
+	for k, v := range rm {
+		token, err := hex.DecodeString(v.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pru, err := l.GetUnvetted(token)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if !reflect.DeepEqual(&pru.RecordMetadata, rm[k]) {
+			t.Fatalf("unexpected rm got %v, wanted %v",
+				spew.Sdump(pru.RecordMetadata),
+				spew.Sdump(rm[k]))
+		}
+		if !reflect.DeepEqual(pru.Files, allFiles[k]) {
+			t.Fatalf("unexpected payload got %v, wanted %v",
+				spew.Sdump(pru.Files), spew.Sdump(allFiles[k]))
+		}
+	}
+
+	// Vet 1 of the records
+	t.Logf("===== VET RECORD 1 =====")
+	emptyMD := []backend.MetadataStream{}
+	token, err := hex.DecodeString(rm[1].Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := l.SetUnvettedStatus(token,
+		backend.MDStatusVetted, emptyMD, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RecordMetadata.Status != backend.MDStatusVetted {
+		t.Fatalf("unexpected status: got %v wanted %v",
+			record.RecordMetadata.Status, backend.MDStatusVetted)
+	}
+	// Get it as well to validate the GetVetted call
+	pru, err := l.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	psrG := &pru.RecordMetadata
+	err = validateMD(psrG, rm[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pru.Files, allFiles[1]) {
+		t.Fatalf("unexpected payload got %v, wanted %v",
+			spew.Sdump(pru.Files), spew.Sdump(allFiles[1]))
+	}
+	_, err = l.GetUnvetted(token)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Anchor all records
+	t.Logf("===== ANCHOR =====")
+	err = l.anchorRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read unconfirmed and verify content
+	unconfirmed, err := l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed.Merkles) != 1 {
+		t.Fatalf("invalid merkles len %v", len(unconfirmed.Merkles))
+	}
+	var mr [sha256.Size]byte
+	copy(mr[:], unconfirmed.Merkles[0])
+	anchor, err := l.readAnchorRecord(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchor.Digests) != propCount+1 {
+		t.Fatalf("invalid anchor digests got %v wanted %v",
+			len(anchor.Digests), propCount+1)
+	}
+	if anchor.Type != AnchorUnverified {
+		t.Fatalf("invalid anchor type %v expected %v", anchor.Type,
+			AnchorUnverified)
+	}
+	// Verify last commit
+	lastCommit, err := l.lastCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	la, err := l.readLastAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(lastCommit.Digest, la.Last) {
+		t.Fatalf("invalid unconfirmed digest got %x wanted %x",
+			lastCommit.Digest, la.Last)
+	}
+
+	// Anchor again and make sure nothing changed
+	t.Logf("===== REANCHOR NOTHING TO DO =====")
+	err = l.anchorRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed2, err := l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unconfirmed, unconfirmed2) {
+		t.Fatalf("unconfirmed got %v wanted %v",
+			spew.Sdump(unconfirmed2),
+			spew.Sdump(unconfirmed))
+	}
+	la2, err := l.readLastAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(la, la2) {
+		t.Fatalf("last anchor got %v wanted %v", spew.Sdump(la2),
+			spew.Sdump(la))
+	}
+
+	// Complete anchor
+	t.Logf("===== COMPLETE ANCHOR PROCESS =====")
+	err = l.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err = l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed.Merkles) != 0 {
+		t.Fatalf("invalid merkles len %v", len(unconfirmed.Merkles))
+	}
+	anchor, err = l.readAnchorRecord(mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anchor.Type != AnchorVerified {
+		t.Fatalf("invalid anchor type %v expected %v", anchor.Type,
+			AnchorVerified)
+	}
+	if anchor.ChainInformation == nil ||
+		anchor.ChainInformation.Transaction != expectedTestTX {
+		t.Fatalf("invalid chain information %v",
+			spew.Sdump(anchor.ChainInformation))
+	}
+
+	// Interleave incomplete anchors:
+	//	vet -> anchor1 -> vet -> anchor2 -> confirm
+	t.Logf("===== INTERLEAVE ANCHORS =====")
+	for _, i := range []int{2, 0} {
+		token, err := hex.DecodeString(rm[i].Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = l.SetUnvettedStatus(token, backend.MDStatusVetted,
+			emptyMD, emptyMD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = l.anchorRecords()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	unconfirmed, err = l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed.Merkles) != 2 {
+		t.Fatalf("invalid merkles len %v", len(unconfirmed.Merkles))
+	}
+
+	t.Logf("===== COMPLETE INTERLEAVED ANCHOR PROCESS =====")
+	err = l.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err = l.readUnconfirmedAnchorRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unconfirmed.Merkles) != 0 {
+		t.Fatalf("invalid merkles len %v", len(unconfirmed.Merkles))
+	}
+
+	// The commit log must still be intact
+	err = l.fsck()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRecord(t *testing.T) {
+	l, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	files := newTestFiles(t, "record", 2)
+	rm, err := l.New([]backend.MetadataStream{{
+		ID:      0,
+		Payload: "this is metadata",
+	}}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyMD := []backend.MetadataStream{}
+
+	// Vetted updates require a vetted record
+	_, err = l.UpdateVettedRecord(token, emptyMD, emptyMD, files, nil)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Update unvetted record.  The vote authorization must be dropped.
+	avMD := []backend.MetadataStream{{
+		ID:      decredplugin.MDStreamAuthorizeVote,
+		Payload: "authorized",
+	}}
+	_, err = l.UpdateUnvettedRecord(token, avMD, emptyMD, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFiles := newTestFiles(t, "update", 1)
+	r, err := l.UpdateUnvettedRecord(token, emptyMD, emptyMD, newFiles,
+		[]string{files[0].Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "1" || r.RecordMetadata.Iteration != 3 ||
+		r.RecordMetadata.Status != backend.MDStatusIterationUnvetted {
+		t.Fatalf("unexpected record %v", spew.Sdump(r.RecordMetadata))
+	}
+	if len(r.Files) != 2 || len(r.Metadata) != 1 {
+		t.Fatalf("unexpected record content %v", spew.Sdump(r))
+	}
+
+	// No changes
+	_, err = l.UpdateUnvettedRecord(token, emptyMD, emptyMD, nil, nil)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+
+	// Deleting a file that does not exist
+	_, err = l.UpdateUnvettedRecord(token, emptyMD, emptyMD, nil,
+		[]string{"nope"})
+	e, ok := err.(backend.ContentVerificationError)
+	if !ok || e.ErrorCode != pd.ErrorStatusFileNotFound {
+		t.Fatalf("expected ErrorStatusFileNotFound, got %v", err)
+	}
+
+	// Publish and update vetted record, this creates a new version.
+	_, err = l.SetUnvettedStatus(token, backend.MDStatusVetted, emptyMD,
+		emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.UpdateUnvettedRecord(token, emptyMD, emptyMD, newFiles, nil)
+	if err != backend.ErrRecordFound {
+		t.Fatalf("expected ErrRecordFound, got %v", err)
+	}
+	r, err = l.UpdateVettedRecord(token, emptyMD, emptyMD,
+		newTestFiles(t, "vetted", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "2" ||
+		r.RecordMetadata.Status != backend.MDStatusVetted {
+		t.Fatalf("unexpected record %v", spew.Sdump(r.RecordMetadata))
+	}
+	v1, err := l.GetVetted(token, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v1.Files) != 2 {
+		t.Fatalf("unexpected version 1 files %v", len(v1.Files))
+	}
+	vetted, _, err := l.Inventory(0, 0, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vetted) != 2 {
+		t.Fatalf("unexpected inventory length %v", len(vetted))
+	}
+
+	// Vetted metadata
+	err = l.UpdateVettedMetadata(token, emptyMD, emptyMD)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+	err = l.UpdateVettedMetadata(token, []backend.MetadataStream{{
+		ID:      0,
+		Payload: " appended",
+	}}, emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid state transitions
+	_, err = l.SetVettedStatus(token, backend.MDStatusCensored, emptyMD,
+		emptyMD)
+	if _, ok := err.(backend.StateTransitionError); !ok {
+		t.Fatalf("expected StateTransitionError, got %v", err)
+	}
+	_, err = l.SetVettedStatus(token, backend.MDStatusArchived, emptyMD,
+		emptyMD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.SetVettedStatus(token, backend.MDStatusArchived, emptyMD,
+		emptyMD)
+	if err != backend.ErrRecordArchived {
+		t.Fatalf("expected ErrRecordArchived, got %v", err)
+	}
+	err = l.UpdateVettedMetadata(token, avMD, emptyMD)
+	if err != backend.ErrRecordArchived {
+		t.Fatalf("expected ErrRecordArchived, got %v", err)
+	}
+}
+
+func TestUpdateReadme(t *testing.T) {
+	l, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	updatedReadmeContent := "Updated Readme Content!! \n"
+	err := l.UpdateReadme(updatedReadmeContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readme, err := l.readme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readme != updatedReadmeContent {
+		t.Fatalf("Expected README.md content to be: %s \n but got: %s ",
+			updatedReadmeContent, readme)
+	}
+
+	// Trying to update readme to the same content returns an error.
+	err = l.UpdateReadme(updatedReadmeContent)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+}
+
+func TestBackendConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) (backend.Backend, func()) {
+		return newTestBackEnd(t)
+	})
+}
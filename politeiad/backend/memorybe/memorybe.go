@@ -0,0 +1,793 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package memorybe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/thi4go/politeia/decredplugin"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/util"
+)
+
+var (
+	_ backend.Backend = (*memoryBackEnd)(nil)
+)
+
+// memoryBackEnd is an in-memory backend context that satisfies the backend
+// interface.  It enforces the same record semantics as gitbe but keeps
+// everything in memory and does not anchor anything.  It is meant to be used
+// in tests.
+type memoryBackEnd struct {
+	sync.Mutex                              // Global lock
+	shutdown   bool                         // Backend is shutdown
+	readme     string                       // README.md content
+	records    map[string][]backend.Record  // [token]versions
+	tombstones map[string]backend.Tombstone // [token]tombstone
+	plugins    backend.Plugins              // Plugins
+}
+
+// isUnvetted returns true if the status belongs to a record that lives in the
+// unvetted set.
+func isUnvetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusUnvetted ||
+		status == backend.MDStatusIterationUnvetted ||
+		status == backend.MDStatusCensored
+}
+
+// isVetted returns true if the status belongs to a record that lives in the
+// vetted set.
+func isVetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusVetted ||
+		status == backend.MDStatusArchived
+}
+
+// copyRecord returns a deep copy of the provided record so that callers can
+// not modify the records that are held by the backend.
+func copyRecord(r backend.Record) backend.Record {
+	c := r
+	if r.Metadata != nil {
+		c.Metadata = make([]backend.MetadataStream, len(r.Metadata))
+		copy(c.Metadata, r.Metadata)
+	}
+	if r.Files != nil {
+		c.Files = make([]backend.File, len(r.Files))
+		copy(c.Files, r.Files)
+	}
+	return c
+}
+
+// getRecord returns a copy of the requested version of a record.  The latest
+// version is returned if version is empty.
+//
+// This function must be called with the lock held.
+func (m *memoryBackEnd) getRecord(id, version string) (*backend.Record, error) {
+	versions, ok := m.records[id]
+	if !ok {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	v := len(versions)
+	if version != "" {
+		var err error
+		v, err = strconv.Atoi(version)
+		if err != nil || v < 1 || v > len(versions) {
+			return nil, backend.ErrRecordNotFound
+		}
+	}
+
+	r := copyRecord(versions[v-1])
+	return &r, nil
+}
+
+// putRecord stores a copy of the record as the version that is set in the
+// record.  Versions are either an update of the latest version or the next
+// version.
+//
+// This function must be called with the lock held.
+func (m *memoryBackEnd) putRecord(r *backend.Record) error {
+	v, err := strconv.Atoi(r.Version)
+	if err != nil {
+		return err
+	}
+
+	id := r.RecordMetadata.Token
+	versions := m.records[id]
+	switch v {
+	case len(versions):
+		versions[v-1] = copyRecord(*r)
+	case len(versions) + 1:
+		versions = append(versions, copyRecord(*r))
+	default:
+		return fmt.Errorf("invalid version %v: latest %v", v,
+			len(versions))
+	}
+	m.records[id] = versions
+
+	return nil
+}
+
+// New takes a record verifies it and stores it in memory as an unvetted
+// record.  The function returns a RecordMetadata.
+//
+// New satisfies the backend interface.
+func (m *memoryBackEnd) New(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
+	log.Tracef("New")
+	fa, err := backend.VerifyContent(metadata, files, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a censorship token.
+	token, err := util.Random(pd.TokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("New %x", token)
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	if _, ok := m.records[id]; ok {
+		return nil, backend.ErrRecordFound
+	}
+
+	hook := backend.HookNewRecord{
+		Metadata: metadata,
+		Files:    files,
+	}
+	err = m.plugins.Hook(backend.HookPreNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := backend.ConvertVerifiedFiles(fa)
+	if err != nil {
+		return nil, err
+	}
+	backend.SortFiles(bf)
+	rm, err := backend.CreateRecordMetadata(id, backend.MDStatusUnvetted, 1, bf)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Version:        "1",
+		Metadata:       backend.ApplyMetadata(nil, nil, metadata),
+		Files:          bf,
+	}
+
+	hook.RecordMetadata = rm
+	err = m.plugins.Hook(backend.HookPostNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.putRecord(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	return rm, nil
+}
+
+// updateRecord is the generic implementation of UpdateVettedRecord and
+// UpdateUnvettedRecord.
+func (m *memoryBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string, vetted bool) (*backend.Record, error) {
+	log.Tracef("updateRecord: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	fa, err := backend.VerifyContent(allMD, filesAdd, filesDel)
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return nil, err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return nil, err
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	old, err := m.getRecord(id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	status := old.RecordMetadata.Status
+	if vetted {
+		switch status {
+		case backend.MDStatusVetted:
+		case backend.MDStatusArchived:
+			return nil, backend.ErrRecordArchived
+		default:
+			return nil, backend.ErrRecordNotFound
+		}
+	} else {
+		switch status {
+		case backend.MDStatusUnvetted, backend.MDStatusIterationUnvetted:
+		case backend.MDStatusVetted, backend.MDStatusArchived:
+			return nil, backend.ErrRecordFound
+		default:
+			return nil, fmt.Errorf("can not update record that "+
+				"has status: %v %v", status, backend.MDStatus[status])
+		}
+	}
+
+	hook := backend.HookEditRecord{
+		Token:       id,
+		Vetted:      vetted,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	err = m.plugins.Hook(backend.HookPreEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify all deletes before executing
+	files := make(map[string]backend.File, len(old.Files))
+	for _, v := range old.Files {
+		files[v.Name] = v
+	}
+	for _, v := range filesDel {
+		if _, ok := files[v]; !ok {
+			return nil, backend.ContentVerificationError{
+				ErrorCode:    pd.ErrorStatusFileNotFound,
+				ErrorContext: []string{v},
+			}
+		}
+		delete(files, v)
+	}
+	added, err := backend.ConvertVerifiedFiles(fa)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range added {
+		files[v.Name] = v
+	}
+	if len(files) == 0 {
+		return nil, backend.ContentVerificationError{
+			ErrorCode: pd.ErrorStatusEmpty,
+		}
+	}
+	bf := make([]backend.File, 0, len(files))
+	for _, v := range files {
+		bf = append(bf, v)
+	}
+	backend.SortFiles(bf)
+	md := backend.ApplyMetadata(old.Metadata, mdAppend, mdOverwrite)
+
+	// If there are no changes DO NOT update the record and reply with no
+	// changes.
+	if reflect.DeepEqual(bf, old.Files) &&
+		reflect.DeepEqual(md, old.Metadata) {
+		return nil, backend.ErrNoChanges
+	}
+
+	// Delete the vote authorization since the record content changed.
+	for k, v := range md {
+		if v.ID == decredplugin.MDStreamAuthorizeVote {
+			md = append(md[:k], md[k+1:]...)
+			break
+		}
+	}
+
+	ns := backend.MDStatusIterationUnvetted
+	version := old.Version
+	if vetted {
+		ns = backend.MDStatusVetted
+		v, err := strconv.ParseUint(old.Version, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		version = strconv.FormatUint(v+1, 10)
+	}
+	rm, err := backend.CreateRecordMetadata(id, ns, old.RecordMetadata.Iteration+1, bf)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Version:        version,
+		Metadata:       md,
+		Files:          bf,
+	}
+
+	err = m.plugins.Hook(backend.HookPostEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.putRecord(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// UpdateVettedRecord updates the vetted record by creating a new version.
+//
+// This function is part of the interface.
+func (m *memoryBackEnd) UpdateVettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateVettedRecord %x", token)
+	return m.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
+		true)
+}
+
+// UpdateUnvettedRecord updates the unvetted record.
+//
+// This function is part of the interface.
+func (m *memoryBackEnd) UpdateUnvettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateUnvettedRecord %x", token)
+	return m.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
+		false)
+}
+
+// UpdateVettedMetadata updates metadata in vetted record.  Record itself is
+// not changed.
+//
+// UpdateVettedMetadata satisfies the backend interface.
+func (m *memoryBackEnd) UpdateVettedMetadata(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream) error {
+	log.Debugf("UpdateVettedMetadata: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	_, err := backend.VerifyContent(allMD, []backend.File{}, []string{})
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return err
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return backend.ErrShutdown
+	}
+
+	r, err := m.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return err
+	}
+	switch r.RecordMetadata.Status {
+	case backend.MDStatusVetted:
+	case backend.MDStatusArchived:
+		return backend.ErrRecordArchived
+	default:
+		return backend.ErrRecordNotFound
+	}
+
+	md := backend.ApplyMetadata(r.Metadata, mdAppend, mdOverwrite)
+	if reflect.DeepEqual(md, r.Metadata) {
+		return backend.ErrNoChanges
+	}
+	r.Metadata = md
+
+	log.Debugf("updating vetted metadata %x", token)
+
+	return m.putRecord(r)
+}
+
+// UpdateReadme updates the README.md content.
+//
+// UpdateReadme satisfies the backend interface.
+func (m *memoryBackEnd) UpdateReadme(content string) error {
+	log.Debugf("UpdateReadme")
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return backend.ErrShutdown
+	}
+
+	if m.readme == content {
+		return backend.ErrNoChanges
+	}
+	m.readme = content
+
+	return nil
+}
+
+// recordExists returns true if the latest version of the record has a status
+// that satisfies the provided function.
+func (m *memoryBackEnd) recordExists(token []byte, f func(backend.MDStatusT) bool) bool {
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return false
+	}
+
+	r, err := m.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return false
+	}
+
+	return f(r.RecordMetadata.Status)
+}
+
+// UnvettedExists returns whether the given token corresponds to an unvetted
+// record.
+//
+// UnvettedExists satisfies the backend interface.
+func (m *memoryBackEnd) UnvettedExists(token []byte) bool {
+	log.Tracef("UnvettedExists %x", token)
+	return m.recordExists(token, isUnvetted)
+}
+
+// VettedExists returns whether the given token corresponds to a vetted
+// record.
+//
+// VettedExists satisfies the backend interface.
+func (m *memoryBackEnd) VettedExists(token []byte) bool {
+	log.Tracef("VettedExists %x", token)
+	return m.recordExists(token, isVetted)
+}
+
+// getRecordLock returns the requested record if its status satisfies the
+// provided function.
+//
+// This function must be called WITHOUT the lock held.
+func (m *memoryBackEnd) getRecordLock(token []byte, version string, f func(backend.MDStatusT) bool) (*backend.Record, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	r, err := m.getRecord(hex.EncodeToString(token), version)
+	if err != nil {
+		return nil, err
+	}
+	if !f(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	return r, nil
+}
+
+// GetUnvetted returns an unvetted record.
+//
+// GetUnvetted satisfies the backend interface.
+func (m *memoryBackEnd) GetUnvetted(token []byte) (*backend.Record, error) {
+	log.Debugf("GetUnvetted %x", token)
+	return m.getRecordLock(token, "", isUnvetted)
+}
+
+// GetVetted returns the requested version of a vetted record.
+//
+// GetVetted satisfies the backend interface.
+func (m *memoryBackEnd) GetVetted(token []byte, version string) (*backend.Record, error) {
+	log.Debugf("GetVetted %x", token)
+	return m.getRecordLock(token, version, isVetted)
+}
+
+// setStatus updates the record status, handles the metadata and returns the
+// updated record without the Files component.
+//
+// This function must be called with the lock held.
+func (m *memoryBackEnd) setStatus(r *backend.Record, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	hook := backend.HookSetRecordStatus{
+		Token:       r.RecordMetadata.Token,
+		Current:     r.RecordMetadata.Status,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	err := m.plugins.Hook(backend.HookPreSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	r.RecordMetadata.Status = status
+	r.RecordMetadata.Iteration += 1
+	r.RecordMetadata.Timestamp = time.Now().Unix()
+	r.Metadata = backend.ApplyMetadata(r.Metadata, mdAppend, mdOverwrite)
+
+	err = m.plugins.Hook(backend.HookPostSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.putRecord(r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Files = nil
+	return r, nil
+}
+
+// SetUnvettedStatus tries to update the status for an unvetted record. It
+// returns the updated record if successful but without the Files component.
+//
+// SetUnvettedStatus satisfies the backend interface.
+func (m *memoryBackEnd) SetUnvettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	r, err := m.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return nil, err
+	}
+	if !isUnvetted(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// We only allow a transition from unvetted to vetted or censored
+	switch {
+	case (r.RecordMetadata.Status == backend.MDStatusUnvetted ||
+		r.RecordMetadata.Status == backend.MDStatusIterationUnvetted) &&
+		status == backend.MDStatusVetted:
+		// unvetted -> vetted
+		return m.setStatus(r, status, mdAppend, mdOverwrite)
+
+	case (r.RecordMetadata.Status == backend.MDStatusUnvetted ||
+		r.RecordMetadata.Status == backend.MDStatusIterationUnvetted) &&
+		status == backend.MDStatusCensored:
+		// unvetted -> censored
+		return m.setStatus(r, status, mdAppend, mdOverwrite)
+	}
+
+	return nil, backend.StateTransitionError{
+		From: r.RecordMetadata.Status,
+		To:   status,
+	}
+}
+
+// SetVettedStatus tries to update the status for a vetted record.  It returns
+// the updated record if successful but without the Files component.
+//
+// SetVettedStatus satisfies the backend interface.
+func (m *memoryBackEnd) SetVettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	r, err := m.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return nil, err
+	}
+	if !isVetted(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Make sure record is not locked.
+	if r.RecordMetadata.Status == backend.MDStatusArchived {
+		return nil, backend.ErrRecordArchived
+	}
+
+	// We only allow a transition from vetted to archived
+	if status != backend.MDStatusArchived {
+		return nil, backend.StateTransitionError{
+			From: r.RecordMetadata.Status,
+			To:   status,
+		}
+	}
+
+	return m.setStatus(r, status, mdAppend, mdOverwrite)
+}
+
+// PurgeRecord removes the files of all versions of a censored record and
+// leaves a tombstone in their place.
+//
+// PurgeRecord satisfies the backend interface.
+func (m *memoryBackEnd) PurgeRecord(token []byte, reason string) (*backend.Tombstone, error) {
+	log.Debugf("PurgeRecord %x", token)
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	r, err := m.getRecord(id, "")
+	if err != nil {
+		return nil, err
+	}
+	if r.RecordMetadata.Status != backend.MDStatusCensored {
+		return nil, backend.ErrRecordNotCensored
+	}
+	if _, ok := m.tombstones[id]; ok {
+		return nil, backend.ErrRecordPurged
+	}
+
+	ts := backend.NewTombstone(r, reason)
+	versions := m.records[id]
+	for k := range versions {
+		versions[k].Files = nil
+	}
+	m.tombstones[id] = *ts
+
+	return ts, nil
+}
+
+// GetTombstone returns the tombstone of a purged record.
+//
+// GetTombstone satisfies the backend interface.
+func (m *memoryBackEnd) GetTombstone(token []byte) (*backend.Tombstone, error) {
+	log.Debugf("GetTombstone %x", token)
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	ts, ok := m.tombstones[hex.EncodeToString(token)]
+	if !ok {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	return &ts, nil
+}
+
+// GetTimestamps is not supported by this backend.
+//
+// GetTimestamps satisfies the backend interface.
+func (m *memoryBackEnd) GetTimestamps(token []byte) ([]backend.RecordTimestamp, error) {
+	return nil, backend.ErrNotSupported
+}
+
+// Inventory returns an inventory of vetted and unvetted records.  If
+// includeFiles is set the content is also returned.
+//
+// Inventory satisfies the backend interface.
+func (m *memoryBackEnd) Inventory(vettedCount, branchCount uint, includeFiles, allVersions bool) ([]backend.Record, []backend.Record, error) {
+	log.Debugf("Inventory: %v %v %v", vettedCount, branchCount, includeFiles)
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, nil, backend.ErrShutdown
+	}
+
+	// Return the records in a stable order.
+	ids := make([]string, 0, len(m.records))
+	for id := range m.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	pr := make([]backend.Record, 0)
+	br := make([]backend.Record, 0)
+	for _, id := range ids {
+		versions := m.records[id]
+		r := copyRecord(versions[len(versions)-1])
+		if !includeFiles {
+			r.Files = nil
+		}
+
+		if isUnvetted(r.RecordMetadata.Status) {
+			br = append(br, r)
+			continue
+		}
+		pr = append(pr, r)
+
+		if allVersions {
+			// Include all versions of the record
+			for _, v := range versions[:len(versions)-1] {
+				r := copyRecord(v)
+				if !includeFiles {
+					r.Files = nil
+				}
+				pr = append(pr, r)
+			}
+		}
+	}
+
+	return pr, br, nil
+}
+
+// InventoryPage returns a filtered page of records, ordered by token, and the
+// cursor of the next page.
+//
+// InventoryPage satisfies the backend interface.
+func (m *memoryBackEnd) InventoryPage(q backend.InventoryQuery) ([]backend.Record, string, error) {
+	log.Debugf("InventoryPage: %v %v", q.Cursor, q.Limit)
+
+	m.Lock()
+	defer m.Unlock()
+	if m.shutdown {
+		return nil, "", backend.ErrShutdown
+	}
+
+	tokens := make([]string, 0, len(m.records))
+	for id := range m.records {
+		tokens = append(tokens, id)
+	}
+
+	return backend.PaginateInventory(q, tokens, m.getRecord)
+}
+
+// RegisterPlugin sets up a plugin and registers it with the backend.
+//
+// RegisterPlugin satisfies the backend interface.
+func (m *memoryBackEnd) RegisterPlugin(p backend.Plugin) error {
+	log.Debugf("RegisterPlugin: %v", p.ID)
+	return m.plugins.Register(p)
+}
+
+// GetPlugins returns a list of currently supported plugins and their settings.
+//
+// GetPlugins satisfies the backend interface.
+func (m *memoryBackEnd) GetPlugins() ([]backend.Plugin, error) {
+	log.Debugf("GetPlugins")
+	return m.plugins.Get(), nil
+}
+
+// Plugin send a passthrough command. The return values are: incomming command
+// identifier, encoded command result and an error if the command failed to
+// execute.  The command is executed without the lock held so that it can call
+// back into the backend.
+//
+// Plugin satisfies the backend interface.
+func (m *memoryBackEnd) Plugin(pluginID, command, payload string) (string, string, error) {
+	log.Debugf("Plugin: %v %v", pluginID, command)
+	reply, err := m.plugins.Cmd(pluginID, command, payload)
+	return command, reply, err
+}
+
+// Close shuts down the backend.  It obtains the lock and sets the shutdown
+// boolean to true.  All interface functions MUST return with errShutdown if
+// the backend is shutting down.
+//
+// Close satisfies the backend interface.
+func (m *memoryBackEnd) Close() {
+	log.Debugf("Close")
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.shutdown = true
+}
+
+// New returns an empty memoryBackEnd context.
+func New() *memoryBackEnd {
+	return &memoryBackEnd{
+		records:    make(map[string][]backend.Record),
+		tombstones: make(map[string]backend.Tombstone),
+	}
+}
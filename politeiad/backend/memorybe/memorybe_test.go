@@ -0,0 +1,96 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package memorybe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/backend/backendtest"
+)
+
+func TestBackendConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) (backend.Backend, func()) {
+		m := New()
+		return m, m.Close
+	})
+}
+
+// upperClient is a plugin client with a single command that upper cases the
+// payload.
+type upperClient struct{}
+
+func (upperClient) Setup() error {
+	return nil
+}
+
+func (upperClient) Cmd(cmd, payload string) (string, error) {
+	if cmd != "upper" {
+		return "", backend.ErrPluginCmdInvalid
+	}
+	return strings.ToUpper(payload), nil
+}
+
+func (upperClient) Hook(h backend.HookT, payload string) error {
+	return nil
+}
+
+func TestPlugin(t *testing.T) {
+	m := New()
+	defer m.Close()
+
+	plugin := backend.Plugin{
+		ID:      "test",
+		Version: "1",
+		Settings: []backend.PluginSetting{
+			{Key: "key", Value: "value"},
+		},
+		Client: upperClient{},
+	}
+	err := m.RegisterPlugin(plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Duplicate and invalid plugins are rejected.
+	err = m.RegisterPlugin(plugin)
+	if err == nil {
+		t.Fatalf("expected duplicate plugin error")
+	}
+	err = m.RegisterPlugin(backend.Plugin{ID: "Invalid",
+		Client: upperClient{}})
+	if err == nil {
+		t.Fatalf("expected invalid plugin id error")
+	}
+	err = m.RegisterPlugin(backend.Plugin{ID: "other"})
+	if err == nil {
+		t.Fatalf("expected missing client error")
+	}
+
+	plugins, err := m.GetPlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || plugins[0].ID != plugin.ID {
+		t.Fatalf("unexpected plugins: %v", plugins)
+	}
+
+	cmd, reply, err := m.Plugin(plugin.ID, "upper", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd != "upper" || reply != "PAYLOAD" {
+		t.Fatalf("unexpected reply: %v %v", cmd, reply)
+	}
+	_, _, err = m.Plugin(plugin.ID, "lower", "payload")
+	if err != backend.ErrPluginCmdInvalid {
+		t.Fatalf("expected invalid command error, got %v", err)
+	}
+	_, _, err = m.Plugin("other", "upper", "payload")
+	if err != backend.ErrPluginNotFound {
+		t.Fatalf("expected plugin not found error, got %v", err)
+	}
+}
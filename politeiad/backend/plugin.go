@@ -0,0 +1,264 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// HookT represents a plugin lifecycle hook.
+type HookT int
+
+const (
+	// All possible plugin hooks.  Pre hooks are executed before a change
+	// is applied and may veto it by returning an error.  Post hooks are
+	// executed once the change has been applied but before it is
+	// committed, which allows plugins to store their own data along with
+	// the change.  An error returned by a post hook aborts the change.
+	HookInvalid             HookT = 0 // Invalid hook
+	HookPreNewRecord        HookT = 1 // Before a record is created
+	HookPostNewRecord       HookT = 2 // After a record is created
+	HookPreEditRecord       HookT = 3 // Before a record is edited
+	HookPostEditRecord      HookT = 4 // After a record is edited
+	HookPreSetRecordStatus  HookT = 5 // Before a record status changes
+	HookPostSetRecordStatus HookT = 6 // After a record status changed
+)
+
+var (
+	// Hooks converts a hook to a human readable string.
+	Hooks = map[HookT]string{
+		HookInvalid:             "invalid hook",
+		HookPreNewRecord:        "pre new record",
+		HookPostNewRecord:       "post new record",
+		HookPreEditRecord:       "pre edit record",
+		HookPostEditRecord:      "post edit record",
+		HookPreSetRecordStatus:  "pre set record status",
+		HookPostSetRecordStatus: "post set record status",
+	}
+
+	// ErrPluginNotFound is emitted when a plugin has not been registered.
+	ErrPluginNotFound = errors.New("plugin not found")
+
+	// ErrPluginCmdInvalid is emitted when a plugin does not support a
+	// command.
+	ErrPluginCmdInvalid = errors.New("invalid plugin command")
+
+	// pluginsMtx protects pluginConstructors.
+	pluginsMtx sync.Mutex
+
+	// pluginConstructors contains all plugins that can be enabled.
+	pluginConstructors = make(map[string]PluginConstructor) // [id]constructor
+)
+
+// PluginCmdExport is the command that is sent to every registered plugin when
+// a record bundle is exported.  The payload is the JSON encoding of
+// PluginExport and the reply is plugin specific.  Plugins that do not keep
+// any record data return ErrPluginCmdInvalid.
+const PluginCmdExport = "export"
+
+// PluginExport is the payload of the export command.
+type PluginExport struct {
+	Token string `json:"token"` // Censorship token
+}
+
+// HookNewRecord is the payload of the new record hooks.  RecordMetadata is
+// only set for the post hook.
+type HookNewRecord struct {
+	RecordMetadata *RecordMetadata  `json:"recordmetadata,omitempty"`
+	Metadata       []MetadataStream `json:"metadata"`
+	Files          []File           `json:"files"`
+}
+
+// HookEditRecord is the payload of the edit record hooks.
+type HookEditRecord struct {
+	Token       string           `json:"token"`       // Censorship token
+	Vetted      bool             `json:"vetted"`      // Record is vetted
+	MDAppend    []MetadataStream `json:"mdappend"`    // Metadata to append
+	MDOverwrite []MetadataStream `json:"mdoverwrite"` // Metadata to overwrite
+	FilesAdd    []File           `json:"filesadd"`    // Files to add
+	FilesDel    []string         `json:"filesdel"`    // Files to delete
+}
+
+// HookSetRecordStatus is the payload of the set record status hooks.
+type HookSetRecordStatus struct {
+	Token       string           `json:"token"`       // Censorship token
+	Current     MDStatusT        `json:"current"`     // Current status
+	Status      MDStatusT        `json:"status"`      // New status
+	MDAppend    []MetadataStream `json:"mdappend"`    // Metadata to append
+	MDOverwrite []MetadataStream `json:"mdoverwrite"` // Metadata to overwrite
+}
+
+// PluginClient is the interface that a politeiad plugin must implement.
+//
+// Hooks are executed with the backend lock held, a plugin must therefore not
+// call back into the backend from a hook.  A hook that rejects a change
+// because of invalid user input should return a ContentVerificationError.
+type PluginClient interface {
+	// Setup performs the one time plugin setup.  It is called when the
+	// plugin is registered with a backend.
+	Setup() error
+
+	// Cmd executes a plugin command and returns the encoded reply.
+	Cmd(cmd, payload string) (string, error)
+
+	// Hook executes a lifecycle hook.  The payload is the JSON encoding
+	// of the payload type of the hook.  Plugins ignore hooks they are
+	// not interested in.
+	Hook(h HookT, payload string) error
+}
+
+// PluginConstructor returns a new plugin for the provided backend.  The
+// settings are the ones provided by the politeiad configuration.
+type PluginConstructor func(b Backend, settings []PluginSetting) (*Plugin, error)
+
+// RegisterPlugin makes a plugin available to politeiad.  It is meant to be
+// called from the init function of the package that implements the plugin
+// and it panics if the plugin id is invalid or already registered.
+func RegisterPlugin(id string, c PluginConstructor) {
+	pluginsMtx.Lock()
+	defer pluginsMtx.Unlock()
+
+	if !PluginRE.MatchString(id) {
+		panic(fmt.Sprintf("invalid plugin id: %v", id))
+	}
+	if _, ok := pluginConstructors[id]; ok {
+		panic(fmt.Sprintf("duplicate plugin: %v", id))
+	}
+	pluginConstructors[id] = c
+}
+
+// RegisteredPlugins returns the sorted ids of all plugins that can be
+// enabled.
+func RegisteredPlugins() []string {
+	pluginsMtx.Lock()
+	defer pluginsMtx.Unlock()
+
+	ids := make([]string, 0, len(pluginConstructors))
+	for k := range pluginConstructors {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// NewPlugin returns a new instance of a registered plugin for the provided
+// backend.
+func NewPlugin(id string, b Backend, settings []PluginSetting) (*Plugin, error) {
+	pluginsMtx.Lock()
+	c, ok := pluginConstructors[id]
+	pluginsMtx.Unlock()
+	if !ok {
+		return nil, ErrPluginNotFound
+	}
+
+	p, err := c(b, settings)
+	if err != nil {
+		return nil, err
+	}
+	if p.ID != id {
+		return nil, fmt.Errorf("plugin %v returned id %v", id, p.ID)
+	}
+	if p.Client == nil {
+		return nil, fmt.Errorf("plugin %v has no client", id)
+	}
+	return p, nil
+}
+
+// Plugins holds the plugins that are registered with a backend.  It is
+// meant to be used by backends to implement plugin registration, plugin
+// commands and hooks.  The zero value is ready to use.
+type Plugins struct {
+	mtx     sync.RWMutex
+	plugins []Plugin // Plugins in order of registration
+}
+
+// Register sets up a plugin and adds it to the list of registered plugins.
+func (p *Plugins) Register(plugin Plugin) error {
+	if !PluginRE.MatchString(plugin.ID) {
+		return fmt.Errorf("invalid plugin id: %v", plugin.ID)
+	}
+	if plugin.Client == nil {
+		return fmt.Errorf("plugin %v has no client", plugin.ID)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, v := range p.plugins {
+		if v.ID == plugin.ID {
+			return fmt.Errorf("duplicate plugin: %v", plugin.ID)
+		}
+	}
+
+	err := plugin.Client.Setup()
+	if err != nil {
+		return fmt.Errorf("setup plugin %v: %v", plugin.ID, err)
+	}
+
+	p.plugins = append(p.plugins, plugin)
+
+	return nil
+}
+
+// Get returns the registered plugins.
+func (p *Plugins) Get() []Plugin {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	plugins := make([]Plugin, len(p.plugins))
+	copy(plugins, p.plugins)
+	return plugins
+}
+
+// Cmd executes a command of the plugin with the provided id.
+func (p *Plugins) Cmd(id, cmd, payload string) (string, error) {
+	p.mtx.RLock()
+	var client PluginClient
+	for _, v := range p.plugins {
+		if v.ID == id {
+			client = v.Client
+			break
+		}
+	}
+	p.mtx.RUnlock()
+
+	if client == nil {
+		return "", ErrPluginNotFound
+	}
+	return client.Cmd(cmd, payload)
+}
+
+// Hook executes a hook of all registered plugins in order of registration.
+// The payload is JSON encoded before it is handed to the plugins.  The first
+// error that is returned by a plugin is returned.
+func (p *Plugins) Hook(h HookT, payload interface{}) error {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if len(p.plugins) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	for _, v := range p.plugins {
+		err := v.Client.Hook(h, string(b))
+		if err != nil {
+			if _, ok := err.(ContentVerificationError); ok {
+				return err
+			}
+			return fmt.Errorf("plugin %v hook %v: %v", v.ID, Hooks[h],
+				err)
+		}
+	}
+
+	return nil
+}
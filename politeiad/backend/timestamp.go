@@ -0,0 +1,37 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	dcrtime "github.com/decred/dcrtime/api/v1"
+	"github.com/decred/dcrtime/merkle"
+)
+
+// TimestampStatusT describes how far along the anchoring process a record
+// version is.
+type TimestampStatusT int
+
+const (
+	TimestampStatusInvalid     TimestampStatusT = 0 // Invalid status
+	TimestampStatusNotAnchored TimestampStatusT = 1 // Not anchored yet
+	TimestampStatusUnconfirmed TimestampStatusT = 2 // Anchor not confirmed
+	TimestampStatusConfirmed   TimestampStatusT = 3 // Anchor confirmed
+)
+
+// RecordTimestamp proves when a record version was anchored in the decred
+// blockchain.  Digest is the commit that created the version.  MerklePath
+// leads from Digest to the anchor merkle root, which is the digest that was
+// timestamped in dcrtime.  ChainInformation leads from the anchor merkle root
+// to the dcrtime merkle root that was included in the transaction and is
+// only set once the anchor has been confirmed.
+type RecordTimestamp struct {
+	Version          string                    // Record version
+	Digest           []byte                    // Anchored commit digest
+	Status           TimestampStatusT          // Anchor status
+	AnchorMerkle     []byte                    // Merkle root of the anchor
+	AnchorTime       int64                     // Time the anchor was dropped
+	MerklePath       *merkle.Branch            // Digest to anchor merkle root
+	ChainInformation *dcrtime.ChainInformation // Dcrtime chain information
+}
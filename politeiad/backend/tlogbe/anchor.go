@@ -0,0 +1,325 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	dcrtime "github.com/decred/dcrtime/api/v1"
+	"github.com/google/trillian"
+	"github.com/thi4go/politeia/politeiad/backend"
+	v1 "github.com/thi4go/politeia/tlog/api/v1"
+	"github.com/thi4go/politeia/util"
+)
+
+// An anchor corresponds to the signed log roots of all trees that changed
+// since the last anchor.  The hash of every log root is timestamped in
+// dcrtime.  Once dcrtime confirms the timestamp a DataAnchor record entry is
+// appended to each tree.  This ties every leaf that precedes the DataAnchor
+// to the blockchain.
+//
+// Unlike gitbe and levelbe the anchor state does not need to be persisted
+// separately.  Trees whose last leaf is not an anchor are simply considered
+// dirty on startup and anchored again.
+
+// pendingAnchor is an anchor that was dropped in dcrtime but that has not been
+// confirmed yet.
+type pendingAnchor struct {
+	trees   []*trillian.Tree     // Anchored trees
+	anchors []v1.DataAnchor      // Signed log root per tree
+	sizes   []uint64             // Tree size per tree
+	digests []*[sha256.Size]byte // Hash of the log root per tree
+}
+
+// scanTrees marks all trees whose last leaf is not an anchor as dirty.
+func (t *tlogBackEnd) scanTrees() error {
+	t.Lock()
+	defer t.Unlock()
+
+	trees, err := t.client.treesAll()
+	if err != nil {
+		return err
+	}
+	for _, tree := range trees {
+		leaves, err := t.client.leavesAll(tree.TreeId)
+		if err != nil {
+			return err
+		}
+		if len(leaves) == 0 ||
+			leafDescriptor(leaves[len(leaves)-1]) == v1.DataDescriptorAnchor {
+			continue
+		}
+		t.dirty[tree.TreeId] = uint64(len(leaves))
+	}
+
+	log.Infof("Unanchored trees: %v", len(t.dirty))
+
+	return nil
+}
+
+// anchor timestamps the provided digests in dcrtime.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) anchor(digests []*[sha256.Size]byte) error {
+	if t.test {
+		for _, v := range digests {
+			t.testAnchors[hex.EncodeToString(v[:])] = false
+		}
+		return nil
+	}
+
+	return t.timestamper.Timestamp("politeia", digests)
+}
+
+// anchorTrees drops an anchor for all trees that changed since they were last
+// anchored.
+func (t *tlogBackEnd) anchorTrees() error {
+	log.Infof("Dropping anchor")
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return fmt.Errorf("anchorTrees: %v", backend.ErrShutdown)
+	}
+
+	if len(t.dirty) == 0 {
+		log.Infof("Anchoring: nothing to do")
+		return nil
+	}
+
+	pa := pendingAnchor{
+		trees:   make([]*trillian.Tree, 0, len(t.dirty)),
+		anchors: make([]v1.DataAnchor, 0, len(t.dirty)),
+		sizes:   make([]uint64, 0, len(t.dirty)),
+		digests: make([]*[sha256.Size]byte, 0, len(t.dirty)),
+	}
+	for treeID := range t.dirty {
+		tree, err := t.client.tree(treeID)
+		if err != nil {
+			return fmt.Errorf("tree %v: %v", treeID, err)
+		}
+		sth, lrv1, err := t.client.signedLogRoot(tree)
+		if err != nil {
+			return fmt.Errorf("signedLogRoot %v: %v", treeID, err)
+		}
+		pa.trees = append(pa.trees, tree)
+		pa.anchors = append(pa.anchors, v1.DataAnchor{
+			RecordId: treeID,
+			STH:      *sth,
+		})
+		pa.sizes = append(pa.sizes, lrv1.TreeSize)
+		pa.digests = append(pa.digests, util.Hash(sth.LogRoot))
+	}
+
+	err := t.anchor(pa.digests)
+	if err != nil {
+		return fmt.Errorf("anchor: %v", err)
+	}
+	t.unconfirmed = append(t.unconfirmed, pa)
+
+	log.Infof("Dropping anchor complete: %v trees", len(pa.trees))
+
+	return nil
+}
+
+// anchorTreesCronJob is the cron job that anchors all dirty trees at a preset
+// time.
+func (t *tlogBackEnd) anchorTreesCronJob() {
+	err := t.anchorTrees()
+	if err != nil {
+		log.Errorf("%v", err)
+	}
+}
+
+// periodicAnchorChecker must be run as a go routine.  It sits around and
+// periodically checks if there is work to do.  It can also be tickled by
+// messaging checkAnchor.
+func (t *tlogBackEnd) periodicAnchorChecker() {
+	log.Infof("Periodic anchor checker launched")
+	defer log.Infof("Periodic anchor checker exited")
+	for {
+		select {
+		case <-t.exit:
+			return
+		case <-t.checkAnchor:
+		case <-time.After(5 * time.Minute):
+		}
+
+		t.Lock()
+		isShutdown := t.shutdown
+		t.Unlock()
+		if isShutdown {
+			return
+		}
+
+		err := t.anchorChecker()
+		if err != nil {
+			// Not much we can do past logging
+			log.Errorf("periodicAnchorChecker: %v", err)
+		}
+	}
+}
+
+// anchorChecker does the work for periodicAnchorChecker.  It lives in its own
+// function for testing purposes.
+func (t *tlogBackEnd) anchorChecker() error {
+	t.Lock()
+	unconfirmed := make([]pendingAnchor, len(t.unconfirmed))
+	copy(unconfirmed, t.unconfirmed)
+	t.Unlock()
+
+	// Check for work
+	if len(unconfirmed) == 0 {
+		return nil
+	}
+
+	confirmed := make([]pendingAnchor, 0, len(unconfirmed))
+	for _, pa := range unconfirmed {
+		vds := make([]dcrtime.VerifyDigest, 0, len(pa.digests))
+		for _, d := range pa.digests {
+			vd, err := t.verifyAnchor(hex.EncodeToString(d[:]))
+			if err != nil {
+				log.Errorf("anchorChecker verify: %v", err)
+				break
+			}
+			if vd.ChainInformation.ChainTimestamp == 0 {
+				// dcrtime returns 0 when there are not enough
+				// confirmations yet.
+				log.Debugf("anchorChecker not enough confirmations: %v",
+					vd.Digest)
+				break
+			}
+			vds = append(vds, *vd)
+		}
+		if len(vds) != len(pa.digests) {
+			continue
+		}
+		for k := range pa.anchors {
+			pa.anchors[k].VerifyDigest = vds[k]
+		}
+		confirmed = append(confirmed, pa)
+	}
+
+	return t.afterAnchorVerify(confirmed)
+}
+
+// afterAnchorVerify completes the anchor verification process.  It appends a
+// DataAnchor record entry to every anchored tree, marks trees that did not
+// change in the meantime clean and removes the anchors from the unconfirmed
+// list.
+func (t *tlogBackEnd) afterAnchorVerify(confirmed []pendingAnchor) error {
+	t.Lock()
+	defer t.Unlock()
+
+	if len(confirmed) == 0 {
+		return nil
+	}
+
+	done := make(map[string]struct{}, len(confirmed))
+	for _, pa := range confirmed {
+		for k, da := range pa.anchors {
+			data, err := json.Marshal(da)
+			if err != nil {
+				return err
+			}
+			_, lr, err := t.appendEntries(pa.trees[k], []entry{{
+				descriptor: v1.DataDescriptorAnchor,
+				data:       data,
+			}})
+			if err != nil {
+				return fmt.Errorf("appendEntries %v: %v", da.RecordId,
+					err)
+			}
+
+			// The tree remains dirty if it changed while we were
+			// waiting for the anchor to drop.  Its size now includes
+			// the anchor leaf.
+			size, ok := t.dirty[da.RecordId]
+			if ok && size == pa.sizes[k] {
+				delete(t.dirty, da.RecordId)
+			} else if ok {
+				t.dirty[da.RecordId] = lr.TreeSize
+			}
+
+			log.Infof("Tree %v anchored in TX %v", da.RecordId,
+				da.VerifyDigest.ChainInformation.Transaction)
+		}
+
+		for _, d := range pa.digests {
+			digest := hex.EncodeToString(d[:])
+			done[digest] = struct{}{}
+
+			// Mark test anchors as confirmed by dcrtime
+			if t.test {
+				t.testAnchors[digest] = true
+			}
+		}
+	}
+
+	// Remove confirmed anchors from the unconfirmed list
+	unconfirmed := make([]pendingAnchor, 0, len(t.unconfirmed))
+	for _, pa := range t.unconfirmed {
+		if _, ok := done[hex.EncodeToString(pa.digests[0][:])]; ok {
+			continue
+		}
+		unconfirmed = append(unconfirmed, pa)
+	}
+	t.unconfirmed = unconfirmed
+
+	return nil
+}
+
+// verifyAnchor asks dcrtime if an anchor has been verified and returns a TX if
+// it has.
+func (t *tlogBackEnd) verifyAnchor(digest string) (*dcrtime.VerifyDigest, error) {
+	var (
+		vr  *dcrtime.VerifyReply
+		err error
+	)
+
+	// In test mode we fake success.
+	if t.test {
+		vr = &dcrtime.VerifyReply{}
+		t.Lock()
+		anchored, ok := t.testAnchors[digest]
+		t.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("test not found")
+		}
+		if anchored {
+			return nil, fmt.Errorf("already anchored")
+		}
+		vr.Digests = append(vr.Digests, dcrtime.VerifyDigest{
+			Digest: digest,
+			Result: dcrtime.ResultOK,
+			ChainInformation: dcrtime.ChainInformation{
+				ChainTimestamp: time.Now().Unix(),
+				Transaction:    expectedTestTX,
+			},
+		})
+	} else {
+		// Call dcrtime
+		vr, err = t.timestamper.Verify("politeia",
+			[]string{digest})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Do some sanity checks
+	if len(vr.Digests) != 1 {
+		return nil, fmt.Errorf("unexpected number of digests")
+	}
+	if vr.Digests[0].Result != dcrtime.ResultOK {
+		return nil, fmt.Errorf("unexpected result: %v",
+			vr.Digests[0].Result)
+	}
+
+	return &vr.Digests[0], nil
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	v1 "github.com/thi4go/politeia/tlog/api/v1"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+var (
+	// ErrBlobNotFound is emitted when a blob could not be found.
+	ErrBlobNotFound = errors.New("blob not found")
+
+	_ blob = (*blobFilesystem)(nil)
+)
+
+// blob is the interface that is used to store record entry payloads.
+type blob interface {
+	put([]byte) ([]byte, error) // Store blob and return identifier
+	get([]byte) ([]byte, error) // Get blob by identifier
+	del([]byte) error           // Attempt to delete object
+}
+
+// blobify gzips and gob encodes a record entry.
+func blobify(re v1.RecordEntry) ([]byte, error) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	enc := gob.NewEncoder(zw)
+	err := enc.Encode(re)
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close() // we must flush gzip buffers
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// deblob reverses blobify.
+func deblob(blob []byte) (*v1.RecordEntry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		return nil, err
+	}
+	r := gob.NewDecoder(zr)
+	var re v1.RecordEntry
+	err = r.Decode(&re)
+	if err != nil {
+		return nil, err
+	}
+	return &re, nil
+}
+
+// newKey returns a new random secretbox key.
+func newKey() (*[32]byte, error) {
+	var k [32]byte
+
+	_, err := io.ReadFull(rand.Reader, k[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// encryptAndPack encrypts data with a random nonce and prefixes the result
+// with that nonce.
+func encryptAndPack(data []byte, key *[32]byte) ([]byte, error) {
+	var nonce [24]byte
+
+	// random nonce
+	_, err := io.ReadFull(rand.Reader, nonce[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypt data
+	blob := secretbox.Seal(nil, data, &nonce, key)
+
+	// pack all the things
+	packed := make([]byte, len(nonce)+len(blob))
+	copy(packed[0:], nonce[:])
+	copy(packed[24:], blob)
+
+	return packed, nil
+}
+
+// unpackAndDecrypt reverses encryptAndPack.
+func unpackAndDecrypt(key *[32]byte, packed []byte) ([]byte, error) {
+	if len(packed) < 24 {
+		return nil, errors.New("not an sbox file")
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], packed[0:24])
+
+	decrypted, ok := secretbox.Open(nil, packed[24:], &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("could not decrypt")
+	}
+	return decrypted, nil
+}
+
+// blobFilesystem provides a blob filesystem that is encrypted if a private key
+// is provided.
+type blobFilesystem struct {
+	path       string    // Location of files
+	privateKey *[32]byte // Private key
+}
+
+// put satisfies the blob interface.
+func (b *blobFilesystem) put(blob []byte) ([]byte, error) {
+	var err error
+	if b.privateKey != nil {
+		blob, err = encryptAndPack(blob, b.privateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	filename := uuid.New().String()
+	err = ioutil.WriteFile(filepath.Join(b.path, filename), blob, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(filename), nil
+}
+
+// get satisfies the blob interface.
+func (b *blobFilesystem) get(id []byte) ([]byte, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(b.path, string(id)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+
+	if b.privateKey != nil {
+		return unpackAndDecrypt(b.privateKey, blob)
+	}
+	return blob, nil
+}
+
+// del satisfies the blob interface.
+func (b *blobFilesystem) del(id []byte) error {
+	err := os.Remove(filepath.Join(b.path, string(id)))
+	if err != nil {
+		// Always return not found
+		return ErrBlobNotFound
+	}
+	return nil
+}
+
+// newBlobFilesystem returns a blob store that lives in the provided
+// directory.  Blobs are encrypted when a private key is provided.
+func newBlobFilesystem(privateKey *[32]byte, path string) (*blobFilesystem, error) {
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &blobFilesystem{
+		path:       path,
+		privateKey: privateKey,
+	}, nil
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	tcrypto "github.com/google/trillian/crypto"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/types"
+	v1 "github.com/thi4go/politeia/tlog/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_ trillianClient = (*testTClient)(nil)
+)
+
+// testTree is a single in-memory trillian log.
+type testTree struct {
+	tree   *trillian.Tree
+	leaves []*trillian.LogLeaf
+	merkle *merkle.InMemoryMerkleTree
+	index  map[string]int64 // [leafIdentityHash]leafIndex
+}
+
+// testTClient is an in-memory stand-in for a trillian log server.  It signs
+// log roots and produces inclusion proofs that verify exactly like the ones
+// returned by a real trillian instance.  It is only meant to be used in
+// tests.
+type testTClient struct {
+	sync.Mutex
+
+	trees  map[int64]*testTree // [treeID]tree
+	signer *tcrypto.Signer
+	pubkey crypto.PublicKey
+}
+
+// signRoot returns the signed log root for the provided tree at its current
+// size.
+//
+// This function must be called with the lock held.
+func (t *testTClient) signRoot(tt *testTree) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	lrv1 := &types.LogRootV1{
+		TreeSize:       uint64(len(tt.leaves)),
+		RootHash:       tt.merkle.CurrentRoot().Hash(),
+		TimestampNanos: uint64(time.Now().UnixNano()),
+		Revision:       uint64(len(tt.leaves)),
+	}
+	slr, err := t.signer.SignLogRoot(lrv1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return slr, lrv1, nil
+}
+
+// lookup returns the tree for the provided tree ID.
+//
+// This function must be called with the lock held.
+func (t *testTClient) lookup(treeID int64) (*testTree, error) {
+	tt, ok := t.trees[treeID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "tree not found: %v",
+			treeID)
+	}
+	return tt, nil
+}
+
+// proof returns the inclusion proof of a leaf at the provided tree size.
+//
+// This function must be called with the lock held.
+func (t *testTClient) proof(tt *testTree, leafIndex int64, treeSize uint64) (*trillian.Proof, error) {
+	if leafIndex < 0 || uint64(leafIndex) >= treeSize ||
+		treeSize > uint64(len(tt.leaves)) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid proof request: leaf %v tree size %v",
+			leafIndex, treeSize)
+	}
+
+	// The in-memory merkle tree indexes leaves starting at 1.
+	path := tt.merkle.PathToRootAtSnapshot(leafIndex+1, int64(treeSize))
+	hashes := make([][]byte, 0, len(path))
+	for _, v := range path {
+		hashes = append(hashes, v.Value.Hash())
+	}
+
+	return &trillian.Proof{
+		LeafIndex: leafIndex,
+		Hashes:    hashes,
+	}, nil
+}
+
+// treeNew satisfies the trillianClient interface.
+func (t *testTClient) treeNew() (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	pk, err := der.ToPublicProto(t.pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Tree IDs are random in trillian as well.
+	var treeID int64
+	for {
+		var b [8]byte
+		_, err := rand.Read(b[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		treeID = int64(binary.BigEndian.Uint64(b[:]) >> 1)
+		if _, ok := t.trees[treeID]; !ok && treeID != 0 {
+			break
+		}
+	}
+
+	tt := &testTree{
+		tree: &trillian.Tree{
+			TreeId:             treeID,
+			TreeState:          trillian.TreeState_ACTIVE,
+			TreeType:           trillian.TreeType_LOG,
+			HashStrategy:       trillian.HashStrategy_RFC6962_SHA256,
+			HashAlgorithm:      sigpb.DigitallySigned_SHA256,
+			SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
+			PublicKey:          pk,
+		},
+		leaves: make([]*trillian.LogLeaf, 0, 64),
+		merkle: merkle.NewInMemoryMerkleTree(rfc6962.DefaultHasher),
+		index:  make(map[string]int64),
+	}
+	t.trees[treeID] = tt
+
+	slr, _, err := t.signRoot(tt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tt.tree, slr, nil
+}
+
+// tree satisfies the trillianClient interface.
+func (t *testTClient) tree(treeID int64) (*trillian.Tree, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	tt, err := t.lookup(treeID)
+	if err != nil {
+		return nil, err
+	}
+	return tt.tree, nil
+}
+
+// treesAll satisfies the trillianClient interface.
+func (t *testTClient) treesAll() ([]*trillian.Tree, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	trees := make([]*trillian.Tree, 0, len(t.trees))
+	for _, v := range t.trees {
+		trees = append(trees, v.tree)
+	}
+	return trees, nil
+}
+
+// leavesAppend satisfies the trillianClient interface.
+func (t *testTClient) leavesAppend(tree *trillian.Tree, leaves []*trillian.LogLeaf) ([]v1.QueuedLeafProof, *types.LogRootV1, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	tt, err := t.lookup(tree.TreeId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Queue leaves.  Trillian identifies duplicates by the leaf identity
+	// hash which defaults to the leaf hash.
+	queued := make([]*trillian.QueuedLogLeaf, 0, len(leaves))
+	for _, v := range leaves {
+		h, err := rfc6962.DefaultHasher.HashLeaf(v.LeafValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		leaf := &trillian.LogLeaf{
+			MerkleLeafHash:   h,
+			LeafValue:        v.LeafValue,
+			ExtraData:        v.ExtraData,
+			LeafIdentityHash: v.LeafIdentityHash,
+		}
+		if len(leaf.LeafIdentityHash) == 0 {
+			leaf.LeafIdentityHash = h
+		}
+
+		if _, ok := tt.index[string(leaf.LeafIdentityHash)]; ok {
+			queued = append(queued, &trillian.QueuedLogLeaf{
+				Leaf: leaf,
+				Status: status.New(codes.AlreadyExists,
+					"leaf already exists").Proto(),
+			})
+			continue
+		}
+
+		idx, _, err := tt.merkle.AddLeaf(leaf.LeafValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		stored := *leaf
+		stored.LeafIndex = idx - 1
+		tt.leaves = append(tt.leaves, &stored)
+		tt.index[string(leaf.LeafIdentityHash)] = stored.LeafIndex
+
+		queued = append(queued, &trillian.QueuedLogLeaf{
+			Leaf:   leaf,
+			Status: status.New(codes.OK, "").Proto(),
+		})
+	}
+
+	_, lrv1, err := t.signRoot(tt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get inclusion proofs
+	proofs := make([]v1.QueuedLeafProof, 0, len(queued))
+	for _, v := range queued {
+		qlp := v1.QueuedLeafProof{
+			QueuedLeaf: *v,
+		}
+		if codes.Code(v.GetStatus().GetCode()) == codes.OK {
+			qlp.Proof, err = t.proof(tt,
+				tt.index[string(v.Leaf.LeafIdentityHash)],
+				lrv1.TreeSize)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		proofs = append(proofs, qlp)
+	}
+
+	return proofs, lrv1, nil
+}
+
+// leavesAll satisfies the trillianClient interface.
+func (t *testTClient) leavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	tt, err := t.lookup(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := make([]*trillian.LogLeaf, 0, len(tt.leaves))
+	for _, v := range tt.leaves {
+		l := *v
+		leaves = append(leaves, &l)
+	}
+	return leaves, nil
+}
+
+// signedLogRoot satisfies the trillianClient interface.
+func (t *testTClient) signedLogRoot(tree *trillian.Tree) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	tt, err := t.lookup(tree.TreeId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t.signRoot(tt)
+}
+
+// inclusionProof satisfies the trillianClient interface.
+func (t *testTClient) inclusionProof(treeID int64, leafIndex int64, treeSize uint64) (*trillian.Proof, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	tt, err := t.lookup(treeID)
+	if err != nil {
+		return nil, err
+	}
+	return t.proof(tt, leafIndex, treeSize)
+}
+
+// close satisfies the trillianClient interface.
+func (t *testTClient) close() {}
+
+// newTestTClient returns an in-memory trillianClient that signs its log roots
+// with a freshly generated ECDSA key.
+func newTestTClient() (*testTClient, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &testTClient{
+		trees:  make(map[int64]*testTree),
+		signer: tcrypto.NewSigner(0, key, crypto.SHA256),
+		pubkey: key.Public(),
+	}, nil
+}
@@ -0,0 +1,1637 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/merkle/rfc6962"
+	"github.com/google/trillian/types"
+	"github.com/robfig/cron"
+	"github.com/syndtr/goleveldb/leveldb"
+	ldbutil "github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/thi4go/politeia/decredplugin"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/backend"
+	v1 "github.com/thi4go/politeia/tlog/api/v1"
+	tlogutil "github.com/thi4go/politeia/tlog/util"
+	"github.com/thi4go/politeia/util"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// DefaultDataPath is the directory, relative to the politeiad data
+	// directory, where the tlog backend stores its data.
+	DefaultDataPath = "tlogbe"
+
+	indexPath = "index" // leveldb token to tree ID index
+	blobsPath = "blobs" // Encrypted record entry blobs
+
+	// anchorSchedule determines how often we anchor the trees.
+	// Seconds Minutes Hours Days Months DayOfWeek
+	anchorSchedule = "0 58 * * * *" // At 58 minutes every hour
+
+	// expectedTestTX is a fake TX used by unit tests.
+	expectedTestTX = "TESTTX"
+
+	// Record entry descriptors.  They are stored in the DataDescriptor of
+	// every record entry and in the extra data of every trillian leaf.
+	descriptorRecordIndex    = "recordindex"    // recordIndex
+	descriptorRecordMetadata = "recordmetadata" // backend.RecordMetadata
+	descriptorMetadataStream = "metadatastream" // backend.MetadataStream
+	descriptorFile           = "file"           // backend.File
+	descriptorReadme         = "readme"         // readme
+	descriptorTombstone      = "tombstone"      // backend.Tombstone
+
+	// Index keys
+	keyReadme   = "readme" // Tree ID of the README.md tree
+	prefixToken = "token:" // token:token -> tree ID
+)
+
+var (
+	_ backend.Backend = (*tlogBackEnd)(nil)
+)
+
+// recordIndex describes a single version of a record.  It contains the merkle
+// leaf hashes of all record entries that make up the record.  A new record
+// index is appended to the tree every time the record changes.  The last
+// record index in a tree describes the current state of the record.
+type recordIndex struct {
+	Version        string            `json:"version"`        // Record version
+	Sequence       uint64            `json:"sequence"`       // Tree size when created, keeps the index unique
+	RecordMetadata []byte            `json:"recordmetadata"` // Merkle leaf hash
+	Metadata       map[uint64][]byte `json:"metadata"`       // [streamID]merkleLeafHash
+	Files          map[string][]byte `json:"files"`          // [filename]merkleLeafHash
+}
+
+// readme is the record entry that is appended to the README.md tree.
+type readme struct {
+	Sequence uint64 `json:"sequence"` // Tree size when created
+	Content  string `json:"content"`  // README.md content
+}
+
+// leafExtraData is stored in the ExtraData field of a trillian leaf.  It
+// points to the blob that holds the record entry and carries the entry
+// descriptor so that trees can be scanned without decrypting every blob.
+type leafExtraData struct {
+	Key        string `json:"key"`        // Blob key
+	Descriptor string `json:"descriptor"` // Record entry descriptor
+}
+
+// entry is a record entry that is about to be appended to a tree.
+type entry struct {
+	descriptor string // Record entry descriptor
+	data       []byte // Record entry payload
+}
+
+// tlogBackEnd is a backend context that stores records in trillian logs.  Every
+// record lives in its own tree and every record change is appended to that
+// tree as a set of record entries.  The record entry payloads are stored
+// encrypted in a blob store and the tree leaves point to them.  This makes
+// every change to a record verifiable through an inclusion proof.
+type tlogBackEnd struct {
+	sync.Mutex                         // Global lock
+	cron        *cron.Cron             // Scheduler for periodic tasks
+	shutdown    bool                   // Backend is shutdown
+	root        string                 // Root directory
+	index       *leveldb.DB            // Token to tree ID index
+	blob        blob                   // Record entry storage
+	client      trillianClient         // Trillian log client
+	id          *identity.FullIdentity // Record entry signing identity
+	timestamper util.Timestamper       // Dcrtime client
+	test        bool                   // Set during UT
+	exit        chan struct{}          // Close channel
+	checkAnchor chan struct{}          // Work notification
+	plugins     backend.Plugins        // Plugins
+
+	// dirty contains the trees that have not been anchored and the tree
+	// size at the time they were last modified.
+	dirty map[int64]uint64 // [treeID]treeSize
+
+	// unconfirmed contains the anchors that have been dropped but that
+	// have not been confirmed by dcrtime yet.
+	unconfirmed []pendingAnchor
+
+	// The following items are used for testing only
+	testAnchors map[string]bool // [digest]anchored
+}
+
+func tokenKey(id string) []byte {
+	return []byte(prefixToken + id)
+}
+
+// merkleLeafHash returns the merkle leaf hash of a record entry.  The leaf
+// value of a record entry is the SHA256 digest of its data.
+func merkleLeafHash(re v1.RecordEntry) ([]byte, error) {
+	h, err := hex.DecodeString(re.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return rfc6962.DefaultHasher.HashLeaf(h)
+}
+
+// isUnvetted returns true if the status belongs to a record that lives in the
+// unvetted set.
+func isUnvetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusUnvetted ||
+		status == backend.MDStatusIterationUnvetted ||
+		status == backend.MDStatusCensored
+}
+
+// isVetted returns true if the status belongs to a record that lives in the
+// vetted set.
+func isVetted(status backend.MDStatusT) bool {
+	return status == backend.MDStatusVetted ||
+		status == backend.MDStatusArchived
+}
+
+// unwindBlobs deletes the provided blobs.  It is used to clean up blobs that
+// did not end up in a tree.
+func (t *tlogBackEnd) unwindBlobs(blobKeys [][]byte) {
+	for _, v := range blobKeys {
+		log.Debugf("Unwinding blob %s", v)
+		err := t.blob.del(v)
+		if err != nil {
+			// Not fatal, the blob is simply orphaned.
+			log.Errorf("del blob %s: %v", v, err)
+		}
+	}
+}
+
+// appendEntries stores the provided entries in the blob store and appends
+// pointers to them to the tree.  Every appended leaf is verified against its
+// inclusion proof.  Entries that already exist in the tree are not appended a
+// second time.  It returns the merkle leaf hashes of the entries, in the same
+// order as the entries, along with the log root the proofs were verified
+// against.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) appendEntries(tree *trillian.Tree, entries []entry) ([][]byte, *types.LogRootV1, error) {
+	var (
+		leaves   = make([]*trillian.LogLeaf, 0, len(entries))
+		hashes   = make([][]byte, 0, len(entries))
+		blobKeys = make([][]byte, 0, len(entries))
+		anchor   = true
+	)
+	for _, v := range entries {
+		if v.descriptor != v1.DataDescriptorAnchor {
+			anchor = false
+		}
+
+		dd, err := json.Marshal(v1.DataDescriptor{
+			Type:       v1.DataTypeStructure,
+			Descriptor: v.descriptor,
+		})
+		if err != nil {
+			t.unwindBlobs(blobKeys)
+			return nil, nil, err
+		}
+		re := tlogutil.RecordEntryNew(t.id, dd, v.data)
+		b, err := blobify(re)
+		if err != nil {
+			t.unwindBlobs(blobKeys)
+			return nil, nil, fmt.Errorf("blobify: %v", err)
+		}
+		key, err := t.blob.put(b)
+		if err != nil {
+			t.unwindBlobs(blobKeys)
+			return nil, nil, fmt.Errorf("put: %v", err)
+		}
+		blobKeys = append(blobKeys, key)
+
+		extraData, err := json.Marshal(leafExtraData{
+			Key:        string(key),
+			Descriptor: v.descriptor,
+		})
+		if err != nil {
+			t.unwindBlobs(blobKeys)
+			return nil, nil, err
+		}
+		h, err := hex.DecodeString(re.Hash)
+		if err != nil {
+			t.unwindBlobs(blobKeys)
+			return nil, nil, err
+		}
+		mlh, err := merkleLeafHash(re)
+		if err != nil {
+			t.unwindBlobs(blobKeys)
+			return nil, nil, err
+		}
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafValue: h, // use hash of data so that we can collide dups
+			ExtraData: extraData,
+		})
+		hashes = append(hashes, mlh)
+	}
+
+	proofs, lrv1, err := t.client.leavesAppend(tree, leaves)
+	if err != nil {
+		t.unwindBlobs(blobKeys)
+		return nil, nil, fmt.Errorf("leavesAppend: %v", err)
+	}
+	if len(proofs) != len(leaves) {
+		t.unwindBlobs(blobKeys)
+		return nil, nil, fmt.Errorf("unexpected number of queued leaves: "+
+			"got %v, want %v", len(proofs), len(leaves))
+	}
+
+	// Verify inclusion proofs and unwind the blobs of duplicate leaves.
+	// The leaves that were appended are part of the tree at this point so
+	// their blobs must not be unwound.
+	verifier, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	dups := make([][]byte, 0, len(proofs))
+	for k, v := range proofs {
+		switch c := codes.Code(v.QueuedLeaf.GetStatus().GetCode()); c {
+		case codes.OK:
+			err = tlogutil.QueuedLeafProofVerify(verifier.PubKey, lrv1, v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("QueuedLeafProofVerify %v: %v",
+					tree.TreeId, err)
+			}
+		case codes.AlreadyExists:
+			dups = append(dups, blobKeys[k])
+		default:
+			return nil, nil, fmt.Errorf("leaf not appended %v: %v",
+				tree.TreeId, v.QueuedLeaf.GetStatus().GetMessage())
+		}
+	}
+	t.unwindBlobs(dups)
+
+	log.Debugf("Stored/Ignored leaves: %v/%v %v", len(leaves)-len(dups),
+		len(dups), tree.TreeId)
+
+	// Mark dirty.  Appending an anchor does not make a tree dirty.
+	if !anchor && len(dups) != len(leaves) {
+		t.dirty[tree.TreeId] = lrv1.TreeSize
+	}
+
+	return hashes, lrv1, nil
+}
+
+// entryData returns the record entry that the provided leaf points to along
+// with its decoded data.  The record entry signature and the binding between
+// the leaf and the record entry data are verified.
+func (t *tlogBackEnd) entryData(leaf *trillian.LogLeaf) (*v1.RecordEntry, []byte, error) {
+	var ed leafExtraData
+	err := json.Unmarshal(leaf.ExtraData, &ed)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := t.blob.get([]byte(ed.Key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("get %v: %v", ed.Key, err)
+	}
+	re, err := deblob(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("deblob %v: %v", ed.Key, err)
+	}
+	err = tlogutil.RecordEntryVerify(*re)
+	if err != nil {
+		return nil, nil, fmt.Errorf("RecordEntryVerify %v: %v", ed.Key, err)
+	}
+	if hex.EncodeToString(leaf.LeafValue) != re.Hash {
+		return nil, nil, fmt.Errorf("leaf value does not match record "+
+			"entry %v", ed.Key)
+	}
+	data, err := base64.StdEncoding.DecodeString(re.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return re, data, nil
+}
+
+// entryDecode retrieves the record entry that the provided leaf points to and
+// JSON decodes its data into v.
+func (t *tlogBackEnd) entryDecode(leaf *trillian.LogLeaf, v interface{}) error {
+	_, data, err := t.entryData(leaf)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// leafDescriptor returns the record entry descriptor of a leaf.
+func leafDescriptor(leaf *trillian.LogLeaf) string {
+	var ed leafExtraData
+	err := json.Unmarshal(leaf.ExtraData, &ed)
+	if err != nil {
+		return ""
+	}
+	return ed.Descriptor
+}
+
+// treeID returns the ID of the tree that holds the provided record.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) treeID(id string) (int64, error) {
+	v, err := t.index.Get(tokenKey(id), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return 0, backend.ErrRecordNotFound
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(string(v), 10, 64)
+}
+
+// findRecordIndex returns the record index of the requested record version,
+// the leaf that holds it and all leaves of the tree.  The most recent record
+// index is returned if version is empty.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) findRecordIndex(treeID int64, version string) (*recordIndex, *trillian.LogLeaf, []*trillian.LogLeaf, error) {
+	leaves, err := t.client.leavesAll(treeID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Walk the leaves backwards since the last record index describes
+	// the current state of the record.
+	for i := len(leaves) - 1; i >= 0; i-- {
+		if leafDescriptor(leaves[i]) != descriptorRecordIndex {
+			continue
+		}
+		var ri recordIndex
+		err = t.entryDecode(leaves[i], &ri)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if version == "" || ri.Version == version {
+			return &ri, leaves[i], leaves, nil
+		}
+	}
+
+	return nil, nil, nil, backend.ErrRecordNotFound
+}
+
+// getRecord returns the requested version of a record.  The latest version is
+// returned if version is empty.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) getRecord(id, version string) (*backend.Record, error) {
+	treeID, err := t.treeID(id)
+	if err != nil {
+		return nil, err
+	}
+	ri, _, leaves, err := t.findRecordIndex(treeID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]*trillian.LogLeaf, len(leaves))
+	for _, v := range leaves {
+		byHash[hex.EncodeToString(v.MerkleLeafHash)] = v
+	}
+	lookup := func(h []byte) (*trillian.LogLeaf, error) {
+		leaf, ok := byHash[hex.EncodeToString(h)]
+		if !ok {
+			return nil, fmt.Errorf("leaf not found %v: %x", treeID, h)
+		}
+		return leaf, nil
+	}
+
+	var r backend.Record
+	r.Version = ri.Version
+
+	leaf, err := lookup(ri.RecordMetadata)
+	if err != nil {
+		return nil, err
+	}
+	err = t.entryDecode(leaf, &r.RecordMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Metadata = make([]backend.MetadataStream, 0, len(ri.Metadata))
+	for _, h := range ri.Metadata {
+		leaf, err := lookup(h)
+		if err != nil {
+			return nil, err
+		}
+		var ms backend.MetadataStream
+		err = t.entryDecode(leaf, &ms)
+		if err != nil {
+			return nil, err
+		}
+		r.Metadata = append(r.Metadata, ms)
+	}
+	backend.SortMetadata(r.Metadata)
+
+	r.Files = make([]backend.File, 0, len(ri.Files))
+	for _, h := range ri.Files {
+		leaf, err := lookup(h)
+		if err != nil {
+			return nil, err
+		}
+		var f backend.File
+		err = t.entryDecode(leaf, &f)
+		if err != nil {
+			return nil, err
+		}
+		r.Files = append(r.Files, f)
+	}
+	backend.SortFiles(r.Files)
+
+	return &r, nil
+}
+
+// putRecord appends the record to the provided tree.  The record metadata,
+// the metadata streams and the files are appended first, followed by the
+// record index that ties them together.  Entries that did not change since
+// a previous version already exist in the tree and are simply referenced by
+// the new record index.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) putRecord(tree *trillian.Tree, r *backend.Record) error {
+	entries := make([]entry, 0, 1+len(r.Metadata)+len(r.Files))
+	rmd, err := json.Marshal(r.RecordMetadata)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry{
+		descriptor: descriptorRecordMetadata,
+		data:       rmd,
+	})
+	for _, v := range r.Metadata {
+		md, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{
+			descriptor: descriptorMetadataStream,
+			data:       md,
+		})
+	}
+	for _, v := range r.Files {
+		f, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{
+			descriptor: descriptorFile,
+			data:       f,
+		})
+	}
+
+	hashes, lrv1, err := t.appendEntries(tree, entries)
+	if err != nil {
+		return err
+	}
+
+	// Create record index
+	ri := recordIndex{
+		Version:        r.Version,
+		Sequence:       lrv1.TreeSize,
+		RecordMetadata: hashes[0],
+		Metadata:       make(map[uint64][]byte, len(r.Metadata)),
+		Files:          make(map[string][]byte, len(r.Files)),
+	}
+	x := 1
+	for _, v := range r.Metadata {
+		ri.Metadata[v.ID] = hashes[x]
+		x++
+	}
+	for _, v := range r.Files {
+		ri.Files[v.Name] = hashes[x]
+		x++
+	}
+	idx, err := json.Marshal(ri)
+	if err != nil {
+		return err
+	}
+	_, _, err = t.appendEntries(tree, []entry{{
+		descriptor: descriptorRecordIndex,
+		data:       idx,
+	}})
+	if err != nil {
+		return err
+	}
+
+	log.Tracef("putRecord %v %v: version %v", r.RecordMetadata.Token,
+		tree.TreeId, r.Version)
+
+	return nil
+}
+
+// putRecordToken looks up the tree of the record and appends the record to
+// it.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) putRecordToken(r *backend.Record) error {
+	treeID, err := t.treeID(r.RecordMetadata.Token)
+	if err != nil {
+		return err
+	}
+	tree, err := t.client.tree(treeID)
+	if err != nil {
+		return err
+	}
+	return t.putRecord(tree, r)
+}
+
+// New takes a record verifies it and stores it in a new tree as an unvetted
+// record.  The function returns a RecordMetadata.
+//
+// New satisfies the backend interface.
+func (t *tlogBackEnd) New(metadata []backend.MetadataStream, files []backend.File) (*backend.RecordMetadata, error) {
+	log.Tracef("New")
+	fa, err := backend.VerifyContent(metadata, files, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a censorship token.
+	token, err := util.Random(pd.TokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("New %x", token)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	hook := backend.HookNewRecord{
+		Metadata: metadata,
+		Files:    files,
+	}
+	err = t.plugins.Hook(backend.HookPreNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, _, err := t.client.treeNew()
+	if err != nil {
+		return nil, fmt.Errorf("treeNew: %v", err)
+	}
+
+	id := hex.EncodeToString(token)
+	bf, err := backend.ConvertVerifiedFiles(fa)
+	if err != nil {
+		return nil, err
+	}
+	backend.SortFiles(bf)
+	rm, err := backend.CreateRecordMetadata(id, backend.MDStatusUnvetted, 1, bf)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Version:        "1",
+		Metadata:       backend.ApplyMetadata(nil, nil, metadata),
+		Files:          bf,
+	}
+
+	hook.RecordMetadata = rm
+	err = t.plugins.Hook(backend.HookPostNewRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.putRecord(tree, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only index the record once it has been stored.
+	err = t.index.Put(tokenKey(id),
+		[]byte(strconv.FormatInt(tree.TreeId, 10)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return rm, nil
+}
+
+// updateRecord is the generic implementation of UpdateVettedRecord and
+// UpdateUnvettedRecord.
+func (t *tlogBackEnd) updateRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string, vetted bool) (*backend.Record, error) {
+	log.Tracef("updateRecord: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	fa, err := backend.VerifyContent(allMD, filesAdd, filesDel)
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return nil, err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return nil, err
+		}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	old, err := t.getRecord(id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	status := old.RecordMetadata.Status
+	if vetted {
+		switch status {
+		case backend.MDStatusVetted:
+		case backend.MDStatusArchived:
+			return nil, backend.ErrRecordArchived
+		default:
+			return nil, backend.ErrRecordNotFound
+		}
+	} else {
+		switch status {
+		case backend.MDStatusUnvetted, backend.MDStatusIterationUnvetted:
+		case backend.MDStatusVetted, backend.MDStatusArchived:
+			return nil, backend.ErrRecordFound
+		default:
+			return nil, fmt.Errorf("can not update record that "+
+				"has status: %v %v", status, backend.MDStatus[status])
+		}
+	}
+
+	hook := backend.HookEditRecord{
+		Token:       id,
+		Vetted:      vetted,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+	err = t.plugins.Hook(backend.HookPreEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify all deletes before executing
+	files := make(map[string]backend.File, len(old.Files))
+	for _, v := range old.Files {
+		files[v.Name] = v
+	}
+	for _, v := range filesDel {
+		if _, ok := files[v]; !ok {
+			return nil, backend.ContentVerificationError{
+				ErrorCode:    pd.ErrorStatusFileNotFound,
+				ErrorContext: []string{v},
+			}
+		}
+		delete(files, v)
+	}
+	added, err := backend.ConvertVerifiedFiles(fa)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range added {
+		files[v.Name] = v
+	}
+	if len(files) == 0 {
+		return nil, backend.ContentVerificationError{
+			ErrorCode: pd.ErrorStatusEmpty,
+		}
+	}
+	bf := make([]backend.File, 0, len(files))
+	for _, v := range files {
+		bf = append(bf, v)
+	}
+	backend.SortFiles(bf)
+	md := backend.ApplyMetadata(old.Metadata, mdAppend, mdOverwrite)
+
+	// If there are no changes DO NOT update the record and reply with no
+	// changes.
+	if reflect.DeepEqual(bf, old.Files) &&
+		reflect.DeepEqual(md, old.Metadata) {
+		return nil, backend.ErrNoChanges
+	}
+
+	// Delete the vote authorization since the record content changed.
+	for k, v := range md {
+		if v.ID == decredplugin.MDStreamAuthorizeVote {
+			md = append(md[:k], md[k+1:]...)
+			break
+		}
+	}
+
+	ns := backend.MDStatusIterationUnvetted
+	version := old.Version
+	if vetted {
+		ns = backend.MDStatusVetted
+		v, err := strconv.ParseUint(old.Version, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		version = strconv.FormatUint(v+1, 10)
+	}
+	rm, err := backend.CreateRecordMetadata(id, ns, old.RecordMetadata.Iteration+1, bf)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Version:        version,
+		Metadata:       md,
+		Files:          bf,
+	}
+
+	err = t.plugins.Hook(backend.HookPostEditRecord, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.putRecordToken(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// UpdateVettedRecord updates the vetted record by creating a new version.
+//
+// This function is part of the interface.
+func (t *tlogBackEnd) UpdateVettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateVettedRecord %x", token)
+	return t.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
+		true)
+}
+
+// UpdateUnvettedRecord updates the unvetted record.
+//
+// This function is part of the interface.
+func (t *tlogBackEnd) UpdateUnvettedRecord(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Debugf("UpdateUnvettedRecord %x", token)
+	return t.updateRecord(token, mdAppend, mdOverwrite, filesAdd, filesDel,
+		false)
+}
+
+// UpdateVettedMetadata updates metadata in vetted record.  Record itself is
+// not changed.
+//
+// UpdateVettedMetadata satisfies the backend interface.
+func (t *tlogBackEnd) UpdateVettedMetadata(token []byte, mdAppend []backend.MetadataStream, mdOverwrite []backend.MetadataStream) error {
+	log.Debugf("UpdateVettedMetadata: %x", token)
+
+	// Send in a single metadata array to verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	_, err := backend.VerifyContent(allMD, []backend.File{}, []string{})
+	if err != nil {
+		e, ok := err.(backend.ContentVerificationError)
+		if !ok {
+			return err
+		}
+		// Allow ErrorStatusEmpty
+		if e.ErrorCode != pd.ErrorStatusEmpty {
+			return err
+		}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	r, err := t.getRecord(id, "")
+	if err != nil {
+		return err
+	}
+	switch r.RecordMetadata.Status {
+	case backend.MDStatusVetted:
+	case backend.MDStatusArchived:
+		return backend.ErrRecordArchived
+	default:
+		return backend.ErrRecordNotFound
+	}
+
+	md := backend.ApplyMetadata(r.Metadata, mdAppend, mdOverwrite)
+	if reflect.DeepEqual(md, r.Metadata) {
+		return backend.ErrNoChanges
+	}
+	r.Metadata = md
+
+	log.Debugf("updating vetted metadata %x", token)
+
+	return t.putRecordToken(r)
+}
+
+// readme returns the README.md content.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) readme() (*trillian.Tree, *readme, error) {
+	v, err := t.index.Get([]byte(keyReadme), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, &readme{}, nil
+		}
+		return nil, nil, err
+	}
+	treeID, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := t.client.tree(treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaves, err := t.client.leavesAll(treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := len(leaves) - 1; i >= 0; i-- {
+		if leafDescriptor(leaves[i]) != descriptorReadme {
+			continue
+		}
+		var rm readme
+		err = t.entryDecode(leaves[i], &rm)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tree, &rm, nil
+	}
+	return tree, &readme{}, nil
+}
+
+// UpdateReadme updates the README.md content.  The README.md lives in its own
+// tree.
+//
+// UpdateReadme satisfies the backend interface.
+func (t *tlogBackEnd) UpdateReadme(content string) error {
+	log.Debugf("UpdateReadme")
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return backend.ErrShutdown
+	}
+
+	tree, rm, err := t.readme()
+	if err != nil {
+		return err
+	}
+	if rm.Content == content {
+		return backend.ErrNoChanges
+	}
+	if tree == nil {
+		tree, _, err = t.client.treeNew()
+		if err != nil {
+			return fmt.Errorf("treeNew: %v", err)
+		}
+		err = t.index.Put([]byte(keyReadme),
+			[]byte(strconv.FormatInt(tree.TreeId, 10)), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, lrv1, err := t.client.signedLogRoot(tree)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(readme{
+		Sequence: lrv1.TreeSize,
+		Content:  content,
+	})
+	if err != nil {
+		return err
+	}
+	_, _, err = t.appendEntries(tree, []entry{{
+		descriptor: descriptorReadme,
+		data:       data,
+	}})
+	return err
+}
+
+// recordExists returns true if the latest version of the record has a status
+// that satisfies the provided function.
+func (t *tlogBackEnd) recordExists(token []byte, f func(backend.MDStatusT) bool) bool {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return false
+	}
+
+	r, err := t.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return false
+	}
+
+	return f(r.RecordMetadata.Status)
+}
+
+// UnvettedExists returns whether the given token corresponds to an unvetted
+// record.
+//
+// UnvettedExists satisfies the backend interface.
+func (t *tlogBackEnd) UnvettedExists(token []byte) bool {
+	log.Tracef("UnvettedExists %x", token)
+	return t.recordExists(token, isUnvetted)
+}
+
+// VettedExists returns whether the given token corresponds to a vetted
+// record.
+//
+// VettedExists satisfies the backend interface.
+func (t *tlogBackEnd) VettedExists(token []byte) bool {
+	log.Tracef("VettedExists %x", token)
+	return t.recordExists(token, isVetted)
+}
+
+// getRecordLock returns the requested record if its status satisfies the
+// provided function.
+//
+// This function must be called WITHOUT the lock held.
+func (t *tlogBackEnd) getRecordLock(token []byte, version string, f func(backend.MDStatusT) bool) (*backend.Record, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	r, err := t.getRecord(hex.EncodeToString(token), version)
+	if err != nil {
+		return nil, err
+	}
+	if !f(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	return r, nil
+}
+
+// GetUnvetted returns an unvetted record.
+//
+// GetUnvetted satisfies the backend interface.
+func (t *tlogBackEnd) GetUnvetted(token []byte) (*backend.Record, error) {
+	log.Debugf("GetUnvetted %x", token)
+	return t.getRecordLock(token, "", isUnvetted)
+}
+
+// GetVetted returns the requested version of a vetted record.
+//
+// GetVetted satisfies the backend interface.
+func (t *tlogBackEnd) GetVetted(token []byte, version string) (*backend.Record, error) {
+	log.Debugf("GetVetted %x", token)
+	return t.getRecordLock(token, version, isVetted)
+}
+
+// setStatus updates the record status, handles the metadata and returns the
+// updated record without the Files component.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) setStatus(r *backend.Record, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	hook := backend.HookSetRecordStatus{
+		Token:       r.RecordMetadata.Token,
+		Current:     r.RecordMetadata.Status,
+		Status:      status,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+	}
+	err := t.plugins.Hook(backend.HookPreSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	r.RecordMetadata.Status = status
+	r.RecordMetadata.Iteration += 1
+	r.RecordMetadata.Timestamp = time.Now().Unix()
+	r.Metadata = backend.ApplyMetadata(r.Metadata, mdAppend, mdOverwrite)
+
+	err = t.plugins.Hook(backend.HookPostSetRecordStatus, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.putRecordToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Files = nil
+	return r, nil
+}
+
+// SetUnvettedStatus tries to update the status for an unvetted record. It
+// returns the updated record if successful but without the Files component.
+//
+// SetUnvettedStatus satisfies the backend interface.
+func (t *tlogBackEnd) SetUnvettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	r, err := t.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return nil, err
+	}
+	if !isUnvetted(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// We only allow a transition from unvetted to vetted or censored
+	switch {
+	case (r.RecordMetadata.Status == backend.MDStatusUnvetted ||
+		r.RecordMetadata.Status == backend.MDStatusIterationUnvetted) &&
+		(status == backend.MDStatusVetted ||
+			status == backend.MDStatusCensored):
+		return t.setStatus(r, status, mdAppend, mdOverwrite)
+	}
+
+	return nil, backend.StateTransitionError{
+		From: r.RecordMetadata.Status,
+		To:   status,
+	}
+}
+
+// SetVettedStatus tries to update the status for a vetted record.  It returns
+// the updated record if successful but without the Files component.
+//
+// SetVettedStatus satisfies the backend interface.
+func (t *tlogBackEnd) SetVettedStatus(token []byte, status backend.MDStatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	log.Debugf("setting status %v (%v) -> %x", status,
+		backend.MDStatus[status], token)
+
+	r, err := t.getRecord(hex.EncodeToString(token), "")
+	if err != nil {
+		return nil, err
+	}
+	if !isVetted(r.RecordMetadata.Status) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Make sure record is not locked.
+	if r.RecordMetadata.Status == backend.MDStatusArchived {
+		return nil, backend.ErrRecordArchived
+	}
+
+	// We only allow a transition from vetted to archived
+	if status != backend.MDStatusArchived {
+		return nil, backend.StateTransitionError{
+			From: r.RecordMetadata.Status,
+			To:   status,
+		}
+	}
+
+	return t.setStatus(r, status, mdAppend, mdOverwrite)
+}
+
+// PurgeRecord removes the files of a censored record and leaves a tombstone
+// in their place.  A record index without files and the tombstone are
+// appended to the tree, after which the blobs of all file entries are
+// deleted.  The file leaves remain in the tree so that their inclusion can
+// still be proven.
+//
+// PurgeRecord satisfies the backend interface.
+func (t *tlogBackEnd) PurgeRecord(token []byte, reason string) (*backend.Tombstone, error) {
+	log.Debugf("PurgeRecord %x", token)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	id := hex.EncodeToString(token)
+	treeID, err := t.treeID(id)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := t.client.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range leaves {
+		if leafDescriptor(v) == descriptorTombstone {
+			return nil, backend.ErrRecordPurged
+		}
+	}
+	r, err := t.getRecord(id, "")
+	if err != nil {
+		return nil, err
+	}
+	if r.RecordMetadata.Status != backend.MDStatusCensored {
+		return nil, backend.ErrRecordNotCensored
+	}
+
+	// Censored records never left the unvetted set so there is only a
+	// single version to replace.
+	ts := backend.NewTombstone(r, reason)
+	r.Files = nil
+	tree, err := t.client.tree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	err = t.putRecord(tree, r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, err
+	}
+	_, _, err = t.appendEntries(tree, []entry{{
+		descriptor: descriptorTombstone,
+		data:       data,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete the file blobs.
+	for _, v := range leaves {
+		var ed leafExtraData
+		err := json.Unmarshal(v.ExtraData, &ed)
+		if err != nil || ed.Descriptor != descriptorFile {
+			continue
+		}
+		err = t.blob.del([]byte(ed.Key))
+		if err != nil {
+			// Not fatal, the record no longer references it.
+			log.Errorf("PurgeRecord: del blob %v: %v", ed.Key, err)
+		}
+	}
+
+	return ts, nil
+}
+
+// GetTombstone returns the tombstone of a purged record.
+//
+// GetTombstone satisfies the backend interface.
+func (t *tlogBackEnd) GetTombstone(token []byte) (*backend.Tombstone, error) {
+	log.Debugf("GetTombstone %x", token)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	treeID, err := t.treeID(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := t.client.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(leaves) - 1; i >= 0; i-- {
+		if leafDescriptor(leaves[i]) != descriptorTombstone {
+			continue
+		}
+		var ts backend.Tombstone
+		err = t.entryDecode(leaves[i], &ts)
+		if err != nil {
+			return nil, err
+		}
+		return &ts, nil
+	}
+
+	return nil, backend.ErrRecordNotFound
+}
+
+// GetTimestamps is not supported by this backend.
+//
+// GetTimestamps satisfies the backend interface.
+func (t *tlogBackEnd) GetTimestamps(token []byte) ([]backend.RecordTimestamp, error) {
+	return nil, backend.ErrNotSupported
+}
+
+// Inventory returns an inventory of vetted and unvetted records.  If
+// includeFiles is set the content is also returned.
+//
+// Inventory satisfies the backend interface.
+func (t *tlogBackEnd) Inventory(vettedCount, branchCount uint, includeFiles, allVersions bool) ([]backend.Record, []backend.Record, error) {
+	log.Debugf("Inventory: %v %v %v", vettedCount, branchCount, includeFiles)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, nil, backend.ErrShutdown
+	}
+
+	iter := t.index.NewIterator(ldbutil.BytesPrefix([]byte(prefixToken)), nil)
+	defer iter.Release()
+
+	pr := make([]backend.Record, 0)
+	br := make([]backend.Record, 0)
+	for iter.Next() {
+		id := string(iter.Key()[len(prefixToken):])
+		r, err := t.getRecord(id, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		if !includeFiles {
+			r.Files = nil
+		}
+
+		if isUnvetted(r.RecordMetadata.Status) {
+			br = append(br, *r)
+			continue
+		}
+		pr = append(pr, *r)
+
+		if allVersions {
+			// Include all versions of the record
+			latest, err := strconv.Atoi(r.Version)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := 1; i < latest; i++ {
+				r, err := t.getRecord(id, strconv.Itoa(i))
+				if err != nil {
+					return nil, nil, err
+				}
+				if !includeFiles {
+					r.Files = nil
+				}
+				pr = append(pr, *r)
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+
+	return pr, br, nil
+}
+
+// InventoryPage returns a filtered page of records, ordered by token, and the
+// cursor of the next page.
+//
+// InventoryPage satisfies the backend interface.
+func (t *tlogBackEnd) InventoryPage(q backend.InventoryQuery) ([]backend.Record, string, error) {
+	log.Debugf("InventoryPage: %v %v", q.Cursor, q.Limit)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, "", backend.ErrShutdown
+	}
+
+	// Only walk the keys that sort after the cursor.
+	r := ldbutil.BytesPrefix([]byte(prefixToken))
+	if q.Cursor != "" {
+		r.Start = append(tokenKey(q.Cursor), 0)
+	}
+	iter := t.index.NewIterator(r, nil)
+	defer iter.Release()
+
+	tokens := make([]string, 0)
+	for iter.Next() {
+		tokens = append(tokens, string(iter.Key()[len(prefixToken):]))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", err
+	}
+
+	return backend.PaginateInventory(q, tokens, t.getRecord)
+}
+
+// RegisterPlugin sets up a plugin and registers it with the backend.
+//
+// RegisterPlugin satisfies the backend interface.
+func (t *tlogBackEnd) RegisterPlugin(p backend.Plugin) error {
+	log.Debugf("RegisterPlugin: %v", p.ID)
+	return t.plugins.Register(p)
+}
+
+// GetPlugins returns a list of currently supported plugins and their settings.
+//
+// GetPlugins satisfies the backend interface.
+func (t *tlogBackEnd) GetPlugins() ([]backend.Plugin, error) {
+	log.Debugf("GetPlugins")
+	return t.plugins.Get(), nil
+}
+
+// Plugin send a passthrough command. The return values are: incomming command
+// identifier, encoded command result and an error if the command failed to
+// execute.
+//
+// Plugin satisfies the backend interface.
+func (t *tlogBackEnd) Plugin(pluginID, command, payload string) (string, string, error) {
+	log.Debugf("Plugin: %v %v", pluginID, command)
+	reply, err := t.plugins.Cmd(pluginID, command, payload)
+	return command, reply, err
+}
+
+// Close shuts down the backend.  It obtains the lock and sets the shutdown
+// boolean to true.  All interface functions MUST return with errShutdown if
+// the backend is shutting down.
+//
+// Close satisfies the backend interface.
+func (t *tlogBackEnd) Close() {
+	log.Debugf("Close")
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.shutdown = true
+	close(t.exit)
+	t.cron.Stop()
+	t.client.close()
+	t.index.Close()
+}
+
+// RecordEntryProofs returns the record entry proofs of all record entries that
+// make up the requested version of a record, including the record index that
+// ties them together.  The latest version is used if version is empty.
+//
+// Entries that have been anchored are proven against the anchored signed log
+// root and carry the dcrtime chain information.  Entries that have not been
+// anchored yet are proven against the latest signed log root.
+func (t *tlogBackEnd) RecordEntryProofs(token []byte, version string) ([]v1.RecordEntryProof, error) {
+	log.Debugf("RecordEntryProofs %x %v", token, version)
+
+	t.Lock()
+	defer t.Unlock()
+	if t.shutdown {
+		return nil, backend.ErrShutdown
+	}
+
+	treeID, err := t.treeID(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+	tree, err := t.client.tree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	ri, leaf, leaves, err := t.findRecordIndex(treeID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the leaf hashes that make up the record
+	hashes := make(map[string]struct{}, 2+len(ri.Metadata)+len(ri.Files))
+	hashes[hex.EncodeToString(leaf.MerkleLeafHash)] = struct{}{}
+	hashes[hex.EncodeToString(ri.RecordMetadata)] = struct{}{}
+	for _, v := range ri.Metadata {
+		hashes[hex.EncodeToString(v)] = struct{}{}
+	}
+	for _, v := range ri.Files {
+		hashes[hex.EncodeToString(v)] = struct{}{}
+	}
+
+	proofs := make([]v1.RecordEntryProof, 0, len(hashes)+1)
+	for _, v := range leaves {
+		if _, ok := hashes[hex.EncodeToString(v.MerkleLeafHash)]; !ok {
+			continue
+		}
+		proofs = append(proofs, t.entryProof(tree, leaves, v))
+	}
+
+	return proofs, nil
+}
+
+// entryProof returns the record entry proof for the provided leaf.
+//
+// This function must be called with the lock held.
+func (t *tlogBackEnd) entryProof(tree *trillian.Tree, leaves []*trillian.LogLeaf, leaf *trillian.LogLeaf) (rep v1.RecordEntryProof) {
+	re, _, err := t.entryData(leaf)
+	if err != nil {
+		rep.Error = fmt.Sprintf("entryData: %v", err)
+		return
+	}
+
+	// Find the first anchor that covers the leaf.  Leaves that were
+	// appended while an anchor was pending precede the anchor in the tree
+	// but are not part of the anchored log root.
+	var (
+		sth      *trillian.SignedLogRoot
+		treeSize uint64
+	)
+	for _, v := range leaves[leaf.LeafIndex+1:] {
+		if leafDescriptor(v) != v1.DataDescriptorAnchor {
+			continue
+		}
+		var da v1.DataAnchor
+		err = t.entryDecode(v, &da)
+		if err != nil {
+			rep.Error = fmt.Sprintf("entryDecode: %v", err)
+			return
+		}
+		var lrv1 types.LogRootV1
+		err = lrv1.UnmarshalBinary(da.STH.LogRoot)
+		if err != nil {
+			rep.Error = fmt.Sprintf("unmarshal LogRootV1: %v", err)
+			return
+		}
+		if lrv1.TreeSize <= uint64(leaf.LeafIndex) {
+			continue
+		}
+		sth = &da.STH
+		treeSize = lrv1.TreeSize // tree size when anchor was dropped
+		rep.Anchor = &da.VerifyDigest.ChainInformation
+		break
+	}
+	if sth == nil {
+		// Entry hasn't been anchored yet
+		var lrv1 *types.LogRootV1
+		sth, lrv1, err = t.client.signedLogRoot(tree)
+		if err != nil {
+			rep.Error = fmt.Sprintf("signedLogRoot: %v", err)
+			return
+		}
+		treeSize = lrv1.TreeSize
+	}
+
+	proof, err := t.client.inclusionProof(tree.TreeId, leaf.LeafIndex,
+		treeSize)
+	if err != nil {
+		rep.Error = fmt.Sprintf("inclusionProof: %v", err)
+		return
+	}
+
+	rep.RecordEntry = re
+	rep.Leaf = leaf
+	rep.STH = sth
+	rep.Proof = proof
+
+	return
+}
+
+// loadKeys loads the trillian signing key and the blob encryption key.  Both
+// keys are created if they do not exist.
+func loadKeys(trillianKeyFile, encryptionKeyFile string) (*keyspb.PrivateKey, *[32]byte, error) {
+	// Create new signing key
+	if !util.FileExists(trillianKeyFile) {
+		log.Infof("Generating trillian signing key...")
+		signingKey, err := keys.NewFromSpec(&keyspb.Specification{
+			Params: &keyspb.Specification_EcdsaParams{},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := der.MarshalPrivateKey(signingKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = ioutil.WriteFile(trillianKeyFile, b, 0400)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		log.Infof("Trillian signing key created...")
+	}
+
+	// Create new encryption key
+	if !util.FileExists(encryptionKeyFile) {
+		log.Infof("Generating encryption key...")
+		key, err := newKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		err = ioutil.WriteFile(encryptionKeyFile, key[:], 0400)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		log.Infof("Encryption key created...")
+	}
+
+	// Load signing key and verify that it is DER encoded
+	signingKey := &keyspb.PrivateKey{}
+	var err error
+	signingKey.Der, err = ioutil.ReadFile(trillianKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = der.UnmarshalPrivateKey(signingKey.Der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Load encryption key
+	b, err := ioutil.ReadFile(encryptionKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var key [32]byte
+	if len(b) != len(key) {
+		return nil, nil, fmt.Errorf("invalid encryption key length")
+	}
+	copy(key[:], b)
+
+	return signingKey, &key, nil
+}
+
+// newBackEnd returns a tlogBackEnd context that uses the provided trillian
+// client.
+func newBackEnd(root string, ts util.Timestamper, tc trillianClient, key *[32]byte, id *identity.FullIdentity) (*tlogBackEnd, error) {
+	dataPath := filepath.Join(root, DefaultDataPath)
+	err := os.MkdirAll(dataPath, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	indexFile := filepath.Join(dataPath, indexPath)
+	log.Infof("Index: %v", indexFile)
+	index, err := leveldb.OpenFile(indexFile, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := newBlobFilesystem(key, filepath.Join(dataPath, blobsPath))
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	return &tlogBackEnd{
+		root:        root,
+		index:       index,
+		blob:        bs,
+		client:      tc,
+		id:          id,
+		cron:        cron.New(),
+		timestamper: ts,
+		exit:        make(chan struct{}),
+		checkAnchor: make(chan struct{}),
+		dirty:       make(map[int64]uint64),
+		testAnchors: make(map[string]bool),
+	}, nil
+}
+
+// New returns a tlogBackEnd context.  The trillian signing key and the blob
+// encryption key are created if they do not exist.  Record entries are
+// signed with the provided identity.
+func New(root string, ts util.Timestamper, trillianHost, trillianKeyFile, encryptionKeyFile string, id *identity.FullIdentity) (*tlogBackEnd, error) {
+	signingKey, key, err := loadKeys(trillianKeyFile, encryptionKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Trillian log server: %v", trillianHost)
+	tc, err := newTClient(trillianHost, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := newBackEnd(root, ts, tc, key, id)
+	if err != nil {
+		tc.close()
+		return nil, err
+	}
+
+	// Scan for unanchored trees
+	log.Infof("Scanning for unanchored trees")
+	err = t.scanTrees()
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	// Launch anchor checker and don't do any work just yet.
+	go t.periodicAnchorChecker()
+
+	// Launch cron.
+	err = t.cron.AddFunc(anchorSchedule, t.anchorTreesCronJob)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	t.cron.Start()
+
+	// Message user
+	log.Infof("Timestamp host: %v", t.timestamper)
+
+	return t, nil
+}
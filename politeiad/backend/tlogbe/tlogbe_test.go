@@ -0,0 +1,504 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/decred/slog"
+	"github.com/google/trillian/client"
+	tcrypto "github.com/google/trillian/crypto"
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/api/v1/mime"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/backend/backendtest"
+	v1 "github.com/thi4go/politeia/tlog/api/v1"
+	tlogutil "github.com/thi4go/politeia/tlog/util"
+	"github.com/thi4go/politeia/util"
+)
+
+type testWriter struct {
+	t *testing.T
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.t.Logf("%s", p)
+	return len(p), nil
+}
+
+func newTestBackEnd(t *testing.T) (*tlogBackEnd, func()) {
+	t.Helper()
+
+	log := slog.NewBackend(&testWriter{t}).Logger("TEST")
+	UseLogger(log)
+
+	dir, err := ioutil.TempDir("", "politeia.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := identity.New()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	key, err := newKey()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	tc, err := newTestTClient()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	tb, err := newBackEnd(dir, util.NewDcrtime(""), tc, key, id)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	tb.test = true
+
+	return tb, func() {
+		tb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestFiles(t *testing.T, name string, count int) []backend.File {
+	t.Helper()
+
+	files := make([]backend.File, 0, count)
+	for j := 0; j < count; j++ {
+		r, err := util.Random(64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Create text file
+		payload := hex.EncodeToString(r)
+		digest := hex.EncodeToString(util.Digest([]byte(payload)))
+		// We expect base64 encoded content
+		b64 := base64.StdEncoding.EncodeToString([]byte(payload))
+
+		files = append(files, backend.File{
+			Name:    name + "_" + strconv.Itoa(j),
+			MIME:    mime.DetectMimeType([]byte(payload)),
+			Digest:  digest,
+			Payload: b64,
+		})
+	}
+	return files
+}
+
+// verifyProofs verifies the record entries and inclusion proofs of a record
+// against the public key of the tree that holds it.
+func verifyProofs(tb *tlogBackEnd, token []byte, proofs []v1.RecordEntryProof) error {
+	treeID, err := tb.treeID(hex.EncodeToString(token))
+	if err != nil {
+		return err
+	}
+	tree, err := tb.client.tree(treeID)
+	if err != nil {
+		return err
+	}
+	verifier, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range proofs {
+		if v.Error != "" {
+			return fmt.Errorf("%v", v.Error)
+		}
+		err = tlogutil.RecordEntryVerify(*v.RecordEntry)
+		if err != nil {
+			return err
+		}
+
+		// The test anchors do not carry a merkle path so only the
+		// inclusion proof is verified.
+		lrv1, err := tcrypto.VerifySignedLogRoot(verifier.PubKey,
+			crypto.SHA256, v.STH)
+		if err != nil {
+			return err
+		}
+		err = verifier.VerifyInclusionByHash(lrv1,
+			v.Leaf.MerkleLeafHash, v.Proof)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestNewRecord(t *testing.T) {
+	tb, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	md := []backend.MetadataStream{{
+		ID:      1,
+		Payload: "this is metadata",
+	}}
+	files := newTestFiles(t, "file", 3)
+	rm, err := tb.New(md, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rm.Status != backend.MDStatusUnvetted || rm.Iteration != 1 {
+		t.Fatalf("unexpected record metadata %v %v", rm.Status,
+			rm.Iteration)
+	}
+
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tb.UnvettedExists(token) || tb.VettedExists(token) {
+		t.Fatalf("record should only exist in unvetted")
+	}
+
+	r, err := tb.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.RecordMetadata, *rm) {
+		t.Fatalf("record metadata mismatch")
+	}
+	if !reflect.DeepEqual(r.Metadata, md) {
+		t.Fatalf("metadata mismatch")
+	}
+	if !reflect.DeepEqual(r.Files, files) {
+		t.Fatalf("files mismatch")
+	}
+	if r.Version != "1" {
+		t.Fatalf("unexpected version %v", r.Version)
+	}
+
+	_, err = tb.GetVetted(token, "")
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Every record entry must have a valid inclusion proof
+	proofs, err := tb.RecordEntryProofs(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// record index + record metadata + metadata stream + files
+	if len(proofs) != 2+len(md)+len(files) {
+		t.Fatalf("unexpected number of proofs: %v", len(proofs))
+	}
+	err = verifyProofs(tb, token, proofs)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRecord(t *testing.T) {
+	tb, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	files := newTestFiles(t, "file", 2)
+	rm, err := tb.New([]backend.MetadataStream{{
+		ID:      1,
+		Payload: "a",
+	}}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unvetted update without changes
+	_, err = tb.UpdateUnvettedRecord(token, nil, nil, nil, nil)
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+
+	// Delete a file that does not exist
+	_, err = tb.UpdateUnvettedRecord(token, nil, nil, nil,
+		[]string{"nope"})
+	if _, ok := err.(backend.ContentVerificationError); !ok {
+		t.Fatalf("expected ContentVerificationError, got %v", err)
+	}
+
+	// Unvetted update, drops the vote authorization
+	r, err := tb.UpdateUnvettedRecord(token,
+		[]backend.MetadataStream{{ID: 1, Payload: "b"}},
+		[]backend.MetadataStream{{
+			ID:      decredplugin.MDStreamAuthorizeVote,
+			Payload: "authorize",
+		}}, newTestFiles(t, "added", 1), []string{files[0].Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Status != backend.MDStatusIterationUnvetted ||
+		r.RecordMetadata.Iteration != 2 || r.Version != "1" {
+		t.Fatalf("unexpected record %v %v %v", r.RecordMetadata.Status,
+			r.RecordMetadata.Iteration, r.Version)
+	}
+	r, err = tb.GetUnvetted(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Files) != 2 || r.Files[0].Name != "added_0" ||
+		r.Files[1].Name != files[1].Name {
+		t.Fatalf("unexpected files %v", r.Files)
+	}
+	if len(r.Metadata) != 1 || r.Metadata[0].Payload != "ab" {
+		t.Fatalf("unexpected metadata %v", r.Metadata)
+	}
+
+	// Vetted update of unvetted record
+	_, err = tb.UpdateVettedRecord(token, nil, nil,
+		newTestFiles(t, "vetted", 1), nil)
+	if err != backend.ErrRecordNotFound {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Publish
+	_, err = tb.SetUnvettedStatus(token, backend.MDStatusArchived, nil,
+		nil)
+	if _, ok := err.(backend.StateTransitionError); !ok {
+		t.Fatalf("expected StateTransitionError, got %v", err)
+	}
+	r, err = tb.SetUnvettedStatus(token, backend.MDStatusVetted, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Files != nil {
+		t.Fatalf("status change must not return files")
+	}
+	if !tb.VettedExists(token) || tb.UnvettedExists(token) {
+		t.Fatalf("record should only exist in vetted")
+	}
+
+	// Unvetted update of vetted record
+	_, err = tb.UpdateUnvettedRecord(token, nil, nil,
+		newTestFiles(t, "unvetted", 1), nil)
+	if err != backend.ErrRecordFound {
+		t.Fatalf("expected ErrRecordFound, got %v", err)
+	}
+
+	// Vetted update creates a new version
+	r, err = tb.UpdateVettedRecord(token, nil, nil,
+		newTestFiles(t, "vetted", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "2" {
+		t.Fatalf("unexpected version %v", r.Version)
+	}
+	v1r, err := tb.GetVetted(token, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v1r.Files) != 2 {
+		t.Fatalf("unexpected version 1 files %v", len(v1r.Files))
+	}
+	v2r, err := tb.GetVetted(token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2r.Version != "2" || len(v2r.Files) != 3 {
+		t.Fatalf("unexpected latest version %v %v", v2r.Version,
+			len(v2r.Files))
+	}
+
+	// Vetted metadata
+	err = tb.UpdateVettedMetadata(token, nil,
+		[]backend.MetadataStream{{ID: 2, Payload: "vetted"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.UpdateVettedMetadata(token, nil,
+		[]backend.MetadataStream{{ID: 2, Payload: "vetted"}})
+	if err != backend.ErrNoChanges {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+
+	// Archive
+	_, err = tb.SetVettedStatus(token, backend.MDStatusArchived, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.SetVettedStatus(token, backend.MDStatusArchived, nil, nil)
+	if err != backend.ErrRecordArchived {
+		t.Fatalf("expected ErrRecordArchived, got %v", err)
+	}
+	_, err = tb.UpdateVettedRecord(token, nil, nil,
+		newTestFiles(t, "archived", 1), nil)
+	if err != backend.ErrRecordArchived {
+		t.Fatalf("expected ErrRecordArchived, got %v", err)
+	}
+
+	// Inventory
+	pr, br, err := tb.Inventory(0, 0, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr) != 2 || len(br) != 0 {
+		t.Fatalf("unexpected inventory %v %v", len(pr), len(br))
+	}
+
+	// All versions must still be provable
+	for _, version := range []string{"1", "2"} {
+		proofs, err := tb.RecordEntryProofs(token, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = verifyProofs(tb, token, proofs)
+		if err != nil {
+			t.Fatalf("version %v: %v", version, err)
+		}
+	}
+}
+
+func TestUpdateReadme(t *testing.T) {
+	tb, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	for _, content := range []string{"one", "two", "one"} {
+		err := tb.UpdateReadme(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tb.UpdateReadme(content)
+		if err != backend.ErrNoChanges {
+			t.Fatalf("expected ErrNoChanges, got %v", err)
+		}
+
+		tb.Lock()
+		_, rm, err := tb.readme()
+		tb.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rm.Content != content {
+			t.Fatalf("unexpected readme got %v, want %v", rm.Content,
+				content)
+		}
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	tb, cleanup := newTestBackEnd(t)
+	defer cleanup()
+
+	// Create records
+	tokens := make([][]byte, 0, 3)
+	for i := 0; i < 3; i++ {
+		rm, err := tb.New(nil, newTestFiles(t, fmt.Sprintf("record%v", i),
+			2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := hex.DecodeString(rm.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tb.dirty) != len(tokens) {
+		t.Fatalf("unexpected dirty trees %v", len(tb.dirty))
+	}
+
+	// Drop and confirm anchor
+	err := tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.testAnchors) != len(tokens) {
+		t.Fatalf("unexpected test anchors %v", len(tb.testAnchors))
+	}
+
+	// Change a record while the anchor is pending.  It must remain dirty.
+	_, err = tb.UpdateUnvettedRecord(tokens[0], nil, nil,
+		newTestFiles(t, "pending", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tb.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.unconfirmed) != 0 {
+		t.Fatalf("unexpected unconfirmed anchors %v",
+			len(tb.unconfirmed))
+	}
+	for k, v := range tb.testAnchors {
+		if !v {
+			t.Fatalf("anchor not confirmed: %v", k)
+		}
+	}
+	if len(tb.dirty) != 1 {
+		t.Fatalf("unexpected dirty trees %v", len(tb.dirty))
+	}
+
+	// Anchored entries carry the chain information
+	for k, token := range tokens {
+		proofs, err := tb.RecordEntryProofs(token, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range proofs {
+			if k != 0 && v.Anchor == nil {
+				t.Fatalf("record %v: entry not anchored", k)
+			}
+			if v.Anchor != nil &&
+				v.Anchor.Transaction != expectedTestTX {
+				t.Fatalf("unexpected transaction %v",
+					v.Anchor.Transaction)
+			}
+		}
+		err = verifyProofs(tb, token, proofs)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing left to anchor after the next round
+	err = tb.anchorTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.anchorChecker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.dirty) != 0 {
+		t.Fatalf("unexpected dirty trees %v", len(tb.dirty))
+	}
+
+	// Scanning must agree
+	tb.dirty = make(map[int64]uint64)
+	err = tb.scanTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.dirty) != 0 {
+		t.Fatalf("unexpected dirty trees after scan %v", len(tb.dirty))
+	}
+}
+
+func TestBackendConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) (backend.Backend, func()) {
+		return newTestBackEnd(t)
+	})
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlogbe
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/trillian"
+	"github.com/google/trillian/client"
+	tcrypto "github.com/google/trillian/crypto"
+	"github.com/google/trillian/crypto/keyspb"
+	"github.com/google/trillian/crypto/sigpb"
+	"github.com/google/trillian/types"
+	v1 "github.com/thi4go/politeia/tlog/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// trillianClient provides the trillian log functionality that tlogbe relies
+// on.  It exists so that the backend can be run against an in-memory trillian
+// log stand-in during tests.
+type trillianClient interface {
+	// treeNew creates a new tree and returns it along with its initial
+	// signed log root.
+	treeNew() (*trillian.Tree, *trillian.SignedLogRoot, error)
+
+	// tree returns the tree for the provided tree ID.
+	tree(treeID int64) (*trillian.Tree, error)
+
+	// treesAll returns all trees.
+	treesAll() ([]*trillian.Tree, error)
+
+	// leavesAppend appends the provided leaves to a tree.  It returns
+	// the queued leaves, with an inclusion proof for every leaf that was
+	// appended, and the log root that the proofs are relative to.
+	// Duplicate leaves are not appended and are returned with a non OK
+	// status.
+	leavesAppend(tree *trillian.Tree, leaves []*trillian.LogLeaf) ([]v1.QueuedLeafProof, *types.LogRootV1, error)
+
+	// leavesAll returns all leaves of a tree ordered by leaf index.
+	leavesAll(treeID int64) ([]*trillian.LogLeaf, error)
+
+	// signedLogRoot returns the latest signed log root of a tree after
+	// verifying its signature.
+	signedLogRoot(tree *trillian.Tree) (*trillian.SignedLogRoot, *types.LogRootV1, error)
+
+	// inclusionProof returns the inclusion proof of a leaf for the
+	// provided tree size.
+	inclusionProof(treeID int64, leafIndex int64, treeSize uint64) (*trillian.Proof, error)
+
+	// close closes the client connection.
+	close()
+}
+
+var (
+	_ trillianClient = (*tclient)(nil)
+)
+
+// tclient is a trillianClient that talks to a trillian log server over gRPC.
+type tclient struct {
+	grpc       *grpc.ClientConn
+	client     trillian.TrillianLogClient
+	admin      trillian.TrillianAdminClient
+	ctx        context.Context
+	signingKey *keyspb.PrivateKey // trillian signing key
+}
+
+// treeNew satisfies the trillianClient interface.
+func (t *tclient) treeNew() (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	k, err := ptypes.MarshalAny(t.signingKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create new trillian tree
+	tree, err := t.admin.CreateTree(t.ctx, &trillian.CreateTreeRequest{
+		Tree: &trillian.Tree{
+			TreeState:          trillian.TreeState_ACTIVE,
+			TreeType:           trillian.TreeType_LOG,
+			HashStrategy:       trillian.HashStrategy_RFC6962_SHA256,
+			HashAlgorithm:      sigpb.DigitallySigned_SHA256,
+			SignatureAlgorithm: sigpb.DigitallySigned_ECDSA,
+			DisplayName:        "",
+			Description:        "",
+			MaxRootDuration:    ptypes.DurationProto(0),
+			PrivateKey:         k,
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Init tree or signer goes bananas
+	ilr, err := t.client.InitLog(t.ctx, &trillian.InitLogRequest{
+		LogId: tree.TreeId,
+	})
+	switch code := status.Code(err); code {
+	case codes.OK:
+		log.Debugf("Initialised Log: %v", tree.TreeId)
+	case codes.Unavailable:
+		return nil, nil, fmt.Errorf("log server unavailable: %v", err)
+	case codes.AlreadyExists:
+		return nil, nil, fmt.Errorf("just-created Log (%v) is already "+
+			"initialised: %v", tree.TreeId, err)
+	default:
+		return nil, nil, fmt.Errorf("failed to InitLog: %v", err)
+	}
+
+	// Verify root signature
+	verifier, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = tcrypto.VerifySignedLogRoot(verifier.PubKey,
+		crypto.SHA256, ilr.Created)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tree, ilr.Created, nil
+}
+
+// tree satisfies the trillianClient interface.
+func (t *tclient) tree(treeID int64) (*trillian.Tree, error) {
+	tree, err := t.admin.GetTree(t.ctx, &trillian.GetTreeRequest{
+		TreeId: treeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tree.TreeId != treeID {
+		// Really shouldn't happen
+		return nil, fmt.Errorf("invalid tree returned got %v wanted %v",
+			tree.TreeId, treeID)
+	}
+	return tree, nil
+}
+
+// treesAll satisfies the trillianClient interface.
+func (t *tclient) treesAll() ([]*trillian.Tree, error) {
+	ltr, err := t.admin.ListTrees(t.ctx, &trillian.ListTreesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return ltr.Tree, nil
+}
+
+// signedLogRoot satisfies the trillianClient interface.
+func (t *tclient) signedLogRoot(tree *trillian.Tree) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	resp, err := t.client.GetLatestSignedLogRoot(t.ctx,
+		&trillian.GetLatestSignedLogRootRequest{LogId: tree.TreeId})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verifier, err := client.NewLogVerifierFromTree(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	lrv1, err := tcrypto.VerifySignedLogRoot(verifier.PubKey,
+		crypto.SHA256, resp.SignedLogRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp.SignedLogRoot, lrv1, nil
+}
+
+// waitForRootUpdate waits until the trillian root is updated.
+func (t *tclient) waitForRootUpdate(tree *trillian.Tree, root *trillian.SignedLogRoot) error {
+	var logRoot types.LogRootV1
+	err := logRoot.UnmarshalBinary(root.LogRoot)
+	if err != nil {
+		return err
+	}
+	c, err := client.NewFromTree(t.client, tree, logRoot)
+	if err != nil {
+		return err
+	}
+	_, err = c.WaitForRootUpdate(t.ctx)
+	return err
+}
+
+// leavesAppend satisfies the trillianClient interface.
+func (t *tclient) leavesAppend(tree *trillian.Tree, leaves []*trillian.LogLeaf) ([]v1.QueuedLeafProof, *types.LogRootV1, error) {
+	// Retrieve the root prior to queueing the leaves so that we can
+	// wait for it to be updated.
+	root, _, err := t.signedLogRoot(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	qlr, err := t.client.QueueLeaves(t.ctx, &trillian.QueueLeavesRequest{
+		LogId:  tree.TreeId,
+		Leaves: leaves,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("QueueLeaves: %v", err)
+	}
+
+	// Only wait if we actually updated the tree
+	var n int
+	for _, v := range qlr.QueuedLeaves {
+		if codes.Code(v.GetStatus().GetCode()) == codes.OK {
+			n++
+		}
+	}
+	if n != 0 {
+		log.Debugf("Waiting for update: %v", tree.TreeId)
+		err = t.waitForRootUpdate(tree, root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("waitForRootUpdate: %v", err)
+		}
+	}
+
+	_, lrv1, err := t.signedLogRoot(tree)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signedLogRoot: %v", err)
+	}
+
+	// Get inclusion proofs
+	proofs := make([]v1.QueuedLeafProof, 0, len(qlr.QueuedLeaves))
+	for _, v := range qlr.QueuedLeaves {
+		qlp := v1.QueuedLeafProof{
+			QueuedLeaf: *v,
+		}
+		if codes.Code(v.GetStatus().GetCode()) == codes.OK {
+			// LeafIndex of a QueuedLogLeaf will not be set so
+			// get the inclusion proof by hash.
+			resp, err := t.client.GetInclusionProofByHash(t.ctx,
+				&trillian.GetInclusionProofByHashRequest{
+					LogId:    tree.TreeId,
+					LeafHash: v.Leaf.MerkleLeafHash,
+					TreeSize: int64(lrv1.TreeSize),
+				})
+			if err != nil {
+				return nil, nil, fmt.Errorf("GetInclusionProofByHash: %v",
+					err)
+			}
+			if len(resp.Proof) != 1 {
+				return nil, nil, fmt.Errorf("invalid number of proofs "+
+					"for leaf %x: got %v, want 1",
+					v.Leaf.MerkleLeafHash, len(resp.Proof))
+			}
+			qlp.Proof = resp.Proof[0]
+		}
+		proofs = append(proofs, qlp)
+	}
+
+	return proofs, lrv1, nil
+}
+
+// leavesAll satisfies the trillianClient interface.
+func (t *tclient) leavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
+	tree, err := t.tree(treeID)
+	if err != nil {
+		return nil, err
+	}
+	_, lrv1, err := t.signedLogRoot(tree)
+	if err != nil {
+		return nil, err
+	}
+	if lrv1.TreeSize == 0 {
+		return []*trillian.LogLeaf{}, nil
+	}
+
+	glbrr, err := t.client.GetLeavesByRange(t.ctx,
+		&trillian.GetLeavesByRangeRequest{
+			LogId:      treeID,
+			StartIndex: 0,
+			Count:      int64(lrv1.TreeSize),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return glbrr.Leaves, nil
+}
+
+// inclusionProof satisfies the trillianClient interface.
+func (t *tclient) inclusionProof(treeID int64, leafIndex int64, treeSize uint64) (*trillian.Proof, error) {
+	gipr, err := t.client.GetInclusionProof(t.ctx,
+		&trillian.GetInclusionProofRequest{
+			LogId:     treeID,
+			LeafIndex: leafIndex,
+			TreeSize:  int64(treeSize),
+		})
+	if err != nil {
+		return nil, err
+	}
+	return gipr.Proof, nil
+}
+
+// close satisfies the trillianClient interface.
+func (t *tclient) close() {
+	t.grpc.Close()
+}
+
+// newTClient returns a trillianClient that is connected to the provided
+// trillian log server.
+func newTClient(host string, signingKey *keyspb.PrivateKey) (*tclient, error) {
+	g, err := grpc.Dial(host, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+
+	return &tclient{
+		grpc:       g,
+		client:     trillian.NewTrillianLogClient(g),
+		admin:      trillian.NewTrillianAdminClient(g),
+		ctx:        context.Background(),
+		signingKey: signingKey,
+	}, nil
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backend
+
+import (
+	"time"
+)
+
+// Tombstone is left behind when the files of a censored record are purged.
+// It retains the merkle root and the digests of the purged files so that it
+// can still be proven that the record existed and what it contained.  The
+// files are in the same order as they were in the record and do not carry a
+// payload.
+type Tombstone struct {
+	Token     string // Record authentication token
+	Merkle    string // Merkle root of all purged files
+	Reason    string // Reason the record was purged
+	Timestamp int64  // Time the record was purged
+	Files     []File // Purged files without payload
+}
+
+// NewTombstone returns the tombstone of the provided record.  The record
+// files are copied without their payload.
+func NewTombstone(r *Record, reason string) *Tombstone {
+	files := make([]File, 0, len(r.Files))
+	for _, v := range r.Files {
+		files = append(files, File{
+			Name:   v.Name,
+			MIME:   v.MIME,
+			Digest: v.Digest,
+		})
+	}
+	return &Tombstone{
+		Token:     r.RecordMetadata.Token,
+		Merkle:    r.RecordMetadata.Merkle,
+		Reason:    reason,
+		Timestamp: time.Now().Unix(),
+		Files:     files,
+	}
+}
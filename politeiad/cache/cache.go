@@ -156,6 +156,15 @@ type PluginDriver interface {
 	// Run a plugin hook. The given gorm.DB should be a transaction so
 	// that the hook actions can be executed atomically.
 	Hook(tx *gorm.DB, hookID, payload string) error
+
+	// Verify compares the plugin tables against the given payload, which
+	// uses the same format as the Build payload, and returns the
+	// differences of each record whose plugin data diverges.
+	Verify(payload string) (map[string][]string, error)
+
+	// Repair replaces the plugin data of the given records using the
+	// given payload, which uses the same format as the Build payload.
+	Repair(tokens []string, payload string) error
 }
 
 // Cache describes the interface used for interacting with an external
@@ -182,6 +191,10 @@ type Cache interface {
 	// Update the metadata streams of a record
 	UpdateRecordMetadata(string, []MetadataStream) error
 
+	// Replace all versions of a record.  The record is removed from
+	// the cache if no versions are provided.
+	ReplaceRecord(string, []Record) error
+
 	// Get the latest version of a set of records
 	Records([]string, bool) (map[string]Record, error)
 
@@ -206,6 +219,12 @@ type Cache interface {
 	// Execute a plugin command
 	PluginExec(PluginCommand) (*PluginCommandReply, error)
 
+	// Verify the cache of a plugin against the plugin inventory
+	PluginVerify(string, string) (map[string][]string, error)
+
+	// Repair the cache of a plugin for a set of records
+	PluginRepair(string, []string, string) error
+
 	// Perform cleanup of the cache
 	Close()
 }
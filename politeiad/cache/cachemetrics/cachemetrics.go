@@ -0,0 +1,198 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package cachemetrics provides a cache that records the latency and the
+// failures of every query of the cache it wraps as Prometheus metrics.
+package cachemetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thi4go/politeia/politeiad/cache"
+)
+
+var (
+	// queryDuration is the duration of cache queries by method.
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "politeia",
+		Subsystem: "cache",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of cache queries.",
+	}, []string{"method"})
+
+	// queryErrors counts the failed cache queries by method.
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "politeia",
+		Subsystem: "cache",
+		Name:      "query_errors_total",
+		Help:      "Number of failed cache queries.",
+	}, []string{"method"})
+)
+
+// cachemetrics implements the cache interface.
+type cachemetrics struct {
+	cache cache.Cache
+}
+
+// observe records the duration of a query that started at the provided time
+// and whether it failed.  Records that are not found are an expected outcome
+// of a lookup and are not counted as failures.
+func observe(method string, start time.Time, err error) {
+	queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil && err != cache.ErrRecordNotFound {
+		queryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// NewRecord satisfies the cache interface.
+func (c *cachemetrics) NewRecord(r cache.Record) error {
+	start := time.Now()
+	err := c.cache.NewRecord(r)
+	observe("NewRecord", start, err)
+	return err
+}
+
+// Record satisfies the cache interface.
+func (c *cachemetrics) Record(token string) (*cache.Record, error) {
+	start := time.Now()
+	r, err := c.cache.Record(token)
+	observe("Record", start, err)
+	return r, err
+}
+
+// RecordVersion satisfies the cache interface.
+func (c *cachemetrics) RecordVersion(token, version string) (*cache.Record, error) {
+	start := time.Now()
+	r, err := c.cache.RecordVersion(token, version)
+	observe("RecordVersion", start, err)
+	return r, err
+}
+
+// UpdateRecord satisfies the cache interface.
+func (c *cachemetrics) UpdateRecord(r cache.Record) error {
+	start := time.Now()
+	err := c.cache.UpdateRecord(r)
+	observe("UpdateRecord", start, err)
+	return err
+}
+
+// UpdateRecordStatus satisfies the cache interface.
+func (c *cachemetrics) UpdateRecordStatus(token, version string, status cache.RecordStatusT, timestamp int64, metadata []cache.MetadataStream) error {
+	start := time.Now()
+	err := c.cache.UpdateRecordStatus(token, version, status, timestamp,
+		metadata)
+	observe("UpdateRecordStatus", start, err)
+	return err
+}
+
+// UpdateRecordMetadata satisfies the cache interface.
+func (c *cachemetrics) UpdateRecordMetadata(token string, md []cache.MetadataStream) error {
+	start := time.Now()
+	err := c.cache.UpdateRecordMetadata(token, md)
+	observe("UpdateRecordMetadata", start, err)
+	return err
+}
+
+// ReplaceRecord satisfies the cache interface.
+func (c *cachemetrics) ReplaceRecord(token string, records []cache.Record) error {
+	start := time.Now()
+	err := c.cache.ReplaceRecord(token, records)
+	observe("ReplaceRecord", start, err)
+	return err
+}
+
+// Records satisfies the cache interface.
+func (c *cachemetrics) Records(tokens []string, fetchFiles bool) (map[string]cache.Record, error) {
+	start := time.Now()
+	r, err := c.cache.Records(tokens, fetchFiles)
+	observe("Records", start, err)
+	return r, err
+}
+
+// Inventory satisfies the cache interface.
+func (c *cachemetrics) Inventory() ([]cache.Record, error) {
+	start := time.Now()
+	r, err := c.cache.Inventory()
+	observe("Inventory", start, err)
+	return r, err
+}
+
+// Setup satisfies the cache interface.
+func (c *cachemetrics) Setup() error {
+	start := time.Now()
+	err := c.cache.Setup()
+	observe("Setup", start, err)
+	return err
+}
+
+// Build satisfies the cache interface.
+func (c *cachemetrics) Build(records []cache.Record) error {
+	start := time.Now()
+	err := c.cache.Build(records)
+	observe("Build", start, err)
+	return err
+}
+
+// RegisterPlugin satisfies the cache interface.
+func (c *cachemetrics) RegisterPlugin(p cache.Plugin) error {
+	start := time.Now()
+	err := c.cache.RegisterPlugin(p)
+	observe("RegisterPlugin", start, err)
+	return err
+}
+
+// PluginSetup satisfies the cache interface.
+func (c *cachemetrics) PluginSetup(id string) error {
+	start := time.Now()
+	err := c.cache.PluginSetup(id)
+	observe("PluginSetup", start, err)
+	return err
+}
+
+// PluginBuild satisfies the cache interface.
+func (c *cachemetrics) PluginBuild(id, payload string) error {
+	start := time.Now()
+	err := c.cache.PluginBuild(id, payload)
+	observe("PluginBuild", start, err)
+	return err
+}
+
+// PluginExec satisfies the cache interface.
+func (c *cachemetrics) PluginExec(pc cache.PluginCommand) (*cache.PluginCommandReply, error) {
+	start := time.Now()
+	r, err := c.cache.PluginExec(pc)
+	observe("PluginExec", start, err)
+	return r, err
+}
+
+// PluginVerify satisfies the cache interface.
+func (c *cachemetrics) PluginVerify(id, payload string) (map[string][]string, error) {
+	start := time.Now()
+	r, err := c.cache.PluginVerify(id, payload)
+	observe("PluginVerify", start, err)
+	return r, err
+}
+
+// PluginRepair satisfies the cache interface.
+func (c *cachemetrics) PluginRepair(id string, tokens []string, payload string) error {
+	start := time.Now()
+	err := c.cache.PluginRepair(id, tokens, payload)
+	observe("PluginRepair", start, err)
+	return err
+}
+
+// Close satisfies the cache interface.
+func (c *cachemetrics) Close() {
+	c.cache.Close()
+}
+
+// New returns a cache that records the query latency and failures of the
+// provided cache.
+func New(c cache.Cache) cache.Cache {
+	return &cachemetrics{
+		cache: c,
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cachemetrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/thi4go/politeia/politeiad/cache"
+	"github.com/thi4go/politeia/politeiad/cache/testcache"
+)
+
+// failingCache is a cache whose Setup always fails.  Calls to any other
+// method panic.
+type failingCache struct {
+	cache.Cache
+	err error
+}
+
+// Setup satisfies the cache interface.
+func (c *failingCache) Setup() error {
+	return c.err
+}
+
+// queries returns the number of recorded queries of the provided method.
+func queries(t *testing.T, method string) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	err := queryDuration.WithLabelValues(method).(prometheus.Metric).Write(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// failures returns the number of recorded failures of the provided method.
+func failures(method string) float64 {
+	return testutil.ToFloat64(queryErrors.WithLabelValues(method))
+}
+
+func TestForward(t *testing.T) {
+	c := New(testcache.New())
+	defer c.Close()
+
+	newRecords := queries(t, "NewRecord")
+	records := queries(t, "Record")
+	recordFailures := failures("Record")
+
+	r := cache.Record{
+		Version: "1",
+		CensorshipRecord: cache.CensorshipRecord{
+			Token: "0123",
+		},
+	}
+	err := c.NewRecord(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Record(r.CensorshipRecord.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CensorshipRecord.Token != r.CensorshipRecord.Token {
+		t.Errorf("got record %v, want %v", got.CensorshipRecord.Token,
+			r.CensorshipRecord.Token)
+	}
+
+	// Records that are not found are forwarded but are not failures
+	_, err = c.Record("4567")
+	if err != cache.ErrRecordNotFound {
+		t.Errorf("got error %v, want %v", err, cache.ErrRecordNotFound)
+	}
+
+	if n := queries(t, "NewRecord") - newRecords; n != 1 {
+		t.Errorf("got %v NewRecord queries, want 1", n)
+	}
+	if n := queries(t, "Record") - records; n != 2 {
+		t.Errorf("got %v Record queries, want 2", n)
+	}
+	if n := failures("Record") - recordFailures; n != 0 {
+		t.Errorf("got %v Record failures, want 0", n)
+	}
+}
+
+func TestFailure(t *testing.T) {
+	want := errors.New("setup failed")
+	c := New(&failingCache{err: want})
+
+	setups := queries(t, "Setup")
+	setupFailures := failures("Setup")
+
+	err := c.Setup()
+	if err != want {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+
+	if n := queries(t, "Setup") - setups; n != 1 {
+		t.Errorf("got %v Setup queries, want 1", n)
+	}
+	if n := failures("Setup") - setupFailures; n != 1 {
+		t.Errorf("got %v Setup failures, want 1", n)
+	}
+}
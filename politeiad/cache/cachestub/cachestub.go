@@ -44,6 +44,11 @@ func (c *cachestub) UpdateRecordMetadata(token string, ms []cache.MetadataStream
 	return nil
 }
 
+// ReplaceRecord is a stub to satisfy the cache interface.
+func (c *cachestub) ReplaceRecord(token string, records []cache.Record) error {
+	return nil
+}
+
 // Inventory is a stub to satisfy the cache interface.
 func (c *cachestub) Inventory() ([]cache.Record, error) {
 	return make([]cache.Record, 0), nil
@@ -83,6 +88,16 @@ func (c *cachestub) PluginExec(pc cache.PluginCommand) (*cache.PluginCommandRepl
 	return &cache.PluginCommandReply{}, nil
 }
 
+// PluginVerify is a stub to satisfy the cache interface.
+func (c *cachestub) PluginVerify(id, payload string) (map[string][]string, error) {
+	return make(map[string][]string), nil
+}
+
+// PluginRepair is a stub to satisfy the cache interface.
+func (c *cachestub) PluginRepair(id string, tokens []string, payload string) error {
+	return nil
+}
+
 // Close is a stub to satisfy the cache interface.
 func (c *cachestub) Close() {}
 
@@ -4,7 +4,10 @@
 
 package cockroachdb
 
-import "github.com/decred/slog"
+import (
+	"github.com/decred/slog"
+	"github.com/thi4go/politeia/politeiad/cache/gormcache"
+)
 
 // log is a logger that is initialized with no output filters.  This
 // means the package will not perform any logging by default until the caller
@@ -15,11 +18,13 @@ var log = slog.Disabled
 // by default until either UseLogger or SetLogWriter are called.
 func DisableLog() {
 	log = slog.Disabled
+	gormcache.DisableLog()
 }
 
 // UseLogger uses a specified Logger to output package logging info.
 // This should be used in preference to SetLogWriter if the caller is also
-// using slog.
+// using slog.  The logger is shared with the gormcache package.
 func UseLogger(logger slog.Logger) {
 	log = logger
+	gormcache.UseLogger(logger)
 }
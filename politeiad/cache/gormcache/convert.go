@@ -2,7 +2,7 @@
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
-package cockroachdb
+package gormcache
 
 import (
 	"fmt"
@@ -116,7 +116,7 @@ func convertCommentFromDecred(c decredplugin.Comment) Comment {
 		CommentID: c.CommentID,
 		Receipt:   c.Receipt,
 		Timestamp: c.Timestamp,
-		Censored:  false,
+		Censored:  c.Censored,
 	}
 }
 
@@ -263,6 +263,37 @@ func convertStartVoteV2FromDecred(sv decredplugin.StartVoteV2, svr decredplugin.
 	}, nil
 }
 
+func convertStartVoteTupleFromDecred(svt decredplugin.StartVoteTuple) (*StartVote, error) {
+	switch svt.StartVote.Version {
+	case decredplugin.VersionStartVoteV1:
+		sv1, err := decredplugin.DecodeStartVoteV1([]byte(svt.StartVote.Payload))
+		if err != nil {
+			return nil, fmt.Errorf("decode StartVoteV1 %v: %v",
+				svt.StartVote.Token, err)
+		}
+		sv, err := convertStartVoteV1FromDecred(*sv1, svt.StartVoteReply)
+		if err != nil {
+			return nil, fmt.Errorf("convertStartVoteV1FromDecred %v: %v",
+				svt.StartVote.Token, err)
+		}
+		return sv, nil
+	case decredplugin.VersionStartVoteV2:
+		sv2, err := decredplugin.DecodeStartVoteV2([]byte(svt.StartVote.Payload))
+		if err != nil {
+			return nil, fmt.Errorf("decode StartVoteV2 %v: %v",
+				svt.StartVote.Token, err)
+		}
+		sv, err := convertStartVoteV2FromDecred(*sv2, svt.StartVoteReply)
+		if err != nil {
+			return nil, fmt.Errorf("convertStartVoteV2FromDecred %v: %v",
+				svt.StartVote.Token, err)
+		}
+		return sv, nil
+	}
+	return nil, fmt.Errorf("invalid StartVote version %v %v",
+		svt.StartVote.Token, svt.StartVote.Version)
+}
+
 func convertStartVoteToDecredV1(sv StartVote) (*decredplugin.StartVote, error) {
 	opts := make([]decredplugin.VoteOption, 0, len(sv.Options))
 	for _, v := range sv.Options {
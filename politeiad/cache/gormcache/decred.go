@@ -2,20 +2,21 @@
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
-package cockroachdb
+package gormcache
 
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/thi4go/politeia/decredplugin"
 	"github.com/thi4go/politeia/mdstream"
 	pd "github.com/thi4go/politeia/politeiad/api/v1"
 	"github.com/thi4go/politeia/politeiad/cache"
-	"github.com/jinzhu/gorm"
 )
 
 const (
@@ -42,6 +43,7 @@ const (
 // decred implements the PluginDriver interface.
 type decred struct {
 	recordsdb *gorm.DB              // Database context
+	dialect   Dialect               // Database specific behavior
 	version   string                // Version of decred cache plugin
 	settings  []cache.PluginSetting // Plugin settings
 }
@@ -222,15 +224,19 @@ func (d *decred) cmdGetNumComments(payload string) (string, error) {
 		Counts int
 	}
 	results := make([]Result, 0, len(gnc.Tokens))
-	err = d.recordsdb.
-		Table("comments").
-		Select("count(*) as counts, token").
-		Group("token").
-		Where("token IN (?)", gnc.Tokens).
-		Find(&results).
-		Error
-	if err != nil {
-		return "", err
+	for _, v := range d.dialect.Chunk(gnc.Tokens) {
+		var r []Result
+		err = d.recordsdb.
+			Table("comments").
+			Select("count(*) as counts, token").
+			Group("token").
+			Where("token IN (?)", v).
+			Find(&r).
+			Error
+		if err != nil {
+			return "", err
+		}
+		results = append(results, r...)
 	}
 
 	// Put results into a map
@@ -1143,56 +1149,49 @@ func (d *decred) getVoteResults(startVotes map[string]StartVote) (map[string][]d
 	return results, nil
 }
 
-func (d *decred) cmdBatchVoteSummary(payload string) (string, error) {
-	log.Tracef("cmdBatchVoteSummary")
-
-	bvs, err := decredplugin.DecodeBatchVoteSummary([]byte(payload))
-	if err != nil {
-		return "", err
-	}
-
+// batchVoteSummary returns the vote summaries of the provided records.  The
+// tokens must fit into a single statement, see Dialect.Chunk.
+func (d *decred) batchVoteSummary(tokens []string, summaries map[string]decredplugin.VoteSummaryReply) error {
 	// This query gets the latest version of each record
 	query := `SELECT a.* FROM records a
 	LEFT OUTER JOIN records b
 		ON a.token = b.token AND a.version < b.version
 	WHERE b.token IS NULL AND a.token IN (?)`
 
-	rows, err := d.recordsdb.Raw(query, bvs.Tokens).Rows()
+	rows, err := d.recordsdb.Raw(query, tokens).Rows()
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer rows.Close()
 
-	records := make(map[string]Record, len(bvs.Tokens))
+	records := make(map[string]Record, len(tokens))
 	for rows.Next() {
 		var r Record
 		err := d.recordsdb.ScanRows(rows, &r)
 		if err != nil {
-			return "", err
+			return err
 		}
 		records[r.Token] = r
 	}
 	if err = rows.Err(); err != nil {
-		return "", err
+		return err
 	}
 
 	authorizeVotes, err := d.getAuthorizeVotesForRecords(records)
 	if err != nil {
-		return "", fmt.Errorf("lookup authorize votes: %v", err)
+		return fmt.Errorf("lookup authorize votes: %v", err)
 	}
 
 	startVotes, err := d.getStartVotes(authorizeVotes)
 	if err != nil {
-		return "", fmt.Errorf("lookup start vote: %v", err)
+		return fmt.Errorf("lookup start vote: %v", err)
 	}
 
 	results, err := d.getVoteResults(startVotes)
 	if err != nil {
-		return "", fmt.Errorf("lookup vote results: %v", err)
+		return fmt.Errorf("lookup vote results: %v", err)
 	}
 
-	summaries := make(map[string]decredplugin.VoteSummaryReply,
-		len(bvs.Tokens))
 	for token := range records {
 		av := authorizeVotes[token]
 		sv := startVotes[token]
@@ -1216,6 +1215,26 @@ func (d *decred) cmdBatchVoteSummary(payload string) (string, error) {
 		summaries[token] = vsr
 	}
 
+	return nil
+}
+
+func (d *decred) cmdBatchVoteSummary(payload string) (string, error) {
+	log.Tracef("cmdBatchVoteSummary")
+
+	bvs, err := decredplugin.DecodeBatchVoteSummary([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	summaries := make(map[string]decredplugin.VoteSummaryReply,
+		len(bvs.Tokens))
+	for _, v := range d.dialect.Chunk(bvs.Tokens) {
+		err := d.batchVoteSummary(v, summaries)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	bvsr := decredplugin.BatchVoteSummaryReply{
 		Summaries: summaries,
 	}
@@ -1479,6 +1498,22 @@ func (d *decred) hookPostUpdateRecordMetadata(tx *gorm.DB, payload string) error
 	return nil
 }
 
+// hookPostDeleteRecord executes the decred plugin post delete record hook.
+// This includes deleting the ProposalGeneralMetadata of the record whose
+// token is passed in as the payload.
+//
+// This function must be called using a transaction.
+func (d *decred) hookPostDeleteRecord(tx *gorm.DB, payload string) error {
+	err := tx.Delete(ProposalGeneralMetadata{
+		Token: payload,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("delete: %v", err)
+	}
+
+	return nil
+}
+
 // Hook executes the given decred plugin hook.
 func (d *decred) Hook(tx *gorm.DB, hookID, payload string) error {
 	log.Tracef("decred Hook: %v", hookID)
@@ -1490,6 +1525,8 @@ func (d *decred) Hook(tx *gorm.DB, hookID, payload string) error {
 		return d.hookPostUpdateRecord(tx, payload)
 	case pluginHookPostUpdateRecordMetadata:
 		return d.hookPostUpdateRecordMetadata(tx, payload)
+	case pluginHookPostDeleteRecord:
+		return d.hookPostDeleteRecord(tx, payload)
 	}
 
 	return nil
@@ -1651,42 +1688,16 @@ func (d *decred) dropTables(tx *gorm.DB) error {
 	}).Error
 }
 
-// build the decred plugin cache using the passed in inventory.
-//
-// This function cannot be called using a transaction because it could
-// potentially exceed cockroachdb's transaction size limit.
-func (d *decred) build(ir *decredplugin.InventoryReply) error {
-	log.Tracef("decred build")
-
-	// Drop all decred plugin tables
-	tx := d.recordsdb.Begin()
-	err := d.dropTables(tx)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("drop tables: %v", err)
-	}
-	err = tx.Commit().Error
-	if err != nil {
-		return err
-	}
-
-	// Create decred plugin tables
-	tx = d.recordsdb.Begin()
-	err = d.createTables(tx)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("create tables: %v", err)
-	}
-	err = tx.Commit().Error
-	if err != nil {
-		return err
-	}
-
+// insertInventory inserts the comments, comment likes, authorize votes, start
+// votes and cast votes of the passed in inventory into the decred plugin
+// tables.  This function has a database parameter so that it can be called
+// inside of a transaction when required.
+func (d *decred) insertInventory(db *gorm.DB, ir *decredplugin.InventoryReply) error {
 	// Build comments cache
 	log.Tracef("decred: building comments cache")
 	for _, v := range ir.Comments {
 		c := convertCommentFromDecred(v)
-		err := d.recordsdb.Create(&c).Error
+		err := db.Create(&c).Error
 		if err != nil {
 			log.Debugf("create comment failed on '%v'", c)
 			return fmt.Errorf("newComment: %v", err)
@@ -1697,7 +1708,7 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 	log.Tracef("decred: building like comments cache")
 	for _, v := range ir.LikeComments {
 		lc := convertLikeCommentFromDecred(v)
-		err := d.recordsdb.Create(&lc).Error
+		err := db.Create(&lc).Error
 		if err != nil {
 			log.Debugf("newLikeComment failed on '%v'", lc)
 			return fmt.Errorf("newLikeComment: %v", err)
@@ -1728,7 +1739,7 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 		}
 
 		av := convertAuthorizeVoteFromDecred(v, r, rv)
-		err = d.newAuthorizeVote(d.recordsdb, av)
+		err = d.newAuthorizeVote(db, av)
 		if err != nil {
 			log.Debugf("newAuthorizeVote failed on '%v'", av)
 			return fmt.Errorf("newAuthorizeVote: %v", err)
@@ -1738,39 +1749,13 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 	// Build start vote cache
 	log.Tracef("decred: building start vote cache")
 	for _, v := range ir.StartVoteTuples {
-		// Handle start vote versioning
-		var sv StartVote
-		switch v.StartVote.Version {
-		case decredplugin.VersionStartVoteV1:
-			svb := []byte(v.StartVote.Payload)
-			sv1, err := decredplugin.DecodeStartVoteV1(svb)
-			if err != nil {
-				return fmt.Errorf("decode StartVoteV2 %v: %v",
-					v.StartVote.Token, err)
-			}
-			svp, err := convertStartVoteV1FromDecred(*sv1, v.StartVoteReply)
-			if err != nil {
-				return fmt.Errorf("convertStartVoteV1FromDecred %v: %v",
-					v.StartVote.Token, err)
-			}
-			sv = *svp
-		case decredplugin.VersionStartVoteV2:
-			svb := []byte(v.StartVote.Payload)
-			sv2, err := decredplugin.DecodeStartVoteV2(svb)
-			if err != nil {
-				return fmt.Errorf("decode StartVoteV1 %v: %v",
-					v.StartVote.Token, err)
-			}
-			svp, err := convertStartVoteV2FromDecred(*sv2, v.StartVoteReply)
-			if err != nil {
-				return fmt.Errorf("convertStartVoteV2FromDecred %v: %v",
-					v.StartVote.Version, err)
-			}
-			sv = *svp
+		sv, err := convertStartVoteTupleFromDecred(v)
+		if err != nil {
+			return err
 		}
 
 		// Insert start vote record
-		err = d.recordsdb.Create(&sv).Error
+		err = db.Create(sv).Error
 		if err != nil {
 			return fmt.Errorf("insert StartVote: %v %v",
 				err, sv.Token)
@@ -1781,13 +1766,40 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 	log.Tracef("decred: building cast vote cache")
 	for _, v := range ir.CastVotes {
 		cv := convertCastVoteFromDecred(v)
-		err := d.recordsdb.Create(&cv).Error
+		err := db.Create(&cv).Error
 		if err != nil {
 			log.Debugf("insert cast vote failed on '%v'", cv)
 			return fmt.Errorf("insert cast vote: %v", err)
 		}
 	}
 
+	return nil
+}
+
+// build the decred plugin cache using the passed in inventory.
+//
+// This function must be called through Dialect.Build.
+func (d *decred) build(tx *gorm.DB, ir *decredplugin.InventoryReply) error {
+	log.Tracef("decred build")
+
+	// Drop all decred plugin tables
+	err := d.dropTables(tx)
+	if err != nil {
+		return fmt.Errorf("drop tables: %v", err)
+	}
+
+	// Create decred plugin tables
+	err = d.createTables(tx)
+	if err != nil {
+		return fmt.Errorf("create tables: %v", err)
+	}
+
+	// Insert the inventory
+	err = d.insertInventory(tx, ir)
+	if err != nil {
+		return err
+	}
+
 	// Build the ProposalGeneralMetadata cache. This metadata is not
 	// part of the decredplugin InventoryReply. It is already stored
 	// in the cached as a MetadataStream with an encoded payload. We
@@ -1795,87 +1807,63 @@ func (d *decred) build(ir *decredplugin.InventoryReply) error {
 	// mdstream, and save it as a ProposalGeneralMetadata record so
 	// that it is queriable. Only the ProposalGeneralMetadata for the
 	// most recent version of the proposal is saved to the cache.
-
-	// Lookup latest version of each record
-	query := `SELECT a.*
+	//
+	// The metadata streams are joined to the latest version of each
+	// record in a single query because the number of records may
+	// exceed the number of variables that can be bound to a
+	// statement.
+	query := `SELECT a.token, a.version, m.payload
             FROM records a
             LEFT OUTER JOIN records b
               ON a.token = b.token
               AND a.version < b.version
-              WHERE b.token IS NULL`
-	rows, err := d.recordsdb.Raw(query).Rows()
+            INNER JOIN metadata_streams m
+              ON m.record_key = a.key
+              WHERE b.token IS NULL
+              AND m.id = ?`
+	rows, err := tx.Raw(query, mdstream.IDProposalGeneral).Rows()
 	if err != nil {
 		return fmt.Errorf("lookup latest records: %v", err)
 	}
 	defer rows.Close()
 
-	records := make([]Record, 0, 1024)
+	pgms := make([]ProposalGeneralMetadata, 0, 1024)
 	for rows.Next() {
-		var r Record
-		err := d.recordsdb.ScanRows(rows, &r)
+		var (
+			token   string
+			version uint64
+			payload string
+		)
+		err := rows.Scan(&token, &version, &payload)
 		if err != nil {
 			return err
 		}
-		records = append(records, r)
-	}
-	if err = rows.Err(); err != nil {
-		return err
-	}
-
-	// Compile a list of record primary keys
-	keys := make([]string, 0, len(records))
-	for _, v := range records {
-		keys = append(keys, v.Key)
-	}
-
-	// Lookup the metadata streams for each record
-	err = d.recordsdb.
-		Preload("Metadata").
-		Where(keys).
-		Find(&records).
-		Error
-	if err != nil {
-		return fmt.Errorf("lookup record metadata: %v", err)
-	}
-
-	for _, v := range records {
-		// Decode the ProposalGeneral mdstream
-		var pg *mdstream.ProposalGeneral
-		for _, md := range v.Metadata {
-			if md.ID == mdstream.IDProposalGeneral {
-				pg, err = mdstream.DecodeProposalGeneral([]byte(md.Payload))
-				if err != nil {
-					return fmt.Errorf("decode ProposalGenral %v '%v': %v",
-						v.Token, md.Payload, err)
-				}
-			}
-		}
-		if pg == nil {
-			// XXX we cannot return an error here until the plugin
-			// architecture is sorted out. Right now, politeiad registers
-			// the decred plugin by default. CMS needs a way to register
-			// just the functionality it needs so that proposal specific
-			// tables do not get built for CMS.
-			// return fmt.Errorf("no ProposalGenral mdstream found %v",
-			//	v.Token)
-
-			continue
+		pg, err := mdstream.DecodeProposalGeneral([]byte(payload))
+		if err != nil {
+			return fmt.Errorf("decode ProposalGenral %v '%v': %v",
+				token, payload, err)
 		}
-
-		// Insert the ProposalGeneralMetadata record
-		pgm := ProposalGeneralMetadata{
-			Token:           v.Token,
-			ProposalVersion: v.Version,
+		pgms = append(pgms, ProposalGeneralMetadata{
+			Token:           token,
+			ProposalVersion: version,
 			Version:         pg.Version,
 			Timestamp:       pg.Timestamp,
 			Name:            pg.Name,
 			Signature:       pg.Signature,
 			PublicKey:       pg.PublicKey,
-		}
-		err := d.recordsdb.Create(&pgm).Error
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	// Insert the ProposalGeneralMetadata records
+	for _, v := range pgms {
+		err := tx.Create(&v).Error
 		if err != nil {
 			return fmt.Errorf("insert ProposalGeneralMetadata %v: %v",
-				pgm, err)
+				v, err)
 		}
 	}
 
@@ -1894,23 +1882,332 @@ func (d *decred) Build(payload string) error {
 		return fmt.Errorf("DecodeInventoryReply: %v", err)
 	}
 
-	// Build the decred plugin cache. This is not run using
-	// a transaction because it could potentially exceed
-	// cockroachdb's transaction size limit.
-	err = d.build(ir)
+	return d.dialect.Build(d.recordsdb, decredplugin.ID,
+		func(tx *gorm.DB) error {
+			return d.build(tx, ir)
+		})
+}
+
+// Decred plugin table entry kinds that are compared by Verify.
+const (
+	entryComment       = "comment"
+	entryLikeComment   = "commentlike"
+	entryAuthorizeVote = "authorizevote"
+	entryStartVote     = "startvote"
+	entryCastVote      = "castvote"
+)
+
+// entryKinds is the order in which divergent entry kinds are reported.
+var entryKinds = []string{
+	entryComment,
+	entryLikeComment,
+	entryAuthorizeVote,
+	entryStartVote,
+	entryCastVote,
+}
+
+// decredEntry is a decred plugin table row encoded as a string so that rows
+// can be compared regardless of where they came from.
+type decredEntry struct {
+	kind  string // Entry kind
+	value string // String encoded row
+}
+
+// decredEntries counts the decred plugin table rows of each record.
+type decredEntries map[string]map[decredEntry]int // [token][entry]count
+
+// add adds a row to the entries of the given record.  Primary keys that are
+// generated by the database must be zeroed by the caller.
+func (e decredEntries) add(token, kind string, row interface{}) {
+	if _, ok := e[token]; !ok {
+		e[token] = make(map[decredEntry]int)
+	}
+	e[token][decredEntry{kind, fmt.Sprintf("%+v", row)}]++
+}
+
+// sortVoteOptions sorts the options of a start vote by ID and zeroes their
+// database generated keys.
+func sortVoteOptions(sv *StartVote) {
+	for i := range sv.Options {
+		sv.Options[i].Key = 0
+	}
+	sort.Slice(sv.Options, func(i, j int) bool {
+		return sv.Options[i].ID < sv.Options[j].ID
+	})
+}
+
+// inventoryEntries returns the decred plugin table rows that building the
+// cache from the passed in inventory would create.
+func inventoryEntries(ir *decredplugin.InventoryReply) (decredEntries, error) {
+	e := make(decredEntries)
+	for _, v := range ir.Comments {
+		c := convertCommentFromDecred(v)
+		e.add(c.Token, entryComment, c)
+	}
+	for _, v := range ir.LikeComments {
+		lc := convertLikeCommentFromDecred(v)
+		e.add(lc.Token, entryLikeComment, lc)
+	}
+
+	// Only the last authorize vote of a record version is kept in
+	// the cache.
+	avr := make(map[string]decredplugin.AuthorizeVoteReply,
+		len(ir.AuthorizeVoteReplies)) // [receipt]AuthorizeVoteReply
+	for _, v := range ir.AuthorizeVoteReplies {
+		avr[v.Receipt] = v
+	}
+	avs := make(map[string]AuthorizeVote,
+		len(ir.AuthorizeVotes)) // [key]AuthorizeVote
+	for _, v := range ir.AuthorizeVotes {
+		r, ok := avr[v.Receipt]
+		if !ok {
+			return nil, fmt.Errorf("AuthorizeVoteReply not found %v",
+				v.Token)
+		}
+		rv, err := strconv.ParseUint(r.RecordVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse version '%v' failed: %v",
+				r.RecordVersion, err)
+		}
+		av := convertAuthorizeVoteFromDecred(v, r, rv)
+		avs[av.Key] = av
+	}
+	for _, v := range avs {
+		e.add(v.Token, entryAuthorizeVote, v)
+	}
+
+	for _, v := range ir.StartVoteTuples {
+		sv, err := convertStartVoteTupleFromDecred(v)
+		if err != nil {
+			return nil, err
+		}
+		sortVoteOptions(sv)
+		e.add(sv.Token, entryStartVote, *sv)
+	}
+	for _, v := range ir.CastVotes {
+		cv := convertCastVoteFromDecred(v)
+		e.add(cv.Token, entryCastVote, cv)
+	}
+
+	return e, nil
+}
+
+// cacheEntries returns the decred plugin table rows that are currently in
+// the cache.
+func (d *decred) cacheEntries() (decredEntries, error) {
+	e := make(decredEntries)
+
+	var comments []Comment
+	err := d.recordsdb.Find(&comments).Error
 	if err != nil {
-		// Remove the version record. This will
-		// force a rebuild on the next start up.
-		err1 := d.recordsdb.Delete(&Version{
-			ID: decredplugin.ID,
-		}).Error
-		if err1 != nil {
-			panic("the cache is out of sync and will not rebuild" +
-				"automatically; a rebuild must be forced")
+		return nil, fmt.Errorf("lookup comments: %v", err)
+	}
+	for _, v := range comments {
+		e.add(v.Token, entryComment, v)
+	}
+
+	var likes []LikeComment
+	err = d.recordsdb.Find(&likes).Error
+	if err != nil {
+		return nil, fmt.Errorf("lookup comment likes: %v", err)
+	}
+	for _, v := range likes {
+		v.Key = 0
+		e.add(v.Token, entryLikeComment, v)
+	}
+
+	var avs []AuthorizeVote
+	err = d.recordsdb.Find(&avs).Error
+	if err != nil {
+		return nil, fmt.Errorf("lookup authorize votes: %v", err)
+	}
+	for _, v := range avs {
+		e.add(v.Token, entryAuthorizeVote, v)
+	}
+
+	// The vote options are looked up separately because preloading
+	// them would bind the token of every start vote to a single
+	// statement.
+	var svs []StartVote
+	err = d.recordsdb.Find(&svs).Error
+	if err != nil {
+		return nil, fmt.Errorf("lookup start votes: %v", err)
+	}
+	var vos []VoteOption
+	err = d.recordsdb.Find(&vos).Error
+	if err != nil {
+		return nil, fmt.Errorf("lookup vote options: %v", err)
+	}
+	options := make(map[string][]VoteOption, len(svs)) // [token]options
+	for _, v := range vos {
+		options[v.Token] = append(options[v.Token], v)
+	}
+	for _, v := range svs {
+		v.Options = options[v.Token]
+		sortVoteOptions(&v)
+		e.add(v.Token, entryStartVote, v)
+	}
+
+	var cvs []CastVote
+	err = d.recordsdb.Find(&cvs).Error
+	if err != nil {
+		return nil, fmt.Errorf("lookup cast votes: %v", err)
+	}
+	for _, v := range cvs {
+		v.Key = 0
+		e.add(v.Token, entryCastVote, v)
+	}
+
+	return e, nil
+}
+
+// Verify compares the decred plugin tables against the passed in inventory
+// payload and returns, for each divergent record, the number of missing and
+// unexpected rows of each kind.
+func (d *decred) Verify(payload string) (map[string][]string, error) {
+	log.Tracef("decred Verify")
+
+	ir, err := decredplugin.DecodeInventoryReply([]byte(payload))
+	if err != nil {
+		return nil, fmt.Errorf("DecodeInventoryReply: %v", err)
+	}
+	want, err := inventoryEntries(ir)
+	if err != nil {
+		return nil, err
+	}
+	got, err := d.cacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]struct{}, len(want)+len(got))
+	for k := range want {
+		tokens[k] = struct{}{}
+	}
+	for k := range got {
+		tokens[k] = struct{}{}
+	}
+
+	diffs := make(map[string][]string)
+	for token := range tokens {
+		missing := make(map[string]int)    // [kind]count
+		unexpected := make(map[string]int) // [kind]count
+		for k, v := range want[token] {
+			if n := v - got[token][k]; n > 0 {
+				missing[k.kind] += n
+			}
+		}
+		for k, v := range got[token] {
+			if n := v - want[token][k]; n > 0 {
+				unexpected[k.kind] += n
+			}
+		}
+
+		var reasons []string
+		for _, kind := range entryKinds {
+			m, u := missing[kind], unexpected[kind]
+			if m == 0 && u == 0 {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("%v: %v missing, "+
+				"%v unexpected", kind, m, u))
+		}
+		if len(reasons) > 0 {
+			diffs[token] = reasons
 		}
 	}
 
-	return err
+	return diffs, nil
+}
+
+// Repair replaces the decred plugin data of the passed in records with the
+// data from the inventory payload.  Vote results are deleted and will be
+// lazily reloaded.
+func (d *decred) Repair(tokens []string, payload string) error {
+	log.Tracef("decred Repair: %v", tokens)
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	ir, err := decredplugin.DecodeInventoryReply([]byte(payload))
+	if err != nil {
+		return fmt.Errorf("DecodeInventoryReply: %v", err)
+	}
+
+	// Filter the inventory down to the records that are being
+	// repaired. Authorize vote replies are looked up by receipt so
+	// they are all kept.
+	repair := make(map[string]struct{}, len(tokens))
+	for _, v := range tokens {
+		repair[v] = struct{}{}
+	}
+	inRepair := func(token string) bool {
+		_, ok := repair[token]
+		return ok
+	}
+	filtered := decredplugin.InventoryReply{
+		AuthorizeVoteReplies: ir.AuthorizeVoteReplies,
+	}
+	for _, v := range ir.Comments {
+		if inRepair(v.Token) {
+			filtered.Comments = append(filtered.Comments, v)
+		}
+	}
+	for _, v := range ir.LikeComments {
+		if inRepair(v.Token) {
+			filtered.LikeComments = append(filtered.LikeComments, v)
+		}
+	}
+	for _, v := range ir.AuthorizeVotes {
+		if inRepair(v.Token) {
+			filtered.AuthorizeVotes = append(filtered.AuthorizeVotes, v)
+		}
+	}
+	for _, v := range ir.StartVoteTuples {
+		if inRepair(v.StartVote.Token) {
+			filtered.StartVoteTuples = append(filtered.StartVoteTuples, v)
+		}
+	}
+	for _, v := range ir.CastVotes {
+		if inRepair(v.Token) {
+			filtered.CastVotes = append(filtered.CastVotes, v)
+		}
+	}
+
+	tx := d.recordsdb.Begin()
+
+	// Delete the existing plugin data
+	models := []interface{}{
+		Comment{},
+		LikeComment{},
+		AuthorizeVote{},
+		VoteOption{},
+		StartVote{},
+		CastVote{},
+		VoteOptionResult{},
+		VoteResults{},
+	}
+	for _, v := range models {
+		for _, c := range d.dialect.Chunk(tokens) {
+			err := tx.Where("token IN (?)", c).
+				Delete(v).
+				Error
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("delete %T: %v", v, err)
+			}
+		}
+	}
+
+	// Insert the plugin data from the inventory
+	err = d.insertInventory(tx, &filtered)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
 }
 
 // Setup creates the decred plugin tables if they do not already exist.  A
@@ -1962,10 +2259,11 @@ func (d *decred) CheckVersion() error {
 }
 
 // newDecredPlugin returns a cache decred plugin context.
-func newDecredPlugin(db *gorm.DB, p cache.Plugin) *decred {
+func newDecredPlugin(db *gorm.DB, dialect Dialect, p cache.Plugin) *decred {
 	log.Tracef("newDecredPlugin")
 	return &decred{
 		recordsdb: db,
+		dialect:   dialect,
 		version:   decredVersion,
 		settings:  p.Settings,
 	}
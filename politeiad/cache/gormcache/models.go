@@ -2,7 +2,7 @@
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
-package cockroachdb
+package gormcache
 
 // Version describes the version of a record or plugin that the database is
 // currently using.
@@ -0,0 +1,30 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"github.com/decred/slog"
+	"github.com/thi4go/politeia/politeiad/cache/gormcache"
+)
+
+// log is a logger that is initialized with no output filters.  This
+// means the package will not perform any logging by default until the caller
+// requests it.
+var log = slog.Disabled
+
+// DisableLog disables all library log output.  Logging output is disabled
+// by default until either UseLogger or SetLogWriter are called.
+func DisableLog() {
+	log = slog.Disabled
+	gormcache.DisableLog()
+}
+
+// UseLogger uses a specified Logger to output package logging info.
+// This should be used in preference to SetLogWriter if the caller is also
+// using slog.  The logger is shared with the gormcache package.
+func UseLogger(logger slog.Logger) {
+	log = logger
+	gormcache.UseLogger(logger)
+}
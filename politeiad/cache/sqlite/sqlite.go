@@ -0,0 +1,96 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/thi4go/politeia/politeiad/cache/gormcache"
+)
+
+const (
+	// busyTimeout is the number of milliseconds a connection waits
+	// for a lock held by another connection.
+	busyTimeout = "5000"
+
+	// maxVariables is the maximum number of values that are bound to
+	// a single statement.  SQLite refuses statements with more than
+	// 999 variables.
+	maxVariables = 500
+)
+
+// dialect implements the SQLite specific parts of the cache.
+type dialect struct{}
+
+// Chunk splits values into lists of at most maxVariables values.
+//
+// Chunk satisfies the gormcache Dialect interface.
+func (dialect) Chunk(values []string) [][]string {
+	chunks := make([][]string, 0, len(values)/maxVariables+1)
+	for len(values) > maxVariables {
+		chunks = append(chunks, values[:maxVariables])
+		values = values[maxVariables:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+	return chunks
+}
+
+// Build runs the build in a single transaction.  SQLite does not limit the
+// size of a transaction and committing every record separately would make
+// the build very slow.  The existing cache is left untouched if the build
+// fails.
+//
+// Build satisfies the gormcache Dialect interface.
+func (dialect) Build(db *gorm.DB, id string, build func(*gorm.DB) error) error {
+	tx := db.Begin()
+	err := build(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// New returns a new cache context that contains a connection to the
+// database stored in the passed in file.  politeiad is the only process that
+// writes to the cache.  Other processes, such as politeiawww, must open the
+// cache read only.  The database file is created if it does not exist and
+// the cache is not opened read only.
+func New(filename string, readOnly bool) (*gormcache.Cache, error) {
+	log.Tracef("New: %v %v", filename, readOnly)
+
+	// Readers do not block the writer, and vice versa, when the
+	// database uses a write-ahead log.  Writers wait for each other
+	// instead of failing right away.
+	v := url.Values{}
+	v.Set("_busy_timeout", busyTimeout)
+	if readOnly {
+		v.Set("mode", "ro")
+	} else {
+		err := os.MkdirAll(filepath.Dir(filename), 0700)
+		if err != nil {
+			return nil, err
+		}
+		v.Set("_journal_mode", "WAL")
+		v.Set("_txlock", "immediate")
+	}
+	addr := "file:" + filepath.Clean(filename) + "?" + v.Encode()
+	db, err := gorm.Open("sqlite3", addr)
+	if err != nil {
+		return nil, fmt.Errorf("open database '%v': %v", addr, err)
+	}
+
+	log.Infof("Cache file: %v", filename)
+
+	return gormcache.New(db, dialect{})
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/politeiad/cache"
+	"github.com/thi4go/politeia/politeiad/cache/gormcache"
+)
+
+func newTestCache(t *testing.T) (*gormcache.Cache, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "sqlitecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(filepath.Join(dir, "cache.db"), false)
+	if err != cache.ErrNoVersionRecord {
+		t.Fatalf("New: got %v, want %v", err, cache.ErrNoVersionRecord)
+	}
+	err = s.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register the decred plugin
+	err = s.RegisterPlugin(cache.Plugin{
+		ID:      decredplugin.ID,
+		Version: decredplugin.Version,
+	})
+	if err != cache.ErrNoVersionRecord {
+		t.Fatalf("RegisterPlugin: got %v, want %v", err,
+			cache.ErrNoVersionRecord)
+	}
+	err = s.PluginSetup(decredplugin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestRecord(token, version string) cache.Record {
+	return cache.Record{
+		Version:   version,
+		Status:    cache.RecordStatusNotReviewed,
+		Timestamp: 1,
+		CensorshipRecord: cache.CensorshipRecord{
+			Token:     token,
+			Merkle:    "merkle" + version,
+			Signature: "signature" + version,
+		},
+		Metadata: []cache.MetadataStream{
+			{ID: 12, Payload: "metadata" + version},
+		},
+		Files: []cache.File{
+			{
+				Name:    "index.md",
+				MIME:    "text/plain; charset=utf-8",
+				Digest:  "digest" + version,
+				Payload: "payload" + version,
+			},
+		},
+	}
+}
+
+func TestRecords(t *testing.T) {
+	s, cleanup := newTestCache(t)
+	defer cleanup()
+
+	r1 := newTestRecord("a", "1")
+	err := s.NewRecord(r1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := newTestRecord("a", "2")
+	err = s.NewRecord(r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The latest version is returned by default
+	r, err := s.Record("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*r, r2) {
+		t.Fatalf("got %v, want %v", *r, r2)
+	}
+	r, err = s.RecordVersion("a", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*r, r1) {
+		t.Fatalf("got %v, want %v", *r, r1)
+	}
+	_, err = s.Record("b")
+	if err != cache.ErrRecordNotFound {
+		t.Fatalf("got %v, want %v", err, cache.ErrRecordNotFound)
+	}
+
+	// Update status
+	err = s.UpdateRecordStatus("a", "2", cache.RecordStatusPublic, 2,
+		r2.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = s.Record("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != cache.RecordStatusPublic || r.Timestamp != 2 {
+		t.Fatalf("status not updated: %v %v", r.Status, r.Timestamp)
+	}
+
+	// Replace all versions
+	r3 := newTestRecord("a", "1")
+	r3.Status = cache.RecordStatusCensored
+	err = s.ReplaceRecord("a", []cache.Record{r3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, err := s.Inventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv) != 1 || !reflect.DeepEqual(inv[0], r3) {
+		t.Fatalf("got %v, want %v", inv, r3)
+	}
+	err = s.ReplaceRecord("a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, err = s.Inventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv) != 0 {
+		t.Fatalf("record not removed: %v", inv)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	s, cleanup := newTestCache(t)
+	defer cleanup()
+
+	// Build a cache that contains more records than can be bound to a
+	// single statement.
+	records := make([]cache.Record, 0, maxVariables*3)
+	tokens := make([]string, 0, maxVariables*3)
+	for i := 0; i < cap(records); i++ {
+		token := strconv.Itoa(i)
+		records = append(records, newTestRecord(token, "1"))
+		tokens = append(tokens, token)
+	}
+	err := s.Build(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := s.Inventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv) != len(records) {
+		t.Fatalf("inventory: got %v records, want %v", len(inv),
+			len(records))
+	}
+	rm, err := s.Records(tokens, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rm) != len(records) {
+		t.Fatalf("records: got %v records, want %v", len(rm),
+			len(records))
+	}
+	for _, v := range records {
+		if !reflect.DeepEqual(rm[v.CensorshipRecord.Token], v) {
+			t.Fatalf("got %v, want %v", rm[v.CensorshipRecord.Token], v)
+		}
+	}
+}
+
+func TestDecredPlugin(t *testing.T) {
+	s, cleanup := newTestCache(t)
+	defer cleanup()
+
+	err := s.NewRecord(newTestRecord("a", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a comment
+	nc := decredplugin.NewComment{
+		Token:     "a",
+		ParentID:  "0",
+		Comment:   "comment",
+		Signature: "signature",
+		PublicKey: "publickey",
+	}
+	ncr := decredplugin.NewCommentReply{
+		CommentID: "1",
+		Receipt:   "receipt",
+		Timestamp: 1,
+	}
+	ncb, err := decredplugin.EncodeNewComment(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ncrb, err := decredplugin.EncodeNewCommentReply(ncr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdNewComment,
+		CommandPayload: string(ncb),
+		ReplyPayload:   string(ncrb),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gnc, err := decredplugin.EncodeGetNumComments(decredplugin.GetNumComments{
+		Tokens: []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := s.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdGetNumComments,
+		CommandPayload: string(gnc),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gncr, err := decredplugin.DecodeGetNumCommentsReply([]byte(reply.Payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gncr.NumComments) != 1 || gncr.NumComments["a"] != 1 {
+		t.Fatalf("unexpected number of comments: %v", gncr.NumComments)
+	}
+
+	// The cache diverges from an inventory without the comment
+	ir := decredplugin.InventoryReply{}
+	irb, err := decredplugin.EncodeInventoryReply(ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := s.PluginVerify(decredplugin.ID, string(irb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"a": {"comment: 0 missing, 1 unexpected"},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("got %v, want %v", diffs, want)
+	}
+
+	// Repairing the record removes the comment
+	err = s.PluginRepair(decredplugin.ID, []string{"a"}, string(irb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = s.PluginVerify(decredplugin.ID, string(irb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("unexpected differences after repair: %v", diffs)
+	}
+
+	// Rebuilding from an inventory with the comment restores it
+	ir.Comments = []decredplugin.Comment{
+		{
+			Token:     nc.Token,
+			ParentID:  nc.ParentID,
+			Comment:   nc.Comment,
+			Signature: nc.Signature,
+			PublicKey: nc.PublicKey,
+			CommentID: ncr.CommentID,
+			Receipt:   ncr.Receipt,
+			Timestamp: ncr.Timestamp,
+		},
+	}
+	irb, err = decredplugin.EncodeInventoryReply(ir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PluginBuild(decredplugin.ID, string(irb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = s.PluginVerify(decredplugin.ID, string(irb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("unexpected differences after build: %v", diffs)
+	}
+}
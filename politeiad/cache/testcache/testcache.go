@@ -163,6 +163,23 @@ func (c *testcache) UpdateRecordMetadata(token string, md []cache.MetadataStream
 	return nil
 }
 
+// ReplaceRecord replaces all versions of a record in the cache.
+func (c *testcache) ReplaceRecord(token string, records []cache.Record) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(records) == 0 {
+		delete(c.records, token)
+		return nil
+	}
+
+	c.records[token] = make(map[string]cache.Record, len(records))
+	for _, r := range records {
+		c.records[token][r.Version] = r
+	}
+	return nil
+}
+
 // inventory returns all records in the cache.
 func (c *testcache) inventory() ([]cache.Record, error) {
 	records := make([]cache.Record, 0, len(c.records))
@@ -231,6 +248,16 @@ func (c *testcache) PluginExec(pc cache.PluginCommand) (*cache.PluginCommandRepl
 	}, nil
 }
 
+// PluginVerify is a stub to satisfy the cache interface.
+func (c *testcache) PluginVerify(id, payload string) (map[string][]string, error) {
+	return make(map[string][]string), nil
+}
+
+// PluginRepair is a stub to satisfy the cache interface.
+func (c *testcache) PluginRepair(id string, tokens []string, payload string) error {
+	return nil
+}
+
 // Close is a stub to satisfy the cache interface.
 func (c *testcache) Close() {}
 
@@ -0,0 +1,104 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CompareRecords compares a cached record against the same record version as
+// it is stored in the backend, which is the source of truth.  It returns a
+// description of every difference.  An empty result means that the cached
+// record is up to date.
+func CompareRecords(backend, cached Record) []string {
+	diffs := make([]string, 0)
+	if backend.Version != cached.Version {
+		diffs = append(diffs, fmt.Sprintf("version: got %v, want %v",
+			cached.Version, backend.Version))
+	}
+	if backend.Status != cached.Status {
+		diffs = append(diffs, fmt.Sprintf("status: got %v, want %v",
+			cached.Status, backend.Status))
+	}
+	if backend.Timestamp != cached.Timestamp {
+		diffs = append(diffs, fmt.Sprintf("timestamp: got %v, want %v",
+			cached.Timestamp, backend.Timestamp))
+	}
+	if backend.CensorshipRecord != cached.CensorshipRecord {
+		diffs = append(diffs, "censorship record")
+	}
+
+	// Metadata streams are compared by ID since their order is not
+	// preserved by all caches.
+	bm := make(map[uint64]string, len(backend.Metadata))
+	for _, v := range backend.Metadata {
+		bm[v.ID] = v.Payload
+	}
+	cm := make(map[uint64]string, len(cached.Metadata))
+	for _, v := range cached.Metadata {
+		cm[v.ID] = v.Payload
+	}
+	ids := make([]uint64, 0, len(bm)+len(cm))
+	for id := range bm {
+		ids = append(ids, id)
+	}
+	for id := range cm {
+		if _, ok := bm[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		b, inBackend := bm[id]
+		c, inCache := cm[id]
+		switch {
+		case !inCache:
+			diffs = append(diffs, fmt.Sprintf("metadata stream %v: "+
+				"missing", id))
+		case !inBackend:
+			diffs = append(diffs, fmt.Sprintf("metadata stream %v: "+
+				"unexpected", id))
+		case b != c:
+			diffs = append(diffs, fmt.Sprintf("metadata stream %v: "+
+				"payload differs", id))
+		}
+	}
+
+	// Files are compared by name for the same reason.
+	bf := make(map[string]File, len(backend.Files))
+	for _, v := range backend.Files {
+		bf[v.Name] = v
+	}
+	cf := make(map[string]File, len(cached.Files))
+	for _, v := range cached.Files {
+		cf[v.Name] = v
+	}
+	names := make([]string, 0, len(bf)+len(cf))
+	for name := range bf {
+		names = append(names, name)
+	}
+	for name := range cf {
+		if _, ok := bf[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b, inBackend := bf[name]
+		c, inCache := cf[name]
+		switch {
+		case !inCache:
+			diffs = append(diffs, fmt.Sprintf("file %v: missing", name))
+		case !inBackend:
+			diffs = append(diffs, fmt.Sprintf("file %v: unexpected",
+				name))
+		case b != c:
+			diffs = append(diffs, fmt.Sprintf("file %v: differs", name))
+		}
+	}
+
+	return diffs
+}
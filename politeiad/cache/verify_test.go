@@ -0,0 +1,72 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareRecords(t *testing.T) {
+	backend := Record{
+		Version:   "2",
+		Status:    RecordStatusPublic,
+		Timestamp: 42,
+		CensorshipRecord: CensorshipRecord{
+			Token:     "token",
+			Merkle:    "merkle",
+			Signature: "signature",
+		},
+		Metadata: []MetadataStream{
+			{ID: 2, Payload: "two"},
+			{ID: 12, Payload: "twelve"},
+		},
+		Files: []File{
+			{Name: "a", MIME: "text/plain", Digest: "da", Payload: "pa"},
+			{Name: "b", MIME: "text/plain", Digest: "db", Payload: "pb"},
+		},
+	}
+
+	// Order of metadata streams and files is ignored
+	cached := backend
+	cached.Metadata = []MetadataStream{backend.Metadata[1],
+		backend.Metadata[0]}
+	cached.Files = []File{backend.Files[1], backend.Files[0]}
+	if diffs := CompareRecords(backend, cached); len(diffs) != 0 {
+		t.Fatalf("unexpected differences: %v", diffs)
+	}
+
+	cached = Record{
+		Version:   "2",
+		Status:    RecordStatusCensored,
+		Timestamp: 42,
+		CensorshipRecord: CensorshipRecord{
+			Token:     "token",
+			Merkle:    "other",
+			Signature: "signature",
+		},
+		Metadata: []MetadataStream{
+			{ID: 2, Payload: "changed"},
+			{ID: 3, Payload: "three"},
+		},
+		Files: []File{
+			{Name: "a", MIME: "text/plain", Digest: "da", Payload: "pa"},
+			{Name: "c", MIME: "text/plain", Digest: "dc", Payload: "pc"},
+		},
+	}
+	want := []string{
+		"status: got 3, want 4",
+		"censorship record",
+		"metadata stream 2: payload differs",
+		"metadata stream 3: unexpected",
+		"metadata stream 12: missing",
+		"file b: missing",
+		"file c: unexpected",
+	}
+	diffs := CompareRecords(backend, cached)
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("got %v, want %v", diffs, want)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/changefeed"
+	"github.com/thi4go/politeia/util"
+)
+
+const (
+	// defaultChangeFeedFilename is the file, relative to the politeiad
+	// data directory, that the change feed is stored in.
+	defaultChangeFeedFilename = "changefeed.journal"
+)
+
+func convertChangeFeedEvent(e changefeed.Event) v1.ChangeEvent {
+	ce := v1.ChangeEvent{
+		Sequence:  e.Sequence,
+		Type:      v1.ChangeT(e.Type),
+		Timestamp: e.Timestamp,
+		Token:     e.Token,
+		Version:   e.Version,
+		Plugin:    e.Plugin,
+		Command:   e.Command,
+	}
+	if e.Status != backend.MDStatusInvalid {
+		ce.Status = convertBackendStatus(e.Status)
+	}
+	return ce
+}
+
+// recordChange appends an event to the change feed.  The change has already
+// been made by the backend at this point so a failure is logged instead of
+// being returned to the client.
+func (p *politeia) recordChange(e changefeed.Event) {
+	_, err := p.feed.Append(e)
+	if err != nil {
+		log.Criticalf("Change feed append failed %v %v: %v",
+			v1.Change[v1.ChangeT(e.Type)], e.Token, err)
+	}
+}
+
+// recordRecordChange appends a change of the provided record to the change
+// feed.
+func (p *politeia) recordRecordChange(t changefeed.EventT, r backend.Record) {
+	p.recordChange(changefeed.Event{
+		Type:    t,
+		Token:   r.RecordMetadata.Token,
+		Version: r.Version,
+		Status:  r.RecordMetadata.Status,
+	})
+}
+
+// pluginCommandToken returns the record token that the provided plugin
+// command payload refers to.  An empty string is returned if the payload
+// does not carry a token.
+func pluginCommandToken(payload string) string {
+	var t struct {
+		Token string `json:"token"`
+	}
+	err := json.Unmarshal([]byte(payload), &t)
+	if err != nil {
+		return ""
+	}
+	return t.Token
+}
+
+func (p *politeia) changeFeed(w http.ResponseWriter, r *http.Request) {
+	var t v1.ChangeFeed
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	// Wait for changes.  The request is released early when the client
+	// goes away.
+	timeout := t.Timeout
+	if timeout > v1.ChangeFeedTimeoutMax {
+		timeout = v1.ChangeFeedTimeoutMax
+	}
+	ctx, cancel := context.WithTimeout(r.Context(),
+		time.Duration(timeout)*time.Second)
+	defer cancel()
+	events, err := p.feed.Wait(ctx, t.Since, int(v1.ChangeFeedPageSize))
+	if err != nil {
+		if err == changefeed.ErrInvalidSequence {
+			p.respondWithUserError(w,
+				v1.ErrorStatusInvalidChangeSequence, nil)
+			return
+		}
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Change feed error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	// Prepare reply
+	reply := v1.ChangeFeedReply{
+		Response: hex.EncodeToString(response[:]),
+		Events:   make([]v1.ChangeEvent, 0, len(events)),
+		Last:     p.feed.Last(),
+	}
+	for _, v := range events {
+		reply.Events = append(reply.Events, convertChangeFeedEvent(v))
+	}
+
+	log.Debugf("Change feed %v: since %v, %v events", remoteAddr(r),
+		t.Since, len(reply.Events))
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package changefeed provides a persistent and ordered log of the changes
+// that politeiad makes to its records.  Every event is assigned a monotonic
+// sequence number when it is appended.  The events are stored in a file, one
+// JSON encoded event per line, so that subscribers can resume from the last
+// sequence number they have seen after either side restarts.
+package changefeed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/thi4go/politeia/politeiad/backend"
+)
+
+// EventT is the type of a change event.
+type EventT int
+
+const (
+	EventInvalid        EventT = 0 // Invalid event
+	EventNewRecord      EventT = 1 // Record was submitted
+	EventUpdateRecord   EventT = 2 // Record files or metadata were updated
+	EventSetStatus      EventT = 3 // Record status was changed
+	EventUpdateMetadata EventT = 4 // Vetted record metadata was updated
+	EventPluginCommand  EventT = 5 // Plugin command was executed
+	EventPurgeRecord    EventT = 6 // Censored record was purged
+)
+
+var (
+	// ErrInvalidSequence is returned when events are requested after a
+	// sequence number that the feed has not reached yet.
+	ErrInvalidSequence = errors.New("invalid sequence")
+
+	// ErrClosed is returned when the feed is used after it was closed.
+	ErrClosed = errors.New("feed closed")
+)
+
+// Event describes a single change.  Token and Version are empty for plugin
+// commands that do not refer to a record.
+type Event struct {
+	Sequence  uint64            `json:"sequence"`          // Sequence number
+	Type      EventT            `json:"type"`              // Type of change
+	Timestamp int64             `json:"timestamp"`         // Time of change
+	Token     string            `json:"token,omitempty"`   // Record token
+	Version   string            `json:"version,omitempty"` // Record version
+	Status    backend.MDStatusT `json:"status,omitempty"`  // Record status
+	Plugin    string            `json:"plugin,omitempty"`  // Plugin ID
+	Command   string            `json:"command,omitempty"` // Plugin command
+}
+
+// Feed is an append only log of change events.  It is safe for concurrent
+// use.
+type Feed struct {
+	sync.RWMutex
+	file    *os.File
+	offsets []int64       // [sequence-1]offset of the event in file
+	size    int64         // Size of file
+	notify  chan struct{} // Closed when an event is appended
+	closed  bool
+}
+
+// New opens the feed that is stored in filename, creating it if it does not
+// exist.  An event that was only partially written when politeiad last shut
+// down is discarded.
+func New(filename string) (*Feed, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the existing events
+	f := &Feed{
+		file:    file,
+		offsets: make([]int64, 0, 1024),
+		notify:  make(chan struct{}),
+	}
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, err
+		}
+		var e Event
+		err = json.Unmarshal(line, &e)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("event at offset %v: %v",
+				f.size, err)
+		}
+		if e.Sequence != uint64(len(f.offsets))+1 {
+			file.Close()
+			return nil, fmt.Errorf("event at offset %v: got "+
+				"sequence %v, want %v", f.size, e.Sequence,
+				len(f.offsets)+1)
+		}
+		f.offsets = append(f.offsets, f.size)
+		f.size += int64(len(line))
+	}
+
+	// Drop a trailing partial event
+	err = file.Truncate(f.size)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	_, err = file.Seek(f.size, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Last returns the sequence number of the most recent event.  Zero is
+// returned if the feed is empty.
+func (f *Feed) Last() uint64 {
+	f.RLock()
+	defer f.RUnlock()
+
+	return uint64(len(f.offsets))
+}
+
+// Append assigns the next sequence number to the provided event, stores it
+// and wakes up all subscribers that are waiting for new events.  The stored
+// event is returned.
+func (f *Feed) Append(e Event) (*Event, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.closed {
+		return nil, ErrClosed
+	}
+
+	e.Sequence = uint64(len(f.offsets)) + 1
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().Unix()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, '\n')
+	_, err = f.file.Write(b)
+	if err != nil {
+		// Do not leave a partial event behind
+		f.file.Truncate(f.size)
+		f.file.Seek(f.size, io.SeekStart)
+		return nil, err
+	}
+	err = f.file.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	f.offsets = append(f.offsets, f.size)
+	f.size += int64(len(b))
+
+	close(f.notify)
+	f.notify = make(chan struct{})
+
+	return &e, nil
+}
+
+// Events returns up to limit events that follow the provided sequence
+// number.  An empty slice is returned if there are no such events.
+func (f *Feed) Events(since uint64, limit int) ([]Event, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.events(since, limit)
+}
+
+// events returns up to limit events that follow the provided sequence
+// number.  This function must be called with the lock held.
+func (f *Feed) events(since uint64, limit int) ([]Event, error) {
+	if f.closed {
+		return nil, ErrClosed
+	}
+	last := uint64(len(f.offsets))
+	if since > last {
+		return nil, ErrInvalidSequence
+	}
+	if since == last || limit <= 0 {
+		return []Event{}, nil
+	}
+
+	end := last
+	if end-since > uint64(limit) {
+		end = since + uint64(limit)
+	}
+	start := f.offsets[since]
+	stop := f.size
+	if end < last {
+		stop = f.offsets[end]
+	}
+	b := make([]byte, stop-start)
+	_, err := f.file.ReadAt(b, start)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, end-since)
+	for _, line := range bytes.SplitAfter(b, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var e Event
+		err := json.Unmarshal(line, &e)
+		if err != nil {
+			return nil, fmt.Errorf("event %v: %v",
+				since+uint64(len(events))+1, err)
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// Wait returns up to limit events that follow the provided sequence number.
+// If there are no such events it blocks until an event is appended or the
+// context is done, in which case an empty slice is returned.
+func (f *Feed) Wait(ctx context.Context, since uint64, limit int) ([]Event, error) {
+	for {
+		f.RLock()
+		events, err := f.events(since, limit)
+		notify := f.notify
+		f.RUnlock()
+		if err != nil || len(events) > 0 || limit <= 0 {
+			return events, err
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return []Event{}, nil
+		}
+	}
+}
+
+// Close closes the feed file.  Subscribers that are waiting for events are
+// woken up and receive ErrClosed.
+func (f *Feed) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	close(f.notify)
+
+	return f.file.Close()
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package changefeed
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFeed(t *testing.T) (*Feed, string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "changefeed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "changefeed.journal")
+	f, err := New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f, filename, func() {
+		f.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAppendEvents(t *testing.T) {
+	f, filename, cleanup := newTestFeed(t)
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		e, err := f.Append(Event{
+			Type:  EventNewRecord,
+			Token: "token",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Sequence != uint64(i)+1 {
+			t.Fatalf("sequence: got %v, want %v", e.Sequence, i+1)
+		}
+	}
+
+	events, err := f.Events(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Sequence != 2 ||
+		events[1].Sequence != 3 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	events, err = f.Events(5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	_, err = f.Events(6, 10)
+	if err != ErrInvalidSequence {
+		t.Fatalf("got %v, want %v", err, ErrInvalidSequence)
+	}
+
+	// Simulate a crash in the middle of writing an event and resume
+	f.Close()
+	fd, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fd.WriteString(`{"sequence":6,"ty`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	f, err = New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Last() != 5 {
+		t.Fatalf("last: got %v, want 5", f.Last())
+	}
+	e, err := f.Append(Event{Type: EventPluginCommand, Plugin: "decred"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Sequence != 6 {
+		t.Fatalf("sequence: got %v, want 6", e.Sequence)
+	}
+	events, err = f.Events(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 6 || events[5].Plugin != "decred" {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
+
+func TestWait(t *testing.T) {
+	f, _, cleanup := newTestFeed(t)
+	defer cleanup()
+
+	// Times out without events
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	events, err := f.Wait(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	// Wakes up when an event is appended
+	done := make(chan []Event)
+	go func() {
+		events, err := f.Wait(context.Background(), 0, 10)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- events
+	}()
+	_, err = f.Append(Event{Type: EventSetStatus, Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case events := <-done:
+		if len(events) != 1 || events[0].Type != EventSetStatus {
+			t.Fatalf("unexpected events: %v", events)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not woken up")
+	}
+}
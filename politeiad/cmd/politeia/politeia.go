@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -55,6 +56,8 @@ var (
 	interactive = flag.String("interactive", "", "Set to "+
 		allowInteractive+" to to turn off interactive mode during "+
 		"identity fetch")
+	clientid = flag.String("clientid", "", "Client identity file "+
+		"used to sign privileged calls instead of rpcuser and rpcpass")
 
 	verify = false // Validate server TLS certificate
 )
@@ -74,6 +77,8 @@ func usage() {
 		"[metadata<id>]... <filename>...\n")
 	fmt.Fprintf(os.Stderr, "  getunvetted       - Retrieve record "+
 		"<id>\n")
+	fmt.Fprintf(os.Stderr, "  getbundle         - Export vetted record "+
+		"bundle <id> <filename>\n")
 	fmt.Fprintf(os.Stderr, "  setunvettedstatus - Set unvetted record "+
 		"status <publish|censor> <id> [actionmdid:metadata]...\n")
 	fmt.Fprintf(os.Stderr, "  updateunvetted    - Update unvetted record "+
@@ -84,6 +89,16 @@ func usage() {
 		"token:<token>\n")
 	fmt.Fprintf(os.Stderr, "  updatevettedmd    - Update vetted record "+
 		"metadata [actionmdid:metadata]... token:<token>\n")
+	fmt.Fprintf(os.Stderr, "  verifycache       - Compare the cache "+
+		"against the backend\n")
+	fmt.Fprintf(os.Stderr, "  repaircache       - Repair the divergent "+
+		"records in the cache\n")
+	fmt.Fprintf(os.Stderr, "  changefeed        - Wait for record "+
+		"changes [since] [timeout]\n")
+	fmt.Fprintf(os.Stderr, "  auditlog          - Retrieve and verify "+
+		"the audit log [since] [filename]\n")
+	fmt.Fprintf(os.Stderr, "  clientidentity    - Create the -clientid "+
+		"identity and print its public key\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, " metadata<id> is the word metadata followed "+
 		"by digits. Example with 2 metadata records "+
@@ -216,7 +231,10 @@ func pluginInventory() (*v1.PluginInventoryReply, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(*rpcuser, *rpcpass)
+	err = setAuth(req, b)
+	if err != nil {
+		return nil, err
+	}
 	r, err := c.Do(req)
 	if err != nil {
 		return nil, err
@@ -288,7 +306,10 @@ func plugin() error {
 	if err != nil {
 		return err
 	}
-	req.SetBasicAuth(*rpcuser, *rpcpass)
+	err = setAuth(req, b)
+	if err != nil {
+		return err
+	}
 	r, err := c.Do(req)
 	if err != nil {
 		return err
@@ -367,7 +388,10 @@ func remoteInventory() (*v1.InventoryReply, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(*rpcuser, *rpcpass)
+	err = setAuth(req, b)
+	if err != nil {
+		return nil, err
+	}
 	r, err := c.Do(req)
 	if err != nil {
 		return nil, err
@@ -427,6 +451,397 @@ func inventory() error {
 	return nil
 }
 
+func verifyCache(repair bool) error {
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v1.VerifyCache{
+		Challenge: hex.EncodeToString(challenge),
+		Repair:    repair,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *printJson {
+		fmt.Println(string(b))
+	}
+
+	c, err := util.NewClient(verify, *rpccert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", *rpchost+v1.VerifyCacheRoute,
+		bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	err = setAuth(req, b)
+	if err != nil {
+		return err
+	}
+	r, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		e, err := getErrorFromResponse(r)
+		if err != nil {
+			return fmt.Errorf("%v", r.Status)
+		}
+		return fmt.Errorf("%v: %v", r.Status, e)
+	}
+
+	bodyBytes := util.ConvertBodyToByteArray(r.Body, *printJson)
+
+	var vcr v1.VerifyCacheReply
+	err = json.Unmarshal(bodyBytes, &vcr)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal VerifyCacheReply: %v",
+			err)
+	}
+
+	// Fetch remote identity
+	id, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	err = util.VerifyChallenge(id, challenge, vcr.Response)
+	if err != nil {
+		return err
+	}
+
+	if !*printJson {
+		fmt.Printf("Records verified: %v\n", vcr.Records)
+		for _, v := range vcr.Divergences {
+			if v.Plugin != "" {
+				fmt.Printf("  %v (plugin %v)\n", v.Token, v.Plugin)
+			} else {
+				fmt.Printf("  %v\n", v.Token)
+			}
+			for _, reason := range v.Reasons {
+				fmt.Printf("    %v\n", reason)
+			}
+		}
+		fmt.Printf("Divergent records: %v\n", len(vcr.Divergences))
+		if vcr.Repaired {
+			fmt.Printf("Divergent records repaired\n")
+		}
+	}
+
+	return nil
+}
+
+// setAuth authenticates a request to a privileged route.  The request is
+// signed with the client identity if one was provided, otherwise the rpcuser
+// and rpcpass credentials are used.
+func setAuth(req *http.Request, body []byte) error {
+	if *clientid == "" {
+		req.SetBasicAuth(*rpcuser, *rpcpass)
+		return nil
+	}
+	id, err := identity.LoadFullIdentity(*clientid)
+	if err != nil {
+		return err
+	}
+	util.SignRequest(req, id, body)
+	return nil
+}
+
+func clientIdentity() error {
+	if *clientid == "" {
+		return fmt.Errorf("must provide the -clientid identity file")
+	}
+
+	// Create the identity unless it already exists
+	id, err := identity.LoadFullIdentity(*clientid)
+	if err != nil {
+		if util.FileExists(*clientid) {
+			return err
+		}
+		id, err = identity.New()
+		if err != nil {
+			return err
+		}
+		err = id.Save(*clientid)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Public key: %v\n", id.Public.String())
+
+	return nil
+}
+
+func changeFeed() error {
+	flags := flag.Args()[1:] // Chop off action.
+
+	// Both the sequence number and the timeout are optional
+	if len(flags) > 2 {
+		return fmt.Errorf("invalid number of arguments")
+	}
+	var (
+		since   uint64
+		timeout uint64
+		err     error
+	)
+	if len(flags) > 0 {
+		since, err = strconv.ParseUint(flags[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sequence number: %v", err)
+		}
+	}
+	if len(flags) > 1 {
+		timeout, err = strconv.ParseUint(flags[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %v", err)
+		}
+	}
+
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v1.ChangeFeed{
+		Challenge: hex.EncodeToString(challenge),
+		Since:     since,
+		Timeout:   uint32(timeout),
+	})
+	if err != nil {
+		return err
+	}
+
+	if *printJson {
+		fmt.Println(string(b))
+	}
+
+	c, err := util.NewClient(verify, *rpccert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", *rpchost+v1.ChangeFeedRoute,
+		bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	err = setAuth(req, b)
+	if err != nil {
+		return err
+	}
+	r, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		e, err := getErrorFromResponse(r)
+		if err != nil {
+			return fmt.Errorf("%v", r.Status)
+		}
+		return fmt.Errorf("%v: %v", r.Status, e)
+	}
+
+	bodyBytes := util.ConvertBodyToByteArray(r.Body, *printJson)
+
+	var cfr v1.ChangeFeedReply
+	err = json.Unmarshal(bodyBytes, &cfr)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal ChangeFeedReply: %v",
+			err)
+	}
+
+	// Fetch remote identity
+	id, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	err = util.VerifyChallenge(id, challenge, cfr.Response)
+	if err != nil {
+		return err
+	}
+
+	if !*printJson {
+		for _, v := range cfr.Events {
+			fmt.Printf("%v %v: %v\n", v.Sequence,
+				time.Unix(v.Timestamp, 0).UTC().Format(time.RFC3339),
+				v1.Change[v.Type])
+			if v.Token != "" {
+				fmt.Printf("  Token  : %v\n", v.Token)
+			}
+			if v.Version != "" {
+				fmt.Printf("  Version: %v\n", v.Version)
+			}
+			if v.Status != v1.RecordStatusInvalid {
+				fmt.Printf("  Status : %v\n",
+					v1.RecordStatus[v.Status])
+			}
+			if v.Plugin != "" {
+				fmt.Printf("  Plugin : %v %v\n", v.Plugin,
+					v.Command)
+			}
+		}
+		fmt.Printf("Last sequence number: %v\n", cfr.Last)
+	}
+
+	return nil
+}
+
+// remoteAuditLog retrieves a page of audit log entries and verifies that they
+// were signed by the server and chain to the provided previous hash.
+func remoteAuditLog(id *identity.PublicIdentity, since uint64, previous string) (*v1.AuditLogReply, error) {
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v1.AuditLog{
+		Challenge: hex.EncodeToString(challenge),
+		Since:     since,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if *printJson {
+		fmt.Println(string(b))
+	}
+
+	c, err := util.NewClient(verify, *rpccert)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", *rpchost+v1.AuditLogRoute,
+		bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	err = setAuth(req, b)
+	if err != nil {
+		return nil, err
+	}
+	r, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		e, err := getErrorFromResponse(r)
+		if err != nil {
+			return nil, fmt.Errorf("%v", r.Status)
+		}
+		return nil, fmt.Errorf("%v: %v", r.Status, e)
+	}
+
+	bodyBytes := util.ConvertBodyToByteArray(r.Body, *printJson)
+
+	var alr v1.AuditLogReply
+	err = json.Unmarshal(bodyBytes, &alr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal AuditLogReply: %v",
+			err)
+	}
+
+	err = util.VerifyChallenge(id, challenge, alr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first page is verified against the hash the server returns for
+	// the entry at since.  Every following page must chain to the page
+	// before it.
+	if previous == "" {
+		previous = alr.Previous
+	}
+	err = v1.VerifyAuditLog(*id, previous, alr.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &alr, nil
+}
+
+func auditLog() error {
+	flags := flag.Args()[1:] // Chop off action.
+
+	// Both the sequence number and the filename are optional
+	if len(flags) > 2 {
+		return fmt.Errorf("invalid number of arguments")
+	}
+	var (
+		since uint64
+		err   error
+	)
+	if len(flags) > 0 {
+		since, err = strconv.ParseUint(flags[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sequence number: %v", err)
+		}
+	}
+
+	// Fetch remote identity
+	id, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Retrieve all entries that follow since
+	var (
+		previous string
+		entries  []v1.AuditEntry
+		last     = since
+	)
+	for {
+		alr, err := remoteAuditLog(id, last, previous)
+		if err != nil {
+			return err
+		}
+		if len(alr.Entries) == 0 {
+			break
+		}
+		entries = append(entries, alr.Entries...)
+		last = alr.Entries[len(alr.Entries)-1].Sequence
+		previous = alr.Entries[len(alr.Entries)-1].Hash
+		if last >= alr.Last {
+			break
+		}
+	}
+
+	// Save the entries for offline verification
+	if len(flags) > 1 {
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(flags[1], b, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !*printJson {
+		for _, v := range entries {
+			fmt.Printf("%v %v: %v %v\n", v.Sequence,
+				time.Unix(v.Timestamp, 0).UTC().Format(time.RFC3339),
+				v.Client, v.Route)
+			if v.Token != "" {
+				fmt.Printf("  Token : %v\n", v.Token)
+			}
+			if v.Status != v1.RecordStatusInvalid {
+				fmt.Printf("  Status: %v\n",
+					v1.RecordStatus[v.Status])
+			}
+			fmt.Printf("  Digest: %v\n", v.Digest)
+		}
+		fmt.Printf("Verified %v audit log entries\n", len(entries))
+	}
+
+	return nil
+}
+
 func getFile(filename string) (*v1.File, *[sha256.Size]byte, error) {
 	var err error
 
@@ -692,7 +1107,10 @@ func updateVettedMetadata() error {
 	if err != nil {
 		return err
 	}
-	req.SetBasicAuth(*rpcuser, *rpcpass)
+	err = setAuth(req, b)
+	if err != nil {
+		return err
+	}
 	r, err := c.Do(req)
 	if err != nil {
 		return err
@@ -982,6 +1400,106 @@ func getUnvetted() error {
 	return nil
 }
 
+func getBundle() error {
+	flags := flag.Args()[1:] // Chop off action.
+
+	// Make sure we have the censorship token and the output file
+	if len(flags) != 2 {
+		return fmt.Errorf("must provide a censorship token and a " +
+			"filename")
+	}
+
+	// Validate censorship token
+	_, err := util.ConvertStringToken(flags[0])
+	if err != nil {
+		return err
+	}
+
+	// Fetch remote identity
+	id, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Create GetBundle command
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	gb := v1.GetBundle{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     flags[0],
+	}
+
+	// Convert to JSON
+	b, err := json.Marshal(gb)
+	if err != nil {
+		return err
+	}
+
+	if *printJson {
+		fmt.Println(string(b))
+	}
+
+	c, err := util.NewClient(verify, *rpccert)
+	if err != nil {
+		return err
+	}
+	r, err := c.Post(*rpchost+v1.GetBundleRoute, "application/json",
+		bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		e, err := getErrorFromResponse(r)
+		if err != nil {
+			return fmt.Errorf("%v", r.Status)
+		}
+		return fmt.Errorf("%v: %v", r.Status, e)
+	}
+
+	bodyBytes := util.ConvertBodyToByteArray(r.Body, *printJson)
+
+	var reply v1.GetBundleReply
+	err = json.Unmarshal(bodyBytes, &reply)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal GetBundleReply: %v",
+			err)
+	}
+
+	// Verify challenge.
+	err = util.VerifyChallenge(id, challenge, reply.Response)
+	if err != nil {
+		return err
+	}
+
+	if reply.Status == v1.RecordStatusNotFound {
+		return fmt.Errorf("record not found: %v", flags[0])
+	}
+
+	// The bundle must be verifiable against the identity that was used
+	// to verify the challenge.
+	if reply.Bundle.ServerPublicKey != hex.EncodeToString(id.Key[:]) {
+		return fmt.Errorf("bundle public key does not match server " +
+			"identity")
+	}
+
+	bb, err := json.MarshalIndent(reply.Bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(flags[1], bb, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bundle of %v written to %v\n", flags[0], flags[1])
+
+	return nil
+}
+
 func getVetted() error {
 	flags := flag.Args()[1:] // Chop off action.
 
@@ -1182,7 +1700,10 @@ func setUnvettedStatus() error {
 	if err != nil {
 		return err
 	}
-	req.SetBasicAuth(*rpcuser, *rpcpass)
+	err = setAuth(req, b)
+	if err != nil {
+		return err
+	}
 	r, err := c.Do(req)
 	if err != nil {
 		return err
@@ -1276,6 +1797,8 @@ func _main() error {
 				return getUnvetted()
 			case "getvetted":
 				return getVetted()
+			case "getbundle":
+				return getBundle()
 			case "setunvettedstatus":
 				return setUnvettedStatus()
 			case "updateunvetted":
@@ -1284,6 +1807,16 @@ func _main() error {
 				return updateRecord(true)
 			case "updatevettedmd":
 				return updateVettedMetadata()
+			case "verifycache":
+				return verifyCache(false)
+			case "repaircache":
+				return verifyCache(true)
+			case "changefeed":
+				return changeFeed()
+			case "auditlog":
+				return auditLog()
+			case "clientidentity":
+				return clientIdentity()
 			default:
 				return fmt.Errorf("invalid action: %v", a)
 			}
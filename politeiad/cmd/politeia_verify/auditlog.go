@@ -0,0 +1,96 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/util"
+)
+
+// auditLogOutput is the JSON output of an audit log verification.
+type auditLogOutput struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	First   uint64 `json:"first"`   // Sequence number of first entry
+	Last    uint64 `json:"last"`    // Sequence number of last entry
+	Entries int    `json:"entries"` // Number of verified entries
+}
+
+// verifyAuditLog verifies that the audit log entries were signed by the
+// server and form an unbroken chain.  A log that starts at the first entry
+// must not refer to a previous entry, otherwise the first entry is trusted
+// to refer to the entry before it.
+func verifyAuditLog(entries []v1.AuditEntry) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("audit log does not contain any entries")
+	}
+	pid, err := util.IdentityFromString(*publicKeyFlag)
+	if err != nil {
+		return err
+	}
+
+	var previous string
+	if entries[0].Sequence > 1 {
+		previous = entries[0].Previous
+	}
+	return v1.VerifyAuditLog(*pid, previous, entries)
+}
+
+func _auditLog() error {
+	payload, err := ioutil.ReadFile(*auditLogFlag)
+	if err != nil {
+		return err
+	}
+
+	var entries []v1.AuditEntry
+	err = json.Unmarshal(payload, &entries)
+	if err != nil {
+		return err
+	}
+
+	verr := verifyAuditLog(entries)
+	if *jsonOutFlag {
+		o := auditLogOutput{
+			Success: verr == nil,
+			Entries: len(entries),
+		}
+		if len(entries) > 0 {
+			o.First = entries[0].Sequence
+			o.Last = entries[len(entries)-1].Sequence
+		}
+		if verr != nil {
+			o.Error = verr.Error()
+		}
+		bytes, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(bytes))
+		return nil
+	}
+
+	if verr != nil {
+		return fmt.Errorf("Audit log failed verification: %v", verr)
+	}
+
+	fmt.Println("Audit log successfully verified")
+	if *verboseFlag {
+		first := entries[0]
+		last := entries[len(entries)-1]
+		fmt.Printf("  Entries: %v through %v\n", first.Sequence,
+			last.Sequence)
+		fmt.Printf("  First  : %v\n", time.Unix(first.Timestamp, 0).UTC())
+		fmt.Printf("  Last   : %v\n", time.Unix(last.Timestamp, 0).UTC())
+		fmt.Printf("  Hash   : %v\n", last.Hash)
+	}
+
+	return nil
+}
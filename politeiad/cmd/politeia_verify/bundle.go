@@ -0,0 +1,376 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/mdstream"
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/util"
+)
+
+// bundleSummary describes what was verified in a bundle.
+type bundleSummary struct {
+	Token                   string   `json:"token"`
+	Versions                int      `json:"versions"`
+	StatusChanges           int      `json:"statuschanges"`
+	UnverifiedStatusChanges int      `json:"unverifiedstatuschanges"`
+	Comments                int      `json:"comments"`
+	CensoredComments        int      `json:"censoredcomments"`
+	CastVotes               int      `json:"castvotes"`
+	VoteAuthorized          bool     `json:"voteauthorized"`
+	VoteStarted             bool     `json:"votestarted"`
+	AnchoredVersions        int      `json:"anchoredversions"`
+	UnknownPlugins          []string `json:"unknownplugins,omitempty"`
+}
+
+// bundleOutput is the JSON output of a bundle verification.
+type bundleOutput struct {
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+	Summary *bundleSummary `json:"summary,omitempty"`
+}
+
+// verifyReceipt verifies that receipt is the server signature of signature.
+func verifyReceipt(pid identity.PublicIdentity, signature, receipt string) error {
+	r, err := util.ConvertSignature(receipt)
+	if err != nil {
+		return err
+	}
+	if !pid.VerifyMessage([]byte(signature), r) {
+		return fmt.Errorf("invalid receipt")
+	}
+	return nil
+}
+
+// verifyClientSignature verifies that signature is the signature of msg by
+// the hex encoded public key.
+func verifyClientSignature(publicKey, msg, signature string) error {
+	sig, err := util.ConvertSignature(signature)
+	if err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return err
+	}
+	pk, err := identity.PublicIdentityFromBytes(b)
+	if err != nil {
+		return err
+	}
+	if !pk.VerifyMessage([]byte(msg), sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// metadataStream returns the payload of the metadata stream with the provided
+// id.  An empty string is returned if the record does not have the stream.
+func metadataStream(r v1.Record, id uint64) string {
+	for _, v := range r.Metadata {
+		if v.ID == id {
+			return v.Payload
+		}
+	}
+	return ""
+}
+
+// verifyRecords verifies the censorship records of all record versions and
+// the status change signatures of the latest version.
+func verifyRecords(pid identity.PublicIdentity, records []v1.Record, s *bundleSummary) error {
+	if len(records) == 0 {
+		return fmt.Errorf("bundle does not contain any records")
+	}
+
+	s.Token = records[0].CensorshipRecord.Token
+	for i, r := range records {
+		version := strconv.Itoa(i + 1)
+		if r.Version != version {
+			return fmt.Errorf("record version %v out of order, want %v",
+				r.Version, version)
+		}
+		if r.CensorshipRecord.Token != s.Token {
+			return fmt.Errorf("record version %v: token %v does not "+
+				"match %v", r.Version, r.CensorshipRecord.Token,
+				s.Token)
+		}
+		err := v1.Verify(pid, r.CensorshipRecord, r.Files)
+		if err != nil {
+			return fmt.Errorf("record version %v: %v", r.Version, err)
+		}
+		s.Versions++
+	}
+
+	// Status changes are appended to the metadata stream, the latest
+	// version therefore contains all of them.  Version 1 status changes
+	// were not signed and can't be verified.
+	latest := records[len(records)-1]
+	payload := metadataStream(latest, mdstream.IDRecordStatusChange)
+	if payload == "" {
+		return nil
+	}
+	rscV1, rscV2, err := mdstream.DecodeRecordStatusChanges([]byte(payload))
+	if err != nil {
+		return fmt.Errorf("DecodeRecordStatusChanges: %v", err)
+	}
+	for _, v := range rscV2 {
+		err := v.VerifySignature(s.Token)
+		if err != nil {
+			return fmt.Errorf("status change to %v: %v",
+				v1.RecordStatus[v.NewStatus], err)
+		}
+		s.StatusChanges++
+	}
+	s.UnverifiedStatusChanges = len(rscV1)
+
+	return nil
+}
+
+// verifyVoteMetadata verifies the vote authorization and the start vote
+// metadata streams and returns the eligible tickets of the vote.
+func verifyVoteMetadata(pid identity.PublicIdentity, r v1.Record, s *bundleSummary) (map[string]struct{}, error) {
+	payload := metadataStream(r, decredplugin.MDStreamAuthorizeVote)
+	if payload != "" {
+		av, err := decredplugin.DecodeAuthorizeVote([]byte(payload))
+		if err != nil {
+			return nil, fmt.Errorf("DecodeAuthorizeVote: %v", err)
+		}
+		err = verifyReceipt(pid, av.Signature, av.Receipt)
+		if err != nil {
+			return nil, fmt.Errorf("authorize vote: %v", err)
+		}
+		s.VoteAuthorized = true
+	}
+
+	payload = metadataStream(r, decredplugin.MDStreamVoteBits)
+	if payload != "" {
+		sv, err := decredplugin.DecodeStartVote([]byte(payload))
+		if err != nil {
+			return nil, fmt.Errorf("DecodeStartVote: %v", err)
+		}
+		switch sv.Version {
+		case decredplugin.VersionStartVoteV1:
+			sv1, err := decredplugin.DecodeStartVoteV1([]byte(sv.Payload))
+			if err != nil {
+				return nil, err
+			}
+			err = sv1.VerifySignature()
+			if err != nil {
+				return nil, fmt.Errorf("start vote: %v", err)
+			}
+		case decredplugin.VersionStartVoteV2:
+			sv2, err := decredplugin.DecodeStartVoteV2([]byte(sv.Payload))
+			if err != nil {
+				return nil, err
+			}
+			err = sv2.VerifySignature()
+			if err != nil {
+				return nil, fmt.Errorf("start vote: %v", err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid start vote version %v",
+				sv.Version)
+		}
+		s.VoteStarted = true
+	}
+
+	eligible := make(map[string]struct{})
+	payload = metadataStream(r, decredplugin.MDStreamVoteSnapshot)
+	if payload != "" {
+		svr, err := decredplugin.DecodeStartVoteReply([]byte(payload))
+		if err != nil {
+			return nil, fmt.Errorf("DecodeStartVoteReply: %v", err)
+		}
+		for _, v := range svr.EligibleTickets {
+			eligible[v] = struct{}{}
+		}
+	}
+
+	return eligible, nil
+}
+
+// verifyDecredExport verifies the comments and cast votes that the decred
+// plugin exported.
+func verifyDecredExport(pid identity.PublicIdentity, latest v1.Record, payload string, s *bundleSummary) error {
+	er, err := decredplugin.DecodeExportReply([]byte(payload))
+	if err != nil {
+		return fmt.Errorf("DecodeExportReply: %v", err)
+	}
+
+	for _, c := range er.Comments {
+		if c.Token != s.Token {
+			return fmt.Errorf("comment %v: token %v does not match %v",
+				c.CommentID, c.Token, s.Token)
+		}
+		err := verifyReceipt(pid, c.Signature, c.Receipt)
+		if err != nil {
+			return fmt.Errorf("comment %v: %v", c.CommentID, err)
+		}
+		s.Comments++
+
+		// The text of censored comments has been removed so the
+		// client signature can no longer be verified.
+		if c.Censored {
+			s.CensoredComments++
+			continue
+		}
+
+		// The server stores an empty parent ID as "0" but the client
+		// signed what it sent.
+		err = verifyClientSignature(c.PublicKey,
+			c.Token+c.ParentID+c.Comment, c.Signature)
+		if err != nil && c.ParentID == "0" {
+			err = verifyClientSignature(c.PublicKey,
+				c.Token+c.Comment, c.Signature)
+		}
+		if err != nil {
+			return fmt.Errorf("comment %v: %v", c.CommentID, err)
+		}
+	}
+
+	eligible, err := verifyVoteMetadata(pid, latest, s)
+	if err != nil {
+		return err
+	}
+	if len(er.CastVotes) > 0 && !s.VoteStarted {
+		return fmt.Errorf("bundle contains votes but the vote was " +
+			"never started")
+	}
+	for _, v := range er.CastVotes {
+		if v.CastVote.Token != s.Token {
+			return fmt.Errorf("ticket %v: token %v does not match %v",
+				v.CastVote.Ticket, v.CastVote.Token, s.Token)
+		}
+		if _, ok := eligible[v.CastVote.Ticket]; !ok {
+			return fmt.Errorf("ticket %v: not eligible to vote",
+				v.CastVote.Ticket)
+		}
+		err := verifyReceipt(pid, v.CastVote.Signature, v.Receipt)
+		if err != nil {
+			return fmt.Errorf("ticket %v: %v", v.CastVote.Ticket, err)
+		}
+		s.CastVotes++
+	}
+
+	return nil
+}
+
+// verifyBundle verifies all signatures, receipts and anchor proofs in a
+// record bundle.  The bundle must have been signed by pid.
+func verifyBundle(pid identity.PublicIdentity, b v1.Bundle) (*bundleSummary, error) {
+	var s bundleSummary
+
+	err := verifyRecords(pid, b.Records, &s)
+	if err != nil {
+		return nil, err
+	}
+	latest := b.Records[len(b.Records)-1]
+
+	for _, v := range b.Plugins {
+		switch v.ID {
+		case decredplugin.ID:
+			err := verifyDecredExport(pid, latest, v.Payload, &s)
+			if err != nil {
+				return nil, fmt.Errorf("plugin %v: %v", v.ID, err)
+			}
+		default:
+			s.UnknownPlugins = append(s.UnknownPlugins, v.ID)
+		}
+	}
+
+	for _, v := range b.Timestamps {
+		version, err := strconv.Atoi(v.Version)
+		if err != nil || version < 1 || version > len(b.Records) {
+			return nil, fmt.Errorf("timestamp of unknown version %v",
+				v.Version)
+		}
+		err = v1.VerifyRecordTimestamp(v)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp of version %v: %v",
+				v.Version, err)
+		}
+		if v.Status == v1.TimestampStatusConfirmed {
+			s.AnchoredVersions++
+		}
+	}
+
+	return &s, nil
+}
+
+func _bundle() error {
+	payload, err := ioutil.ReadFile(*bundleFlag)
+	if err != nil {
+		return err
+	}
+
+	var b v1.Bundle
+	err = json.Unmarshal(payload, &b)
+	if err != nil {
+		return err
+	}
+
+	// The bundle carries the server key.  If a key was provided on the
+	// command line the bundle must have been signed with it.
+	if *publicKeyFlag != "" && *publicKeyFlag != b.ServerPublicKey {
+		return fmt.Errorf("bundle public key %v does not match %v",
+			b.ServerPublicKey, *publicKeyFlag)
+	}
+	key, err := hex.DecodeString(b.ServerPublicKey)
+	if err != nil {
+		return err
+	}
+	pid, err := identity.PublicIdentityFromBytes(key)
+	if err != nil {
+		return err
+	}
+
+	s, verr := verifyBundle(*pid, b)
+	if *jsonOutFlag {
+		o := bundleOutput{
+			Success: verr == nil,
+			Summary: s,
+		}
+		if verr != nil {
+			o.Error = verr.Error()
+		}
+		bytes, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(bytes))
+		return nil
+	}
+
+	if verr != nil {
+		return fmt.Errorf("Bundle failed verification: %v", verr)
+	}
+
+	fmt.Println("Bundle successfully verified")
+	if *verboseFlag {
+		fmt.Printf("  Token          : %v\n", s.Token)
+		fmt.Printf("  Versions       : %v\n", s.Versions)
+		fmt.Printf("  Anchored       : %v\n", s.AnchoredVersions)
+		fmt.Printf("  Status changes : %v (%v unsigned)\n",
+			s.StatusChanges, s.UnverifiedStatusChanges)
+		fmt.Printf("  Comments       : %v (%v censored)\n", s.Comments,
+			s.CensoredComments)
+		fmt.Printf("  Vote authorized: %v\n", s.VoteAuthorized)
+		fmt.Printf("  Vote started   : %v\n", s.VoteStarted)
+		fmt.Printf("  Cast votes     : %v\n", s.CastVotes)
+		for _, v := range s.UnknownPlugins {
+			fmt.Printf("  Not verified   : plugin %v\n", v)
+		}
+	}
+
+	return nil
+}
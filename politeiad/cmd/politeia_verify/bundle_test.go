@@ -0,0 +1,431 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/mdstream"
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/util"
+)
+
+// testBundle holds the identities that signed a test bundle.
+type testBundle struct {
+	server *identity.FullIdentity // politeiad
+	admin  *identity.FullIdentity // Changes the record status
+	user   *identity.FullIdentity // Comments and starts the vote
+	bundle v1.Bundle
+}
+
+func newTestIdentity(t *testing.T) *identity.FullIdentity {
+	t.Helper()
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// sign returns the hex encoded signature of msg.
+func sign(id *identity.FullIdentity, msg string) string {
+	s := id.SignMessage([]byte(msg))
+	return hex.EncodeToString(s[:])
+}
+
+// newRecordVersion returns a record version with a single file and a
+// censorship record that is signed by the server.
+func (tb *testBundle) newRecordVersion(token, version string, md []v1.MetadataStream) v1.Record {
+	payload := []byte("version " + version)
+	d := sha256.Sum256(payload)
+	root := merkle.Root([]*[sha256.Size]byte{&d})
+	m := hex.EncodeToString(root[:])
+	return v1.Record{
+		Status:   v1.RecordStatusPublic,
+		Version:  version,
+		Metadata: md,
+		CensorshipRecord: v1.CensorshipRecord{
+			Token:     token,
+			Merkle:    m,
+			Signature: sign(tb.server, m+token),
+		},
+		Files: []v1.File{{
+			Name:    "index.md",
+			MIME:    "text/plain; charset=utf-8",
+			Digest:  hex.EncodeToString(d[:]),
+			Payload: base64.StdEncoding.EncodeToString(payload),
+		}},
+	}
+}
+
+// newTimestamp returns an anchor proof of the provided record version.  The
+// commit digest is anchored along with another digest and the anchor is
+// confirmed in a dcrtime merkle tree.
+func newTimestamp(t *testing.T, version string) v1.RecordTimestamp {
+	t.Helper()
+
+	digest := sha256.Sum256([]byte("commit " + version))
+	other := sha256.Sum256([]byte("other commit"))
+	leaves := []*[sha256.Size]byte{&digest, &other}
+	anchor := merkle.Root(leaves)
+
+	otherAnchor := sha256.Sum256([]byte("other anchor"))
+	chainLeaves := []*[sha256.Size]byte{anchor, &otherAnchor}
+	chainRoot := merkle.Root(chainLeaves)
+
+	return v1.RecordTimestamp{
+		Version:      version,
+		Digest:       hex.EncodeToString(digest[:]),
+		Status:       v1.TimestampStatusConfirmed,
+		AnchorMerkle: hex.EncodeToString(anchor[:]),
+		MerklePath:   merkle.AuthPath(leaves, &digest),
+		ChainInformation: &v1.ChainInformation{
+			MerkleRoot: hex.EncodeToString(chainRoot[:]),
+			MerklePath: *merkle.AuthPath(chainLeaves, anchor),
+		},
+	}
+}
+
+// newTestBundle builds the bundle of a proposal with two versions, a signed
+// status change, comments, an authorized and started vote with cast votes and
+// anchor proofs of both versions.
+func newTestBundle(t *testing.T) *testBundle {
+	t.Helper()
+
+	tb := testBundle{
+		server: newTestIdentity(t),
+		admin:  newTestIdentity(t),
+		user:   newTestIdentity(t),
+	}
+	token := strings.Repeat("a", 64)
+	userKey := hex.EncodeToString(tb.user.Public.Key[:])
+
+	// Status change
+	msg := "looks good"
+	rsc, err := mdstream.EncodeRecordStatusChangeV2(
+		mdstream.RecordStatusChangeV2{
+			Version:             mdstream.VersionRecordStatusChange,
+			NewStatus:           v1.RecordStatusPublic,
+			StatusChangeMessage: msg,
+			Signature: sign(tb.admin, token+
+				strconv.Itoa(int(v1.RecordStatusPublic))+msg),
+			AdminPubKey: hex.EncodeToString(tb.admin.Public.Key[:]),
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Vote authorization, parameters and ticket snapshot
+	avSig := sign(tb.user, token+"2"+decredplugin.AuthVoteActionAuthorize)
+	av, err := decredplugin.EncodeAuthorizeVote(decredplugin.AuthorizeVote{
+		Version:   decredplugin.VersionAuthorizeVote,
+		Receipt:   sign(tb.server, avSig),
+		Action:    decredplugin.AuthVoteActionAuthorize,
+		Token:     token,
+		Signature: avSig,
+		PublicKey: userKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote := decredplugin.VoteV2{
+		Token:            token,
+		ProposalVersion:  2,
+		Type:             decredplugin.VoteTypeStandard,
+		Mask:             0x03,
+		QuorumPercentage: 20,
+		PassPercentage:   60,
+		Options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x01},
+			{Id: "yes", Bits: 0x02},
+		},
+	}
+	vb, err := json.Marshal(vote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := decredplugin.EncodeStartVoteV2(decredplugin.StartVoteV2{
+		Version:   decredplugin.VersionStartVoteV2,
+		PublicKey: userKey,
+		Vote:      vote,
+		Signature: sign(tb.user, hex.EncodeToString(util.Digest(vb))),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr, err := decredplugin.EncodeStartVoteReply(decredplugin.StartVoteReply{
+		Version:         decredplugin.VersionStartVoteReply,
+		EligibleTickets: []string{"ticket1", "ticket2", "ticket3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tb.bundle = v1.Bundle{
+		ServerPublicKey: hex.EncodeToString(tb.server.Public.Key[:]),
+		Records: []v1.Record{
+			tb.newRecordVersion(token, "1", nil),
+			tb.newRecordVersion(token, "2", []v1.MetadataStream{
+				{ID: mdstream.IDRecordStatusChange, Payload: string(rsc)},
+				{ID: decredplugin.MDStreamAuthorizeVote, Payload: string(av)},
+				{ID: decredplugin.MDStreamVoteBits, Payload: string(sv)},
+				{ID: decredplugin.MDStreamVoteSnapshot, Payload: string(svr)},
+			}),
+		},
+		Timestamps: []v1.RecordTimestamp{
+			newTimestamp(t, "1"),
+			newTimestamp(t, "2"),
+		},
+	}
+
+	// Comments and cast votes
+	comment := func(id, parentID, text string, censored bool) decredplugin.Comment {
+		s := sign(tb.user, token+parentID+text)
+		c := decredplugin.Comment{
+			Token:     token,
+			ParentID:  parentID,
+			Comment:   text,
+			Signature: s,
+			PublicKey: userKey,
+			CommentID: id,
+			Receipt:   sign(tb.server, s),
+			Censored:  censored,
+		}
+		if censored {
+			c.Comment = ""
+		}
+		return c
+	}
+	// The server stores the parent ID of top level comments as "0"
+	topLevel := comment("1", "", "first", false)
+	topLevel.ParentID = "0"
+	castVote := func(ticket, bit string) decredplugin.CastVoteDetails {
+		cv := decredplugin.CastVote{
+			Token:     token,
+			Ticket:    ticket,
+			VoteBit:   bit,
+			Signature: hex.EncodeToString([]byte(ticket + bit)),
+		}
+		return decredplugin.CastVoteDetails{
+			CastVote: cv,
+			Receipt:  sign(tb.server, cv.Signature),
+		}
+	}
+	tb.setExport(t, decredplugin.ExportReply{
+		Comments: []decredplugin.Comment{
+			topLevel,
+			comment("2", "1", "reply", false),
+			comment("3", "1", "spam", true),
+		},
+		CastVotes: []decredplugin.CastVoteDetails{
+			castVote("ticket1", "2"),
+			castVote("ticket2", "1"),
+		},
+	})
+
+	return &tb
+}
+
+// export returns the decred plugin export of the bundle.
+func (tb *testBundle) export(t *testing.T) decredplugin.ExportReply {
+	t.Helper()
+
+	for _, v := range tb.bundle.Plugins {
+		if v.ID != decredplugin.ID {
+			continue
+		}
+		er, err := decredplugin.DecodeExportReply([]byte(v.Payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *er
+	}
+	t.Fatalf("bundle does not contain a decred plugin export")
+	return decredplugin.ExportReply{}
+}
+
+// setExport replaces the decred plugin export of the bundle.
+func (tb *testBundle) setExport(t *testing.T, er decredplugin.ExportReply) {
+	t.Helper()
+
+	payload, err := decredplugin.EncodeExportReply(er)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb.bundle.Plugins = []v1.PluginExport{{
+		ID:      decredplugin.ID,
+		Payload: string(payload),
+	}}
+}
+
+// setMetadata replaces a metadata stream of the latest record version.
+func (tb *testBundle) setMetadata(id uint64, payload string) {
+	latest := &tb.bundle.Records[len(tb.bundle.Records)-1]
+	for k, v := range latest.Metadata {
+		if v.ID == id {
+			latest.Metadata[k].Payload = payload
+		}
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	tb := newTestBundle(t)
+	pid := tb.server.Public
+
+	s, err := verifyBundle(pid, tb.bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bundleSummary{
+		Token:            strings.Repeat("a", 64),
+		Versions:         2,
+		StatusChanges:    1,
+		Comments:         3,
+		CensoredComments: 1,
+		CastVotes:        2,
+		VoteAuthorized:   true,
+		VoteStarted:      true,
+		AnchoredVersions: 2,
+	}
+	if s.Token != want.Token || s.Versions != want.Versions ||
+		s.StatusChanges != want.StatusChanges ||
+		s.UnverifiedStatusChanges != want.UnverifiedStatusChanges ||
+		s.Comments != want.Comments ||
+		s.CensoredComments != want.CensoredComments ||
+		s.CastVotes != want.CastVotes ||
+		s.VoteAuthorized != want.VoteAuthorized ||
+		s.VoteStarted != want.VoteStarted ||
+		s.AnchoredVersions != want.AnchoredVersions ||
+		len(s.UnknownPlugins) != 0 {
+		t.Fatalf("got summary %+v, want %+v", *s, want)
+	}
+
+	// A bundle survives the round trip through its JSON encoding
+	b, err := json.Marshal(tb.bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded v1.Bundle
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifyBundle(pid, decoded)
+	if err != nil {
+		t.Fatalf("decoded bundle: %v", err)
+	}
+}
+
+func TestVerifyBundleTampered(t *testing.T) {
+	var tests = []struct {
+		name   string
+		tamper func(t *testing.T, tb *testBundle)
+		want   string // Expected error prefix
+	}{
+		{"file payload", func(t *testing.T, tb *testBundle) {
+			tb.bundle.Records[0].Files[0].Payload =
+				base64.StdEncoding.EncodeToString([]byte("forged"))
+		}, "record version 1"},
+
+		{"censorship signature", func(t *testing.T, tb *testBundle) {
+			cr := &tb.bundle.Records[1].CensorshipRecord
+			cr.Signature = sign(tb.admin, cr.Merkle+cr.Token)
+		}, "record version 2"},
+
+		{"missing version", func(t *testing.T, tb *testBundle) {
+			tb.bundle.Records = tb.bundle.Records[1:]
+		}, "record version 2 out of order"},
+
+		{"status change message", func(t *testing.T, tb *testBundle) {
+			latest := tb.bundle.Records[1]
+			payload := metadataStream(latest, mdstream.IDRecordStatusChange)
+			tb.setMetadata(mdstream.IDRecordStatusChange,
+				strings.Replace(payload, "looks good", "spam", 1))
+		}, "status change"},
+
+		{"start vote parameters", func(t *testing.T, tb *testBundle) {
+			latest := tb.bundle.Records[1]
+			payload := metadataStream(latest, decredplugin.MDStreamVoteBits)
+			tb.setMetadata(decredplugin.MDStreamVoteBits,
+				strings.Replace(payload, `"passpercentage":60`,
+					`"passpercentage":10`, 1))
+		}, "plugin decred: start vote"},
+
+		{"comment text", func(t *testing.T, tb *testBundle) {
+			er := tb.export(t)
+			er.Comments[1].Comment = "forged"
+			tb.setExport(t, er)
+		}, "plugin decred: comment 2"},
+
+		{"comment receipt", func(t *testing.T, tb *testBundle) {
+			er := tb.export(t)
+			er.Comments[0].Receipt = er.Comments[1].Receipt
+			tb.setExport(t, er)
+		}, "plugin decred: comment 1"},
+
+		{"vote signature", func(t *testing.T, tb *testBundle) {
+			er := tb.export(t)
+			er.CastVotes[0].CastVote.Signature =
+				er.CastVotes[1].CastVote.Signature
+			tb.setExport(t, er)
+		}, "plugin decred: ticket ticket1"},
+
+		{"ineligible ticket", func(t *testing.T, tb *testBundle) {
+			er := tb.export(t)
+			er.CastVotes[1].CastVote.Ticket = "ticket4"
+			tb.setExport(t, er)
+		}, "plugin decred: ticket ticket4: not eligible"},
+
+		{"vote for another record", func(t *testing.T, tb *testBundle) {
+			er := tb.export(t)
+			er.CastVotes[0].CastVote.Token = strings.Repeat("b", 64)
+			tb.setExport(t, er)
+		}, "plugin decred: ticket ticket1: token"},
+
+		{"votes without a started vote", func(t *testing.T, tb *testBundle) {
+			tb.setMetadata(decredplugin.MDStreamVoteBits, "")
+		}, "plugin decred: bundle contains votes"},
+
+		{"anchor proof", func(t *testing.T, tb *testBundle) {
+			other := sha256.Sum256([]byte("forged commit"))
+			tb.bundle.Timestamps[1].Digest = hex.EncodeToString(other[:])
+		}, "timestamp of version 2"},
+
+		{"timestamp of unknown version", func(t *testing.T, tb *testBundle) {
+			tb.bundle.Timestamps[1].Version = "3"
+		}, "timestamp of unknown version"},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			tb := newTestBundle(t)
+			v.tamper(t, tb)
+
+			_, err := verifyBundle(tb.server.Public, tb.bundle)
+			if err == nil {
+				t.Fatalf("got valid, want error %q", v.want)
+			}
+			if !strings.HasPrefix(err.Error(), v.want) {
+				t.Fatalf("got error %q, want %q", err, v.want)
+			}
+		})
+	}
+
+	// A bundle that was signed by another server is rejected
+	tb := newTestBundle(t)
+	_, err := verifyBundle(newTestIdentity(t).Public, tb.bundle)
+	if err == nil {
+		t.Fatalf("bundle verified against another server key")
+	}
+}
@@ -12,9 +12,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/agl/ed25519"
 	"github.com/decred/dcrtime/merkle"
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
 )
 
 var (
@@ -22,6 +25,9 @@ var (
 	tokenFlag     = flag.String("t", "", "record censorship token")
 	signatureFlag = flag.String("s", "", "record censorship signature")
 	jsonInFlag    = flag.String("jsonin", "", "JSON record file")
+	tombstoneFlag = flag.String("tombstone", "", "JSON tombstone file")
+	bundleFlag    = flag.String("bundle", "", "JSON record bundle file")
+	auditLogFlag  = flag.String("auditlog", "", "JSON audit log file")
 	jsonOutFlag   = flag.Bool("jsonout", false, "return output as JSON")
 	verboseFlag   = flag.Bool("v", false, "verbose output")
 )
@@ -31,6 +37,13 @@ type record struct {
 	ServerPublicKey  string           `json:"serverPubkey"`
 }
 
+// tombstone is the tombstone of a purged record as returned by politeiad
+// along with the server public key.
+type tombstone struct {
+	v1.Tombstone
+	ServerPublicKey string `json:"serverPubkey"`
+}
+
 type censorshipRecord struct {
 	Token     string `json:"token"`
 	Merkle    string `json:"merkle"`
@@ -54,6 +67,19 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "  -jsonin <filename> - A path to a JSON file which "+
 		"represents the record. If this option is set, the other input "+
 		"options (-k, -t, -s) should not be provided.\n")
+	fmt.Fprintf(os.Stderr, "  -tombstone <filename> - A path to a JSON file "+
+		"which represents the tombstone of a purged record. If this "+
+		"option is set, the other input options (-k, -t, -s, -jsonin) "+
+		"should not be provided.\n")
+	fmt.Fprintf(os.Stderr, "  -bundle <filename> - A path to a JSON file "+
+		"which represents a record bundle. If this option is set, "+
+		"-k may be provided to require a specific server key and the "+
+		"other input options (-t, -s, -jsonin, -tombstone) should not "+
+		"be provided.\n")
+	fmt.Fprintf(os.Stderr, "  -auditlog <filename> - A path to a JSON file "+
+		"which represents politeiad audit log entries. If this option "+
+		"is set, -k must be provided and the other input options (-t, "+
+		"-s, -jsonin, -tombstone, -bundle) should not be provided.\n")
 	fmt.Fprintf(os.Stderr, "  -jsonout           - JSON output\n")
 	fmt.Fprintf(os.Stderr, "\n")
 }
@@ -91,8 +117,112 @@ func verifyRecord(key [ed25519.PublicKeySize]byte, merkle, token string, signatu
 	return ed25519.Verify(&key, []byte(merkle+token), &signature)
 }
 
+// verifyTombstone verifies that the tombstone was issued by the server and
+// that it describes the files of the purged record.
+func verifyTombstone(t tombstone) (bool, error) {
+	key, err := hex.DecodeString(t.ServerPublicKey)
+	if err != nil {
+		return false, err
+	}
+	pid, err := identity.PublicIdentityFromBytes(key)
+	if err != nil {
+		return false, err
+	}
+
+	err = v1.VerifyTombstone(*pid, t.Tombstone)
+	switch err {
+	case nil:
+		return true, nil
+	case v1.ErrInvalidMerkle, v1.ErrCorrupt:
+		return false, nil
+	}
+	return false, err
+}
+
+func _tombstone() error {
+	payload, err := ioutil.ReadFile(*tombstoneFlag)
+	if err != nil {
+		return err
+	}
+
+	var t tombstone
+	err = json.Unmarshal(payload, &t)
+	if err != nil {
+		return err
+	}
+
+	verified, err := verifyTombstone(t)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutFlag {
+		bytes, err := json.Marshal(output{
+			Success: verified,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(bytes))
+		return nil
+	}
+
+	if !verified {
+		if *verboseFlag {
+			return fmt.Errorf("Tombstone failed verification. Please "+
+				"ensure the public key is correct.\n"+
+				"  Token : %v\n"+
+				"  Merkle: %v", t.CensorshipRecord.Token,
+				t.CensorshipRecord.Merkle)
+		}
+
+		return fmt.Errorf("Tombstone failed verification")
+	}
+
+	fmt.Println("Tombstone successfully verified")
+	if *verboseFlag {
+		fmt.Printf("  Token : %v\n", t.CensorshipRecord.Token)
+		fmt.Printf("  Merkle: %v\n", t.CensorshipRecord.Merkle)
+		fmt.Printf("  Purged: %v\n", time.Unix(t.Timestamp, 0).UTC())
+		fmt.Printf("  Reason: %v\n", t.Reason)
+		for _, v := range t.Files {
+			fmt.Printf("  File  : %v %v\n", v.Digest, v.Name)
+		}
+	}
+
+	return nil
+}
+
 func _main() error {
 	flag.Parse()
+	if *auditLogFlag != "" {
+		if *publicKeyFlag == "" || *tokenFlag != "" ||
+			*signatureFlag != "" || *jsonInFlag != "" ||
+			*tombstoneFlag != "" || *bundleFlag != "" {
+			usage()
+			return fmt.Errorf("must provide -k with -auditlog " +
+				"and none of the other input parameters")
+		}
+		return _auditLog()
+	}
+	if *bundleFlag != "" {
+		if *tokenFlag != "" || *signatureFlag != "" ||
+			*jsonInFlag != "" || *tombstoneFlag != "" {
+			usage()
+			return fmt.Errorf("must only provide either -bundle " +
+				"or the other input parameters")
+		}
+		return _bundle()
+	}
+	if *tombstoneFlag != "" {
+		if *publicKeyFlag != "" || *jsonInFlag != "" {
+			usage()
+			return fmt.Errorf("must only provide either -tombstone " +
+				"or the other input parameters")
+		}
+		return _tombstone()
+	}
 	if (*publicKeyFlag == "" || *tokenFlag == "" || *signatureFlag == "") &&
 		*jsonInFlag == "" {
 		usage()
@@ -0,0 +1,765 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	dcrdataapi "github.com/decred/dcrdata/api/types/v4"
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/backend/gitbe"
+	"github.com/thi4go/politeia/politeiad/cache"
+	"github.com/thi4go/politeia/politeiad/cache/cockroachdb"
+	"github.com/thi4go/politeia/politeiad/sharedconfig"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/util"
+)
+
+const (
+	defaultDataDirname     = sharedconfig.DefaultDataDirname
+	defaultVettedDirname   = gitbe.DefaultVettedPath
+	defaultJournalsDirname = gitbe.DefaultJournalsPath
+	defaultIdentityFile    = "identity.json"
+
+	defaultMainnetDcrdata = "https://dcrdata.decred.org:443"
+	defaultTestnetDcrdata = "https://testnet.decred.org:443"
+
+	// The following mirror the gitbe file layout.
+	ballotFilename      = "ballot.journal"
+	mdFilenameSuffix    = ".metadata.txt"
+	journalActionAdd    = "add"
+	pluginDataDirname   = "plugins"
+	decredPluginDirname = "decred"
+
+	// dcrdataBatchSize is the number of tickets that are looked up in a
+	// single dcrdata request.
+	dcrdataBatchSize = 500
+)
+
+var (
+	defaultHomeDir = sharedconfig.DefaultHomeDir
+
+	// CLI flags
+	homeDir   = flag.String("homedir", defaultHomeDir, "politeiad home dir path")
+	testnet   = flag.Bool("testnet", false, "audit testnet data")
+	dcrdata   = flag.String("dcrdata", "", "dcrdata host used to look up ticket commitment addresses")
+	id        = flag.String("identity", "", "politeiad identity file used to verify vote receipts (default: <homedir>/identity.json)")
+	wwwHost   = flag.String("www", "", "politeiawww API host to compare the results with, e.g. https://proposals.decred.org/api")
+	cacheHost = flag.String("cachehost", "", "cache ip:port to compare the results with")
+	cacheRoot = flag.String("cacherootcert", "", "file containing the CA certificate for the cache")
+	cacheCert = flag.String("cachecert", "", "file containing the client certificate for the cache")
+	cacheKey  = flag.String("cachekey", "", "file containing the client certificate key for the cache")
+	verbose   = flag.Bool("v", false, "print every discrepancy")
+)
+
+// startVote contains the vote parameters that are required to tally a vote.
+type startVote struct {
+	mask     uint64
+	quorum   uint32
+	pass     uint32
+	options  []decredplugin.VoteOption
+	eligible map[string]struct{} // [ticket]struct{}
+}
+
+// result is the outcome of the audit of a single proposal vote.
+type result struct {
+	token         string
+	votes         int               // Votes found in the journal
+	tally         map[string]uint64 // [votebit]valid votes
+	approved      bool
+	discrepancies []string
+}
+
+func (r *result) discrepancy(format string, args ...interface{}) {
+	r.discrepancies = append(r.discrepancies, fmt.Sprintf(format, args...))
+}
+
+// auditor holds the context that is shared between proposal audits.
+type auditor struct {
+	params   *chaincfg.Params
+	vetted   string                   // Vetted repo path
+	journals string                   // Journals path
+	dcrdata  string                   // Dcrdata host
+	identity *identity.PublicIdentity // politeiad identity, optional
+	cache    cache.Cache              // Cache, optional
+	www      string                   // politeiawww host, optional
+}
+
+// latestVersion returns the most recent version of a vetted record.
+func latestVersion(dir string) (string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var latest uint64
+	for _, v := range files {
+		n, err := strconv.ParseUint(v.Name(), 10, 64)
+		if err != nil || !v.IsDir() {
+			continue
+		}
+		if n > latest {
+			latest = n
+		}
+	}
+	if latest == 0 {
+		return "", fmt.Errorf("no versions found: %v", dir)
+	}
+	return strconv.FormatUint(latest, 10), nil
+}
+
+// loadStartVote reads the vote parameters and the ticket snapshot of a
+// proposal from the vetted repo.
+func (a *auditor) loadStartVote(token string) (*startVote, error) {
+	version, err := latestVersion(filepath.Join(a.vetted, token))
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(a.vetted, token, version)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir,
+		fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteBits,
+			mdFilenameSuffix)))
+	if err != nil {
+		return nil, err
+	}
+	sv, err := decredplugin.DecodeStartVote(b)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeStartVote: %v", err)
+	}
+
+	var s startVote
+	switch sv.Version {
+	case decredplugin.VersionStartVoteV1:
+		sv1, err := decredplugin.DecodeStartVoteV1([]byte(sv.Payload))
+		if err != nil {
+			return nil, err
+		}
+		s.mask = sv1.Vote.Mask
+		s.quorum = sv1.Vote.QuorumPercentage
+		s.pass = sv1.Vote.PassPercentage
+		s.options = sv1.Vote.Options
+	case decredplugin.VersionStartVoteV2:
+		sv2, err := decredplugin.DecodeStartVoteV2([]byte(sv.Payload))
+		if err != nil {
+			return nil, err
+		}
+		s.mask = sv2.Vote.Mask
+		s.quorum = sv2.Vote.QuorumPercentage
+		s.pass = sv2.Vote.PassPercentage
+		s.options = sv2.Vote.Options
+	default:
+		return nil, fmt.Errorf("invalid start vote version %v",
+			sv.Version)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir,
+		fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteSnapshot,
+			mdFilenameSuffix)))
+	if err != nil {
+		return nil, err
+	}
+	svr, err := decredplugin.DecodeStartVoteReply(b)
+	if err != nil {
+		return nil, fmt.Errorf("DecodeStartVoteReply: %v", err)
+	}
+	s.eligible = make(map[string]struct{}, len(svr.EligibleTickets))
+	for _, v := range svr.EligibleTickets {
+		s.eligible[v] = struct{}{}
+	}
+
+	return &s, nil
+}
+
+// loadBallot reads all votes from the ballot journal of a proposal.  The
+// journal in the journals directory is preferred since the copy in the
+// vetted repo is only updated when the journals are flushed.
+func (a *auditor) loadBallot(token string) ([]gitbe.CastVoteJournal, error) {
+	filename := filepath.Join(a.journals, token, ballotFilename)
+	if !util.FileExists(filename) {
+		version, err := latestVersion(filepath.Join(a.vetted, token))
+		if err != nil {
+			return nil, err
+		}
+		filename = filepath.Join(a.vetted, token, version,
+			pluginDataDirname, decredPluginDirname, ballotFilename)
+		if !util.FileExists(filename) {
+			return []gitbe.CastVoteJournal{}, nil
+		}
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	votes := make([]gitbe.CastVoteJournal, 0, 1024)
+	s := bufio.NewScanner(f)
+	for i := 1; s.Scan(); i++ {
+		d := json.NewDecoder(bytes.NewReader(s.Bytes()))
+
+		var action gitbe.JournalAction
+		err := d.Decode(&action)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: journal action: %v", i, err)
+		}
+		if action.Action != journalActionAdd {
+			return nil, fmt.Errorf("line %v: invalid action: %v", i,
+				action.Action)
+		}
+
+		var cvj gitbe.CastVoteJournal
+		err = d.Decode(&cvj)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: journal add: %v", i, err)
+		}
+		votes = append(votes, cvj)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// largestCommitmentAddresses returns the largest commitment address of the
+// provided tickets.  Tickets for which no address was found are omitted.
+func (a *auditor) largestCommitmentAddresses(tickets []string) (map[string]string, error) {
+	addrs := make(map[string]string, len(tickets))
+	for i := 0; i < len(tickets); i += dcrdataBatchSize {
+		end := i + dcrdataBatchSize
+		if end > len(tickets) {
+			end = len(tickets)
+		}
+		reqBody, err := json.Marshal(dcrdataapi.Txns{
+			Transactions: tickets[i:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		url := a.dcrdata + "/api/txs/trimmed"
+		r, err := http.Post(url, "application/json; charset=utf-8",
+			bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		var ttxs []dcrdataapi.TrimmedTx
+		if r.StatusCode != http.StatusOK {
+			body, _ := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			return nil, fmt.Errorf("dcrdata error: %v %v %s",
+				r.StatusCode, url, body)
+		}
+		err = json.NewDecoder(r.Body).Decode(&ttxs)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// Best is the address with the largest commit amount.
+		for _, tx := range ttxs {
+			var (
+				bestAddr   string
+				bestAmount float64
+			)
+			for _, v := range tx.Vout {
+				spk := v.ScriptPubKeyDecoded
+				if spk.CommitAmt == nil || len(spk.Addresses) == 0 {
+					continue
+				}
+				if *spk.CommitAmt > bestAmount {
+					bestAddr = spk.Addresses[0]
+					bestAmount = *spk.CommitAmt
+				}
+			}
+			if bestAddr != "" {
+				addrs[tx.TxID] = bestAddr
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// verifyMessage verifies that the hex encoded signature of message was
+// created by the private key of the provided P2PKH address.  This mirrors the
+// verification that is performed by gitbe when a ballot is cast.
+func (a *auditor) verifyMessage(address, message, signature string) error {
+	addr, err := dcrutil.DecodeAddress(address)
+	if err != nil {
+		return fmt.Errorf("could not decode address: %v", err)
+	}
+	if _, ok := addr.(*dcrutil.AddressPubKeyHash); !ok {
+		return fmt.Errorf("address is not a pay-to-pubkey-hash "+
+			"address: %v", address)
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %v", err)
+	}
+
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, message)
+	pk, wasCompressed, err := secp256k1.RecoverCompact(sig,
+		chainhash.HashB(buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("invalid signature")
+	}
+	var serializedPK []byte
+	if wasCompressed {
+		serializedPK = pk.SerializeCompressed()
+	} else {
+		serializedPK = pk.SerializeUncompressed()
+	}
+	pka, err := dcrutil.NewAddressSecpPubKey(serializedPK, a.params)
+	if err != nil {
+		return fmt.Errorf("invalid signature")
+	}
+	if pka.EncodeAddress() != address {
+		return fmt.Errorf("signature does not match %v", address)
+	}
+	return nil
+}
+
+// verifyReceipt verifies that the receipt is the politeiad signature of the
+// client signature.
+func (a *auditor) verifyReceipt(cvj gitbe.CastVoteJournal) error {
+	r, err := util.ConvertSignature(cvj.Receipt)
+	if err != nil {
+		return err
+	}
+	if !a.identity.VerifyMessage([]byte(cvj.CastVote.Signature), r) {
+		return fmt.Errorf("invalid receipt")
+	}
+	return nil
+}
+
+// validVoteBit returns true if the hex encoded vote bit is one of the vote
+// options.
+func validVoteBit(sv *startVote, bit string) bool {
+	b, err := strconv.ParseUint(bit, 16, 64)
+	if err != nil || b == 0 || sv.mask&b != b {
+		return false
+	}
+	for _, v := range sv.options {
+		if v.Bits == b {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeVoteBit returns the hex encoded vote bit without leading zeros.
+// This is the encoding that the cache uses for the vote option bits so that
+// tallies can be compared regardless of how the voter encoded the bit.
+func normalizeVoteBit(bit string) (string, error) {
+	b, err := strconv.ParseUint(bit, 16, 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(b, 16), nil
+}
+
+// tallyEqual returns true if both tallies contain the same vote counts.
+// Options without votes may be omitted from either tally.
+func tallyEqual(t1, t2 map[string]uint64) bool {
+	for k, v := range t1 {
+		if t2[k] != v {
+			return false
+		}
+	}
+	for k, v := range t2 {
+		if t1[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// approved returns whether the vote was approved using the same rules as the
+// cache.  Only yes/no votes are supported.
+func approved(sv *startVote, tally map[string]uint64) bool {
+	var total, yes uint64
+	for _, v := range tally {
+		total += v
+	}
+	for _, v := range sv.options {
+		if v.Id == "yes" {
+			yes = tally[strconv.FormatUint(v.Bits, 16)]
+		}
+	}
+	quorum := uint64(float64(sv.quorum) / 100 * float64(len(sv.eligible)))
+	pass := uint64(float64(sv.pass) / 100 * float64(total))
+	return total >= quorum && yes >= pass
+}
+
+// compareCache compares the votes and the vote summary in the cache with the
+// audited votes.
+func (a *auditor) compareCache(r *result, valid map[string]string) error {
+	payload, err := decredplugin.EncodeVoteResults(decredplugin.VoteResults{
+		Token: r.token,
+	})
+	if err != nil {
+		return err
+	}
+	reply, err := a.cache.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdProposalVotes,
+		CommandPayload: string(payload),
+	})
+	if err != nil {
+		return fmt.Errorf("cache proposal votes: %v", err)
+	}
+	vrr, err := decredplugin.DecodeVoteResultsReply([]byte(reply.Payload))
+	if err != nil {
+		return err
+	}
+	cached := make(map[string]string, len(vrr.CastVotes)) // [ticket]votebit
+	for _, v := range vrr.CastVotes {
+		bit, err := normalizeVoteBit(v.VoteBit)
+		if err != nil {
+			r.discrepancy("cache: invalid vote bit %v: %v",
+				v.VoteBit, v.Ticket)
+		}
+		cached[v.Ticket] = bit
+	}
+	for ticket, bit := range valid {
+		b, ok := cached[ticket]
+		switch {
+		case !ok:
+			r.discrepancy("cache: vote missing: %v", ticket)
+		case b == "":
+			// Invalid vote bit, reported above
+		case b != bit:
+			r.discrepancy("cache: vote bit %v, journal %v: %v", b,
+				bit, ticket)
+		}
+	}
+	for ticket := range cached {
+		if _, ok := valid[ticket]; !ok {
+			r.discrepancy("cache: vote not in journal: %v", ticket)
+		}
+	}
+
+	payload, err = decredplugin.EncodeVoteSummary(decredplugin.VoteSummary{
+		Token: r.token,
+	})
+	if err != nil {
+		return err
+	}
+	reply, err = a.cache.PluginExec(cache.PluginCommand{
+		ID:             decredplugin.ID,
+		Command:        decredplugin.CmdVoteSummary,
+		CommandPayload: string(payload),
+	})
+	if err != nil {
+		return fmt.Errorf("cache vote summary: %v", err)
+	}
+	vsr, err := decredplugin.DecodeVoteSummaryReply([]byte(reply.Payload))
+	if err != nil {
+		return err
+	}
+	tally := make(map[string]uint64, len(vsr.Results))
+	for _, v := range vsr.Results {
+		tally[strconv.FormatUint(v.Bits, 16)] = v.Votes
+	}
+	if !tallyEqual(tally, r.tally) {
+		r.discrepancy("cache: vote summary %v, journal %v", tally,
+			r.tally)
+	}
+
+	return nil
+}
+
+// compareWWW compares the votes that are returned by the politeiawww votes
+// endpoint with the audited votes.
+func (a *auditor) compareWWW(r *result) error {
+	url := a.www + www.PoliteiaWWWAPIRoute + "/proposals/" + r.token +
+		"/votes"
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("politeiawww error: %v %v %s",
+			resp.StatusCode, url, body)
+	}
+	var vrr www.VoteResultsReply
+	err = json.NewDecoder(resp.Body).Decode(&vrr)
+	if err != nil {
+		return err
+	}
+
+	tally := make(map[string]uint64)
+	var invalid int
+	for _, v := range vrr.CastVotes {
+		bit, err := normalizeVoteBit(v.VoteBit)
+		if err != nil {
+			invalid++
+			continue
+		}
+		tally[bit]++
+	}
+	if invalid != 0 {
+		r.discrepancy("www: %v invalid vote bits", invalid)
+	}
+	if !tallyEqual(tally, r.tally) {
+		r.discrepancy("www: tally %v, journal %v", tally, r.tally)
+	}
+	return nil
+}
+
+// audit re-derives the results of a proposal vote from its ballot journal
+// and ticket snapshot.
+func (a *auditor) audit(token string) (*result, error) {
+	sv, err := a.loadStartVote(token)
+	if err != nil {
+		return nil, err
+	}
+	votes, err := a.loadBallot(token)
+	if err != nil {
+		return nil, err
+	}
+
+	r := result{
+		token: token,
+		votes: len(votes),
+		tally: make(map[string]uint64, len(sv.options)),
+	}
+
+	tickets := make([]string, 0, len(votes))
+	for _, v := range votes {
+		tickets = append(tickets, v.CastVote.Ticket)
+	}
+	addrs, err := a.largestCommitmentAddresses(tickets)
+	if err != nil {
+		return nil, fmt.Errorf("largestCommitmentAddresses: %v", err)
+	}
+
+	valid := make(map[string]string, len(votes)) // [ticket]votebit
+	for _, v := range votes {
+		cv := v.CastVote
+		switch {
+		case cv.Token != token:
+			r.discrepancy("vote for %v: %v", cv.Token, cv.Ticket)
+			continue
+		case valid[cv.Ticket] != "":
+			r.discrepancy("duplicate vote: %v", cv.Ticket)
+			continue
+		}
+		if _, ok := sv.eligible[cv.Ticket]; !ok {
+			r.discrepancy("ineligible ticket: %v", cv.Ticket)
+			continue
+		}
+		if !validVoteBit(sv, cv.VoteBit) {
+			r.discrepancy("invalid vote bit %v: %v", cv.VoteBit,
+				cv.Ticket)
+			continue
+		}
+		addr, ok := addrs[cv.Ticket]
+		if !ok {
+			r.discrepancy("no commitment address: %v", cv.Ticket)
+			continue
+		}
+		err := a.verifyMessage(addr, cv.Token+cv.Ticket+cv.VoteBit,
+			cv.Signature)
+		if err != nil {
+			r.discrepancy("invalid signature: %v: %v", cv.Ticket, err)
+			continue
+		}
+		if a.identity != nil {
+			err := a.verifyReceipt(v)
+			if err != nil {
+				r.discrepancy("%v: %v", err, cv.Ticket)
+			}
+		}
+
+		// The vote bit was validated above.
+		bit, _ := normalizeVoteBit(cv.VoteBit)
+		valid[cv.Ticket] = bit
+		r.tally[bit]++
+	}
+	r.approved = approved(sv, r.tally)
+
+	if a.cache != nil {
+		err := a.compareCache(&r, valid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.www != "" {
+		err := a.compareWWW(&r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+// voteTokens returns the tokens of all vetted records that have a vote
+// snapshot.
+func (a *auditor) voteTokens() ([]string, error) {
+	dirs, err := ioutil.ReadDir(a.vetted)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]string, 0, len(dirs))
+	for _, v := range dirs {
+		if !util.IsDigest(v.Name()) {
+			continue
+		}
+		dir := filepath.Join(a.vetted, v.Name())
+		version, err := latestVersion(dir)
+		if err != nil {
+			continue
+		}
+		snapshot := filepath.Join(dir, version,
+			fmt.Sprintf("%02v%v", decredplugin.MDStreamVoteSnapshot,
+				mdFilenameSuffix))
+		if util.FileExists(snapshot) {
+			tokens = append(tokens, v.Name())
+		}
+	}
+	sort.Strings(tokens)
+	return tokens, nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: politeiavoteaudit [options] [token...]\n")
+	fmt.Fprintf(os.Stderr, " options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nAll proposal votes are audited when no "+
+		"token is provided.\n")
+}
+
+func _main() error {
+	flag.Usage = usage
+	flag.Parse()
+
+	params := &chaincfg.MainNetParams
+	dcrdataHost := defaultMainnetDcrdata
+	if *testnet {
+		params = &chaincfg.TestNet3Params
+		dcrdataHost = defaultTestnetDcrdata
+	}
+	if *dcrdata != "" {
+		dcrdataHost = *dcrdata
+	}
+
+	home := util.CleanAndExpandPath(*homeDir)
+	dataDir := filepath.Join(home, defaultDataDirname, params.Name)
+	a := auditor{
+		params:   params,
+		vetted:   filepath.Join(dataDir, defaultVettedDirname),
+		journals: filepath.Join(dataDir, defaultJournalsDirname),
+		dcrdata:  strings.TrimSuffix(dcrdataHost, "/"),
+		www:      strings.TrimSuffix(*wwwHost, "/"),
+	}
+
+	// Load the politeiad identity to verify the vote receipts.
+	idFile := *id
+	if idFile == "" {
+		idFile = filepath.Join(home, defaultIdentityFile)
+	}
+	if util.FileExists(idFile) {
+		fi, err := identity.LoadFullIdentity(idFile)
+		if err != nil {
+			return fmt.Errorf("load identity: %v", err)
+		}
+		a.identity = &fi.Public
+	} else if *id != "" {
+		return fmt.Errorf("identity not found: %v", *id)
+	} else {
+		fmt.Printf("Identity not found, vote receipts are not verified\n")
+	}
+
+	// Connect to the cache.
+	if *cacheHost != "" {
+		c, err := cockroachdb.New(cockroachdb.UserPoliteiawww, *cacheHost,
+			params.Name, util.CleanAndExpandPath(*cacheRoot),
+			util.CleanAndExpandPath(*cacheCert),
+			util.CleanAndExpandPath(*cacheKey))
+		if err != nil {
+			return fmt.Errorf("cache: %v", err)
+		}
+		defer c.Close()
+		err = c.RegisterPlugin(cache.Plugin{
+			ID:      decredplugin.ID,
+			Version: decredplugin.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("cache register plugin: %v", err)
+		}
+		a.cache = c
+	}
+
+	tokens := flag.Args()
+	if len(tokens) == 0 {
+		var err error
+		tokens, err = a.voteTokens()
+		if err != nil {
+			return err
+		}
+	}
+
+	var failed int
+	for _, token := range tokens {
+		r, err := a.audit(token)
+		if err != nil {
+			return fmt.Errorf("%v: %v", token, err)
+		}
+
+		outcome := "rejected"
+		if r.approved {
+			outcome = "approved"
+		}
+		fmt.Printf("%v: %v votes, tally %v, %v, %v discrepancies\n",
+			token, r.votes, r.tally, outcome, len(r.discrepancies))
+		if *verbose {
+			for _, v := range r.discrepancies {
+				fmt.Printf("  %v\n", v)
+			}
+		}
+		if len(r.discrepancies) != 0 {
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%v of %v votes have discrepancies", failed,
+			len(tokens))
+	}
+
+	return nil
+}
+
+func main() {
+	err := _main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
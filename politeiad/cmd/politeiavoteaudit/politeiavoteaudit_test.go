@@ -0,0 +1,487 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/backend/gitbe"
+	"github.com/thi4go/politeia/politeiad/dcrdatasim"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+// testTicket is a simulated ticket along with the key of its largest
+// commitment address.
+type testTicket struct {
+	hash    string
+	address string
+	key     *secp256k1.PrivateKey
+}
+
+func newTestTicket(t *testing.T, i int) testTicket {
+	t.Helper()
+
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := dcrutil.NewAddressSecpPubKey(
+		key.PubKey().SerializeCompressed(), &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := chainhash.HashH([]byte("ticket" + strconv.Itoa(i)))
+	return testTicket{
+		hash:    h.String(),
+		address: addr.EncodeAddress(),
+		key:     key,
+	}
+}
+
+// sign returns the hex encoded compact signature of message that is created
+// by the commitment address key of the ticket.
+func (tt testTicket) sign(t *testing.T, message string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	wire.WriteVarString(&buf, 0, message)
+	sig, err := secp256k1.SignCompact(tt.key, chainhash.HashB(buf.Bytes()),
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(sig)
+}
+
+// vote returns a cast vote that is signed by the commitment address key of
+// the ticket.
+func (tt testTicket) vote(t *testing.T, token, voteBit string) decredplugin.CastVote {
+	t.Helper()
+
+	return decredplugin.CastVote{
+		Token:     token,
+		Ticket:    tt.hash,
+		VoteBit:   voteBit,
+		Signature: tt.sign(t, token+tt.hash+voteBit),
+	}
+}
+
+// newTestStartVote returns yes/no vote parameters with the provided eligible
+// tickets.
+func newTestStartVote(eligible ...string) *startVote {
+	sv := startVote{
+		mask:   0x03,
+		quorum: 20,
+		pass:   60,
+		options: []decredplugin.VoteOption{
+			{Id: "no", Bits: 0x01},
+			{Id: "yes", Bits: 0x02},
+		},
+		eligible: make(map[string]struct{}, len(eligible)),
+	}
+	for _, v := range eligible {
+		sv.eligible[v] = struct{}{}
+	}
+	return &sv
+}
+
+func TestVerifyMessage(t *testing.T) {
+	a := auditor{params: &chaincfg.TestNet3Params}
+	tt := newTestTicket(t, 0)
+	other := newTestTicket(t, 1)
+	message := "token" + tt.hash + "2"
+
+	p2sh, err := dcrutil.NewAddressScriptHash([]byte("script"),
+		&chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name      string
+		address   string
+		message   string
+		signature string
+		valid     bool
+	}{
+		{"valid", tt.address, message, tt.sign(t, message), true},
+		{"wrong message", tt.address, message + "1", tt.sign(t, message),
+			false},
+		{"wrong key", tt.address, message, other.sign(t, message), false},
+		{"malformed signature", tt.address, message, "zz", false},
+		{"invalid signature", tt.address, message,
+			hex.EncodeToString(make([]byte, 65)), false},
+		{"invalid address", "invalid", message, tt.sign(t, message),
+			false},
+		{"script hash address", p2sh.EncodeAddress(), message,
+			tt.sign(t, message), false},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			err := a.verifyMessage(v.address, v.message, v.signature)
+			if v.valid && err != nil {
+				t.Errorf("got error %v, want valid", err)
+			}
+			if !v.valid && err == nil {
+				t.Errorf("got valid, want error")
+			}
+		})
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	fi, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := auditor{identity: &fi.Public}
+
+	signature := "clientsignature"
+	receipt := fi.SignMessage([]byte(signature))
+	other := fi.SignMessage([]byte("other"))
+
+	var tests = []struct {
+		name    string
+		receipt string
+		valid   bool
+	}{
+		{"valid", hex.EncodeToString(receipt[:]), true},
+		{"other message", hex.EncodeToString(other[:]), false},
+		{"malformed", "zz", false},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			err := a.verifyReceipt(gitbe.CastVoteJournal{
+				CastVote: decredplugin.CastVote{
+					Signature: signature,
+				},
+				Receipt: v.receipt,
+			})
+			if v.valid && err != nil {
+				t.Errorf("got error %v, want valid", err)
+			}
+			if !v.valid && err == nil {
+				t.Errorf("got valid, want error")
+			}
+		})
+	}
+}
+
+func TestValidVoteBit(t *testing.T) {
+	sv := newTestStartVote()
+
+	var tests = []struct {
+		bit  string
+		want bool
+	}{
+		{"1", true},
+		{"2", true},
+		{"0", false},  // No option
+		{"3", false},  // Both options
+		{"4", false},  // Outside of the mask
+		{"zz", false}, // Not hex
+		{"", false},
+	}
+	for _, v := range tests {
+		got := validVoteBit(sv, v.bit)
+		if got != v.want {
+			t.Errorf("%q: got %v, want %v", v.bit, got, v.want)
+		}
+	}
+}
+
+func TestTallyEqual(t *testing.T) {
+	var tests = []struct {
+		name string
+		t1   map[string]uint64
+		t2   map[string]uint64
+		want bool
+	}{
+		{"equal", map[string]uint64{"1": 2, "2": 3},
+			map[string]uint64{"1": 2, "2": 3}, true},
+		{"omitted option without votes", map[string]uint64{"1": 2, "2": 0},
+			map[string]uint64{"1": 2}, true},
+		{"different count", map[string]uint64{"1": 2, "2": 3},
+			map[string]uint64{"1": 2, "2": 4}, false},
+		{"missing option", map[string]uint64{"1": 2},
+			map[string]uint64{"1": 2, "2": 1}, false},
+		{"empty", map[string]uint64{}, nil, true},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			if got := tallyEqual(v.t1, v.t2); got != v.want {
+				t.Errorf("t1, t2: got %v, want %v", got, v.want)
+			}
+			if got := tallyEqual(v.t2, v.t1); got != v.want {
+				t.Errorf("t2, t1: got %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestApproved(t *testing.T) {
+	// Ten eligible tickets, a quorum of 20% and a pass percentage of 60%
+	eligible := make([]string, 10)
+	for i := range eligible {
+		eligible[i] = strconv.Itoa(i)
+	}
+	sv := newTestStartVote(eligible...)
+
+	var tests = []struct {
+		name  string
+		tally map[string]uint64
+		want  bool
+	}{
+		{"approved", map[string]uint64{"2": 3, "1": 1}, true},
+		{"exactly the pass percentage", map[string]uint64{"2": 3, "1": 2},
+			true},
+		{"below the pass percentage", map[string]uint64{"2": 1, "1": 3},
+			false},
+		{"below quorum", map[string]uint64{"2": 1}, false},
+		{"no votes", map[string]uint64{}, false},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			if got := approved(sv, v.tally); got != v.want {
+				t.Errorf("got %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+// writeMetadata writes a metadata stream of a vetted record version.
+func writeMetadata(t *testing.T, dir string, id int, payload interface{}) {
+	t.Helper()
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir,
+		fmt.Sprintf("%02v%v", id, mdFilenameSuffix)), b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeBallot writes the ballot journal of a proposal.
+func writeBallot(t *testing.T, filename string, votes []gitbe.CastVoteJournal) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	for _, v := range votes {
+		err := e.Encode(gitbe.JournalAction{
+			Version: "1",
+			Action:  journalActionAdd,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Truncate(buf.Len() - 1) // Both objects share a line
+		err = e.Encode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filename, buf.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "politeiavoteaudit.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fi, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tickets 0-3 and 5 are eligible, ticket 4 is not.  Ticket 5 has no
+	// commitment address.
+	tickets := make([]testTicket, 6)
+	commitments := make(map[string]string, len(tickets))
+	for i := range tickets {
+		tickets[i] = newTestTicket(t, i)
+		if i != 5 {
+			commitments[tickets[i].hash] = tickets[i].address
+		}
+	}
+	sim, err := dcrdatasim.New(dcrdatasim.Fixture{
+		Commitments: commitments,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dcrdata := httptest.NewServer(sim)
+	defer dcrdata.Close()
+
+	// Setup the vote parameters and the ticket snapshot
+	token := strings.Repeat("a", 64)
+	a := auditor{
+		params:   &chaincfg.TestNet3Params,
+		vetted:   filepath.Join(dir, "vetted"),
+		journals: filepath.Join(dir, "journals"),
+		dcrdata:  dcrdata.URL,
+		identity: &fi.Public,
+	}
+	mdDir := filepath.Join(a.vetted, token, "1")
+	err = os.MkdirAll(mdDir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv := newTestStartVote()
+	writeMetadata(t, mdDir, decredplugin.MDStreamVoteBits,
+		decredplugin.StartVoteV2{
+			Version: decredplugin.VersionStartVoteV2,
+			Vote: decredplugin.VoteV2{
+				Token:            token,
+				Mask:             sv.mask,
+				QuorumPercentage: sv.quorum,
+				PassPercentage:   sv.pass,
+				Options:          sv.options,
+			},
+		})
+	writeMetadata(t, mdDir, decredplugin.MDStreamVoteSnapshot,
+		decredplugin.StartVoteReply{
+			EligibleTickets: []string{tickets[0].hash, tickets[1].hash,
+				tickets[2].hash, tickets[3].hash, tickets[5].hash},
+		})
+
+	// Cast the votes.  Only the first two votes are valid.  The first vote
+	// bit is zero padded and must be tallied as "2".
+	receipt := func(cv decredplugin.CastVote) gitbe.CastVoteJournal {
+		r := fi.SignMessage([]byte(cv.Signature))
+		return gitbe.CastVoteJournal{
+			CastVote: cv,
+			Receipt:  hex.EncodeToString(r[:]),
+		}
+	}
+	forged := tickets[2].vote(t, token, "2")
+	forged.Signature = tickets[3].sign(t, token+tickets[2].hash+"2")
+	badReceipt := receipt(tickets[1].vote(t, token, "1"))
+	badReceipt.Receipt = receipt(tickets[0].vote(t, token, "2")).Receipt
+	writeBallot(t, filepath.Join(a.journals, token, ballotFilename),
+		[]gitbe.CastVoteJournal{
+			receipt(tickets[0].vote(t, token, "02")),
+			badReceipt,
+			receipt(tickets[0].vote(t, token, "1")),
+			receipt(tickets[4].vote(t, token, "2")),
+			receipt(forged),
+			receipt(tickets[3].vote(t, token, "4")),
+			receipt(tickets[3].vote(t, strings.Repeat("b", 64), "2")),
+			receipt(tickets[5].vote(t, token, "2")),
+		})
+
+	r, err := a.audit(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.votes != 8 {
+		t.Errorf("got %v votes, want 8", r.votes)
+	}
+	wantTally := map[string]uint64{"2": 1, "1": 1}
+	if !tallyEqual(r.tally, wantTally) {
+		t.Errorf("got tally %v, want %v", r.tally, wantTally)
+	}
+
+	want := []string{
+		"invalid receipt: " + tickets[1].hash,
+		"duplicate vote: " + tickets[0].hash,
+		"ineligible ticket: " + tickets[4].hash,
+		"invalid signature: " + tickets[2].hash,
+		"invalid vote bit 4: " + tickets[3].hash,
+		"vote for " + strings.Repeat("b", 64) + ": " + tickets[3].hash,
+		"no commitment address: " + tickets[5].hash,
+	}
+	if len(r.discrepancies) != len(want) {
+		t.Fatalf("got discrepancies %v, want %v", r.discrepancies, want)
+	}
+	for i, v := range want {
+		if !strings.HasPrefix(r.discrepancies[i], v) {
+			t.Errorf("got discrepancy %q, want %q", r.discrepancies[i],
+				v)
+		}
+	}
+}
+
+func TestCompareWWW(t *testing.T) {
+	token := strings.Repeat("a", 64)
+	journal := map[string]uint64{"2": 2, "1": 1}
+
+	var tests = []struct {
+		name  string
+		votes []string // Vote bits returned by politeiawww
+		want  int      // Number of discrepancies
+	}{
+		{"matching tally", []string{"2", "1", "2"}, 0},
+		{"missing vote", []string{"2", "1"}, 1},
+		{"different vote", []string{"2", "1", "1"}, 1},
+		{"zero padded vote", []string{"02", "1", "2"}, 0},
+		{"invalid vote", []string{"2", "1", "2", "zz"}, 1},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					path := www.PoliteiaWWWAPIRoute + "/proposals/" +
+						token + "/votes"
+					if r.URL.Path != path {
+						http.NotFound(w, r)
+						return
+					}
+					var vrr www.VoteResultsReply
+					for _, bit := range v.votes {
+						vrr.CastVotes = append(vrr.CastVotes,
+							www.CastVote{VoteBit: bit})
+					}
+					json.NewEncoder(w).Encode(vrr)
+				}))
+			defer s.Close()
+
+			a := auditor{www: s.URL}
+			r := result{
+				token: token,
+				tally: journal,
+			}
+			err := a.compareWWW(&r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(r.discrepancies) != v.want {
+				t.Errorf("got discrepancies %v, want %v",
+					r.discrepancies, v.want)
+			}
+		})
+	}
+}
@@ -20,8 +20,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	v1 "github.com/decred/dcrtime/api/v1"
+	"github.com/thi4go/politeia/decredplugin"
+	"github.com/thi4go/politeia/politeiad/backend"
 	"github.com/thi4go/politeia/politeiad/sharedconfig"
 	"github.com/thi4go/politeia/util"
 	"github.com/thi4go/politeia/util/version"
@@ -41,6 +44,20 @@ const (
 
 	defaultMainnetDcrdata = "dcrdata.decred.org:443"
 	defaultTestnetDcrdata = "testnet.decred.org:443"
+
+	// Backend options
+	backendGit     = "git"
+	backendLevelDB = "leveldb"
+	backendTlog    = "tlog"
+	defaultBackend = backendGit
+
+	defaultTrillianHost = "localhost:8090"
+
+	// Cache options
+	cacheCockroachDB     = "cockroachdb"
+	cacheSQLite          = "sqlite"
+	defaultCacheDB       = cacheCockroachDB
+	defaultCacheFilename = "cache.db"
 )
 
 var (
@@ -51,6 +68,9 @@ var (
 	defaultHTTPSCertFile = filepath.Join(defaultHomeDir, "https.cert")
 	defaultLogDir        = filepath.Join(defaultHomeDir, defaultLogDirname)
 	defaultIdentityFile  = filepath.Join(defaultHomeDir, defaultIdentityFilename)
+
+	defaultTrillianKeyFile   = filepath.Join(defaultHomeDir, "trillian.der")
+	defaultEncryptionKeyFile = filepath.Join(defaultHomeDir, "encryption.key")
 )
 
 // runServiceCommand is only set to a real function on Windows.  It is used
@@ -73,6 +93,8 @@ type config struct {
 	MemProfile    string   `long:"memprofile" description:"Write mem profile to the specified file"`
 	DebugLevel    string   `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 	Listeners     []string `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 49152, testnet: 59152)"`
+	Clients       []string `long:"client" description:"Add a client that signs privileged commands with its identity: <name>:<hex public key>:<permission>[,<permission>...] (permissions: inventory, status, metadata, plugin, admin, all)"`
+	Metrics       string   `long:"metrics" description:"Serve Prometheus metrics over plain HTTP on the given interface/port at /metrics (e.g. localhost:49153)"`
 	Version       string
 	HTTPSCert     string `long:"httpscert" description:"File containing the https certificate file"`
 	HTTPSKey      string `long:"httpskey" description:"File containing the https certificate key"`
@@ -81,6 +103,8 @@ type config struct {
 	DcrtimeHost   string `long:"dcrtimehost" description:"Dcrtime ip:port"`
 	DcrtimeCert   string `long:"dcrtimecert" description:"File containing the https certificate file for dcrtimehost"`
 	EnableCache   bool   `long:"enablecache" description:"Enable the external cache"`
+	CacheDB       string `long:"cachedb" description:"Cache database {cockroachdb, sqlite}"`
+	CacheFile     string `long:"cachefile" description:"File containing the cache database, sqlite cache only (default: cache.db in the data directory)"`
 	CacheHost     string `long:"cachehost" description:"Cache ip:port"`
 	CacheRootCert string `long:"cacherootcert" description:"File containing the CA certificate for the cache"`
 	CacheCert     string `long:"cachecert" description:"File containing the politeiad client certificate for the cache"`
@@ -89,6 +113,19 @@ type config struct {
 	Identity      string `long:"identity" description:"File containing the politeiad identity file"`
 	GitTrace      bool   `long:"gittrace" description:"Enable git tracing in logs"`
 	DcrdataHost   string `long:"dcrdatahost" description:"Dcrdata ip:port"`
+	Backend       string `long:"backend" description:"Record backend type {git, leveldb, tlog}"`
+	TrillianHost  string `long:"trillianhost" description:"Trillian log server ip:port, tlog backend only"`
+	TrillianKey   string `long:"trilliankey" description:"File containing the trillian signing key, tlog backend only"`
+	EncryptionKey string `long:"encryptionkey" description:"File containing the record blob encryption key, tlog backend only"`
+
+	Plugins        []string `long:"plugin" description:"Enable a plugin, may be specified multiple times (default: decred on the git backend)"`
+	PluginSettings []string `long:"pluginsetting" description:"Plugin setting in the format pluginid,key=value, may be specified multiple times"`
+
+	LocalDcrtime      bool          `long:"localdcrtime" description:"Simulate anchoring locally instead of using dcrtimehost, for testing only"`
+	LocalDcrtimeDelay time.Duration `long:"localdcrtimedelay" description:"Anchor confirmation delay of the local dcrtime simulation"`
+
+	MaxUploads     int   `long:"maxuploads" description:"Maximum number of chunked uploads that may be pending at the same time"`
+	MaxUploadBytes int64 `long:"maxuploadbytes" description:"Maximum number of bytes that all pending chunked uploads may occupy on disk"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service
@@ -291,6 +328,11 @@ func loadConfig() (*config, []string, error) {
 		HTTPSKey:   defaultHTTPSKeyFile,
 		HTTPSCert:  defaultHTTPSCertFile,
 		Version:    version.String(),
+		Backend:    defaultBackend,
+		CacheDB:    defaultCacheDB,
+
+		MaxUploads:     defaultMaxUploads,
+		MaxUploadBytes: defaultMaxUploadBytes,
 	}
 
 	// Service options which are only added on Windows.
@@ -460,7 +502,21 @@ func loadConfig() (*config, []string, error) {
 	}
 
 	// Validate cache options.
-	if cfg.EnableCache {
+	switch cfg.CacheDB {
+	case cacheCockroachDB:
+		// Valid selection; continue
+	case cacheSQLite:
+		if cfg.CacheFile == "" {
+			cfg.CacheFile = filepath.Join(cfg.DataDir,
+				defaultCacheFilename)
+		}
+		cfg.CacheFile = cleanAndExpandPath(cfg.CacheFile)
+	default:
+		return nil, nil, fmt.Errorf("invalid cachedb '%v'; must be "+
+			"either %v or %v", cfg.CacheDB, cacheCockroachDB,
+			cacheSQLite)
+	}
+	if cfg.EnableCache && cfg.CacheDB == cacheCockroachDB {
 		switch {
 		case cfg.CacheHost == "":
 			return nil, nil, fmt.Errorf("the enablecache param can " +
@@ -532,6 +588,27 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	// Validate metrics listener
+	if cfg.Metrics != "" {
+		_, _, err := net.SplitHostPort(cfg.Metrics)
+		if err != nil {
+			str := "%s: Invalid metrics listener: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
+	// Validate upload limits
+	if cfg.MaxUploads <= 0 || cfg.MaxUploadBytes <= 0 {
+		str := "%s: maxuploads and maxuploadbytes must be positive"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Add the default listener if none were specified. The default
 	// listener is all addresses on the listen port for the network
 	// we are to connect to.
@@ -545,6 +622,29 @@ func loadConfig() (*config, []string, error) {
 	// duplicate addresses.
 	cfg.Listeners = normalizeAddresses(cfg.Listeners, port)
 
+	// Validate backend type.
+	switch cfg.Backend {
+	case backendGit, backendLevelDB:
+	case backendTlog:
+		if cfg.TrillianHost == "" {
+			cfg.TrillianHost = defaultTrillianHost
+		}
+		if cfg.TrillianKey == "" {
+			cfg.TrillianKey = defaultTrillianKeyFile
+		}
+		cfg.TrillianKey = cleanAndExpandPath(cfg.TrillianKey)
+		if cfg.EncryptionKey == "" {
+			cfg.EncryptionKey = defaultEncryptionKeyFile
+		}
+		cfg.EncryptionKey = cleanAndExpandPath(cfg.EncryptionKey)
+	default:
+		str := "%s: invalid backend type '%v'"
+		err := fmt.Errorf(str, funcName, cfg.Backend)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	if len(cfg.DcrdataHost) == 0 {
 		if cfg.TestNet {
 			cfg.DcrdataHost = defaultTestnetDcrdata
@@ -554,6 +654,37 @@ func loadConfig() (*config, []string, error) {
 	}
 	cfg.DcrdataHost = "https://" + cfg.DcrdataHost
 
+	// Enable the decred plugin by default on the git backend.
+	if len(cfg.Plugins) == 0 && cfg.Backend == backendGit {
+		cfg.Plugins = []string{decredplugin.ID}
+	}
+
+	// Validate plugin settings and pass the dcrdata host to the decred
+	// plugin unless it was explicitly set.
+	var dcrdataSet bool
+	for _, v := range cfg.PluginSettings {
+		id, s, err := parsePluginSetting(v)
+		if err != nil {
+			err := fmt.Errorf("%s: %v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		if id == decredplugin.ID && s.Key == "dcrdata" {
+			dcrdataSet = true
+		}
+	}
+	var decredEnabled bool
+	for _, v := range cfg.Plugins {
+		if v == decredplugin.ID {
+			decredEnabled = true
+		}
+	}
+	if decredEnabled && !dcrdataSet {
+		cfg.PluginSettings = append(cfg.PluginSettings,
+			decredplugin.ID+",dcrdata="+cfg.DcrdataHost)
+	}
+
 	if cfg.TestNet {
 		var timeHost string
 		if len(cfg.DcrtimeHost) == 0 {
@@ -621,3 +752,22 @@ func loadConfig() (*config, []string, error) {
 
 	return &cfg, remainingArgs, nil
 }
+
+// parsePluginSetting parses a plugin setting in the format
+// pluginid,key=value.
+func parsePluginSetting(setting string) (string, backend.PluginSetting, error) {
+	s := strings.SplitN(setting, ",", 2)
+	if len(s) != 2 || !backend.PluginRE.MatchString(s[0]) {
+		return "", backend.PluginSetting{},
+			fmt.Errorf("invalid plugin setting: %v", setting)
+	}
+	kv := strings.SplitN(s[1], "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return "", backend.PluginSetting{},
+			fmt.Errorf("invalid plugin setting: %v", setting)
+	}
+	return s[0], backend.PluginSetting{
+		Key:   kv[0],
+		Value: kv[1],
+	}, nil
+}
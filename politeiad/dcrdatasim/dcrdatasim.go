@@ -0,0 +1,268 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package dcrdatasim provides an offline dcrdata simulator.  The simulator
+// serves a scripted chain that is loaded from a fixture file, which allows
+// the politeiad voting flows to be exercised without a live dcrdata.
+//
+// The fixture describes the best block, the ticket pool at specific heights
+// and the largest commitment address of every ticket.  Blocks that are not
+// listed in the fixture are generated on the fly and inherit the ticket pool
+// of the closest listed block below them.  A fixture looks as follows:
+//
+//	{
+//	  "bestblock": 300,
+//	  "blocks": [
+//	    {"height": 10, "ticketpool": ["<ticket>", "<ticket>"]}
+//	  ],
+//	  "commitments": {"<ticket>": "<address>"}
+//	}
+package dcrdatasim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	dcrdataapi "github.com/decred/dcrdata/api/types/v4"
+)
+
+const (
+	// commitAmount is the commitment amount that is reported for every
+	// ticket commitment output.
+	commitAmount = 100.0
+)
+
+// Block is a block of the scripted chain.
+type Block struct {
+	Height     uint32   `json:"height"`               // Block height
+	Hash       string   `json:"hash,omitempty"`       // Block hash, generated when empty
+	TicketPool []string `json:"ticketpool,omitempty"` // Live tickets as of this block
+}
+
+// Fixture is the on disk description of a scripted chain.
+type Fixture struct {
+	BestBlock   uint32            `json:"bestblock"`   // Initial best block height
+	Blocks      []Block           `json:"blocks"`      // Blocks that change the ticket pool
+	Commitments map[string]string `json:"commitments"` // [ticket]largest commitment address
+}
+
+// Simulator serves a scripted chain using the subset of the dcrdata API that
+// is used by politeia.  It is safe for concurrent use.
+type Simulator struct {
+	sync.RWMutex
+	best        uint32
+	blocks      []Block           // Sorted by height
+	commitments map[string]string // [ticket]address
+}
+
+// blockHash returns the deterministic hash of a block that was not listed in
+// the fixture.
+func blockHash(height uint32) string {
+	h := sha256.Sum256([]byte("dcrdatasim" +
+		strconv.FormatUint(uint64(height), 10)))
+	return hex.EncodeToString(h[:])
+}
+
+// block returns the block at the provided height.  This function must be
+// called with the lock held.
+func (s *Simulator) block(height uint32) Block {
+	// Find the closest listed block at or below height.
+	i := sort.Search(len(s.blocks), func(i int) bool {
+		return s.blocks[i].Height > height
+	}) - 1
+	if i < 0 {
+		return Block{
+			Height: height,
+			Hash:   blockHash(height),
+		}
+	}
+	b := Block{
+		Height:     height,
+		Hash:       blockHash(height),
+		TicketPool: s.blocks[i].TicketPool,
+	}
+	if s.blocks[i].Height == height && s.blocks[i].Hash != "" {
+		b.Hash = s.blocks[i].Hash
+	}
+	return b
+}
+
+// BestBlock returns the best block.
+func (s *Simulator) BestBlock() (*dcrdataapi.BlockDataBasic, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	b := s.block(s.best)
+	return &dcrdataapi.BlockDataBasic{
+		Height: b.Height,
+		Hash:   b.Hash,
+	}, nil
+}
+
+// Block returns the block at the provided height.
+func (s *Simulator) Block(height uint32) (*dcrdataapi.BlockDataBasic, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if height > s.best {
+		return nil, fmt.Errorf("block not found: %v", height)
+	}
+	b := s.block(height)
+	return &dcrdataapi.BlockDataBasic{
+		Height: b.Height,
+		Hash:   b.Hash,
+	}, nil
+}
+
+// TicketPool returns the sorted ticket pool as of the block with the
+// provided hash.
+func (s *Simulator) TicketPool(hash string) ([]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for h := int64(s.best); h >= 0; h-- {
+		b := s.block(uint32(h))
+		if b.Hash != hash {
+			continue
+		}
+		pool := make([]string, len(b.TicketPool))
+		copy(pool, b.TicketPool)
+		sort.Strings(pool)
+		return pool, nil
+	}
+	return nil, fmt.Errorf("block not found: %v", hash)
+}
+
+// TrimmedTxs returns the provided ticket transactions.  Every ticket that is
+// known to the simulator has a single commitment output that pays to its
+// commitment address.  Unknown transactions are returned without outputs.
+func (s *Simulator) TrimmedTxs(hashes []string) ([]dcrdataapi.TrimmedTx, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	ttxs := make([]dcrdataapi.TrimmedTx, 0, len(hashes))
+	for _, v := range hashes {
+		ttx := dcrdataapi.TrimmedTx{
+			TxID: v,
+			Vout: []dcrdataapi.Vout{},
+		}
+		if addr, ok := s.commitments[v]; ok {
+			amount := commitAmount
+			ttx.Vout = append(ttx.Vout, dcrdataapi.Vout{
+				N: 1,
+				ScriptPubKeyDecoded: dcrdataapi.ScriptPubKey{
+					Type:      "sstxcommitment",
+					Addresses: []string{addr},
+					CommitAmt: &amount,
+				},
+			})
+		}
+		ttxs = append(ttxs, ttx)
+	}
+	return ttxs, nil
+}
+
+// Mine advances the best block by the provided number of blocks.
+func (s *Simulator) Mine(blocks uint32) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.best += blocks
+}
+
+// respond writes the JSON encoding of reply.
+func respond(w http.ResponseWriter, reply interface{}, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(reply)
+}
+
+// ServeHTTP serves the simulated chain using the dcrdata API routes that are
+// used by politeia.  It satisfies the http.Handler interface.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && path == "/api/block/best":
+		bb, err := s.BestBlock()
+		respond(w, bb, err)
+	case r.Method == http.MethodGet &&
+		strings.HasPrefix(path, "/api/stake/pool/b/"):
+		hash := strings.TrimSuffix(strings.TrimPrefix(path,
+			"/api/stake/pool/b/"), "/full")
+		pool, err := s.TicketPool(hash)
+		respond(w, pool, err)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/api/block/"):
+		height, err := strconv.ParseUint(strings.TrimPrefix(path,
+			"/api/block/"), 10, 32)
+		if err != nil {
+			http.Error(w, "invalid height", http.StatusBadRequest)
+			return
+		}
+		b, err := s.Block(uint32(height))
+		respond(w, b, err)
+	case r.Method == http.MethodPost && path == "/api/txs/trimmed":
+		var txns dcrdataapi.Txns
+		err := json.NewDecoder(r.Body).Decode(&txns)
+		if err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		ttxs, err := s.TrimmedTxs(txns.Transactions)
+		respond(w, ttxs, err)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// New returns a simulator that serves the chain described by the fixture.
+func New(f Fixture) (*Simulator, error) {
+	blocks := make([]Block, len(f.Blocks))
+	copy(blocks, f.Blocks)
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Height < blocks[j].Height
+	})
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].Height == blocks[i-1].Height {
+			return nil, fmt.Errorf("duplicate block: %v",
+				blocks[i].Height)
+		}
+	}
+
+	commitments := make(map[string]string, len(f.Commitments))
+	for k, v := range f.Commitments {
+		commitments[k] = v
+	}
+
+	return &Simulator{
+		best:        f.BestBlock,
+		blocks:      blocks,
+		commitments: commitments,
+	}, nil
+}
+
+// Load returns a simulator that serves the chain described by the fixture
+// file.
+func Load(filename string) (*Simulator, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return New(f)
+}
@@ -42,14 +42,20 @@ var (
 
 	log            = backendLog.Logger("POLI")
 	gitbeLog       = backendLog.Logger("GITB")
+	levelbeLog     = backendLog.Logger("LVLB")
+	tlogbeLog      = backendLog.Logger("TLOG")
 	cockroachdbLog = backendLog.Logger("CODB")
+	sqliteLog      = backendLog.Logger("SQLT")
 )
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
 var subsystemLoggers = map[string]slog.Logger{
 	"POLI": log,
 	"GITB": gitbeLog,
+	"LVLB": levelbeLog,
+	"TLOG": tlogbeLog,
 	"CODB": cockroachdbLog,
+	"SQLT": sqliteLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -0,0 +1,57 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// httpRequests counts the served requests by route, method and
+	// status code.
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "politeiad",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of served HTTP requests.",
+	}, []string{"route", "method", "code"})
+
+	// httpDuration is the latency of the served requests by route, method
+	// and status code.
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "politeiad",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served HTTP requests.",
+	}, []string{"route", "method", "code"})
+)
+
+// instrument records the count and latency of the requests to a route.
+func instrument(route string, f http.HandlerFunc) http.HandlerFunc {
+	labels := prometheus.Labels{"route": route}
+	return promhttp.InstrumentHandlerCounter(
+		httpRequests.MustCurryWith(labels),
+		promhttp.InstrumentHandlerDuration(
+			httpDuration.MustCurryWith(labels), f))
+}
+
+// serveMetrics serves the Prometheus metrics on the provided listener.  The
+// metrics are served over plain HTTP so the listener should not be publicly
+// reachable.
+func serveMetrics(listen string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		log.Infof("Metrics listen: %v", listen)
+		err := http.ListenAndServe(listen, mux)
+		if err != nil {
+			log.Errorf("Metrics listener: %v", err)
+		}
+	}()
+}
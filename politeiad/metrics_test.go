@@ -0,0 +1,103 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// requestCount returns the number of requests that were served by the route
+// with the provided method and status code, and the number of durations that
+// were recorded for them.
+func requestCount(t *testing.T, route, method, code string) (float64, uint64) {
+	t.Helper()
+
+	n := testutil.ToFloat64(httpRequests.WithLabelValues(route, method, code))
+	var m dto.Metric
+	err := httpDuration.WithLabelValues(route, method, code).(prometheus.Metric).Write(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrument(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	route := "/v1/metricstest/"
+	p.addRoute(http.MethodPost, route, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}, permissionPublic)
+
+	var tests = []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"ok", "", "200"},
+		{"bad request", "?fail=1", "400"},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			requests, durations := requestCount(t, route, "post", v.code)
+
+			r := httptest.NewRequest(http.MethodPost, route+v.query,
+				strings.NewReader("{}"))
+			w := httptest.NewRecorder()
+			p.router.ServeHTTP(w, r)
+
+			gotRequests, gotDurations := requestCount(t, route, "post",
+				v.code)
+			if n := gotRequests - requests; n != 1 {
+				t.Errorf("got %v requests, want 1", n)
+			}
+			if n := gotDurations - durations; n != 1 {
+				t.Errorf("got %v durations, want 1", n)
+			}
+		})
+	}
+
+	// The metrics of the route are served
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen := l.Addr().String()
+	l.Close()
+	serveMetrics(listen)
+
+	var body []byte
+	for i := 0; i < 50; i++ {
+		var resp *http.Response
+		resp, err = http.Get("http://" + listen + "/metrics")
+		if err == nil {
+			body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `politeiad_http_requests_total{code="200",method="post",` +
+		`route="` + route + `"}`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("metrics do not contain %v", want)
+	}
+}
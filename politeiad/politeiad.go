@@ -7,6 +7,7 @@ package main
 import (
 	"crypto/elliptic"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -17,29 +18,29 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/thi4go/politeia/decredplugin"
 	v1 "github.com/thi4go/politeia/politeiad/api/v1"
 	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/auditlog"
 	"github.com/thi4go/politeia/politeiad/backend"
 	"github.com/thi4go/politeia/politeiad/backend/gitbe"
+	"github.com/thi4go/politeia/politeiad/backend/levelbe"
+	"github.com/thi4go/politeia/politeiad/backend/tlogbe"
 	"github.com/thi4go/politeia/politeiad/cache"
+	"github.com/thi4go/politeia/politeiad/cache/cachemetrics"
 	"github.com/thi4go/politeia/politeiad/cache/cachestub"
 	"github.com/thi4go/politeia/politeiad/cache/cockroachdb"
+	"github.com/thi4go/politeia/politeiad/cache/sqlite"
+	"github.com/thi4go/politeia/politeiad/changefeed"
 	"github.com/thi4go/politeia/util"
 	"github.com/thi4go/politeia/util/version"
 	"github.com/gorilla/mux"
 )
 
-type permission uint
-
-const (
-	permissionPublic permission = iota
-	permissionAuth
-)
-
 // politeia application context.
 type politeia struct {
 	backend  backend.Backend
@@ -48,13 +49,20 @@ type politeia struct {
 	router   *mux.Router
 	identity *identity.FullIdentity
 	plugins  map[string]v1.Plugin
+	uploads  *uploads
+	feed     *changefeed.Feed
+	audits   *auditlog.Log
+	clients  *clients
 }
 
 func remoteAddr(r *http.Request) string {
 	via := r.RemoteAddr
 	xff := r.Header.Get(v1.Forward)
 	if xff != "" {
-		return fmt.Sprintf("%v via %v", xff, r.RemoteAddr)
+		via = fmt.Sprintf("%v via %v", xff, r.RemoteAddr)
+	}
+	if c := requestClient(r); c != "" {
+		via = fmt.Sprintf("%v (%v)", via, c)
 	}
 	return via
 }
@@ -124,6 +132,116 @@ func convertFrontendStatus(status v1.RecordStatusT) backend.MDStatusT {
 	return s
 }
 
+// convertBackendDiffAction converts a backend diff action to an API diff
+// action.
+func convertBackendDiffAction(action backend.DiffActionT) v1.DiffActionT {
+	a := v1.DiffActionInvalid
+	switch action {
+	case backend.DiffActionAdded:
+		a = v1.DiffActionAdded
+	case backend.DiffActionRemoved:
+		a = v1.DiffActionRemoved
+	case backend.DiffActionModified:
+		a = v1.DiffActionModified
+	}
+	return a
+}
+
+// convertBackendFileDiff converts a backend file diff to an API file diff.
+func convertBackendFileDiff(fd backend.FileDiff) v1.FileDiff {
+	return v1.FileDiff{
+		Name:      fd.Name,
+		Action:    convertBackendDiffAction(fd.Action),
+		MIME:      fd.MIME,
+		OldDigest: fd.OldDigest,
+		NewDigest: fd.NewDigest,
+		Diff:      fd.Diff,
+	}
+}
+
+// convertBackendMetadataStreamDiff converts a backend metadata stream diff to
+// an API metadata stream diff.
+func convertBackendMetadataStreamDiff(md backend.MetadataStreamDiff) v1.MetadataStreamDiff {
+	return v1.MetadataStreamDiff{
+		ID:     md.ID,
+		Action: convertBackendDiffAction(md.Action),
+		Diff:   md.Diff,
+	}
+}
+
+// convertFrontendInventoryFilter converts an API inventory filter to a
+// backend inventory filter.  It returns false if the filter is invalid.
+func convertFrontendInventoryFilter(f v1.InventoryFilter) (backend.InventoryFilter, bool) {
+	filter := backend.InventoryFilter{
+		Statuses:      make([]backend.MDStatusT, 0, len(f.Statuses)),
+		FromTimestamp: f.FromTimestamp,
+		ToTimestamp:   f.ToTimestamp,
+		MDStreams:     f.MDStreams,
+	}
+	for _, v := range f.Statuses {
+		var s backend.MDStatusT
+		switch v {
+		case v1.RecordStatusUnreviewedChanges:
+			s = backend.MDStatusIterationUnvetted
+		default:
+			s = convertFrontendStatus(v)
+		}
+		if s == backend.MDStatusInvalid {
+			return filter, false
+		}
+		filter.Statuses = append(filter.Statuses, s)
+	}
+	if f.FromTimestamp < 0 || f.ToTimestamp < 0 ||
+		(f.ToTimestamp != 0 && f.FromTimestamp > f.ToTimestamp) {
+		return filter, false
+	}
+	for _, v := range f.MDStreams {
+		if v >= v1.MetadataStreamsMax {
+			return filter, false
+		}
+	}
+	return filter, true
+}
+
+// convertBackendTimestampStatus converts a backend timestamp status to an API
+// timestamp status.
+func convertBackendTimestampStatus(status backend.TimestampStatusT) v1.TimestampStatusT {
+	s := v1.TimestampStatusInvalid
+	switch status {
+	case backend.TimestampStatusNotAnchored:
+		s = v1.TimestampStatusNotAnchored
+	case backend.TimestampStatusUnconfirmed:
+		s = v1.TimestampStatusUnconfirmed
+	case backend.TimestampStatusConfirmed:
+		s = v1.TimestampStatusConfirmed
+	}
+	return s
+}
+
+// convertBackendRecordTimestamp converts a backend record timestamp to an API
+// record timestamp.
+func convertBackendRecordTimestamp(rt backend.RecordTimestamp) v1.RecordTimestamp {
+	t := v1.RecordTimestamp{
+		Version:    rt.Version,
+		Digest:     hex.EncodeToString(rt.Digest),
+		Status:     convertBackendTimestampStatus(rt.Status),
+		AnchorTime: rt.AnchorTime,
+		MerklePath: rt.MerklePath,
+	}
+	if len(rt.AnchorMerkle) != 0 {
+		t.AnchorMerkle = hex.EncodeToString(rt.AnchorMerkle)
+	}
+	if rt.ChainInformation != nil {
+		t.ChainInformation = &v1.ChainInformation{
+			ChainTimestamp: rt.ChainInformation.ChainTimestamp,
+			Transaction:    rt.ChainInformation.Transaction,
+			MerkleRoot:     rt.ChainInformation.MerkleRoot,
+			MerklePath:     rt.ChainInformation.MerklePath,
+		}
+	}
+	return t
+}
+
 func convertFrontendFiles(f []v1.File) []backend.File {
 	files := make([]backend.File, 0, len(f))
 	for _, v := range f {
@@ -186,6 +304,35 @@ func (p *politeia) convertBackendRecord(br backend.Record) v1.Record {
 	return pr
 }
 
+func (p *politeia) convertBackendTombstone(bt backend.Tombstone) v1.Tombstone {
+	// Calculate censorship record signature
+	signature := p.identity.SignMessage([]byte(bt.Merkle + bt.Token))
+
+	t := v1.Tombstone{
+		CensorshipRecord: v1.CensorshipRecord{
+			Merkle:    bt.Merkle,
+			Token:     bt.Token,
+			Signature: hex.EncodeToString(signature[:]),
+		},
+		Reason:    bt.Reason,
+		Timestamp: bt.Timestamp,
+		Files:     make([]v1.File, 0, len(bt.Files)),
+	}
+	for _, v := range bt.Files {
+		t.Files = append(t.Files, v1.File{
+			Name:   v.Name,
+			MIME:   v.MIME,
+			Digest: v.Digest,
+		})
+	}
+
+	// Calculate tombstone signature
+	signature = p.identity.SignMessage(v1.TombstoneMessage(t))
+	t.Signature = hex.EncodeToString(signature[:])
+
+	return t
+}
+
 func convertBackendStatusToCache(status backend.MDStatusT) cache.RecordStatusT {
 	s := cache.RecordStatusInvalid
 	switch status {
@@ -316,8 +463,23 @@ func (p *politeia) newRecord(w http.ResponseWriter, r *http.Request) {
 
 	log.Infof("New record submitted %v", remoteAddr(r))
 
+	// Assemble the files that were uploaded in parts.
+	files, uploadIDs, err := p.resolveUploads(t.Files)
+	if err != nil {
+		if e, ok := err.(uploadError); ok {
+			log.Errorf("%v New record upload error: %v",
+				remoteAddr(r), e)
+			p.respondWithUserError(w, e.ErrorCode, e.ErrorContext)
+			return
+		}
+		errorCode := time.Now().Unix()
+		log.Errorf("%v New record upload error code %v: %v",
+			remoteAddr(r), errorCode, err)
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
 	md := convertFrontendMetadataStream(t.Metadata)
-	files := convertFrontendFiles(t.Files)
 	rm, err := p.backend.New(md, files)
 	if err != nil {
 		// Check for content error.
@@ -337,18 +499,31 @@ func (p *politeia) newRecord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update cache.
-	record := p.convertBackendRecordToCache(backend.Record{
-		RecordMetadata: *rm,
-		Version:        "1",
-		Metadata:       md,
-		Files:          files,
-	})
-	err = p.cache.NewRecord(record)
+	// Update cache.  The cache stores the payloads of all files so the
+	// uploaded files are read back from disk.
+	err = loadUploadPayloads(files)
 	if err != nil {
-		log.Criticalf("Cache new record failed %v: %v",
-			record.CensorshipRecord.Token, err)
+		log.Criticalf("Cache new record failed %v: %v", rm.Token, err)
+	} else {
+		record := p.convertBackendRecordToCache(backend.Record{
+			RecordMetadata: *rm,
+			Version:        "1",
+			Metadata:       md,
+			Files:          files,
+		})
+		err = p.cache.NewRecord(record)
+		if err != nil {
+			log.Criticalf("Cache new record failed %v: %v",
+				record.CensorshipRecord.Token, err)
+		}
 	}
+	p.uploads.Remove(uploadIDs)
+	p.recordChange(changefeed.Event{
+		Type:    changefeed.EventNewRecord,
+		Token:   rm.Token,
+		Version: "1",
+		Status:  rm.Status,
+	})
 
 	// Prepare reply.
 	signature := p.identity.SignMessage([]byte(rm.Merkle + rm.Token))
@@ -369,6 +544,152 @@ func (p *politeia) newRecord(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// resolveUploads converts the files to backend files.  Files that reference a
+// chunked upload are assembled on disk and handed to the backend by path so
+// that they are never held in memory.  It returns the identifiers of the
+// uploads that were used.  They must be removed once the files have been
+// committed.
+func (p *politeia) resolveUploads(files []v1.File) ([]backend.File, []string, error) {
+	bf := convertFrontendFiles(files)
+	ids := make([]string, 0, len(files))
+	for k, v := range files {
+		if v.Upload == "" {
+			continue
+		}
+		if v.Payload != "" {
+			return nil, nil, uploadError{
+				ErrorCode:    v1.ErrorStatusInvalidUpload,
+				ErrorContext: []string{v.Upload, "payload"},
+			}
+		}
+
+		up, path, err := p.uploads.Assemble(v.Upload)
+		if err != nil {
+			return nil, nil, err
+		}
+		if v.Name == "" {
+			bf[k].Name = up.name
+		}
+		if v.MIME == "" {
+			bf[k].MIME = up.mime
+		}
+		if v.Digest == "" {
+			bf[k].Digest = up.digest
+		}
+		bf[k].Path = path
+		ids = append(ids, v.Upload)
+	}
+	return bf, ids, nil
+}
+
+// loadUploadPayloads reads the files that were handed to the backend by path
+// back into their payloads.
+func loadUploadPayloads(files []backend.File) error {
+	for k, v := range files {
+		if v.Path == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(v.Path)
+		if err != nil {
+			return err
+		}
+		files[k].Payload = base64.StdEncoding.EncodeToString(b)
+		files[k].Path = ""
+	}
+	return nil
+}
+
+func (p *politeia) uploadInit(w http.ResponseWriter, r *http.Request) {
+	var t v1.UploadInit
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	up, err := p.uploads.Init(t.Name, t.MIME, t.Digest, t.Size)
+	if err != nil {
+		if e, ok := err.(uploadError); ok {
+			log.Errorf("%v Upload init error: %v", remoteAddr(r), e)
+			p.respondWithUserError(w, e.ErrorCode, e.ErrorContext)
+			return
+		}
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Upload init error code %v: %v", remoteAddr(r),
+			errorCode, err)
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	reply := v1.UploadInitReply{
+		Response: hex.EncodeToString(response[:]),
+		UploadID: up.id,
+		PartSize: v1.UploadPartSize,
+		Parts:    up.parts,
+		Expires:  up.expires,
+	}
+
+	log.Infof("Upload init %v: id %v size %v parts %v", remoteAddr(r),
+		up.id, up.size, up.parts)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeia) uploadPart(w http.ResponseWriter, r *http.Request) {
+	var t v1.UploadPart
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body,
+		2*v1.UploadPartSize))
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	payload, err := base64.StdEncoding.DecodeString(t.Payload)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidBase64, nil)
+		return
+	}
+
+	err = p.uploads.Put(t.UploadID, t.Index, t.Digest, payload)
+	if err != nil {
+		if e, ok := err.(uploadError); ok {
+			log.Errorf("%v Upload part error: %v", remoteAddr(r), e)
+			p.respondWithUserError(w, e.ErrorCode, e.ErrorContext)
+			return
+		}
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Upload part error code %v: %v", remoteAddr(r),
+			errorCode, err)
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	reply := v1.UploadPartReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	log.Debugf("Upload part %v: id %v index %v", remoteAddr(r),
+		t.UploadID, t.Index)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 func (p *politeia) updateRecord(w http.ResponseWriter, r *http.Request, vetted bool) {
 	cmd := "unvetted"
 	if vetted {
@@ -402,17 +723,33 @@ func (p *politeia) updateRecord(w http.ResponseWriter, r *http.Request, vetted b
 	log.Infof("Update %v record submitted %v: %x", cmd, remoteAddr(r),
 		token)
 
+	// Assemble the files that were uploaded in parts.
+	filesAdd, uploadIDs, err := p.resolveUploads(t.FilesAdd)
+	if err != nil {
+		if e, ok := err.(uploadError); ok {
+			log.Errorf("%v update %v record upload error: %v",
+				remoteAddr(r), cmd, e)
+			p.respondWithUserError(w, e.ErrorCode, e.ErrorContext)
+			return
+		}
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Update %v record upload error code %v: %v",
+			remoteAddr(r), cmd, errorCode, err)
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
 	var record *backend.Record
 	if vetted {
 		record, err = p.backend.UpdateVettedRecord(token,
 			convertFrontendMetadataStream(t.MDAppend),
 			convertFrontendMetadataStream(t.MDOverwrite),
-			convertFrontendFiles(t.FilesAdd), t.FilesDel)
+			filesAdd, t.FilesDel)
 	} else {
 		record, err = p.backend.UpdateUnvettedRecord(token,
 			convertFrontendMetadataStream(t.MDAppend),
 			convertFrontendMetadataStream(t.MDOverwrite),
-			convertFrontendFiles(t.FilesAdd), t.FilesDel)
+			filesAdd, t.FilesDel)
 	}
 	if err != nil {
 		if err == backend.ErrRecordFound {
@@ -452,7 +789,10 @@ func (p *politeia) updateRecord(w http.ResponseWriter, r *http.Request, vetted b
 		return
 	}
 
-	// Update cache.
+	p.uploads.Remove(uploadIDs)
+
+	// Update cache.  The backend returns the record with the payloads of
+	// all files, including the uploaded ones.
 	cr := p.convertBackendRecordToCache(*record)
 	if vetted {
 		// Create a new cache entry for new versions.
@@ -470,6 +810,7 @@ func (p *politeia) updateRecord(w http.ResponseWriter, r *http.Request, vetted b
 				cr.CensorshipRecord.Token, err)
 		}
 	}
+	p.recordRecordChange(changefeed.EventUpdateRecord, *record)
 
 	// Prepare reply.
 	response := p.identity.SignMessage(challenge)
@@ -511,6 +852,10 @@ func (p *politeia) updateReadme(w http.ResponseWriter, r *http.Request) {
 		Response: hex.EncodeToString(response[:]),
 	}
 
+	if !p.auditOrFail(w, r, "", v1.RecordStatusInvalid, t) {
+		return
+	}
+
 	err = p.backend.UpdateReadme(t.Content)
 	if err != nil {
 		errorCode := time.Now().Unix()
@@ -565,9 +910,14 @@ func (p *politeia) getUnvetted(w http.ResponseWriter, r *http.Request) {
 	} else {
 		reply.Record = p.convertBackendRecord(*bpr)
 
-		// Double check record bits before sending them off
+		// Double check record bits before sending them off.  Purged
+		// records no longer have files and are verified through
+		// their tombstone instead.
 		err := v1.Verify(p.identity.Public,
 			reply.Record.CensorshipRecord, reply.Record.Files)
+		if err != nil && p.isPurged(token, bpr) {
+			err = nil
+		}
 		if err != nil {
 			// Generic internal error.
 			errorCode := time.Now().Unix()
@@ -629,9 +979,14 @@ func (p *politeia) getVetted(w http.ResponseWriter, r *http.Request) {
 	} else {
 		reply.Record = p.convertBackendRecord(*bpr)
 
-		// Double check record bits before sending them off
+		// Double check record bits before sending them off.  Purged
+		// records no longer have files and are verified through
+		// their tombstone instead.
 		err := v1.Verify(p.identity.Public,
 			reply.Record.CensorshipRecord, reply.Record.Files)
+		if err != nil && p.isPurged(token, bpr) {
+			err = nil
+		}
 		if err != nil {
 			// Generic internal error.
 			errorCode := time.Now().Unix()
@@ -649,6 +1004,152 @@ func (p *politeia) getVetted(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// parseVersion parses a record version.  An empty version returns 0.
+func parseVersion(version string) (uint64, error) {
+	if version == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(version, 10, 64)
+}
+
+func (p *politeia) getVettedDiff(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetVettedDiff
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	reply := v1.GetVettedDiffReply{
+		Response: hex.EncodeToString(response[:]),
+		Files:    []v1.FileDiff{},
+		Metadata: []v1.MetadataStreamDiff{},
+	}
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Validate versions
+	oldVersion, err := parseVersion(t.OldVersion)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRecordVersion,
+			[]string{t.OldVersion})
+		return
+	}
+	newVersion, err := parseVersion(t.NewVersion)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRecordVersion,
+			[]string{t.NewVersion})
+		return
+	}
+
+	// Ask backend about the latest version of the record.
+	latest, err := p.backend.GetVetted(token, "")
+	if err == backend.ErrRecordNotFound {
+		reply.Status = v1.RecordStatusNotFound
+		log.Errorf("Get vetted diff %v: token %v not found",
+			remoteAddr(r), t.Token)
+		util.RespondWithJSON(w, http.StatusOK, reply)
+		return
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get vetted diff error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	latestVersion, err := strconv.ParseUint(latest.Version, 10, 64)
+	if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get vetted diff error code %v: invalid "+
+			"version %v", remoteAddr(r), errorCode, latest.Version)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	// Fill in the defaults and make sure the versions describe a valid
+	// range.
+	if newVersion == 0 {
+		newVersion = latestVersion
+	}
+	if oldVersion == 0 && newVersion > 1 {
+		oldVersion = newVersion - 1
+	}
+	if oldVersion < 1 || oldVersion >= newVersion ||
+		newVersion > latestVersion {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRecordVersion,
+			[]string{strconv.FormatUint(oldVersion, 10),
+				strconv.FormatUint(newVersion, 10)})
+		return
+	}
+	reply.Status = convertBackendStatus(latest.RecordMetadata.Status)
+	reply.OldVersion = strconv.FormatUint(oldVersion, 10)
+	reply.NewVersion = strconv.FormatUint(newVersion, 10)
+
+	// Retrieve both versions and diff them.
+	from, err := p.backend.GetVetted(token, reply.OldVersion)
+	if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get vetted diff error code %v: version %v: %v",
+			remoteAddr(r), errorCode, reply.OldVersion, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	to := latest
+	if newVersion != latestVersion {
+		to, err = p.backend.GetVetted(token, reply.NewVersion)
+		if err != nil {
+			// Generic internal error.
+			errorCode := time.Now().Unix()
+			log.Errorf("%v Get vetted diff error code %v: "+
+				"version %v: %v", remoteAddr(r), errorCode,
+				reply.NewVersion, err)
+
+			p.respondWithServerError(w, errorCode)
+			return
+		}
+	}
+	fd, md, err := backend.DiffRecords(from, to)
+	if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get vetted diff error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	for _, v := range fd {
+		reply.Files = append(reply.Files, convertBackendFileDiff(v))
+	}
+	for _, v := range md {
+		reply.Metadata = append(reply.Metadata,
+			convertBackendMetadataStreamDiff(v))
+	}
+
+	log.Infof("Get vetted diff %v: token %v versions %v..%v",
+		remoteAddr(r), t.Token, reply.OldVersion, reply.NewVersion)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 func (p *politeia) inventory(w http.ResponseWriter, r *http.Request) {
 	var i v1.Inventory
 	decoder := json.NewDecoder(r.Body)
@@ -698,40 +1199,152 @@ func (p *politeia) inventory(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
-func (p *politeia) check(user, pass string) bool {
-	if user != p.cfg.RPCUser || pass != p.cfg.RPCPass {
-		return false
-	}
-	return true
-}
-
-func (p *politeia) auth(fn http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		user, pass, ok := r.BasicAuth()
-		if !ok || !p.check(user, pass) {
-			log.Errorf("%v Unauthorized access for: %v",
-				remoteAddr(r), user)
-			w.Header().Set("WWW-Authenticate",
-				`Basic realm="Politeiad"`)
-			w.WriteHeader(401)
-			p.respondWithUserError(w, v1.ErrorStatusInvalidRPCCredentials, nil)
-			return
-		}
-		log.Infof("%v Authorized access for: %v",
-			remoteAddr(r), user)
-		fn(w, r)
-	}
-}
-
-func (p *politeia) setVettedStatus(w http.ResponseWriter, r *http.Request) {
-	var t v1.SetVettedStatus
+func (p *politeia) inventoryPage(w http.ResponseWriter, r *http.Request) {
+	var i v1.InventoryPage
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&t); err != nil {
+	if err := decoder.Decode(&i); err != nil {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
 		return
 	}
 
-	challenge, err := hex.DecodeString(t.Challenge)
+	challenge, err := hex.DecodeString(i.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	// Validate cursor
+	if i.Cursor != "" {
+		_, err := util.ConvertStringToken(i.Cursor)
+		if err != nil {
+			p.respondWithUserError(w, v1.ErrorStatusInvalidCursor,
+				[]string{i.Cursor})
+			return
+		}
+	}
+
+	// Validate filter
+	filter, ok := convertFrontendInventoryFilter(i.Filter)
+	if !ok {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidInventoryFilter,
+			nil)
+		return
+	}
+
+	limit := i.Limit
+	if limit == 0 || limit > v1.InventoryPageSize {
+		limit = v1.InventoryPageSize
+	}
+
+	// Ask backend for a page of the inventory
+	brs, cursor, err := p.backend.InventoryPage(backend.InventoryQuery{
+		Cursor:       i.Cursor,
+		Limit:        limit,
+		IncludeFiles: i.IncludeFiles,
+		AllVersions:  i.AllVersions,
+		Filter:       filter,
+	})
+	if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Inventory page error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	// Convert backend records
+	records := make([]v1.Record, 0, len(brs))
+	for _, v := range brs {
+		records = append(records, p.convertBackendRecord(v))
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, v1.InventoryPageReply{
+		Response: hex.EncodeToString(response[:]),
+		Records:  records,
+		Cursor:   cursor,
+	})
+}
+
+// walkInventory pages through the backend inventory that matches the query
+// and calls fn with every version of each record that is returned.  The
+// latest version is first.  Only a single page of records is held in memory
+// at a time.  The query limit sets the page size and defaults to
+// InventoryPageSize.
+func (p *politeia) walkInventory(q backend.InventoryQuery, fn func(token string, versions []backend.Record) error) error {
+	q.Cursor = ""
+	if q.Limit == 0 {
+		q.Limit = v1.InventoryPageSize
+	}
+	for {
+		records, cursor, err := p.backend.InventoryPage(q)
+		if err != nil {
+			return fmt.Errorf("InventoryPage %v: %v", q.Cursor, err)
+		}
+
+		// The older versions of a record directly follow its latest
+		// version.
+		for len(records) > 0 {
+			token := records[0].RecordMetadata.Token
+			i := 1
+			for i < len(records) && records[i].RecordMetadata.Token == token {
+				i++
+			}
+			err := fn(token, records[:i])
+			if err != nil {
+				return err
+			}
+			records = records[i:]
+		}
+
+		if cursor == "" {
+			return nil
+		}
+		q.Cursor = cursor
+	}
+}
+
+// buildCache builds the records cache from scratch.  The cache tables are
+// recreated empty and then filled in one inventory page at a time so that the
+// entire repository is never held in memory.
+func (p *politeia) buildCache() error {
+	err := p.cache.Build(nil)
+	if err != nil {
+		return err
+	}
+
+	q := backend.InventoryQuery{
+		IncludeFiles: true,
+		AllVersions:  true,
+	}
+	err = p.walkInventory(q, func(token string, versions []backend.Record) error {
+		crs := make([]cache.Record, 0, len(versions))
+		for _, r := range versions {
+			crs = append(crs, p.convertBackendRecordToCache(r))
+		}
+		return p.cache.ReplaceRecord(token, crs)
+	})
+	if err != nil {
+		// The cache tables have already been recreated so the cache
+		// is not rebuilt automatically on the next start up.
+		return fmt.Errorf("%v; the cache is incomplete and must be "+
+			"rebuilt using --buildcache", err)
+	}
+
+	return nil
+}
+
+func (p *politeia) setVettedStatus(w http.ResponseWriter, r *http.Request) {
+	var t v1.SetVettedStatus
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
 	if err != nil || len(challenge) != v1.ChallengeSize {
 		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
 		return
@@ -745,6 +1358,10 @@ func (p *politeia) setVettedStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !p.auditOrFail(w, r, t.Token, t.Status, t) {
+		return
+	}
+
 	// Ask backend to update  status
 	record, err := p.backend.SetVettedStatus(token,
 		convertFrontendStatus(t.Status),
@@ -781,6 +1398,7 @@ func (p *politeia) setVettedStatus(w http.ResponseWriter, r *http.Request) {
 		log.Criticalf("Cache set vetted status failed %v: %v",
 			cr.CensorshipRecord.Token, err)
 	}
+	p.recordRecordChange(changefeed.EventSetStatus, *record)
 
 	// Prepare reply.
 	reply := v1.SetVettedStatusReply{
@@ -816,6 +1434,10 @@ func (p *politeia) setUnvettedStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !p.auditOrFail(w, r, t.Token, t.Status, t) {
+		return
+	}
+
 	// Ask backend to update unvetted status
 	record, err := p.backend.SetUnvettedStatus(token,
 		convertFrontendStatus(t.Status),
@@ -852,6 +1474,7 @@ func (p *politeia) setUnvettedStatus(w http.ResponseWriter, r *http.Request) {
 		log.Criticalf("Cache set unvetted status failed %v: %v",
 			cr.CensorshipRecord.Token, err)
 	}
+	p.recordRecordChange(changefeed.EventSetStatus, *record)
 
 	// Prepare reply.
 	reply := v1.SetUnvettedStatusReply{
@@ -865,6 +1488,347 @@ func (p *politeia) setUnvettedStatus(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// isPurged returns true if the provided record has been purged.
+func (p *politeia) isPurged(token []byte, br *backend.Record) bool {
+	if br.RecordMetadata.Status != backend.MDStatusCensored ||
+		len(br.Files) != 0 {
+		return false
+	}
+	_, err := p.backend.GetTombstone(token)
+	return err == nil
+}
+
+func (p *politeia) purgeRecord(w http.ResponseWriter, r *http.Request) {
+	var t v1.PurgeRecord
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// A reason is mandatory
+	if strings.TrimSpace(t.Reason) == "" {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload,
+			[]string{"reason"})
+		return
+	}
+
+	if !p.auditOrFail(w, r, t.Token, v1.RecordStatusCensored, t) {
+		return
+	}
+
+	// Ask backend to purge the record
+	ts, err := p.backend.PurgeRecord(token, t.Reason)
+	if err != nil {
+		// Check for specific errors
+		switch err {
+		case backend.ErrRecordNotFound:
+			log.Errorf("%v purge record not found: %x",
+				remoteAddr(r), token)
+			p.respondWithUserError(w, v1.ErrorStatusRecordNotFound,
+				nil)
+			return
+		case backend.ErrRecordNotCensored:
+			log.Errorf("%v purge record not censored: %x",
+				remoteAddr(r), token)
+			p.respondWithUserError(w,
+				v1.ErrorStatusRecordNotCensored, nil)
+			return
+		case backend.ErrRecordPurged:
+			log.Errorf("%v purge record already purged: %x",
+				remoteAddr(r), token)
+			p.respondWithUserError(w, v1.ErrorStatusRecordPurged,
+				nil)
+			return
+		}
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Purge record error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	// Update cache.
+	record, err := p.backend.GetUnvetted(token)
+	if err == nil {
+		err = p.cache.UpdateRecord(p.convertBackendRecordToCache(*record))
+	}
+	if err != nil {
+		log.Criticalf("Cache purge record failed %v: %v", t.Token, err)
+	}
+	p.recordChange(changefeed.Event{
+		Type:   changefeed.EventPurgeRecord,
+		Token:  ts.Token,
+		Status: backend.MDStatusCensored,
+	})
+
+	// Prepare reply.
+	reply := v1.PurgeRecordReply{
+		Response:  hex.EncodeToString(response[:]),
+		Tombstone: p.convertBackendTombstone(*ts),
+	}
+
+	log.Infof("Purge record %v: token %v", remoteAddr(r), t.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeia) getTombstone(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetTombstone
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	reply := v1.GetTombstoneReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Ask backend about the censorship token.
+	ts, err := p.backend.GetTombstone(token)
+	if err == backend.ErrRecordNotFound {
+		reply.Status = v1.RecordStatusNotFound
+		log.Errorf("Get tombstone %v: token %v not found",
+			remoteAddr(r), t.Token)
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get tombstone error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	} else {
+		reply.Status = v1.RecordStatusCensored
+		reply.Tombstone = p.convertBackendTombstone(*ts)
+
+		log.Infof("Get tombstone %v: token %v", remoteAddr(r), t.Token)
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeia) getTimestamps(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetTimestamps
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	reply := v1.GetTimestampsReply{
+		Response:   hex.EncodeToString(response[:]),
+		Timestamps: []v1.RecordTimestamp{},
+	}
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Ask backend about the censorship token.
+	record, err := p.backend.GetVetted(token, "")
+	if err == backend.ErrRecordNotFound {
+		reply.Status = v1.RecordStatusNotFound
+		log.Errorf("Get timestamps %v: token %v not found",
+			remoteAddr(r), t.Token)
+		util.RespondWithJSON(w, http.StatusOK, reply)
+		return
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get timestamps error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	reply.Status = convertBackendStatus(record.RecordMetadata.Status)
+
+	rts, err := p.backend.GetTimestamps(token)
+	if err == backend.ErrNotSupported {
+		p.respondWithUserError(w, v1.ErrorStatusNotSupported, nil)
+		return
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get timestamps error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	for _, v := range rts {
+		reply.Timestamps = append(reply.Timestamps,
+			convertBackendRecordTimestamp(v))
+	}
+
+	log.Infof("Get timestamps %v: token %v", remoteAddr(r), t.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeia) getBundle(w http.ResponseWriter, r *http.Request) {
+	var t v1.GetBundle
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	reply := v1.GetBundleReply{
+		Response: hex.EncodeToString(response[:]),
+		Bundle: v1.Bundle{
+			ServerPublicKey: hex.EncodeToString(p.identity.Public.Key[:]),
+			Records:         []v1.Record{},
+			Timestamps:      []v1.RecordTimestamp{},
+			Plugins:         []v1.PluginExport{},
+		},
+	}
+
+	// Validate token
+	token, err := util.ConvertStringToken(t.Token)
+	if err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Ask backend about the censorship token.
+	record, err := p.backend.GetVetted(token, "")
+	if err == backend.ErrRecordNotFound {
+		reply.Status = v1.RecordStatusNotFound
+		log.Errorf("Get bundle %v: token %v not found",
+			remoteAddr(r), t.Token)
+		util.RespondWithJSON(w, http.StatusOK, reply)
+		return
+	} else if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get bundle error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	reply.Status = convertBackendStatus(record.RecordMetadata.Status)
+
+	// bundle collects all data that makes up the bundle.
+	bundle := func() error {
+		latest, err := parseVersion(record.Version)
+		if err != nil {
+			return err
+		}
+		for i := uint64(1); i <= latest; i++ {
+			version := strconv.FormatUint(i, 10)
+			br, err := p.backend.GetVetted(token, version)
+			if err != nil {
+				return fmt.Errorf("GetVetted %v: %v", version, err)
+			}
+			reply.Bundle.Records = append(reply.Bundle.Records,
+				p.convertBackendRecord(*br))
+		}
+
+		// Backends that do not anchor records export a bundle
+		// without anchor proofs.
+		rts, err := p.backend.GetTimestamps(token)
+		if err != nil && err != backend.ErrNotSupported {
+			return fmt.Errorf("GetTimestamps: %v", err)
+		}
+		for _, v := range rts {
+			reply.Bundle.Timestamps = append(reply.Bundle.Timestamps,
+				convertBackendRecordTimestamp(v))
+		}
+
+		plugins, err := p.backend.GetPlugins()
+		if err != nil {
+			return fmt.Errorf("GetPlugins: %v", err)
+		}
+		payload, err := json.Marshal(backend.PluginExport{
+			Token: t.Token,
+		})
+		if err != nil {
+			return err
+		}
+		for _, v := range plugins {
+			_, export, err := p.backend.Plugin(v.ID,
+				backend.PluginCmdExport, string(payload))
+			if err == backend.ErrPluginCmdInvalid {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("plugin %v export: %v", v.ID,
+					err)
+			}
+			reply.Bundle.Plugins = append(reply.Bundle.Plugins,
+				v1.PluginExport{
+					ID:      v.ID,
+					Payload: export,
+				})
+		}
+		return nil
+	}
+	err = bundle()
+	if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Get bundle error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+
+	log.Infof("Get bundle %v: token %v", remoteAddr(r), t.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 func (p *politeia) cacheUpdateVettedMetadata(token []byte) error {
 	r, err := p.backend.GetVetted(token, "")
 	if err != nil {
@@ -901,6 +1865,10 @@ func (p *politeia) updateVettedMetadata(w http.ResponseWriter, r *http.Request)
 	log.Infof("Update vetted metadata submitted %v: %x", remoteAddr(r),
 		token)
 
+	if !p.auditOrFail(w, r, t.Token, v1.RecordStatusInvalid, t) {
+		return
+	}
+
 	err = p.backend.UpdateVettedMetadata(token,
 		convertFrontendMetadataStream(t.MDAppend),
 		convertFrontendMetadataStream(t.MDOverwrite))
@@ -934,6 +1902,10 @@ func (p *politeia) updateVettedMetadata(w http.ResponseWriter, r *http.Request)
 		log.Criticalf("Cache updated vetted metadata failed %x: %v",
 			token, err)
 	}
+	p.recordChange(changefeed.Event{
+		Type:  changefeed.EventUpdateMetadata,
+		Token: hex.EncodeToString(token),
+	})
 
 	// Reply
 	reply := v1.UpdateVettedMetadataReply{
@@ -987,7 +1959,17 @@ func (p *politeia) pluginCommand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cid, payload, err := p.backend.Plugin(pc.Command, pc.Payload)
+	cid, payload, err := p.backend.Plugin(pc.ID, pc.Command, pc.Payload)
+	if err == backend.ErrPluginNotFound {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidPluginID,
+			[]string{pc.ID})
+		return
+	}
+	if err == backend.ErrPluginCmdInvalid {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidPluginCmd,
+			[]string{pc.Command})
+		return
+	}
 	if err != nil {
 		// Generic internal error.
 		errorCode := time.Now().Unix()
@@ -1000,7 +1982,7 @@ func (p *politeia) pluginCommand(w http.ResponseWriter, r *http.Request) {
 
 	// Send plugin command to cache
 	_, err = p.cache.PluginExec(cache.PluginCommand{
-		ID:             decredplugin.ID,
+		ID:             pc.ID,
 		Command:        pc.Command,
 		CommandPayload: pc.Payload,
 		ReplyPayload:   payload,
@@ -1010,6 +1992,12 @@ func (p *politeia) pluginCommand(w http.ResponseWriter, r *http.Request) {
 			"commandPayload:%v replyPayload:%v error:%v",
 			pc.Command, pc.Payload, payload, err)
 	}
+	p.recordChange(changefeed.Event{
+		Type:    changefeed.EventPluginCommand,
+		Token:   pluginCommandToken(pc.Payload),
+		Plugin:  pc.ID,
+		Command: pc.Command,
+	})
 
 	response := p.identity.SignMessage(challenge)
 	reply := v1.PluginCommandReply{
@@ -1036,7 +2024,13 @@ func logging(f http.HandlerFunc) http.HandlerFunc {
 		}))
 
 		// Log incoming connection
-		log.Infof("%v %v %v %v", remoteAddr(r), r.Method, r.URL, r.Proto)
+		if c := requestClient(r); c != "" {
+			log.Infof("%v %v %v %v client %v", remoteAddr(r), r.Method,
+				r.URL, r.Proto, c)
+		} else {
+			log.Infof("%v %v %v %v", remoteAddr(r), r.Method, r.URL,
+				r.Proto)
+		}
 		f(w, r)
 	}
 }
@@ -1049,11 +2043,15 @@ func closeBody(f http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// addRoute registers the handler for the route.  Privileged routes are
+// authenticated before the request is logged so that the log attributes the
+// call to the client.
 func (p *politeia) addRoute(method string, route string, handler http.HandlerFunc, perm permission) {
-	if perm == permissionAuth {
-		handler = p.auth(handler)
+	handler = logging(handler)
+	if perm != permissionPublic {
+		handler = p.auth(handler, perm)
 	}
-	handler = closeBody(logging(handler))
+	handler = closeBody(instrument(route, handler))
 
 	p.router.StrictSlash(true).HandleFunc(route, handler).Methods(method)
 }
@@ -1151,32 +2149,106 @@ func _main() error {
 		}
 	}
 
-	// Setup backend.
-	gitbe.UseLogger(gitbeLog)
-	b, err := gitbe.New(activeNetParams.Params, loadedCfg.DataDir,
-		loadedCfg.DcrtimeHost, "", p.identity, loadedCfg.GitTrace,
-		loadedCfg.DcrdataHost)
+	// Setup chunked uploads.
+	p.uploads, err = newUploads(filepath.Join(loadedCfg.DataDir,
+		defaultUploadsDirname), loadedCfg.MaxUploads,
+		loadedCfg.MaxUploadBytes)
 	if err != nil {
 		return err
 	}
-	p.backend = b
+
+	// Setup clients that are allowed to call privileged routes.
+	p.clients, err = newClients(loadedCfg)
+	if err != nil {
+		return err
+	}
+	if len(loadedCfg.Clients) > 0 {
+		log.Infof("Clients: %v", strings.Join(p.clients.names(), ", "))
+	}
+
+	// Setup change feed.
+	p.feed, err = changefeed.New(filepath.Join(loadedCfg.DataDir,
+		defaultChangeFeedFilename))
+	if err != nil {
+		return fmt.Errorf("change feed: %v", err)
+	}
+	log.Infof("Change feed: %v events", p.feed.Last())
+
+	// Setup audit log.
+	p.audits, err = auditlog.New(filepath.Join(loadedCfg.DataDir,
+		defaultAuditLogFilename), p.identity)
+	if err != nil {
+		return fmt.Errorf("audit log: %v", err)
+	}
+	log.Infof("Audit log: %v entries", p.audits.Last())
+
+	// Setup timestamper.
+	ts := util.NewDcrtime(loadedCfg.DcrtimeHost)
+	if loadedCfg.LocalDcrtime {
+		log.Warnf("Anchors are simulated locally and are not " +
+			"timestamped in the decred blockchain")
+		ts = util.NewLocalTimestamper(loadedCfg.LocalDcrtimeDelay)
+	}
+
+	// Setup backend.
+	switch loadedCfg.Backend {
+	case backendGit:
+		gitbe.UseLogger(gitbeLog)
+		b, err := gitbe.New(activeNetParams.Params, loadedCfg.DataDir,
+			ts, "", p.identity, loadedCfg.GitTrace)
+		if err != nil {
+			return err
+		}
+		p.backend = b
+	case backendLevelDB:
+		levelbe.UseLogger(levelbeLog)
+		b, err := levelbe.New(loadedCfg.DataDir, ts)
+		if err != nil {
+			return err
+		}
+		p.backend = b
+	case backendTlog:
+		tlogbe.UseLogger(tlogbeLog)
+		b, err := tlogbe.New(loadedCfg.DataDir, ts,
+			loadedCfg.TrillianHost, loadedCfg.TrillianKey,
+			loadedCfg.EncryptionKey, p.identity)
+		if err != nil {
+			return err
+		}
+		p.backend = b
+	default:
+		return fmt.Errorf("invalid backend: %v", loadedCfg.Backend)
+	}
 
 	// Setup cache
 	if p.cfg.EnableCache {
 		// Create a new cache context
-		cockroachdb.UseLogger(cockroachdbLog)
-		net := filepath.Base(p.cfg.DataDir)
-		db, err := cockroachdb.New(cockroachdb.UserPoliteiad, p.cfg.CacheHost,
-			net, p.cfg.CacheRootCert, p.cfg.CacheCert, p.cfg.CacheKey)
+		var (
+			db  cache.Cache
+			err error
+		)
+		switch p.cfg.CacheDB {
+		case cacheCockroachDB:
+			cockroachdb.UseLogger(cockroachdbLog)
+			net := filepath.Base(p.cfg.DataDir)
+			db, err = cockroachdb.New(cockroachdb.UserPoliteiad,
+				p.cfg.CacheHost, net, p.cfg.CacheRootCert,
+				p.cfg.CacheCert, p.cfg.CacheKey)
+		case cacheSQLite:
+			sqlite.UseLogger(sqliteLog)
+			db, err = sqlite.New(p.cfg.CacheFile, false)
+		default:
+			return fmt.Errorf("invalid cache: %v", p.cfg.CacheDB)
+		}
 		if err == cache.ErrNoVersionRecord || err == cache.ErrWrongVersion {
 			// The cache version record was either not found or
 			// is the wrong version which means that the cache
 			// needs to be built/rebuilt.
 			p.cfg.BuildCache = true
 		} else if err != nil {
-			return fmt.Errorf("cockroachdb new: %v", err)
+			return fmt.Errorf("%v new: %v", p.cfg.CacheDB, err)
 		}
-		p.cache = db
+		p.cache = cachemetrics.New(db)
 
 		// Setup the cache tables
 		err = p.cache.Setup()
@@ -1201,20 +2273,66 @@ func _main() error {
 		permissionPublic)
 	p.addRoute(http.MethodPost, v1.GetVettedRoute, p.getVetted,
 		permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetVettedDiffRoute, p.getVettedDiff,
+		permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetTimestampsRoute, p.getTimestamps,
+		permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetBundleRoute, p.getBundle,
+		permissionPublic)
+	p.addRoute(http.MethodPost, v1.GetTombstoneRoute, p.getTombstone,
+		permissionPublic)
+	p.addRoute(http.MethodPost, v1.UploadInitRoute, p.uploadInit,
+		permissionPublic)
+	p.addRoute(http.MethodPost, v1.UploadPartRoute, p.uploadPart,
+		permissionPublic)
 
-	// Routes that require auth
+	// Routes that require auth.  Clients must be granted the permission of
+	// the route.
 	p.addRoute(http.MethodPost, v1.InventoryRoute, p.inventory,
-		permissionAuth)
+		permissionInventory)
+	p.addRoute(http.MethodPost, v1.InventoryPageRoute, p.inventoryPage,
+		permissionInventory)
 	p.addRoute(http.MethodPost, v1.SetUnvettedStatusRoute,
-		p.setUnvettedStatus, permissionAuth)
+		p.setUnvettedStatus, permissionStatus)
 	p.addRoute(http.MethodPost, v1.SetVettedStatusRoute,
-		p.setVettedStatus, permissionAuth)
+		p.setVettedStatus, permissionStatus)
 	p.addRoute(http.MethodPost, v1.UpdateVettedMetadataRoute,
-		p.updateVettedMetadata, permissionAuth)
+		p.updateVettedMetadata, permissionMetadata)
 	p.addRoute(http.MethodPost, v1.UpdateReadmeRoute,
-		p.updateReadme, permissionAuth)
+		p.updateReadme, permissionAdmin)
+	p.addRoute(http.MethodPost, v1.ChangeFeedRoute, p.changeFeed,
+		permissionInventory)
+	p.addRoute(http.MethodPost, v1.AuditLogRoute, p.auditLog,
+		permissionInventory)
+	p.addRoute(http.MethodPost, v1.PurgeRecordRoute,
+		p.purgeRecord, permissionAdmin)
+	if p.cfg.EnableCache {
+		p.addRoute(http.MethodPost, v1.VerifyCacheRoute,
+			p.verifyCache, permissionAdmin)
+	}
 
 	// Setup plugins
+	settings := make(map[string][]backend.PluginSetting)
+	for _, v := range loadedCfg.PluginSettings {
+		id, s, err := parsePluginSetting(v)
+		if err != nil {
+			return err
+		}
+		settings[id] = append(settings[id], s)
+	}
+	for _, id := range loadedCfg.Plugins {
+		plugin, err := backend.NewPlugin(id, p.backend, settings[id])
+		if err == backend.ErrPluginNotFound {
+			return fmt.Errorf("unknown plugin %v, available plugins: %v",
+				id, backend.RegisteredPlugins())
+		} else if err != nil {
+			return fmt.Errorf("new plugin %v: %v", id, err)
+		}
+		err = p.backend.RegisterPlugin(*plugin)
+		if err != nil {
+			return err
+		}
+	}
 	plugins, err := p.backend.GetPlugins()
 	if err != nil {
 		return err
@@ -1222,9 +2340,9 @@ func _main() error {
 	if len(plugins) > 0 {
 		// Set plugin routes. Requires auth.
 		p.addRoute(http.MethodPost, v1.PluginCommandRoute, p.pluginCommand,
-			permissionAuth)
+			permissionPlugin)
 		p.addRoute(http.MethodPost, v1.PluginInventoryRoute, p.pluginInventory,
-			permissionAuth)
+			permissionInventory)
 
 		for _, v := range plugins {
 			// make sure we only have lowercase names
@@ -1262,23 +2380,7 @@ func _main() error {
 
 	// Build the cache
 	if p.cfg.BuildCache {
-		// Fetch all versions of all records from the inventory and
-		// use them to build the cache.
-		vetted, unvetted, err := p.backend.Inventory(0, 0, true, true)
-		if err != nil {
-			return fmt.Errorf("backend inventory: %v", err)
-		}
-
-		inv := make([]cache.Record, 0, len(vetted)+len(unvetted))
-		for _, r := range vetted {
-			inv = append(inv, p.convertBackendRecordToCache(r))
-		}
-		for _, r := range unvetted {
-			inv = append(inv, p.convertBackendRecordToCache(r))
-		}
-
-		// Build the cache
-		err = p.cache.Build(inv)
+		err = p.buildCache()
 		if err != nil {
 			return fmt.Errorf("build cache: %v", err)
 		}
@@ -1289,18 +2391,13 @@ func _main() error {
 		// entire plugin inventory into memory is only a temporary
 		// solution.
 		for _, v := range p.plugins {
-			var cmd string
-			for _, s := range v.Settings {
-				if s.Key == "inventory" {
-					cmd = s.Value
-				}
-			}
+			cmd := pluginInventoryCmd(v)
 			if cmd == "" {
 				continue
 			}
 
 			// Fetch plugin inventory
-			_, payload, err := p.backend.Plugin(cmd, "")
+			_, payload, err := p.backend.Plugin(v.ID, cmd, "")
 			if err != nil {
 				log.Errorf("Failed to get plugin data to build cache "+
 					"plugin:%v command:%v error:%v", v.ID, cmd, err)
@@ -1314,6 +2411,11 @@ func _main() error {
 		}
 	}
 
+	// Serve metrics
+	if loadedCfg.Metrics != "" {
+		serveMetrics(loadedCfg.Metrics)
+	}
+
 	// Bind to a port and pass our router in
 	listenC := make(chan error)
 	for _, listener := range loadedCfg.Listeners {
@@ -1346,6 +2448,9 @@ func _main() error {
 done:
 	p.cache.Close()
 	p.backend.Close()
+	p.uploads.Close()
+	p.feed.Close()
+	p.audits.Close()
 
 	log.Infof("Exiting")
 
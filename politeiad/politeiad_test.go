@@ -0,0 +1,384 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
+	"github.com/thi4go/politeia/politeiad/api/v1/mime"
+	"github.com/thi4go/politeia/politeiad/auditlog"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/backend/memorybe"
+	"github.com/thi4go/politeia/politeiad/cache/testcache"
+	"github.com/thi4go/politeia/politeiad/changefeed"
+	"github.com/thi4go/politeia/util"
+)
+
+// newTestPoliteia returns a politeia context that is backed by an in-memory
+// backend and a test cache, and a closure that cleans up the test environment
+// when invoked.
+func newTestPoliteia(t *testing.T) (*politeia, func()) {
+	t.Helper()
+
+	// Make a temp directory for test data. Temp directory
+	// is removed in the returned closure.
+	dataDir, err := ioutil.TempDir("", "politeiad.test")
+	if err != nil {
+		t.Fatalf("open tmp dir: %v", err)
+	}
+
+	// Setup logging
+	initLogRotator(filepath.Join(dataDir, "politeiad.test.log"))
+	setLogLevels("off")
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := politeia{
+		backend: memorybe.New(),
+		cache:   testcache.New(),
+		cfg: &config{
+			DataDir: dataDir,
+		},
+		router:   mux.NewRouter(),
+		identity: id,
+		plugins:  make(map[string]v1.Plugin),
+	}
+	p.feed, err = changefeed.New(filepath.Join(dataDir,
+		defaultChangeFeedFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.audits, err = auditlog.New(filepath.Join(dataDir,
+		defaultAuditLogFilename), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &p, func() {
+		t.Helper()
+
+		p.backend.Close()
+		p.feed.Close()
+		p.audits.Close()
+
+		err := logRotator.Close()
+		if err != nil {
+			t.Fatalf("close log rotator: %v", err)
+		}
+
+		err = os.RemoveAll(dataDir)
+		if err != nil {
+			t.Fatalf("remove tmp dir: %v", err)
+		}
+	}
+}
+
+// newTestFile returns a text file with the given name and content.
+func newTestFile(name, content string) backend.File {
+	return backend.File{
+		Name:    name,
+		MIME:    mime.DetectMimeType([]byte(content)),
+		Digest:  hex.EncodeToString(util.Digest([]byte(content))),
+		Payload: base64.StdEncoding.EncodeToString([]byte(content)),
+	}
+}
+
+// newTestRecord creates a record in the backend and returns its token.  Vetted
+// records are made public and updated until they have the requested number of
+// versions.  Every version holds a single file that names the version.
+func newTestRecord(t *testing.T, p *politeia, vetted bool, versions int) string {
+	t.Helper()
+
+	rm, err := p.backend.New(nil, []backend.File{
+		newTestFile("version_1.md", "version 1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vetted {
+		return rm.Token
+	}
+
+	token, err := hex.DecodeString(rm.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.backend.SetUnvettedStatus(token, backend.MDStatusVetted,
+		nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 2; i <= versions; i++ {
+		v := strconv.Itoa(i)
+		prev := strconv.Itoa(i - 1)
+		_, err = p.backend.UpdateVettedRecord(token, nil, nil,
+			[]backend.File{
+				newTestFile("version_"+v+".md", "version "+v),
+			}, []string{"version_" + prev + ".md"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rm.Token
+}
+
+func TestWalkInventory(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	want := map[string]int{
+		newTestRecord(t, p, false, 1): 1,
+		newTestRecord(t, p, true, 1):  1,
+		newTestRecord(t, p, true, 3):  3,
+	}
+
+	// Use a page size of one record to walk across pages
+	got := make(map[string]int)
+	q := backend.InventoryQuery{
+		Limit:       1,
+		AllVersions: true,
+	}
+	err := p.walkInventory(q, func(token string, versions []backend.Record) error {
+		if _, ok := got[token]; ok {
+			t.Errorf("record %v walked twice", token)
+		}
+		got[token] = len(versions)
+
+		// The latest version comes first
+		latest := strconv.Itoa(len(versions))
+		if versions[0].Version != latest {
+			t.Errorf("%v: got first version %v, want %v", token,
+				versions[0].Version, latest)
+		}
+		for _, v := range versions {
+			if v.RecordMetadata.Token != token {
+				t.Errorf("got record %v in versions of %v",
+					v.RecordMetadata.Token, token)
+			}
+			if len(v.Files) != 0 {
+				t.Errorf("%v: got files, want none", token)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v records, want %v", len(got), len(want))
+	}
+	for token, n := range want {
+		if got[token] != n {
+			t.Errorf("%v: got %v versions, want %v", token, got[token], n)
+		}
+	}
+}
+
+func TestBuildCache(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	unvetted := newTestRecord(t, p, false, 1)
+	vetted := newTestRecord(t, p, true, 2)
+
+	err := p.buildCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		token   string
+		version string
+		payload string
+	}{
+		{unvetted, "1", "version 1"},
+		{vetted, "1", "version 1"},
+		{vetted, "2", "version 2"},
+	}
+	for _, v := range tests {
+		r, err := p.cache.RecordVersion(v.token, v.version)
+		if err != nil {
+			t.Fatalf("%v %v: %v", v.token, v.version, err)
+		}
+		want := base64.StdEncoding.EncodeToString([]byte(v.payload))
+		if len(r.Files) != 1 || r.Files[0].Payload != want {
+			t.Errorf("%v %v: got files %v, want payload %v", v.token,
+				v.version, r.Files, v.payload)
+		}
+	}
+}
+
+// newTestRequest returns a request for the given route with the JSON encoded
+// payload as its body.
+func newTestRequest(t *testing.T, route string, payload interface{}) *http.Request {
+	t.Helper()
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewRequest(http.MethodPost, route, bytes.NewReader(b))
+}
+
+// newTestChallenge returns a hex encoded random challenge.
+func newTestChallenge(t *testing.T) string {
+	t.Helper()
+
+	challenge, err := util.Random(v1.ChallengeSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(challenge)
+}
+
+// userErrorCode returns the politeiad error code of a user error reply.
+func userErrorCode(t *testing.T, w *httptest.ResponseRecorder) v1.ErrorStatusT {
+	t.Helper()
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %v, want %v", w.Code, http.StatusBadRequest)
+	}
+	var uer v1.UserErrorReply
+	err := json.Unmarshal(w.Body.Bytes(), &uer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uer.ErrorCode
+}
+
+func TestPurgeRecordErrors(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	unvetted := newTestRecord(t, p, false, 1)
+	notFound := strings.Repeat("0", len(unvetted))
+
+	var tests = []struct {
+		name  string
+		token string
+		want  v1.ErrorStatusT
+	}{
+		{"record not found", notFound, v1.ErrorStatusRecordNotFound},
+		{"record not censored", unvetted, v1.ErrorStatusRecordNotCensored},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			r := newTestRequest(t, v1.PurgeRecordRoute, v1.PurgeRecord{
+				Challenge: newTestChallenge(t),
+				Token:     v.token,
+				Reason:    "spam",
+			})
+			w := httptest.NewRecorder()
+			p.purgeRecord(w, r)
+
+			got := userErrorCode(t, w)
+			if got != v.want {
+				t.Errorf("got error %v, want %v", v1.ErrorStatus[got],
+					v1.ErrorStatus[v.want])
+			}
+		})
+	}
+}
+
+func TestCompareCache(t *testing.T) {
+	p, cleanup := newTestPoliteia(t)
+	defer cleanup()
+
+	unvetted := newTestRecord(t, p, false, 1)
+	vetted := newTestRecord(t, p, true, 2)
+	err := p.buildCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A record with a stale status and a record that only exists in the
+	// cache
+	err = p.cache.UpdateRecordStatus(vetted, "2", 0, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := p.cache.RecordVersion(unvetted, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan := strings.Repeat("0", len(unvetted))
+	cr.CensorshipRecord.Token = orphan
+	err = p.cache.NewRecord(*cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := p.compareCache(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Records != 2 {
+		t.Errorf("got %v records, want 2", reply.Records)
+	}
+	got := make(map[string]bool)
+	for _, v := range reply.Divergences {
+		got[v.Token] = true
+	}
+	if len(got) != 2 || !got[vetted] || !got[orphan] {
+		t.Fatalf("got divergences %v, want %v and %v", reply.Divergences,
+			vetted, orphan)
+	}
+	if reply.Repaired {
+		t.Errorf("cache was repaired without asking")
+	}
+
+	reply, err = p.compareCache(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Repaired {
+		t.Errorf("cache was not repaired")
+	}
+
+	// The repaired records are read from the backend with their files
+	for _, version := range []string{"1", "2"} {
+		r, err := p.cache.RecordVersion(vetted, version)
+		if err != nil {
+			t.Fatalf("%v: %v", version, err)
+		}
+		want := base64.StdEncoding.EncodeToString([]byte("version " +
+			version))
+		if len(r.Files) != 1 || r.Files[0].Payload != want {
+			t.Errorf("%v: got files %v, want payload %v", version,
+				r.Files, want)
+		}
+	}
+	_, err = p.cache.RecordVersion(orphan, "1")
+	if err == nil {
+		t.Errorf("orphan record was not removed")
+	}
+
+	reply, err = p.compareCache(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Divergences) != 0 {
+		t.Errorf("got divergences %v after repair", reply.Divergences)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strconv"
 	"sync"
 	"testing"
@@ -349,6 +350,69 @@ func (p *TestPoliteiad) handlePluginCommand(w http.ResponseWriter, r *http.Reque
 		})
 }
 
+// handleInventoryPage returns a page of the latest version of all records
+// ordered by token.  Filters and older record versions are not supported.
+func (p *TestPoliteiad) handleInventoryPage(w http.ResponseWriter, r *http.Request) {
+	// Decode request
+	var t v1.InventoryPage
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	// Verify challenge
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	limit := t.Limit
+	if limit == 0 || limit > v1.InventoryPageSize {
+		limit = v1.InventoryPageSize
+	}
+
+	p.RLock()
+	defer p.RUnlock()
+
+	tokens := make([]string, 0, len(p.records))
+	for token := range p.records {
+		if token > t.Cursor {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Strings(tokens)
+
+	var cursor string
+	if uint(len(tokens)) > limit {
+		tokens = tokens[:limit]
+		cursor = tokens[len(tokens)-1]
+	}
+
+	records := make([]v1.Record, 0, len(tokens))
+	for _, token := range tokens {
+		record, err := p.record(token)
+		if err != nil {
+			respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+			return
+		}
+		rc := *record
+		if !t.IncludeFiles {
+			rc.Files = nil
+		}
+		records = append(records, rc)
+	}
+
+	util.RespondWithJSON(w, http.StatusOK,
+		v1.InventoryPageReply{
+			Response: hex.EncodeToString(response[:]),
+			Records:  records,
+			Cursor:   cursor,
+		})
+}
+
 func (p *TestPoliteiad) handleSetVettedStatus(w http.ResponseWriter, r *http.Request) {
 	// Decode request
 	var t v1.SetVettedStatus
@@ -497,6 +561,7 @@ func New(t *testing.T, c cache.Cache) *TestPoliteiad {
 	router.HandleFunc(v1.SetUnvettedStatusRoute, p.handleSetUnvettedStatus)
 	router.HandleFunc(v1.SetVettedStatusRoute, p.handleSetVettedStatus)
 	router.HandleFunc(v1.PluginCommandRoute, p.handlePluginCommand)
+	router.HandleFunc(v1.InventoryPageRoute, p.handleInventoryPage)
 
 	// Setup the test server
 	p.server = httptest.NewServer(router)
@@ -0,0 +1,384 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/util"
+)
+
+const (
+	// defaultUploadsDirname is the directory, relative to the politeiad
+	// data directory, where the parts of pending uploads are stored.
+	defaultUploadsDirname = "uploads"
+
+	// defaultMaxUploads is the default number of uploads that may be
+	// pending at the same time.
+	defaultMaxUploads = 16
+
+	// defaultMaxUploadBytes is the default number of bytes that all
+	// pending uploads combined may declare.
+	defaultMaxUploadBytes = 1 << 30
+
+	// uploadPruneInterval is the interval at which expired uploads are
+	// removed.
+	uploadPruneInterval = time.Minute
+
+	// uploadFilename is the name of the assembled file in the directory of
+	// an upload.
+	uploadFilename = "file"
+)
+
+// uploadError is returned when an upload request can not be honored.
+type uploadError struct {
+	ErrorCode    v1.ErrorStatusT
+	ErrorContext []string
+}
+
+func (u uploadError) Error() string {
+	return fmt.Sprintf("%v: %v", v1.ErrorStatus[u.ErrorCode], u.ErrorContext)
+}
+
+// upload describes a file that is being uploaded in parts.  The parts are
+// stored on disk as they arrive and are only assembled when the upload is
+// committed into a record.  The upload lock serializes the disk I/O of a
+// single upload so that the uploads lock does not have to be held for it.
+type upload struct {
+	sync.Mutex                 // Protects received and assembled
+	id         string          // Upload identifier
+	name       string          // Suggested filename
+	mime       string          // MIME type
+	digest     string          // SHA256 of the assembled file
+	size       int64           // Size of the assembled file
+	parts      uint            // Number of parts
+	received   map[uint]string // [index]digest of the received parts
+	expires    int64           // Unix time the upload expires
+	assembled  bool            // Parts have been assembled into a file
+}
+
+// partSize returns the expected size of the part at the provided index.  All
+// parts are UploadPartSize bytes long except for the last one.
+func (u *upload) partSize(index uint) int64 {
+	if index == u.parts-1 {
+		return u.size - int64(u.parts-1)*v1.UploadPartSize
+	}
+	return v1.UploadPartSize
+}
+
+// uploads keeps track of the pending uploads.  The number of pending uploads
+// and the number of bytes they may occupy on disk are capped since uploads
+// can be started by anyone.
+type uploads struct {
+	sync.Mutex
+	root       string             // Directory that holds the upload parts
+	maxPending int                // Maximum number of pending uploads
+	maxBytes   int64              // Maximum size of all pending uploads
+	reserved   int64              // Size of all pending uploads
+	pending    map[string]*upload // [id]upload
+	quit       chan struct{}      // Stops the pruning of expired uploads
+}
+
+// newUploads returns an uploads context that stores its parts in the
+// provided directory.  Parts of uploads that were pending when politeiad
+// last shut down are discarded.  Expired uploads are removed periodically
+// until Close is called.
+func newUploads(root string, maxPending int, maxBytes int64) (*uploads, error) {
+	err := os.RemoveAll(root)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(root, 0700)
+	if err != nil {
+		return nil, err
+	}
+	u := &uploads{
+		root:       root,
+		maxPending: maxPending,
+		maxBytes:   maxBytes,
+		pending:    make(map[string]*upload),
+		quit:       make(chan struct{}),
+	}
+	go u.pruner()
+	return u, nil
+}
+
+// pruner removes expired uploads every uploadPruneInterval so that uploads
+// that are abandoned do not occupy disk space until the next request.
+func (u *uploads) pruner() {
+	ticker := time.NewTicker(uploadPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			u.Lock()
+			u.prune()
+			u.Unlock()
+		case <-u.quit:
+			return
+		}
+	}
+}
+
+// Close stops the pruning of expired uploads.
+func (u *uploads) Close() {
+	close(u.quit)
+}
+
+// path returns the path of a single part of an upload.
+func (u *uploads) path(id string, index uint) string {
+	return filepath.Join(u.root, id, strconv.FormatUint(uint64(index), 10))
+}
+
+// prune removes all expired uploads.
+//
+// This function must be called with the lock held.
+func (u *uploads) prune() {
+	now := time.Now().Unix()
+	for id, v := range u.pending {
+		if v.expires > now {
+			continue
+		}
+		log.Debugf("upload %v expired", id)
+		u.remove(id)
+	}
+}
+
+// remove discards an upload and its parts.
+//
+// This function must be called with the lock held.
+func (u *uploads) remove(id string) {
+	if up, ok := u.pending[id]; ok {
+		u.reserved -= up.size
+	}
+	delete(u.pending, id)
+	err := os.RemoveAll(filepath.Join(u.root, id))
+	if err != nil {
+		log.Errorf("remove upload %v: %v", id, err)
+	}
+}
+
+// get returns a pending upload that has not expired.
+//
+// This function must be called with the lock held.
+func (u *uploads) get(id string) (*upload, error) {
+	up, ok := u.pending[id]
+	if !ok || up.expires <= time.Now().Unix() {
+		return nil, uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidUpload,
+			ErrorContext: []string{id},
+		}
+	}
+	return up, nil
+}
+
+// Init starts a new upload of a file with the provided digest and size.
+func (u *uploads) Init(name, mime, digest string, size int64) (*upload, error) {
+	if size <= 0 || size > v1.UploadMaxSize {
+		return nil, uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidUpload,
+			ErrorContext: []string{"size"},
+		}
+	}
+	if _, ok := util.ConvertDigest(digest); !ok {
+		return nil, uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidFileDigest,
+			ErrorContext: []string{name},
+		}
+	}
+
+	r, err := util.Random(v1.UploadIDSize)
+	if err != nil {
+		return nil, err
+	}
+	up := &upload{
+		id:       hex.EncodeToString(r),
+		name:     name,
+		mime:     mime,
+		digest:   digest,
+		size:     size,
+		parts:    uint((size + v1.UploadPartSize - 1) / v1.UploadPartSize),
+		received: make(map[uint]string),
+		expires:  time.Now().Unix() + v1.UploadExpiry,
+	}
+
+	u.Lock()
+	defer u.Unlock()
+
+	u.prune()
+	if len(u.pending) >= u.maxPending || u.reserved+size > u.maxBytes {
+		return nil, uploadError{
+			ErrorCode: v1.ErrorStatusUploadLimit,
+		}
+	}
+	err = os.MkdirAll(filepath.Join(u.root, up.id), 0700)
+	if err != nil {
+		return nil, err
+	}
+	u.pending[up.id] = up
+	u.reserved += size
+
+	return up, nil
+}
+
+// Put stores a single part of an upload.  The part must have the expected
+// size and match the provided digest.  Parts may be sent in any order and a
+// part that was already received is overwritten.
+func (u *uploads) Put(id string, index uint, digest string, payload []byte) error {
+	u.Lock()
+	up, err := u.get(id)
+	u.Unlock()
+	if err != nil {
+		return err
+	}
+
+	up.Lock()
+	defer up.Unlock()
+
+	if up.assembled {
+		return uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidUpload,
+			ErrorContext: []string{id, "assembled"},
+		}
+	}
+
+	i := strconv.FormatUint(uint64(index), 10)
+	if index >= up.parts || int64(len(payload)) != up.partSize(index) {
+		return uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidUploadPart,
+			ErrorContext: []string{i},
+		}
+	}
+	if hex.EncodeToString(util.Digest(payload)) != digest {
+		return uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidUploadPart,
+			ErrorContext: []string{i, "digest"},
+		}
+	}
+
+	err = ioutil.WriteFile(u.path(id, index), payload, 0600)
+	if err != nil {
+		return err
+	}
+	up.received[index] = digest
+
+	return nil
+}
+
+// Assemble concatenates the parts of an upload into a single file on disk and
+// returns the upload along with the path of that file.  All parts must have
+// been received.  The size and digest of the file are verified against the
+// ones that were declared when the upload was started.  An upload that fails
+// verification is discarded.  Assembling an upload that has already been
+// assembled returns the existing file.
+func (u *uploads) Assemble(id string) (*upload, string, error) {
+	u.Lock()
+	up, err := u.get(id)
+	u.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+
+	up.Lock()
+	defer up.Unlock()
+
+	path := filepath.Join(u.root, id, uploadFilename)
+	if up.assembled {
+		return up, path, nil
+	}
+	if uint(len(up.received)) != up.parts {
+		return nil, "", uploadError{
+			ErrorCode:    v1.ErrorStatusUploadIncomplete,
+			ErrorContext: []string{id},
+		}
+	}
+
+	err = u.assemble(up, path)
+	if err != nil {
+		// The parts are removed while assembling so the upload can
+		// not be retried.
+		u.Lock()
+		u.remove(id)
+		u.Unlock()
+		return nil, "", err
+	}
+	up.assembled = true
+
+	return up, path, nil
+}
+
+// assemble writes the parts of an upload to the provided path and verifies
+// the result.  Parts are removed once they have been copied so that an
+// upload does not occupy twice its size on disk.
+//
+// This function must be called with the upload lock held.
+func (u *uploads) assemble(up *upload, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	var size int64
+	for i := uint(0); i < up.parts; i++ {
+		part, err := os.Open(u.path(up.id, i))
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(w, part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+		size += n
+
+		err = os.Remove(u.path(up.id, i))
+		if err != nil {
+			return err
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	if size != up.size {
+		return uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidUpload,
+			ErrorContext: []string{up.id, "size"},
+		}
+	}
+	if hex.EncodeToString(h.Sum(nil)) != up.digest {
+		return uploadError{
+			ErrorCode:    v1.ErrorStatusInvalidFileDigest,
+			ErrorContext: []string{up.name},
+		}
+	}
+
+	return nil
+}
+
+// Remove discards the provided uploads.  It is called once the uploads have
+// been committed into a record.
+func (u *uploads) Remove(ids []string) {
+	u.Lock()
+	defer u.Unlock()
+
+	for _, id := range ids {
+		u.remove(id)
+	}
+}
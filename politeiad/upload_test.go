@@ -0,0 +1,427 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/util"
+)
+
+// newTestUploads returns an uploads context that stores its parts in the data
+// directory of a test politeia context and a closure that cleans up the test
+// environment when invoked.
+func newTestUploads(t *testing.T, maxPending int, maxBytes int64) (*uploads, func()) {
+	t.Helper()
+
+	p, cleanup := newTestPoliteia(t)
+	u, err := newUploads(filepath.Join(p.cfg.DataDir, defaultUploadsDirname),
+		maxPending, maxBytes)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return u, func() {
+		t.Helper()
+
+		u.Close()
+		cleanup()
+	}
+}
+
+// newTestPayload returns a random payload of the provided size.
+func newTestPayload(t *testing.T, size int64) []byte {
+	t.Helper()
+
+	b, err := util.Random(int(size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// digestHex returns the hex encoded SHA256 digest of b.
+func digestHex(b []byte) string {
+	return hex.EncodeToString(util.Digest(b))
+}
+
+// part returns the part of the payload at the provided index.
+func part(payload []byte, index uint) []byte {
+	start := int64(index) * v1.UploadPartSize
+	end := start + v1.UploadPartSize
+	if end > int64(len(payload)) {
+		end = int64(len(payload))
+	}
+	return payload[start:end]
+}
+
+// putParts uploads all parts of the payload in the order of the provided
+// indexes.
+func putParts(t *testing.T, u *uploads, id string, payload []byte, indexes ...uint) {
+	t.Helper()
+
+	for _, i := range indexes {
+		p := part(payload, i)
+		err := u.Put(id, i, digestHex(p), p)
+		if err != nil {
+			t.Fatalf("put part %v: %v", i, err)
+		}
+	}
+}
+
+// uploadErrorCode returns the error code of an upload error.
+func uploadErrorCode(t *testing.T, err error) v1.ErrorStatusT {
+	t.Helper()
+
+	e, ok := err.(uploadError)
+	if !ok {
+		t.Fatalf("got error %v, want upload error", err)
+	}
+	return e.ErrorCode
+}
+
+func TestUploadAssemble(t *testing.T) {
+	u, cleanup := newTestUploads(t, defaultMaxUploads, defaultMaxUploadBytes)
+	defer cleanup()
+
+	payload := newTestPayload(t, 2*v1.UploadPartSize+v1.UploadPartSize/2)
+	up, err := u.Init("file.bin", "application/octet-stream",
+		digestHex(payload), int64(len(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.parts != 3 {
+		t.Fatalf("got %v parts, want 3", up.parts)
+	}
+
+	// Parts may arrive in any order and may be sent more than once
+	putParts(t, u, up.id, payload, 2, 0, 2, 1)
+
+	_, path, err := u.Assemble(up.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, payload) {
+		t.Fatalf("assembled file does not match the payload")
+	}
+
+	// The parts are gone once the file has been assembled
+	for i := uint(0); i < up.parts; i++ {
+		if _, err := os.Stat(u.path(up.id, i)); !os.IsNotExist(err) {
+			t.Errorf("part %v was not removed: %v", i, err)
+		}
+	}
+
+	// Assembling again returns the same file
+	_, again, err := u.Assemble(up.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != path {
+		t.Errorf("got path %v, want %v", again, path)
+	}
+
+	// No parts are accepted after assembling
+	p := part(payload, 0)
+	err = u.Put(up.id, 0, digestHex(p), p)
+	if got := uploadErrorCode(t, err); got != v1.ErrorStatusInvalidUpload {
+		t.Errorf("got error %v, want %v", v1.ErrorStatus[got],
+			v1.ErrorStatus[v1.ErrorStatusInvalidUpload])
+	}
+
+	// Removing the upload releases its space
+	u.Remove([]string{up.id})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("assembled file was not removed: %v", err)
+	}
+	if u.reserved != 0 {
+		t.Errorf("got %v reserved bytes, want 0", u.reserved)
+	}
+}
+
+func TestUploadConcurrent(t *testing.T) {
+	u, cleanup := newTestUploads(t, defaultMaxUploads, defaultMaxUploadBytes)
+	defer cleanup()
+
+	payloads := make(map[string][]byte)
+	for i := 0; i < 3; i++ {
+		payload := newTestPayload(t, 3*v1.UploadPartSize)
+		up, err := u.Init("file.bin", "application/octet-stream",
+			digestHex(payload), int64(len(payload)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		payloads[up.id] = payload
+	}
+
+	// Parts of different uploads and of the same upload are put at the
+	// same time
+	var wg sync.WaitGroup
+	errC := make(chan error, 3*len(payloads))
+	for id, payload := range payloads {
+		for i := uint(0); i < 3; i++ {
+			wg.Add(1)
+			go func(id string, payload []byte, i uint) {
+				defer wg.Done()
+				p := part(payload, i)
+				errC <- u.Put(id, i, digestHex(p), p)
+			}(id, payload, i)
+		}
+	}
+	wg.Wait()
+	close(errC)
+	for err := range errC {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Uploads are assembled at the same time
+	errC = make(chan error, len(payloads))
+	for id, payload := range payloads {
+		wg.Add(1)
+		go func(id string, payload []byte) {
+			defer wg.Done()
+			_, path, err := u.Assemble(id)
+			if err != nil {
+				errC <- err
+				return
+			}
+			b, err := ioutil.ReadFile(path)
+			if err == nil && !bytes.Equal(b, payload) {
+				err = fmt.Errorf("upload %v does not match", id)
+			}
+			errC <- err
+		}(id, payload)
+	}
+	wg.Wait()
+	close(errC)
+	for err := range errC {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUploadPutErrors(t *testing.T) {
+	u, cleanup := newTestUploads(t, defaultMaxUploads, defaultMaxUploadBytes)
+	defer cleanup()
+
+	payload := newTestPayload(t, v1.UploadPartSize+1)
+	up, err := u.Init("file.bin", "application/octet-stream",
+		digestHex(payload), int64(len(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := part(payload, 0)
+	last := part(payload, 1)
+
+	var tests = []struct {
+		name    string
+		id      string
+		index   uint
+		digest  string
+		payload []byte
+		want    v1.ErrorStatusT
+	}{
+		{"unknown upload", "00", 0, digestHex(first), first,
+			v1.ErrorStatusInvalidUpload},
+		{"index out of range", up.id, 2, digestHex(last), last,
+			v1.ErrorStatusInvalidUploadPart},
+		{"short part", up.id, 0, digestHex(last), last,
+			v1.ErrorStatusInvalidUploadPart},
+		{"long last part", up.id, 1, digestHex(first), first,
+			v1.ErrorStatusInvalidUploadPart},
+		{"digest mismatch", up.id, 0, digestHex(last), first,
+			v1.ErrorStatusInvalidUploadPart},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			err := u.Put(v.id, v.index, v.digest, v.payload)
+			got := uploadErrorCode(t, err)
+			if got != v.want {
+				t.Errorf("got error %v, want %v",
+					v1.ErrorStatus[got], v1.ErrorStatus[v.want])
+			}
+		})
+	}
+	if len(up.received) != 0 {
+		t.Errorf("got %v received parts, want 0", len(up.received))
+	}
+}
+
+func TestUploadAssembleErrors(t *testing.T) {
+	u, cleanup := newTestUploads(t, defaultMaxUploads, defaultMaxUploadBytes)
+	defer cleanup()
+
+	payload := newTestPayload(t, v1.UploadPartSize+1)
+	size := int64(len(payload))
+
+	var tests = []struct {
+		name   string
+		digest string
+		setup  func(t *testing.T, id string)
+		want   v1.ErrorStatusT
+	}{
+		{"incomplete", digestHex(payload),
+			func(t *testing.T, id string) {
+				putParts(t, u, id, payload, 1)
+			}, v1.ErrorStatusUploadIncomplete},
+
+		{"size mismatch", digestHex(payload),
+			func(t *testing.T, id string) {
+				putParts(t, u, id, payload, 0, 1)
+
+				// Corrupt a part after it has been verified
+				err := os.Truncate(u.path(id, 0), 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}, v1.ErrorStatusInvalidUpload},
+
+		{"digest mismatch", digestHex([]byte("other")),
+			func(t *testing.T, id string) {
+				putParts(t, u, id, payload, 0, 1)
+			}, v1.ErrorStatusInvalidFileDigest},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			up, err := u.Init("file.bin", "application/octet-stream",
+				v.digest, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v.setup(t, up.id)
+
+			_, _, err = u.Assemble(up.id)
+			got := uploadErrorCode(t, err)
+			if got != v.want {
+				t.Fatalf("got error %v, want %v",
+					v1.ErrorStatus[got], v1.ErrorStatus[v.want])
+			}
+			if got == v1.ErrorStatusUploadIncomplete {
+				u.Remove([]string{up.id})
+				return
+			}
+
+			// Uploads that fail verification are discarded
+			if _, ok := u.pending[up.id]; ok {
+				t.Errorf("upload was not discarded")
+			}
+			_, err = os.Stat(filepath.Join(u.root, up.id))
+			if !os.IsNotExist(err) {
+				t.Errorf("upload directory was not removed: %v", err)
+			}
+		})
+	}
+	if u.reserved != 0 {
+		t.Errorf("got %v reserved bytes, want 0", u.reserved)
+	}
+}
+
+func TestUploadExpiry(t *testing.T) {
+	u, cleanup := newTestUploads(t, defaultMaxUploads, defaultMaxUploadBytes)
+	defer cleanup()
+
+	payload := newTestPayload(t, 1)
+	expired, err := u.Init("expired.bin", "application/octet-stream",
+		digestHex(payload), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := u.Init("live.bin", "application/octet-stream",
+		digestHex(payload), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putParts(t, u, expired.id, payload, 0)
+	expired.expires = time.Now().Unix() - 1
+
+	// Expired uploads can not be used even before they are pruned
+	err = u.Put(expired.id, 0, digestHex(payload), payload)
+	if got := uploadErrorCode(t, err); got != v1.ErrorStatusInvalidUpload {
+		t.Errorf("got error %v, want %v", v1.ErrorStatus[got],
+			v1.ErrorStatus[v1.ErrorStatusInvalidUpload])
+	}
+
+	u.Lock()
+	u.prune()
+	u.Unlock()
+
+	if _, ok := u.pending[expired.id]; ok {
+		t.Errorf("expired upload was not pruned")
+	}
+	_, err = os.Stat(filepath.Join(u.root, expired.id))
+	if !os.IsNotExist(err) {
+		t.Errorf("expired upload directory was not removed: %v", err)
+	}
+	if _, ok := u.pending[live.id]; !ok {
+		t.Errorf("live upload was pruned")
+	}
+	if u.reserved != live.size {
+		t.Errorf("got %v reserved bytes, want %v", u.reserved, live.size)
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	payload := newTestPayload(t, 1)
+	digest := digestHex(payload)
+
+	var tests = []struct {
+		name       string
+		maxPending int
+		maxBytes   int64
+		sizes      []int64 // Sizes of the uploads that are accepted
+		size       int64   // Size of the upload that is rejected
+	}{
+		{"too many uploads", 2, defaultMaxUploadBytes, []int64{1, 1}, 1},
+		{"too many bytes", defaultMaxUploads, 10, []int64{4, 4}, 3},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			u, cleanup := newTestUploads(t, v.maxPending, v.maxBytes)
+			defer cleanup()
+
+			var ids []string
+			for _, size := range v.sizes {
+				up, err := u.Init("file.bin", "application/octet-stream",
+					digest, size)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, up.id)
+			}
+
+			_, err := u.Init("file.bin", "application/octet-stream",
+				digest, v.size)
+			got := uploadErrorCode(t, err)
+			if got != v1.ErrorStatusUploadLimit {
+				t.Fatalf("got error %v, want %v", v1.ErrorStatus[got],
+					v1.ErrorStatus[v1.ErrorStatusUploadLimit])
+			}
+
+			// Space is released once an upload is removed
+			u.Remove(ids[:1])
+			_, err = u.Init("file.bin", "application/octet-stream",
+				digest, v.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	v1 "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/backend"
+	"github.com/thi4go/politeia/politeiad/cache"
+	"github.com/thi4go/politeia/util"
+)
+
+// pluginInventoryCmd returns the plugin command that returns the inventory
+// that is used to build the cache of a plugin.  An empty string is returned
+// if the plugin does not keep data in the cache.
+func pluginInventoryCmd(p v1.Plugin) string {
+	var cmd string
+	for _, s := range p.Settings {
+		if s.Key == "inventory" {
+			cmd = s.Value
+		}
+	}
+	return cmd
+}
+
+// verifyCacheRecord compares all versions of a record in the cache against
+// the backend and returns a description of every difference.  The backend
+// versions do not include files.  The file digests are covered by the merkle
+// root of the censorship record, the files are therefore not compared.
+func (p *politeia) verifyCacheRecord(token string, versions []cache.Record) ([]string, error) {
+	reasons := make([]string, 0)
+	for _, v := range versions {
+		cr, err := p.cache.RecordVersion(token, v.Version)
+		if err == cache.ErrRecordNotFound {
+			reasons = append(reasons, fmt.Sprintf("version %v: missing",
+				v.Version))
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("cache record version %v %v: %v",
+				token, v.Version, err)
+		}
+		cr.Files = nil
+		for _, d := range cache.CompareRecords(v, *cr) {
+			reasons = append(reasons, fmt.Sprintf("version %v: %v",
+				v.Version, d))
+		}
+	}
+
+	// The cache must not contain versions that the backend does not
+	// know about.
+	latest := versions[len(versions)-1]
+	cr, err := p.cache.Record(token)
+	if err != nil {
+		// The missing versions were reported above.
+		if err == cache.ErrRecordNotFound {
+			return reasons, nil
+		}
+		return nil, fmt.Errorf("cache record %v: %v", token, err)
+	}
+	if cr.Version != latest.Version {
+		reasons = append(reasons, fmt.Sprintf("latest version: got %v, "+
+			"want %v", cr.Version, latest.Version))
+	}
+
+	return reasons, nil
+}
+
+// backendRecordVersions returns all versions of a record, including their
+// files, ordered from the oldest to the latest version.  Nil is returned if
+// the record does not exist in the backend.
+func (p *politeia) backendRecordVersions(token string) ([]cache.Record, error) {
+	t, err := util.ConvertStringToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := p.backend.GetVetted(t, "")
+	if err == backend.ErrRecordNotFound {
+		r, err = p.backend.GetUnvetted(t)
+		if err == backend.ErrRecordNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("GetUnvetted: %v", err)
+		}
+		return []cache.Record{p.convertBackendRecordToCache(*r)}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("GetVetted: %v", err)
+	}
+
+	latest, err := parseVersion(r.Version)
+	if err != nil {
+		return nil, err
+	}
+	crs := make([]cache.Record, 0, latest)
+	for i := uint64(1); i < latest; i++ {
+		version := strconv.FormatUint(i, 10)
+		br, err := p.backend.GetVetted(t, version)
+		if err != nil {
+			return nil, fmt.Errorf("GetVetted %v: %v", version, err)
+		}
+		crs = append(crs, p.convertBackendRecordToCache(*br))
+	}
+	return append(crs, p.convertBackendRecordToCache(*r)), nil
+}
+
+// compareCache compares the cache against the backend and, if repair is set,
+// rebuilds the divergent records.  The backend inventory is read one page at
+// a time without files.  Divergent records are read again from the backend
+// when they are repaired so that a record that changed while the cache was
+// being verified is not reverted to the state it had during the comparison.
+func (p *politeia) compareCache(repair bool) (*v1.VerifyCacheReply, error) {
+	// Compare the records
+	records := make(map[string]struct{})  // [token]
+	diverged := make(map[string][]string) // [token]reasons
+	q := backend.InventoryQuery{
+		AllVersions: true,
+	}
+	err := p.walkInventory(q, func(token string, versions []backend.Record) error {
+		// The latest version comes first.
+		crs := make([]cache.Record, len(versions))
+		for i, r := range versions {
+			crs[len(versions)-1-i] = p.convertBackendRecordToCache(r)
+		}
+		records[token] = struct{}{}
+
+		reasons, err := p.verifyCacheRecord(token, crs)
+		if err != nil {
+			return err
+		}
+		if len(reasons) > 0 {
+			diverged[token] = reasons
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("backend inventory: %v", err)
+	}
+
+	// Records that only exist in the cache
+	inv, err := p.cache.Inventory()
+	if err != nil {
+		return nil, fmt.Errorf("cache inventory: %v", err)
+	}
+	for _, v := range inv {
+		token := v.CensorshipRecord.Token
+		if _, ok := records[token]; !ok {
+			diverged[token] = []string{"not found in backend"}
+		}
+	}
+
+	// Compare the plugin data
+	pluginIDs := make([]string, 0, len(p.plugins))
+	for k := range p.plugins {
+		pluginIDs = append(pluginIDs, k)
+	}
+	sort.Strings(pluginIDs)
+	payloads := make(map[string]string)                    // [pluginID]payload
+	pluginDiverged := make(map[string]map[string][]string) // [pluginID][token]reasons
+	for _, id := range pluginIDs {
+		cmd := pluginInventoryCmd(p.plugins[id])
+		if cmd == "" {
+			continue
+		}
+		_, payload, err := p.backend.Plugin(id, cmd, "")
+		if err != nil {
+			return nil, fmt.Errorf("plugin %v inventory: %v", id, err)
+		}
+		d, err := p.cache.PluginVerify(id, payload)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %v verify: %v", id, err)
+		}
+		payloads[id] = payload
+		pluginDiverged[id] = d
+	}
+
+	// Prepare reply
+	reply := v1.VerifyCacheReply{
+		Records:     len(records),
+		Divergences: make([]v1.CacheDivergence, 0, len(diverged)),
+	}
+	tokens := make([]string, 0, len(diverged))
+	for k := range diverged {
+		tokens = append(tokens, k)
+	}
+	sort.Strings(tokens)
+	for _, v := range tokens {
+		reply.Divergences = append(reply.Divergences, v1.CacheDivergence{
+			Token:   v,
+			Reasons: diverged[v],
+		})
+	}
+	for _, id := range pluginIDs {
+		pt := make([]string, 0, len(pluginDiverged[id]))
+		for k := range pluginDiverged[id] {
+			pt = append(pt, k)
+		}
+		sort.Strings(pt)
+		for _, v := range pt {
+			reply.Divergences = append(reply.Divergences,
+				v1.CacheDivergence{
+					Token:   v,
+					Plugin:  id,
+					Reasons: pluginDiverged[id][v],
+				})
+		}
+	}
+
+	if !repair || len(reply.Divergences) == 0 {
+		return &reply, nil
+	}
+
+	// Repair the divergent records.  Records that no longer exist in
+	// the backend are removed from the cache.
+	for _, v := range tokens {
+		crs, err := p.backendRecordVersions(v)
+		if err != nil {
+			return nil, fmt.Errorf("backend record %v: %v", v, err)
+		}
+		err = p.cache.ReplaceRecord(v, crs)
+		if err != nil {
+			return nil, fmt.Errorf("replace record %v: %v", v, err)
+		}
+		log.Infof("Cache record repaired: %v", v)
+	}
+	for _, id := range pluginIDs {
+		if len(pluginDiverged[id]) == 0 {
+			continue
+		}
+		pt := make([]string, 0, len(pluginDiverged[id]))
+		for k := range pluginDiverged[id] {
+			pt = append(pt, k)
+		}
+		err := p.cache.PluginRepair(id, pt, payloads[id])
+		if err != nil {
+			return nil, fmt.Errorf("plugin %v repair: %v", id, err)
+		}
+		log.Infof("Cache plugin %v repaired: %v records", id, len(pt))
+	}
+	reply.Repaired = true
+
+	return &reply, nil
+}
+
+func (p *politeia) verifyCache(w http.ResponseWriter, r *http.Request) {
+	var t v1.VerifyCache
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&t); err != nil {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidRequestPayload, nil)
+		return
+	}
+
+	challenge, err := hex.DecodeString(t.Challenge)
+	if err != nil || len(challenge) != v1.ChallengeSize {
+		p.respondWithUserError(w, v1.ErrorStatusInvalidChallenge, nil)
+		return
+	}
+	response := p.identity.SignMessage(challenge)
+
+	reply, err := p.compareCache(t.Repair)
+	if err != nil {
+		// Generic internal error.
+		errorCode := time.Now().Unix()
+		log.Errorf("%v Verify cache error code %v: %v",
+			remoteAddr(r), errorCode, err)
+
+		p.respondWithServerError(w, errorCode)
+		return
+	}
+	reply.Response = hex.EncodeToString(response[:])
+
+	log.Infof("Verify cache %v: %v records, %v divergences, repaired %v",
+		remoteAddr(r), reply.Records, len(reply.Divergences),
+		reply.Repaired)
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
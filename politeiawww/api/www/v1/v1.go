@@ -5,8 +5,10 @@
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
 
+	"github.com/decred/dcrtime/merkle"
 	"github.com/thi4go/politeia/decredplugin"
 )
 
@@ -16,6 +18,11 @@ type PropStatusT int
 type PropVoteStatusT int
 type UserManageActionT int
 type EmailNotificationT int
+type DiffActionT int
+type TimestampStatusT int
+type WebhookDeliveryStatusT int
+type EmailDigestFrequencyT int
+type FollowActionT int
 
 const (
 	PoliteiaWWWAPIVersion = 1 // API version this backend understands
@@ -46,7 +53,13 @@ const (
 	RouteUserPaymentsRescan       = "/user/payments/rescan"
 	RouteManageUser               = "/user/manage"
 	RouteEditUser                 = "/user/edit"
+	RouteUserFollows              = "/user/follows"
 	RouteUsers                    = "/users"
+	RouteWebhooks                 = "/webhooks"
+	RouteNewWebhook               = "/webhooks/new"
+	RouteDeleteWebhook            = "/webhooks/delete"
+	RouteWebhookDeliveries        = "/webhooks/deliveries"
+	RouteFailedEmails             = "/emails/failed"
 	RouteTokenInventory           = "/proposals/tokeninventory"
 	RouteBatchProposals           = "/proposals/batch"
 	RouteBatchVoteSummary         = "/proposals/batchvotesummary"
@@ -65,6 +78,9 @@ const (
 	RouteCommentsGet              = "/proposals/{token:[A-z0-9]{64}}/comments"
 	RouteVoteResults              = "/proposals/{token:[A-z0-9]{64}}/votes"
 	RouteVoteStatus               = "/proposals/{token:[A-z0-9]{64}}/votestatus"
+	RouteProposalDiff             = "/proposals/{token:[A-z0-9]{64}}/diff"
+	RouteProposalTimestamps       = "/proposals/{token:[A-z0-9]{64}}/timestamps"
+	RouteProposalBundle           = "/proposals/{token:[A-z0-9]{64}}/bundle"
 	RouteNewComment               = "/comments/new"
 	RouteLikeComment              = "/comments/like"
 	RouteCensorComment            = "/comments/censor"
@@ -94,6 +110,15 @@ const (
 	// accepted when creating a new proposal
 	PolicyMaxMDSize = 512 * 1024
 
+	// PolicyMaxAttachments is the maximum number of attachments, files
+	// that are neither images nor markdown, accepted when creating a new
+	// proposal
+	PolicyMaxAttachments = 5
+
+	// PolicyMaxAttachmentSize is the maximum attachment file size (in
+	// bytes) accepted when creating a new proposal
+	PolicyMaxAttachmentSize = 2 * 1024 * 1024
+
 	// PolicyMinPasswordLength is the minimum number of characters
 	// accepted for user passwords
 	PolicyMinPasswordLength = 8
@@ -188,6 +213,15 @@ const (
 	ErrorStatusInvalidLogin                ErrorStatusT = 63
 	ErrorStatusCommentIsCensored           ErrorStatusT = 64
 	ErrorStatusInvalidProposalVersion      ErrorStatusT = 65
+	ErrorStatusMaxAttachmentsExceeded      ErrorStatusT = 66
+	ErrorStatusMaxAttachmentSizeExceeded   ErrorStatusT = 67
+	ErrorStatusMalformedFile               ErrorStatusT = 68
+	ErrorStatusInvalidWebhookURL           ErrorStatusT = 69
+	ErrorStatusInvalidWebhookEvent         ErrorStatusT = 70
+	ErrorStatusInvalidWebhookSecret        ErrorStatusT = 71
+	ErrorStatusWebhookNotFound             ErrorStatusT = 72
+	ErrorStatusInvalidEmailDigestFrequency ErrorStatusT = 73
+	ErrorStatusInvalidFollowAction         ErrorStatusT = 74
 
 	// Proposal state codes
 	//
@@ -232,6 +266,18 @@ const (
 	UserManageDeactivate                      UserManageActionT = 6
 	UserManageReactivate                      UserManageActionT = 7
 
+	// Proposal diff actions
+	DiffActionInvalid  DiffActionT = 0 // Invalid action
+	DiffActionAdded    DiffActionT = 1 // Only present in the new version
+	DiffActionRemoved  DiffActionT = 2 // Only present in the old version
+	DiffActionModified DiffActionT = 3 // Present in both but different
+
+	// Proposal timestamp status codes
+	TimestampStatusInvalid     TimestampStatusT = 0 // Invalid status
+	TimestampStatusNotAnchored TimestampStatusT = 1 // Not anchored yet
+	TimestampStatusUnconfirmed TimestampStatusT = 2 // Anchor not confirmed
+	TimestampStatusConfirmed   TimestampStatusT = 3 // Anchor confirmed
+
 	// Email notification types
 	NotificationEmailMyProposalStatusChange      EmailNotificationT = 1 << 0
 	NotificationEmailMyProposalVoteStarted       EmailNotificationT = 1 << 1
@@ -242,6 +288,18 @@ const (
 	NotificationEmailAdminProposalVoteAuthorized EmailNotificationT = 1 << 6
 	NotificationEmailCommentOnMyProposal         EmailNotificationT = 1 << 7
 	NotificationEmailCommentOnMyComment          EmailNotificationT = 1 << 8
+	NotificationEmailFollowedProposal            EmailNotificationT = 1 << 9
+
+	// Email digest frequencies. Notifications that a user has moved to the
+	// email digest are batched and sent at this frequency.
+	EmailDigestFrequencyInvalid EmailDigestFrequencyT = 0 // Invalid frequency
+	EmailDigestFrequencyDaily   EmailDigestFrequencyT = 1 // Once a day
+	EmailDigestFrequencyWeekly  EmailDigestFrequencyT = 2 // Once a week
+
+	// Proposal follow actions
+	FollowActionInvalid  FollowActionT = 0 // Invalid action
+	FollowActionFollow   FollowActionT = 1 // Follow a proposal
+	FollowActionUnfollow FollowActionT = 2 // Unfollow a proposal
 )
 
 var (
@@ -329,6 +387,15 @@ var (
 		ErrorStatusInvalidLogin:                "invalid login credentials",
 		ErrorStatusCommentIsCensored:           "comment is censored",
 		ErrorStatusInvalidProposalVersion:      "invalid proposal version",
+		ErrorStatusMaxAttachmentsExceeded:      "maximum attachment files exceeded",
+		ErrorStatusMaxAttachmentSizeExceeded:   "maximum attachment file size exceeded",
+		ErrorStatusMalformedFile:               "malformed file",
+		ErrorStatusInvalidWebhookURL:           "invalid webhook url",
+		ErrorStatusInvalidWebhookEvent:         "invalid webhook event",
+		ErrorStatusInvalidWebhookSecret:        "invalid webhook secret",
+		ErrorStatusWebhookNotFound:             "webhook not found",
+		ErrorStatusInvalidEmailDigestFrequency: "invalid email digest frequency",
+		ErrorStatusInvalidFollowAction:         "invalid follow action",
 	}
 
 	// PropStatus converts propsal status codes to human readable text
@@ -362,6 +429,22 @@ var (
 		UserManageDeactivate:                      "deactivate user",
 		UserManageReactivate:                      "reactivate user",
 	}
+
+	// DiffAction converts diff actions to human readable text
+	DiffAction = map[DiffActionT]string{
+		DiffActionInvalid:  "invalid",
+		DiffActionAdded:    "added",
+		DiffActionRemoved:  "removed",
+		DiffActionModified: "modified",
+	}
+
+	// TimestampStatus converts timestamp status codes to human readable text
+	TimestampStatus = map[TimestampStatusT]string{
+		TimestampStatusInvalid:     "invalid",
+		TimestampStatusNotAnchored: "not anchored",
+		TimestampStatusUnconfirmed: "unconfirmed",
+		TimestampStatusConfirmed:   "confirmed",
+	}
 )
 
 // File describes an individual file that is part of the proposal.  The
@@ -764,6 +847,92 @@ type ProposalDetailsReply struct {
 	Proposal ProposalRecord `json:"proposal"`
 }
 
+// ProposalDiff is used to retrieve the changes between two versions of a
+// public proposal.  NewVersion defaults to the latest version and OldVersion
+// defaults to the version that precedes NewVersion.
+type ProposalDiff struct {
+	Token      string `json:"token"`                // Censorship token
+	OldVersion string `json:"oldversion,omitempty"` // Old proposal version
+	NewVersion string `json:"newversion,omitempty"` // New proposal version
+}
+
+// FileDiff describes how a single proposal file changed.  Text files carry a
+// unified diff, binary files are only described by their digests.
+type FileDiff struct {
+	Name      string      `json:"name"`                // Filename
+	Action    DiffActionT `json:"action"`              // How the file changed
+	MIME      string      `json:"mime"`                // Mime type
+	OldDigest string      `json:"olddigest,omitempty"` // Old payload digest
+	NewDigest string      `json:"newdigest,omitempty"` // New payload digest
+	Diff      string      `json:"diff,omitempty"`      // Unified diff
+}
+
+// MetadataStreamDiff describes how a single proposal metadata stream changed.
+type MetadataStreamDiff struct {
+	ID     uint64      `json:"id"`     // Stream identity
+	Action DiffActionT `json:"action"` // How the stream changed
+	Diff   string      `json:"diff"`   // Unified diff
+}
+
+// ProposalDiffReply is used to reply to a ProposalDiff command.  Unchanged
+// files and metadata streams are omitted.
+type ProposalDiffReply struct {
+	OldVersion string               `json:"oldversion"` // Old proposal version
+	NewVersion string               `json:"newversion"` // New proposal version
+	Files      []FileDiff           `json:"files"`      // Changed files
+	Metadata   []MetadataStreamDiff `json:"metadata"`   // Changed streams
+}
+
+// ProposalTimestamps is used to retrieve the anchor proofs of all versions of
+// a public proposal.
+type ProposalTimestamps struct {
+	Token string `json:"token"` // Censorship token
+}
+
+// ChainInformation describes the dcrtime anchor of a digest.  MerklePath
+// leads from the digest to MerkleRoot, which is stored in Transaction.
+type ChainInformation struct {
+	ChainTimestamp int64         `json:"chaintimestamp"` // Time of the block
+	Transaction    string        `json:"transaction"`    // Anchor transaction
+	MerkleRoot     string        `json:"merkleroot"`     // Dcrtime merkle root
+	MerklePath     merkle.Branch `json:"merklepath"`     // Path to MerkleRoot
+}
+
+// ProposalTimestamp proves when a proposal version was anchored in the decred
+// blockchain.  Digest is the politeiad commit that created the version and
+// MerklePath leads from Digest to AnchorMerkle, the digest that politeiad
+// timestamped in dcrtime.  ChainInformation is only set once the anchor has
+// been confirmed.
+type ProposalTimestamp struct {
+	Version          string            `json:"version"`                    // Proposal version
+	Digest           string            `json:"digest"`                     // Anchored commit digest
+	Status           TimestampStatusT  `json:"status"`                     // Anchor status
+	AnchorMerkle     string            `json:"anchormerkle,omitempty"`     // Anchor merkle root
+	AnchorTime       int64             `json:"anchortime,omitempty"`       // Time anchor was dropped
+	MerklePath       *merkle.Branch    `json:"merklepath,omitempty"`       // Path to AnchorMerkle
+	ChainInformation *ChainInformation `json:"chaininformation,omitempty"` // Dcrtime anchor
+}
+
+// ProposalTimestampsReply is used to reply to a ProposalTimestamps command.
+// Timestamps are ordered by proposal version.
+type ProposalTimestampsReply struct {
+	Timestamps []ProposalTimestamp `json:"timestamps"` // Anchor proofs
+}
+
+// ProposalBundle is used to export a self-contained bundle of a public
+// proposal that can be verified offline.
+type ProposalBundle struct {
+	Token string `json:"token"` // Censorship token
+}
+
+// ProposalBundleReply is used to reply to a ProposalBundle command.  Bundle is
+// the politeiad record bundle, forwarded verbatim so that the signatures it
+// contains remain verifiable.  See the politeiad v1.Bundle type for the
+// layout.
+type ProposalBundleReply struct {
+	Bundle json.RawMessage `json:"bundle"` // Politeiad record bundle
+}
+
 // BatchProposals is used to request the proposal details for each of the
 // provided censorship tokens. The returned proposals do not include the
 // proposal files.
@@ -846,6 +1015,8 @@ type PolicyReply struct {
 	MaxImageSize               uint     `json:"maximagesize"`
 	MaxMDs                     uint     `json:"maxmds"`
 	MaxMDSize                  uint     `json:"maxmdsize"`
+	MaxAttachments             uint     `json:"maxattachments"`
+	MaxAttachmentSize          uint     `json:"maxattachmentsize"`
 	ValidMIMETypes             []string `json:"validmimetypes"`
 	MinProposalNameLength      uint     `json:"minproposalnamelength"`
 	MaxProposalNameLength      uint     `json:"maxproposalnamelength"`
@@ -1134,38 +1305,198 @@ type ManageUser struct {
 // ManageUserReply is the reply for the ManageUserReply command.
 type ManageUserReply struct{}
 
+const (
+	// Webhook event types
+	WebhookEventProposalSubmitted      = "proposalsubmitted"
+	WebhookEventProposalStatusChange   = "proposalstatuschange"
+	WebhookEventProposalEdited         = "proposaledited"
+	WebhookEventProposalVoteAuthorized = "proposalvoteauthorized"
+	WebhookEventProposalVoteStarted    = "proposalvotestarted"
+	WebhookEventProposalVoteFinished   = "proposalvotefinished"
+	WebhookEventComment                = "comment"
+	WebhookEventCommentVote            = "commentvote"
+	WebhookEventVotesCast              = "votescast"
+	WebhookEventUserManage             = "usermanage"
+	WebhookEventInvoiceComment         = "invoicecomment"
+	WebhookEventInvoiceStatusUpdate    = "invoicestatusupdate"
+	WebhookEventDCCNew                 = "dccnew"
+	WebhookEventDCCSupportOppose       = "dccsupportoppose"
+
+	// Webhook request headers
+	WebhookHeaderEvent     = "X-Politeia-Event"     // Event type
+	WebhookHeaderDelivery  = "X-Politeia-Delivery"  // Delivery ID
+	WebhookHeaderSignature = "X-Politeia-Signature" // Hex HMAC-SHA256 of the body
+
+	// WebhookSecretMinLength is the minimum length of a webhook secret.
+	WebhookSecretMinLength = 16
+
+	// Webhook delivery statuses
+	WebhookDeliveryStatusInvalid   WebhookDeliveryStatusT = 0 // Invalid status
+	WebhookDeliveryStatusPending   WebhookDeliveryStatusT = 1 // Waiting to be delivered
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatusT = 2 // Delivered
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatusT = 3 // Gave up delivering
+)
+
+// Webhook is an admin configured URL that politeiawww events are posted to.
+// The secret is never returned.
+type Webhook struct {
+	ID        string   `json:"id"`        // Webhook ID
+	URL       string   `json:"url"`       // URL that events are posted to
+	Events    []string `json:"events"`    // Event types, all events if empty
+	CreatedAt int64    `json:"createdat"` // UNIX timestamp of creation
+}
+
+// WebhookPayload is the JSON request body that is posted to a webhook. The
+// WebhookHeaderSignature header contains the hex encoded HMAC-SHA256 of the
+// request body, keyed with the webhook secret.
+type WebhookPayload struct {
+	ID        string      `json:"id"`        // Delivery ID
+	Event     string      `json:"event"`     // Event type
+	Timestamp int64       `json:"timestamp"` // UNIX timestamp of event
+	Data      interface{} `json:"data"`      // Event specific data
+}
+
+// WebhookDelivery describes a delivery of an event to a webhook.
+type WebhookDelivery struct {
+	ID           string                 `json:"id"`                     // Delivery ID
+	Event        string                 `json:"event"`                  // Event type
+	Status       WebhookDeliveryStatusT `json:"status"`                 // Delivery status
+	Attempts     uint32                 `json:"attempts"`               // Number of delivery attempts
+	NextAttempt  int64                  `json:"nextattempt,omitempty"`  // UNIX timestamp of next attempt
+	LastAttempt  int64                  `json:"lastattempt,omitempty"`  // UNIX timestamp of last attempt
+	ResponseCode int                    `json:"responsecode,omitempty"` // HTTP status code of last attempt
+	Error        string                 `json:"error,omitempty"`        // Error of last attempt
+	CreatedAt    int64                  `json:"createdat"`              // UNIX timestamp of creation
+}
+
+// NewWebhook creates a webhook. Events that are posted to the webhook are
+// signed with Secret.
+type NewWebhook struct {
+	URL    string   `json:"url"`    // HTTP or HTTPS URL
+	Events []string `json:"events"` // Event types, all events if empty
+	Secret string   `json:"secret"` // HMAC signing key
+}
+
+// NewWebhookReply is the reply to the NewWebhook command.
+type NewWebhookReply struct {
+	Webhook Webhook `json:"webhook"`
+}
+
+// DeleteWebhook deletes a webhook along with its delivery log.
+type DeleteWebhook struct {
+	ID string `json:"id"` // Webhook ID
+}
+
+// DeleteWebhookReply is the reply to the DeleteWebhook command.
+type DeleteWebhookReply struct{}
+
+// Webhooks retrieves all webhooks.
+type Webhooks struct{}
+
+// WebhooksReply is the reply to the Webhooks command.
+type WebhooksReply struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookDeliveries retrieves the most recent deliveries of a webhook.
+type WebhookDeliveries struct {
+	ID string `json:"id"` // Webhook ID
+}
+
+// WebhookDeliveriesReply is the reply to the WebhookDeliveries command. The
+// deliveries are ordered from newest to oldest.
+type WebhookDeliveriesReply struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// FailedEmail describes an email that could not be sent after the maximum
+// number of attempts. The email body is not returned.
+type FailedEmail struct {
+	ID          string `json:"id"`          // Email ID
+	Recipient   string `json:"recipient"`   // Recipient email address
+	Subject     string `json:"subject"`     // Email subject
+	Attempts    uint32 `json:"attempts"`    // Number of send attempts
+	LastAttempt int64  `json:"lastattempt"` // UNIX timestamp of last attempt
+	Error       string `json:"error"`       // Error of last attempt
+	CreatedAt   int64  `json:"createdat"`   // UNIX timestamp of creation
+}
+
+// FailedEmails retrieves the emails that could not be sent.
+type FailedEmails struct{}
+
+// FailedEmailsReply is the reply to the FailedEmails command. The most recent
+// failed emails are returned, newest first.
+type FailedEmailsReply struct {
+	Emails []FailedEmail `json:"emails"`
+}
+
 // EditUser edits a user's preferences.
+//
+// EmailNotifications is a bitmask of the notifications that are enabled.
+// EmailDigests is a bitmask of the enabled notifications that are batched
+// into an email digest instead of being sent immediately. The digest is sent
+// at the EmailDigestFrequency. Fields that are not set are left unchanged.
 type EditUser struct {
-	EmailNotifications *uint64 `json:"emailnotifications"` // Notify the user via emails
+	EmailNotifications   *uint64                `json:"emailnotifications"`             // Notify the user via emails
+	EmailDigests         *uint64                `json:"emaildigests,omitempty"`         // Notifications sent in digest
+	EmailDigestFrequency *EmailDigestFrequencyT `json:"emaildigestfrequency,omitempty"` // Digest frequency
 }
 
 // EditUserReply is the reply for the EditUser command.
 type EditUserReply struct{}
 
+// ProposalFollow is a proposal that a user follows.
+type ProposalFollow struct {
+	Token     string `json:"token"`     // Censorship token
+	CreatedAt int64  `json:"createdat"` // UNIX timestamp of when it was followed
+}
+
+// UserFollows retrieves the proposals that the logged in user follows.
+type UserFollows struct{}
+
+// UserFollowsReply is the reply for the UserFollows command.
+type UserFollowsReply struct {
+	Follows []ProposalFollow `json:"follows"`
+}
+
+// EditUserFollows follows or unfollows a proposal for the logged in user.
+// Followers are notified of edits, new comments, vote authorizations, votes
+// starting and finishing and status changes of the proposal by the
+// NotificationEmailFollowedProposal email notification.
+type EditUserFollows struct {
+	Token  string        `json:"token"`  // Censorship token
+	Action FollowActionT `json:"action"` // Follow or unfollow
+}
+
+// EditUserFollowsReply is the reply for the EditUserFollows command.
+type EditUserFollowsReply struct{}
+
 // User represents an individual user.
 type User struct {
-	ID                              string         `json:"id"`
-	Email                           string         `json:"email"`
-	Username                        string         `json:"username"`
-	Admin                           bool           `json:"isadmin"`
-	NewUserPaywallAddress           string         `json:"newuserpaywalladdress"`
-	NewUserPaywallAmount            uint64         `json:"newuserpaywallamount"`
-	NewUserPaywallTx                string         `json:"newuserpaywalltx"`
-	NewUserPaywallTxNotBefore       int64          `json:"newuserpaywalltxnotbefore"`
-	NewUserPaywallPollExpiry        int64          `json:"newuserpaywallpollexpiry"`
-	NewUserVerificationToken        []byte         `json:"newuserverificationtoken"`
-	NewUserVerificationExpiry       int64          `json:"newuserverificationexpiry"`
-	UpdateKeyVerificationToken      []byte         `json:"updatekeyverificationtoken"`
-	UpdateKeyVerificationExpiry     int64          `json:"updatekeyverificationexpiry"`
-	ResetPasswordVerificationToken  []byte         `json:"resetpasswordverificationtoken"`
-	ResetPasswordVerificationExpiry int64          `json:"resetpasswordverificationexpiry"`
-	LastLoginTime                   int64          `json:"lastlogintime"`
-	FailedLoginAttempts             uint64         `json:"failedloginattempts"`
-	Deactivated                     bool           `json:"isdeactivated"`
-	Locked                          bool           `json:"islocked"`
-	Identities                      []UserIdentity `json:"identities"`
-	ProposalCredits                 uint64         `json:"proposalcredits"`
-	EmailNotifications              uint64         `json:"emailnotifications"` // Notify the user via emails
+	ID                              string                `json:"id"`
+	Email                           string                `json:"email"`
+	Username                        string                `json:"username"`
+	Admin                           bool                  `json:"isadmin"`
+	NewUserPaywallAddress           string                `json:"newuserpaywalladdress"`
+	NewUserPaywallAmount            uint64                `json:"newuserpaywallamount"`
+	NewUserPaywallTx                string                `json:"newuserpaywalltx"`
+	NewUserPaywallTxNotBefore       int64                 `json:"newuserpaywalltxnotbefore"`
+	NewUserPaywallPollExpiry        int64                 `json:"newuserpaywallpollexpiry"`
+	NewUserVerificationToken        []byte                `json:"newuserverificationtoken"`
+	NewUserVerificationExpiry       int64                 `json:"newuserverificationexpiry"`
+	UpdateKeyVerificationToken      []byte                `json:"updatekeyverificationtoken"`
+	UpdateKeyVerificationExpiry     int64                 `json:"updatekeyverificationexpiry"`
+	ResetPasswordVerificationToken  []byte                `json:"resetpasswordverificationtoken"`
+	ResetPasswordVerificationExpiry int64                 `json:"resetpasswordverificationexpiry"`
+	LastLoginTime                   int64                 `json:"lastlogintime"`
+	FailedLoginAttempts             uint64                `json:"failedloginattempts"`
+	Deactivated                     bool                  `json:"isdeactivated"`
+	Locked                          bool                  `json:"islocked"`
+	Identities                      []UserIdentity        `json:"identities"`
+	ProposalCredits                 uint64                `json:"proposalcredits"`
+	EmailNotifications              uint64                `json:"emailnotifications"`   // Notify the user via emails
+	EmailDigests                    uint64                `json:"emaildigests"`         // Notifications sent in digest
+	EmailDigestFrequency            EmailDigestFrequencyT `json:"emaildigestfrequency"` // Digest frequency
 }
 
 // UserIdentity represents a user's unique identity.
@@ -1216,9 +1547,15 @@ type TokenInventoryReply struct {
 
 // Websocket commands
 const (
-	WSCError     = "error"
-	WSCPing      = "ping"
-	WSCSubscribe = "subscribe"
+	WSCError          = "error"
+	WSCPing           = "ping"
+	WSCSubscribe      = "subscribe"
+	WSCProposalStatus = "proposalstatus"
+	WSCNewComment     = "newcomment"
+	WSCCommentVote    = "commentvote"
+	WSCVoteStarted    = "votestarted"
+	WSCVoteFinished   = "votefinished"
+	WSCVoteTally      = "votetally"
 )
 
 // WSHeader is required to be sent before any other command. The point is to
@@ -1238,12 +1575,62 @@ type WSError struct {
 }
 
 // WSSubscribe is a client side push to tell the server what RPCs it wishes to
-// subscribe to.
+// subscribe to. Proposal notifications can be limited to the proposals in
+// Tokens. Notifications for all proposals are sent when Tokens is empty.
 type WSSubscribe struct {
-	RPCS []string `json:"rpcs"` // Commands that the client wants to subscribe to
+	RPCS   []string `json:"rpcs"`             // Commands that the client wants to subscribe to
+	Tokens []string `json:"tokens,omitempty"` // Censorship tokens to filter proposal notifications by
 }
 
 // WSPing is a server side push to the client to see if it is still alive.
 type WSPing struct {
 	Timestamp int64 `json:"timestamp"` // Server side timestamp
 }
+
+// WSProposalStatus is a server side push to notify the client that the status
+// of a public proposal has changed.
+type WSProposalStatus struct {
+	Token     string      `json:"token"`     // Censorship token
+	Status    PropStatusT `json:"status"`    // New proposal status
+	Timestamp int64       `json:"timestamp"` // Server side timestamp
+}
+
+// WSNewComment is a server side push to notify the client that a comment was
+// made on a proposal.
+type WSNewComment struct {
+	Comment Comment `json:"comment"` // New comment
+}
+
+// WSCommentVote is a server side push to notify the client that the votes of
+// a comment have changed.
+type WSCommentVote struct {
+	Token     string `json:"token"`     // Censorship token
+	CommentID string `json:"commentid"` // Comment ID
+	Result    int64  `json:"result"`    // Current tally of likes, can be negative
+	Upvotes   uint64 `json:"upvotes"`   // Current tally of pro votes
+	Downvotes uint64 `json:"downvotes"` // Current tally of contra votes
+}
+
+// WSVoteStarted is a server side push to notify the client that the voting
+// period of a proposal has started.
+type WSVoteStarted struct {
+	Token            string `json:"token"`            // Censorship token
+	StartBlockHeight uint32 `json:"startblockheight"` // Block height of vote start
+	EndBlockHeight   uint32 `json:"endblockheight"`   // Block height of vote end
+}
+
+// WSVoteFinished is a server side push to notify the client that the voting
+// period of a proposal has ended. The final results can be retrieved with the
+// VoteResults call.
+type WSVoteFinished struct {
+	Token     string `json:"token"`     // Censorship token
+	EndHeight uint64 `json:"endheight"` // Block height of vote end
+}
+
+// WSVoteTally is a server side push to notify the client that votes were cast
+// on a proposal. Votes contains the number of votes that were added to each
+// vote option since the previous notification, not the running totals.
+type WSVoteTally struct {
+	Token string            `json:"token"` // Censorship token
+	Votes map[string]uint64 `json:"votes"` // [votebit]number of new votes
+}
@@ -49,6 +49,16 @@ func (cmd *HelpCmd) Execute(args []string) error {
 		fmt.Printf("%s\n", shared.UpdateUserKeyHelpMsg)
 	case "users":
 		fmt.Printf("%s\n", shared.UsersHelpMsg)
+	case "newwebhook":
+		fmt.Printf("%s\n", shared.NewWebhookHelpMsg)
+	case "deletewebhook":
+		fmt.Printf("%s\n", shared.DeleteWebhookHelpMsg)
+	case "webhooks":
+		fmt.Printf("%s\n", shared.WebhooksHelpMsg)
+	case "webhookdeliveries":
+		fmt.Printf("%s\n", shared.WebhookDeliveriesHelpMsg)
+	case "failedemails":
+		fmt.Printf("%s\n", shared.FailedEmailsHelpMsg)
 	case "userdetails":
 		fmt.Printf("%s\n", userDetailsHelpMsg)
 	case "policy":
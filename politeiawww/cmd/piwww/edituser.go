@@ -18,10 +18,13 @@ type EditUserCmd struct {
 	Args struct {
 		NotifType string `long:"emailnotifications"` // Email notification bit field
 	} `positional-args:"true" required:"true"`
+	Digests         string `long:"digests" optional:"true"`         // Notifications sent in email digest
+	DigestFrequency string `long:"digestfrequency" optional:"true"` // Email digest frequency
 }
 
-// Execute executes the edit user command.
-func (cmd *EditUserCmd) Execute(args []string) error {
+// parseEmailNotifications parses a numeric email notification bit field or a
+// comma separated list of human readable email notifications.
+func parseEmailNotifications(notifs string) (uint64, error) {
 	emailNotifs := map[string]v1.EmailNotificationT{
 		"userproposalchange":        v1.NotificationEmailMyProposalStatusChange,
 		"userproposalvotingstarted": v1.NotificationEmailMyProposalVoteStarted,
@@ -32,39 +35,68 @@ func (cmd *EditUserCmd) Execute(args []string) error {
 		"userauthorizedvote":        v1.NotificationEmailAdminProposalVoteAuthorized,
 		"commentonproposal":         v1.NotificationEmailCommentOnMyProposal,
 		"commentoncomment":          v1.NotificationEmailCommentOnMyComment,
+		"followedproposal":          v1.NotificationEmailFollowedProposal,
 	}
 
 	var notif v1.EmailNotificationT
-	a, err := strconv.ParseUint(cmd.Args.NotifType, 10, 64)
+	a, err := strconv.ParseUint(notifs, 10, 64)
 	if err == nil {
 		// Numeric action code found
 		notif = v1.EmailNotificationT(a)
-	} else if a, ok := emailNotifs[cmd.Args.NotifType]; ok {
+	} else if a, ok := emailNotifs[notifs]; ok {
 		// Human readable action code found
 		notif = a
-	} else if strings.Contains(cmd.Args.NotifType, ",") {
+	} else if strings.Contains(notifs, ",") {
 		// List of human readable action codes found
 
 		notif = a
 		// Parse list of strings and calculate associated integer
-		s := strings.Split(cmd.Args.NotifType, ",")
+		s := strings.Split(notifs, ",")
 		for _, v := range s {
 			a, ok := emailNotifs[v]
 			if !ok {
-				return fmt.Errorf("Invalid edituser option. Type " +
+				return 0, fmt.Errorf("Invalid edituser option. Type " +
 					"'help edituser' for list of valid options")
 			}
 			notif |= a
 		}
 	} else {
-		return fmt.Errorf("Invalid edituser option. Type 'help edituser' " +
+		return 0, fmt.Errorf("Invalid edituser option. Type 'help edituser' " +
 			"for list of valid options")
 	}
 
+	return uint64(notif), nil
+}
+
+// Execute executes the edit user command.
+func (cmd *EditUserCmd) Execute(args []string) error {
+	notif, err := parseEmailNotifications(cmd.Args.NotifType)
+	if err != nil {
+		return err
+	}
+
 	// Setup request
-	helper := uint64(notif)
 	eu := &v1.EditUser{
-		EmailNotifications: &helper,
+		EmailNotifications: &notif,
+	}
+	if cmd.Digests != "" {
+		digests, err := parseEmailNotifications(cmd.Digests)
+		if err != nil {
+			return err
+		}
+		eu.EmailDigests = &digests
+	}
+	if cmd.DigestFrequency != "" {
+		frequencies := map[string]v1.EmailDigestFrequencyT{
+			"daily":  v1.EmailDigestFrequencyDaily,
+			"weekly": v1.EmailDigestFrequencyWeekly,
+		}
+		f, ok := frequencies[cmd.DigestFrequency]
+		if !ok {
+			return fmt.Errorf("Invalid digest frequency. Valid " +
+				"frequencies are daily and weekly")
+		}
+		eu.EmailDigestFrequency = &f
 	}
 
 	// Print request details
@@ -85,13 +117,22 @@ func (cmd *EditUserCmd) Execute(args []string) error {
 
 // editUserHelpMsg is the output of the help command when 'edituser' is
 // specified.
-const editUserHelpMsg = `edituser "emailnotifications"
+const editUserHelpMsg = `edituser [flags] "emailnotifications"
 
 Edit user settings for the logged in user.
+
+Notifications that are enabled in emailnotifications are emailed immediately
+unless they are also included in --digests, in which case they are batched
+into a daily or weekly email digest.
  
 Arguments:
 1. emailnotifications       (string, required)   Email notification bit field
 
+Flags:
+  --digests                 (string, optional)   Notifications sent in digest
+  --digestfrequency         (string, optional)   Digest frequency (daily or
+                                                 weekly)
+
 Valid options are:
 
 1.   userproposalchange         Notify when status of my proposal changes
@@ -103,10 +144,13 @@ Valid options are:
 64.  userauthorizedvote         Notify when user authorizes vote (admin only)
 128. commentonproposal          Notify when comment is made on my proposal
 256. commentoncomment           Notify when comment is made on my comment
+512. followedproposal           Notify of activity on proposals that I follow
 
 Request:
 {
-  "emailnotifications":  (uint64)  Bit field
+  "emailnotifications":    (uint64)  Bit field
+  "emaildigests":          (uint64)  Bit field
+  "emaildigestfrequency":  (int)     Digest frequency (1 daily, 2 weekly)
 }
 
 Response:
@@ -0,0 +1,82 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ExportBundleCmd exports the record bundle of a proposal to a file.  The
+// bundle can be verified offline using politeia_verify.
+type ExportBundleCmd struct {
+	Args struct {
+		Token    string `positional-arg-name:"token" required:"true"`    // Censorship token
+		Filename string `positional-arg-name:"filename" required:"true"` // Output file
+	} `positional-args:"true"`
+}
+
+// Execute executes the export bundle command.
+func (cmd *ExportBundleCmd) Execute(args []string) error {
+	// Get server's public key
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+
+	// Get bundle
+	pbr, err := client.ProposalBundle(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+
+	// The bundle must have been signed by the server we are talking to
+	var b struct {
+		ServerPublicKey string `json:"serverpublickey"`
+	}
+	err = json.Unmarshal(pbr.Bundle, &b)
+	if err != nil {
+		return fmt.Errorf("unmarshal bundle: %v", err)
+	}
+	if b.ServerPublicKey != vr.PubKey {
+		return fmt.Errorf("bundle public key %v does not match server "+
+			"public key %v", b.ServerPublicKey, vr.PubKey)
+	}
+
+	// Write bundle to disk
+	var out bytes.Buffer
+	err = json.Indent(&out, pbr.Bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(cmd.Args.Filename, out.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bundle of %v written to %v\n", cmd.Args.Token,
+		cmd.Args.Filename)
+
+	return nil
+}
+
+// exportBundleHelpMsg is the output for the help command when 'exportbundle'
+// is specified.
+const exportBundleHelpMsg = `exportbundle "token" "filename"
+
+Export a self-contained bundle of a public proposal to a file.  The bundle
+contains all proposal versions, metadata, censorship records, comments, cast
+votes and anchor proofs and can be verified offline using:
+
+politeia_verify -bundle "filename"
+
+Arguments:
+1. token      (string, required)   Censorship token
+2. filename   (string, required)   File the bundle is written to
+
+Result:
+Bundle of "token" written to "filename"`
@@ -0,0 +1,60 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/cmd/shared"
+)
+
+// FollowCmd follows a proposal for the logged in user.
+type FollowCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token"` // Censorship token
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute executes the follow command.
+func (cmd *FollowCmd) Execute(args []string) error {
+	euf := &v1.EditUserFollows{
+		Token:  cmd.Args.Token,
+		Action: v1.FollowActionFollow,
+	}
+
+	// Print request details
+	err := shared.PrintJSON(euf)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	eufr, err := client.EditUserFollows(euf)
+	if err != nil {
+		return err
+	}
+
+	// Print response details
+	return shared.PrintJSON(eufr)
+}
+
+// followHelpMsg is the output of the help command when 'follow' is specified.
+const followHelpMsg = `follow "token"
+
+Follow a vetted proposal. Followers are emailed about edits, new comments,
+vote authorizations, the start and end of the vote and status changes of the
+proposal. Emails are only sent when the followedproposal email notification
+is enabled, see 'piwww help edituser'.
+
+Arguments:
+1. token       (string, required)  Proposal censorship token
+
+Request:
+{
+  "token":     (string)  Proposal censorship token
+  "action":    (int)     Follow action (1 follow, 2 unfollow)
+}
+
+Response:
+{}`
@@ -0,0 +1,38 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import "github.com/thi4go/politeia/politeiawww/cmd/shared"
+
+// FollowsCmd retrieves the proposals that the logged in user follows.
+type FollowsCmd struct{}
+
+// Execute executes the follows command.
+func (cmd *FollowsCmd) Execute(args []string) error {
+	ufr, err := client.UserFollows()
+	if err != nil {
+		return err
+	}
+	return shared.PrintJSON(ufr)
+}
+
+// followsHelpMsg is the output of the help command when 'follows' is
+// specified.
+const followsHelpMsg = `follows
+
+Fetch the proposals that the logged in user follows.
+
+Arguments:
+None
+
+Response:
+{
+  "follows": [
+    {
+      "token":      (string)  Proposal censorship token
+      "createdat":  (int64)   Unix timestamp of when it was followed
+    }
+  ]
+}`
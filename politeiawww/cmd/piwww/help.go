@@ -45,6 +45,8 @@ func (cmd *HelpCmd) Execute(args []string) error {
 		fmt.Printf("%s\n", userDetailsHelpMsg)
 	case "proposaldetails":
 		fmt.Printf("%s\n", proposalDetailsHelpMsg)
+	case "exportbundle":
+		fmt.Printf("%s\n", exportBundleHelpMsg)
 	case "userproposals":
 		fmt.Printf("%s\n", userProposalsHelpMsg)
 	case "vettedproposals":
@@ -65,12 +67,28 @@ func (cmd *HelpCmd) Execute(args []string) error {
 		fmt.Printf("%s\n", shared.ManageUserHelpMsg)
 	case "users":
 		fmt.Printf("%s\n", shared.UsersHelpMsg)
+	case "newwebhook":
+		fmt.Printf("%s\n", shared.NewWebhookHelpMsg)
+	case "deletewebhook":
+		fmt.Printf("%s\n", shared.DeleteWebhookHelpMsg)
+	case "webhooks":
+		fmt.Printf("%s\n", shared.WebhooksHelpMsg)
+	case "webhookdeliveries":
+		fmt.Printf("%s\n", shared.WebhookDeliveriesHelpMsg)
+	case "failedemails":
+		fmt.Printf("%s\n", shared.FailedEmailsHelpMsg)
 	case "verifyuseremail":
 		fmt.Printf("%s\n", verifyUserEmailHelpMsg)
 	case "version":
 		fmt.Printf("%s\n", shared.VersionHelpMsg)
 	case "edituser":
 		fmt.Printf("%s\n", editUserHelpMsg)
+	case "follow":
+		fmt.Printf("%s\n", followHelpMsg)
+	case "unfollow":
+		fmt.Printf("%s\n", unfollowHelpMsg)
+	case "follows":
+		fmt.Printf("%s\n", followsHelpMsg)
 	case "subscribe":
 		fmt.Printf("%s\n", subscribeHelpMsg)
 	case "me":
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"github.com/thi4go/politeia/politeiad/api/v1/mime"
 	"github.com/thi4go/politeia/politeiawww/api/www/v1"
@@ -91,9 +92,25 @@ func (cmd *NewProposalCmd) Execute(args []string) error {
 			return fmt.Errorf("ReadFile %v: %v", path, err)
 		}
 
+		// CSV files can not be told apart from plain text by their
+		// content so the file extension is used instead.
+		mimeType := mime.DetectMimeType(attachment)
+		if strings.EqualFold(filepath.Ext(file), ".csv") &&
+			mime.MimeMatches("text/csv", mimeType) {
+			mimeType = "text/csv"
+		}
+
+		// Remove active content since politeiad rejects it.
+		if mime.MimeValid(mimeType) {
+			attachment, err = mime.Sanitize(mimeType, attachment)
+			if err != nil {
+				return fmt.Errorf("Sanitize %v: %v", path, err)
+			}
+		}
+
 		f := v1.File{
 			Name:    filepath.Base(file),
-			MIME:    mime.DetectMimeType(attachment),
+			MIME:    mimeType,
 			Digest:  hex.EncodeToString(util.Digest(attachment)),
 			Payload: base64.StdEncoding.EncodeToString(attachment),
 		}
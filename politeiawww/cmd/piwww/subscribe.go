@@ -22,7 +22,8 @@ import (
 
 // SubscribeCmd opens a websocket connect to politeiawww.
 type SubscribeCmd struct {
-	Close bool `long:"close" optional:"true"` // Do not keep connetion alive
+	Close  bool     `long:"close" optional:"true"` // Do not keep connetion alive
+	Tokens []string `long:"token" optional:"true"` // Proposal notification filter
 }
 
 // Execute executes the subscribe command.
@@ -75,15 +76,17 @@ func (cmd *SubscribeCmd) Execute(args []string) error {
 	if err != nil {
 		return err
 	}
-	err = shared.PrintJSON(v1.WSSubscribe{RPCS: subscribe})
+	s := v1.WSSubscribe{
+		RPCS:   subscribe,
+		Tokens: cmd.Tokens,
+	}
+	err = shared.PrintJSON(s)
 	if err != nil {
 		return err
 	}
 
 	// Send subscribe command
-	err = utilwww.WSWrite(ws, v1.WSCSubscribe, "1", v1.WSSubscribe{
-		RPCS: subscribe,
-	})
+	err = utilwww.WSWrite(ws, v1.WSCSubscribe, "1", s)
 	if err != nil {
 		return err
 	}
@@ -110,18 +113,33 @@ func (cmd *SubscribeCmd) Execute(args []string) error {
 
 // subscribeHelpMsg is the output of the help command when 'subscribe' is
 // specified.
-const subscribeHelpMsg = `subscribe [auth] <ping...>
+const subscribeHelpMsg = `subscribe [auth] <command...>
 
 Connect and subcribe to www websocket. If auth is provided the connection will
 be made to the authenticated websocket (must be logged in).
 
 Flags:
 	--close	  (bool, optional)   Do not keep the websocket connection alive
-
-Supported commands:
-	- ping (does not require authentication)
+	--token	  (string, optional) Only receive proposal notifications for this
+	                             proposal. Can be provided multiple times.
+
+Supported commands (none require authentication):
+	- ping
+	- proposalstatus
+	- newcomment
+	- commentvote
+	- votestarted
+	- votefinished
+	- votetally
 
 Request:
 {
+  "rpcs":   [
+    "newcomment",
+    "votetally"
+  ],
+  "tokens": [
+    "5a6d2fe8a1fd4ea7b4fdd5f8ba4bbb5b0e70e6a8c7eac5a8b0ec5c5c39ec4f1b"
+  ]
 }
 `
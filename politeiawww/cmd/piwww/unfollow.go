@@ -0,0 +1,58 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/cmd/shared"
+)
+
+// UnfollowCmd unfollows a proposal for the logged in user.
+type UnfollowCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token"` // Censorship token
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute executes the unfollow command.
+func (cmd *UnfollowCmd) Execute(args []string) error {
+	euf := &v1.EditUserFollows{
+		Token:  cmd.Args.Token,
+		Action: v1.FollowActionUnfollow,
+	}
+
+	// Print request details
+	err := shared.PrintJSON(euf)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	eufr, err := client.EditUserFollows(euf)
+	if err != nil {
+		return err
+	}
+
+	// Print response details
+	return shared.PrintJSON(eufr)
+}
+
+// unfollowHelpMsg is the output of the help command when 'unfollow' is
+// specified.
+const unfollowHelpMsg = `unfollow "token"
+
+Unfollow a proposal.
+
+Arguments:
+1. token       (string, required)  Proposal censorship token
+
+Request:
+{
+  "token":     (string)  Proposal censorship token
+  "action":    (int)     Follow action (1 follow, 2 unfollow)
+}
+
+Response:
+{}`
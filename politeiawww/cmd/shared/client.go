@@ -822,6 +822,31 @@ func (c *Client) ProposalDetails(token string, pd *www.ProposalsDetails) (*www.P
 	return &pr, nil
 }
 
+// ProposalBundle retrieves the record bundle of the specified proposal.
+func (c *Client) ProposalBundle(token string) (*www.ProposalBundleReply, error) {
+	route := "/proposals/" + token + "/bundle"
+	responseBody, err := c.makeRequest(http.MethodGet,
+		www.PoliteiaWWWAPIRoute, route, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbr www.ProposalBundleReply
+	err = json.Unmarshal(responseBody, &pbr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal ProposalBundleReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(pbr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &pbr, nil
+}
+
 // UserProposals retrieves the proposals that have been submitted by the
 // specified user.
 func (c *Client) UserProposals(up *www.UserProposals) (*www.UserProposalsReply, error) {
@@ -1390,6 +1415,126 @@ func (c *Client) ManageUser(mu *www.ManageUser) (*www.ManageUserReply, error) {
 	return &mur, nil
 }
 
+// Webhooks retrieves the webhooks that have been configured by the admins.
+func (c *Client) Webhooks(w *www.Webhooks) (*www.WebhooksReply, error) {
+	responseBody, err := c.makeRequest(http.MethodGet,
+		www.PoliteiaWWWAPIRoute, www.RouteWebhooks, w)
+	if err != nil {
+		return nil, err
+	}
+
+	var wr www.WebhooksReply
+	err = json.Unmarshal(responseBody, &wr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal WebhooksReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(wr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &wr, nil
+}
+
+// NewWebhook creates a webhook that is posted the specified events.
+func (c *Client) NewWebhook(nw *www.NewWebhook) (*www.NewWebhookReply, error) {
+	responseBody, err := c.makeRequest(http.MethodPost,
+		www.PoliteiaWWWAPIRoute, www.RouteNewWebhook, nw)
+	if err != nil {
+		return nil, err
+	}
+
+	var nwr www.NewWebhookReply
+	err = json.Unmarshal(responseBody, &nwr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal NewWebhookReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(nwr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &nwr, nil
+}
+
+// DeleteWebhook deletes a webhook along with its pending deliveries.
+func (c *Client) DeleteWebhook(dw *www.DeleteWebhook) (*www.DeleteWebhookReply, error) {
+	responseBody, err := c.makeRequest(http.MethodPost,
+		www.PoliteiaWWWAPIRoute, www.RouteDeleteWebhook, dw)
+	if err != nil {
+		return nil, err
+	}
+
+	var dwr www.DeleteWebhookReply
+	err = json.Unmarshal(responseBody, &dwr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal DeleteWebhookReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(dwr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &dwr, nil
+}
+
+// WebhookDeliveries retrieves the most recent deliveries of a webhook.
+func (c *Client) WebhookDeliveries(wd *www.WebhookDeliveries) (*www.WebhookDeliveriesReply, error) {
+	responseBody, err := c.makeRequest(http.MethodGet,
+		www.PoliteiaWWWAPIRoute, www.RouteWebhookDeliveries, wd)
+	if err != nil {
+		return nil, err
+	}
+
+	var wdr www.WebhookDeliveriesReply
+	err = json.Unmarshal(responseBody, &wdr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal WebhookDeliveriesReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(wdr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &wdr, nil
+}
+
+// FailedEmails retrieves the emails that could not be sent.
+func (c *Client) FailedEmails(fe *www.FailedEmails) (*www.FailedEmailsReply, error) {
+	responseBody, err := c.makeRequest(http.MethodGet,
+		www.PoliteiaWWWAPIRoute, www.RouteFailedEmails, fe)
+	if err != nil {
+		return nil, err
+	}
+
+	var fer www.FailedEmailsReply
+	err = json.Unmarshal(responseBody, &fer)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal FailedEmailsReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(fer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fer, nil
+}
+
 // EditUser allows the logged in user to update their user settings.
 func (c *Client) EditUser(eu *www.EditUser) (*www.EditUserReply, error) {
 	responseBody, err := c.makeRequest(http.MethodPost,
@@ -1414,6 +1559,54 @@ func (c *Client) EditUser(eu *www.EditUser) (*www.EditUserReply, error) {
 	return &eur, nil
 }
 
+// UserFollows retrieves the proposals that the logged in user follows.
+func (c *Client) UserFollows() (*www.UserFollowsReply, error) {
+	responseBody, err := c.makeRequest(http.MethodGet,
+		www.PoliteiaWWWAPIRoute, www.RouteUserFollows, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ufr www.UserFollowsReply
+	err = json.Unmarshal(responseBody, &ufr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal UserFollowsReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(ufr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ufr, nil
+}
+
+// EditUserFollows follows or unfollows a proposal for the logged in user.
+func (c *Client) EditUserFollows(euf *www.EditUserFollows) (*www.EditUserFollowsReply, error) {
+	responseBody, err := c.makeRequest(http.MethodPost,
+		www.PoliteiaWWWAPIRoute, www.RouteUserFollows, euf)
+	if err != nil {
+		return nil, err
+	}
+
+	var eufr www.EditUserFollowsReply
+	err = json.Unmarshal(responseBody, &eufr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal EditUserFollowsReply: %v", err)
+	}
+
+	if c.cfg.Verbose {
+		err := prettyPrintJSON(eufr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &eufr, nil
+}
+
 // AuthorizeVote authorizes the voting period for the specified proposal using
 // the logged in user.
 func (c *Client) AuthorizeVote(av *www.AuthorizeVote) (*www.AuthorizeVoteReply, error) {
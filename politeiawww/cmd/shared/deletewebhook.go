@@ -0,0 +1,45 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package shared
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+// DeleteWebhookCmd deletes a webhook along with its pending deliveries.
+type DeleteWebhookCmd struct {
+	Args struct {
+		ID string `positional-arg-name:"id"` // Webhook ID
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute executes the delete webhook command.
+func (cmd *DeleteWebhookCmd) Execute(args []string) error {
+	dwr, err := client.DeleteWebhook(&v1.DeleteWebhook{
+		ID: cmd.Args.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return PrintJSON(dwr)
+}
+
+// DeleteWebhookHelpMsg is the output of the help command when
+// 'deletewebhook' is specified.
+const DeleteWebhookHelpMsg = `deletewebhook "id"
+
+Delete a webhook. Deliveries that have not been sent yet are discarded.
+Requires admin privileges.
+
+Arguments:
+1. id          (string, required)   Webhook ID
+
+Request:
+{
+  "id":  (string)  Webhook ID
+}
+
+Response:
+{}`
@@ -0,0 +1,45 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package shared
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+// FailedEmailsCmd retrieves the emails that could not be sent.
+type FailedEmailsCmd struct{}
+
+// Execute executes the failed emails command.
+func (cmd *FailedEmailsCmd) Execute(args []string) error {
+	fer, err := client.FailedEmails(&v1.FailedEmails{})
+	if err != nil {
+		return err
+	}
+	return PrintJSON(fer)
+}
+
+// FailedEmailsHelpMsg is the output of the help command when 'failedemails'
+// is specified.
+const FailedEmailsHelpMsg = `failedemails
+
+Fetch the most recent emails that could not be sent, newest first. Requires
+admin privileges.
+
+Arguments: None
+
+Response:
+{
+  "emails": [
+    {
+      "id":           (string)  Email ID
+      "recipient":    (string)  Recipient email address
+      "subject":      (string)  Email subject
+      "attempts":     (uint32)  Number of send attempts
+      "lastattempt":  (int64)   Unix timestamp of the last attempt
+      "error":        (string)  Error of the last attempt
+      "createdat":    (int64)   Unix timestamp of creation
+    }
+  ]
+}`
@@ -0,0 +1,73 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package shared
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+// NewWebhookCmd creates a webhook that politeiawww posts events to.
+type NewWebhookCmd struct {
+	Args struct {
+		URL    string `positional-arg-name:"url"`    // Webhook URL
+		Secret string `positional-arg-name:"secret"` // Signing secret
+	} `positional-args:"true" required:"true"`
+	Events []string `long:"event"` // Event filter
+}
+
+// Execute executes the new webhook command.
+func (cmd *NewWebhookCmd) Execute(args []string) error {
+	nw := &v1.NewWebhook{
+		URL:    cmd.Args.URL,
+		Events: cmd.Events,
+		Secret: cmd.Args.Secret,
+	}
+
+	nwr, err := client.NewWebhook(nw)
+	if err != nil {
+		return err
+	}
+	return PrintJSON(nwr)
+}
+
+// NewWebhookHelpMsg is the output of the help command when 'newwebhook' is
+// specified.
+const NewWebhookHelpMsg = `newwebhook [flags] "url" "secret"
+
+Create a webhook. politeiawww posts a signed JSON payload to the webhook URL
+for every event that the webhook is subscribed to. Requires admin privileges.
+
+Arguments:
+1. url          (string, required)   Webhook URL
+2. secret       (string, required)   Secret that is used to sign the payloads
+
+Flags:
+  --event       (string, optional)   Event to post to the webhook. This flag
+                                     can be specified multiple times. All
+                                     events are posted if no event is
+                                     specified.
+
+Valid events are:
+  proposalsubmitted, proposalstatuschange, proposaledited,
+  proposalvoteauthorized, proposalvotestarted, proposalvotefinished, comment,
+  commentvote, votescast, usermanage, invoicecomment, invoicestatusupdate,
+  dccnew, dccsupportoppose
+
+Request:
+{
+  "url":     (string)    Webhook URL
+  "events":  ([]string)  Events to post to the webhook
+  "secret":  (string)    Signing secret
+}
+
+Response:
+{
+  "webhook": {
+    "id":         (string)    Webhook ID
+    "url":        (string)    Webhook URL
+    "events":     ([]string)  Events that are posted to the webhook
+    "createdat":  (int64)     Unix timestamp of creation
+  }
+}`
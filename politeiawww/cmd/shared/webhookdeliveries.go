@@ -0,0 +1,54 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package shared
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+// WebhookDeliveriesCmd retrieves the most recent deliveries of a webhook.
+type WebhookDeliveriesCmd struct {
+	Args struct {
+		ID string `positional-arg-name:"id"` // Webhook ID
+	} `positional-args:"true" required:"true"`
+}
+
+// Execute executes the webhook deliveries command.
+func (cmd *WebhookDeliveriesCmd) Execute(args []string) error {
+	wdr, err := client.WebhookDeliveries(&v1.WebhookDeliveries{
+		ID: cmd.Args.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return PrintJSON(wdr)
+}
+
+// WebhookDeliveriesHelpMsg is the output of the help command when
+// 'webhookdeliveries' is specified.
+const WebhookDeliveriesHelpMsg = `webhookdeliveries "id"
+
+Fetch the most recent deliveries of a webhook, newest first. Requires admin
+privileges.
+
+Arguments:
+1. id          (string, required)   Webhook ID
+
+Response:
+{
+  "deliveries": [
+    {
+      "id":           (string)  Delivery ID
+      "event":        (string)  Event name
+      "status":       (int)     Delivery status
+      "attempts":     (uint32)  Number of delivery attempts
+      "nextattempt":  (int64)   Unix timestamp of the next attempt
+      "lastattempt":  (int64)   Unix timestamp of the last attempt
+      "responsecode": (int)     HTTP status code of the last attempt
+      "error":        (string)  Error of the last attempt
+      "createdat":    (int64)   Unix timestamp of creation
+    }
+  ]
+}`
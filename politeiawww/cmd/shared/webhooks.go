@@ -0,0 +1,41 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package shared
+
+import (
+	v1 "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+// WebhooksCmd retrieves all webhooks.
+type WebhooksCmd struct{}
+
+// Execute executes the webhooks command.
+func (cmd *WebhooksCmd) Execute(args []string) error {
+	wr, err := client.Webhooks(&v1.Webhooks{})
+	if err != nil {
+		return err
+	}
+	return PrintJSON(wr)
+}
+
+// WebhooksHelpMsg is the output of the help command when 'webhooks' is
+// specified.
+const WebhooksHelpMsg = `webhooks
+
+Fetch all webhooks. Requires admin privileges.
+
+Arguments: None
+
+Response:
+{
+  "webhooks": [
+    {
+      "id":         (string)    Webhook ID
+      "url":        (string)    Webhook URL
+      "events":     ([]string)  Events that are posted to the webhook
+      "createdat":  (int64)     Unix timestamp of creation
+    }
+  ]
+}`
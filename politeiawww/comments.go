@@ -607,6 +607,16 @@ func (p *politeiawww) processLikeComment(lc www.LikeComment, u *user.User) (*www
 			lc.CommentID, err)
 	}
 
+	// Fire off comment vote event
+	if err == nil && lcr.Error == "" {
+		p.fireEvent(EventTypeCommentVote, EventDataCommentVote{
+			Token:     lc.Token,
+			CommentID: lc.CommentID,
+			Upvotes:   votes.up,
+			Downvotes: votes.down,
+		})
+	}
+
 	return &www.LikeCommentReply{
 		Result:    int64(votes.up - votes.down),
 		Upvotes:   votes.up,
@@ -53,6 +53,7 @@ const (
 
 	defaultMailAddress    = "Politeia <noreply@example.org>"
 	defaultCMSMailAddress = "Contractor Management System <noreply@example.org>"
+	defaultMailRateLimit  = uint(20)
 
 	defaultDcrdataMainnet = "dcrdata.decred.org:443"
 	defaultDcrdataTestnet = "testnet.decred.org:443"
@@ -76,6 +77,12 @@ const (
 	userDBCockroach = "cockroachdb"
 
 	defaultUserDB = userDBLevel
+
+	// Cache database options
+	cacheCockroachDB = "cockroachdb"
+	cacheSQLite      = "sqlite"
+
+	defaultCacheDB = cacheCockroachDB
 )
 
 var (
@@ -107,6 +114,7 @@ type config struct {
 	MemProfile               string   `long:"memprofile" description:"Write mem profile to the specified file"`
 	DebugLevel               string   `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 	Listeners                []string `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 49152, testnet: 59152)"`
+	Metrics                  string   `long:"metrics" description:"Serve Prometheus metrics over plain HTTP on the given interface/port at /metrics (e.g. localhost:4444)"`
 	Version                  string
 	HTTPSCert                string `long:"httpscert" description:"File containing the https certificate file"`
 	HTTPSKey                 string `long:"httpskey" description:"File containing the https certificate key"`
@@ -117,16 +125,21 @@ type config struct {
 	Identity                 *identity.PublicIdentity
 	RPCUser                  string `long:"rpcuser" description:"RPC user name for privileged commands"`
 	RPCPass                  string `long:"rpcpass" description:"RPC password for privileged commands"`
+	RPCIdentity              string `long:"rpcidentity" description:"File containing the client identity that signs privileged commands instead of rpcuser and rpcpass"`
 	MailHost                 string `long:"mailhost" description:"Email server address in this format: <host>:<port>"`
 	MailUser                 string `long:"mailuser" description:"Email server username"`
 	MailPass                 string `long:"mailpass" description:"Email server password"`
 	MailAddress              string `long:"mailaddress" description:"Email address for outgoing email in the format: name <address>"`
+	MailDir                  string `long:"maildir" description:"Write outgoing email to this maildir instead of sending it to the email server (dev use only)"`
+	MailRateLimit            uint   `long:"mailratelimit" description:"Maximum number of emails that are sent to a single email address per hour; 0 disables the limit"`
 	DBHost                   string `long:"dbhost" description:"Database ip:port"`
 	DBRootCert               string `long:"dbrootcert" description:"File containing the CA certificate for the database"`
 	DBCert                   string `long:"dbcert" description:"File containing the politeiawww client certificate for the database"`
 	DBKey                    string `long:"dbkey" description:"File containing the politeiawww client certificate key for the database"`
 	BuildCMSDB               bool   `long:"buildcmsdb" description:"Build the cmsdb from scratch"`
 	UserDB                   string `long:"userdb" description:"Database choice for the user database"`
+	CacheDB                  string `long:"cachedb" description:"Database choice for the cache; must match politeiad (cockroachdb or sqlite)"`
+	CacheFile                string `long:"cachefile" description:"The politeiad SQLite cache file; required when cachedb is sqlite"`
 	EncryptionKey            string `long:"encryptionkey" description:"File containing encryption key used for encrypting user data at rest"`
 	OldEncryptionKey         string `long:"oldencryptionkey" description:"File containing old encryption key (only set when rotating keys)"`
 	FetchIdentity            bool   `long:"fetchidentity" description:"Whether or not politeiawww fetches the identity from politeiad."`
@@ -393,8 +406,10 @@ func loadConfig() (*config, []string, error) {
 		VoteDurationMin:          defaultVoteDurationMin,
 		VoteDurationMax:          defaultVoteDurationMax,
 		MailAddress:              defaultMailAddress,
+		MailRateLimit:            defaultMailRateLimit,
 		Mode:                     defaultWWWMode,
 		UserDB:                   defaultUserDB,
+		CacheDB:                  defaultCacheDB,
 	}
 
 	// Service options which are only added on Windows.
@@ -591,43 +606,62 @@ func loadConfig() (*config, []string, error) {
 	cfg.RPCCert = cleanAndExpandPath(cfg.RPCCert)
 
 	// Validate cache options.
-	switch {
-	case cfg.DBHost == "":
-		return nil, nil, fmt.Errorf("dbhost param is required")
-	case cfg.DBRootCert == "":
-		return nil, nil, fmt.Errorf("dbrootcert param is required")
-	case cfg.DBCert == "":
-		return nil, nil, fmt.Errorf("dbcert param is required")
-	case cfg.DBKey == "":
-		return nil, nil, fmt.Errorf("dbkey param is required")
-	}
-
-	cfg.DBRootCert = cleanAndExpandPath(cfg.DBRootCert)
-	cfg.DBCert = cleanAndExpandPath(cfg.DBCert)
-	cfg.DBKey = cleanAndExpandPath(cfg.DBKey)
-
-	// Validate db host.
-	_, err = url.Parse(cfg.DBHost)
-	if err != nil {
-		return nil, nil, fmt.Errorf("parse dbhost: %v", err)
-	}
+	switch cfg.CacheDB {
+	case cacheCockroachDB:
+		// Valid selection; continue
+	case cacheSQLite:
+		if cfg.CacheFile == "" {
+			return nil, nil, fmt.Errorf("cachefile param is required " +
+				"when using the sqlite cache")
+		}
+		cfg.CacheFile = cleanAndExpandPath(cfg.CacheFile)
+	default:
+		return nil, nil, fmt.Errorf("invalid cachedb '%v'; must "+
+			"be either cockroachdb or sqlite", cfg.CacheDB)
+	}
+
+	// Validate the CockroachDB connection options.  These are only
+	// required when one of the databases lives in CockroachDB.
+	if cfg.CacheDB == cacheCockroachDB || cfg.UserDB == userDBCockroach ||
+		cfg.Mode == cmsWWWMode {
+		switch {
+		case cfg.DBHost == "":
+			return nil, nil, fmt.Errorf("dbhost param is required")
+		case cfg.DBRootCert == "":
+			return nil, nil, fmt.Errorf("dbrootcert param is required")
+		case cfg.DBCert == "":
+			return nil, nil, fmt.Errorf("dbcert param is required")
+		case cfg.DBKey == "":
+			return nil, nil, fmt.Errorf("dbkey param is required")
+		}
+
+		cfg.DBRootCert = cleanAndExpandPath(cfg.DBRootCert)
+		cfg.DBCert = cleanAndExpandPath(cfg.DBCert)
+		cfg.DBKey = cleanAndExpandPath(cfg.DBKey)
+
+		// Validate db host.
+		_, err = url.Parse(cfg.DBHost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse dbhost: %v", err)
+		}
 
-	// Validate db root cert.
-	b, err := ioutil.ReadFile(cfg.DBRootCert)
-	if err != nil {
-		return nil, nil, fmt.Errorf("read dbrootcert: %v", err)
-	}
-	block, _ := pem.Decode(b)
-	_, err = x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, nil, fmt.Errorf("parse dbrootcert: %v", err)
-	}
+		// Validate db root cert.
+		b, err := ioutil.ReadFile(cfg.DBRootCert)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read dbrootcert: %v", err)
+		}
+		block, _ := pem.Decode(b)
+		_, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse dbrootcert: %v", err)
+		}
 
-	// Validate db key pair.
-	_, err = tls.LoadX509KeyPair(cfg.DBCert, cfg.DBKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("load key pair dbcert "+
-			"and dbkey: %v", err)
+		// Validate db key pair.
+		_, err = tls.LoadX509KeyPair(cfg.DBCert, cfg.DBKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load key pair dbcert "+
+				"and dbkey: %v", err)
+		}
 	}
 
 	// Validate user database selection.
@@ -703,6 +737,18 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	// Validate metrics listener
+	if cfg.Metrics != "" {
+		_, _, err := net.SplitHostPort(cfg.Metrics)
+		if err != nil {
+			str := "%s: Invalid metrics listener: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
 	// Add the default listener if none were specified. The default
 	// listener is all addresses on the listen port for the network
 	// we are to connect to.
@@ -767,8 +813,25 @@ func loadConfig() (*config, []string, error) {
 		log.Warnf("RPC password not set, using random value")
 	}
 
+	// Validate the client identity that is used to sign privileged
+	// commands.
+	if cfg.RPCIdentity != "" {
+		cfg.RPCIdentity = cleanAndExpandPath(cfg.RPCIdentity)
+		if !util.FileExists(cfg.RPCIdentity) {
+			return nil, nil, fmt.Errorf("file not found %v",
+				cfg.RPCIdentity)
+		}
+	}
+
 	// Valide mail settings
 	switch {
+	case cfg.MailDir != "":
+		// Email is written to a maildir
+		if cfg.MailHost != "" || cfg.MailUser != "" || cfg.MailPass != "" {
+			return nil, nil, fmt.Errorf("maildir cannot be used " +
+				"with mailhost, mailuser or mailpass")
+		}
+		cfg.MailDir = cleanAndExpandPath(cfg.MailDir)
 	case cfg.MailHost == "" && cfg.MailUser == "" &&
 		cfg.MailPass == "" && cfg.WebServerAddress == "":
 		// Email is disabled; this is ok
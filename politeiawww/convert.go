@@ -253,6 +253,69 @@ func convertPropCensorFromWWW(f www.CensorshipRecord) pd.CensorshipRecord {
 	}
 }
 
+func convertDiffActionFromPD(a pd.DiffActionT) www.DiffActionT {
+	switch a {
+	case pd.DiffActionAdded:
+		return www.DiffActionAdded
+	case pd.DiffActionRemoved:
+		return www.DiffActionRemoved
+	case pd.DiffActionModified:
+		return www.DiffActionModified
+	}
+	return www.DiffActionInvalid
+}
+
+func convertFileDiffFromPD(f pd.FileDiff) www.FileDiff {
+	return www.FileDiff{
+		Name:      f.Name,
+		Action:    convertDiffActionFromPD(f.Action),
+		MIME:      f.MIME,
+		OldDigest: f.OldDigest,
+		NewDigest: f.NewDigest,
+		Diff:      f.Diff,
+	}
+}
+
+func convertMetadataStreamDiffFromPD(m pd.MetadataStreamDiff) www.MetadataStreamDiff {
+	return www.MetadataStreamDiff{
+		ID:     m.ID,
+		Action: convertDiffActionFromPD(m.Action),
+		Diff:   m.Diff,
+	}
+}
+
+func convertTimestampStatusFromPD(s pd.TimestampStatusT) www.TimestampStatusT {
+	switch s {
+	case pd.TimestampStatusNotAnchored:
+		return www.TimestampStatusNotAnchored
+	case pd.TimestampStatusUnconfirmed:
+		return www.TimestampStatusUnconfirmed
+	case pd.TimestampStatusConfirmed:
+		return www.TimestampStatusConfirmed
+	}
+	return www.TimestampStatusInvalid
+}
+
+func convertProposalTimestampFromPD(t pd.RecordTimestamp) www.ProposalTimestamp {
+	pt := www.ProposalTimestamp{
+		Version:      t.Version,
+		Digest:       t.Digest,
+		Status:       convertTimestampStatusFromPD(t.Status),
+		AnchorMerkle: t.AnchorMerkle,
+		AnchorTime:   t.AnchorTime,
+		MerklePath:   t.MerklePath,
+	}
+	if t.ChainInformation != nil {
+		pt.ChainInformation = &www.ChainInformation{
+			ChainTimestamp: t.ChainInformation.ChainTimestamp,
+			Transaction:    t.ChainInformation.Transaction,
+			MerkleRoot:     t.ChainInformation.MerkleRoot,
+			MerklePath:     t.ChainInformation.MerklePath,
+		}
+	}
+	return pt
+}
+
 func convertErrorStatusFromPD(s int) www.ErrorStatusT {
 	switch pd.ErrorStatusT(s) {
 	case pd.ErrorStatusInvalidFileDigest:
@@ -263,10 +326,14 @@ func convertErrorStatusFromPD(s int) www.ErrorStatusT {
 		return www.ErrorStatusInvalidMIMEType
 	case pd.ErrorStatusUnsupportedMIMEType:
 		return www.ErrorStatusUnsupportedMIMEType
+	case pd.ErrorStatusMalformedFile:
+		return www.ErrorStatusMalformedFile
 	case pd.ErrorStatusInvalidRecordStatusTransition:
 		return www.ErrorStatusInvalidPropStatusTransition
 	case pd.ErrorStatusInvalidFilename:
 		return www.ErrorStatusInvalidFilename
+	case pd.ErrorStatusInvalidRecordVersion:
+		return www.ErrorStatusInvalidProposalVersion
 
 		// These cases are intentionally omitted because
 		// they are indicative of some internal server error,
@@ -866,126 +933,6 @@ func convertRecordToDatabaseInvoice(p pd.Record) (*cmsdatabase.Invoice, error) {
 		Version:         p.Version,
 	}
 
-	// Decode invoice file
-	for _, v := range p.Files {
-		if v.Name == invoiceFile {
-			b, err := base64.StdEncoding.DecodeString(v.Payload)
-			if err != nil {
-				return nil, err
-			}
-
-			var ii cms.InvoiceInput
-			err = json.Unmarshal(b, &ii)
-			if err != nil {
-				return nil, www.UserError{
-					ErrorCode: www.ErrorStatusInvalidInput,
-				}
-			}
-
-			dbInvoice.Month = ii.Month
-			dbInvoice.Year = ii.Year
-			dbInvoice.ExchangeRate = ii.ExchangeRate
-			dbInvoice.LineItems = convertLineItemsToDatabase(dbInvoice.Token,
-				ii.LineItems)
-			dbInvoice.ContractorContact = ii.ContractorContact
-			dbInvoice.ContractorLocation = ii.ContractorLocation
-			dbInvoice.ContractorRate = ii.ContractorRate
-			dbInvoice.ContractorName = ii.ContractorName
-			dbInvoice.PaymentAddress = ii.PaymentAddress
-		}
-	}
-	payout, err := calculatePayout(dbInvoice)
-	if err != nil {
-		return nil, err
-	}
-	payment := cmsdatabase.Payments{
-		Address:      dbInvoice.PaymentAddress,
-		AmountNeeded: int64(payout.DCRTotal),
-	}
-	for _, m := range p.Metadata {
-		switch m.ID {
-		case mdstream.IDInvoiceGeneral:
-			var mdGeneral mdstream.InvoiceGeneral
-			err := json.Unmarshal([]byte(m.Payload), &mdGeneral)
-			if err != nil {
-				return nil, fmt.Errorf("could not decode metadata '%v' token '%v': %v",
-					p.Metadata, p.CensorshipRecord.Token, err)
-			}
-
-			dbInvoice.Timestamp = mdGeneral.Timestamp
-			dbInvoice.PublicKey = mdGeneral.PublicKey
-			dbInvoice.UserSignature = mdGeneral.Signature
-		case mdstream.IDInvoiceStatusChange:
-			sc, err := mdstream.DecodeInvoiceStatusChange([]byte(m.Payload))
-			if err != nil {
-				return nil, fmt.Errorf("could not decode metadata '%v' token '%v': %v",
-					m, p.CensorshipRecord.Token, err)
-			}
-
-			invChanges := make([]cmsdatabase.InvoiceChange, 0, len(sc))
-			for _, s := range sc {
-				invChange := cmsdatabase.InvoiceChange{
-					AdminPublicKey: s.AdminPublicKey,
-					NewStatus:      s.NewStatus,
-					Reason:         s.Reason,
-					Timestamp:      s.Timestamp,
-				}
-				invChanges = append(invChanges, invChange)
-				// Capture information about payments
-				dbInvoice.Status = s.NewStatus
-				if s.NewStatus == cms.InvoiceStatusApproved {
-					payment.Status = cms.PaymentStatusWatching
-					payment.TimeStarted = s.Timestamp
-				} else if s.NewStatus == cms.InvoiceStatusPaid {
-					payment.Status = cms.PaymentStatusPaid
-				}
-			}
-			dbInvoice.Changes = invChanges
-
-		case mdstream.IDInvoicePayment:
-			ip, err := mdstream.DecodeInvoicePayment([]byte(m.Payload))
-			if err != nil {
-				return nil, fmt.Errorf("could not decode metadata '%v' token '%v': %v",
-					m, p.CensorshipRecord.Token, err)
-			}
-
-			// We don't need all of the payments.
-			// Just the most recent one.
-			for _, s := range ip {
-				payment.TxIDs = s.TxIDs
-				payment.TimeLastUpdated = s.Timestamp
-				payment.AmountReceived = s.AmountReceived
-			}
-			dbInvoice.Payments = payment
-		default:
-			// Log error but proceed
-			log.Errorf("initializeInventory: invalid "+
-				"metadata stream ID %v token %v",
-				m.ID, p.CensorshipRecord.Token)
-		}
-	}
-
-	return &dbInvoice, nil
-}
-
-func convertCacheToDatabaseInvoice(p cache.Record) (*cmsdatabase.Invoice, error) {
-	dbInvoice := cmsdatabase.Invoice{
-		Token:           p.CensorshipRecord.Token,
-		ServerSignature: p.CensorshipRecord.Signature,
-		Version:         p.Version,
-	}
-
-	fs := make([]www.File, 0, len(p.Files))
-	for _, v := range p.Files {
-		f := www.File{
-			Name:    v.Name,
-			MIME:    v.MIME,
-			Digest:  v.Digest,
-			Payload: v.Payload,
-		}
-		fs = append(fs, f)
-	}
-	dbInvoice.Files = fs
 	// Decode invoice file
 	for _, v := range p.Files {
 		if v.Name == invoiceFile {
@@ -1407,88 +1354,6 @@ func convertRecordToDatabaseDCC(p pd.Record) (*cmsdatabase.DCC, error) {
 	return &dbDCC, nil
 }
 
-func convertCacheToDatabaseDCC(p cache.Record) (*cmsdatabase.DCC, error) {
-	dbDCC := cmsdatabase.DCC{
-		Token:           p.CensorshipRecord.Token,
-		ServerSignature: p.CensorshipRecord.Signature,
-	}
-
-	fs := make([]www.File, 0, len(p.Files))
-	for _, v := range p.Files {
-		f := www.File{
-			Name:    v.Name,
-			MIME:    v.MIME,
-			Digest:  v.Digest,
-			Payload: v.Payload,
-		}
-		fs = append(fs, f)
-	}
-	dbDCC.Files = fs
-
-	// Decode invoice file
-	for _, v := range p.Files {
-		if v.Name == dccFile {
-			b, err := base64.StdEncoding.DecodeString(v.Payload)
-			if err != nil {
-				return nil, err
-			}
-
-			var dcc cms.DCCInput
-			err = json.Unmarshal(b, &dcc)
-			if err != nil {
-				return nil, fmt.Errorf("could not decode DCC input data: token '%v': %v",
-					p.CensorshipRecord.Token, err)
-			}
-			dbDCC.Type = dcc.Type
-			dbDCC.NomineeUserID = dcc.NomineeUserID
-			dbDCC.SponsorStatement = dcc.SponsorStatement
-			dbDCC.Domain = dcc.Domain
-			dbDCC.ContractorType = dcc.ContractorType
-		}
-	}
-
-	for _, m := range p.Metadata {
-		switch m.ID {
-		case mdstream.IDRecordStatusChange:
-			// Ignore initial stream change since it's just the automatic change from
-			// unvetted to vetted
-			continue
-		case mdstream.IDDCCGeneral:
-			var mdGeneral mdstream.DCCGeneral
-			err := json.Unmarshal([]byte(m.Payload), &mdGeneral)
-			if err != nil {
-				return nil, fmt.Errorf("could not decode metadata '%v' token '%v': %v",
-					p.Metadata, p.CensorshipRecord.Token, err)
-			}
-
-			dbDCC.Timestamp = mdGeneral.Timestamp
-			dbDCC.PublicKey = mdGeneral.PublicKey
-			dbDCC.UserSignature = mdGeneral.Signature
-
-		case mdstream.IDDCCStatusChange:
-			sc, err := mdstream.DecodeDCCStatusChange([]byte(m.Payload))
-			if err != nil {
-				return nil, fmt.Errorf("could not decode metadata '%v' token '%v': %v",
-					m, p.CensorshipRecord.Token, err)
-			}
-
-			// We don't need all of the status changes.
-			// Just the most recent one.
-			for _, s := range sc {
-				dbDCC.Status = s.NewStatus
-				dbDCC.StatusChangeReason = s.Reason
-			}
-		default:
-			// Log error but proceed
-			log.Errorf("initializeInventory: invalid "+
-				"metadata stream ID %v token %v",
-				m.ID, p.CensorshipRecord.Token)
-		}
-	}
-
-	return &dbDCC, nil
-}
-
 func convertDCCDatabaseToRecord(dbDCC *cmsdatabase.DCC) cms.DCCRecord {
 	dccRecord := cms.DCCRecord{}
 
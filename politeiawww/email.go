@@ -57,7 +57,7 @@ func (p *politeiawww) sendEmailTo(subject, body, toAddress string) error {
 	if p.smtp.disabled {
 		return nil
 	}
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
+	return p.sendEmail(subject, body, func(msg *goemail.Message) error {
 		msg.AddTo(toAddress)
 		return nil
 	})
@@ -163,7 +163,7 @@ func (p *politeiawww) emailAuthorForVettedProposal(proposal *www.ProposalRecord,
 		return err
 	}
 
-	return p.sendEmailTo(subject, body, authorUser.Email)
+	return p.emailUser(authorUser, www.NotificationEmailMyProposalStatusChange, subject, body)
 }
 
 // emailAuthorForCensoredProposal sends an email notification for a new
@@ -196,7 +196,7 @@ func (p *politeiawww) emailAuthorForCensoredProposal(proposal *www.ProposalRecor
 		return err
 	}
 
-	return p.sendEmailTo(subject, body, authorUser.Email)
+	return p.emailUser(authorUser, www.NotificationEmailMyProposalStatusChange, subject, body)
 }
 
 // emailUsersForVettedProposal sends an email notification for a new proposal
@@ -226,20 +226,12 @@ func (p *politeiawww) emailUsersForVettedProposal(proposal *www.ProposalRecord,
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
-		// Add user emails to the goemail.Message
-		return p.db.AllUsers(func(u *user.User) {
+	return p.emailUsers(www.NotificationEmailRegularProposalVetted, subject,
+		body, func(u *user.User) bool {
 			// Don't notify the user under certain conditions.
-			if u.NewUserPaywallTx == "" || u.Deactivated ||
-				u.ID == adminUser.ID || u.ID == authorUser.ID ||
-				(u.EmailNotifications&
-					uint64(www.NotificationEmailRegularProposalVetted)) == 0 {
-				return
-			}
-
-			msg.AddBCC(u.Email)
+			return u.NewUserPaywallTx != "" &&
+				u.ID != adminUser.ID && u.ID != authorUser.ID
 		})
-	})
 }
 
 // emailUsersForEditedProposal sends an email notification for a proposal being
@@ -270,20 +262,11 @@ func (p *politeiawww) emailUsersForEditedProposal(proposal *www.ProposalRecord,
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
-		// Add user emails to the goemail.Message
-		return p.db.AllUsers(func(u *user.User) {
+	return p.emailUsers(www.NotificationEmailRegularProposalEdited, subject,
+		body, func(u *user.User) bool {
 			// Don't notify the user under certain conditions.
-			if u.NewUserPaywallTx == "" || u.Deactivated ||
-				u.ID == authorUser.ID ||
-				(u.EmailNotifications&
-					uint64(www.NotificationEmailRegularProposalEdited)) == 0 {
-				return
-			}
-
-			msg.AddBCC(u.Email)
+			return u.NewUserPaywallTx != "" && u.ID != authorUser.ID
 		})
-	})
 }
 
 // emailUsersForProposalVoteStarted sends an email notification for a proposal
@@ -316,7 +299,8 @@ func (p *politeiawww) emailUsersForProposalVoteStarted(proposal *www.ProposalRec
 			return err
 		}
 
-		err = p.sendEmailTo(subject, body, authorUser.Email)
+		err = p.emailUser(authorUser,
+			www.NotificationEmailMyProposalVoteStarted, subject, body)
 		if err != nil {
 			return err
 		}
@@ -328,21 +312,12 @@ func (p *politeiawww) emailUsersForProposalVoteStarted(proposal *www.ProposalRec
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
-		// Add user emails to the goemail.Message
-		return p.db.AllUsers(func(u *user.User) {
+	return p.emailUsers(www.NotificationEmailRegularProposalVoteStarted,
+		subject, body, func(u *user.User) bool {
 			// Don't notify the user under certain conditions.
-			if u.NewUserPaywallTx == "" || u.Deactivated ||
-				u.ID == adminUser.ID ||
-				u.ID == authorUser.ID ||
-				(u.EmailNotifications&
-					uint64(www.NotificationEmailRegularProposalVoteStarted)) == 0 {
-				return
-			}
-
-			msg.AddBCC(u.Email)
+			return u.NewUserPaywallTx != "" &&
+				u.ID != adminUser.ID && u.ID != authorUser.ID
 		})
-	})
 }
 
 func (p *politeiawww) emailAdminsForNewSubmittedProposal(token string, propName string, username string, userEmail string) error {
@@ -368,17 +343,10 @@ func (p *politeiawww) emailAdminsForNewSubmittedProposal(token string, propName
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
-		// Add admin emails to the goemail.Message
-		return p.db.AllUsers(func(u *user.User) {
-			if !u.Admin || u.Deactivated ||
-				(u.EmailNotifications&
-					uint64(www.NotificationEmailAdminProposalNew) == 0) {
-				return
-			}
-			msg.AddBCC(u.Email)
+	return p.emailUsers(www.NotificationEmailAdminProposalNew, subject, body,
+		func(u *user.User) bool {
+			return u.Admin
 		})
-	})
 }
 
 func (p *politeiawww) emailAdminsForProposalVoteAuthorized(proposal *www.ProposalRecord, authorUser *user.User) error {
@@ -405,17 +373,10 @@ func (p *politeiawww) emailAdminsForProposalVoteAuthorized(proposal *www.Proposa
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
-		// Add admin emails to the goemail.Message
-		return p.db.AllUsers(func(u *user.User) {
-			if !u.Admin || u.Deactivated ||
-				(u.EmailNotifications&
-					uint64(www.NotificationEmailAdminProposalVoteAuthorized) == 0) {
-				return
-			}
-			msg.AddBCC(u.Email)
+	return p.emailUsers(www.NotificationEmailAdminProposalVoteAuthorized, subject, body,
+		func(u *user.User) bool {
+			return u.Admin
 		})
-	})
 }
 
 // emailAuthorForCommentOnProposal sends an email notification to a proposal
@@ -453,7 +414,7 @@ func (p *politeiawww) emailAuthorForCommentOnProposal(proposal *www.ProposalReco
 		return err
 	}
 
-	return p.sendEmailTo(subject, body, authorUser.Email)
+	return p.emailUser(authorUser, www.NotificationEmailCommentOnMyProposal, subject, body)
 }
 
 // emailAuthorForCommentOnComment sends an email notification to a comment
@@ -491,7 +452,73 @@ func (p *politeiawww) emailAuthorForCommentOnComment(proposal *www.ProposalRecor
 		return err
 	}
 
-	return p.sendEmailTo(subject, body, authorUser.Email)
+	return p.emailUser(authorUser, www.NotificationEmailCommentOnMyComment, subject, body)
+}
+
+// emailFollowersForProposal sends an email notification for activity on a
+// proposal to the users that follow the proposal. The user that caused the
+// activity is not notified. The link points to the activity when it is set
+// and to the proposal otherwise. Followers that can not be notified are
+// logged and skipped.
+func (p *politeiawww) emailFollowersForProposal(proposal *www.ProposalRecord, activity, link, actorID string) error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	token := proposal.CensorshipRecord.Token
+	follows, err := p.db.ProposalFollowsByToken(token)
+	if err != nil {
+		return err
+	}
+	if len(follows) == 0 {
+		return nil
+	}
+
+	if link == "" {
+		l, err := url.Parse(p.cfg.WebServerAddress + "/proposals/" + token)
+		if err != nil {
+			return err
+		}
+		link = l.String()
+	}
+
+	tplData := followedProposalTemplateData{
+		Link:     link,
+		Name:     proposal.Name,
+		Activity: activity,
+	}
+
+	subject := "New Activity On A Followed Proposal"
+	body, err := createBody(templateFollowedProposal, &tplData)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range follows {
+		if v.UserID.String() == actorID {
+			continue
+		}
+		// A failure to notify one follower must not keep the
+		// remaining followers from being notified.
+		u, err := p.db.UserGetById(v.UserID)
+		if err != nil {
+			log.Errorf("emailFollowersForProposal %v: UserGetById %v: %v",
+				token, v.UserID, err)
+			continue
+		}
+		if u.Deactivated {
+			continue
+		}
+		err = p.emailUser(u, www.NotificationEmailFollowedProposal, subject,
+			body)
+		if err != nil {
+			log.Errorf("emailFollowersForProposal %v: emailUser %v: %v",
+				token, v.UserID, err)
+			continue
+		}
+	}
+
+	return nil
 }
 
 // emailUpdateUserKeyVerificationLink emails the link with the verification
@@ -692,7 +719,7 @@ func (p *politeiawww) emailAdminsForNewDCC(token string) error {
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
+	return p.sendEmail(subject, body, func(msg *goemail.Message) error {
 		// Add admin emails to the goemail.Message
 		return p.db.AllUsers(func(u *user.User) {
 			if !u.Admin || u.Deactivated {
@@ -723,7 +750,7 @@ func (p *politeiawww) emailAdminsForNewDCCSupportOppose(token string) error {
 		return err
 	}
 
-	return p.smtp.sendEmail(subject, body, func(msg *goemail.Message) error {
+	return p.sendEmail(subject, body, func(msg *goemail.Message) error {
 		// Add admin emails to the goemail.Message
 		return p.db.AllUsers(func(u *user.User) {
 			if !u.Admin || u.Deactivated {
@@ -0,0 +1,257 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dajohi/goemail"
+	"github.com/google/uuid"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+// Seconds Minutes Hours Days Months DayOfWeek
+const emailDigestSchedule = "0 0 * * * *" // Check at the start of every hour
+
+// emailModeT represents how a user is notified of an email notification.
+type emailModeT int
+
+const (
+	emailModeOff       emailModeT = 0 // Not notified
+	emailModeImmediate emailModeT = 1 // Notified with a separate email
+	emailModeDigest    emailModeT = 2 // Notified in the email digest
+)
+
+// emailNotificationMode returns how the user is notified of the given email
+// notification. Notifications that are enabled are sent immediately unless
+// the user has moved them to the email digest.
+func emailNotificationMode(u *user.User, n www.EmailNotificationT) emailModeT {
+	switch {
+	case u.EmailNotifications&uint64(n) == 0:
+		return emailModeOff
+	case u.EmailDigests&uint64(n) != 0:
+		return emailModeDigest
+	default:
+		return emailModeImmediate
+	}
+}
+
+// validEmailDigestFrequency returns whether the given email digest frequency
+// is valid.
+func validEmailDigestFrequency(f www.EmailDigestFrequencyT) bool {
+	switch f {
+	case www.EmailDigestFrequencyDaily, www.EmailDigestFrequencyWeekly:
+		return true
+	}
+	return false
+}
+
+// emailDigestFrequency returns the email digest frequency of the user. Users
+// that have never picked a frequency get a daily digest.
+func emailDigestFrequency(u *user.User) www.EmailDigestFrequencyT {
+	f := www.EmailDigestFrequencyT(u.EmailDigestFreq)
+	if !validEmailDigestFrequency(f) {
+		return www.EmailDigestFrequencyDaily
+	}
+	return f
+}
+
+// emailDigestPeriodStart returns the start of the digest period that the
+// given time falls in. Daily periods start at midnight UTC and weekly periods
+// start on Monday at midnight UTC.
+func emailDigestPeriodStart(f www.EmailDigestFrequencyT, t time.Time) time.Time {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if f == www.EmailDigestFrequencyWeekly {
+		// Weekday counts from Sunday
+		days := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -days)
+	}
+	return start
+}
+
+// queueEmailDigestEntry saves a notification that is sent to the user in
+// their next email digest.
+func (p *politeiawww) queueEmailDigestEntry(userID uuid.UUID, n www.EmailNotificationT, subject, body string) error {
+	err := p.db.EmailDigestEntrySave(user.EmailDigestEntry{
+		ID:           uuid.New(),
+		UserID:       userID,
+		Notification: uint64(n),
+		Subject:      subject,
+		Body:         body,
+		CreatedAt:    time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("EmailDigestEntrySave: %v", err)
+	}
+	return nil
+}
+
+// emailUser notifies a single user of the given email notification according
+// to the user's notification preferences.
+func (p *politeiawww) emailUser(u *user.User, n www.EmailNotificationT, subject, body string) error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	switch emailNotificationMode(u, n) {
+	case emailModeImmediate:
+		return p.sendEmailTo(subject, body, u.Email)
+	case emailModeDigest:
+		return p.queueEmailDigestEntry(u.ID, n, subject, body)
+	}
+
+	return nil
+}
+
+// emailUsers notifies every active user for which include returns true of
+// the given email notification according to the user's notification
+// preferences. Users that are notified immediately are sent a single email
+// with the users BCC'd.
+func (p *politeiawww) emailUsers(n www.EmailNotificationT, subject, body string, include func(*user.User) bool) error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	digest := make([]uuid.UUID, 0)
+	err := p.sendEmail(subject, body, func(msg *goemail.Message) error {
+		// Add user emails to the goemail.Message
+		return p.db.AllUsers(func(u *user.User) {
+			if u.Deactivated || !include(u) {
+				return
+			}
+			switch emailNotificationMode(u, n) {
+			case emailModeImmediate:
+				msg.AddBCC(u.Email)
+			case emailModeDigest:
+				digest = append(digest, u.ID)
+			}
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, v := range digest {
+		err := p.queueEmailDigestEntry(v, n, subject, body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emailUserDigest sends the user an email digest that contains the given
+// entries.
+func (p *politeiawww) emailUserDigest(u *user.User, entries []user.EmailDigestEntry) error {
+	period := "Daily"
+	if emailDigestFrequency(u) == www.EmailDigestFrequencyWeekly {
+		period = "Weekly"
+	}
+
+	tplData := emailDigestTemplateData{
+		Username: u.Username,
+		Period:   strings.ToLower(period),
+		Entries:  make([]emailDigestEntryTemplateData, 0, len(entries)),
+	}
+	for _, v := range entries {
+		tplData.Entries = append(tplData.Entries,
+			emailDigestEntryTemplateData{
+				Subject: v.Subject,
+				Body:    strings.TrimSpace(v.Body),
+			})
+	}
+
+	subject := "Your " + period + " Politeia Digest"
+	body, err := createBody(templateEmailDigest, &tplData)
+	if err != nil {
+		return err
+	}
+
+	return p.sendEmailTo(subject, body, u.Email)
+}
+
+// sendEmailDigests sends the email digests that are due. A user's digest is
+// due once an entry in it was created before the start of the user's current
+// digest period. The entries of a digest are deleted once the digest has been
+// queued in the email outbox. Entries of deactivated users are dropped.
+func (p *politeiawww) sendEmailDigests(now time.Time) {
+	entries, err := p.db.AllEmailDigestEntries()
+	if err != nil {
+		log.Errorf("sendEmailDigests: AllEmailDigestEntries: %v", err)
+		return
+	}
+
+	// Group the entries by user. Entries are ordered oldest first.
+	users := make([]uuid.UUID, 0)
+	byUser := make(map[uuid.UUID][]user.EmailDigestEntry)
+	for _, v := range entries {
+		if _, ok := byUser[v.UserID]; !ok {
+			users = append(users, v.UserID)
+		}
+		byUser[v.UserID] = append(byUser[v.UserID], v)
+	}
+
+	for _, userID := range users {
+		ue := byUser[userID]
+		u, err := p.db.UserGetById(userID)
+		switch {
+		case err == user.ErrUserNotFound:
+			u = &user.User{Deactivated: true}
+		case err != nil:
+			log.Errorf("sendEmailDigests: UserGetById %v: %v", userID, err)
+			continue
+		}
+
+		if !u.Deactivated {
+			start := emailDigestPeriodStart(emailDigestFrequency(u), now)
+			if ue[0].CreatedAt >= start.Unix() {
+				// Not due yet
+				continue
+			}
+
+			err = p.emailUserDigest(u, ue)
+			if err != nil {
+				log.Errorf("sendEmailDigests: emailUserDigest %v: %v",
+					userID, err)
+				continue
+			}
+			log.Debugf("Email digest queued for %v: %v entries",
+				userID, len(ue))
+		}
+
+		for _, v := range ue {
+			err = p.db.EmailDigestEntryDeleteByID(v.ID)
+			if err != nil {
+				log.Errorf("sendEmailDigests: EmailDigestEntryDeleteByID "+
+					"%v: %v", v.ID, err)
+			}
+		}
+	}
+}
+
+// initEmailDigests launches the cron job that sends the email digests that
+// are due. The job runs hourly so that digests that were missed while
+// politeiawww was down are sent once it is back up.
+func (p *politeiawww) initEmailDigests() error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	log.Infof("Starting cron for email digests")
+	err := p.cron.AddFunc(emailDigestSchedule, func() {
+		p.sendEmailDigests(time.Now())
+	})
+	if err != nil {
+		return err
+	}
+	p.cron.Start()
+
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+func TestProcessEditUserEmailDigests(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	u, _ := newUser(t, p, true, false)
+
+	notifs := uint64(www.NotificationEmailRegularProposalVetted |
+		www.NotificationEmailCommentOnMyProposal)
+	digests := uint64(www.NotificationEmailRegularProposalVetted)
+	weekly := www.EmailDigestFrequencyWeekly
+	invalid := www.EmailDigestFrequencyT(3)
+
+	var tests = []struct {
+		name string
+		eu   www.EditUser
+		want error
+	}{
+		{"invalid digest frequency",
+			www.EditUser{
+				EmailDigestFrequency: &invalid,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidEmailDigestFrequency,
+			}},
+
+		{"success",
+			www.EditUser{
+				EmailNotifications:   &notifs,
+				EmailDigests:         &digests,
+				EmailDigestFrequency: &weekly,
+			},
+			nil},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processEditUser(&v.eu, u)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+
+			if v.want != nil {
+				return
+			}
+
+			ud, err := p.db.UserGetById(u.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wu := convertWWWUserFromDatabaseUser(ud)
+			if wu.EmailNotifications != notifs ||
+				wu.EmailDigests != digests ||
+				wu.EmailDigestFrequency != weekly {
+				t.Errorf("got preferences %v %v %v, want %v %v %v",
+					wu.EmailNotifications, wu.EmailDigests,
+					wu.EmailDigestFrequency, notifs, digests, weekly)
+			}
+		})
+	}
+}
+
+func TestEmailNotificationMode(t *testing.T) {
+	n := www.NotificationEmailRegularProposalVetted
+	var tests = []struct {
+		name    string
+		notifs  uint64
+		digests uint64
+		want    emailModeT
+	}{
+		{"off", 0, 0, emailModeOff},
+		{"off with digest", 0, uint64(n), emailModeOff},
+		{"immediate", uint64(n), 0, emailModeImmediate},
+		{"digest", uint64(n), uint64(n), emailModeDigest},
+	}
+
+	for _, v := range tests {
+		u := user.User{
+			EmailNotifications: v.notifs,
+			EmailDigests:       v.digests,
+		}
+		got := emailNotificationMode(&u, n)
+		if got != v.want {
+			t.Errorf("%v: got mode %v, want %v", v.name, got, v.want)
+		}
+	}
+}
+
+func TestEmailDigestPeriodStart(t *testing.T) {
+	// Wednesday
+	now := time.Date(2019, 11, 13, 15, 4, 5, 0, time.UTC)
+	var tests = []struct {
+		frequency www.EmailDigestFrequencyT
+		now       time.Time
+		want      time.Time
+	}{
+		{www.EmailDigestFrequencyDaily, now,
+			time.Date(2019, 11, 13, 0, 0, 0, 0, time.UTC)},
+		{www.EmailDigestFrequencyWeekly, now,
+			time.Date(2019, 11, 11, 0, 0, 0, 0, time.UTC)},
+		// Sunday belongs to the week that started on Monday
+		{www.EmailDigestFrequencyWeekly,
+			time.Date(2019, 11, 17, 23, 0, 0, 0, time.UTC),
+			time.Date(2019, 11, 11, 0, 0, 0, 0, time.UTC)},
+		{www.EmailDigestFrequencyWeekly,
+			time.Date(2019, 11, 18, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, 11, 18, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, v := range tests {
+		got := emailDigestPeriodStart(v.frequency, v.now)
+		if !got.Equal(v.want) {
+			t.Errorf("%v %v: got %v, want %v", v.frequency, v.now,
+				got, v.want)
+		}
+	}
+}
+
+func TestEmailDigests(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	// Write emails to a maildir
+	dir, err := ioutil.TempDir("", "maildir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p.smtp, err = newSMTP("", "", "", "Politeia <noreply@example.org>",
+		dir, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup users for every notification mode
+	n := www.NotificationEmailRegularProposalVetted
+	immediate, _ := newUser(t, p, true, false)
+	immediate.EmailNotifications = uint64(n)
+	digest, _ := newUser(t, p, true, false)
+	digest.EmailNotifications = uint64(n)
+	digest.EmailDigests = uint64(n)
+	off, _ := newUser(t, p, true, false)
+	off.EmailNotifications = 0
+	for _, u := range []*user.User{immediate, digest, off} {
+		err := p.db.UserUpdate(*u)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = p.emailUsers(n, "Subject", "Body", func(u *user.User) bool {
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the immediate user has been emailed
+	pending, err := p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Recipient != immediate.Email {
+		t.Fatalf("got pending emails %v, want one to %v", pending,
+			immediate.Email)
+	}
+	err = p.db.EmailDeleteByID(pending[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := p.db.AllEmailDigestEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].UserID != digest.ID {
+		t.Fatalf("got digest entries %v, want one for %v", entries,
+			digest.ID)
+	}
+
+	// The digest is not sent before the period has ended
+	now := time.Now()
+	p.sendEmailDigests(now)
+	pending, err = p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("got %v pending emails, want 0", len(pending))
+	}
+
+	// The digest is sent once the period has ended
+	p.sendEmailDigests(now.Add(24 * time.Hour))
+	pending, err = p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Recipient != digest.Email {
+		t.Fatalf("got pending emails %v, want one to %v", pending,
+			digest.Email)
+	}
+	if !strings.Contains(pending[0].Body, "Subject") ||
+		!strings.Contains(pending[0].Body, "Body") {
+		t.Errorf("digest does not contain the notification: %v",
+			pending[0].Body)
+	}
+	entries, err = p.db.AllEmailDigestEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("got %v digest entries, want 0", len(entries))
+	}
+}
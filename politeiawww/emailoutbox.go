@@ -0,0 +1,298 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dajohi/goemail"
+	"github.com/google/uuid"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+const (
+	// emailMaxAttempts is the number of times an email is attempted before
+	// it is marked as failed.
+	emailMaxAttempts = 10
+
+	// emailRetryInterval is the delay before the first retry of an email
+	// that could not be sent. The delay doubles with every attempt up to
+	// emailMaxRetryInterval.
+	emailRetryInterval    = time.Minute
+	emailMaxRetryInterval = 6 * time.Hour
+
+	// emailPollInterval is the interval at which the outbox is checked for
+	// emails that are due.
+	emailPollInterval = 10 * time.Second
+
+	// emailRateLimitWindow is the window over which the number of emails
+	// that are sent to a single recipient is limited.
+	emailRateLimitWindow = time.Hour
+
+	// failedEmailsLimit is the maximum number of emails that are returned
+	// by the FailedEmails command.
+	failedEmailsLimit = 100
+)
+
+// emailRateLimiter limits the number of emails that are sent to a single
+// recipient within emailRateLimitWindow. Emails that exceed the limit are
+// deferred, not dropped.
+type emailRateLimiter struct {
+	sync.Mutex
+	limit int                // Max emails per recipient per window, 0 is unlimited
+	sent  map[string][]int64 // [recipient][]UNIX timestamp of send attempt
+}
+
+// newEmailRateLimiter returns a new emailRateLimiter.
+func newEmailRateLimiter(limit uint) *emailRateLimiter {
+	return &emailRateLimiter{
+		limit: int(limit),
+		sent:  make(map[string][]int64),
+	}
+}
+
+// reserve records a send attempt to the recipient and returns true if the
+// recipient is within its limit. Otherwise it returns false along with the
+// time at which the recipient is within its limit again.
+func (r *emailRateLimiter) reserve(recipient string, now time.Time) (bool, time.Time) {
+	if r.limit == 0 {
+		return true, now
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// Forget the send attempts that have left the window
+	recipient = strings.ToLower(recipient)
+	windowStart := now.Add(-emailRateLimitWindow).Unix()
+	sent := r.sent[recipient]
+	for len(sent) > 0 && sent[0] <= windowStart {
+		sent = sent[1:]
+	}
+
+	if len(sent) >= r.limit {
+		r.sent[recipient] = sent
+		return false, time.Unix(sent[0], 0).Add(emailRateLimitWindow)
+	}
+
+	r.sent[recipient] = append(sent, now.Unix())
+	return true, now
+}
+
+// prune forgets all recipients that have no send attempts within the window.
+func (r *emailRateLimiter) prune(now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	windowStart := now.Add(-emailRateLimitWindow).Unix()
+	for k, v := range r.sent {
+		if len(v) == 0 || v[len(v)-1] <= windowStart {
+			delete(r.sent, k)
+		}
+	}
+}
+
+// emailRetryDelay returns the delay before the next attempt of an email that
+// has failed the provided number of times.
+func emailRetryDelay(attempts uint32) time.Duration {
+	return retryDelay(emailRetryInterval, emailMaxRetryInterval, attempts)
+}
+
+// convertWWWFailedEmailFromUser converts a user database email to a www
+// failed email. The body is left out since it can contain verification
+// tokens.
+func convertWWWFailedEmailFromUser(e user.Email) www.FailedEmail {
+	return www.FailedEmail{
+		ID:          e.ID.String(),
+		Recipient:   e.Recipient,
+		Subject:     e.Subject,
+		Attempts:    e.Attempts,
+		LastAttempt: e.LastAttempt,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+// wakeEmailSender signals the email sender that there are new emails in the
+// outbox.
+func (p *politeiawww) wakeEmailSender() {
+	select {
+	case p.emailC <- struct{}{}:
+	default:
+	}
+}
+
+// sendEmail queues an email with the given subject and body in the email
+// outbox, and the caller must supply a function which is used to add email
+// addresses to send the email to. A separate email is queued for every
+// recipient so that recipients are retried and rate limited independently.
+func (p *politeiawww) sendEmail(subject, body string, addToAddressesFn func(*goemail.Message) error) error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	var msg goemail.Message
+	err := addToAddressesFn(&msg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	queued := make(map[string]struct{})
+	for _, r := range msg.Recipients() {
+		if _, ok := queued[r]; ok {
+			continue
+		}
+		queued[r] = struct{}{}
+
+		err := p.db.EmailSave(user.Email{
+			ID:          uuid.New(),
+			Recipient:   r,
+			Subject:     subject,
+			Body:        body,
+			Status:      user.EmailStatusPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			return fmt.Errorf("EmailSave: %v", err)
+		}
+	}
+
+	if len(queued) > 0 {
+		p.wakeEmailSender()
+	}
+
+	return nil
+}
+
+// attemptEmail attempts to send an email from the outbox and updates the
+// email with the outcome. It returns whether the email was sent.
+func (p *politeiawww) attemptEmail(e *user.Email) bool {
+	now := time.Now()
+	e.Attempts++
+	e.LastAttempt = now.Unix()
+	e.Error = ""
+
+	err := p.smtp.sendEmail(e.Subject, e.Body,
+		func(msg *goemail.Message) error {
+			msg.AddTo(e.Recipient)
+			return nil
+		})
+	switch {
+	case err == nil:
+		return true
+	case e.Attempts >= emailMaxAttempts:
+		e.Status = user.EmailStatusFailed
+		e.NextAttempt = 0
+		e.Error = err.Error()
+	default:
+		e.NextAttempt = now.Add(emailRetryDelay(e.Attempts)).Unix()
+		e.Error = err.Error()
+	}
+
+	return false
+}
+
+// sendPendingEmails attempts every email in the outbox that is due. Emails
+// that have been sent are removed from the outbox.
+func (p *politeiawww) sendPendingEmails() {
+	pending, err := p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		log.Errorf("sendPendingEmails: EmailsByStatus: %v", err)
+		return
+	}
+
+	for _, e := range pending {
+		now := time.Now()
+		if e.NextAttempt > now.Unix() {
+			continue
+		}
+
+		ok, next := p.emailLimits.reserve(e.Recipient, now)
+		if !ok {
+			log.Debugf("Email %v: rate limited until %v", e.ID,
+				next.Format(time.RFC3339))
+			e.NextAttempt = next.Unix()
+		} else if p.attemptEmail(&e) {
+			log.Debugf("Email %v: sent", e.ID)
+			err = p.db.EmailDeleteByID(e.ID)
+			if err != nil {
+				log.Errorf("sendPendingEmails: EmailDeleteByID %v: %v",
+					e.ID, err)
+			}
+			continue
+		} else if e.Status == user.EmailStatusFailed {
+			log.Errorf("Email %v: giving up after %v attempts: %v",
+				e.ID, e.Attempts, e.Error)
+		} else {
+			log.Infof("Email %v: attempt %v: %v", e.ID, e.Attempts,
+				e.Error)
+		}
+
+		err = p.db.EmailSave(e)
+		if err != nil {
+			log.Errorf("sendPendingEmails: EmailSave %v: %v", e.ID, err)
+		}
+	}
+
+	p.emailLimits.prune(time.Now())
+}
+
+// emailSender sends the emails in the outbox. It is woken up when emails are
+// queued and periodically checks for emails that are due for a retry.
+func (p *politeiawww) emailSender() {
+	for {
+		p.sendPendingEmails()
+
+		select {
+		case <-p.emailC:
+		case <-time.After(emailPollInterval):
+		}
+	}
+}
+
+// initEmailOutbox starts the thread that sends the emails in the outbox.
+// Emails that were queued before politeiawww was restarted are resumed.
+func (p *politeiawww) initEmailOutbox() error {
+	if p.smtp.disabled {
+		return nil
+	}
+
+	pending, err := p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Pending emails: %v", len(pending))
+
+	go p.emailSender()
+
+	return nil
+}
+
+// processFailedEmails returns the most recent emails that could not be sent,
+// newest first.
+func (p *politeiawww) processFailedEmails() (*www.FailedEmailsReply, error) {
+	log.Tracef("processFailedEmails")
+
+	failed, err := p.db.EmailsByStatus(user.EmailStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]www.FailedEmail, 0, failedEmailsLimit)
+	for i := len(failed) - 1; i >= 0 && len(emails) < failedEmailsLimit; i-- {
+		emails = append(emails, convertWWWFailedEmailFromUser(failed[i]))
+	}
+
+	return &www.FailedEmailsReply{
+		Emails: emails,
+	}, nil
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dajohi/goemail"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+// failingTransport is a mail transport that fails every send.
+type failingTransport struct{}
+
+func (failingTransport) send(*goemail.Message) error {
+	return errors.New("connection refused")
+}
+
+// readMaildir returns the messages in the new directory of a maildir.
+func readMaildir(t *testing.T, dir string) []*mail.Message {
+	t.Helper()
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make([]*mail.Message, 0, len(files))
+	for _, v := range files {
+		f, err := os.Open(filepath.Join(dir, "new", v.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := mail.ReadMessage(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestEmailOutbox(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	// Write emails to a maildir
+	dir, err := ioutil.TempDir("", "maildir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p.smtp, err = newSMTP("", "", "", "Politeia <noreply@example.org>",
+		dir, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Queue an email for two recipients. Duplicate recipients only
+	// receive the email once.
+	err = p.sendEmail("Subject", "Body", func(msg *goemail.Message) error {
+		msg.AddBCC("alice@example.org")
+		msg.AddBCC("bob@example.org")
+		msg.AddBCC("alice@example.org")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("got %v pending emails, want 2", len(pending))
+	}
+
+	// Send the emails
+	p.sendPendingEmails()
+	msgs := readMaildir(t, dir)
+	if len(msgs) != 2 {
+		t.Fatalf("got %v messages, want 2", len(msgs))
+	}
+	recipients := make(map[string]struct{})
+	for _, msg := range msgs {
+		recipients[msg.Header.Get("Delivered-To")] = struct{}{}
+		if msg.Header.Get("Subject") != "Subject" {
+			t.Errorf("got subject %v, want Subject",
+				msg.Header.Get("Subject"))
+		}
+		if msg.Header.Get("Message-ID") == "" {
+			t.Errorf("missing message id")
+		}
+	}
+	for _, v := range []string{"alice@example.org", "bob@example.org"} {
+		if _, ok := recipients[v]; !ok {
+			t.Errorf("no message delivered to %v", v)
+		}
+	}
+	pending, err = p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("got %v pending emails, want 0", len(pending))
+	}
+
+	// An email that can't be sent stays in the outbox
+	p.smtp.transport = failingTransport{}
+	err = p.sendEmailTo("Subject", "Body", "alice@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.sendPendingEmails()
+	pending, err = p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("got %v pending emails, want 1", len(pending))
+	}
+	e := pending[0]
+	if e.Attempts != 1 || e.Error == "" ||
+		e.NextAttempt <= time.Now().Unix() {
+		t.Fatalf("unexpected pending email %v", e)
+	}
+
+	// The email is dead lettered after the last attempt
+	e.Attempts = emailMaxAttempts - 1
+	e.NextAttempt = 0
+	err = p.db.EmailSave(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.sendPendingEmails()
+	fer, err := p.processFailedEmails()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fer.Emails) != 1 || fer.Emails[0].ID != e.ID.String() ||
+		fer.Emails[0].Attempts != emailMaxAttempts {
+		t.Fatalf("unexpected failed emails %v", fer.Emails)
+	}
+}
+
+func TestEmailRateLimiter(t *testing.T) {
+	now := time.Now()
+	r := newEmailRateLimiter(2)
+
+	for i := 0; i < 2; i++ {
+		ok, _ := r.reserve("alice@example.org", now)
+		if !ok {
+			t.Fatalf("email %v was rate limited", i)
+		}
+	}
+
+	// Recipients are limited independently and case insensitively
+	ok, next := r.reserve("Alice@example.org", now.Add(time.Minute))
+	if ok {
+		t.Fatalf("email over the limit was not rate limited")
+	}
+	if want := now.Add(emailRateLimitWindow); next.Unix() != want.Unix() {
+		t.Errorf("got next attempt %v, want %v", next, want)
+	}
+	ok, _ = r.reserve("bob@example.org", now)
+	if !ok {
+		t.Fatalf("email to another recipient was rate limited")
+	}
+
+	// Send attempts leave the window
+	ok, _ = r.reserve("alice@example.org", now.Add(emailRateLimitWindow))
+	if !ok {
+		t.Fatalf("email was rate limited after the window")
+	}
+
+	// A limit of zero disables rate limiting
+	r = newEmailRateLimiter(0)
+	for i := 0; i < 10; i++ {
+		ok, _ := r.reserve("alice@example.org", now)
+		if !ok {
+			t.Fatalf("email %v was rate limited", i)
+		}
+	}
+}
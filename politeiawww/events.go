@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/thi4go/politeia/decredplugin"
 	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
 	www2 "github.com/thi4go/politeia/politeiawww/api/www/v2"
 	"github.com/thi4go/politeia/politeiawww/user"
@@ -35,6 +36,9 @@ const (
 	EventTypeInvoiceStatusUpdate // CMS Type
 	EventTypeDCCNew              // DCC Type
 	EventTypeDCCSupportOppose    // DCC Type
+	EventTypeCommentVote
+	EventTypeVotesCast
+	EventTypeProposalVoteFinished
 )
 
 type EventDataProposalSubmitted struct {
@@ -54,8 +58,9 @@ type EventDataProposalEdited struct {
 }
 
 type EventDataProposalVoteStarted struct {
-	AdminUser *user.User
-	StartVote *www2.StartVote
+	AdminUser      *user.User
+	StartVote      *www2.StartVote
+	StartVoteReply *www2.StartVoteReply
 }
 
 type EventDataProposalVoteAuthorized struct {
@@ -67,6 +72,23 @@ type EventDataComment struct {
 	Comment *www.Comment
 }
 
+type EventDataCommentVote struct {
+	Token     string
+	CommentID string
+	Upvotes   uint64
+	Downvotes uint64
+}
+
+type EventDataVotesCast struct {
+	Token string
+	Votes map[string]uint64 // [votebit]number of votes
+}
+
+type EventDataProposalVoteFinished struct {
+	Token     string
+	EndHeight uint64
+}
+
 type EventDataUserManage struct {
 	AdminUser  *user.User
 	User       *user.User
@@ -136,6 +158,8 @@ func (p *politeiawww) initEventManager() {
 	p._setupProposalStatusChangeLogging()
 	p._setupProposalVoteStartedLogging()
 	p._setupUserManageLogging()
+	p._setupWebsocketNotifications()
+	p._setupWebhooks()
 
 	if p.smtp.disabled {
 		return
@@ -147,6 +171,7 @@ func (p *politeiawww) initEventManager() {
 	p._setupProposalVoteStartedEmailNotification()
 	p._setupProposalVoteAuthorizedEmailNotification()
 	p._setupCommentReplyEmailNotifications()
+	p._setupFollowedProposalEmailNotifications()
 }
 
 func (p *politeiawww) initCMSEventManager() {
@@ -155,6 +180,8 @@ func (p *politeiawww) initCMSEventManager() {
 
 	p.eventManager = &EventManager{}
 
+	p._setupWebhooks()
+
 	if p.smtp.disabled {
 		return
 	}
@@ -503,6 +530,97 @@ func (p *politeiawww) _setupCommentReplyEmailNotifications() {
 	p.eventManager._register(EventTypeComment, ch)
 }
 
+// _setupFollowedProposalEmailNotifications emails the followers of a proposal
+// when there is new activity on the proposal.
+//
+// This function must be called WITH the mutex held.
+func (p *politeiawww) _setupFollowedProposalEmailNotifications() {
+	ch := make(chan interface{})
+	go func() {
+		for data := range ch {
+			var (
+				proposal *www.ProposalRecord
+				token    string
+				activity string
+				link     string
+				actorID  string // User that caused the activity
+			)
+			switch d := data.(type) {
+			case EventDataProposalEdited:
+				proposal = d.Proposal
+				token = d.Proposal.CensorshipRecord.Token
+				activity = fmt.Sprintf("The proposal has been edited "+
+					"(version %v).", d.Proposal.Version)
+				actorID = d.Proposal.UserId
+
+			case EventDataComment:
+				token = d.Comment.Token
+				activity = fmt.Sprintf("%v commented on the proposal.",
+					d.Comment.Username)
+				link = fmt.Sprintf("%v/proposals/%v/comments/%v",
+					p.cfg.WebServerAddress, token, d.Comment.CommentID)
+				actorID = d.Comment.UserID
+
+			case EventDataProposalVoteAuthorized:
+				token = d.AuthorizeVote.Token
+				activity = "The proposal author has authorized voting " +
+					"to start."
+				if d.AuthorizeVote.Action == decredplugin.AuthVoteActionRevoke {
+					activity = "The proposal author has revoked the vote " +
+						"authorization."
+				}
+				actorID = d.User.ID.String()
+
+			case EventDataProposalVoteStarted:
+				token = d.StartVote.Vote.Token
+				activity = "Voting on the proposal has started."
+				actorID = d.AdminUser.ID.String()
+
+			case EventDataProposalVoteFinished:
+				token = d.Token
+				activity = "Voting on the proposal has finished."
+
+			case EventDataProposalStatusChange:
+				proposal = d.Proposal
+				token = d.Proposal.CensorshipRecord.Token
+				activity = fmt.Sprintf("The proposal status has been "+
+					"changed to %v.",
+					www.PropStatus[d.SetProposalStatus.ProposalStatus])
+				if d.SetProposalStatus.StatusChangeMessage != "" {
+					activity += "\nReason: " +
+						d.SetProposalStatus.StatusChangeMessage
+				}
+				actorID = d.AdminUser.ID.String()
+
+			default:
+				log.Errorf("invalid event data")
+				continue
+			}
+
+			if proposal == nil {
+				var err error
+				proposal, err = p.getProp(token)
+				if err != nil {
+					log.Errorf("proposal not found %v: %v", token, err)
+					continue
+				}
+			}
+
+			err := p.emailFollowersForProposal(proposal, activity, link,
+				actorID)
+			if err != nil {
+				log.Errorf("email followers of proposal %v: %v", token, err)
+			}
+		}
+	}()
+	p.eventManager._register(EventTypeProposalEdited, ch)
+	p.eventManager._register(EventTypeComment, ch)
+	p.eventManager._register(EventTypeProposalVoteAuthorized, ch)
+	p.eventManager._register(EventTypeProposalVoteStarted, ch)
+	p.eventManager._register(EventTypeProposalVoteFinished, ch)
+	p.eventManager._register(EventTypeProposalStatusChange, ch)
+}
+
 func (p *politeiawww) _setupUserManageLogging() {
 	ch := make(chan interface{})
 	go func() {
@@ -0,0 +1,96 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	"github.com/thi4go/politeia/politeiad/cache"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+// convertWWWProposalFollowFromUser converts a user database proposal follow
+// to a www proposal follow.
+func convertWWWProposalFollowFromUser(f user.ProposalFollow) www.ProposalFollow {
+	return www.ProposalFollow{
+		Token:     f.Token,
+		CreatedAt: f.CreatedAt,
+	}
+}
+
+// processUserFollows returns the proposals that the user follows.
+func (p *politeiawww) processUserFollows(u *user.User) (*www.UserFollowsReply, error) {
+	log.Tracef("processUserFollows: %v", u.ID)
+
+	follows, err := p.db.ProposalFollowsByUserID(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pf := make([]www.ProposalFollow, 0, len(follows))
+	for _, v := range follows {
+		pf = append(pf, convertWWWProposalFollowFromUser(v))
+	}
+
+	return &www.UserFollowsReply{
+		Follows: pf,
+	}, nil
+}
+
+// processEditUserFollows follows or unfollows a proposal for the user. Only
+// vetted proposals can be followed. Following a proposal that the user
+// already follows and unfollowing a proposal that the user does not follow
+// are not errors.
+func (p *politeiawww) processEditUserFollows(euf www.EditUserFollows, u *user.User) (*www.EditUserFollowsReply, error) {
+	log.Tracef("processEditUserFollows: %v %v %v", u.ID, euf.Token,
+		euf.Action)
+
+	if !tokenIsValid(euf.Token) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidCensorshipToken,
+		}
+	}
+
+	switch euf.Action {
+	case www.FollowActionFollow:
+		prop, err := p.getProp(euf.Token)
+		if err != nil {
+			if err == cache.ErrRecordNotFound {
+				err = www.UserError{
+					ErrorCode: www.ErrorStatusProposalNotFound,
+				}
+			}
+			return nil, err
+		}
+		if prop.State != www.PropStateVetted {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusWrongStatus,
+			}
+		}
+
+		err = p.db.ProposalFollowSave(user.ProposalFollow{
+			UserID:    u.ID,
+			Token:     euf.Token,
+			CreatedAt: time.Now().Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+	case www.FollowActionUnfollow:
+		err := p.db.ProposalFollowDelete(u.ID, euf.Token)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidFollowAction,
+		}
+	}
+
+	return &www.EditUserFollowsReply{}, nil
+}
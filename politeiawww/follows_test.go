@@ -0,0 +1,193 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+func TestProcessEditUserFollows(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	d := newTestPoliteiad(t, p)
+	defer d.Close()
+
+	usr, id := newUser(t, p, true, false)
+
+	propPublic := newProposalRecord(t, usr, id, www.PropStatusPublic)
+	propUnvetted := newProposalRecord(t, usr, id, www.PropStatusNotReviewed)
+	d.AddRecord(t, convertPropToPD(t, propPublic))
+	d.AddRecord(t, convertPropToPD(t, propUnvetted))
+	tokenPublic := propPublic.CensorshipRecord.Token
+	tokenNotFound := strings.Repeat("0", len(tokenPublic))
+
+	var tests = []struct {
+		name string
+		euf  www.EditUserFollows
+		want error
+	}{
+		{"invalid token",
+			www.EditUserFollows{
+				Token:  "invalid",
+				Action: www.FollowActionFollow,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidCensorshipToken,
+			}},
+
+		{"invalid action",
+			www.EditUserFollows{
+				Token:  tokenPublic,
+				Action: www.FollowActionInvalid,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidFollowAction,
+			}},
+
+		{"proposal not found",
+			www.EditUserFollows{
+				Token:  tokenNotFound,
+				Action: www.FollowActionFollow,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			}},
+
+		{"unvetted proposal",
+			www.EditUserFollows{
+				Token:  propUnvetted.CensorshipRecord.Token,
+				Action: www.FollowActionFollow,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusWrongStatus,
+			}},
+
+		{"success",
+			www.EditUserFollows{
+				Token:  tokenPublic,
+				Action: www.FollowActionFollow,
+			},
+			nil},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processEditUserFollows(v.euf, usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+		})
+	}
+
+	// The followed proposal is listed
+	ufr, err := p.processUserFollows(usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ufr.Follows) != 1 || ufr.Follows[0].Token != tokenPublic {
+		t.Fatalf("got follows %v, want %v", ufr.Follows, tokenPublic)
+	}
+
+	// Unfollow the proposal
+	_, err = p.processEditUserFollows(www.EditUserFollows{
+		Token:  tokenPublic,
+		Action: www.FollowActionUnfollow,
+	}, usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ufr, err = p.processUserFollows(usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ufr.Follows) != 0 {
+		t.Fatalf("got %v follows, want 0", len(ufr.Follows))
+	}
+}
+
+func TestEmailFollowersForProposal(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	// Write emails to a maildir
+	dir, err := ioutil.TempDir("", "maildir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p.smtp, err = newSMTP("", "", "", "Politeia <noreply@example.org>",
+		dir, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	author, id := newUser(t, p, true, false)
+	prop := newProposalRecord(t, author, id, www.PropStatusPublic)
+	token := prop.CensorshipRecord.Token
+
+	// Setup followers. Only followers that have enabled the followed
+	// proposal notification and that did not cause the activity are
+	// notified.
+	n := uint64(www.NotificationEmailFollowedProposal)
+	follower, _ := newUser(t, p, true, false)
+	follower.EmailNotifications = n
+	disabled, _ := newUser(t, p, true, false)
+	disabled.EmailNotifications = 0
+	actor, _ := newUser(t, p, true, false)
+	actor.EmailNotifications = n
+	for _, u := range []*user.User{follower, disabled, actor} {
+		err := p.db.UserUpdate(*u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = p.db.ProposalFollowSave(user.ProposalFollow{
+			UserID: u.ID,
+			Token:  token,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A follower that can not be looked up does not keep the other
+	// followers from being notified. The nil ID is listed first.
+	err = p.db.ProposalFollowSave(user.ProposalFollow{
+		UserID: uuid.Nil,
+		Token:  token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.emailFollowersForProposal(&prop, "Voting on the proposal has "+
+		"started.", "", actor.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := p.db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Recipient != follower.Email {
+		t.Fatalf("got pending emails %v, want one to %v", pending,
+			follower.Email)
+	}
+	if !strings.Contains(pending[0].Body, token) ||
+		!strings.Contains(pending[0].Body, "Voting on the proposal") {
+		t.Errorf("email does not describe the activity: %v",
+			pending[0].Body)
+	}
+}
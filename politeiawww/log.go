@@ -43,6 +43,7 @@ var (
 	log            = backendLog.Logger("PWWW")
 	localdbLog     = backendLog.Logger("LODB")
 	cockroachdbLog = backendLog.Logger("CODB")
+	sqliteLog      = backendLog.Logger("SQLT")
 )
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -50,6 +51,7 @@ var subsystemLoggers = map[string]slog.Logger{
 	"PWWW": log,
 	"LODB": localdbLog,
 	"CODB": cockroachdbLog,
+	"SQLT": sqliteLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -0,0 +1,109 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dajohi/goemail"
+)
+
+// maildir is a mail transport that writes email messages to a maildir
+// instead of sending them. It allows email flows to be inspected without a
+// SMTP server.
+//
+// Every recipient gets its own copy of a message. The recipient is recorded
+// in the Delivered-To header since BCC recipients are not part of the
+// message headers.
+type maildir struct {
+	sync.Mutex
+	path     string // Maildir root
+	hostname string // Hostname used in unique file names
+	count    uint64 // Number of messages written
+}
+
+// newMaildir returns a maildir transport that writes to the given path. The
+// tmp, new and cur directories are created if they do not exist.
+func newMaildir(path string) (*maildir, error) {
+	for _, v := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(path, v), 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	// The maildir spec reserves '/' and ':' in file names
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).
+		Replace(hostname)
+
+	return &maildir{
+		path:     path,
+		hostname: hostname,
+	}, nil
+}
+
+// uniqueName returns a unique file name for a new message as described by
+// the maildir spec.
+func (m *maildir) uniqueName() string {
+	m.Lock()
+	defer m.Unlock()
+
+	m.count++
+	now := time.Now()
+	return fmt.Sprintf("%v.M%vP%vQ%v.%v", now.Unix(), now.Nanosecond()/1000,
+		os.Getpid(), m.count, m.hostname)
+}
+
+// formatMessage returns the RFC 5322 representation of the message that is
+// delivered to the given recipient.
+func formatMessage(msg *goemail.Message, recipient, messageID string) []byte {
+	var b bytes.Buffer
+	b.WriteString("Delivered-To: " + recipient + "\n")
+	b.WriteString("Message-ID: <" + messageID + ">\n")
+	b.Write(msg.Body())
+
+	// RFC 5322 requires CRLF line endings
+	body := bytes.Replace(b.Bytes(), []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(body, []byte("\n"), []byte("\r\n"), -1)
+}
+
+// send writes a copy of the message for every recipient to the maildir.
+// Messages are written to tmp and then moved to new so that readers never see
+// partially written messages.
+//
+// This function satisfies the mailTransport interface.
+func (m *maildir) send(msg *goemail.Message) error {
+	recipients := msg.Recipients()
+	if len(recipients) == 0 {
+		return goemail.ErrNoRecipients
+	}
+
+	for _, r := range recipients {
+		name := m.uniqueName()
+		id := strings.TrimSuffix(name, "."+m.hostname) + "@politeiawww"
+		tmp := filepath.Join(m.path, "tmp", name)
+		err := ioutil.WriteFile(tmp, formatMessage(msg, r, id), 0600)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(tmp, filepath.Join(m.path, "new", name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
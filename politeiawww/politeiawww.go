@@ -14,6 +14,7 @@ import (
 	"github.com/decred/dcrd/chaincfg"
 	exptypes "github.com/decred/dcrdata/explorer/types/v2"
 	pstypes "github.com/decred/dcrdata/pubsub/types/v3"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
 	"github.com/thi4go/politeia/politeiad/api/v1/mime"
 	"github.com/thi4go/politeia/politeiad/cache"
 	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
@@ -52,6 +53,10 @@ var (
 		template.New("comment_reply_on_proposal").Parse(templateCommentReplyOnProposalRaw))
 	templateCommentReplyOnComment = template.Must(
 		template.New("comment_reply_on_comment").Parse(templateCommentReplyOnCommentRaw))
+	templateFollowedProposal = template.Must(
+		template.New("followed_proposal").Parse(templateFollowedProposalRaw))
+	templateEmailDigest = template.Must(
+		template.New("email_digest").Parse(templateEmailDigestRaw))
 )
 
 // wsContext is the websocket context. If uuid == "" then it is an
@@ -62,8 +67,10 @@ type wsContext struct {
 	conn          *websocket.Conn
 	wg            sync.WaitGroup
 	subscriptions map[string]struct{}
+	tokens        map[string]struct{} // Proposal filter, empty means all
 	errorC        chan www.WSError
 	pingC         chan struct{}
+	eventC        chan wsEvent
 	done          chan struct{} // SHUT...DOWN...EVERYTHING...
 }
 
@@ -94,11 +101,16 @@ type politeiawww struct {
 	plugins []Plugin
 
 	// Politeiad client
-	client *http.Client
+	client      *http.Client
+	rpcIdentity *identity.FullIdentity // Signs privileged commands
 
 	// SMTP client
 	smtp *smtp
 
+	// The email outbox is kept in the user database.
+	emailC      chan struct{}     // Wakes up the email sender
+	emailLimits *emailRateLimiter // Per recipient rate limits
+
 	templates map[string]*template.Template
 	tmplMtx   sync.RWMutex
 
@@ -137,6 +149,19 @@ type politeiawww struct {
 	// the dcrdata best block route of politeiad is used as a fallback.
 	bestBlock uint64
 	bbMtx     sync.RWMutex
+
+	// activeVotes contains the end heights of the proposals whose voting
+	// period is active. It is used to notify websocket subscribers when a
+	// voting period ends.
+	activeVotes map[string]uint64 // [token]endHeight
+	avMtx       sync.Mutex
+
+	// webhooks contains the admin configured webhooks. The outbox of
+	// webhook deliveries is kept in the user database.
+	webhooks      map[uuid.UUID]user.Webhook // [webhookID]Webhook
+	webhookMtx    sync.RWMutex
+	webhookC      chan struct{} // Wakes up the webhook sender
+	webhookClient *http.Client
 }
 
 // XXX rig this up
@@ -276,6 +301,80 @@ func (p *politeiawww) handleProposalDetails(w http.ResponseWriter, r *http.Reque
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// handleProposalDiff handles the incoming proposal diff command.  It returns
+// the changes between two versions of a public proposal.
+func (p *politeiawww) handleProposalDiff(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalDiff")
+
+	// Get versions from query string parameters
+	var pdf www.ProposalDiff
+	err := util.ParseGetParams(r, &pdf)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleProposalDiff: ParseGetParams",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	// Get proposal token from path parameters
+	pathParams := mux.Vars(r)
+	pdf.Token = pathParams["token"]
+
+	reply, err := p.processProposalDiff(pdf)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalDiff: processProposalDiff %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleProposalTimestamps handles the incoming proposal timestamps command.
+// It returns the anchor proofs of all versions of a public proposal.
+func (p *politeiawww) handleProposalTimestamps(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalTimestamps")
+
+	// Get proposal token from path parameters
+	pathParams := mux.Vars(r)
+	pt := www.ProposalTimestamps{
+		Token: pathParams["token"],
+	}
+
+	reply, err := p.processProposalTimestamps(pt)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalTimestamps: processProposalTimestamps %v",
+			err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// handleProposalBundle handles the incoming proposal bundle command.  It
+// returns a self-contained bundle of a public proposal that can be verified
+// offline.
+func (p *politeiawww) handleProposalBundle(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleProposalBundle")
+
+	// Get proposal token from path parameters
+	pathParams := mux.Vars(r)
+	pb := www.ProposalBundle{
+		Token: pathParams["token"],
+	}
+
+	reply, err := p.processProposalBundle(pb)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleProposalBundle: processProposalBundle %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 // handleBatchVoteSummary handles the incoming batch vote summary command. It
 // returns a VoteSummary for each of the provided censorship tokens.
 func (p *politeiawww) handleBatchVoteSummary(w http.ResponseWriter, r *http.Request) {
@@ -349,6 +448,8 @@ func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
 		MaxImageSize:               www.PolicyMaxImageSize,
 		MaxMDs:                     www.PolicyMaxMDs,
 		MaxMDSize:                  www.PolicyMaxMDSize,
+		MaxAttachments:             www.PolicyMaxAttachments,
+		MaxAttachmentSize:          www.PolicyMaxAttachmentSize,
 		ValidMIMETypes:             mime.ValidMimeTypes(),
 		MinProposalNameLength:      www.PolicyMinProposalNameLength,
 		MaxProposalNameLength:      www.PolicyMaxProposalNameLength,
@@ -796,6 +897,7 @@ func (p *politeiawww) handleWebsocketRead(wc *wsContext) {
 			//	spew.Sdump(subscribe))
 
 			subscriptions := make(map[string]struct{})
+			tokens := make(map[string]struct{})
 			var errors []string
 			for _, v := range subscribe.RPCS {
 				if !utilwww.ValidSubscription(v) {
@@ -816,11 +918,21 @@ func (p *politeiawww) handleWebsocketRead(wc *wsContext) {
 				}
 				subscriptions[v] = struct{}{}
 			}
+			for _, v := range subscribe.Tokens {
+				if !tokenIsValid(v) {
+					log.Tracef("invalid token %v %v", wc, v)
+					errors = append(errors,
+						fmt.Sprintf("invalid token %v", v))
+					continue
+				}
+				tokens[v] = struct{}{}
+			}
 
 			if len(errors) == 0 {
 				// Replace old subscriptions
 				p.wsMtx.Lock()
 				wc.subscriptions = subscriptions
+				wc.tokens = tokens
 				p.wsMtx.Unlock()
 			} else {
 				wc.errorC <- www.WSError{
@@ -833,8 +945,7 @@ func (p *politeiawww) handleWebsocketRead(wc *wsContext) {
 	}
 }
 
-// handleWebsocketWrite attempts to notify a subscribed websocket. Currently
-// only ping is supported.
+// handleWebsocketWrite attempts to notify a subscribed websocket.
 func (p *politeiawww) handleWebsocketWrite(wc *wsContext) {
 	defer wc.wg.Done()
 	log.Tracef("handleWebsocketWrite %v", wc)
@@ -866,6 +977,15 @@ func (p *politeiawww) handleWebsocketWrite(wc *wsContext) {
 			cmd = www.WSCPing
 			id = ""
 			payload = www.WSPing{Timestamp: time.Now().Unix()}
+		case e, ok := <-wc.eventC:
+			if !ok {
+				log.Tracef("handleWebsocketWrite event not ok"+
+					" %v", wc)
+				return
+			}
+			cmd = e.command
+			id = ""
+			payload = e.payload
 		}
 
 		err := utilwww.WSWrite(wc.conn, cmd, id, payload)
@@ -963,6 +1083,7 @@ func (p *politeiawww) setupPiDcrdataWSSubs() error {
 				log.Debugf("wsDcrdata message WebsocketBlock(height=%v)",
 					m.Block.Height)
 				p.updateBestBlock(uint64(m.Block.Height))
+				p.finishActiveVotes(uint64(m.Block.Height))
 			case *pstypes.HangUp:
 				log.Infof("Dcrdata has hung up. Will reconnect.")
 				err = p.resetPiDcrdataWSSubs()
@@ -996,8 +1117,10 @@ func (p *politeiawww) handleWebsocket(w http.ResponseWriter, r *http.Request, id
 	wc := wsContext{
 		uuid:          id,
 		subscriptions: make(map[string]struct{}),
+		tokens:        make(map[string]struct{}),
 		pingC:         make(chan struct{}),
 		errorC:        make(chan www.WSError),
+		eventC:        make(chan wsEvent, wsEventBufferSize),
 		done:          make(chan struct{}),
 	}
 
@@ -1227,6 +1350,15 @@ func (p *politeiawww) setPoliteiaWWWRoutes() {
 	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
 		www.RouteProposalDetails, p.handleProposalDetails,
 		permissionPublic)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteProposalDiff, p.handleProposalDiff,
+		permissionPublic)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteProposalTimestamps, p.handleProposalTimestamps,
+		permissionPublic)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteProposalBundle, p.handleProposalBundle,
+		permissionPublic)
 	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
 		www.RoutePolicy, p.handlePolicy,
 		permissionPublic)
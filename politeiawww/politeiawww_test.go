@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
 	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
 	"github.com/thi4go/politeia/util/version"
 	"github.com/go-test/deep"
@@ -346,3 +347,45 @@ func TestHandleProposalDetails(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkInventory(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	d := newTestPoliteiad(t, p)
+	defer d.Close()
+
+	usr, id := newUser(t, p, true, false)
+
+	want := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		prop := newProposalRecord(t, usr, id, www.PropStatusPublic)
+		d.AddRecord(t, convertPropToPD(t, prop))
+		want[prop.CensorshipRecord.Token] = true
+	}
+
+	// Use a page size of one record to walk across pages
+	got := make(map[string]bool)
+	ip := pd.InventoryPage{
+		Limit:        1,
+		IncludeFiles: true,
+	}
+	err := p.walkInventory(ip, func(r pd.Record) error {
+		token := r.CensorshipRecord.Token
+		if got[token] {
+			t.Errorf("record %v walked twice", token)
+		}
+		got[token] = true
+		if len(r.Files) == 0 {
+			t.Errorf("record %v has no files", token)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got records %v, want %v", got, want)
+	}
+}
@@ -191,9 +191,10 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 	filenames := make(map[string]int, len(np.Files))
 	// Check that the file number policy is followed.
 	var (
-		numMDs, numImages, numIndexFiles      int
-		mdExceedsMaxSize, imageExceedsMaxSize bool
-		hashes                                []*[sha256.Size]byte
+		numMDs, numImages, numAttachments, numIndexFiles int
+		mdExceedsMaxSize, imageExceedsMaxSize            bool
+		attachmentExceedsMaxSize                         bool
+		hashes                                           []*[sha256.Size]byte
 	)
 	for _, v := range np.Files {
 		filenames[v.Name]++
@@ -210,6 +211,15 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 			if len(data) > www.PolicyMaxImageSize {
 				imageExceedsMaxSize = true
 			}
+		} else if !strings.HasPrefix(v.MIME, "text/plain") {
+			numAttachments++
+			data, err = base64.StdEncoding.DecodeString(v.Payload)
+			if err != nil {
+				return err
+			}
+			if len(data) > www.PolicyMaxAttachmentSize {
+				attachmentExceedsMaxSize = true
+			}
 		} else {
 			numMDs++
 
@@ -281,6 +291,18 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 		}
 	}
 
+	if numAttachments > www.PolicyMaxAttachments {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusMaxAttachmentsExceeded,
+		}
+	}
+
+	if attachmentExceedsMaxSize {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusMaxAttachmentSizeExceeded,
+		}
+	}
+
 	// proposal title validation
 	name, err := getProposalName(np.Files)
 	if err != nil {
@@ -859,6 +881,169 @@ func (p *politeiawww) processProposalDetails(propDetails www.ProposalsDetails, u
 	return &reply, nil
 }
 
+// processProposalDiff asks politeiad for the changes between two versions of
+// a public proposal and returns them.
+func (p *politeiawww) processProposalDiff(pdf www.ProposalDiff) (*www.ProposalDiffReply, error) {
+	log.Tracef("processProposalDiff: %v", pdf.Token)
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	gvd := pd.GetVettedDiff{
+		Challenge:  hex.EncodeToString(challenge),
+		Token:      pdf.Token,
+		OldVersion: pdf.OldVersion,
+		NewVersion: pdf.NewVersion,
+	}
+
+	// Send politeiad request
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.GetVettedDiffRoute, gvd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle response
+	var reply pd.GetVettedDiffReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"GetVettedDiffReply: %v", err)
+	}
+
+	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Status == pd.RecordStatusNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	files := make([]www.FileDiff, 0, len(reply.Files))
+	for _, v := range reply.Files {
+		files = append(files, convertFileDiffFromPD(v))
+	}
+	md := make([]www.MetadataStreamDiff, 0, len(reply.Metadata))
+	for _, v := range reply.Metadata {
+		md = append(md, convertMetadataStreamDiffFromPD(v))
+	}
+
+	return &www.ProposalDiffReply{
+		OldVersion: reply.OldVersion,
+		NewVersion: reply.NewVersion,
+		Files:      files,
+		Metadata:   md,
+	}, nil
+}
+
+// processProposalTimestamps asks politeiad for the anchor proofs of all
+// versions of a public proposal and returns them.
+func (p *politeiawww) processProposalTimestamps(pt www.ProposalTimestamps) (*www.ProposalTimestampsReply, error) {
+	log.Tracef("processProposalTimestamps: %v", pt.Token)
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	gt := pd.GetTimestamps{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     pt.Token,
+	}
+
+	// Send politeiad request
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.GetTimestampsRoute, gt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle response
+	var reply pd.GetTimestampsReply
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"GetTimestampsReply: %v", err)
+	}
+
+	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Status == pd.RecordStatusNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	timestamps := make([]www.ProposalTimestamp, 0, len(reply.Timestamps))
+	for _, v := range reply.Timestamps {
+		timestamps = append(timestamps, convertProposalTimestampFromPD(v))
+	}
+
+	return &www.ProposalTimestampsReply{
+		Timestamps: timestamps,
+	}, nil
+}
+
+// processProposalBundle asks politeiad for the bundle of a public proposal
+// and returns it.  The bundle is forwarded verbatim so that clients can verify
+// it against the politeiad public key.
+func (p *politeiawww) processProposalBundle(pb www.ProposalBundle) (*www.ProposalBundleReply, error) {
+	log.Tracef("processProposalBundle: %v", pb.Token)
+
+	challenge, err := util.Random(pd.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	gb := pd.GetBundle{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     pb.Token,
+	}
+
+	// Send politeiad request
+	responseBody, err := p.makeRequest(http.MethodPost,
+		pd.GetBundleRoute, gb)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle response.  Only the envelope is decoded, the bundle itself
+	// is returned untouched.
+	var reply struct {
+		Response string           `json:"response"`
+		Status   pd.RecordStatusT `json:"status"`
+		Bundle   json.RawMessage  `json:"bundle"`
+	}
+	err = json.Unmarshal(responseBody, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal "+
+			"GetBundleReply: %v", err)
+	}
+
+	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Status == pd.RecordStatusNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusProposalNotFound,
+		}
+	}
+
+	return &www.ProposalBundleReply{
+		Bundle: reply.Bundle,
+	}, nil
+}
+
 // cacheVoteSumamary stores a given VoteSummary in memory.  This is to only
 // be used for proposals whose voting period has ended so that we don't have
 // to worry about cache invalidation issues.
@@ -1912,6 +2097,27 @@ func (p *politeiawww) processCastVotes(ballot *www.Ballot) (*www.BallotReply, er
 		return nil, err
 	}
 	brr := convertBallotReplyFromDecredPlugin(*br)
+
+	// Fire off a votes cast event for every proposal that received
+	// valid votes. The receipts are in the same order as the votes.
+	votes := make(map[string]map[string]uint64) // [token][votebit]count
+	for i, r := range brr.Receipts {
+		if r.Error != "" || i >= len(ballot.Votes) {
+			continue
+		}
+		v := ballot.Votes[i]
+		if _, ok := votes[v.Token]; !ok {
+			votes[v.Token] = make(map[string]uint64)
+		}
+		votes[v.Token][v.VoteBit]++
+	}
+	for token, bits := range votes {
+		p.fireEvent(EventTypeVotesCast, EventDataVotesCast{
+			Token: token,
+			Votes: bits,
+		})
+	}
+
 	return &brr, nil
 }
 
@@ -2274,8 +2480,9 @@ func (p *politeiawww) processStartVoteV2(sv www2.StartVote, u *user.User) (*www2
 	// Fire off start vote event
 	p.fireEvent(EventTypeProposalVoteStarted,
 		EventDataProposalVoteStarted{
-			AdminUser: u,
-			StartVote: &sv,
+			AdminUser:      u,
+			StartVote:      &sv,
+			StartVoteReply: svr,
 		},
 	)
 
@@ -10,9 +10,26 @@ import (
 	"github.com/dajohi/goemail"
 )
 
+// mailTransport delivers email messages.
+type mailTransport interface {
+	send(*goemail.Message) error
+}
+
+// smtpTransport delivers email messages to a SMTP server.
+type smtpTransport struct {
+	client *goemail.SMTP // SMTP client
+}
+
+// send sends the message to the SMTP server.
+//
+// This function satisfies the mailTransport interface.
+func (t *smtpTransport) send(msg *goemail.Message) error {
+	return t.client.Send(msg)
+}
+
 // smtp is a SMTP client for sending Politeia emails.
 type smtp struct {
-	client      *goemail.SMTP // SMTP client
+	transport   mailTransport // Mail transport
 	mailName    string        // Email address name
 	mailAddress string        // Email address
 	disabled    bool          // Has email been disabled
@@ -20,7 +37,8 @@ type smtp struct {
 
 // sendEmail sends an email with the given subject and body, and the caller
 // must supply a function which is used to add email addresses to send the
-// email to.
+// email to. The email is sent immediately. Notifications are queued in the
+// email outbox instead; see politeiawww.sendEmail.
 func (s *smtp) sendEmail(subject, body string, addToAddressesFn func(*goemail.Message) error) error {
 	if s.disabled {
 		return nil
@@ -33,27 +51,45 @@ func (s *smtp) sendEmail(subject, body string, addToAddressesFn func(*goemail.Me
 	}
 
 	msg.SetName(s.mailName)
-	return s.client.Send(msg)
+	err = s.transport.send(msg)
+	if err != nil {
+		emailFailures.Inc()
+	}
+	return err
 }
 
-// newSMTP returns a new smtp context.
-func newSMTP(host, user, password, emailAddress string, systemCerts *x509.CertPool, skipVerify bool) (*smtp, error) {
+// newSMTP returns a new smtp context. Emails are written to the given maildir
+// instead of being sent to the SMTP server when a maildir is provided.
+func newSMTP(host, user, password, emailAddress, mailDir string, systemCerts *x509.CertPool, skipVerify bool) (*smtp, error) {
 	// Check if email has been disabled
-	if host == "" || user == "" || password == "" {
+	if mailDir == "" && (host == "" || user == "" || password == "") {
 		return &smtp{
 			disabled: true,
 		}, nil
 	}
 
-	// Parse mail host
-	h := fmt.Sprintf("smtps://%v:%v@%v", user, password, host)
-	u, err := url.Parse(h)
+	// Parse email address
+	a, err := mail.ParseAddress(emailAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse email address
-	a, err := mail.ParseAddress(emailAddress)
+	if mailDir != "" {
+		md, err := newMaildir(mailDir)
+		if err != nil {
+			return nil, err
+		}
+		return &smtp{
+			transport:   md,
+			mailName:    a.Name,
+			mailAddress: a.Address,
+			disabled:    false,
+		}, nil
+	}
+
+	// Parse mail host
+	h := fmt.Sprintf("smtps://%v:%v@%v", user, password, host)
+	u, err := url.Parse(h)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +109,7 @@ func newSMTP(host, user, password, emailAddress string, systemCerts *x509.CertPo
 	}
 
 	return &smtp{
-		client:      client,
+		transport:   &smtpTransport{client: client},
 		mailName:    a.Name,
 		mailAddress: a.Address,
 		disabled:    false,
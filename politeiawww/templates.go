@@ -92,6 +92,23 @@ type commentReplyOnCommentTemplateData struct {
 	CommentLink  string
 }
 
+type followedProposalTemplateData struct {
+	Link     string
+	Name     string
+	Activity string
+}
+
+type emailDigestEntryTemplateData struct {
+	Subject string
+	Body    string
+}
+
+type emailDigestTemplateData struct {
+	Username string
+	Period   string
+	Entries  []emailDigestEntryTemplateData
+}
+
 type newInvoiceCommentTemplateData struct {
 }
 
@@ -235,6 +252,26 @@ Proposal: {{.ProposalName}}
 Comment: {{.CommentLink}}
 `
 
+const templateFollowedProposalRaw = `
+There is new activity on a proposal that you follow on Politeia:
+
+{{.Activity}}
+
+{{.Name}}
+{{.Link}}
+`
+
+const templateEmailDigestRaw = `
+Hi {{.Username}}, here is your {{.Period}} summary of Politeia notifications.
+{{range .Entries}}
+{{.Subject}}
+
+{{.Body}}
+{{end}}
+You can choose which notifications are sent immediately, in this summary or
+not at all in your Politeia account settings.
+`
+
 const templateInviteNewUserEmailRaw = `
 You are invited to join Decred as a contractor! To complete your registration, you will need to use the following link and register on the CMS site:
 
@@ -185,7 +185,7 @@ func newTestPoliteiawww(t *testing.T) (*politeiawww, func()) {
 	}
 
 	// Setup smtp
-	smtp, err := newSMTP("", "", "", "", nil, false)
+	smtp, err := newSMTP("", "", "", "", "", nil, false)
 	if err != nil {
 		t.Fatalf("setup SMTP: %v", err)
 	}
@@ -213,6 +213,10 @@ func newTestPoliteiawww(t *testing.T) (*politeiawww, func()) {
 		userEmails:      make(map[string]uuid.UUID),
 		userPaywallPool: make(map[uuid.UUID]paywallPoolMember),
 		commentVotes:    make(map[string]counters),
+		webhooks:        make(map[uuid.UUID]user.Webhook),
+		webhookC:        make(chan struct{}, 1),
+		emailC:          make(chan struct{}, 1),
+		emailLimits:     newEmailRateLimiter(0),
 	}
 
 	// Setup routes
@@ -113,6 +113,8 @@ func convertWWWUserFromDatabaseUser(user *user.User) www.User {
 		Identities:                      convertWWWIdentitiesFromDatabaseIdentities(user.Identities),
 		ProposalCredits:                 ProposalCreditBalance(user),
 		EmailNotifications:              user.EmailNotifications,
+		EmailDigests:                    user.EmailDigests,
+		EmailDigestFrequency:            emailDigestFrequency(user),
 	}
 }
 
@@ -615,9 +617,22 @@ func (p *politeiawww) processUserDetails(ud *www.UserDetails, isCurrentUser bool
 
 // processEditUser edits a user's preferences.
 func (p *politeiawww) processEditUser(eu *www.EditUser, user *user.User) (*www.EditUserReply, error) {
+	if eu.EmailDigestFrequency != nil &&
+		!validEmailDigestFrequency(*eu.EmailDigestFrequency) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidEmailDigestFrequency,
+		}
+	}
+
 	if eu.EmailNotifications != nil {
 		user.EmailNotifications = *eu.EmailNotifications
 	}
+	if eu.EmailDigests != nil {
+		user.EmailDigests = *eu.EmailDigests
+	}
+	if eu.EmailDigestFrequency != nil {
+		user.EmailDigestFreq = int(*eu.EmailDigestFrequency)
+	}
 
 	// Update the user in the database.
 	err := p.db.UserUpdate(*user)
@@ -30,6 +30,12 @@ const (
 	tableIdentities = "identities"
 	tableSessions   = "sessions"
 
+	tableWebhooks           = "webhooks"
+	tableWebhookDeliveries  = "webhook_deliveries"
+	tableEmails             = "emails"
+	tableEmailDigestEntries = "email_digest_entries"
+	tableProposalFollows    = "proposal_follows"
+
 	// Database user (read/write access)
 	userPoliteiawww = "politeiawww"
 
@@ -552,6 +558,625 @@ func (c *cockroachdb) SessionsDeleteByUserID(uid uuid.UUID, exemptSessionIDs []s
 		Error
 }
 
+// WebhookSave saves the given webhook to the database. New webhooks are
+// inserted into the database. Existing webhooks are updated in the database.
+//
+// WebhookSave satisfies the user Database interface.
+func (c *cockroachdb) WebhookSave(w user.Webhook) error {
+	log.Tracef("WebhookSave: %v", w.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	b, err := user.EncodeWebhook(w)
+	if err != nil {
+		return err
+	}
+	eb, err := c.encrypt(user.VersionWebhook, b)
+	if err != nil {
+		return err
+	}
+	webhook := Webhook{
+		ID:   w.ID,
+		Blob: eb,
+	}
+
+	// Check if webhook already exists
+	var update bool
+	err = c.userDB.
+		Where("id = ?", w.ID).
+		Find(&Webhook{}).
+		Error
+	switch err {
+	case nil:
+		// Webhook already exists; update existing webhook
+		update = true
+	case gorm.ErrRecordNotFound:
+		// Webhook doesn't exist; continue
+	default:
+		// All other errors
+		return fmt.Errorf("lookup: %v", err)
+	}
+
+	// Save webhook record
+	if update {
+		err = c.userDB.Save(&webhook).Error
+		if err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	} else {
+		err = c.userDB.Create(&webhook).Error
+		if err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// WebhookDeleteByID deletes the webhook with the given id along with all of
+// its deliveries.
+//
+// WebhookDeleteByID satisfies the user Database interface.
+func (c *cockroachdb) WebhookDeleteByID(id uuid.UUID) error {
+	log.Tracef("WebhookDeleteByID: %v", id)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	tx := c.userDB.Begin()
+	db := tx.Delete(&Webhook{ID: id})
+	if db.Error != nil {
+		tx.Rollback()
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		return user.ErrWebhookNotFound
+	}
+	err := tx.
+		Where("webhook_id = ?", id).
+		Delete(WebhookDelivery{}).
+		Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// AllWebhooks returns all webhooks.
+//
+// AllWebhooks satisfies the user Database interface.
+func (c *cockroachdb) AllWebhooks() ([]user.Webhook, error) {
+	log.Tracef("AllWebhooks")
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var webhooks []Webhook
+	err := c.userDB.Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ws := make([]user.Webhook, 0, len(webhooks))
+	for _, v := range webhooks {
+		b, _, err := c.decrypt(v.Blob)
+		if err != nil {
+			return nil, err
+		}
+		w, err := user.DecodeWebhook(b)
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, *w)
+	}
+
+	return ws, nil
+}
+
+// WebhookDeliverySave saves the given webhook delivery to the database. New
+// deliveries are inserted into the database. Existing deliveries are updated
+// in the database.
+//
+// WebhookDeliverySave satisfies the user Database interface.
+func (c *cockroachdb) WebhookDeliverySave(d user.WebhookDelivery) error {
+	log.Tracef("WebhookDeliverySave: %v %v", d.WebhookID, d.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	b, err := user.EncodeWebhookDelivery(d)
+	if err != nil {
+		return err
+	}
+	eb, err := c.encrypt(user.VersionWebhookDelivery, b)
+	if err != nil {
+		return err
+	}
+	delivery := WebhookDelivery{
+		ID:        d.ID,
+		WebhookID: d.WebhookID,
+		Status:    int(d.Status),
+		CreatedAt: d.CreatedAt,
+		Blob:      eb,
+	}
+
+	// Check if delivery already exists
+	var update bool
+	err = c.userDB.
+		Where("id = ?", d.ID).
+		Find(&WebhookDelivery{}).
+		Error
+	switch err {
+	case nil:
+		// Delivery already exists; update existing delivery
+		update = true
+	case gorm.ErrRecordNotFound:
+		// Delivery doesn't exist; continue
+	default:
+		// All other errors
+		return fmt.Errorf("lookup: %v", err)
+	}
+
+	// Save delivery record
+	if update {
+		err = c.userDB.Save(&delivery).Error
+		if err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	} else {
+		err = c.userDB.Create(&delivery).Error
+		if err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// convertWebhookDeliveriesToUser decrypts and decodes the given webhook
+// deliveries.
+func (c *cockroachdb) convertWebhookDeliveriesToUser(deliveries []WebhookDelivery) ([]user.WebhookDelivery, error) {
+	ds := make([]user.WebhookDelivery, 0, len(deliveries))
+	for _, v := range deliveries {
+		b, _, err := c.decrypt(v.Blob)
+		if err != nil {
+			return nil, err
+		}
+		d, err := user.DecodeWebhookDelivery(b)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, *d)
+	}
+	return ds, nil
+}
+
+// WebhookDeliveriesPending returns all webhook deliveries that have not been
+// delivered yet, ordered by creation time.
+//
+// WebhookDeliveriesPending satisfies the user Database interface.
+func (c *cockroachdb) WebhookDeliveriesPending() ([]user.WebhookDelivery, error) {
+	log.Tracef("WebhookDeliveriesPending")
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var deliveries []WebhookDelivery
+	err := c.userDB.
+		Where("status = ?", int(user.WebhookDeliveryStatusPending)).
+		Order("created_at").
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return c.convertWebhookDeliveriesToUser(deliveries)
+}
+
+// WebhookDeliveriesPrune deletes the deliveries that have been delivered or
+// have failed and that were created before the given UNIX timestamp. The
+// number of deleted deliveries is returned.
+//
+// WebhookDeliveriesPrune satisfies the user Database interface.
+func (c *cockroachdb) WebhookDeliveriesPrune(before int64) (int, error) {
+	log.Tracef("WebhookDeliveriesPrune: %v", before)
+
+	if c.isShutdown() {
+		return 0, user.ErrShutdown
+	}
+
+	db := c.userDB.
+		Where("status <> ? AND created_at < ?",
+			int(user.WebhookDeliveryStatusPending), before).
+		Delete(WebhookDelivery{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+
+	return int(db.RowsAffected), nil
+}
+
+// WebhookDeliveriesByWebhookID returns all deliveries of the given webhook,
+// ordered by creation time.
+//
+// WebhookDeliveriesByWebhookID satisfies the user Database interface.
+func (c *cockroachdb) WebhookDeliveriesByWebhookID(id uuid.UUID) ([]user.WebhookDelivery, error) {
+	log.Tracef("WebhookDeliveriesByWebhookID: %v", id)
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var deliveries []WebhookDelivery
+	err := c.userDB.
+		Where("webhook_id = ?", id).
+		Order("created_at").
+		Find(&deliveries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return c.convertWebhookDeliveriesToUser(deliveries)
+}
+
+// EmailSave saves the given email to the email outbox. New emails are
+// inserted into the database. Existing emails are updated in the database.
+//
+// EmailSave satisfies the user Database interface.
+func (c *cockroachdb) EmailSave(e user.Email) error {
+	log.Tracef("EmailSave: %v", e.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	b, err := user.EncodeEmail(e)
+	if err != nil {
+		return err
+	}
+	eb, err := c.encrypt(user.VersionEmail, b)
+	if err != nil {
+		return err
+	}
+	email := Email{
+		ID:        e.ID,
+		Status:    int(e.Status),
+		CreatedAt: e.CreatedAt,
+		Blob:      eb,
+	}
+
+	// Check if email already exists
+	var update bool
+	err = c.userDB.
+		Where("id = ?", e.ID).
+		Find(&Email{}).
+		Error
+	switch err {
+	case nil:
+		// Email already exists; update existing email
+		update = true
+	case gorm.ErrRecordNotFound:
+		// Email doesn't exist; continue
+	default:
+		// All other errors
+		return fmt.Errorf("lookup: %v", err)
+	}
+
+	// Save email record
+	if update {
+		err = c.userDB.Save(&email).Error
+		if err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	} else {
+		err = c.userDB.Create(&email).Error
+		if err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// EmailDeleteByID deletes the email with the given id from the email outbox.
+//
+// EmailDeleteByID satisfies the user Database interface.
+func (c *cockroachdb) EmailDeleteByID(id uuid.UUID) error {
+	log.Tracef("EmailDeleteByID: %v", id)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	return c.userDB.Delete(&Email{ID: id}).Error
+}
+
+// EmailsByStatus returns all emails in the email outbox that have the given
+// status, ordered by creation time.
+//
+// EmailsByStatus satisfies the user Database interface.
+func (c *cockroachdb) EmailsByStatus(status user.EmailStatusT) ([]user.Email, error) {
+	log.Tracef("EmailsByStatus: %v", status)
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var emails []Email
+	err := c.userDB.
+		Where("status = ?", int(status)).
+		Order("created_at").
+		Find(&emails).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]user.Email, 0, len(emails))
+	for _, v := range emails {
+		b, _, err := c.decrypt(v.Blob)
+		if err != nil {
+			return nil, err
+		}
+		e, err := user.DecodeEmail(b)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, *e)
+	}
+
+	return es, nil
+}
+
+// EmailDigestEntrySave saves the given email digest entry. New entries are
+// inserted into the database. Existing entries are updated in the database.
+//
+// EmailDigestEntrySave satisfies the user Database interface.
+func (c *cockroachdb) EmailDigestEntrySave(e user.EmailDigestEntry) error {
+	log.Tracef("EmailDigestEntrySave: %v", e.ID)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	b, err := user.EncodeEmailDigestEntry(e)
+	if err != nil {
+		return err
+	}
+	eb, err := c.encrypt(user.VersionEmailDigestEntry, b)
+	if err != nil {
+		return err
+	}
+	entry := EmailDigestEntry{
+		ID:        e.ID,
+		UserID:    e.UserID,
+		CreatedAt: e.CreatedAt,
+		Blob:      eb,
+	}
+
+	// Check if entry already exists
+	var update bool
+	err = c.userDB.
+		Where("id = ?", e.ID).
+		Find(&EmailDigestEntry{}).
+		Error
+	switch err {
+	case nil:
+		// Entry already exists; update existing entry
+		update = true
+	case gorm.ErrRecordNotFound:
+		// Entry doesn't exist; continue
+	default:
+		// All other errors
+		return fmt.Errorf("lookup: %v", err)
+	}
+
+	// Save entry record
+	if update {
+		err = c.userDB.Save(&entry).Error
+		if err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	} else {
+		err = c.userDB.Create(&entry).Error
+		if err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// EmailDigestEntryDeleteByID deletes the email digest entry with the given
+// id.
+//
+// EmailDigestEntryDeleteByID satisfies the user Database interface.
+func (c *cockroachdb) EmailDigestEntryDeleteByID(id uuid.UUID) error {
+	log.Tracef("EmailDigestEntryDeleteByID: %v", id)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	return c.userDB.Delete(&EmailDigestEntry{ID: id}).Error
+}
+
+// AllEmailDigestEntries returns all email digest entries, ordered by creation
+// time.
+//
+// AllEmailDigestEntries satisfies the user Database interface.
+func (c *cockroachdb) AllEmailDigestEntries() ([]user.EmailDigestEntry, error) {
+	log.Tracef("AllEmailDigestEntries")
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var entries []EmailDigestEntry
+	err := c.userDB.
+		Order("created_at").
+		Find(&entries).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]user.EmailDigestEntry, 0, len(entries))
+	for _, v := range entries {
+		b, _, err := c.decrypt(v.Blob)
+		if err != nil {
+			return nil, err
+		}
+		e, err := user.DecodeEmailDigestEntry(b)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, *e)
+	}
+
+	return es, nil
+}
+
+// convertProposalFollowsToUser converts database proposal follows to user
+// proposal follows.
+func convertProposalFollowsToUser(follows []ProposalFollow) []user.ProposalFollow {
+	fs := make([]user.ProposalFollow, 0, len(follows))
+	for _, v := range follows {
+		fs = append(fs, user.ProposalFollow{
+			UserID:    v.UserID,
+			Token:     v.Token,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+	return fs
+}
+
+// ProposalFollowSave saves the given proposal follow. New follows are
+// inserted into the database. Existing follows are updated in the database.
+//
+// ProposalFollowSave satisfies the user Database interface.
+func (c *cockroachdb) ProposalFollowSave(f user.ProposalFollow) error {
+	log.Tracef("ProposalFollowSave: %v %v", f.UserID, f.Token)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	follow := ProposalFollow{
+		UserID:    f.UserID,
+		Token:     f.Token,
+		CreatedAt: f.CreatedAt,
+	}
+
+	// Check if follow already exists
+	var update bool
+	err := c.userDB.
+		Where("user_id = ? AND token = ?", f.UserID, f.Token).
+		Find(&ProposalFollow{}).
+		Error
+	switch err {
+	case nil:
+		// Follow already exists; update existing follow
+		update = true
+	case gorm.ErrRecordNotFound:
+		// Follow doesn't exist; continue
+	default:
+		// All other errors
+		return fmt.Errorf("lookup: %v", err)
+	}
+
+	// Save follow record
+	if update {
+		err = c.userDB.Save(&follow).Error
+		if err != nil {
+			return fmt.Errorf("save: %v", err)
+		}
+	} else {
+		err = c.userDB.Create(&follow).Error
+		if err != nil {
+			return fmt.Errorf("create: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ProposalFollowDelete deletes the follow of the given proposal by the given
+// user.
+//
+// ProposalFollowDelete satisfies the user Database interface.
+func (c *cockroachdb) ProposalFollowDelete(userID uuid.UUID, token string) error {
+	log.Tracef("ProposalFollowDelete: %v %v", userID, token)
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	return c.userDB.
+		Where("user_id = ? AND token = ?", userID, token).
+		Delete(ProposalFollow{}).
+		Error
+}
+
+// ProposalFollowsByUserID returns all proposal follows of the given user,
+// ordered by creation time.
+//
+// ProposalFollowsByUserID satisfies the user Database interface.
+func (c *cockroachdb) ProposalFollowsByUserID(userID uuid.UUID) ([]user.ProposalFollow, error) {
+	log.Tracef("ProposalFollowsByUserID: %v", userID)
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var follows []ProposalFollow
+	err := c.userDB.
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&follows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return convertProposalFollowsToUser(follows), nil
+}
+
+// ProposalFollowsByToken returns all follows of the given proposal, ordered
+// by creation time.
+//
+// ProposalFollowsByToken satisfies the user Database interface.
+func (c *cockroachdb) ProposalFollowsByToken(token string) ([]user.ProposalFollow, error) {
+	log.Tracef("ProposalFollowsByToken: %v", token)
+
+	if c.isShutdown() {
+		return nil, user.ErrShutdown
+	}
+
+	var follows []ProposalFollow
+	err := c.userDB.
+		Where("token = ?", token).
+		Order("created_at").
+		Find(&follows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return convertProposalFollowsToUser(follows), nil
+}
+
 // rotateKeys rotates the existing database encryption key with the given new
 // key.
 //
@@ -613,6 +1238,118 @@ func rotateKeys(tx *gorm.DB, oldKey *[32]byte, newKey *[32]byte) error {
 		}
 	}
 
+	// Rotate keys for webhooks table
+	var webhooks []Webhook
+	err = tx.Find(&webhooks).Error
+	if err != nil {
+		return err
+	}
+
+	for _, v := range webhooks {
+		b, _, err := sbox.Decrypt(oldKey, v.Blob)
+		if err != nil {
+			return fmt.Errorf("decrypt webhook '%v': %v",
+				v.ID, err)
+		}
+
+		eb, err := sbox.Encrypt(user.VersionWebhook, newKey, b)
+		if err != nil {
+			return fmt.Errorf("encrypt webhook '%v': %v",
+				v.ID, err)
+		}
+
+		v.Blob = eb
+		err = tx.Save(&v).Error
+		if err != nil {
+			return fmt.Errorf("save webhook '%v': %v",
+				v.ID, err)
+		}
+	}
+
+	// Rotate keys for webhook deliveries table
+	var deliveries []WebhookDelivery
+	err = tx.Find(&deliveries).Error
+	if err != nil {
+		return err
+	}
+
+	for _, v := range deliveries {
+		b, _, err := sbox.Decrypt(oldKey, v.Blob)
+		if err != nil {
+			return fmt.Errorf("decrypt webhook delivery '%v': %v",
+				v.ID, err)
+		}
+
+		eb, err := sbox.Encrypt(user.VersionWebhookDelivery, newKey, b)
+		if err != nil {
+			return fmt.Errorf("encrypt webhook delivery '%v': %v",
+				v.ID, err)
+		}
+
+		v.Blob = eb
+		err = tx.Save(&v).Error
+		if err != nil {
+			return fmt.Errorf("save webhook delivery '%v': %v",
+				v.ID, err)
+		}
+	}
+
+	// Rotate keys for emails table
+	var emails []Email
+	err = tx.Find(&emails).Error
+	if err != nil {
+		return err
+	}
+
+	for _, v := range emails {
+		b, _, err := sbox.Decrypt(oldKey, v.Blob)
+		if err != nil {
+			return fmt.Errorf("decrypt email '%v': %v",
+				v.ID, err)
+		}
+
+		eb, err := sbox.Encrypt(user.VersionEmail, newKey, b)
+		if err != nil {
+			return fmt.Errorf("encrypt email '%v': %v",
+				v.ID, err)
+		}
+
+		v.Blob = eb
+		err = tx.Save(&v).Error
+		if err != nil {
+			return fmt.Errorf("save email '%v': %v",
+				v.ID, err)
+		}
+	}
+
+	// Rotate keys for email digest entries table
+	var entries []EmailDigestEntry
+	err = tx.Find(&entries).Error
+	if err != nil {
+		return err
+	}
+
+	for _, v := range entries {
+		b, _, err := sbox.Decrypt(oldKey, v.Blob)
+		if err != nil {
+			return fmt.Errorf("decrypt email digest entry '%v': %v",
+				v.ID, err)
+		}
+
+		eb, err := sbox.Encrypt(user.VersionEmailDigestEntry, newKey, b)
+		if err != nil {
+			return fmt.Errorf("encrypt email digest entry '%v': %v",
+				v.ID, err)
+		}
+
+		v.Blob = eb
+		err = tx.Save(&v).Error
+		if err != nil {
+			return fmt.Errorf("save email digest entry '%v': %v",
+				v.ID, err)
+		}
+	}
+
 	return nil
 }
 
@@ -781,6 +1518,36 @@ func (c *cockroachdb) createTables(tx *gorm.DB) error {
 			return err
 		}
 	}
+	if !tx.HasTable(tableWebhooks) {
+		err := tx.CreateTable(&Webhook{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if !tx.HasTable(tableWebhookDeliveries) {
+		err := tx.CreateTable(&WebhookDelivery{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if !tx.HasTable(tableEmails) {
+		err := tx.CreateTable(&Email{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if !tx.HasTable(tableEmailDigestEntries) {
+		err := tx.CreateTable(&EmailDigestEntry{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if !tx.HasTable(tableProposalFollows) {
+		err := tx.CreateTable(&ProposalFollow{}).Error
+		if err != nil {
+			return err
+		}
+	}
 
 	// Insert version record
 	kv := KeyValue{
@@ -67,6 +67,76 @@ func (Session) TableName() string {
 	return tableSessions
 }
 
+// Webhook represents an admin configured webhook. Blob is an encrypted
+// user.Webhook, which contains the webhook secret.
+type Webhook struct {
+	ID   uuid.UUID `gorm:"primary_key"` // UUID
+	Blob []byte    `gorm:"not null"`    // Encrypted webhook
+}
+
+// TableName returns the table name of the Webhook table.
+func (Webhook) TableName() string {
+	return tableWebhooks
+}
+
+// WebhookDelivery represents a single webhook delivery. Blob is an encrypted
+// user.WebhookDelivery. The fields that have been broken out of the encrypted
+// blob are the fields that need to be queryable.
+type WebhookDelivery struct {
+	ID        uuid.UUID `gorm:"primary_key"`    // UUID
+	WebhookID uuid.UUID `gorm:"not null;index"` // Webhook UUID
+	Status    int       `gorm:"not null;index"` // Delivery status
+	CreatedAt int64     `gorm:"not null"`       // Created at UNIX timestamp
+	Blob      []byte    `gorm:"not null"`       // Encrypted webhook delivery
+}
+
+// TableName returns the table name of the WebhookDelivery table.
+func (WebhookDelivery) TableName() string {
+	return tableWebhookDeliveries
+}
+
+// Email represents an email in the email outbox. Blob is an encrypted
+// user.Email. The fields that have been broken out of the encrypted blob are
+// the fields that need to be queryable.
+type Email struct {
+	ID        uuid.UUID `gorm:"primary_key"`    // UUID
+	Status    int       `gorm:"not null;index"` // Email status
+	CreatedAt int64     `gorm:"not null"`       // Created at UNIX timestamp
+	Blob      []byte    `gorm:"not null"`       // Encrypted email
+}
+
+// TableName returns the table name of the Email table.
+func (Email) TableName() string {
+	return tableEmails
+}
+
+// EmailDigestEntry represents a notification that is waiting to be sent as
+// part of an email digest. Blob is an encrypted user.EmailDigestEntry.
+type EmailDigestEntry struct {
+	ID        uuid.UUID `gorm:"primary_key"`    // UUID
+	UserID    uuid.UUID `gorm:"not null;index"` // User UUID
+	CreatedAt int64     `gorm:"not null"`       // Created at UNIX timestamp
+	Blob      []byte    `gorm:"not null"`       // Encrypted digest entry
+}
+
+// TableName returns the table name of the EmailDigestEntry table.
+func (EmailDigestEntry) TableName() string {
+	return tableEmailDigestEntries
+}
+
+// ProposalFollow represents a user following a proposal. It is not
+// encrypted since all of its fields need to be queryable.
+type ProposalFollow struct {
+	UserID    uuid.UUID `gorm:"primary_key"`       // User UUID
+	Token     string    `gorm:"primary_key;index"` // Proposal censorship token
+	CreatedAt int64     `gorm:"not null"`          // Created at UNIX timestamp
+}
+
+// TableName returns the table name of the ProposalFollow table.
+func (ProposalFollow) TableName() string {
+	return tableProposalFollows
+}
+
 // CMSUser represents a CMS user. A CMS user includes the politeiawww User
 // object as well as CMS specific user fields. A CMS user must correspond to
 // a politeiawww User.
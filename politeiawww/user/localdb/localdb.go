@@ -1,8 +1,10 @@
 package localdb
 
 import (
+	"bytes"
 	"encoding/binary"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -21,6 +23,26 @@ const (
 
 	// The key for a user session is sessionPrefix+sessionID
 	sessionPrefix = "session:"
+
+	// The key for a webhook is webhookPrefix+webhookID
+	webhookPrefix = "webhook:"
+
+	// The key for a webhook delivery is
+	// webhookDeliveryPrefix+webhookID+":"+deliveryID
+	webhookDeliveryPrefix = "webhookdelivery:"
+
+	// The key of a pending webhook delivery in the index of pending
+	// deliveries is webhookPendingPrefix+webhookID+":"+deliveryID
+	webhookPendingPrefix = "webhookpending:"
+
+	// The key for an email in the email outbox is emailPrefix+emailID
+	emailPrefix = "email:"
+
+	// The key for an email digest entry is emailDigestPrefix+entryID
+	emailDigestPrefix = "emaildigest:"
+
+	// The key for a proposal follow is followPrefix+token+":"+userID
+	followPrefix = "follow:"
 )
 
 var (
@@ -48,7 +70,13 @@ type Version struct {
 func isUserRecord(key string) bool {
 	return key != UserVersionKey &&
 		key != LastPaywallAddressIndex &&
-		!strings.HasPrefix(key, sessionPrefix)
+		!strings.HasPrefix(key, sessionPrefix) &&
+		!strings.HasPrefix(key, webhookPrefix) &&
+		!strings.HasPrefix(key, webhookDeliveryPrefix) &&
+		!strings.HasPrefix(key, webhookPendingPrefix) &&
+		!strings.HasPrefix(key, emailPrefix) &&
+		!strings.HasPrefix(key, emailDigestPrefix) &&
+		!strings.HasPrefix(key, followPrefix)
 }
 
 // Store new user.
@@ -505,6 +533,533 @@ func (l *localdb) SessionsDeleteByUserID(uid uuid.UUID, exemptSessionIDs []strin
 	return l.userdb.Write(batch, nil)
 }
 
+// WebhookSave saves the given webhook to the database. New webhooks are
+// inserted into the database. Existing webhooks are updated in the database.
+//
+// WebhookSave satisfies the user.Database interface.
+func (l *localdb) WebhookSave(w user.Webhook) error {
+	log.Tracef("WebhookSave: %v", w.ID)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	payload, err := user.EncodeWebhook(w)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(webhookPrefix + w.ID.String())
+	return l.userdb.Put(key, payload, nil)
+}
+
+// WebhookDeleteByID deletes the webhook with the given id along with all of
+// its deliveries.
+//
+// WebhookDeleteByID satisfies the user.Database interface.
+func (l *localdb) WebhookDeleteByID(id uuid.UUID) error {
+	log.Tracef("WebhookDeleteByID: %v", id)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	key := []byte(webhookPrefix + id.String())
+	ok, err := l.userdb.Has(key, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return user.ErrWebhookNotFound
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(key)
+	for _, v := range []string{webhookDeliveryPrefix, webhookPendingPrefix} {
+		prefix := []byte(v + id.String() + ":")
+		iter := l.userdb.NewIterator(util.BytesPrefix(prefix), nil)
+		for iter.Next() {
+			batch.Delete(append([]byte{}, iter.Key()...))
+		}
+		iter.Release()
+		err = iter.Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	return l.userdb.Write(batch, nil)
+}
+
+// AllWebhooks returns all webhooks.
+//
+// AllWebhooks satisfies the user.Database interface.
+func (l *localdb) AllWebhooks() ([]user.Webhook, error) {
+	log.Tracef("AllWebhooks")
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	webhooks := make([]user.Webhook, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(webhookPrefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		w, err := user.DecodeWebhook(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+
+	return webhooks, iter.Error()
+}
+
+// WebhookDeliverySave saves the given webhook delivery to the database. New
+// deliveries are inserted into the database. Existing deliveries are updated
+// in the database. Pending deliveries are added to the index of pending
+// deliveries and are removed from it once they are delivered or have failed.
+//
+// WebhookDeliverySave satisfies the user.Database interface.
+func (l *localdb) WebhookDeliverySave(d user.WebhookDelivery) error {
+	log.Tracef("WebhookDeliverySave: %v %v", d.WebhookID, d.ID)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	payload, err := user.EncodeWebhookDelivery(d)
+	if err != nil {
+		return err
+	}
+
+	id := d.WebhookID.String() + ":" + d.ID.String()
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(webhookDeliveryPrefix+id), payload)
+	if d.Status == user.WebhookDeliveryStatusPending {
+		batch.Put([]byte(webhookPendingPrefix+id), []byte{})
+	} else {
+		batch.Delete([]byte(webhookPendingPrefix + id))
+	}
+	return l.userdb.Write(batch, nil)
+}
+
+// sortWebhookDeliveries sorts the given deliveries by creation time, oldest
+// first.
+func sortWebhookDeliveries(deliveries []user.WebhookDelivery) {
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt < deliveries[j].CreatedAt
+	})
+}
+
+// webhookDeliveries returns the webhook deliveries whose keys start with the
+// given prefix, ordered by creation time.
+//
+// This function must be called WITH the read lock held.
+func (l *localdb) webhookDeliveries(prefix string) ([]user.WebhookDelivery, error) {
+	deliveries := make([]user.WebhookDelivery, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		d, err := user.DecodeWebhookDelivery(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	sortWebhookDeliveries(deliveries)
+
+	return deliveries, nil
+}
+
+// WebhookDeliveriesPending returns all webhook deliveries that have not been
+// delivered yet, ordered by creation time. Only the index of pending
+// deliveries is scanned so that the cost does not grow with the number of
+// deliveries that have been made.
+//
+// WebhookDeliveriesPending satisfies the user.Database interface.
+func (l *localdb) WebhookDeliveriesPending() ([]user.WebhookDelivery, error) {
+	log.Tracef("WebhookDeliveriesPending")
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	deliveries := make([]user.WebhookDelivery, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(webhookPendingPrefix)),
+		nil)
+	defer iter.Release()
+	for iter.Next() {
+		id := bytes.TrimPrefix(iter.Key(), []byte(webhookPendingPrefix))
+		payload, err := l.userdb.Get(append([]byte(webhookDeliveryPrefix),
+			id...), nil)
+		if err != nil {
+			return nil, err
+		}
+		d, err := user.DecodeWebhookDelivery(payload)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	sortWebhookDeliveries(deliveries)
+
+	return deliveries, nil
+}
+
+// WebhookDeliveriesPrune deletes the deliveries that have been delivered or
+// have failed and that were created before the given UNIX timestamp. The
+// number of deleted deliveries is returned.
+//
+// WebhookDeliveriesPrune satisfies the user.Database interface.
+func (l *localdb) WebhookDeliveriesPrune(before int64) (int, error) {
+	log.Tracef("WebhookDeliveriesPrune: %v", before)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return 0, user.ErrShutdown
+	}
+
+	batch := new(leveldb.Batch)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(webhookDeliveryPrefix)),
+		nil)
+	for iter.Next() {
+		d, err := user.DecodeWebhookDelivery(iter.Value())
+		if err != nil {
+			iter.Release()
+			return 0, err
+		}
+		if d.Status == user.WebhookDeliveryStatusPending ||
+			d.CreatedAt >= before {
+			continue
+		}
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	err := iter.Error()
+	if err != nil {
+		return 0, err
+	}
+
+	err = l.userdb.Write(batch, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return batch.Len(), nil
+}
+
+// WebhookDeliveriesByWebhookID returns all deliveries of the given webhook,
+// ordered by creation time.
+//
+// WebhookDeliveriesByWebhookID satisfies the user.Database interface.
+func (l *localdb) WebhookDeliveriesByWebhookID(id uuid.UUID) ([]user.WebhookDelivery, error) {
+	log.Tracef("WebhookDeliveriesByWebhookID: %v", id)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	return l.webhookDeliveries(webhookDeliveryPrefix + id.String() + ":")
+}
+
+// EmailSave saves the given email to the email outbox. New emails are
+// inserted into the database. Existing emails are updated in the database.
+//
+// EmailSave satisfies the user.Database interface.
+func (l *localdb) EmailSave(e user.Email) error {
+	log.Tracef("EmailSave: %v", e.ID)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	payload, err := user.EncodeEmail(e)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(emailPrefix + e.ID.String())
+	return l.userdb.Put(key, payload, nil)
+}
+
+// EmailDeleteByID deletes the email with the given id from the email outbox.
+//
+// EmailDeleteByID satisfies the user.Database interface.
+func (l *localdb) EmailDeleteByID(id uuid.UUID) error {
+	log.Tracef("EmailDeleteByID: %v", id)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	return l.userdb.Delete([]byte(emailPrefix+id.String()), nil)
+}
+
+// EmailsByStatus returns all emails in the email outbox that have the given
+// status, ordered by creation time.
+//
+// EmailsByStatus satisfies the user.Database interface.
+func (l *localdb) EmailsByStatus(status user.EmailStatusT) ([]user.Email, error) {
+	log.Tracef("EmailsByStatus: %v", status)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	emails := make([]user.Email, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(emailPrefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		e, err := user.DecodeEmail(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		if e.Status == status {
+			emails = append(emails, *e)
+		}
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	sort.SliceStable(emails, func(i, j int) bool {
+		return emails[i].CreatedAt < emails[j].CreatedAt
+	})
+
+	return emails, nil
+}
+
+// EmailDigestEntrySave saves the given email digest entry. New entries are
+// inserted into the database. Existing entries are updated in the database.
+//
+// EmailDigestEntrySave satisfies the user.Database interface.
+func (l *localdb) EmailDigestEntrySave(e user.EmailDigestEntry) error {
+	log.Tracef("EmailDigestEntrySave: %v", e.ID)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	payload, err := user.EncodeEmailDigestEntry(e)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(emailDigestPrefix + e.ID.String())
+	return l.userdb.Put(key, payload, nil)
+}
+
+// EmailDigestEntryDeleteByID deletes the email digest entry with the given
+// id.
+//
+// EmailDigestEntryDeleteByID satisfies the user.Database interface.
+func (l *localdb) EmailDigestEntryDeleteByID(id uuid.UUID) error {
+	log.Tracef("EmailDigestEntryDeleteByID: %v", id)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	return l.userdb.Delete([]byte(emailDigestPrefix+id.String()), nil)
+}
+
+// AllEmailDigestEntries returns all email digest entries, ordered by creation
+// time.
+//
+// AllEmailDigestEntries satisfies the user.Database interface.
+func (l *localdb) AllEmailDigestEntries() ([]user.EmailDigestEntry, error) {
+	log.Tracef("AllEmailDigestEntries")
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	entries := make([]user.EmailDigestEntry, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(emailDigestPrefix)),
+		nil)
+	defer iter.Release()
+	for iter.Next() {
+		e, err := user.DecodeEmailDigestEntry(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt < entries[j].CreatedAt
+	})
+
+	return entries, nil
+}
+
+// ProposalFollowSave saves the given proposal follow. New follows are
+// inserted into the database. Existing follows are updated in the database.
+//
+// ProposalFollowSave satisfies the user.Database interface.
+func (l *localdb) ProposalFollowSave(f user.ProposalFollow) error {
+	log.Tracef("ProposalFollowSave: %v %v", f.UserID, f.Token)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	payload, err := user.EncodeProposalFollow(f)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(followPrefix + f.Token + ":" + f.UserID.String())
+	return l.userdb.Put(key, payload, nil)
+}
+
+// ProposalFollowDelete deletes the follow of the given proposal by the given
+// user.
+//
+// ProposalFollowDelete satisfies the user.Database interface.
+func (l *localdb) ProposalFollowDelete(userID uuid.UUID, token string) error {
+	log.Tracef("ProposalFollowDelete: %v %v", userID, token)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	key := []byte(followPrefix + token + ":" + userID.String())
+	return l.userdb.Delete(key, nil)
+}
+
+// proposalFollows returns all proposal follows whose key starts with the
+// given prefix and that match the provided filter.
+//
+// This function must be called WITH the read lock held.
+func (l *localdb) proposalFollows(prefix string, filter func(*user.ProposalFollow) bool) ([]user.ProposalFollow, error) {
+	follows := make([]user.ProposalFollow, 0)
+	iter := l.userdb.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		f, err := user.DecodeProposalFollow(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		if filter(f) {
+			follows = append(follows, *f)
+		}
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	sort.SliceStable(follows, func(i, j int) bool {
+		return follows[i].CreatedAt < follows[j].CreatedAt
+	})
+
+	return follows, nil
+}
+
+// ProposalFollowsByUserID returns all proposal follows of the given user,
+// ordered by creation time.
+//
+// ProposalFollowsByUserID satisfies the user.Database interface.
+func (l *localdb) ProposalFollowsByUserID(userID uuid.UUID) ([]user.ProposalFollow, error) {
+	log.Tracef("ProposalFollowsByUserID: %v", userID)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	return l.proposalFollows(followPrefix,
+		func(f *user.ProposalFollow) bool {
+			return f.UserID == userID
+		})
+}
+
+// ProposalFollowsByToken returns all follows of the given proposal, ordered
+// by creation time.
+//
+// ProposalFollowsByToken satisfies the user.Database interface.
+func (l *localdb) ProposalFollowsByToken(token string) ([]user.ProposalFollow, error) {
+	log.Tracef("ProposalFollowsByToken: %v", token)
+
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return nil, user.ErrShutdown
+	}
+
+	return l.proposalFollows(followPrefix+token+":",
+		func(f *user.ProposalFollow) bool {
+			return true
+		})
+}
+
 // New creates a new localdb instance.
 func New(root string) (*localdb, error) {
 	log.Tracef("localdb New: %v", root)
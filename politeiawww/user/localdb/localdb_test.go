@@ -180,6 +180,349 @@ func TestSessionDeleteByID(t *testing.T) {
 	}
 }
 
+func TestWebhooks(t *testing.T) {
+	db, dataDir := setupTestData(t)
+	defer teardownTestData(t, db, dataDir)
+
+	// Save webhooks
+	w1 := user.Webhook{
+		ID:     uuid.New(),
+		URL:    "https://localhost/1",
+		Secret: "secret",
+	}
+	w2 := user.Webhook{
+		ID:     uuid.New(),
+		URL:    "https://localhost/2",
+		Events: []string{"comment"},
+		Secret: "secret",
+	}
+	for _, w := range []user.Webhook{w1, w2} {
+		err := db.WebhookSave(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	webhooks, err := db.AllWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 2 {
+		t.Fatalf("got %v webhooks, want 2", len(webhooks))
+	}
+
+	// Save deliveries
+	d1 := user.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: w1.ID,
+		Status:    user.WebhookDeliveryStatusPending,
+		CreatedAt: 1,
+	}
+	d2 := user.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookID: w2.ID,
+		Status:    user.WebhookDeliveryStatusPending,
+		CreatedAt: 2,
+	}
+	for _, d := range []user.WebhookDelivery{d1, d2} {
+		err := db.WebhookDeliverySave(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Update a delivery
+	d1.Status = user.WebhookDeliveryStatusDelivered
+	err = db.WebhookDeliverySave(d1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != d2.ID {
+		t.Errorf("got pending deliveries %v, want %v", pending, d2)
+	}
+	deliveries, err := db.WebhookDeliveriesByWebhookID(w1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0] != d1 {
+		t.Errorf("got deliveries %v, want %v", deliveries, d1)
+	}
+
+	// Delete a webhook along with its deliveries
+	err = db.WebhookDeleteByID(w2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("got %v pending deliveries, want 0", len(pending))
+	}
+	err = db.WebhookDeleteByID(w2.ID)
+	if err != user.ErrWebhookNotFound {
+		t.Errorf("got error '%v', want '%v'", err,
+			user.ErrWebhookNotFound)
+	}
+	webhooks, err = db.AllWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 1 || webhooks[0].ID != w1.ID {
+		t.Errorf("got webhooks %v, want %v", webhooks, w1)
+	}
+}
+
+func TestWebhookDeliveriesPrune(t *testing.T) {
+	db, dataDir := setupTestData(t)
+	defer teardownTestData(t, db, dataDir)
+
+	w := user.Webhook{
+		ID:     uuid.New(),
+		URL:    "https://localhost/1",
+		Secret: "secret",
+	}
+	err := db.WebhookSave(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newDelivery := func(status user.WebhookDeliveryStatusT, createdAt int64) user.WebhookDelivery {
+		d := user.WebhookDelivery{
+			ID:        uuid.New(),
+			WebhookID: w.ID,
+			Status:    status,
+			CreatedAt: createdAt,
+		}
+		err := db.WebhookDeliverySave(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	oldPending := newDelivery(user.WebhookDeliveryStatusPending, 1)
+	newDelivery(user.WebhookDeliveryStatusDelivered, 2)
+	newDelivery(user.WebhookDeliveryStatusFailed, 3)
+	recent := newDelivery(user.WebhookDeliveryStatusDelivered, 10)
+
+	// Only deliveries that are done and old enough are pruned
+	n, err := db.WebhookDeliveriesPrune(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %v pruned deliveries, want 2", n)
+	}
+	deliveries, err := db.WebhookDeliveriesByWebhookID(w.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != oldPending.ID ||
+		deliveries[1].ID != recent.ID {
+		t.Errorf("got deliveries %v, want %v and %v", deliveries,
+			oldPending, recent)
+	}
+
+	// A delivery leaves the pending index once it is done
+	pending, err := db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0] != oldPending {
+		t.Errorf("got pending deliveries %v, want %v", pending, oldPending)
+	}
+	oldPending.Status = user.WebhookDeliveryStatusFailed
+	err = db.WebhookDeliverySave(oldPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("got %v pending deliveries, want 0", len(pending))
+	}
+}
+
+func TestEmails(t *testing.T) {
+	db, dataDir := setupTestData(t)
+	defer teardownTestData(t, db, dataDir)
+
+	// Save emails
+	e1 := user.Email{
+		ID:        uuid.New(),
+		Recipient: "alice@example.org",
+		Status:    user.EmailStatusPending,
+		CreatedAt: 2,
+	}
+	e2 := user.Email{
+		ID:        uuid.New(),
+		Recipient: "bob@example.org",
+		Status:    user.EmailStatusPending,
+		CreatedAt: 1,
+	}
+	for _, e := range []user.Email{e1, e2} {
+		err := db.EmailSave(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].ID != e2.ID {
+		t.Fatalf("got pending emails %v, want oldest first", pending)
+	}
+
+	// Update an email
+	e1.Status = user.EmailStatusFailed
+	err = db.EmailSave(e1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := db.EmailsByStatus(user.EmailStatusFailed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0] != e1 {
+		t.Errorf("got failed emails %v, want %v", failed, e1)
+	}
+
+	// Delete an email
+	err = db.EmailDeleteByID(e2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = db.EmailsByStatus(user.EmailStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("got %v pending emails, want 0", len(pending))
+	}
+}
+
+func TestEmailDigestEntries(t *testing.T) {
+	db, dataDir := setupTestData(t)
+	defer teardownTestData(t, db, dataDir)
+
+	// Save entries
+	userID := uuid.New()
+	e1 := user.EmailDigestEntry{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Subject:   "Subject 1",
+		CreatedAt: 2,
+	}
+	e2 := user.EmailDigestEntry{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Subject:   "Subject 2",
+		CreatedAt: 1,
+	}
+	for _, e := range []user.EmailDigestEntry{e1, e2} {
+		err := db.EmailDigestEntrySave(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Digest entries are not mistaken for emails or users
+	emails, err := db.EmailsByStatus(user.EmailStatusInvalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 0 {
+		t.Fatalf("got %v emails, want 0", len(emails))
+	}
+	entries, err := db.AllEmailDigestEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != e2 || entries[1] != e1 {
+		t.Fatalf("got entries %v, want oldest first", entries)
+	}
+
+	// Delete an entry
+	err = db.EmailDigestEntryDeleteByID(e2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = db.AllEmailDigestEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0] != e1 {
+		t.Errorf("got entries %v, want %v", entries, e1)
+	}
+}
+
+func TestProposalFollows(t *testing.T) {
+	db, dataDir := setupTestData(t)
+	defer teardownTestData(t, db, dataDir)
+
+	// Save follows
+	token1 := "cd3b2d8dc3ef8c4fcec2c5f4a4a0fa3cc9baa3d36f4d2a1d1f6fd1e9bde9ee71"
+	token2 := "ab3b2d8dc3ef8c4fcec2c5f4a4a0fa3cc9baa3d36f4d2a1d1f6fd1e9bde9ee71"
+	user1 := uuid.New()
+	user2 := uuid.New()
+	f1 := user.ProposalFollow{
+		UserID:    user1,
+		Token:     token1,
+		CreatedAt: 2,
+	}
+	f2 := user.ProposalFollow{
+		UserID:    user1,
+		Token:     token2,
+		CreatedAt: 1,
+	}
+	f3 := user.ProposalFollow{
+		UserID:    user2,
+		Token:     token1,
+		CreatedAt: 3,
+	}
+	for _, f := range []user.ProposalFollow{f1, f2, f3} {
+		err := db.ProposalFollowSave(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	follows, err := db.ProposalFollowsByUserID(user1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 2 || follows[0] != f2 || follows[1] != f1 {
+		t.Fatalf("got follows %v, want oldest first", follows)
+	}
+	follows, err = db.ProposalFollowsByToken(token1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 2 || follows[0] != f1 || follows[1] != f3 {
+		t.Fatalf("got follows %v, want oldest first", follows)
+	}
+
+	// Delete a follow
+	err = db.ProposalFollowDelete(user1, token1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follows, err = db.ProposalFollowsByToken(token1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0] != f3 {
+		t.Errorf("got follows %v, want %v", follows, f3)
+	}
+}
+
 func TestIsUserRecord(t *testing.T) {
 	tests := []struct {
 		input string
@@ -197,6 +540,30 @@ func TestIsUserRecord(t *testing.T) {
 			input: sessionPrefix + uuid.New().String(),
 			want:  false,
 		},
+		{
+			input: webhookPrefix + uuid.New().String(),
+			want:  false,
+		},
+		{
+			input: webhookDeliveryPrefix + uuid.New().String(),
+			want:  false,
+		},
+		{
+			input: webhookPendingPrefix + uuid.New().String(),
+			want:  false,
+		},
+		{
+			input: emailPrefix + uuid.New().String(),
+			want:  false,
+		},
+		{
+			input: emailDigestPrefix + uuid.New().String(),
+			want:  false,
+		},
+		{
+			input: followPrefix + "token:" + uuid.New().String(),
+			want:  false,
+		},
 	}
 
 	for _, test := range tests {
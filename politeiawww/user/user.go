@@ -38,6 +38,10 @@ var (
 	// ErrInvalidPluginCmd is emitted when an invalid plugin command
 	// is used.
 	ErrInvalidPluginCmd = errors.New("invalid plugin command")
+
+	// ErrWebhookNotFound indicates that a webhook was not found in the
+	// database.
+	ErrWebhookNotFound = errors.New("webhook not found")
 )
 
 // Identity wraps an ed25519 public key and timestamps to indicate if it is
@@ -144,6 +148,8 @@ type User struct {
 	HashedPassword      []byte    `json:"hashedpassword"`      // Blowfish hash
 	Admin               bool      `json:"admin"`               // Is user an admin
 	EmailNotifications  uint64    `json:"emailnotifications"`  // Email notification setting
+	EmailDigests        uint64    `json:"emaildigests"`        // Notifications sent in digest
+	EmailDigestFreq     int       `json:"emaildigestfreq"`     // Email digest frequency
 	LastLoginTime       int64     `json:"lastlogintime"`       // Unix timestamp of last login
 	FailedLoginAttempts uint64    `json:"failedloginattempts"` // Sequential failed login attempts
 	Deactivated         bool      `json:"deactivated"`         // Is account deactivated
@@ -434,6 +440,217 @@ func DecodeSession(payload []byte) (*Session, error) {
 	return &s, nil
 }
 
+// Webhook is an admin configured URL that politeiawww events are posted to.
+// The request body of every delivery is signed with Secret using HMAC-SHA256.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`        // Unique webhook ID
+	URL       string    `json:"url"`       // URL that events are posted to
+	Events    []string  `json:"events"`    // Event types, all events if empty
+	Secret    string    `json:"secret"`    // HMAC signing key
+	CreatedAt int64     `json:"createdat"` // Created at UNIX timestamp
+}
+
+// VersionWebhook is the version of the Webhook struct.
+const VersionWebhook uint32 = 1
+
+// EncodeWebhook encodes Webhook into a JSON byte slice.
+func EncodeWebhook(w Webhook) ([]byte, error) {
+	b, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeWebhook decodes a JSON byte slice into a Webhook.
+func DecodeWebhook(payload []byte) (*Webhook, error) {
+	var w Webhook
+
+	err := json.Unmarshal(payload, &w)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// WebhookDeliveryStatusT represents the status of a webhook delivery.
+type WebhookDeliveryStatusT int
+
+const (
+	// Webhook delivery statuses
+	WebhookDeliveryStatusInvalid   WebhookDeliveryStatusT = 0 // Invalid status
+	WebhookDeliveryStatusPending   WebhookDeliveryStatusT = 1 // Waiting to be delivered
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatusT = 2 // Delivered
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatusT = 3 // Gave up delivering
+)
+
+// WebhookDelivery is a single event that is posted to a webhook. Pending
+// deliveries make up the webhook outbox. Deliveries are kept for a retention
+// period after they have been delivered or have failed so that they can be
+// inspected by admins.
+type WebhookDelivery struct {
+	ID           uuid.UUID              `json:"id"`           // Unique delivery ID
+	WebhookID    uuid.UUID              `json:"webhookid"`    // Webhook ID
+	Event        string                 `json:"event"`        // Event type
+	Payload      string                 `json:"payload"`      // JSON request body
+	Status       WebhookDeliveryStatusT `json:"status"`       // Delivery status
+	Attempts     uint32                 `json:"attempts"`     // Number of delivery attempts
+	NextAttempt  int64                  `json:"nextattempt"`  // UNIX timestamp of next attempt
+	LastAttempt  int64                  `json:"lastattempt"`  // UNIX timestamp of last attempt
+	ResponseCode int                    `json:"responsecode"` // HTTP status code of last attempt
+	Error        string                 `json:"error"`        // Error of last attempt
+	CreatedAt    int64                  `json:"createdat"`    // Created at UNIX timestamp
+}
+
+// VersionWebhookDelivery is the version of the WebhookDelivery struct.
+const VersionWebhookDelivery uint32 = 1
+
+// EncodeWebhookDelivery encodes WebhookDelivery into a JSON byte slice.
+func EncodeWebhookDelivery(d WebhookDelivery) ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeWebhookDelivery decodes a JSON byte slice into a WebhookDelivery.
+func DecodeWebhookDelivery(payload []byte) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+
+	err := json.Unmarshal(payload, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// EmailStatusT represents the status of an email in the email outbox.
+type EmailStatusT int
+
+const (
+	// Email statuses
+	EmailStatusInvalid EmailStatusT = 0 // Invalid status
+	EmailStatusPending EmailStatusT = 1 // Waiting to be sent
+	EmailStatusFailed  EmailStatusT = 2 // Gave up sending
+)
+
+// Email is a single email message in the email outbox. Emails are addressed
+// to a single recipient. Pending emails are deleted once they have been sent.
+// Emails that could not be sent are kept as failed so that they can be
+// inspected by admins.
+type Email struct {
+	ID          uuid.UUID    `json:"id"`          // Unique email ID
+	Recipient   string       `json:"recipient"`   // Recipient email address
+	Subject     string       `json:"subject"`     // Email subject
+	Body        string       `json:"body"`        // Email body
+	Status      EmailStatusT `json:"status"`      // Email status
+	Attempts    uint32       `json:"attempts"`    // Number of send attempts
+	NextAttempt int64        `json:"nextattempt"` // UNIX timestamp of next attempt
+	LastAttempt int64        `json:"lastattempt"` // UNIX timestamp of last attempt
+	Error       string       `json:"error"`       // Error of last attempt
+	CreatedAt   int64        `json:"createdat"`   // Created at UNIX timestamp
+}
+
+// VersionEmail is the version of the Email struct.
+const VersionEmail uint32 = 1
+
+// EncodeEmail encodes Email into a JSON byte slice.
+func EncodeEmail(e Email) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeEmail decodes a JSON byte slice into an Email.
+func DecodeEmail(payload []byte) (*Email, error) {
+	var e Email
+
+	err := json.Unmarshal(payload, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// EmailDigestEntry is a notification that is waiting to be sent to a user as
+// part of an email digest. Entries are deleted once the digest that contains
+// them has been queued in the email outbox.
+type EmailDigestEntry struct {
+	ID           uuid.UUID `json:"id"`           // Unique entry ID
+	UserID       uuid.UUID `json:"userid"`       // User to notify
+	Notification uint64    `json:"notification"` // Email notification type
+	Subject      string    `json:"subject"`      // Notification subject
+	Body         string    `json:"body"`         // Notification body
+	CreatedAt    int64     `json:"createdat"`    // Created at UNIX timestamp
+}
+
+// VersionEmailDigestEntry is the version of the EmailDigestEntry struct.
+const VersionEmailDigestEntry uint32 = 1
+
+// EncodeEmailDigestEntry encodes EmailDigestEntry into a JSON byte slice.
+func EncodeEmailDigestEntry(e EmailDigestEntry) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeEmailDigestEntry decodes a JSON byte slice into an EmailDigestEntry.
+func DecodeEmailDigestEntry(payload []byte) (*EmailDigestEntry, error) {
+	var e EmailDigestEntry
+
+	err := json.Unmarshal(payload, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// ProposalFollow represents a user following a proposal. Followers are
+// notified of activity on the proposal.
+type ProposalFollow struct {
+	UserID    uuid.UUID `json:"userid"`    // User that follows the proposal
+	Token     string    `json:"token"`     // Proposal censorship token
+	CreatedAt int64     `json:"createdat"` // Created at UNIX timestamp
+}
+
+// VersionProposalFollow is the version of the ProposalFollow struct.
+const VersionProposalFollow uint32 = 1
+
+// EncodeProposalFollow encodes ProposalFollow into a JSON byte slice.
+func EncodeProposalFollow(f ProposalFollow) ([]byte, error) {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecodeProposalFollow decodes a JSON byte slice into a ProposalFollow.
+func DecodeProposalFollow(payload []byte) (*ProposalFollow, error) {
+	var f ProposalFollow
+
+	err := json.Unmarshal(payload, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
 // Database describes the interface used for interacting with the user
 // database.
 type Database interface {
@@ -470,6 +687,58 @@ type Database interface {
 	// Delete all sessions for a user except for the given session IDs
 	SessionsDeleteByUserID(id uuid.UUID, exemptSessionIDs []string) error
 
+	// Create or update a webhook
+	WebhookSave(Webhook) error
+
+	// Delete a webhook and all of its deliveries
+	WebhookDeleteByID(uuid.UUID) error
+
+	// Return all webhooks
+	AllWebhooks() ([]Webhook, error)
+
+	// Create or update a webhook delivery
+	WebhookDeliverySave(WebhookDelivery) error
+
+	// Return all pending webhook deliveries
+	WebhookDeliveriesPending() ([]WebhookDelivery, error)
+
+	// Delete the delivered and failed webhook deliveries that were
+	// created before the given UNIX timestamp
+	WebhookDeliveriesPrune(int64) (int, error)
+
+	// Return all deliveries of a webhook
+	WebhookDeliveriesByWebhookID(uuid.UUID) ([]WebhookDelivery, error)
+
+	// Create or update an email in the email outbox
+	EmailSave(Email) error
+
+	// Delete an email from the email outbox
+	EmailDeleteByID(uuid.UUID) error
+
+	// Return all emails with the given status
+	EmailsByStatus(EmailStatusT) ([]Email, error)
+
+	// Create or update an email digest entry
+	EmailDigestEntrySave(EmailDigestEntry) error
+
+	// Delete an email digest entry
+	EmailDigestEntryDeleteByID(uuid.UUID) error
+
+	// Return all email digest entries
+	AllEmailDigestEntries() ([]EmailDigestEntry, error)
+
+	// Create or update a proposal follow
+	ProposalFollowSave(ProposalFollow) error
+
+	// Delete the follow of a proposal by a user
+	ProposalFollowDelete(userID uuid.UUID, token string) error
+
+	// Return all proposal follows of a user
+	ProposalFollowsByUserID(uuid.UUID) ([]ProposalFollow, error)
+
+	// Return all follows of a proposal
+	ProposalFollowsByToken(token string) ([]ProposalFollow, error)
+
 	// Register a plugin
 	RegisterPlugin(Plugin) error
 
@@ -541,6 +541,60 @@ func (p *politeiawww) handleEditUser(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, eur)
 }
 
+// handleUserFollows handles fetching the proposals that the logged in user
+// follows.
+func (p *politeiawww) handleUserFollows(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleUserFollows")
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleUserFollows: getSessionUser %v",
+			err)
+		return
+	}
+
+	ufr, err := p.processUserFollows(user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleUserFollows: processUserFollows %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, ufr)
+}
+
+// handleEditUserFollows handles following and unfollowing a proposal for the
+// logged in user.
+func (p *politeiawww) handleEditUserFollows(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleEditUserFollows")
+
+	var euf www.EditUserFollows
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&euf); err != nil {
+		RespondWithError(w, r, 0, "handleEditUserFollows: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	user, err := p.getSessionUser(w, r)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditUserFollows: getSessionUser %v", err)
+		return
+	}
+
+	eufr, err := p.processEditUserFollows(euf, user)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleEditUserFollows: processEditUserFollows %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, eufr)
+}
+
 // handleUsers handles fetching a list of users.
 func (p *politeiawww) handleUsers(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("handleUsers")
@@ -656,6 +710,106 @@ func (p *politeiawww) handleManageUser(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, mur)
 }
 
+// handleNewWebhook handles creating a webhook.
+func (p *politeiawww) handleNewWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleNewWebhook")
+
+	var nw www.NewWebhook
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&nw); err != nil {
+		RespondWithError(w, r, 0, "handleNewWebhook: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	nwr, err := p.processNewWebhook(nw)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleNewWebhook: processNewWebhook %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, nwr)
+}
+
+// handleDeleteWebhook handles deleting a webhook.
+func (p *politeiawww) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDeleteWebhook")
+
+	var dw www.DeleteWebhook
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dw); err != nil {
+		RespondWithError(w, r, 0, "handleDeleteWebhook: unmarshal",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	dwr, err := p.processDeleteWebhook(dw)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleDeleteWebhook: processDeleteWebhook %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, dwr)
+}
+
+// handleWebhooks handles fetching all webhooks.
+func (p *politeiawww) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleWebhooks")
+
+	wr, err := p.processWebhooks()
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleWebhooks: processWebhooks %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, wr)
+}
+
+// handleWebhookDeliveries handles fetching the delivery log of a webhook.
+func (p *politeiawww) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleWebhookDeliveries")
+
+	var wd www.WebhookDeliveries
+	err := util.ParseGetParams(r, &wd)
+	if err != nil {
+		RespondWithError(w, r, 0, "handleWebhookDeliveries: ParseGetParams",
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidInput,
+			})
+		return
+	}
+
+	wdr, err := p.processWebhookDeliveries(wd)
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleWebhookDeliveries: processWebhookDeliveries %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, wdr)
+}
+
+// handleFailedEmails handles fetching the emails that could not be sent.
+func (p *politeiawww) handleFailedEmails(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleFailedEmails")
+
+	fer, err := p.processFailedEmails()
+	if err != nil {
+		RespondWithError(w, r, 0,
+			"handleFailedEmails: processFailedEmails %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, fer)
+}
+
 // handleUserCommentsLikes returns the user votes on comments of a given proposal.
 func (p *politeiawww) handleUserCommentsLikes(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("handleUserCommentsLikes")
@@ -789,6 +943,12 @@ func (p *politeiawww) setUserWWWRoutes() {
 	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
 		www.RouteUserProposalCredits, p.handleUserProposalCredits,
 		permissionLogin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteUserFollows, p.handleUserFollows,
+		permissionLogin)
+	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
+		www.RouteUserFollows, p.handleEditUserFollows,
+		permissionLogin)
 
 	// Routes that require being logged in as an admin user.
 	p.addRoute(http.MethodPut, www.PoliteiaWWWAPIRoute,
@@ -797,6 +957,21 @@ func (p *politeiawww) setUserWWWRoutes() {
 	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
 		www.RouteManageUser, p.handleManageUser,
 		permissionAdmin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteWebhooks, p.handleWebhooks,
+		permissionAdmin)
+	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
+		www.RouteNewWebhook, p.handleNewWebhook,
+		permissionAdmin)
+	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
+		www.RouteDeleteWebhook, p.handleDeleteWebhook,
+		permissionAdmin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteWebhookDeliveries, p.handleWebhookDeliveries,
+		permissionAdmin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteFailedEmails, p.handleFailedEmails,
+		permissionAdmin)
 }
 
 // setCMSUserWWWRoutes setsup the user routes for cms mode
@@ -860,4 +1035,19 @@ func (p *politeiawww) setCMSUserWWWRoutes() {
 	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
 		www.RouteManageUser, p.handleManageUser,
 		permissionAdmin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteWebhooks, p.handleWebhooks,
+		permissionAdmin)
+	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
+		www.RouteNewWebhook, p.handleNewWebhook,
+		permissionAdmin)
+	p.addRoute(http.MethodPost, www.PoliteiaWWWAPIRoute,
+		www.RouteDeleteWebhook, p.handleDeleteWebhook,
+		permissionAdmin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteWebhookDeliveries, p.handleWebhookDeliveries,
+		permissionAdmin)
+	p.addRoute(http.MethodGet, www.PoliteiaWWWAPIRoute,
+		www.RouteFailedEmails, p.handleFailedEmails,
+		permissionAdmin)
 }
@@ -21,6 +21,12 @@ func validCommand(cmd string) bool {
 	case v1.WSCError:
 	case v1.WSCPing:
 	case v1.WSCSubscribe:
+	case v1.WSCProposalStatus:
+	case v1.WSCNewComment:
+	case v1.WSCCommentVote:
+	case v1.WSCVoteStarted:
+	case v1.WSCVoteFinished:
+	case v1.WSCVoteTally:
 	default:
 		return false
 	}
@@ -30,6 +36,12 @@ func validCommand(cmd string) bool {
 func ValidSubscription(cmd string) bool {
 	switch cmd {
 	case v1.WSCPing:
+	case v1.WSCProposalStatus:
+	case v1.WSCNewComment:
+	case v1.WSCCommentVote:
+	case v1.WSCVoteStarted:
+	case v1.WSCVoteFinished:
+	case v1.WSCVoteTally:
 	default:
 		return false
 	}
@@ -39,6 +51,12 @@ func ValidSubscription(cmd string) bool {
 func SubsciptionReqAuth(cmd string) bool {
 	switch cmd {
 	case v1.WSCPing:
+	case v1.WSCProposalStatus:
+	case v1.WSCNewComment:
+	case v1.WSCCommentVote:
+	case v1.WSCVoteStarted:
+	case v1.WSCVoteFinished:
+	case v1.WSCVoteTally:
 	default:
 		return true
 	}
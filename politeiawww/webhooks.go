@@ -0,0 +1,657 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+const (
+	// webhookMaxAttempts is the number of times a delivery is attempted
+	// before it is marked as failed.
+	webhookMaxAttempts = 10
+
+	// webhookRetryInterval is the delay before the first retry of a failed
+	// delivery. The delay doubles with every attempt up to
+	// webhookMaxRetryInterval.
+	webhookRetryInterval    = 30 * time.Second
+	webhookMaxRetryInterval = 6 * time.Hour
+
+	// webhookPollInterval is the interval at which the outbox is checked
+	// for deliveries that are due for a retry.
+	webhookPollInterval = 10 * time.Second
+
+	// webhookDeliveryRetention is the time that deliveries are kept after
+	// they were created once they have been delivered or have failed.
+	webhookDeliveryRetention = 7 * 24 * time.Hour
+
+	// webhookPruneInterval is the interval at which deliveries that are
+	// past the retention period are deleted.
+	webhookPruneInterval = time.Hour
+
+	// webhookTimeout is the timeout of a single delivery attempt.
+	webhookTimeout = 15 * time.Second
+
+	// webhookDeliveriesLimit is the maximum number of deliveries that are
+	// returned by the WebhookDeliveries command.
+	webhookDeliveriesLimit = 100
+)
+
+// webhookEvents maps the event types that are posted to webhooks to their
+// webhook event names.
+var webhookEvents = map[EventT]string{
+	EventTypeProposalSubmitted:      www.WebhookEventProposalSubmitted,
+	EventTypeProposalStatusChange:   www.WebhookEventProposalStatusChange,
+	EventTypeProposalEdited:         www.WebhookEventProposalEdited,
+	EventTypeProposalVoteAuthorized: www.WebhookEventProposalVoteAuthorized,
+	EventTypeProposalVoteStarted:    www.WebhookEventProposalVoteStarted,
+	EventTypeProposalVoteFinished:   www.WebhookEventProposalVoteFinished,
+	EventTypeComment:                www.WebhookEventComment,
+	EventTypeCommentVote:            www.WebhookEventCommentVote,
+	EventTypeVotesCast:              www.WebhookEventVotesCast,
+	EventTypeUserManage:             www.WebhookEventUserManage,
+	EventTypeInvoiceComment:         www.WebhookEventInvoiceComment,
+	EventTypeInvoiceStatusUpdate:    www.WebhookEventInvoiceStatusUpdate,
+	EventTypeDCCNew:                 www.WebhookEventDCCNew,
+	EventTypeDCCSupportOppose:       www.WebhookEventDCCSupportOppose,
+}
+
+// webhookRecordData is the webhook data of proposal, invoice and DCC events.
+type webhookRecordData struct {
+	Token         string `json:"token"`
+	Version       string `json:"version,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Status        int    `json:"status,omitempty"`
+	Message       string `json:"message,omitempty"`
+	Action        string `json:"action,omitempty"`
+	Username      string `json:"username,omitempty"`
+	AdminUsername string `json:"adminusername,omitempty"`
+}
+
+// webhookUserData is the webhook data of user management events.
+type webhookUserData struct {
+	UserID        string                `json:"userid"`
+	Username      string                `json:"username"`
+	Action        www.UserManageActionT `json:"action"`
+	Reason        string                `json:"reason,omitempty"`
+	AdminUsername string                `json:"adminusername"`
+}
+
+// username returns the username of the provided user or an empty string if
+// there is no user.
+func username(u *user.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.Username
+}
+
+// convertWebhookDataFromEventData returns the data that is posted to webhooks
+// for the provided event data. Event data contains full user records, which
+// must never leave the server, so every event type is converted explicitly.
+func convertWebhookDataFromEventData(data interface{}) (interface{}, error) {
+	switch d := data.(type) {
+	case EventDataProposalSubmitted:
+		return webhookRecordData{
+			Token:    d.CensorshipRecord.Token,
+			Name:     d.ProposalName,
+			Username: username(d.User),
+		}, nil
+	case EventDataProposalStatusChange:
+		return webhookRecordData{
+			Token:         d.Proposal.CensorshipRecord.Token,
+			Version:       d.Proposal.Version,
+			Name:          d.Proposal.Name,
+			Status:        int(d.SetProposalStatus.ProposalStatus),
+			Message:       d.SetProposalStatus.StatusChangeMessage,
+			AdminUsername: username(d.AdminUser),
+		}, nil
+	case EventDataProposalEdited:
+		return webhookRecordData{
+			Token:    d.Proposal.CensorshipRecord.Token,
+			Version:  d.Proposal.Version,
+			Name:     d.Proposal.Name,
+			Username: d.Proposal.Username,
+		}, nil
+	case EventDataProposalVoteAuthorized:
+		return webhookRecordData{
+			Token:    d.AuthorizeVote.Token,
+			Action:   d.AuthorizeVote.Action,
+			Username: username(d.User),
+		}, nil
+	case EventDataProposalVoteStarted:
+		vs := www.WSVoteStarted{
+			Token: d.StartVote.Vote.Token,
+		}
+		if d.StartVoteReply != nil {
+			vs.StartBlockHeight = d.StartVoteReply.StartBlockHeight
+			vs.EndBlockHeight = d.StartVoteReply.EndBlockHeight
+		}
+		return vs, nil
+	case EventDataProposalVoteFinished:
+		return www.WSVoteFinished{
+			Token:     d.Token,
+			EndHeight: d.EndHeight,
+		}, nil
+	case EventDataComment:
+		return www.WSNewComment{
+			Comment: *d.Comment,
+		}, nil
+	case EventDataCommentVote:
+		return www.WSCommentVote{
+			Token:     d.Token,
+			CommentID: d.CommentID,
+			Result:    int64(d.Upvotes - d.Downvotes),
+			Upvotes:   d.Upvotes,
+			Downvotes: d.Downvotes,
+		}, nil
+	case EventDataVotesCast:
+		return www.WSVoteTally{
+			Token: d.Token,
+			Votes: d.Votes,
+		}, nil
+	case EventDataUserManage:
+		return webhookUserData{
+			UserID:        d.ManageUser.UserID,
+			Username:      username(d.User),
+			Action:        d.ManageUser.Action,
+			Reason:        d.ManageUser.Reason,
+			AdminUsername: username(d.AdminUser),
+		}, nil
+	case EventDataInvoiceComment:
+		return webhookRecordData{
+			Token:    d.Token,
+			Username: username(d.User),
+		}, nil
+	case EventDataInvoiceStatusUpdate:
+		return webhookRecordData{
+			Token:    d.Token,
+			Username: username(d.User),
+		}, nil
+	case EventDataDCCNew:
+		return webhookRecordData{
+			Token: d.Token,
+		}, nil
+	case EventDataDCCSupportOppose:
+		return webhookRecordData{
+			Token: d.Token,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("invalid event data %T", data)
+}
+
+// convertWWWWebhookFromUser converts a user database webhook to a www
+// webhook. The secret is left out.
+func convertWWWWebhookFromUser(w user.Webhook) www.Webhook {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	return www.Webhook{
+		ID:        w.ID.String(),
+		URL:       w.URL,
+		Events:    events,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// convertWWWWebhookDeliveryFromUser converts a user database webhook delivery
+// to a www webhook delivery.
+func convertWWWWebhookDeliveryFromUser(d user.WebhookDelivery) www.WebhookDelivery {
+	return www.WebhookDelivery{
+		ID:           d.ID.String(),
+		Event:        d.Event,
+		Status:       www.WebhookDeliveryStatusT(d.Status),
+		Attempts:     d.Attempts,
+		NextAttempt:  d.NextAttempt,
+		LastAttempt:  d.LastAttempt,
+		ResponseCode: d.ResponseCode,
+		Error:        d.Error,
+		CreatedAt:    d.CreatedAt,
+	}
+}
+
+// webhookSignature returns the hex encoded HMAC-SHA256 of the provided
+// request body.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay returns the delay before the next attempt of an operation that
+// has failed the provided number of times. The delay starts at interval and
+// doubles with every attempt up to max.
+func retryDelay(interval, max time.Duration, attempts uint32) time.Duration {
+	d := interval
+	for i := uint32(1); i < attempts; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return d
+}
+
+// webhookRetryDelay returns the delay before the next attempt of a delivery
+// that has failed the provided number of times.
+func webhookRetryDelay(attempts uint32) time.Duration {
+	return retryDelay(webhookRetryInterval, webhookMaxRetryInterval, attempts)
+}
+
+// webhookWantsEvent returns whether the provided webhook is subscribed to the
+// provided event.
+func webhookWantsEvent(w user.Webhook, event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, v := range w.Events {
+		if v == event {
+			return true
+		}
+	}
+	return false
+}
+
+// wakeWebhookSender signals the webhook sender that there are new deliveries
+// in the outbox.
+func (p *politeiawww) wakeWebhookSender() {
+	select {
+	case p.webhookC <- struct{}{}:
+	default:
+	}
+}
+
+// queueWebhookDeliveries adds a delivery of the provided event to the outbox
+// of every webhook that is subscribed to it.
+func (p *politeiawww) queueWebhookDeliveries(event string, data interface{}) error {
+	wd, err := convertWebhookDataFromEventData(data)
+	if err != nil {
+		return err
+	}
+
+	p.webhookMtx.RLock()
+	webhooks := make([]user.Webhook, 0, len(p.webhooks))
+	for _, w := range p.webhooks {
+		if webhookWantsEvent(w, event) {
+			webhooks = append(webhooks, w)
+		}
+	}
+	p.webhookMtx.RUnlock()
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	for _, w := range webhooks {
+		id := uuid.New()
+		payload, err := json.Marshal(www.WebhookPayload{
+			ID:        id.String(),
+			Event:     event,
+			Timestamp: now,
+			Data:      wd,
+		})
+		if err != nil {
+			return err
+		}
+		err = p.db.WebhookDeliverySave(user.WebhookDelivery{
+			ID:          id,
+			WebhookID:   w.ID,
+			Event:       event,
+			Payload:     string(payload),
+			Status:      user.WebhookDeliveryStatusPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			return fmt.Errorf("WebhookDeliverySave %v: %v", w.ID, err)
+		}
+	}
+
+	p.wakeWebhookSender()
+
+	return nil
+}
+
+// deliverWebhook attempts to post a delivery to its webhook and updates the
+// delivery with the outcome.
+func (p *politeiawww) deliverWebhook(w user.Webhook, d *user.WebhookDelivery) {
+	now := time.Now()
+	d.Attempts++
+	d.LastAttempt = now.Unix()
+	d.ResponseCode = 0
+	d.Error = ""
+
+	err := func() error {
+		req, err := http.NewRequest(http.MethodPost, w.URL,
+			bytes.NewReader([]byte(d.Payload)))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(www.WebhookHeaderEvent, d.Event)
+		req.Header.Set(www.WebhookHeaderDelivery, d.ID.String())
+		req.Header.Set(www.WebhookHeaderSignature,
+			webhookSignature(w.Secret, []byte(d.Payload)))
+
+		r, err := p.webhookClient.Do(req)
+		if err != nil {
+			return err
+		}
+		r.Body.Close()
+
+		d.ResponseCode = r.StatusCode
+		if r.StatusCode < 200 || r.StatusCode > 299 {
+			return fmt.Errorf("unexpected status %v", r.Status)
+		}
+		return nil
+	}()
+
+	switch {
+	case err == nil:
+		d.Status = user.WebhookDeliveryStatusDelivered
+		d.NextAttempt = 0
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = user.WebhookDeliveryStatusFailed
+		d.NextAttempt = 0
+		d.Error = err.Error()
+	default:
+		d.NextAttempt = now.Add(webhookRetryDelay(d.Attempts)).Unix()
+		d.Error = err.Error()
+	}
+}
+
+// sendWebhookDeliveries attempts every delivery in the outbox that is due.
+// Once a delivery to a webhook fails, the remaining deliveries to that
+// webhook are left for the next pass so that a webhook that is down is not
+// sent every pending delivery on each pass. Events are queued concurrently so
+// deliveries are not guaranteed to arrive in the order the events occurred.
+func (p *politeiawww) sendWebhookDeliveries() {
+	pending, err := p.db.WebhookDeliveriesPending()
+	if err != nil {
+		log.Errorf("sendWebhookDeliveries: WebhookDeliveriesPending: %v",
+			err)
+		return
+	}
+
+	now := time.Now().Unix()
+	failed := make(map[uuid.UUID]struct{}) // [webhookID]struct{}
+	for _, d := range pending {
+		if _, ok := failed[d.WebhookID]; ok {
+			continue
+		}
+		if d.NextAttempt > now {
+			failed[d.WebhookID] = struct{}{}
+			continue
+		}
+
+		p.webhookMtx.RLock()
+		w, ok := p.webhooks[d.WebhookID]
+		p.webhookMtx.RUnlock()
+		if !ok {
+			// Webhook was deleted
+			continue
+		}
+
+		p.deliverWebhook(w, &d)
+		switch d.Status {
+		case user.WebhookDeliveryStatusDelivered:
+			log.Debugf("Webhook delivery %v %v: delivered",
+				d.WebhookID, d.ID)
+		case user.WebhookDeliveryStatusFailed:
+			log.Errorf("Webhook delivery %v %v: giving up after %v "+
+				"attempts: %v", d.WebhookID, d.ID, d.Attempts, d.Error)
+			failed[d.WebhookID] = struct{}{}
+		default:
+			log.Infof("Webhook delivery %v %v: attempt %v: %v",
+				d.WebhookID, d.ID, d.Attempts, d.Error)
+			failed[d.WebhookID] = struct{}{}
+		}
+
+		err = p.db.WebhookDeliverySave(d)
+		if err != nil {
+			log.Errorf("sendWebhookDeliveries: WebhookDeliverySave "+
+				"%v: %v", d.ID, err)
+		}
+	}
+}
+
+// pruneWebhookDeliveries deletes the delivered and failed deliveries that are
+// past the retention period.
+func (p *politeiawww) pruneWebhookDeliveries() {
+	before := time.Now().Add(-webhookDeliveryRetention).Unix()
+	n, err := p.db.WebhookDeliveriesPrune(before)
+	if err != nil {
+		log.Errorf("pruneWebhookDeliveries: WebhookDeliveriesPrune: %v",
+			err)
+		return
+	}
+	if n > 0 {
+		log.Debugf("Webhook deliveries pruned: %v", n)
+	}
+}
+
+// webhookSender delivers the deliveries in the outbox. It is woken up when
+// deliveries are queued and periodically checks for deliveries that are due
+// for a retry. Deliveries that are past the retention period are pruned once
+// every webhookPruneInterval.
+func (p *politeiawww) webhookSender() {
+	var pruned time.Time
+	for {
+		if time.Since(pruned) >= webhookPruneInterval {
+			p.pruneWebhookDeliveries()
+			pruned = time.Now()
+		}
+		p.sendWebhookDeliveries()
+
+		select {
+		case <-p.webhookC:
+		case <-time.After(webhookPollInterval):
+		}
+	}
+}
+
+// initWebhooks loads the configured webhooks and starts the thread that
+// delivers events to them. Deliveries that were queued before politeiawww was
+// restarted are resumed.
+func (p *politeiawww) initWebhooks() error {
+	webhooks, err := p.db.AllWebhooks()
+	if err != nil {
+		return err
+	}
+
+	p.webhookMtx.Lock()
+	for _, w := range webhooks {
+		p.webhooks[w.ID] = w
+	}
+	p.webhookMtx.Unlock()
+
+	log.Infof("Webhooks: %v", len(webhooks))
+
+	go p.webhookSender()
+
+	return nil
+}
+
+// _setupWebhooks queues a webhook delivery for every event.
+//
+// This function must be called WITH the mutex held.
+func (p *politeiawww) _setupWebhooks() {
+	for eventType, event := range webhookEvents {
+		ch := make(chan interface{})
+		go func(event string, ch chan interface{}) {
+			for data := range ch {
+				err := p.queueWebhookDeliveries(event, data)
+				if err != nil {
+					log.Errorf("queue webhook deliveries %v: %v",
+						event, err)
+				}
+			}
+		}(event, ch)
+		p.eventManager._register(eventType, ch)
+	}
+}
+
+// processNewWebhook creates a webhook.
+func (p *politeiawww) processNewWebhook(nw www.NewWebhook) (*www.NewWebhookReply, error) {
+	log.Tracef("processNewWebhook: %v", nw.URL)
+
+	u, err := url.Parse(nw.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidWebhookURL,
+		}
+	}
+
+	valid := make(map[string]struct{}, len(webhookEvents))
+	for _, v := range webhookEvents {
+		valid[v] = struct{}{}
+	}
+	events := make([]string, 0, len(nw.Events))
+	seen := make(map[string]struct{}, len(nw.Events))
+	for _, v := range nw.Events {
+		if _, ok := valid[v]; !ok {
+			return nil, www.UserError{
+				ErrorCode:    www.ErrorStatusInvalidWebhookEvent,
+				ErrorContext: []string{v},
+			}
+		}
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		events = append(events, v)
+	}
+
+	if len(nw.Secret) < www.WebhookSecretMinLength {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidWebhookSecret,
+		}
+	}
+
+	w := user.Webhook{
+		ID:        uuid.New(),
+		URL:       u.String(),
+		Events:    events,
+		Secret:    nw.Secret,
+		CreatedAt: time.Now().Unix(),
+	}
+	err = p.db.WebhookSave(w)
+	if err != nil {
+		return nil, err
+	}
+
+	p.webhookMtx.Lock()
+	p.webhooks[w.ID] = w
+	p.webhookMtx.Unlock()
+
+	return &www.NewWebhookReply{
+		Webhook: convertWWWWebhookFromUser(w),
+	}, nil
+}
+
+// processDeleteWebhook deletes a webhook along with its deliveries.
+func (p *politeiawww) processDeleteWebhook(dw www.DeleteWebhook) (*www.DeleteWebhookReply, error) {
+	log.Tracef("processDeleteWebhook: %v", dw.ID)
+
+	id, err := uuid.Parse(dw.ID)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidUUID,
+		}
+	}
+
+	// The webhook is removed from memory first so that no deliveries
+	// are queued for it while it is being deleted.
+	p.webhookMtx.Lock()
+	w, ok := p.webhooks[id]
+	delete(p.webhooks, id)
+	p.webhookMtx.Unlock()
+
+	err = p.db.WebhookDeleteByID(id)
+	if err == user.ErrWebhookNotFound {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWebhookNotFound,
+		}
+	} else if err != nil {
+		if ok {
+			p.webhookMtx.Lock()
+			p.webhooks[id] = w
+			p.webhookMtx.Unlock()
+		}
+		return nil, err
+	}
+
+	return &www.DeleteWebhookReply{}, nil
+}
+
+// processWebhooks returns all webhooks.
+func (p *politeiawww) processWebhooks() (*www.WebhooksReply, error) {
+	log.Tracef("processWebhooks")
+
+	p.webhookMtx.RLock()
+	defer p.webhookMtx.RUnlock()
+
+	webhooks := make([]www.Webhook, 0, len(p.webhooks))
+	for _, w := range p.webhooks {
+		webhooks = append(webhooks, convertWWWWebhookFromUser(w))
+	}
+
+	return &www.WebhooksReply{
+		Webhooks: webhooks,
+	}, nil
+}
+
+// processWebhookDeliveries returns the most recent deliveries of a webhook,
+// newest first.
+func (p *politeiawww) processWebhookDeliveries(wd www.WebhookDeliveries) (*www.WebhookDeliveriesReply, error) {
+	log.Tracef("processWebhookDeliveries: %v", wd.ID)
+
+	id, err := uuid.Parse(wd.ID)
+	if err != nil {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvalidUUID,
+		}
+	}
+
+	p.webhookMtx.RLock()
+	_, ok := p.webhooks[id]
+	p.webhookMtx.RUnlock()
+	if !ok {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusWebhookNotFound,
+		}
+	}
+
+	ds, err := p.db.WebhookDeliveriesByWebhookID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]www.WebhookDelivery, 0, webhookDeliveriesLimit)
+	for i := len(ds) - 1; i >= 0 && len(deliveries) < webhookDeliveriesLimit; i-- {
+		deliveries = append(deliveries,
+			convertWWWWebhookDeliveryFromUser(ds[i]))
+	}
+
+	return &www.WebhookDeliveriesReply{
+		Deliveries: deliveries,
+	}, nil
+}
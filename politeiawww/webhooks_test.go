@@ -0,0 +1,275 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+	"github.com/thi4go/politeia/politeiawww/user"
+)
+
+func TestProcessNewWebhook(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	secret := "0123456789abcdef"
+	var tests = []struct {
+		name string
+		nw   www.NewWebhook
+		want error
+	}{
+		{"invalid url",
+			www.NewWebhook{
+				URL:    "localhost:8080",
+				Secret: secret,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidWebhookURL,
+			}},
+
+		{"invalid scheme",
+			www.NewWebhook{
+				URL:    "ftp://localhost/hook",
+				Secret: secret,
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidWebhookURL,
+			}},
+
+		{"invalid event",
+			www.NewWebhook{
+				URL:    "https://localhost/hook",
+				Events: []string{"pingo"},
+				Secret: secret,
+			},
+			www.UserError{
+				ErrorCode:    www.ErrorStatusInvalidWebhookEvent,
+				ErrorContext: []string{"pingo"},
+			}},
+
+		{"short secret",
+			www.NewWebhook{
+				URL:    "https://localhost/hook",
+				Secret: "secret",
+			},
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvalidWebhookSecret,
+			}},
+
+		{"success",
+			www.NewWebhook{
+				URL:    "https://localhost/hook",
+				Events: []string{www.WebhookEventDCCNew},
+				Secret: secret,
+			},
+			nil},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			nwr, err := p.processNewWebhook(v.nw)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+
+			if v.want != nil {
+				return
+			}
+
+			// The webhook is listed without its secret
+			wr, err := p.processWebhooks()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(wr.Webhooks) != 1 ||
+				wr.Webhooks[0].ID != nwr.Webhook.ID {
+				t.Fatalf("got webhooks %v, want %v", wr.Webhooks,
+					nwr.Webhook)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	p, cleanup := newTestPoliteiawww(t)
+	defer cleanup()
+
+	// Setup webhook receiver
+	secret := "0123456789abcdef"
+	status := http.StatusOK
+	received := make(chan www.WebhookPayload, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		got := r.Header.Get(www.WebhookHeaderSignature)
+		want := webhookSignature(secret, body)
+		if got != want {
+			t.Errorf("got signature %v, want %v", got, want)
+		}
+		var wp www.WebhookPayload
+		err = json.Unmarshal(body, &wp)
+		if err != nil {
+			t.Error(err)
+		}
+		if r.Header.Get(www.WebhookHeaderDelivery) != wp.ID {
+			t.Errorf("delivery header does not match payload")
+		}
+		received <- wp
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	p.webhookClient = ts.Client()
+
+	nwr, err := p.processNewWebhook(www.NewWebhook{
+		URL:    ts.URL,
+		Events: []string{www.WebhookEventDCCNew},
+		Secret: secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events that the webhook is not subscribed to are not queued
+	err = p.queueWebhookDeliveries(www.WebhookEventDCCSupportOppose,
+		EventDataDCCSupportOppose{Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := p.db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("got %v pending deliveries, want 0", len(pending))
+	}
+
+	// A successful delivery
+	err = p.queueWebhookDeliveries(www.WebhookEventDCCNew,
+		EventDataDCCNew{Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.sendWebhookDeliveries()
+	wp := <-received
+	if wp.Event != www.WebhookEventDCCNew {
+		t.Errorf("got event %v, want %v", wp.Event,
+			www.WebhookEventDCCNew)
+	}
+
+	// A failed delivery stays in the outbox
+	status = http.StatusInternalServerError
+	err = p.queueWebhookDeliveries(www.WebhookEventDCCNew,
+		EventDataDCCNew{Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.sendWebhookDeliveries()
+	<-received
+	pending, err = p.db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("got %v pending deliveries, want 1", len(pending))
+	}
+	d := pending[0]
+	if d.Attempts != 1 || d.ResponseCode != status ||
+		d.NextAttempt <= time.Now().Unix() {
+		t.Fatalf("unexpected pending delivery %v", d)
+	}
+
+	// The retry is not attempted before it is due
+	p.sendWebhookDeliveries()
+	select {
+	case <-received:
+		t.Fatalf("retry was attempted early")
+	default:
+	}
+
+	// The delivery log contains both deliveries
+	wdr, err := p.processWebhookDeliveries(www.WebhookDeliveries{
+		ID: nwr.Webhook.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[www.WebhookDeliveryStatusT]int)
+	for _, v := range wdr.Deliveries {
+		statuses[v.Status]++
+	}
+	if len(wdr.Deliveries) != 2 ||
+		statuses[www.WebhookDeliveryStatusPending] != 1 ||
+		statuses[www.WebhookDeliveryStatusDelivered] != 1 {
+		t.Fatalf("unexpected deliveries %v", wdr.Deliveries)
+	}
+
+	// Deleting the webhook removes its outbox
+	_, err = p.processDeleteWebhook(www.DeleteWebhook{
+		ID: nwr.Webhook.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = p.db.WebhookDeliveriesPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("got %v pending deliveries, want 0", len(pending))
+	}
+	_, err = p.processDeleteWebhook(www.DeleteWebhook{
+		ID: nwr.Webhook.ID,
+	})
+	got := errToStr(err)
+	want := errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusWebhookNotFound,
+	})
+	if got != want {
+		t.Errorf("got error %v, want %v", got, want)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	var tests = []struct {
+		attempts uint32
+		want     time.Duration
+	}{
+		{1, webhookRetryInterval},
+		{2, 2 * webhookRetryInterval},
+		{3, 4 * webhookRetryInterval},
+		{20, webhookMaxRetryInterval},
+	}
+
+	for _, v := range tests {
+		got := webhookRetryDelay(v.attempts)
+		if got != v.want {
+			t.Errorf("attempts %v: got %v, want %v", v.attempts,
+				got, v.want)
+		}
+	}
+
+	// Retries are capped at the maximum number of attempts
+	p := politeiawww{
+		webhookClient: &http.Client{},
+	}
+	d := user.WebhookDelivery{
+		Attempts: webhookMaxAttempts - 1,
+		Payload:  "{}",
+	}
+	p.deliverWebhook(user.Webhook{URL: "http://"}, &d)
+	if d.Status != user.WebhookDeliveryStatusFailed || d.Error == "" {
+		t.Fatalf("unexpected delivery %v", d)
+	}
+}
@@ -198,6 +198,7 @@ func (w *wsDcrdata) reconnect() error {
 	if w.isShutdown() {
 		return errShutdown
 	}
+	dcrdataReconnects.Inc()
 
 	prevSubscriptions := make(map[string]struct{}, len(w.subscriptions))
 	timeToWait := 1 * time.Minute
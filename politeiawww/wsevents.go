@@ -0,0 +1,194 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"time"
+
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+const (
+	// wsEventBufferSize is the number of notifications that can be queued
+	// for a websocket. Notifications are dropped for websockets that do
+	// not keep up.
+	wsEventBufferSize = 64
+)
+
+// wsEvent is a server side notification that is queued for a websocket.
+type wsEvent struct {
+	command string
+	payload interface{}
+}
+
+// websocketNotify queues a proposal notification for every websocket that is
+// subscribed to the provided command and whose token filter, if any, contains
+// the provided token.
+func (p *politeiawww) websocketNotify(cmd, token string, payload interface{}) {
+	log.Tracef("websocketNotify %v %v", cmd, token)
+
+	p.wsMtx.RLock()
+	defer p.wsMtx.RUnlock()
+
+	for _, contexts := range p.ws {
+		for _, wc := range contexts {
+			if _, ok := wc.subscriptions[cmd]; !ok {
+				continue
+			}
+			if len(wc.tokens) > 0 {
+				if _, ok := wc.tokens[token]; !ok {
+					continue
+				}
+			}
+
+			select {
+			case wc.eventC <- wsEvent{command: cmd, payload: payload}:
+			default:
+				log.Debugf("websocketNotify: dropped %v %v for %v",
+					cmd, token, wc)
+			}
+		}
+	}
+}
+
+// addActiveVote starts tracking the end height of a proposal vote.
+func (p *politeiawww) addActiveVote(token string, endHeight uint64) {
+	p.avMtx.Lock()
+	defer p.avMtx.Unlock()
+
+	p.activeVotes[token] = endHeight
+}
+
+// finishActiveVotes fires a vote finished event for every tracked proposal
+// vote that has ended at the provided best block.
+func (p *politeiawww) finishActiveVotes(bestBlock uint64) {
+	finished := make(map[string]uint64)
+	p.avMtx.Lock()
+	for token, endHeight := range p.activeVotes {
+		if bestBlock >= endHeight {
+			finished[token] = endHeight
+			delete(p.activeVotes, token)
+		}
+	}
+	p.avMtx.Unlock()
+
+	for token, endHeight := range finished {
+		p.fireEvent(EventTypeProposalVoteFinished,
+			EventDataProposalVoteFinished{
+				Token:     token,
+				EndHeight: endHeight,
+			},
+		)
+	}
+}
+
+// initActiveVotes loads the end heights of the proposal votes that are
+// currently active.
+func (p *politeiawww) initActiveVotes() error {
+	bb, err := p.getBestBlock()
+	if err != nil {
+		return err
+	}
+	ti, err := p.decredTokenInventory(bb, false)
+	if err != nil {
+		return err
+	}
+	vs, err := p.getVoteSummaries(ti.Active, bb)
+	if err != nil {
+		return err
+	}
+
+	p.avMtx.Lock()
+	defer p.avMtx.Unlock()
+
+	for token, v := range vs {
+		p.activeVotes[token] = v.EndHeight
+	}
+
+	log.Infof("Active proposal votes: %v", len(p.activeVotes))
+
+	return nil
+}
+
+// _setupWebsocketNotifications forwards proposal events to the websockets
+// that are subscribed to them.
+//
+// This function must be called WITH the mutex held.
+func (p *politeiawww) _setupWebsocketNotifications() {
+	ch := make(chan interface{})
+	go func() {
+		for data := range ch {
+			switch d := data.(type) {
+			case EventDataProposalStatusChange:
+				// Only vetted proposals are public
+				status := d.SetProposalStatus.ProposalStatus
+				if status != www.PropStatusPublic &&
+					status != www.PropStatusAbandoned {
+					continue
+				}
+				token := d.Proposal.CensorshipRecord.Token
+				p.websocketNotify(www.WSCProposalStatus, token,
+					www.WSProposalStatus{
+						Token:     token,
+						Status:    status,
+						Timestamp: time.Now().Unix(),
+					})
+
+			case EventDataComment:
+				p.websocketNotify(www.WSCNewComment, d.Comment.Token,
+					www.WSNewComment{
+						Comment: *d.Comment,
+					})
+
+			case EventDataCommentVote:
+				p.websocketNotify(www.WSCCommentVote, d.Token,
+					www.WSCommentVote{
+						Token:     d.Token,
+						CommentID: d.CommentID,
+						Result:    int64(d.Upvotes - d.Downvotes),
+						Upvotes:   d.Upvotes,
+						Downvotes: d.Downvotes,
+					})
+
+			case EventDataProposalVoteStarted:
+				if d.StartVoteReply == nil {
+					continue
+				}
+				token := d.StartVote.Vote.Token
+				p.addActiveVote(token,
+					uint64(d.StartVoteReply.EndBlockHeight))
+				p.websocketNotify(www.WSCVoteStarted, token,
+					www.WSVoteStarted{
+						Token:            token,
+						StartBlockHeight: d.StartVoteReply.StartBlockHeight,
+						EndBlockHeight:   d.StartVoteReply.EndBlockHeight,
+					})
+
+			case EventDataVotesCast:
+				p.websocketNotify(www.WSCVoteTally, d.Token,
+					www.WSVoteTally{
+						Token: d.Token,
+						Votes: d.Votes,
+					})
+
+			case EventDataProposalVoteFinished:
+				p.websocketNotify(www.WSCVoteFinished, d.Token,
+					www.WSVoteFinished{
+						Token:     d.Token,
+						EndHeight: d.EndHeight,
+					})
+
+			default:
+				log.Errorf("invalid event data")
+			}
+		}
+	}()
+	p.eventManager._register(EventTypeProposalStatusChange, ch)
+	p.eventManager._register(EventTypeComment, ch)
+	p.eventManager._register(EventTypeCommentVote, ch)
+	p.eventManager._register(EventTypeProposalVoteStarted, ch)
+	p.eventManager._register(EventTypeVotesCast, ch)
+	p.eventManager._register(EventTypeProposalVoteFinished, ch)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
+)
+
+func TestWebsocketNotify(t *testing.T) {
+	newContext := func(subscriptions, tokens []string) *wsContext {
+		wc := wsContext{
+			subscriptions: make(map[string]struct{}),
+			tokens:        make(map[string]struct{}),
+			eventC:        make(chan wsEvent, 1),
+		}
+		for _, v := range subscriptions {
+			wc.subscriptions[v] = struct{}{}
+		}
+		for _, v := range tokens {
+			wc.tokens[v] = struct{}{}
+		}
+		return &wc
+	}
+
+	// Setup websockets
+	all := newContext([]string{www.WSCVoteTally}, nil)
+	filtered := newContext([]string{www.WSCVoteTally}, []string{"a"})
+	unsubscribed := newContext([]string{www.WSCPing}, nil)
+	p := politeiawww{
+		ws: map[string]map[string]*wsContext{
+			"": {
+				"all":      all,
+				"filtered": filtered,
+			},
+			"user": {
+				"unsubscribed": unsubscribed,
+			},
+		},
+	}
+
+	// Setup tests
+	tests := []struct {
+		name  string
+		token string
+		wc    *wsContext
+		want  bool // Whether the notification is queued
+	}{
+		{"all tokens", "b", all, true},
+		{"token not in filter", "b", filtered, false},
+		{"token in filter", "a", filtered, true},
+		{"not subscribed", "a", unsubscribed, false},
+	}
+
+	// Run tests
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p.websocketNotify(www.WSCVoteTally, test.token,
+				www.WSVoteTally{Token: test.token})
+
+			var got bool
+			select {
+			case e := <-test.wc.eventC:
+				got = true
+				if e.command != www.WSCVoteTally {
+					t.Errorf("got command %v, want %v",
+						e.command, www.WSCVoteTally)
+				}
+			default:
+			}
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+
+			// Drain the notifications of the other websockets
+			for _, wc := range []*wsContext{all, filtered, unsubscribed} {
+				select {
+				case <-wc.eventC:
+				default:
+				}
+			}
+		})
+	}
+
+	// A full queue drops the notification instead of blocking
+	p.websocketNotify(www.WSCVoteTally, "a", www.WSVoteTally{})
+	p.websocketNotify(www.WSCVoteTally, "a", www.WSVoteTally{})
+	if len(all.eventC) != 1 {
+		t.Errorf("got %v queued notifications, want 1", len(all.eventC))
+	}
+}
+
+func TestFinishActiveVotes(t *testing.T) {
+	p := politeiawww{
+		test: true,
+		activeVotes: map[string]uint64{
+			"a": 100,
+			"b": 200,
+		},
+	}
+
+	p.finishActiveVotes(99)
+	if len(p.activeVotes) != 2 {
+		t.Fatalf("got %v active votes, want 2", len(p.activeVotes))
+	}
+
+	p.finishActiveVotes(100)
+	if _, ok := p.activeVotes["a"]; ok {
+		t.Fatalf("vote a is still active at its end height")
+	}
+	if _, ok := p.activeVotes["b"]; !ok {
+		t.Fatalf("vote b is no longer active")
+	}
+}
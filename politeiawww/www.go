@@ -10,6 +10,7 @@ import (
 	"crypto/elliptic"
 	"crypto/tls"
 	_ "encoding/gob"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -24,8 +25,12 @@ import (
 	"time"
 
 	"github.com/thi4go/politeia/mdstream"
+	pd "github.com/thi4go/politeia/politeiad/api/v1"
+	"github.com/thi4go/politeia/politeiad/api/v1/identity"
 	"github.com/thi4go/politeia/politeiad/cache"
+	"github.com/thi4go/politeia/politeiad/cache/cachemetrics"
 	cachedb "github.com/thi4go/politeia/politeiad/cache/cockroachdb"
+	sqlitecache "github.com/thi4go/politeia/politeiad/cache/sqlite"
 	cms "github.com/thi4go/politeia/politeiawww/api/cms/v1"
 	www "github.com/thi4go/politeia/politeiawww/api/www/v1"
 	database "github.com/thi4go/politeia/politeiawww/cmsdatabase"
@@ -183,6 +188,11 @@ func RespondWithError(w http.ResponseWriter, r *http.Request, userHttpCode int,
 func (p *politeiawww) addRoute(method string, routeVersion string, route string, handler http.HandlerFunc, perm permission) {
 	fullRoute := routeVersion + route
 
+	// Websocket connections are long lived and are counted separately
+	if method != "" {
+		handler = instrument(fullRoute, handler)
+	}
+
 	switch perm {
 	case permissionAdmin:
 		handler = logging(p.isLoggedInAsAdmin(handler))
@@ -234,7 +244,11 @@ func (p *politeiawww) makeRequest(method string, route string, v interface{}) ([
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(p.cfg.RPCUser, p.cfg.RPCPass)
+	if p.rpcIdentity != nil {
+		util.SignRequest(req, p.rpcIdentity, requestBody)
+	} else {
+		req.SetBasicAuth(p.cfg.RPCUser, p.cfg.RPCPass)
+	}
 	r, err := p.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -258,6 +272,51 @@ func (p *politeiawww) makeRequest(method string, route string, v interface{}) ([
 	return responseBody, nil
 }
 
+// walkInventory pages through the politeiad inventory and calls fn with each
+// record that is returned.  The challenge and cursor of the request are set by
+// walkInventory, the remaining fields select the records.  Only a single page
+// of records is held in memory at a time.
+func (p *politeiawww) walkInventory(ip pd.InventoryPage, fn func(pd.Record) error) error {
+	ip.Cursor = ""
+	for {
+		challenge, err := util.Random(pd.ChallengeSize)
+		if err != nil {
+			return err
+		}
+		ip.Challenge = hex.EncodeToString(challenge)
+
+		responseBody, err := p.makeRequest(http.MethodPost,
+			pd.InventoryPageRoute, ip)
+		if err != nil {
+			return fmt.Errorf("makeRequest: %v", err)
+		}
+
+		var reply pd.InventoryPageReply
+		err = json.Unmarshal(responseBody, &reply)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal "+
+				"InventoryPageReply: %v", err)
+		}
+
+		err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range reply.Records {
+			err := fn(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		if reply.Cursor == "" {
+			return nil
+		}
+		ip.Cursor = reply.Cursor
+	}
+}
+
 func _main() error {
 	// Load configuration and parse command line.  This function also
 	// initializes logging and configures it accordingly.
@@ -329,6 +388,12 @@ func _main() error {
 		userPaywallPool: make(map[uuid.UUID]paywallPoolMember),
 		commentVotes:    make(map[string]counters),
 		voteSummaries:   make(map[string]www.VoteSummary),
+		activeVotes:     make(map[string]uint64),
+		webhooks:        make(map[uuid.UUID]user.Webhook),
+		webhookC:        make(chan struct{}, 1),
+		webhookClient:   &http.Client{Timeout: webhookTimeout},
+		emailC:          make(chan struct{}, 1),
+		emailLimits:     newEmailRateLimiter(loadedCfg.MailRateLimit),
 		params:          activeNetParams.Params,
 	}
 
@@ -337,10 +402,20 @@ func _main() error {
 		return p.getIdentity()
 	}
 
+	// Load the client identity that signs privileged commands
+	if p.cfg.RPCIdentity != "" {
+		p.rpcIdentity, err = identity.LoadFullIdentity(p.cfg.RPCIdentity)
+		if err != nil {
+			return fmt.Errorf("load rpcidentity: %v", err)
+		}
+		log.Infof("Signing politeiad commands with identity %v",
+			p.rpcIdentity.Public.String())
+	}
+
 	// Setup email
 	smtp, err := newSMTP(p.cfg.MailHost, p.cfg.MailUser,
-		p.cfg.MailPass, p.cfg.MailAddress, p.cfg.SystemCerts,
-		p.cfg.SMTPSkipVerify)
+		p.cfg.MailPass, p.cfg.MailAddress, p.cfg.MailDir,
+		p.cfg.SystemCerts, p.cfg.SMTPSkipVerify)
 	if err != nil {
 		return fmt.Errorf("unable to initialize SMTP client: %v",
 			err)
@@ -397,10 +472,19 @@ func _main() error {
 	}
 
 	// Setup cache connection
-	cachedb.UseLogger(cockroachdbLog)
-	net := filepath.Base(p.cfg.DataDir)
-	p.cache, err = cachedb.New(cachedb.UserPoliteiawww, p.cfg.DBHost,
-		net, p.cfg.DBRootCert, p.cfg.DBCert, p.cfg.DBKey)
+	switch p.cfg.CacheDB {
+	case cacheCockroachDB:
+		cachedb.UseLogger(cockroachdbLog)
+		net := filepath.Base(p.cfg.DataDir)
+		p.cache, err = cachedb.New(cachedb.UserPoliteiawww, p.cfg.DBHost,
+			net, p.cfg.DBRootCert, p.cfg.DBCert, p.cfg.DBKey)
+	case cacheSQLite:
+		// The SQLite cache is written by politeiad only; open it
+		// read-only so that the two processes cannot race each
+		// other when writing.
+		sqlitecache.UseLogger(sqliteLog)
+		p.cache, err = sqlitecache.New(p.cfg.CacheFile, true)
+	}
 	if err != nil {
 		switch err {
 		case cache.ErrNoVersionRecord:
@@ -412,6 +496,7 @@ func _main() error {
 		}
 		return fmt.Errorf("cachedb new: %v", err)
 	}
+	p.cache = cachemetrics.New(p.cache)
 
 	// Register plugins with cache
 	for _, v := range p.plugins {
@@ -452,10 +537,28 @@ func _main() error {
 			return err
 		}
 		p.initEventManager()
+
+		// Track active proposal votes for websocket notifications
+		err = p.initActiveVotes()
+		if err != nil {
+			return fmt.Errorf("initActiveVotes: %v", err)
+		}
 	} else if p.cfg.Mode == "cmswww" {
 		p.initCMSEventManager()
 	}
 
+	// Start sending the emails in the email outbox
+	err = p.initEmailOutbox()
+	if err != nil {
+		return fmt.Errorf("initEmailOutbox: %v", err)
+	}
+
+	// Start delivering events to webhooks
+	err = p.initWebhooks()
+	if err != nil {
+		return fmt.Errorf("initWebhooks: %v", err)
+	}
+
 	// Load or create new CSRF key
 	log.Infof("Load CSRF key")
 	csrfKeyFilename := filepath.Join(p.cfg.DataDir, "csrf.key")
@@ -522,6 +625,13 @@ func _main() error {
 			// should be logged.
 			log.Errorf("Unable to setup pi dcrdata subs: %v", err)
 		}
+
+		// Setup email digests
+		p.cron = cron.New()
+		err = p.initEmailDigests()
+		if err != nil {
+			return fmt.Errorf("initEmailDigests: %v", err)
+		}
 	case cmsWWWMode:
 		p.setCMSWWWRoutes()
 		// XXX setup user routes
@@ -547,22 +657,20 @@ func _main() error {
 
 		// Build the cms database
 		if p.cfg.BuildCMSDB {
-			// Fetch all versions of all records from the inventory and
-			// use them to build the cache.
-			vetted, err := p.cache.Inventory()
-			if err != nil {
-				return fmt.Errorf("backend inventory: %v", err)
+			// Page through the politeiad inventory and use the latest
+			// version of each record to build the cms database.
+			dbInvs := make([]database.Invoice, 0)
+			dbDCCs := make([]database.DCC, 0)
+			ip := pd.InventoryPage{
+				IncludeFiles: true,
 			}
-
-			dbInvs := make([]database.Invoice, 0, len(vetted))
-			dbDCCs := make([]database.DCC, 0, len(vetted))
-			for _, r := range vetted {
+			err := p.walkInventory(ip, func(r pd.Record) error {
 				for _, m := range r.Metadata {
 					switch m.ID {
 					case mdstream.IDInvoiceGeneral:
-						i, err := convertCacheToDatabaseInvoice(r)
+						i, err := convertRecordToDatabaseInvoice(r)
 						if err != nil {
-							log.Errorf("convertCacheToDatabaseInvoice: %v", err)
+							log.Errorf("convertRecordToDatabaseInvoice: %v", err)
 							break
 						}
 						u, err := p.db.UserGetByPubKey(i.PublicKey)
@@ -574,14 +682,18 @@ func _main() error {
 						i.Username = u.Username
 						dbInvs = append(dbInvs, *i)
 					case mdstream.IDDCCGeneral:
-						d, err := convertCacheToDatabaseDCC(r)
+						d, err := convertRecordToDatabaseDCC(r)
 						if err != nil {
-							log.Errorf("convertCacheToDatabaseDCC: %v", err)
+							log.Errorf("convertRecordToDatabaseDCC: %v", err)
 							break
 						}
 						dbDCCs = append(dbDCCs, *d)
 					}
 				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("politeiad inventory: %v", err)
 			}
 
 			// Build the cache
@@ -634,6 +746,12 @@ func _main() error {
 	}
 	p.sessions = NewSessionStore(p.db, sessionMaxAge, cookieKey)
 
+	// Serve metrics
+	if loadedCfg.Metrics != "" {
+		p.registerMetrics()
+		serveMetrics(loadedCfg.Metrics)
+	}
+
 	// Bind to a port and pass our router in
 	listenC := make(chan error)
 	for _, listener := range loadedCfg.Listeners {
@@ -290,7 +290,7 @@ func (t *tserver) anchorRecords() {
 
 	log.Infof("Anchoring records: %v", len(anchors))
 
-	err := util.Timestamp("tserver", t.cfg.DcrtimeHost, hashes)
+	err := t.timestamper.Timestamp("tserver", hashes)
 	if err != nil {
 		exitError = err
 		return
@@ -336,7 +336,7 @@ func (t *tserver) waitForAchor(trees []*trillian.Tree, anchors []v1.DataAnchor,
 
 		log.Tracef("anchorRecords checking anchor")
 
-		vr, err := util.Verify("tserver", t.cfg.DcrtimeHost, waitFor)
+		vr, err := t.timestamper.Verify("tserver", waitFor)
 		if err != nil {
 			if _, ok := err.(util.ErrNotAnchored); ok {
 				// Anchor not dropped, try again
@@ -623,7 +623,7 @@ func (t *tserver) fsckRecord(tree *trillian.Tree, lrv1 *types.LogRootV1) error {
 				glbrr.Leaves[x].ExtraData)
 
 			// Verify anchor
-			_, err = util.Verify("tserver", t.cfg.DcrtimeHost,
+			_, err = t.timestamper.Verify("tserver",
 				[]string{da.VerifyDigest.Digest})
 			if err != nil {
 				return fmt.Errorf("fsckRecord failed "+
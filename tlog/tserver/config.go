@@ -16,6 +16,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil"
 	v1 "github.com/decred/dcrtime/api/v1"
@@ -77,6 +78,9 @@ type config struct {
 	TrillianHost  string `long:"trillianhost" description:"Trillian log host"`
 	DcrtimeHost   string `long:"dcrtimehost" description:"Dcrtime ip:port"`
 	DcrtimeCert   string `long:"dcrtimecert" description:"File containing the https certificate file for dcrtimehost"`
+
+	LocalDcrtime      bool          `long:"localdcrtime" description:"Simulate anchoring locally instead of using dcrtimehost, for testing only"`
+	LocalDcrtimeDelay time.Duration `long:"localdcrtimedelay" description:"Anchor confirmation delay of the local dcrtime simulation"`
 }
 
 // serviceOptions defines the configuration options for the daemon as a service
@@ -55,6 +55,8 @@ type tserver struct {
 
 	s Blob // Storage interface
 
+	timestamper util.Timestamper // Dcrtime client
+
 	cron *cron.Cron // Scheduler for periodic tasks
 
 	cfg    *config
@@ -911,7 +913,13 @@ func _main() error {
 	defer g.Close()
 
 	// Dcrtime host
-	log.Infof("Anchor host: %v", loadedCfg.DcrtimeHost)
+	timestamper := util.NewDcrtime(loadedCfg.DcrtimeHost)
+	if loadedCfg.LocalDcrtime {
+		log.Warnf("Anchors are simulated locally and are not " +
+			"timestamped in the decred blockchain")
+		timestamper = util.NewLocalTimestamper(loadedCfg.LocalDcrtimeDelay)
+	}
+	log.Infof("Anchor host: %v", timestamper)
 
 	// Setup application context.
 	t := &tserver{
@@ -922,6 +930,8 @@ func _main() error {
 		ctx:        context.Background(),
 		signingKey: &keyspb.PrivateKey{},
 		dirty:      make(map[int64]int64),
+
+		timestamper: timestamper,
 	}
 
 	// Load certs, if there.  If they aren't there assume OS is used to
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/thi4go/politeia/politeiad/api/v1"
 	"github.com/thi4go/politeia/politeiad/api/v1/identity"
@@ -100,3 +102,15 @@ func VerifyChallenge(id *identity.PublicIdentity, challenge []byte, signature st
 
 	return nil
 }
+
+// SignRequest signs a request to a privileged politeiad route with the
+// provided client identity.  The body must be the exact payload that is sent
+// with the request.
+func SignRequest(r *http.Request, id *identity.FullIdentity, body []byte) {
+	timestamp := time.Now().Unix()
+	sig := id.SignMessage(v1.RequestMessage(r.Method, r.URL.Path,
+		timestamp, body))
+	r.Header.Set(v1.ClientKeyHeader, id.Public.String())
+	r.Header.Set(v1.ClientTimestampHeader, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(v1.ClientSignatureHeader, hex.EncodeToString(sig[:]))
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/decred/dcrtime/api/v1"
+	"github.com/decred/dcrtime/merkle"
+)
+
+// Timestamper timestamps digests and verifies that they have been anchored in
+// the decred blockchain.  The semantics of both methods are identical to the
+// ones of the Timestamp and Verify functions.
+type Timestamper interface {
+	// Timestamp submits digests to be anchored.
+	Timestamp(id string, digests []*[sha256.Size]byte) error
+
+	// Verify returns the anchor information of the provided digests.  An
+	// ErrNotAnchored error is returned if a digest was timestamped but has
+	// not been anchored yet.
+	Verify(id string, digests []string) (*v1.VerifyReply, error)
+}
+
+// dcrtime is a Timestamper that uses a dcrtime host.
+type dcrtime struct {
+	host string
+}
+
+// Timestamp satisfies the Timestamper interface.
+func (d *dcrtime) Timestamp(id string, digests []*[sha256.Size]byte) error {
+	return Timestamp(id, d.host, digests)
+}
+
+// Verify satisfies the Timestamper interface.
+func (d *dcrtime) Verify(id string, digests []string) (*v1.VerifyReply, error) {
+	return Verify(id, d.host, digests)
+}
+
+// String returns the dcrtime host.
+func (d *dcrtime) String() string {
+	return d.host
+}
+
+// NewDcrtime returns a Timestamper that uses the provided dcrtime host.  The
+// caller is responsible for assembling the host string based on what net to
+// use.
+func NewDcrtime(host string) Timestamper {
+	return &dcrtime{
+		host: host,
+	}
+}
+
+// localAnchor is a simulated anchor.
+type localAnchor struct {
+	timestamp  int64                // Chain timestamp
+	tx         string               // Simulated transaction hash
+	merkleRoot *[sha256.Size]byte   // Merkle root of all anchored digests
+	leaves     []*[sha256.Size]byte // Sorted anchored digests
+}
+
+// localDigest is a digest that was submitted to a LocalTimestamper.
+type localDigest struct {
+	submitted time.Time    // Time the digest was timestamped
+	anchor    *localAnchor // Anchor, nil until the digest is anchored
+}
+
+// LocalTimestamper is a Timestamper that simulates dcrtime without accessing
+// the network.  Digests are anchored once the confirmation delay has elapsed
+// since they were timestamped.  All digests that are ready when Verify is
+// called are anchored together, just like dcrtime anchors all digests that
+// were collected since the previous anchor in a single transaction.
+//
+// LocalTimestamper does not persist its state and is meant for testing only.
+type LocalTimestamper struct {
+	sync.Mutex
+	delay   time.Duration
+	digests map[string]*localDigest // [digest]localDigest
+	anchors int                     // Number of anchors
+}
+
+// anchor anchors all digests that have reached the confirmation delay.  This
+// function must be called with the lock held.
+func (l *LocalTimestamper) anchor(now time.Time) {
+	ready := make([]string, 0, len(l.digests))
+	for k, v := range l.digests {
+		if v.anchor == nil && now.Sub(v.submitted) >= l.delay {
+			ready = append(ready, k)
+		}
+	}
+	if len(ready) == 0 {
+		return
+	}
+
+	sort.Strings(ready)
+	leaves := make([]*[sha256.Size]byte, 0, len(ready))
+	for _, v := range ready {
+		d, ok := ConvertDigest(v)
+		if !ok {
+			// Timestamp only stores hex encoded digests.
+			panic("invalid digest: " + v)
+		}
+		leaves = append(leaves, &d)
+	}
+	root := merkle.Root(leaves)
+
+	// The transaction hash is derived from the merkle root and the
+	// anchor number so that every anchor has a unique transaction.
+	l.anchors++
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(l.anchors))
+	tx := sha256.Sum256(append(root[:], n[:]...))
+
+	a := &localAnchor{
+		timestamp:  now.Unix(),
+		tx:         hex.EncodeToString(tx[:]),
+		merkleRoot: root,
+		leaves:     leaves,
+	}
+	for _, v := range ready {
+		l.digests[v].anchor = a
+	}
+}
+
+// Timestamp satisfies the Timestamper interface.  Digests that have been
+// timestamped before are ignored.
+func (l *LocalTimestamper) Timestamp(id string, digests []*[sha256.Size]byte) error {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	for _, v := range digests {
+		d := hex.EncodeToString(v[:])
+		if _, ok := l.digests[d]; ok {
+			continue
+		}
+		l.digests[d] = &localDigest{
+			submitted: now,
+		}
+	}
+	return nil
+}
+
+// Verify satisfies the Timestamper interface.
+func (l *LocalTimestamper) Verify(id string, digests []string) (*v1.VerifyReply, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.anchor(time.Now())
+
+	vr := v1.VerifyReply{
+		ID:      id,
+		Digests: make([]v1.VerifyDigest, 0, len(digests)),
+	}
+	for _, digest := range digests {
+		if !isDigest(digest) {
+			return nil, fmt.Errorf("not a valid digest: %v", digest)
+		}
+		ld, ok := l.digests[digest]
+		if !ok {
+			return nil, fmt.Errorf("Digest not found: %v", digest)
+		}
+		if ld.anchor == nil {
+			return nil, ErrNotAnchored{
+				err: fmt.Errorf("%v Not anchored", digest),
+			}
+		}
+
+		d, _ := ConvertDigest(digest)
+		vr.Digests = append(vr.Digests, v1.VerifyDigest{
+			Digest:          digest,
+			ServerTimestamp: ld.submitted.Unix(),
+			Result:          v1.ResultOK,
+			ChainInformation: v1.ChainInformation{
+				ChainTimestamp: ld.anchor.timestamp,
+				Transaction:    ld.anchor.tx,
+				MerkleRoot:     hex.EncodeToString(ld.anchor.merkleRoot[:]),
+				MerklePath:     *merkle.AuthPath(ld.anchor.leaves, &d),
+			},
+		})
+	}
+
+	return &vr, nil
+}
+
+// String returns a description of the timestamper.
+func (l *LocalTimestamper) String() string {
+	return fmt.Sprintf("local (confirmation delay %v)", l.delay)
+}
+
+// NewLocalTimestamper returns a LocalTimestamper that anchors digests once
+// the provided confirmation delay has elapsed.
+func NewLocalTimestamper(delay time.Duration) *LocalTimestamper {
+	return &LocalTimestamper{
+		delay:   delay,
+		digests: make(map[string]*localDigest),
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrtime/merkle"
+)
+
+func TestLocalTimestamper(t *testing.T) {
+	digests := make([]*[sha256.Size]byte, 0, 3)
+	hexDigests := make([]string, 0, 3)
+	for _, v := range []string{"a", "b", "c"} {
+		d := sha256.Sum256([]byte(v))
+		digests = append(digests, &d)
+		hexDigests = append(hexDigests, hex.EncodeToString(d[:]))
+	}
+
+	// Digests are not anchored before the confirmation delay elapsed.
+	l := NewLocalTimestamper(time.Hour)
+	err := l.Timestamp("test", digests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Verify("test", hexDigests)
+	if _, ok := err.(ErrNotAnchored); !ok {
+		t.Fatalf("expected ErrNotAnchored, got %v", err)
+	}
+
+	// Digests are anchored together once the delay elapsed.
+	l = NewLocalTimestamper(0)
+	err = l.Timestamp("test", digests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr, err := l.Verify("test", hexDigests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vr.Digests) != len(digests) {
+		t.Fatalf("got %v digests, want %v", len(vr.Digests),
+			len(digests))
+	}
+	tx := vr.Digests[0].ChainInformation.Transaction
+	for _, v := range vr.Digests {
+		ci := v.ChainInformation
+		if ci.Transaction != tx {
+			t.Fatalf("digests anchored in different transactions")
+		}
+		root, err := merkle.VerifyAuthPath(&ci.MerklePath)
+		if err != nil {
+			t.Fatalf("%v: %v", v.Digest, err)
+		}
+		if hex.EncodeToString(root[:]) != ci.MerkleRoot {
+			t.Fatalf("%v: invalid merkle root", v.Digest)
+		}
+		if ci.MerklePath.NumLeaves != uint32(len(digests)) {
+			t.Fatalf("%v: got %v leaves, want %v", v.Digest,
+				ci.MerklePath.NumLeaves, len(digests))
+		}
+	}
+
+	// Digests that are timestamped later are anchored in a new
+	// transaction.
+	d := sha256.Sum256([]byte("d"))
+	err = l.Timestamp("test", []*[sha256.Size]byte{&d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vr, err = l.Verify("test", []string{hex.EncodeToString(d[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vr.Digests[0].ChainInformation.Transaction == tx {
+		t.Fatalf("expected a new anchor transaction")
+	}
+
+	// Unknown digests are rejected.
+	e := sha256.Sum256([]byte("e"))
+	_, err = l.Verify("test", []string{hex.EncodeToString(e[:])})
+	if err == nil {
+		t.Fatalf("expected error for unknown digest")
+	}
+}